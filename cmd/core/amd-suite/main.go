@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+
+	"github.com/alecthomas/kong"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	programName = "amd-test-suite"
+	programDesc = "AMD PSB/SME/SEV Test Suite"
+)
+
+var (
+	testnos   []int
+	logfile   = "test_log.json"
+	gitcommit string
+	gittag    string
+)
+
+type temptest struct {
+	Testnumber  int
+	Testname    string
+	Description string
+	Result      string
+	Error       string
+	Status      string
+}
+
+func main() {
+	ctx := kong.Parse(&cli,
+		kong.Name(programName),
+		kong.Description(programDesc),
+		kong.UsageOnError(),
+		kong.ConfigureHelp(kong.HelpOptions{
+			Compact: true,
+			Summary: true,
+		}))
+
+	logrus.SetOutput(os.Stdout)
+
+	err := ctx.Run(&context{})
+	ctx.FatalIfErrorf(err)
+}
@@ -32,11 +32,13 @@ type markdownCmd struct{}
 type versionCmd struct{}
 
 type execTestsCmd struct {
-	Set         string `required:"" default:"all" help:"Select subset of tests. Options: all, static, runtime, or choose tests by number e.g. --set=1,3,4"`
-	Strict      bool   `required:"" default:"false" short:"s" help:"Enable strict mode. This enables more tests and checks."`
-	Interactive bool   `optional:"" short:"i" help:"Interactive mode. Errors will stop the testing."`
-	Config      string `optional:"" short:"c" help:"Path/Filename to config file."`
-	Firmware    string `optional:"" short:"f" help:"Path/Filename to firmware to test with."`
+	Set          string `required:"" default:"all" help:"Select subset of tests. Options: all, static, runtime, or choose tests by number e.g. --set=1,3,4"`
+	Strict       bool   `required:"" default:"false" short:"s" help:"Enable strict mode. This enables more tests and checks."`
+	Interactive  bool   `optional:"" short:"i" help:"Interactive mode. Errors will stop the testing."`
+	Config       string `optional:"" short:"c" help:"Path/Filename to config file."`
+	Firmware     string `optional:"" short:"f" help:"Path/Filename to firmware to test with."`
+	Log          string `optional:"" help:"Give a path/filename for the test report. e.g.: /path/to/filename.json"`
+	ReportFormat string `optional:"" help:"Write a test report in the given format instead of the test_log.json result list. Options: json, junit, sarif"`
 }
 
 var cli struct {
@@ -56,6 +58,18 @@ func (e *execTestsCmd) Run(ctx *context) error {
 		log.Warn("Unable to map CPU model to Boot Guard/CBnT generation")
 	}
 
+	var err error
+	if e.ReportFormat != "" {
+		reportFormat, err = test.ParseReportFormat(e.ReportFormat)
+		if err != nil {
+			return err
+		}
+		logfile = "test_log." + reportFormat.Extension()
+	}
+	if e.Log != "" {
+		logfile = e.Log
+	}
+
 	data, err := os.ReadFile(e.Firmware)
 	if err != nil {
 		return fmt.Errorf("can't read firmware file")
@@ -183,6 +197,40 @@ func getRuntimeTest() []*test.Test {
 	return tests
 }
 
+func writeReport(testGroup string, tests []*test.Test) error {
+	if reportFormat == "" {
+		var t []temptest
+		bgVersion := string(intel.RuntimeBGVersion())
+		for index := range tests {
+			if tests[index].Status != test.NotImplemented {
+				ttemp := temptest{
+					Testnumber:  index,
+					Testname:    tests[index].Name,
+					Description: tests[index].Description,
+					BgVersion:   bgVersion,
+					Result:      tests[index].Result.String(),
+					Error:       tests[index].ErrorText,
+					Status:      tests[index].Status.String(),
+				}
+				t = append(t, ttemp)
+			}
+		}
+		data, _ := json.MarshalIndent(t, "", "")
+		return os.WriteFile(logfile, data, 0o664)
+	}
+
+	f, err := os.Create(logfile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	report := test.NewReport(programName, gittag, testGroup, tests)
+	report.Properties = map[string]string{
+		"BgVersion": string(intel.RuntimeBGVersion()),
+	}
+	return report.Write(f, reportFormat)
+}
+
 func run(testGroup string, tests []*test.Test, preset *test.PreSet, interactive bool) bool {
 	result := false
 
@@ -211,25 +259,7 @@ func run(testGroup string, tests []*test.Test, preset *test.PreSet, interactive
 	}
 
 	if !interactive {
-		var t []temptest
-		bgVersion := string(intel.RuntimeBGVersion())
-		for index := range tests {
-			if tests[index].Status != test.NotImplemented {
-				ttemp := temptest{
-					Testnumber:  index,
-					Testname:    tests[index].Name,
-					Description: tests[index].Description,
-					BgVersion:   bgVersion,
-					Result:      tests[index].Result.String(),
-					Error:       tests[index].ErrorText,
-					Status:      tests[index].Status.String(),
-				}
-				t = append(t, ttemp)
-			}
-		}
-		data, _ := json.MarshalIndent(t, "", "")
-		err := os.WriteFile(logfile, data, 0o664)
-		if err != nil {
+		if err := writeReport(testGroup, tests); err != nil {
 			log.Errorf("Error writing log file: %v", err)
 		}
 
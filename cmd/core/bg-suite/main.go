@@ -4,6 +4,7 @@ import (
 	"os"
 
 	"github.com/9elements/converged-security-suite/v2/pkg/log"
+	"github.com/9elements/converged-security-suite/v2/pkg/test"
 	"github.com/alecthomas/kong"
 	"github.com/linuxboot/fiano/pkg/intel/metadata/cbnt"
 	fianoLog "github.com/linuxboot/fiano/pkg/log"
@@ -16,10 +17,11 @@ const (
 )
 
 var (
-	testnos   []int
-	logfile   = "test_log.json"
-	gitcommit string
-	gittag    string
+	testnos      []int
+	logfile      = "test_log.json"
+	reportFormat test.ReportFormat
+	gitcommit    string
+	gittag       string
 )
 
 type temptest struct {
@@ -8,12 +8,14 @@ import (
 
 	"github.com/9elements/converged-security-suite/v2/pkg/provisioning/txt"
 	"github.com/9elements/converged-security-suite/v2/pkg/tools"
+	tpmdevice "github.com/9elements/converged-security-suite/v2/pkg/tpm"
 )
 
 // Context for kong command line parser
 // We need a TPM device in most commands.
 type context struct {
-	debug bool
+	debug  bool
+	tpmdev string
 }
 
 type versionCmd struct{}
@@ -35,18 +37,29 @@ type platProvCmd struct {
 	Config string `arg:"" required:"" name:"config" default:"lcp.config" help:"Filename of LCP config file in JSON format" type:"path"`
 	Out    string `flag:"" optional:"" name:"output" help:"Filename to write binary PS index LCP Policy into" type:"path"`
 }
+type poDeleteCmd struct{}
+type poDefineCmd struct{}
+type poUpdateCmd struct {
+	Config string `arg:"" required:"" name:"config" default:"lcp.config" help:"Filename of LCP config file in JSON format" type:"path"`
+	Out    string `flag:"" optional:"" name:"output" help:"Filename to write binary PO index LCP Policy into" type:"path"`
+}
 type showCmd struct{}
 
 var cli struct {
 	Debug                    bool `help:"Enable debug mode"`
 	ManifestStrictOrderCheck bool `help:"Enable checking of manifest elements order"`
 
+	TpmDev string `short:"t" aliases:"tpmdev" help:"Select TPM-Path. e.g.:--tpmdev=/dev/tpmX, with X as number of the TPM module. Use 'sim:' for the in-process TPM 2.0 simulator or 'sim:<host>:<port>' for the Microsoft TPM 2.0 simulator"`
+
 	Version      versionCmd   `cmd:"" help:"Prints the version of the program"`
 	AuxDelete    auxDeleteCmd `cmd:"" help:"Delete AUX index if exists in TPM NVRAM"`
 	AuxDefine    auxDefineCmd `cmd:"" help:"Define AUX index if not exists in TPM NVRAM"`
 	PsDelete     psDeleteCmd  `cmd:"" help:"Delete PS index if exists in TPM NVRAM"`
 	PsDefine     psDefineCmd  `cmd:"" help:"Define PS index if not exists in TPM NVRAM"`
 	PsUpdate     psUpdateCmd  `cmd:"" help:"Update PS index content in TPM NVRAM"`
+	PoDelete     poDeleteCmd  `cmd:"" help:"Delete PO index if exists in TPM NVRAM"`
+	PoDefine     poDefineCmd  `cmd:"" help:"Define PO index if not exists in TPM NVRAM"`
+	PoUpdate     poUpdateCmd  `cmd:"" help:"Update PO index content in TPM NVRAM"`
 	PlatformProv platProvCmd  `cmd:"" help:"Provision PS & AUX index with LCP config"`
 	Show         showCmd      `cmd:"" help:"Show current provisioned PS & AUX index in NVRAM on stdout"`
 }
@@ -58,7 +71,7 @@ func (v *versionCmd) Run(ctx *context) error {
 
 func (a *auxDeleteCmd) Run(ctx *context) error {
 	// Set Aux Delete bit in LCP Policy and writes it to PS index in TPM NVRAM
-	tpm, err := hwapi.NewTPM()
+	tpm, err := tpmdevice.OpenTPM(ctx.tpmdev)
 	if err != nil {
 		return err
 	}
@@ -96,7 +109,7 @@ func (a *auxDeleteCmd) Run(ctx *context) error {
 
 func (a *auxDefineCmd) Run(ctx *context) error {
 	// Define AUX index in TPM NVRAM
-	tpm, err := hwapi.NewTPM()
+	tpm, err := tpmdevice.OpenTPM(ctx.tpmdev)
 	if err != nil {
 		return err
 	}
@@ -122,7 +135,7 @@ func (a *auxDefineCmd) Run(ctx *context) error {
 
 func (p *psDeleteCmd) Run(ctx *context) error {
 	// Delete PS index in TPM NVRAM
-	tpm, err := hwapi.NewTPM()
+	tpm, err := tpmdevice.OpenTPM(ctx.tpmdev)
 	if err != nil {
 		return err
 	}
@@ -145,7 +158,7 @@ func (p *psDeleteCmd) Run(ctx *context) error {
 
 func (p *psDefineCmd) Run(ctx *context) error {
 	// Define PS index in TPM NVRAM
-	tpm, err := hwapi.NewTPM()
+	tpm, err := tpmdevice.OpenTPM(ctx.tpmdev)
 	if err != nil {
 		return err
 	}
@@ -175,7 +188,7 @@ func (p *psDefineCmd) Run(ctx *context) error {
 
 func (p *psUpdateCmd) Run(ctx *context) error {
 	// Writes new LCP Policy to PS index in TPM NVRAM
-	tpm, err := hwapi.NewTPM()
+	tpm, err := tpmdevice.OpenTPM(ctx.tpmdev)
 	if err != nil {
 		return err
 	}
@@ -207,7 +220,7 @@ func (p *psUpdateCmd) Run(ctx *context) error {
 
 func (p *platProvCmd) Run(ctx *context) error {
 	// Provision PS & AUX index in TPM NVRAM with LCP Policy
-	tpm, err := hwapi.NewTPM()
+	tpm, err := tpmdevice.OpenTPM(ctx.tpmdev)
 	if err != nil {
 		return err
 	}
@@ -240,9 +253,83 @@ func (p *platProvCmd) Run(ctx *context) error {
 	return nil
 }
 
+func (p *poDeleteCmd) Run(ctx *context) error {
+	// Delete PO index in TPM NVRAM
+	tpm, err := tpmdevice.OpenTPM(ctx.tpmdev)
+	if err != nil {
+		return err
+	}
+	switch tpm.Version {
+	case hwapi.TPMVersion12:
+		return fmt.Errorf("TPM 1.2 not supported yet")
+	case hwapi.TPMVersion20:
+		if err = txt.DeletePOIndexTPM20(tpm.RWC); err != nil {
+			return fmt.Errorf("couldn't delete PO index: %v", err)
+		}
+	default:
+		return fmt.Errorf("TPM device not recognized")
+	}
+	return nil
+}
+
+func (p *poDefineCmd) Run(ctx *context) error {
+	// Define PO index in TPM NVRAM
+	tpm, err := tpmdevice.OpenTPM(ctx.tpmdev)
+	if err != nil {
+		return err
+	}
+	switch tpm.Version {
+	case hwapi.TPMVersion12:
+		return fmt.Errorf("TPM 1.2 not supported yet")
+	case hwapi.TPMVersion20:
+		passHash, err := readPassphraseHashTPM20()
+		if err != nil {
+			return fmt.Errorf("couldn't read password from stdin: %v", err)
+		}
+		if err = txt.DefinePOIndexTPM20(tpm.RWC, passHash); err != nil {
+			return fmt.Errorf("couldn't define PO index: %v", err)
+		}
+	default:
+		return fmt.Errorf("TPM device not recognized")
+	}
+	return nil
+}
+
+func (p *poUpdateCmd) Run(ctx *context) error {
+	// Writes new LCP Policy to PO index in TPM NVRAM
+	tpm, err := tpmdevice.OpenTPM(ctx.tpmdev)
+	if err != nil {
+		return err
+	}
+	switch tpm.Version {
+	case hwapi.TPMVersion12:
+		return fmt.Errorf("TPM 1.2 not supported yet")
+	case hwapi.TPMVersion20:
+		lcp, err := loadConfig(p.Config)
+		if err != nil {
+			return fmt.Errorf("couldn't parse LCP config file: %v", err)
+		}
+		passHash, err := readPassphraseHashTPM20()
+		if err != nil {
+			return fmt.Errorf("couldn't read password from stdin: %v", err)
+		}
+		if err = txt.WritePOIndexTPM20(tpm.RWC, lcp, passHash); err != nil {
+			return fmt.Errorf("couldn't update PO index: %v", err)
+		}
+		if len(p.Out) > 0 {
+			if err = writePSPolicy2file(lcp, p.Out); err != nil {
+				return fmt.Errorf("couldn't write PO Policy2 into file: %v", err)
+			}
+		}
+	default:
+		return fmt.Errorf("TPM device not recognized")
+	}
+	return nil
+}
+
 func (s *showCmd) Run(ctx *context) error {
 	// Show PS & AUX index content from TPM NVRAM
-	tpm, err := hwapi.NewTPM()
+	tpm, err := tpmdevice.OpenTPM(ctx.tpmdev)
 	if err != nil {
 		return err
 	}
@@ -29,6 +29,7 @@ func main() {
 
 	// Run commands
 	err := ctx.Run(&context{
-		debug: cli.Debug})
+		debug:  cli.Debug,
+		tpmdev: cli.TpmDev})
 	ctx.FatalIfErrorf(err)
 }
@@ -6,8 +6,10 @@ import (
 	"os"
 	"sort"
 
+	hwInternal "github.com/9elements/converged-security-suite/v2/pkg/hwapi"
 	"github.com/9elements/converged-security-suite/v2/pkg/test"
 	"github.com/9elements/converged-security-suite/v2/pkg/tools"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpm"
 	"github.com/google/go-tpm/legacy/tpm2"
 
 	"github.com/9elements/go-linux-lowlevel-hw/pkg/hwapi"
@@ -28,29 +30,68 @@ type markdownCmd struct{}
 
 type versionCmd struct{}
 
+type captureCmd struct {
+	Output string `optional:"" short:"o" default:"snapshot.json" help:"Path/Filename to write the hardware snapshot to."`
+}
+
+type dumpTXTHeapCmd struct {
+	JSON     bool   `optional:"" help:"Print the TXT heap as JSON."`
+	Snapshot string `optional:"" help:"Path/Filename to a hardware snapshot recorded by the capture command. The TXT heap is read from the snapshot instead of the local hardware."`
+	Input    string `optional:"" help:"Path/Filename to a raw dump of the TXT heap."`
+}
+
 type execTestsCmd struct {
-	Set         string `required:"" default:"all" help:"Select subset of tests. Options: all, uefi, txtready, tboot, cbnt, legacy"`
-	Interactive bool   `optional:"" short:"i" help:"Interactive mode. Errors will stop the testing."`
-	Config      string `optional:"" short:"c" help:"Path/Filename to config file."`
-	Log         string `optional:"" help:"Give a path/filename for test result output inJSON format. e.g.: /path/to/filename.json"`
+	Set          string `required:"" default:"all" help:"Select subset of tests. Options: all, uefi, txtready, tboot, cbnt, legacy"`
+	Interactive  bool   `optional:"" short:"i" help:"Interactive mode. Errors will stop the testing."`
+	Config       string `optional:"" short:"c" help:"Path/Filename to config file."`
+	Log          string `optional:"" help:"Give a path/filename for the test report. e.g.: /path/to/filename.json"`
+	ReportFormat string `optional:"" help:"Write a test report in the given format instead of the test_log.json result list. Options: json, junit, sarif"`
+	Firmware     string `optional:"" short:"f" help:"Path/Filename to firmware to test with. Required by the cbnt set."`
+	Snapshot     string `optional:"" help:"Path/Filename to a hardware snapshot recorded by the capture command. The tests are executed against the snapshot instead of the local hardware."`
 }
 
 var cli struct {
 	ManifestStrictOrderCheck bool `help:"Enable checking of manifest elements order"`
 
-	TpmDev string `short:"t" help:"Select TPM-Path. e.g.:--tpmdev=/dev/tpmX, with X as number of the TPM module"`
+	TpmDev string `short:"t" aliases:"tpmdev" help:"Select TPM-Path. e.g.:--tpmdev=/dev/tpmX, with X as number of the TPM module. Use 'sim:' for the in-process TPM 2.0 simulator or 'sim:<host>:<port>' for the Microsoft TPM 2.0 simulator"`
 
-	ExecTests execTestsCmd `cmd:"" help:"Executes tests given be TestNo or TestSet"`
-	List      listCmd      `cmd:"" help:"Lists all tests"`
-	Markdown  markdownCmd  `cmd:"" help:"Output test implementation state as Markdown"`
-	Version   versionCmd   `cmd:"" help:"Prints the version of the program"`
+	ExecTests   execTestsCmd   `cmd:"" help:"Executes tests given be TestNo or TestSet"`
+	Capture     captureCmd     `cmd:"" help:"Records a snapshot of the hardware to execute the tests later on another machine"`
+	DumpTXTHeap dumpTXTHeapCmd `cmd:"" name:"dump-txt-heap" help:"Decodes the TXT heap (BiosData, OsMleData, OsSinitData and SinitMleData)"`
+	List        listCmd        `cmd:"" help:"Lists all tests"`
+	Markdown    markdownCmd    `cmd:"" help:"Output test implementation state as Markdown"`
+	Version     versionCmd     `cmd:"" help:"Prints the version of the program"`
 }
 
 func (e *execTestsCmd) Run(ctx *context) error {
 	ret := false
+	var err error
+	if e.ReportFormat != "" {
+		reportFormat, err = test.ParseReportFormat(e.ReportFormat)
+		if err != nil {
+			return err
+		}
+		logfile = "test_log." + reportFormat.Extension()
+	}
+	if e.Log != "" {
+		logfile = e.Log
+	}
+
+	var hwAPI hwapi.LowLevelHardwareInterfaces
+	if e.Snapshot != "" {
+		if cli.TpmDev != "" {
+			return fmt.Errorf("--tpmdev can't be used together with --snapshot")
+		}
+		hwAPI, err = loadSnapshot(e.Snapshot)
+		if err != nil {
+			return err
+		}
+	} else {
+		hwAPI = localHardware()
+	}
+
 	preset := new(test.PreSet)
 	if e.Config != "" {
-		var err error
 		preset, err = test.ParsePreSet(e.Config)
 		if err != nil {
 			os.Exit(1)
@@ -65,18 +106,26 @@ func (e *execTestsCmd) Run(ctx *context) error {
 	switch e.Set {
 	case "all":
 		log.Info("For more information about the documents and chapters, run: txt-suite -m")
-		ret = run("All", getTests(), preset, e.Interactive)
+		ret = run("All", getTests(), hwAPI, preset, e.Interactive)
 	case "uefi":
-		ret = run("UEFI", test.TestsTXTUEFI, preset, e.Interactive)
+		ret = run("UEFI", test.TestsTXTUEFI, hwAPI, preset, e.Interactive)
 	case "txtready":
 		log.Info("For more information about the documents and chapters, run: txt-suite -m")
-		ret = run("TXT Ready", test.TestsTXTReady, preset, e.Interactive)
+		ret = run("TXT Ready", test.TestsTXTReady, hwAPI, preset, e.Interactive)
 	case "tboot":
-		ret = run("Tboot", test.TestsTXTTBoot, preset, e.Interactive)
+		ret = run("Tboot", test.TestsTXTTBoot, hwAPI, preset, e.Interactive)
 	case "cbnt":
-		return fmt.Errorf("CBnT support not implemented yet")
+		if e.Firmware == "" {
+			return fmt.Errorf("the cbnt set requires a firmware image, see --firmware")
+		}
+		data, err := os.ReadFile(e.Firmware)
+		if err != nil {
+			return fmt.Errorf("can't read firmware file: %w", err)
+		}
+		preset.Firmware = data
+		ret = run("CBnT", test.TestsCBnT[:], hwAPI, preset, e.Interactive)
 	case "legacy":
-		ret = run("Legacy TXT", test.TestsTXTLegacy, preset, e.Interactive)
+		ret = run("Legacy TXT", test.TestsTXTLegacy, hwAPI, preset, e.Interactive)
 	default:
 		return fmt.Errorf("no valid test set given")
 	}
@@ -86,6 +135,98 @@ func (e *execTestsCmd) Run(ctx *context) error {
 	return nil
 }
 
+// tpmDevice replaces the TPM of the local hardware with the TPM selected
+// by --tpmdev.
+type tpmDevice struct {
+	hwapi.LowLevelHardwareInterfaces
+	device string
+}
+
+func (t tpmDevice) NewTPM() (*hwapi.TPM, error) {
+	return tpm.OpenTPM(t.device)
+}
+
+func localHardware() hwapi.LowLevelHardwareInterfaces {
+	if cli.TpmDev == "" {
+		return hwapi.GetAPI()
+	}
+	return tpmDevice{LowLevelHardwareInterfaces: hwapi.GetAPI(), device: cli.TpmDev}
+}
+
+func loadSnapshot(path string) (hwapi.LowLevelHardwareInterfaces, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open the snapshot: %w", err)
+	}
+	defer f.Close()
+	snapshot, err := hwInternal.ReadSnapshot(f)
+	if err != nil {
+		return nil, err
+	}
+	return hwInternal.NewSnapshotReplay(snapshot), nil
+}
+
+func (c *captureCmd) Run(ctx *context) error {
+	snapshot, err := hwInternal.CaptureSnapshot(localHardware())
+	if err != nil {
+		// the snapshot is still usable, only the failed parts are missing
+		log.Warnf("some data was not recorded: %v", err)
+	}
+	f, err := os.Create(c.Output)
+	if err != nil {
+		return fmt.Errorf("unable to create '%s': %w", c.Output, err)
+	}
+	if err := snapshot.Write(f); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("unable to write the snapshot: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("unable to write the snapshot: %w", err)
+	}
+	log.Infof("The snapshot is written to %s", c.Output)
+	return nil
+}
+
+func (d *dumpTXTHeapCmd) Run(ctx *context) error {
+	if d.Snapshot != "" && d.Input != "" {
+		return fmt.Errorf("--snapshot can't be used together with --input")
+	}
+	var (
+		heap []byte
+		err  error
+	)
+	switch {
+	case d.Input != "":
+		heap, err = os.ReadFile(d.Input)
+	case d.Snapshot != "":
+		var hwAPI hwapi.LowLevelHardwareInterfaces
+		hwAPI, err = loadSnapshot(d.Snapshot)
+		if err == nil {
+			heap, err = tools.FetchTXTHeap(hwAPI)
+		}
+	default:
+		heap, err = tools.FetchTXTHeap(hwapi.GetAPI())
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read the TXT heap: %w", err)
+	}
+
+	txtHeap, err := tools.ParseTXTHeap(heap)
+	if err != nil {
+		return fmt.Errorf("unable to parse the TXT heap: %w", err)
+	}
+	if d.JSON {
+		b, err := txtHeap.JSON()
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+	fmt.Print(txtHeap.PrettyString())
+	return nil
+}
+
 func (l *listCmd) Run(ctx *context) error {
 	tests := getTests()
 	for i := range tests {
@@ -142,10 +283,29 @@ func getTests() []*test.Test {
 	return tests
 }
 
-func run(testGroup string, tests []*test.Test, preset *test.PreSet, interactive bool) bool {
-	result := false
+func writeReport(testGroup string, tests []*test.Test) error {
+	if reportFormat == "" {
+		var t []temptest
+		for index := range tests {
+			if tests[index].Status != test.NotImplemented {
+				ttemp := temptest{index, tests[index].Name, tests[index].Result.String(), tests[index].ErrorText, tests[index].Status.String()}
+				t = append(t, ttemp)
+			}
+		}
+		data, _ := json.MarshalIndent(t, "", "")
+		return os.WriteFile(logfile, data, 0o664)
+	}
 
-	hwAPI := hwapi.GetAPI()
+	f, err := os.Create(logfile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return test.NewReport(programName, gittag, testGroup, tests).Write(f, reportFormat)
+}
+
+func run(testGroup string, tests []*test.Test, hwAPI hwapi.LowLevelHardwareInterfaces, preset *test.PreSet, interactive bool) bool {
+	result := false
 
 	log.Infof("%s tests", a.Bold(a.Gray(20-1, testGroup).BgGray(4-1)))
 	log.Info("--------------------------------------------------")
@@ -170,16 +330,7 @@ func run(testGroup string, tests []*test.Test, preset *test.PreSet, interactive
 	}
 
 	if !interactive {
-		var t []temptest
-		for index := range tests {
-			if tests[index].Status != test.NotImplemented {
-				ttemp := temptest{index, tests[index].Name, tests[index].Result.String(), tests[index].ErrorText, tests[index].Status.String()}
-				t = append(t, ttemp)
-			}
-		}
-		data, _ := json.MarshalIndent(t, "", "")
-		err := os.WriteFile(logfile, data, 0o664)
-		if err != nil {
+		if err := writeReport(testGroup, tests); err != nil {
 			log.Errorf("failed to write to file: %v\n", err)
 			return false
 		}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"github.com/9elements/converged-security-suite/v2/pkg/log"
+	"github.com/9elements/converged-security-suite/v2/pkg/test"
 	"github.com/alecthomas/kong"
 	"github.com/linuxboot/fiano/pkg/intel/metadata/cbnt"
 	fianoLog "github.com/linuxboot/fiano/pkg/log"
@@ -13,11 +14,12 @@ const (
 )
 
 var (
-	testnos   []int
-	testerg   bool
-	logfile   = "test_log.json"
-	gitcommit string
-	gittag    string
+	testnos      []int
+	testerg      bool
+	logfile      = "test_log.json"
+	reportFormat test.ReportFormat
+	gitcommit    string
+	gittag       string
 )
 
 type temptest struct {
@@ -0,0 +1,197 @@
+package analyzesecureboot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/go-tpm/legacy/tpm2"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/tpm"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmeventlog"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmeventlog/secureboot"
+)
+
+func usageAndExit() {
+	flag.Usage()
+	os.Exit(2)
+}
+
+// Command is the implementation of `commands.Command`.
+type Command struct {
+	eventLog    *string
+	inputFormat tpmeventlog.Format
+	hashAlgo    *string
+	pcr7        *string
+	pcrRead     *string
+	quote       *string
+	readTPM     *bool
+	isJSON      *bool
+}
+
+// Usage prints the syntax of arguments for this command
+func (cmd Command) Usage() string {
+	return ""
+}
+
+// Description explains what this verb commands to do
+func (cmd Command) Description() string {
+	return "analyze the Secure Boot policy measured into PCR7"
+}
+
+// SetupFlagSet is called to allow the command implementation
+// to setup which option flags it has.
+func (cmd *Command) SetupFlagSet(flag *flag.FlagSet) {
+	cmd.eventLog = flag.String("event-log", "/sys/kernel/security/tpm0/binary_bios_measurements", "path to the EventLog")
+	flag.Var(&cmd.inputFormat, "input-format", "select input format of the EventLog")
+	cmd.hashAlgo = flag.String("hash-algo", tpm2.AlgSHA256.String(), "the PCR bank to analyze")
+	cmd.pcr7 = flag.String("pcr7", "", "[optional] the expected PCR7 value in hex")
+	cmd.pcrRead = flag.String("pcrread", "", "[optional] path to the output of 'pcr0tool pcrread 7' or 'tpm2_pcrread' to compare the replayed PCR7 value with")
+	cmd.quote = flag.String("quote", "", "[optional] path to a TPMS_ATTEST quote of PCR7 (for example, 'tpm2_quote -m') to compare the replayed PCR7 value with; the signature is not verified")
+	cmd.readTPM = flag.Bool("read-tpm", false, "compare the replayed PCR7 value with the value read from the local TPM")
+	cmd.isJSON = flag.Bool("json", false, "print the report as JSON")
+}
+
+// Execute is the main function here. It is responsible to
+// start the execution of the command.
+//
+// `args` are the arguments left unused by verb itself and options.
+func (cmd Command) Execute(ctx context.Context, args []string) {
+	if len(args) > 0 {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "error: too many parameters\n")
+		usageAndExit()
+	}
+
+	hashAlgo := tpm2.AlgUnknown
+	for _, alg := range []tpm2.Algorithm{tpm2.AlgSHA1, tpm2.AlgSHA256, tpm2.AlgSHA384, tpm2.AlgSHA512} {
+		if strings.EqualFold(*cmd.hashAlgo, alg.String()) {
+			hashAlgo = alg
+		}
+	}
+	if hashAlgo == tpm2.AlgUnknown {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "error: algo '%s' is unknown\n", *cmd.hashAlgo)
+		usageAndExit()
+	}
+
+	eventLogFile, err := os.Open(*cmd.eventLog)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to open EventLog '%s': %v\n", *cmd.eventLog, err)
+		os.Exit(1)
+	}
+	eventLog, err := tpmeventlog.ParseWithFormat(eventLogFile, cmd.inputFormat)
+	_ = eventLogFile.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to parse EventLog '%s': %v\n", *cmd.eventLog, err)
+		os.Exit(1)
+	}
+
+	report, err := secureboot.Analyze(eventLog, hashAlgo)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to analyze EventLog '%s': %v\n", *cmd.eventLog, err)
+		os.Exit(1)
+	}
+
+	verifyErr := cmd.verify(report)
+
+	if *cmd.isJSON {
+		b, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("%s\n", b)
+	} else {
+		printReport(report)
+	}
+
+	if verifyErr != nil {
+		fmt.Fprintf(os.Stderr, "verification failed: %v\n", verifyErr)
+		os.Exit(1)
+	}
+}
+
+func (cmd Command) verify(report *secureboot.Report) error {
+	var errs []error
+	if *cmd.pcr7 != "" {
+		var expected []byte
+		if _, err := fmt.Sscanf(*cmd.pcr7, "%X", &expected); err != nil {
+			return fmt.Errorf("unable to parse the PCR7 value '%s': %w", *cmd.pcr7, err)
+		}
+		errs = append(errs, report.VerifyPCR(expected))
+	}
+	if *cmd.pcrRead != "" {
+		output, err := os.ReadFile(*cmd.pcrRead)
+		if err != nil {
+			return fmt.Errorf("unable to read '%s': %w", *cmd.pcrRead, err)
+		}
+		expected, err := secureboot.ParsePCRReadOutput(output, report.HashAlgo)
+		if err != nil {
+			return fmt.Errorf("unable to parse '%s': %w", *cmd.pcrRead, err)
+		}
+		errs = append(errs, report.VerifyPCR(expected))
+	}
+	if *cmd.quote != "" {
+		attest, err := os.ReadFile(*cmd.quote)
+		if err != nil {
+			return fmt.Errorf("unable to read '%s': %w", *cmd.quote, err)
+		}
+		errs = append(errs, report.VerifyQuote(attest))
+	}
+	if *cmd.readTPM {
+		expected, err := tpm.ReadPCRFromTPM(secureboot.PCRIndex, report.HashAlgo)
+		if err != nil {
+			return fmt.Errorf("unable to read PCR%d from the TPM: %w", secureboot.PCRIndex, err)
+		}
+		errs = append(errs, report.VerifyPCR(expected))
+	}
+	return errors.Join(errs...)
+}
+
+func printReport(report *secureboot.Report) {
+	fmt.Printf("PCR%d (%s): %X\n", secureboot.PCRIndex, report.HashAlgo, report.PCR7)
+
+	secureBoot := "not measured"
+	if report.SecureBoot != nil {
+		secureBoot = "disabled"
+		if *report.SecureBoot {
+			secureBoot = "enabled"
+		}
+	}
+	fmt.Printf("SecureBoot: %s\n", secureBoot)
+
+	for _, db := range []struct {
+		Name string
+		DB   *secureboot.SignatureDatabase
+	}{
+		{Name: "PK", DB: report.PK},
+		{Name: "KEK", DB: report.KEK},
+		{Name: "db", DB: report.DB},
+		{Name: "dbx", DB: report.DBX},
+	} {
+		if db.DB == nil {
+			fmt.Printf("%s: not measured\n", db.Name)
+			continue
+		}
+		fmt.Printf("%s: %d certificates, %d hashes\n", db.Name, len(db.DB.Certificates), len(db.DB.Hashes))
+		for _, cert := range db.DB.Certificates {
+			fmt.Printf("\t* %s\n", cert)
+		}
+	}
+
+	fmt.Printf("Authorities used to verify loaded images:\n")
+	for _, authority := range report.Authorities {
+		if authority.Certificate != nil {
+			fmt.Printf("\t* %s: %s\n", authority.VariableName, authority.Certificate)
+		} else {
+			fmt.Printf("\t* %s: %X\n", authority.VariableName, authority.Data)
+		}
+	}
+
+	fmt.Printf("Issues:\n")
+	for _, issue := range report.Issues {
+		fmt.Printf("\t* %s\n", issue)
+	}
+}
@@ -6,10 +6,12 @@ import (
 	"crypto/sha1"
 	"encoding/binary"
 	"encoding/hex"
+	"errors"
 	"flag"
 	"fmt"
 	"hash"
 	"os"
+	"path/filepath"
 
 	"github.com/9elements/converged-security-suite/v2/cmd/exp/pcr0tool/commands"
 	"github.com/9elements/converged-security-suite/v2/cmd/exp/pcr0tool/commands/dumpregisters/helpers"
@@ -36,14 +38,19 @@ type Command struct {
 	flow             *string
 	registers        helpers.FlagRegisters
 	expectedPCR0Flag *string
+
+	bruteforceShardFlag         *string
+	bruteforceCheckpointDirFlag *string
 }
 
 // SetupFlagSet is called to allow the command implementation
 // to setup which option flags it has.
 func (cmd *Command) SetupFlagSet(flag *flag.FlagSet) {
 	cmd.flow = flag.String("flow", flows.Root.Name, "values: "+commands.FlowCommandLineValues())
-	flag.Var(&cmd.registers, "registers", "[optional] file that contains registers as a json array (use value '/dev' to use registers of the local machine)")
+	flag.Var(&cmd.registers, "registers", "[optional] file that contains registers (YAML, JSON or a raw TXT public space dump; use value '/dev' to use registers of the local machine)")
 	cmd.expectedPCR0Flag = flag.String("expected-pcr0", "", "")
+	cmd.bruteforceShardFlag = flag.String("bruteforce-shard", "0/1", "process only the given shard of the brute-force search, in format <index>/<count>; requires -bruteforce-checkpoint-dir if count is more than 1")
+	cmd.bruteforceCheckpointDirFlag = flag.String("bruteforce-checkpoint-dir", "", "[optional] a directory to save the progress of the brute-force search to (it is resumed from there after a restart), shared by all shards")
 }
 
 // Usage prints the syntax of arguments for this command
@@ -79,6 +86,28 @@ func (cmd Command) Execute(ctx context.Context, args []string) {
 		panic(fmt.Sprintf("value of -expected-pcr0 should have length %d bytes", sha1.Size))
 	}
 
+	shard, err := bruteforcer.ParseShard(*cmd.bruteforceShardFlag)
+	if err != nil {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "%v\n", err)
+		usageAndExit()
+	}
+	checkpointDir := *cmd.bruteforceCheckpointDirFlag
+	if shard.Count > 1 && checkpointDir == "" {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "error: -bruteforce-checkpoint-dir is required to merge the results of a sharded search\n")
+		usageAndExit()
+	}
+	if checkpointDir != "" {
+		if err := os.MkdirAll(checkpointDir, 0755); err != nil {
+			panic(err)
+		}
+	}
+	checkpointPath := func(shardIndex uint64) string {
+		if checkpointDir == "" {
+			return ""
+		}
+		return filepath.Join(checkpointDir, fmt.Sprintf("acm-policy-status-shard%d-of-%d.json", shardIndex, shard.Count))
+	}
+
 	flow, ok := flows.GetFlowByName(*cmd.flow)
 	if !ok {
 		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "unknown boot flow: '%s'\n", *cmd.flow)
@@ -167,7 +196,8 @@ func (cmd Command) Execute(ctx context.Context, args []string) {
 		tpmCommands tpm.Commands
 	}
 	firstMeasurementTail := firstMeasurementData[8:]
-	combination, err := bruteforcer.BruteForce(
+	checkpoint, err := bruteforcer.BruteForceShard(
+		ctx,
 		firstMeasurementData[:8], // initialData
 		8,                        // itemSize
 		0,                        // minDistance
@@ -199,15 +229,41 @@ func (cmd Command) Execute(ctx context.Context, args []string) {
 			return bytes.Equal(ctx.tpm.PCRValues[0][tpm2.AlgSHA1], expectedHash)
 		},
 		bruteforcer.ApplyBitFlipsBytes, // applyBitFlipsFunc
-		0,
+		shard,
+		checkpointPath(shard.Index),
+		bruteforcer.DefaultShardSettings(),
 	)
 	if err != nil {
 		panic(err)
 	}
-	if combination == nil {
+
+	found := checkpoint.Found
+	if shard.Count > 1 {
+		var checkpoints []*bruteforcer.Checkpoint[bruteforcer.UniqueUnorderedCombination]
+		for shardIndex := uint64(0); shardIndex < shard.Count; shardIndex++ {
+			cp, err := bruteforcer.LoadCheckpoint[bruteforcer.UniqueUnorderedCombination](checkpointPath(shardIndex))
+			switch {
+			case err == nil:
+				checkpoints = append(checkpoints, cp)
+			case os.IsNotExist(err):
+			default:
+				panic(err)
+			}
+		}
+		found, err = bruteforcer.MergeCheckpoints(checkpoints)
+		if errors.As(err, &bruteforcer.ErrShardIncomplete{}) {
+			fmt.Printf("shard %s is processed; the result depends on other shards: process them (with the same -bruteforce-checkpoint-dir) and rerun the command\n", shard)
+			return
+		}
+		if err != nil {
+			panic(err)
+		}
+	}
+	if found == nil {
 		fmt.Printf("unable to brute force\n")
 		return
 	}
+	combination := found.Result
 
 	// printing the result
 	result := make([]byte, 8)
@@ -0,0 +1,143 @@
+package buildgolden
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/9elements/converged-security-suite/v2/cmd/exp/pcr0tool/commands"
+	"github.com/9elements/converged-security-suite/v2/cmd/exp/pcr0tool/commands/dumpregisters/helpers"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/flows"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/golden"
+	"github.com/9elements/converged-security-suite/v2/pkg/registers"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmdetection"
+)
+
+func usageAndExit() {
+	flag.Usage()
+	os.Exit(2)
+}
+
+const (
+	formatJSON = "json"
+	formatCSV  = "csv"
+)
+
+// Command is the implementation of `commands.Command`.
+type Command struct {
+	flows             *string
+	registers         helpers.FlagRegisters
+	tpmDeviceFlag     *string
+	acmPolicyStatuses *string
+	generateACMPolicy *bool
+	format            *string
+	output            *string
+}
+
+// Usage prints the syntax of arguments for this command
+func (cmd Command) Usage() string {
+	return "<directory with firmware images>"
+}
+
+// Description explains what this verb commands to do
+func (cmd Command) Description() string {
+	return "build a database of expected PCR0 values of firmware images"
+}
+
+// SetupFlagSet is called to allow the command implementation
+// to setup which option flags it has.
+func (cmd *Command) SetupFlagSet(flag *flag.FlagSet) {
+	cmd.flows = flag.String("flows", "", "[optional] comma-separated list of flows to try (all flows of the vendor of an image by default), values: "+commands.FlowCommandLineValues())
+	flag.Var(&cmd.registers, "registers", "[optional] file that contains registers of the target machines (YAML, named registers JSON, reads JSON or a raw TXT public space dump)")
+	cmd.tpmDeviceFlag = flag.String("tpm-device", tpmdetection.TypeTPM20.String(), "values: "+commands.TPMTypeCommandLineValues())
+	cmd.acmPolicyStatuses = flag.String("acm-policy-status", "", "[optional] comma-separated list of plausible ACM_POLICY_STATUS values in hex to try in addition to the one from -registers")
+	cmd.generateACMPolicy = flag.Bool("generate-acm-policy-status", true, "try the plausible ACM_POLICY_STATUS values generated for each image (Boot Guard profiles, TXT support, TPM types and startup localities)")
+	cmd.format = flag.String("format", formatJSON, "output format, values: "+formatJSON+", "+formatCSV)
+	cmd.output = flag.String("output", "", "[optional] path to write the database to (stdout by default)")
+}
+
+// Execute is the main function here. It is responsible to
+// start the execution of the command.
+//
+// `args` are the arguments left unused by verb itself and options.
+func (cmd Command) Execute(ctx context.Context, args []string) {
+	if len(args) < 1 {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "error: no path to the directory with firmware images was specified\n")
+		usageAndExit()
+	}
+	if len(args) > 1 {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "error: too many parameters\n")
+		usageAndExit()
+	}
+	if *cmd.format != formatJSON && *cmd.format != formatCSV {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "error: unknown format '%s'\n", *cmd.format)
+		usageAndExit()
+	}
+
+	settings := golden.DefaultSettings()
+	settings.Registers = registers.Registers(cmd.registers)
+	settings.GenerateACMPolicyStatuses = *cmd.generateACMPolicy
+
+	var err error
+	settings.TPMType, err = tpmdetection.FromString(*cmd.tpmDeviceFlag)
+	if err != nil {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "%v\n", err)
+		usageAndExit()
+	}
+
+	if *cmd.flows != "" {
+		for _, flowName := range strings.Split(*cmd.flows, ",") {
+			flow, ok := flows.GetFlowByName(strings.TrimSpace(flowName))
+			if !ok {
+				_, _ = fmt.Fprintf(flag.CommandLine.Output(), "unknown boot flow: '%s'\n", flowName)
+				usageAndExit()
+			}
+			settings.Flows = append(settings.Flows, flow)
+		}
+	}
+
+	if *cmd.acmPolicyStatuses != "" {
+		for _, valueString := range strings.Split(*cmd.acmPolicyStatuses, ",") {
+			valueString = strings.TrimPrefix(strings.TrimSpace(valueString), "0x")
+			value, err := strconv.ParseUint(valueString, 16, 64)
+			if err != nil {
+				_, _ = fmt.Fprintf(flag.CommandLine.Output(), "unable to parse ACM_POLICY_STATUS value '%s': %v\n", valueString, err)
+				usageAndExit()
+			}
+			settings.ACMPolicyStatuses = append(settings.ACMPolicyStatuses, registers.ACMPolicyStatus(value))
+		}
+	}
+
+	db, err := golden.Build(ctx, args[0], settings)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to build the database: %v\n", err)
+		os.Exit(1)
+	}
+
+	var w io.WriteCloser = os.Stdout
+	if *cmd.output != "" {
+		w, err = os.Create(*cmd.output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "unable to create '%s': %v\n", *cmd.output, err)
+			os.Exit(1)
+		}
+	}
+
+	switch *cmd.format {
+	case formatJSON:
+		err = db.WriteJSON(w)
+	case formatCSV:
+		err = db.WriteCSV(w)
+	}
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to write the database: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -7,19 +7,18 @@ import (
 	"flag"
 	"fmt"
 	"log"
-	"math"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
 	"strings"
 
 	pkgbytes "github.com/linuxboot/fiano/pkg/bytes"
-	fianoUEFI "github.com/linuxboot/fiano/pkg/uefi"
 
 	"github.com/9elements/converged-security-suite/v2/cmd/exp/pcr0tool/commands"
 	"github.com/9elements/converged-security-suite/v2/cmd/exp/pcr0tool/commands/diff/format"
 	"github.com/9elements/converged-security-suite/v2/cmd/exp/pcr0tool/commands/dumpregisters/helpers"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/bootengine"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/cache"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/flows"
 	bfformat "github.com/9elements/converged-security-suite/v2/pkg/bootflow/lib/format"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/amdpsp"
@@ -32,6 +31,7 @@ import (
 	"github.com/9elements/converged-security-suite/v2/pkg/diff"
 	"github.com/9elements/converged-security-suite/v2/pkg/ostools"
 	"github.com/9elements/converged-security-suite/v2/pkg/registers"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmdetection"
 )
 
 func assertNoError(err error) {
@@ -73,6 +73,7 @@ type Command struct {
 	outputFormat  *string
 	flow          *string
 	netPprof      *string
+	tpmDevice     *string
 	registers     helpers.FlagRegisters
 }
 
@@ -95,8 +96,9 @@ It makes sense to use this option together with "-force-scan-area bios_region" t
 but ignore the overridden bytes. The value is represented in hex characters separated by comma, for example: "00,ff". Default: ""`)
 	cmd.outputFormat = flag.String("output-format", "analyzed-text", `Values: "analyzed-text", "analyzed-json", "json"`)
 	cmd.flow = flag.String("flow", flows.Root.Name, "values: "+commands.FlowCommandLineValues())
+	cmd.tpmDevice = flag.String("tpm-device", tpmdetection.TypeTPM20.String(), "values: "+commands.TPMTypeCommandLineValues())
 	cmd.netPprof = flag.String("net-pprof", "", `start listening for "net/http/pprof", example value: "127.0.0.1:6060"`)
-	flag.Var(&cmd.registers, "registers", "[optional] file that contains registers as a json array (use value '/dev' to use registers of the local machine)")
+	flag.Var(&cmd.registers, "registers", "[optional] file that contains registers (YAML, JSON or a raw TXT public space dump; use value '/dev' to use registers of the local machine)")
 }
 
 func parseByteSet(s string) ([]byte, error) {
@@ -133,8 +135,14 @@ func (cmd Command) Execute(ctx context.Context, args []string) {
 		usageAndExit()
 	}
 
+	tpmType, err := tpmdetection.FromString(*cmd.tpmDevice)
+	if err != nil {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "%v\n", err)
+		usageAndExit()
+	}
+
 	state := types.NewState()
-	state.IncludeSubSystem(tpm.NewTPM())
+	state.IncludeSubSystem(tpm.NewTPMOfType(tpmType))
 	state.IncludeSubSystem(intelpch.NewPCH())
 	state.IncludeSubSystem(amdpsp.NewPSP())
 
@@ -158,10 +166,12 @@ func (cmd Command) Execute(ctx context.Context, args []string) {
 	firmwareGoodData, err := ostools.FileToBytes(args[0])
 	assertNoError(err)
 	firmwareGood := biosimage.New(firmwareGoodData)
+	firmwareGood.Cache = cache.FromCtx(ctx)
 
 	firmwareBadData, err := ostools.FileToBytes(args[1])
 	assertNoError(err)
 	firmwareBad := biosimage.New(firmwareBadData)
+	firmwareBad.Cache = cache.FromCtx(ctx)
 
 	state.IncludeSystemArtifact(firmwareGood)
 	process := bootengine.NewBootProcess(state)
@@ -177,21 +187,14 @@ func (cmd Command) Execute(ctx context.Context, args []string) {
 
 	switch *cmd.forceScanArea {
 	case `bios_region`:
-		firmwareGoodUEFI, err := firmwareGood.Parse()
+		firmwareGoodLayout, err := firmwareGood.Layout()
 		assertNoError(err)
-		nodes, err := firmwareGoodUEFI.GetByRegionType(fianoUEFI.RegionTypeBIOS)
+		biosRegion, err := firmwareGoodLayout.BIOSRegion()
 		if err != nil {
 			_, _ = fmt.Fprintf(os.Stderr, "Unable to find bios_region, error: %v\n", err)
 			os.Exit(1)
 		}
-		var fileRanges pkgbytes.Ranges
-		for _, r := range nodes {
-			if r.Offset == math.MaxUint64 {
-				_, _ = fmt.Fprintf(os.Stderr, "Unable to detect the offset of the node\n")
-				continue
-			}
-			fileRanges = append(fileRanges, r.Range)
-		}
+		fileRanges := pkgbytes.Ranges{biosRegion}
 		memRanges, err = biosimage.PhysMemMapper{}.Unresolve(firmwareGood, fileRanges...)
 		assertNoError(err)
 	case ``:
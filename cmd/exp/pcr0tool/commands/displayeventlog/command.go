@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/9elements/converged-security-suite/v2/cmd/exp/pcr0tool/commands/displayeventlog/format"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
@@ -18,11 +19,12 @@ func usageAndExit() {
 
 // Command is the implementation of `commands.Command`.
 type Command struct {
-	eventLog *string
-	pcrIndex *int64
-	hashAlgo *int64
-	calcPCR  *bool
-	format   flagFormat
+	eventLog    *string
+	inputFormat tpmeventlog.Format
+	pcrIndex    *int64
+	hashAlgo    *int64
+	calcPCR     *bool
+	format      flagFormat
 }
 
 // Usage prints the syntax of arguments for this command
@@ -38,11 +40,20 @@ func (cmd Command) Description() string {
 // SetupFlagSet is called to allow the command implementation
 // to setup which option flags it has.
 func (cmd *Command) SetupFlagSet(flag *flag.FlagSet) {
-	cmd.eventLog = flag.String("event-log", "/sys/kernel/security/tpm0/binary_bios_measurements", "path to the binary EventLog")
+	cmd.eventLog = flag.String("event-log", "/sys/kernel/security/tpm0/binary_bios_measurements", "path to the EventLog")
+	flag.Var(&cmd.inputFormat, "input-format", "select input format, allowed values: "+inputFormatValues())
 	cmd.pcrIndex = flag.Int64("pcr-index", -1, "filter for specific PCR register")
 	cmd.hashAlgo = flag.Int64("hash-algo", 0, "filter by hash algorithm")
 	cmd.calcPCR = flag.Bool("calc-pcr", false, "should calculate the PCR value")
-	flag.Var(&cmd.format, "format", "select output format, allowed values: plaintext-oneline, plaintext-multiline")
+	flag.Var(&cmd.format, "format", "select output format, allowed values: plaintext-oneline, plaintext-multiline, cel-tlv, cel-json, cel-cbor, json")
+}
+
+func inputFormatValues() string {
+	var values []string
+	for _, format := range tpmeventlog.Formats() {
+		values = append(values, format.String())
+	}
+	return strings.Join(values, ", ")
 }
 
 func ptr[T any](in T) *T {
@@ -65,7 +76,7 @@ func (cmd Command) Execute(ctx context.Context, args []string) {
 		return
 	}
 
-	eventLog, err := tpmeventlog.Parse(eventLogFile)
+	eventLog, err := tpmeventlog.ParseWithFormat(eventLogFile, cmd.inputFormat)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "unable to parse EventLog '%s': %v", *cmd.eventLog, err)
 		return
@@ -84,7 +95,20 @@ func (cmd Command) Execute(ctx context.Context, args []string) {
 	if *cmd.hashAlgo != 0 {
 		filterHashAlgo = format.HashAlgoPtr(tpmeventlog.TPMAlgorithm(*cmd.hashAlgo))
 	}
-	fmt.Print(format.EventLog(eventLog, filterPCRIndex, filterHashAlgo, "", cmd.format == flagFormatPlaintextMultiline))
+	if celFormat, ok := cmd.format.CELFormat(); ok {
+		err := filterEventLog(eventLog, filterPCRIndex, filterHashAlgo).WriteCEL(os.Stdout, celFormat)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "unable to write the EventLog: %v", err)
+			return
+		}
+	} else if cmd.format == flagFormatJSON {
+		if err := format.EventLogJSON(os.Stdout, eventLog, filterPCRIndex, filterHashAlgo); err != nil {
+			fmt.Fprintf(os.Stderr, "unable to write the EventLog: %v", err)
+			return
+		}
+	} else {
+		fmt.Print(format.EventLog(eventLog, filterPCRIndex, filterHashAlgo, "", cmd.format == flagFormatPlaintextMultiline))
+	}
 
 	if *cmd.calcPCR {
 		calculatedValue, err := tpmeventlog.Replay(eventLog, pcr.ID(*cmd.pcrIndex), tpmeventlog.TPMAlgorithm(*cmd.hashAlgo), nil)
@@ -95,3 +119,21 @@ func (cmd Command) Execute(ctx context.Context, args []string) {
 		fmt.Printf("Calc\t%2d\t%10s\t%3d\t%X\t\n", *cmd.pcrIndex, "", *cmd.hashAlgo, calculatedValue)
 	}
 }
+
+func filterEventLog(
+	eventLog *tpmeventlog.TPMEventLog,
+	filterPCRIndex *pcr.ID,
+	filterHashAlgo *tpmeventlog.TPMAlgorithm,
+) *tpmeventlog.TPMEventLog {
+	result := &tpmeventlog.TPMEventLog{}
+	for _, ev := range eventLog.Events {
+		if filterPCRIndex != nil && ev.PCRIndex != *filterPCRIndex {
+			continue
+		}
+		if filterHashAlgo != nil && (ev.Digest == nil || ev.Digest.HashAlgo != *filterHashAlgo) {
+			continue
+		}
+		result.Events = append(result.Events, ev)
+	}
+	return result
+}
@@ -4,6 +4,8 @@ import (
 	"flag"
 	"fmt"
 	"strings"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmeventlog"
 )
 
 var _ flag.Value = (*flagFormat)(nil)
@@ -13,6 +15,10 @@ type flagFormat uint
 const (
 	flagFormatPlaintextOneline = flagFormat(iota)
 	flagFormatPlaintextMultiline
+	flagFormatCELTLV
+	flagFormatCELJSON
+	flagFormatCELCBOR
+	flagFormatJSON
 	endOfFlagFormat
 )
 
@@ -23,10 +29,32 @@ func (f flagFormat) String() string {
 		return "plaintext-oneline"
 	case flagFormatPlaintextMultiline:
 		return "plaintext-multiline"
+	case flagFormatCELTLV:
+		return "cel-tlv"
+	case flagFormatCELJSON:
+		return "cel-json"
+	case flagFormatCELCBOR:
+		return "cel-cbor"
+	case flagFormatJSON:
+		return "json"
 	}
 	return fmt.Sprintf("unknown_format_%d", f)
 }
 
+// CELFormat returns the encoding of the TCG Canonical Event Log
+// if the format is one of CEL formats.
+func (f flagFormat) CELFormat() (tpmeventlog.Format, bool) {
+	switch f {
+	case flagFormatCELTLV:
+		return tpmeventlog.FormatCELTLV, true
+	case flagFormatCELJSON:
+		return tpmeventlog.FormatCELJSON, true
+	case flagFormatCELCBOR:
+		return tpmeventlog.FormatCELCBOR, true
+	}
+	return 0, false
+}
+
 // Set implements flag.Value.
 func (f *flagFormat) Set(in string) error {
 	in = strings.Trim(strings.ToLower(in), " ")
@@ -0,0 +1,74 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmeventlog"
+)
+
+// maxFirmwareSize is the size of the memory window below 4GiB where
+// the BIOS region is mapped. The real size of the firmware is unknown
+// here, so the event data of PCR0 events is interpreted assuming
+// the firmware could be mapped to any address within this window.
+const maxFirmwareSize = 16 << 20
+
+// JSONEvent is a single entry of EventLogJSON.
+type JSONEvent struct {
+	Index      int
+	PCRIndex   pcr.ID
+	Type       string
+	TypeID     uint32
+	HashAlgo   string `json:",omitempty"`
+	Digest     string `json:",omitempty"`
+	Data       string
+	Parsed     *tpmeventlog.EventDataParsed `json:",omitempty"`
+	ParseError string                       `json:",omitempty"`
+}
+
+// EventLogJSON writes the EventLog as a JSON array of JSONEvent-s,
+// including the decoded event data (see tpmeventlog.ParseEventData).
+func EventLogJSON(
+	w io.Writer,
+	eventLog *tpmeventlog.TPMEventLog,
+	filterPCRIndex *pcr.ID,
+	filterHashAlgo *tpmeventlog.TPMAlgorithm,
+) error {
+	result := []JSONEvent{}
+	for idx, ev := range eventLog.Events {
+		if filterPCRIndex != nil && *filterPCRIndex != ev.PCRIndex {
+			continue
+		}
+		if filterHashAlgo != nil && (ev.Digest == nil || ev.Digest.HashAlgo != *filterHashAlgo) {
+			continue
+		}
+
+		entry := JSONEvent{
+			Index:    idx,
+			PCRIndex: ev.PCRIndex,
+			Type:     ev.Type.String(),
+			TypeID:   uint32(ev.Type),
+			Data:     fmt.Sprintf("%X", ev.Data),
+		}
+		if ev.Digest != nil {
+			entry.HashAlgo = ev.Digest.HashAlgo.String()
+			entry.Digest = fmt.Sprintf("%X", ev.Digest.Digest)
+		}
+		parsed, err := tpmeventlog.ParseEventData(ev, maxFirmwareSize)
+		if err != nil {
+			entry.ParseError = err.Error()
+		} else {
+			entry.Parsed = parsed
+		}
+		result = append(result, entry)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		return fmt.Errorf("unable to encode the EventLog to JSON: %w", err)
+	}
+	return nil
+}
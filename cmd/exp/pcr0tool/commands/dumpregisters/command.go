@@ -36,7 +36,7 @@ func (cmd *Command) SetupFlagSet(flag *flag.FlagSet) {
 		"[optional] dumps all registers into a file")
 	cmd.txtPublicDump = flag.String("txt-public-dump", "",
 		"[optional] override TXT public space with a file")
-	flag.Var(&cmd.registers, "registers", "[optional] file that contains registers as a json array (use value '/dev' to use registers of the local machine)")
+	flag.Var(&cmd.registers, "registers", "[optional] file that contains registers (YAML, JSON or a raw TXT public space dump; use value '/dev' to use registers of the local machine)")
 }
 
 // Execute is the main function here. It is responsible to
@@ -68,7 +68,7 @@ func (cmd Command) Execute(ctx context.Context, args []string) {
 	if len(*cmd.outputFile) > 0 {
 		b, err := yaml.Marshal(regs)
 		if err != nil {
-			panic(fmt.Sprintf("failed to marshal registers into json, err: %v", err))
+			panic(fmt.Sprintf("failed to marshal registers into yaml, err: %v", err))
 		}
 		err = os.WriteFile(*cmd.outputFile, b, 0o666)
 		if err != nil {
@@ -0,0 +1,79 @@
+package dumptxtheap
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/tools"
+	"github.com/9elements/go-linux-lowlevel-hw/pkg/hwapi"
+)
+
+// Command is the implementation of `commands.Command`.
+type Command struct {
+	outputFile *string
+	heapDump   *string
+	asJSON     *bool
+}
+
+// Usage prints the syntax of arguments for this command
+func (cmd Command) Usage() string {
+	return ""
+}
+
+// Description explains what this verb commands to do
+func (cmd Command) Description() string {
+	return "decode the TXT heap (BiosData, OsMleData, OsSinitData and SinitMleData) from /dev/mem. Works only on Linux"
+}
+
+// SetupFlagSet is called to allow the command implementation
+// to setup which option flags it has.
+func (cmd *Command) SetupFlagSet(flag *flag.FlagSet) {
+	cmd.outputFile = flag.String("output", "",
+		"[optional] dumps the raw TXT heap into a file")
+	cmd.heapDump = flag.String("heap-dump", "",
+		"[optional] decode a raw TXT heap dump from a file instead of the local machine")
+	cmd.asJSON = flag.Bool("json", false,
+		"[optional] print the decoded TXT heap as JSON")
+}
+
+// Execute is the main function here. It is responsible to
+// start the execution of the command.
+//
+// `args` are the arguments left unused by verb itself and options.
+func (cmd Command) Execute(ctx context.Context, args []string) {
+	var (
+		heap []byte
+		err  error
+	)
+	if *cmd.heapDump != "" {
+		heap, err = os.ReadFile(*cmd.heapDump)
+	} else {
+		heap, err = tools.FetchTXTHeap(hwapi.GetAPI())
+	}
+	if err != nil {
+		panic(fmt.Errorf("unable to read the TXT heap: %w", err))
+	}
+
+	if len(*cmd.outputFile) > 0 {
+		err = os.WriteFile(*cmd.outputFile, heap, 0o666)
+		if err != nil {
+			panic(fmt.Sprintf("failed to write data to file %s, err: %v", *cmd.outputFile, err))
+		}
+	}
+
+	txtHeap, err := tools.ParseTXTHeap(heap)
+	if err != nil {
+		panic(fmt.Errorf("unable to parse the TXT heap: %w", err))
+	}
+	if *cmd.asJSON {
+		b, err := txtHeap.JSON()
+		if err != nil {
+			panic(err)
+		}
+		fmt.Println(string(b))
+		return
+	}
+	fmt.Print(txtHeap.PrettyString())
+}
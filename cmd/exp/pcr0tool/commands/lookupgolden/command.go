@@ -0,0 +1,93 @@
+package lookupgolden
+
+import (
+	"context"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/golden"
+)
+
+func usageAndExit() {
+	flag.Usage()
+	os.Exit(2)
+}
+
+// Command is the implementation of `commands.Command`.
+type Command struct {
+	database *string
+}
+
+// Usage prints the syntax of arguments for this command
+func (cmd Command) Usage() string {
+	return "<PCR0 value>"
+}
+
+// Description explains what this verb commands to do
+func (cmd Command) Description() string {
+	return "find firmware images with the given PCR0 value in a database built by build_golden"
+}
+
+// SetupFlagSet is called to allow the command implementation
+// to setup which option flags it has.
+func (cmd *Command) SetupFlagSet(flag *flag.FlagSet) {
+	cmd.database = flag.String("db", "", "path to the database (JSON or CSV)")
+}
+
+// Execute is the main function here. It is responsible to
+// start the execution of the command.
+//
+// `args` are the arguments left unused by verb itself and options.
+func (cmd Command) Execute(ctx context.Context, args []string) {
+	if len(args) < 1 {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "error: no PCR0 value was specified\n")
+		usageAndExit()
+	}
+	if len(args) > 1 {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "error: too many parameters\n")
+		usageAndExit()
+	}
+	if *cmd.database == "" {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "error: flag -db is required\n")
+		usageAndExit()
+	}
+
+	pcr0, err := hex.DecodeString(strings.TrimPrefix(args[0], "0x"))
+	if err != nil {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "unable to parse PCR0 value '%s': %v\n", args[0], err)
+		usageAndExit()
+	}
+
+	f, err := os.Open(*cmd.database)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to open the database '%s': %v\n", *cmd.database, err)
+		os.Exit(1)
+	}
+	db, err := golden.Read(f)
+	_ = f.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to read the database '%s': %v\n", *cmd.database, err)
+		os.Exit(1)
+	}
+
+	matches := db.Lookup(pcr0)
+	if len(matches) == 0 {
+		fmt.Fprintf(os.Stderr, "no firmware images with PCR0 %X found\n", pcr0)
+		os.Exit(1)
+	}
+	for _, match := range matches {
+		entry := match.Entry
+		fmt.Printf("%s (SHA256:%s)\n", entry.Image, entry.ImageSHA256)
+		if entry.BIOSInfo != nil {
+			fmt.Printf("\tBIOS: %s %s (%s)\n", entry.BIOSInfo.Vendor, entry.BIOSInfo.Version, entry.BIOSInfo.ReleaseDate)
+		}
+		fmt.Printf("\tFlow: %s\n", entry.Flow)
+		if entry.ACMPolicyStatus != nil {
+			fmt.Printf("\tACM_POLICY_STATUS: %016X\n", uint64(*entry.ACMPolicyStatus))
+		}
+		fmt.Printf("\tBank: %s\n", match.Bank)
+	}
+}
@@ -8,12 +8,11 @@ import (
 	"os"
 	"strings"
 
-	fianoGUID "github.com/linuxboot/fiano/pkg/guid"
 	fianoUEFI "github.com/linuxboot/fiano/pkg/uefi"
 
-	"github.com/9elements/converged-security-suite/v2/pkg/uefi"
-	"github.com/9elements/converged-security-suite/v2/pkg/uefi/ffs"
-	pkgbytes "github.com/linuxboot/fiano/pkg/bytes"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/cache"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/biosimage"
+	"github.com/9elements/converged-security-suite/v2/pkg/ostools"
 )
 
 func assertNoError(err error) {
@@ -48,26 +47,6 @@ func (cmd *Command) SetupFlagSet(flag *flag.FlagSet) {
 	cmd.asTree = flag.Bool("as-tree", false, `display the result as a tree`)
 }
 
-type visitor struct {
-	Callback func(*visitor, fianoUEFI.Firmware) error
-	Parent   *visitor
-}
-
-func (v *visitor) Run(f fianoUEFI.Firmware) error {
-	return f.Apply(v)
-}
-
-func (v *visitor) Visit(f fianoUEFI.Firmware) error {
-	err := v.Callback(v, f)
-	if err != nil {
-		return err
-	}
-
-	vCopy := *v
-	vCopy.Parent = v
-	return f.ApplyChildren(&vCopy)
-}
-
 // Execute is the main function here. It is responsible to
 // start the execution of the command.
 //
@@ -84,63 +63,30 @@ func (cmd Command) Execute(ctx context.Context, args []string) {
 	imagePath := args[0]
 
 	fianoUEFI.DisableDecompression = false
-	firmware, err := uefi.ParseUEFIFirmwareFile(imagePath)
+	imageBytes, err := ostools.FileToBytes(imagePath)
 	assertNoError(err)
-
-	nodes, err := firmware.GetByRange(pkgbytes.Range{
-		Offset: 0,
-		Length: uint64(len(firmware.Buf())),
-	})
+	firmware := biosimage.New(imageBytes)
+	firmware.Cache = cache.FromCtx(ctx)
+	layout, err := firmware.Layout()
 	assertNoError(err)
 
-	rangeMap := map[fianoUEFI.Firmware]pkgbytes.Range{}
-	for _, node := range nodes {
-		rangeMap[node.Firmware] = node.Range
-	}
-
-	err = firmware.Apply(&visitor{Callback: func(v *visitor, f fianoUEFI.Firmware) error {
-		// calculating the nesting level
-		nestingLevel := 0
-		{
-			parent := v.Parent
-			for parent != nil {
-				nestingLevel++
-				parent = parent.Parent
-			}
-		}
-
-		var guid *fianoGUID.GUID
-		switch f := f.(type) {
-		case *fianoUEFI.File:
-			guid = &f.Header.GUID
-		case *fianoUEFI.FirmwareVolume:
-			guid = &f.FVName
-		}
-		var guidString string
-		if guid != nil {
-			guidString = guid.String()
-		} else {
-			guidString = `________-____-____-____-____________`
+	for _, node := range layout.Nodes {
+		guidString := `________-____-____-____-____________`
+		if node.GUID != nil {
+			guidString = node.GUID.String()
 		}
 
 		var moduleName string
-		{
-			moduleNamePtr := ffs.Node{Firmware: f}.ModuleName()
-			if moduleNamePtr != nil {
-				moduleName = *moduleNamePtr
-			}
+		if node.ModuleName != nil {
+			moduleName = *node.ModuleName
 		}
 
-		nodeRange := rangeMap[f]
 		if *cmd.asTree {
-			fmt.Print(strings.Repeat("  ", nestingLevel))
+			fmt.Print(strings.Repeat("  ", node.Level))
 		} else {
-			fmt.Printf("%d ", nestingLevel)
+			fmt.Printf("%d ", node.Level)
 		}
-		fmt.Printf("%s %T %s %d %d\n", guidString, f, moduleName, nodeRange.Offset,
-			nodeRange.Length)
-
-		return nil
-	}})
-	assertNoError(err)
+		fmt.Printf("%s %s %s %d %d\n", guidString, node.Type, moduleName, node.Range.Offset,
+			node.Range.Length)
+	}
 }
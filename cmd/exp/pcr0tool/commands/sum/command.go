@@ -4,26 +4,32 @@ import (
 	"bytes"
 	"context"
 	"encoding/hex"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/9elements/converged-security-suite/v2/cmd/exp/pcr0tool/commands"
 	"github.com/9elements/converged-security-suite/v2/cmd/exp/pcr0tool/commands/dumpregisters/helpers"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/bootengine"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/cache"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/flows"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/lib/format"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/amdpsp"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/intelpch"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcrbruteforcer"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/amdregisters"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/biosimage"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/txtpublic"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/types"
+	"github.com/9elements/converged-security-suite/v2/pkg/bruteforcer"
 	"github.com/9elements/converged-security-suite/v2/pkg/registers"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmdetection"
 	"github.com/9elements/converged-security-suite/v2/pkg/tpmeventlog"
 	"github.com/facebookincubator/go-belt/tool/logger"
 	"github.com/google/go-tpm/legacy/tpm2"
@@ -46,9 +52,15 @@ type Command struct {
 	registers               helpers.FlagRegisters
 	compareWithEventLogFlag *string
 	expectedPCR0Flag        *string
+	expectedPCR0AlgoFlag    *string
+	tpmDeviceFlag           *string
+	writeEventLogFlag       *string
 
 	printMeasuredBytesLimitFlag *uint
 
+	bruteforceShardFlag         *string
+	bruteforceCheckpointDirFlag *string
+
 	// Intel-specific advanced options
 	decrementACMPolicyStatus *uint
 }
@@ -67,11 +79,16 @@ func (cmd Command) Description() string {
 // to setup which option flags it has.
 func (cmd *Command) SetupFlagSet(flag *flag.FlagSet) {
 	cmd.flow = flag.String("flow", flows.Root.Name, "values: "+commands.FlowCommandLineValues())
-	flag.Var(&cmd.registers, "registers", "[optional] file that contains registers as a json array (use value '/dev' to use registers of the local machine)")
+	flag.Var(&cmd.registers, "registers", "[optional] file that contains registers (YAML, JSON or a raw TXT public space dump; use value '/dev' to use registers of the local machine)")
 	cmd.decrementACMPolicyStatus = flag.Uint("decrement-acm-policy-status", 0, "[advanced] decrement Intel ACM Policy Status value")
 	cmd.compareWithEventLogFlag = flag.String("compare-with-eventlog", "", "")
 	cmd.expectedPCR0Flag = flag.String("expected-pcr0", "", "")
+	cmd.expectedPCR0AlgoFlag = flag.String("expected-pcr0-algo", "", "[optional] hash algorithm of -expected-pcr0 (SHA1, SHA256, SHA384, SHA512 or SM3_256); by default it is derived from the digest size, and a 32-byte digest is treated as SHA256")
+	cmd.tpmDeviceFlag = flag.String("tpm-device", tpmdetection.TypeTPM20.String(), "values: "+commands.TPMTypeCommandLineValues())
 	cmd.printMeasuredBytesLimitFlag = flag.Uint("print-measured-bytes-limit", 0, "")
+	cmd.writeEventLogFlag = flag.String("write-eventlog", "", "[optional] path to write the expected TPM EventLog to (in the TPM1.2 binary format for TPM1.2, and in the crypto-agile one otherwise)")
+	cmd.bruteforceShardFlag = flag.String("bruteforce-shard", "0/1", "[optional] the part of the PCR0 brute-force search to be processed by this process, in format '<index>/<count>'")
+	cmd.bruteforceCheckpointDirFlag = flag.String("bruteforce-checkpoint-dir", "", "[optional] directory to save the progress of the PCR0 brute-force search to (and to resume it from); it should be shared by all the processes of a sharded search")
 }
 
 // Execute is the main function here. It is responsible to
@@ -109,13 +126,55 @@ func (cmd Command) Execute(ctx context.Context, args []string) {
 		}
 	}
 
+	tpmType, err := tpmdetection.FromString(*cmd.tpmDeviceFlag)
+	if err != nil {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "%v\n", err)
+		usageAndExit()
+	}
+
+	bruteforceShard, err := bruteforcer.ParseShard(*cmd.bruteforceShardFlag)
+	if err != nil {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "%v\n", err)
+		usageAndExit()
+	}
+	if bruteforceShard.Count > 1 && *cmd.bruteforceCheckpointDirFlag == "" {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "error: -bruteforce-checkpoint-dir is required to merge the results of a sharded search\n")
+		usageAndExit()
+	}
+	if *cmd.bruteforceCheckpointDirFlag != "" {
+		err := os.MkdirAll(*cmd.bruteforceCheckpointDirFlag, 0755)
+		assertNoError(err)
+	}
+	reproducer := &pcr0Reproducer{
+		shard:         bruteforceShard,
+		checkpointDir: *cmd.bruteforceCheckpointDirFlag,
+	}
+
+	var (
+		expectedPCR0    []byte
+		pcr0HashAlgo    tpm.Algorithm
+		hasExpectedPCR0 = *cmd.expectedPCR0Flag != ""
+	)
+	if hasExpectedPCR0 {
+		expectedPCR0, err = hex.DecodeString(*cmd.expectedPCR0Flag)
+		if err != nil {
+			_, _ = fmt.Fprintf(flag.CommandLine.Output(), "unable to parse the expected PCR0 value: %v\n", err)
+			usageAndExit()
+		}
+		pcr0HashAlgo, err = expectedPCR0HashAlgo(*cmd.expectedPCR0AlgoFlag, len(expectedPCR0))
+		if err != nil {
+			_, _ = fmt.Fprintf(flag.CommandLine.Output(), "%v\n", err)
+			usageAndExit()
+		}
+	}
+
 	biosFirmwarePath := args[0]
 	biosFirmware, err := os.ReadFile(biosFirmwarePath)
 	if err != nil {
 		panic(fmt.Errorf("unable to read BIOS firmware image '%s': %w", biosFirmwarePath, err))
 	}
 
-	process := boot(ctx, flow, biosFirmware, registers.Registers(cmd.registers))
+	process := boot(ctx, flow, tpmType, biosFirmware, registers.Registers(cmd.registers))
 
 	printBootResults(ctx, process, *cmd.printMeasuredBytesLimitFlag)
 
@@ -124,20 +183,28 @@ func (cmd Command) Execute(ctx context.Context, args []string) {
 		panic(err)
 	}
 
-	var reproducePCR0Result *pcrbruteforcer.ReproducePCR0Result
-	commandLog := tpmInstance.CommandLog
-	if *cmd.expectedPCR0Flag != "" {
-		expectedPCR0, err := hex.DecodeString(*cmd.expectedPCR0Flag)
-		if err != nil {
-			panic(err)
+	if *cmd.writeEventLogFlag != "" {
+		var buf bytes.Buffer
+		eventLog := tpmInstance.EventLog.TPMEventLog()
+		if tpmType == tpmdetection.TypeTPM12 {
+			err = eventLog.WriteLegacy(&buf)
+		} else {
+			err = eventLog.WriteCryptoAgile(&buf)
 		}
+		assertNoError(err)
+		err = os.WriteFile(*cmd.writeEventLogFlag, buf.Bytes(), 0644)
+		assertNoError(err)
+	}
 
-		reproducePCR0Result, err = pcrbruteforcer.ReproduceExpectedPCR0(ctx, commandLog, tpm2.AlgSHA256, expectedPCR0, pcrbruteforcer.DefaultSettingsReproducePCR0())
+	var reproducePCR0Result *pcrbruteforcer.ReproducePCR0Result
+	commandLog := tpmInstance.CommandLog
+	if hasExpectedPCR0 {
+		reproducePCR0Result, err = reproducer.Reproduce(ctx, commandLog, pcr0HashAlgo, expectedPCR0, pcrbruteforcer.DefaultSettingsReproducePCR0())
 		if err != nil {
 			panic(err)
 		}
 
-		printReproducePCR0Result(ctx, expectedPCR0, commandLog, reproducePCR0Result, tpm2.AlgSHA256, registers.Registers(cmd.registers))
+		printReproducePCR0Result(ctx, expectedPCR0, commandLog, reproducePCR0Result, pcr0HashAlgo, registers.Registers(cmd.registers))
 	}
 
 	var combinedCommandLog tpm.CommandLog
@@ -157,11 +224,22 @@ func (cmd Command) Execute(ctx context.Context, args []string) {
 			panic(err)
 		}
 
-		result, _, issues, err := pcrbruteforcer.ReproduceEventLog(ctx, process, parsedEventLog, tpm2.AlgSHA256, pcrbruteforcer.DefaultSettingsReproduceEventLog())
-		if err != nil {
-			panic(err)
+		var result pcrbruteforcer.ReproduceEventLogResult
+		for _, hashAlgo := range eventLogAlgos(parsedEventLog) {
+			if !isSupportedAlgo(tpmInstance, hashAlgo) {
+				logger.Warnf(ctx, "PCR bank %s of the EventLog is not supported, skipping it", pcr.AlgorithmString(hashAlgo))
+				continue
+			}
+			bankResult, _, issues, err := pcrbruteforcer.ReproduceEventLog(ctx, process, parsedEventLog, hashAlgo, pcrbruteforcer.DefaultSettingsReproduceEventLog())
+			if err != nil {
+				panic(err)
+			}
+			printEventLogIssues(ctx, hashAlgo, issues)
+			printEventLogComparison(ctx, tpmInstance, parsedEventLog, hashAlgo)
+			if result == nil || hashAlgo == tpm2.AlgSHA256 {
+				result = bankResult
+			}
 		}
-		printEventLogIssues(ctx, issues)
 
 		eventLog := tpm.EventLogFromParsed(parsedEventLog)
 		commands := eventLog.RestoreCommands()
@@ -171,15 +249,12 @@ func (cmd Command) Execute(ctx context.Context, args []string) {
 				Command: cmd,
 			})
 		}
-		combinedCommandLog = result.CombineAsRestoredCommandLog()
-	}
-
-	expectedPCR0, err := hex.DecodeString(*cmd.expectedPCR0Flag)
-	if err != nil {
-		panic(err)
+		if result != nil {
+			combinedCommandLog = result.CombineAsRestoredCommandLog()
+		}
 	}
 
-	if reproducePCR0Result != nil || *cmd.expectedPCR0Flag == "" {
+	if reproducePCR0Result != nil || !hasExpectedPCR0 {
 		return
 	}
 
@@ -198,21 +273,101 @@ func (cmd Command) Execute(ctx context.Context, args []string) {
 
 			logger.Debugf(ctx, "ReproducePCR0Settings = %#+v", settings)
 
-			reproducePCR0Result, err = pcrbruteforcer.ReproduceExpectedPCR0(ctx, commandLog, tpm2.AlgSHA256, expectedPCR0, settings)
+			reproducePCR0Result, err = reproducer.Reproduce(ctx, commandLog, pcr0HashAlgo, expectedPCR0, settings)
 			if err != nil {
 				panic(err)
 			}
 
-			printReproducePCR0Result(ctx, expectedPCR0, commandLog, reproducePCR0Result, tpm2.AlgSHA256, registers.Registers(cmd.registers))
+			printReproducePCR0Result(ctx, expectedPCR0, commandLog, reproducePCR0Result, pcr0HashAlgo, registers.Registers(cmd.registers))
 			if reproducePCR0Result != nil {
 				return
 			}
 		}
 	}
 
+	if reproducer.isPending {
+		fmt.Printf("shard %s of the PCR0 brute-force search is processed; the result depends on other shards: process them (with the same -bruteforce-checkpoint-dir) and rerun the command\n", reproducer.shard)
+		return
+	}
 	fmt.Println("unable to reproduce PCR0")
 }
 
+// pcr0Reproducer runs the attempts to reproduce PCR0. If a checkpoint
+// directory is set, then every attempt is processed as a shard of
+// a search distributed among processes, which could be interrupted and
+// resumed; and the result is the merged result of all the shards.
+type pcr0Reproducer struct {
+	shard         bruteforcer.Shard
+	checkpointDir string
+
+	attempt int
+
+	// isPending is true if the result of a previous attempt is not known
+	// yet, because some of its shards are not processed.
+	isPending bool
+}
+
+// Reproduce runs the next attempt to reproduce PCR0. It returns nil if
+// PCR0 was not reproduced or the result is not final yet (see isPending).
+func (r *pcr0Reproducer) Reproduce(
+	ctx context.Context,
+	commandLog tpm.CommandLog,
+	hashAlgo tpm.Algorithm,
+	expectedPCR0 tpm.Digest,
+	settings pcrbruteforcer.SettingsReproducePCR0,
+) (*pcrbruteforcer.ReproducePCR0Result, error) {
+	attempt := r.attempt
+	r.attempt++
+	if r.checkpointDir == "" {
+		return pcrbruteforcer.ReproduceExpectedPCR0(ctx, commandLog, hashAlgo, expectedPCR0, settings)
+	}
+
+	checkpointPath := func(shardIndex uint64) string {
+		return filepath.Join(r.checkpointDir, fmt.Sprintf("pcr0-attempt%d-shard%d-of-%d.json", attempt, shardIndex, r.shard.Count))
+	}
+
+	_, err := pcrbruteforcer.ReproduceExpectedPCR0Shard(
+		ctx,
+		commandLog,
+		hashAlgo,
+		expectedPCR0,
+		settings,
+		r.shard,
+		checkpointPath(r.shard.Index),
+		bruteforcer.DefaultShardSettings(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to process shard %s of attempt %d: %w", r.shard, attempt, err)
+	}
+
+	var checkpoints []*bruteforcer.Checkpoint[pcrbruteforcer.ReproducePCR0ShardResult]
+	for shardIndex := uint64(0); shardIndex < r.shard.Count; shardIndex++ {
+		cp, err := bruteforcer.LoadCheckpoint[pcrbruteforcer.ReproducePCR0ShardResult](checkpointPath(shardIndex))
+		switch {
+		case err == nil:
+			checkpoints = append(checkpoints, cp)
+		case os.IsNotExist(err):
+		default:
+			return nil, err
+		}
+	}
+
+	result, err := pcrbruteforcer.MergeReproduceExpectedPCR0Shards(commandLog, hashAlgo, checkpoints)
+	if errors.As(err, &bruteforcer.ErrShardIncomplete{}) {
+		logger.Debugf(ctx, "attempt %d: %v", attempt, err)
+		r.isPending = true
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to merge the shards of attempt %d: %w", attempt, err)
+	}
+	if r.isPending {
+		// a previous attempt may still succeed, so this result is not final
+		return nil, nil
+	}
+	return result, nil
+}
+
 func sanitizeCommandLog(commandLog tpm.CommandLog) tpm.CommandLog {
 	commandLogSanitized := make(tpm.CommandLog, 0, len(commandLog))
 	isInitialized := false
@@ -262,15 +417,18 @@ func sanitizeCommandLog(commandLog tpm.CommandLog) tpm.CommandLog {
 func boot(
 	ctx context.Context,
 	flow types.Flow,
+	tpmType tpmdetection.Type,
 	biosFirmware []byte,
 	regs registers.Registers,
 ) *bootengine.BootProcess {
 	// the main part
 	state := types.NewState()
-	state.IncludeSubSystem(tpm.NewTPM())
+	state.IncludeSubSystem(tpm.NewTPMOfType(tpmType))
 	state.IncludeSubSystem(intelpch.NewPCH())
 	state.IncludeSubSystem(amdpsp.NewPSP())
-	state.IncludeSystemArtifact(biosimage.New(biosFirmware))
+	biosImage := biosimage.New(biosFirmware)
+	biosImage.Cache = cache.FromCtx(ctx)
+	state.IncludeSystemArtifact(biosImage)
 	state.IncludeSystemArtifact(txtpublic.New(registers.Registers(regs)))
 	state.IncludeSystemArtifact(amdregisters.New(registers.Registers(regs)))
 	state.SetFlow(flow)
@@ -333,15 +491,110 @@ func printBootResults(
 
 	fmt.Printf("\nFinal PCR values:\n")
 	for pcrID, values := range tpmInstance.PCRValues {
-		fmt.Printf("\tPCR[%d]: SHA1:%s SHA256:%s\n", pcrID, values[tpm2.AlgSHA1], values[tpm2.AlgSHA256])
+		fmt.Printf("\tPCR[%d]:", pcrID)
+		for _, hashAlgo := range tpmInstance.SupportedAlgos {
+			fmt.Printf(" %s:%s", pcr.AlgorithmString(hashAlgo), values[hashAlgo])
+		}
+		fmt.Printf("\n")
+	}
+}
+
+// eventLogAlgos returns the hash algorithms (PCR banks) present in the EventLog
+// in the order of their first appearance.
+func eventLogAlgos(eventLog *tpmeventlog.TPMEventLog) []tpm.Algorithm {
+	var result []tpm.Algorithm
+	seen := map[tpm.Algorithm]struct{}{}
+	for _, ev := range eventLog.Events {
+		if ev.Digest == nil {
+			continue
+		}
+		if _, ok := seen[ev.Digest.HashAlgo]; ok {
+			continue
+		}
+		seen[ev.Digest.HashAlgo] = struct{}{}
+		result = append(result, ev.Digest.HashAlgo)
+	}
+	return result
+}
+
+func isSupportedAlgo(tpmInstance *tpm.TPM, hashAlgo tpm.Algorithm) bool {
+	for _, supportedAlgo := range tpmInstance.SupportedAlgos {
+		if supportedAlgo == hashAlgo {
+			return true
+		}
+	}
+	return false
+}
+
+// expectedPCR0HashAlgo returns the hash algorithm of the expected PCR0 value.
+//
+// The digest size does not identify the algorithm unambiguously (SHA256 and
+// SM3_256 are both 32 bytes), so the algorithm name takes precedence if
+// specified; otherwise the size is used and a 32-byte digest is SHA256.
+func expectedPCR0HashAlgo(algoName string, digestSize int) (tpm.Algorithm, error) {
+	if algoName != "" {
+		hashAlgo, err := pcr.AlgorithmFromString(algoName)
+		if err != nil {
+			return 0, err
+		}
+		h, err := pcr.Hash(hashAlgo)
+		if err != nil {
+			return 0, fmt.Errorf("unable to get a hasher for %s: %w", pcr.AlgorithmString(hashAlgo), err)
+		}
+		if h.Size() != digestSize {
+			return 0, fmt.Errorf("the expected PCR0 value is %d bytes long, but the digest size of %s is %d", digestSize, pcr.AlgorithmString(hashAlgo), h.Size())
+		}
+		return hashAlgo, nil
+	}
+
+	for _, hashAlgo := range []tpm.Algorithm{tpm2.AlgSHA1, tpm2.AlgSHA256, tpm2.AlgSHA384, tpm2.AlgSHA512} {
+		h, err := pcr.Hash(hashAlgo)
+		if err == nil && h.Size() == digestSize {
+			return hashAlgo, nil
+		}
+	}
+	return 0, fmt.Errorf("unable to detect the hash algorithm of a %d-byte PCR0 value, use -expected-pcr0-algo", digestSize)
+}
+
+func printEventLogComparison(
+	ctx context.Context,
+	tpmInstance *tpm.TPM,
+	eventLog *tpmeventlog.TPMEventLog,
+	hashAlgo tpm.Algorithm,
+) {
+	fmt.Printf("\nEventLog replay comparison (%s):\n", pcr.AlgorithmString(hashAlgo))
+	for pcrID := pcr.ID(0); pcrID < tpm.PCRRegistersAmount; pcrID++ {
+		events, err := eventLog.FilterEvents(pcrID, hashAlgo)
+		if err != nil {
+			logger.Errorf(ctx, "unable to filter events of PCR%d:%s: %v", pcrID, pcr.AlgorithmString(hashAlgo), err)
+			continue
+		}
+		if len(events) == 0 {
+			continue
+		}
+		replayed, err := tpmeventlog.Replay(eventLog, pcrID, hashAlgo, nil)
+		if err != nil {
+			logger.Errorf(ctx, "unable to replay PCR%d:%s: %v", pcrID, pcr.AlgorithmString(hashAlgo), err)
+			continue
+		}
+		calculated, err := tpmInstance.PCRValues.Get(pcrID, hashAlgo)
+		if err != nil {
+			logger.Errorf(ctx, "unable to get the calculated value of PCR%d:%s: %v", pcrID, pcr.AlgorithmString(hashAlgo), err)
+			continue
+		}
+		verdict := "MATCH"
+		if !bytes.Equal(replayed, calculated) {
+			verdict = "MISMATCH"
+		}
+		fmt.Printf("\tPCR[%d]: %s (EventLog:%X calculated:%s)\n", pcrID, verdict, replayed, calculated)
 	}
 }
 
-func printEventLogIssues(ctx context.Context, issues []pcrbruteforcer.Issue) {
+func printEventLogIssues(ctx context.Context, hashAlgo tpm.Algorithm, issues []pcrbruteforcer.Issue) {
 	if len(issues) == 0 {
 		return
 	}
-	fmt.Printf("\nTPM EventLog replay issues:\n")
+	fmt.Printf("\nTPM EventLog replay issues (%s):\n", pcr.AlgorithmString(hashAlgo))
 	for idx, issue := range issues {
 		fmt.Printf("\t%3d.) %v\n", idx+1, issue)
 	}
@@ -31,7 +31,7 @@ type Command struct {
 // SetupFlagSet is called to allow the command implementation
 // to setup which option flags it has.
 func (cmd *Command) SetupFlagSet(flag *flag.FlagSet) {
-	flag.Var(&cmd.registers, "registers", "[optional] file that contains registers as a json array (use value '/dev' to use registers of the local machine)")
+	flag.Var(&cmd.registers, "registers", "[optional] file that contains registers (YAML, JSON or a raw TXT public space dump; use value '/dev' to use registers of the local machine)")
 	cmd.injectBenignCorruptionFlag = flag.String("inject-benign-corruption", "", "output file")
 }
 
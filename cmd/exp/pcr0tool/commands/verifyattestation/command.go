@@ -0,0 +1,224 @@
+package verifyattestation
+
+import (
+	"context"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/9elements/converged-security-suite/v2/cmd/exp/pcr0tool/commands"
+	"github.com/9elements/converged-security-suite/v2/cmd/exp/pcr0tool/commands/dumpregisters/helpers"
+	"github.com/9elements/converged-security-suite/v2/pkg/attestation"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/bootengine"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/flows"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/amdpsp"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/intelpch"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcrbruteforcer"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/amdregisters"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/biosimage"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/txtpublic"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/types"
+	"github.com/9elements/converged-security-suite/v2/pkg/registers"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmdetection"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmeventlog"
+)
+
+func usageAndExit() {
+	flag.Usage()
+	os.Exit(2)
+}
+
+// Command is the implementation of `commands.Command`.
+type Command struct {
+	flow          *string
+	registers     helpers.FlagRegisters
+	tpmDeviceFlag *string
+	eventLog      *string
+	inputFormat   tpmeventlog.Format
+	quote         *string
+	signature     *string
+	akPublic      *string
+	nonce         *string
+}
+
+// Usage prints the syntax of arguments for this command
+func (cmd Command) Usage() string {
+	return "<firmware>"
+}
+
+// Description explains what this verb commands to do
+func (cmd Command) Description() string {
+	return "verify a TPM2 quote and its EventLog against a firmware image"
+}
+
+// SetupFlagSet is called to allow the command implementation
+// to setup which option flags it has.
+func (cmd *Command) SetupFlagSet(flag *flag.FlagSet) {
+	cmd.flow = flag.String("flow", flows.Root.Name, "values: "+commands.FlowCommandLineValues())
+	flag.Var(&cmd.registers, "registers", "[optional] file that contains registers of the attested machine (YAML, named registers JSON, reads JSON or a raw TXT public space dump)")
+	cmd.tpmDeviceFlag = flag.String("tpm-device", tpmdetection.TypeTPM20.String(), "values: "+commands.TPMTypeCommandLineValues())
+	cmd.eventLog = flag.String("event-log", "", "path to the EventLog of the attested machine")
+	flag.Var(&cmd.inputFormat, "input-format", "select input format of the EventLog")
+	cmd.quote = flag.String("quote", "", "path to the TPMS_ATTEST structure of the quote (for example, 'tpm2_quote -m')")
+	cmd.signature = flag.String("signature", "", "path to the TPMT_SIGNATURE structure of the quote (for example, 'tpm2_quote -s')")
+	cmd.akPublic = flag.String("ak-pub", "", "path to the public key of the Attestation Key (PEM, TPM2B_PUBLIC or TPMT_PUBLIC)")
+	cmd.nonce = flag.String("nonce", "", "[optional] the expected nonce (qualifying data) of the quote in hex")
+}
+
+// Execute is the main function here. It is responsible to
+// start the execution of the command.
+//
+// `args` are the arguments left unused by verb itself and options.
+func (cmd Command) Execute(ctx context.Context, args []string) {
+	if len(args) < 1 {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "error: no path to the firmware was specified\n")
+		usageAndExit()
+	}
+	if len(args) > 1 {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "error: too many parameters\n")
+		usageAndExit()
+	}
+	for _, required := range []struct {
+		name  string
+		value string
+	}{
+		{"event-log", *cmd.eventLog},
+		{"quote", *cmd.quote},
+		{"signature", *cmd.signature},
+		{"ak-pub", *cmd.akPublic},
+	} {
+		if required.value == "" {
+			_, _ = fmt.Fprintf(flag.CommandLine.Output(), "error: flag -%s is required\n", required.name)
+			usageAndExit()
+		}
+	}
+
+	flow, ok := flows.GetFlowByName(*cmd.flow)
+	if !ok {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "unknown boot flow: '%s'\n", *cmd.flow)
+		usageAndExit()
+	}
+
+	tpmType, err := tpmdetection.FromString(*cmd.tpmDeviceFlag)
+	if err != nil {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "%v\n", err)
+		usageAndExit()
+	}
+
+	var nonce []byte
+	if *cmd.nonce != "" {
+		nonce, err = hex.DecodeString(*cmd.nonce)
+		if err != nil {
+			_, _ = fmt.Fprintf(flag.CommandLine.Output(), "unable to parse the nonce '%s': %v\n", *cmd.nonce, err)
+			usageAndExit()
+		}
+	}
+
+	evidence := attestation.Evidence{
+		Attest:    readFile("quote", *cmd.quote),
+		Signature: readFile("signature", *cmd.signature),
+		Nonce:     nonce,
+	}
+	evidence.AKPublic, err = attestation.ParseAKPublic(readFile("AK public key", *cmd.akPublic))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to parse AK public key '%s': %v\n", *cmd.akPublic, err)
+		os.Exit(1)
+	}
+
+	eventLogFile, err := os.Open(*cmd.eventLog)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to open EventLog '%s': %v\n", *cmd.eventLog, err)
+		os.Exit(1)
+	}
+	evidence.EventLog, err = tpmeventlog.ParseWithFormat(eventLogFile, cmd.inputFormat)
+	_ = eventLogFile.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to parse EventLog '%s': %v\n", *cmd.eventLog, err)
+		os.Exit(1)
+	}
+
+	biosFirmware := readFile("BIOS firmware image", args[0])
+
+	state := types.NewState()
+	state.IncludeSubSystem(tpm.NewTPMOfType(tpmType))
+	state.IncludeSubSystem(intelpch.NewPCH())
+	state.IncludeSubSystem(amdpsp.NewPSP())
+	state.IncludeSystemArtifact(biosimage.New(biosFirmware))
+	state.IncludeSystemArtifact(txtpublic.New(registers.Registers(cmd.registers)))
+	state.IncludeSystemArtifact(amdregisters.New(registers.Registers(cmd.registers)))
+	state.SetFlow(flow)
+	process := bootengine.NewBootProcess(state)
+	process.Finish(ctx)
+
+	result, err := attestation.Verify(ctx, evidence, process)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to verify the attestation: %v\n", err)
+		os.Exit(1)
+	}
+
+	printResult(result)
+	if result.Verdict != attestation.VerdictTrusted {
+		os.Exit(1)
+	}
+}
+
+func readFile(description, path string) []byte {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to read %s '%s': %v\n", description, path, err)
+		os.Exit(1)
+	}
+	return data
+}
+
+func printResult(result *attestation.Result) {
+	if result.Quote != nil {
+		selection := result.Quote.PCRSelection()
+		fmt.Printf("Quote: bank %s, PCRs %v, nonce %X\n", pcr.AlgorithmString(selection.Hash), selection.PCRs, []byte(result.Quote.Attest.ExtraData))
+		for _, pcrIndex := range result.Quote.PCRIDs() {
+			fmt.Printf("\tPCR[%d] replayed from the EventLog: %X\n", pcrIndex, result.ReplayedPCRs[pcrIndex])
+		}
+	}
+	if result.ExpectedPCR0 != nil {
+		fmt.Printf("PCR0 expected for the firmware image: %X\n", result.ExpectedPCR0)
+	}
+	if result.ACMPolicyStatus != nil {
+		fmt.Printf("Corrected ACM Policy Status: %016X\n", *result.ACMPolicyStatus)
+	}
+
+	if len(result.EventLogIssues) > 0 {
+		fmt.Printf("\nEventLog issues:\n")
+		for idx, issue := range result.EventLogIssues {
+			fmt.Printf("\t%3d.) %v\n", idx+1, issue)
+		}
+	}
+	isHeaderPrinted := false
+	for idx, entry := range result.EventLogResult {
+		if entry.Status == pcrbruteforcer.ReproduceEventLogEntryStatusMatch {
+			continue
+		}
+		if !isHeaderPrinted {
+			fmt.Printf("\nEventLog entries not matching the firmware image:\n")
+			isHeaderPrinted = true
+		}
+		switch entry.Status {
+		case pcrbruteforcer.ReproduceEventLogEntryStatusMismatch:
+			fmt.Printf("\t%3d.) mismatch: calculated %v, logged %v\n", idx, entry.Calculated, entry.Expected)
+		case pcrbruteforcer.ReproduceEventLogEntryStatusUnexpected:
+			fmt.Printf("\t%3d.) unexpected: logged %v\n", idx, entry.Expected)
+		case pcrbruteforcer.ReproduceEventLogEntryStatusMissing:
+			fmt.Printf("\t%3d.) missing: calculated %v\n", idx, entry.Calculated)
+		}
+	}
+
+	if len(result.Reasons) > 0 {
+		fmt.Printf("\nFailed checks:\n")
+		for idx, reason := range result.Reasons {
+			fmt.Printf("\t%3d.) %v\n", idx+1, reason)
+		}
+	}
+	fmt.Printf("\nVerdict: %s\n", result.Verdict)
+}
@@ -0,0 +1,131 @@
+package verifyeventlog
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/tpm"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmeventlog"
+)
+
+func usageAndExit() {
+	flag.Usage()
+	os.Exit(2)
+}
+
+const (
+	pcrSourceAuto  = "auto"
+	pcrSourceTPM   = "tpm"
+	pcrSourceSysfs = "sysfs"
+)
+
+// Command is the implementation of `commands.Command`.
+type Command struct {
+	eventLog    *string
+	inputFormat tpmeventlog.Format
+	pcrSource   *string
+}
+
+// Usage prints the syntax of arguments for this command
+func (cmd Command) Usage() string {
+	return ""
+}
+
+// Description explains what this verb commands to do
+func (cmd Command) Description() string {
+	return "compare all PCRs of all active banks with the values replayed from the EventLog"
+}
+
+// SetupFlagSet is called to allow the command implementation
+// to setup which option flags it has.
+func (cmd *Command) SetupFlagSet(flag *flag.FlagSet) {
+	cmd.eventLog = flag.String("event-log", "/sys/kernel/security/tpm0/binary_bios_measurements", "path to the EventLog")
+	flag.Var(&cmd.inputFormat, "input-format", "select input format of the EventLog")
+	cmd.pcrSource = flag.String("pcr-source", pcrSourceAuto, "where to read PCR values from, allowed values: auto (sysfs, and TPM if sysfs is not available), tpm, sysfs")
+}
+
+// Execute is the main function here. It is responsible to
+// start the execution of the command.
+//
+// `args` are the arguments left unused by verb itself and options.
+func (cmd Command) Execute(ctx context.Context, args []string) {
+	if len(args) > 0 {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "error: too many parameters\n")
+		usageAndExit()
+	}
+
+	banks, err := cmd.readPCRBanks(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to read PCR values: %v\n", err)
+		os.Exit(1)
+	}
+
+	eventLogFile, err := os.Open(*cmd.eventLog)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to open EventLog '%s': %v\n", *cmd.eventLog, err)
+		os.Exit(1)
+	}
+	eventLog, err := tpmeventlog.ParseWithFormat(eventLogFile, cmd.inputFormat)
+	_ = eventLogFile.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to parse EventLog '%s': %v\n", *cmd.eventLog, err)
+		os.Exit(1)
+	}
+
+	logAlgos := map[tpmeventlog.TPMAlgorithm]bool{}
+	for _, hashAlgo := range eventLog.HashAlgos() {
+		logAlgos[hashAlgo] = true
+		if _, ok := banks[hashAlgo]; !ok {
+			fmt.Printf("bank %s: present in the EventLog, but not active in the TPM, skipped\n", hashAlgo)
+		}
+	}
+	for hashAlgo := range banks {
+		if !logAlgos[hashAlgo] {
+			fmt.Printf("bank %s: active in the TPM, but not present in the EventLog, skipped\n", hashAlgo)
+		}
+	}
+
+	mismatches := 0
+	for _, v := range tpmeventlog.VerifyPCRs(eventLog, banks) {
+		switch {
+		case v.Err != nil:
+			mismatches++
+			fmt.Printf("PCR%-2d %-7s ERROR     %v\n", v.PCRIndex, v.HashAlgo, v.Err)
+		case v.IsMatch():
+			fmt.Printf("PCR%-2d %-7s OK        %X\n", v.PCRIndex, v.HashAlgo, v.Replayed)
+		default:
+			mismatches++
+			fmt.Printf("PCR%-2d %-7s MISMATCH  expected:%X replayed:%X\n", v.PCRIndex, v.HashAlgo, v.Expected, v.Replayed)
+			if v.Diagnosis != nil {
+				fmt.Printf("\t%s\n", v.Diagnosis)
+			}
+		}
+	}
+
+	if mismatches > 0 {
+		fmt.Fprintf(os.Stderr, "%d PCR values are not reproducible from the EventLog\n", mismatches)
+		os.Exit(1)
+	}
+}
+
+func (cmd Command) readPCRBanks(ctx context.Context) (tpm.PCRBanks, error) {
+	switch *cmd.pcrSource {
+	case pcrSourceTPM:
+		return tpm.ReadPCRBanksFromTPM(ctx)
+	case pcrSourceSysfs:
+		return tpm.ReadPCRBanksFromSysfs()
+	case pcrSourceAuto:
+		banks, err := tpm.ReadPCRBanksFromSysfs()
+		if err == nil {
+			return banks, nil
+		}
+		banks, tpmErr := tpm.ReadPCRBanksFromTPM(ctx)
+		if tpmErr != nil {
+			return nil, fmt.Errorf("unable to read from sysfs (%v) and from TPM (%w)", err, tpmErr)
+		}
+		return banks, nil
+	}
+	return nil, fmt.Errorf("unknown PCR source '%s'", *cmd.pcrSource)
+}
@@ -8,16 +8,23 @@ import (
 	"sort"
 
 	"github.com/9elements/converged-security-suite/v2/cmd/exp/pcr0tool/commands"
+	"github.com/9elements/converged-security-suite/v2/cmd/exp/pcr0tool/commands/analyzesecureboot"
 	bruteforceacmpolicystatus "github.com/9elements/converged-security-suite/v2/cmd/exp/pcr0tool/commands/bruteforce_acm_policy_status"
+	"github.com/9elements/converged-security-suite/v2/cmd/exp/pcr0tool/commands/buildgolden"
 	"github.com/9elements/converged-security-suite/v2/cmd/exp/pcr0tool/commands/diff"
 	"github.com/9elements/converged-security-suite/v2/cmd/exp/pcr0tool/commands/displayeventlog"
 	"github.com/9elements/converged-security-suite/v2/cmd/exp/pcr0tool/commands/displayfwinfo"
 	"github.com/9elements/converged-security-suite/v2/cmd/exp/pcr0tool/commands/dumpfit"
 	"github.com/9elements/converged-security-suite/v2/cmd/exp/pcr0tool/commands/dumpregisters"
+	"github.com/9elements/converged-security-suite/v2/cmd/exp/pcr0tool/commands/dumptxtheap"
+	"github.com/9elements/converged-security-suite/v2/cmd/exp/pcr0tool/commands/lookupgolden"
 	"github.com/9elements/converged-security-suite/v2/cmd/exp/pcr0tool/commands/pcrread"
 	"github.com/9elements/converged-security-suite/v2/cmd/exp/pcr0tool/commands/printnodes"
 	"github.com/9elements/converged-security-suite/v2/cmd/exp/pcr0tool/commands/sum"
 	validatesecurity "github.com/9elements/converged-security-suite/v2/cmd/exp/pcr0tool/commands/validate_security"
+	"github.com/9elements/converged-security-suite/v2/cmd/exp/pcr0tool/commands/verifyattestation"
+	"github.com/9elements/converged-security-suite/v2/cmd/exp/pcr0tool/commands/verifyeventlog"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/cache"
 	"github.com/9elements/converged-security-suite/v2/pkg/log"
 	"github.com/facebookincubator/go-belt/tool/logger"
 	"github.com/facebookincubator/go-belt/tool/logger/implementation/logrus"
@@ -26,16 +33,22 @@ import (
 )
 
 var knownCommands = map[string]commands.Command{
+	"analyze_secureboot":           &analyzesecureboot.Command{},
 	"bruteforce_acm_policy_status": &bruteforceacmpolicystatus.Command{},
+	"build_golden":                 &buildgolden.Command{},
 	"diff":                         &diff.Command{},
 	"display_eventlog":             &displayeventlog.Command{},
 	"display_fwinfo":               &displayfwinfo.Command{},
 	"dump_fit":                     &dumpfit.Command{},
 	"dump_registers":               &dumpregisters.Command{},
+	"dump_txt_heap":                &dumptxtheap.Command{},
+	"lookup_golden":                &lookupgolden.Command{},
 	"pcrread":                      &pcrread.Command{},
 	"printnodes":                   &printnodes.Command{},
 	"validate_security":            &validatesecurity.Command{},
 	"sum":                          &sum.Command{},
+	"verify_attestation":           &verifyattestation.Command{},
+	"verify_eventlog":              &verifyeventlog.Command{},
 }
 
 func usageAndExit() {
@@ -43,7 +56,12 @@ func usageAndExit() {
 	os.Exit(2) // the standard Go's exit-code on invalid flags
 }
 
-var logLevel = logger.LevelWarning
+var (
+	logLevel        = logger.LevelWarning
+	cacheMaxEntries = flag.Int("cache-max-entries", cache.DefaultMaxEntries, "maximal amount of cached parsing results (0 means unlimited)")
+	cacheMaxBytes   = flag.Uint64("cache-max-bytes", cache.DefaultMaxBytes, "maximal approximate size of cached parsing results in bytes (0 means unlimited)")
+	cacheDir        = flag.String("cache-dir", "", "directory to persist parsed firmware layouts (UEFI nodes, FIT and PCD) between runs (empty means disabled)")
+)
 
 func setupFlag() {
 	flag.Usage = func() {
@@ -76,6 +94,11 @@ func main() {
 
 	ctx := logger.CtxWithLogger(context.Background(), logrus.Default().WithLevel(logLevel))
 	fianoLog.DefaultLogger = log.NewFianoLogger(logger.FromCtx(ctx), logger.LevelTrace)
+	parseCache := cache.NewLRU(*cacheMaxEntries, *cacheMaxBytes, nil)
+	ctx = cache.CtxWithCache(ctx, parseCache)
+	if *cacheDir != "" {
+		ctx = withDiskCache(ctx, *cacheDir, parseCache)
+	}
 
 	commandName := flag.Arg(0)
 	command := knownCommands[commandName]
@@ -97,4 +120,22 @@ func main() {
 	command.SetupFlagSet(flagSet)
 	_ = flagSet.Parse(os.Args[len(os.Args)-flag.NArg()+1:])
 	command.Execute(ctx, flagSet.Args())
+	logger.Debugf(ctx, "cache statistics: %+v", parseCache.Stats())
+}
+
+func withDiskCache(ctx context.Context, dir string, mem cache.Cache) context.Context {
+	version := toolVersion()
+	if version == "" {
+		logger.Warnf(ctx, "unable to identify the build of the tool, the disk cache is disabled")
+		return ctx
+	}
+
+	diskCache := cache.NewDisk(dir, "pcr0tool", version, mem)
+	diskCache.OnError = func(err error) {
+		logger.Warnf(ctx, "disk cache: %v", err)
+	}
+	if err := diskCache.Prune(); err != nil {
+		logger.Warnf(ctx, "unable to prune the disk cache: %v", err)
+	}
+	return cache.CtxWithCache(ctx, diskCache)
 }
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"runtime/debug"
+)
+
+// toolVersion returns the identifier of the build of the tool, which
+// is used to invalidate the disk cache on upgrades. It returns an empty
+// string if the build could not be identified.
+func toolVersion() string {
+	buildInfo, ok := debug.ReadBuildInfo()
+	if ok && buildInfo.Main.Version != "" && buildInfo.Main.Version != "(devel)" {
+		return buildInfo.Main.Version
+	}
+
+	// A local build: there is no reliable version, so
+	// using the hash of the executable instead.
+	exePath, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	f, err := os.Open(exePath)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return ""
+	}
+	return "exe-" + hex.EncodeToString(hasher.Sum(nil))[:16]
+}
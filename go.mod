@@ -11,6 +11,7 @@ require (
 	github.com/edsrzf/mmap-go v1.1.0
 	github.com/facebookincubator/go-belt v0.0.0-20230703220829-b6b46c95ec1f
 	github.com/fearful-symmetry/gomsr v0.0.1
+	github.com/fxamacker/cbor/v2 v2.9.1
 	github.com/go-ng/slices v0.0.0-20230703171042-6195d35636a2
 	github.com/go-ng/xmath v0.0.0-20230704233441-028f5ea62335
 	github.com/golang-collections/go-datastructures v0.0.0-20150211160725-59788d5eb259
@@ -26,6 +27,7 @@ require (
 	github.com/steakknife/hamming v0.0.0-20180906055917-c99c65617cd3
 	github.com/stretchr/testify v1.8.4
 	github.com/tidwall/pretty v1.2.1
+	github.com/tjfoc/gmsm v1.4.1
 	github.com/u-root/cpuid v0.0.0
 	github.com/ulikunitz/xz v0.5.14
 	github.com/xaionaro-facebook/go-dmidecode v0.0.0-20220413144237-c42d5bef2498
@@ -53,7 +55,7 @@ require (
 	github.com/pierrec/lz4 v2.6.1+incompatible // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/rivo/uniseg v0.2.0 // indirect
-	github.com/tjfoc/gmsm v1.4.1 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
 	golang.org/x/sync v0.21.0 // indirect
 	golang.org/x/sys v0.46.0 // indirect
 	golang.org/x/text v0.31.0 // indirect
@@ -0,0 +1,272 @@
+package acpi
+
+import (
+	"fmt"
+)
+
+// DMARSignature is the signature of the DMAR table.
+const DMARSignature = "DMAR"
+
+// The layout of the DMAR table is defined in "Intel Virtualization
+// Technology for Directed I/O Architecture Specification", chapter 8.
+
+const (
+	dmarFixedSize          = 12 // Host Address Width + Flags + Reserved
+	dmarStructHeaderSize   = 4  // Type + Length
+	dmarDRHDHeaderSize     = 16
+	dmarRMRRHeaderSize     = 24
+	dmarDeviceScopeMinSize = 6 // Type + Length + Flags + Reserved + Enumeration ID + Start Bus Number
+)
+
+// DMARStructType is the type of a remapping structure.
+type DMARStructType uint16
+
+const (
+	// DMARStructTypeDRHD is DMA Remapping Hardware Unit Definition
+	DMARStructTypeDRHD = DMARStructType(0)
+	// DMARStructTypeRMRR is Reserved Memory Region Reporting
+	DMARStructTypeRMRR = DMARStructType(1)
+	// DMARStructTypeATSR is Root Port ATS Capability Reporting
+	DMARStructTypeATSR = DMARStructType(2)
+	// DMARStructTypeRHSA is Remapping Hardware Static Affinity
+	DMARStructTypeRHSA = DMARStructType(3)
+	// DMARStructTypeANDD is ACPI Name-space Device Declaration
+	DMARStructTypeANDD = DMARStructType(4)
+	// DMARStructTypeSATC is SoC Integrated Address Translation Cache
+	DMARStructTypeSATC = DMARStructType(5)
+)
+
+// DMARDeviceScopeType is the type of a device scope entry.
+type DMARDeviceScopeType uint8
+
+const (
+	// DMARDeviceScopeTypePCIEndpoint is a PCI endpoint device
+	DMARDeviceScopeTypePCIEndpoint = DMARDeviceScopeType(1)
+	// DMARDeviceScopeTypePCISubHierarchy is a PCI-PCI bridge and all
+	// the devices behind it
+	DMARDeviceScopeTypePCISubHierarchy = DMARDeviceScopeType(2)
+	// DMARDeviceScopeTypeIOAPIC is an I/O APIC
+	DMARDeviceScopeTypeIOAPIC = DMARDeviceScopeType(3)
+	// DMARDeviceScopeTypeHPET is a MSI capable HPET
+	DMARDeviceScopeTypeHPET = DMARDeviceScopeType(4)
+	// DMARDeviceScopeTypeACPINamespaceDevice is an ACPI name-space enumerated device
+	DMARDeviceScopeTypeACPINamespaceDevice = DMARDeviceScopeType(5)
+)
+
+// String implements fmt.Stringer.
+func (t DMARDeviceScopeType) String() string {
+	switch t {
+	case DMARDeviceScopeTypePCIEndpoint:
+		return "PCI Endpoint"
+	case DMARDeviceScopeTypePCISubHierarchy:
+		return "PCI Sub-hierarchy"
+	case DMARDeviceScopeTypeIOAPIC:
+		return "IOAPIC"
+	case DMARDeviceScopeTypeHPET:
+		return "HPET"
+	case DMARDeviceScopeTypeACPINamespaceDevice:
+		return "ACPI Namespace Device"
+	}
+	return fmt.Sprintf("unknown_device_scope_type_%d", uint8(t))
+}
+
+// DMARPCIPath is an element of the hierarchical path from the
+// start bus to the device.
+type DMARPCIPath struct {
+	Device   uint8
+	Function uint8
+}
+
+// DMARDeviceScope is a device scope structure.
+type DMARDeviceScope struct {
+	Type           DMARDeviceScopeType
+	Length         uint8
+	Flags          uint8
+	Reserved       uint8
+	EnumerationID  uint8
+	StartBusNumber uint8
+	Path           []DMARPCIPath
+}
+
+// Validate checks that the device scope entry is well-formed.
+func (s DMARDeviceScope) Validate() error {
+	if s.Type < DMARDeviceScopeTypePCIEndpoint || s.Type > DMARDeviceScopeTypeACPINamespaceDevice {
+		return fmt.Errorf("device scope has invalid type %d", uint8(s.Type))
+	}
+	if len(s.Path) == 0 {
+		return fmt.Errorf("device scope of type %s has an empty path", s.Type)
+	}
+	if s.Reserved != 0 {
+		return fmt.Errorf("device scope of type %s has non-zero reserved field", s.Type)
+	}
+	return nil
+}
+
+// ValidateLength checks that the length of the device scope entry
+// matches its path.
+func (s DMARDeviceScope) ValidateLength() error {
+	if s.Length < dmarDeviceScopeMinSize+2 {
+		return fmt.Errorf("device scope of type %s is too short: %d", s.Type, s.Length)
+	}
+	if (s.Length-dmarDeviceScopeMinSize)%2 != 0 {
+		return fmt.Errorf("device scope of type %s has invalid length %d", s.Type, s.Length)
+	}
+	return nil
+}
+
+// DMARDRHD is a DMA Remapping Hardware Unit Definition structure.
+type DMARDRHD struct {
+	Flags               uint8
+	Size                uint8
+	Segment             uint16
+	RegisterBaseAddress uint64
+	DeviceScopes        []DMARDeviceScope
+}
+
+// IncludePCIAll returns true if the remapping hardware unit covers
+// all the PCI devices of the segment which are not reported by other
+// remapping hardware units.
+func (d DMARDRHD) IncludePCIAll() bool {
+	return d.Flags&0x1 != 0
+}
+
+// RegisterSetSize returns the size of the remapping hardware register set.
+func (d DMARDRHD) RegisterSetSize() uint64 {
+	return 4096 << (d.Size & 0xf)
+}
+
+// DMARRMRR is a Reserved Memory Region Reporting structure.
+type DMARRMRR struct {
+	Segment      uint16
+	BaseAddress  uint64
+	LimitAddress uint64
+	DeviceScopes []DMARDeviceScope
+}
+
+// DMARStruct is a remapping structure which is not parsed into
+// a specific type.
+type DMARStruct struct {
+	Type DMARStructType
+	// Data is the structure contents following Type and Length.
+	Data []byte
+}
+
+// DMAR is the DMA Remapping Reporting table.
+type DMAR struct {
+	Header
+	HostAddressWidth uint8
+	Flags            uint8
+	DRHDs            []DMARDRHD
+	RMRRs            []DMARRMRR
+	Others           []DMARStruct
+}
+
+// ParseDMAR parses and validates the DMAR table.
+func ParseDMAR(b []byte) (*DMAR, error) {
+	hdr, body, err := ParseHeader(b, DMARSignature)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) < dmarFixedSize {
+		return nil, fmt.Errorf("DMAR is too short")
+	}
+
+	dmar := &DMAR{
+		Header:           *hdr,
+		HostAddressWidth: body[0],
+		Flags:            body[1],
+	}
+	for cur := dmarFixedSize; cur < len(body); {
+		offset := HeaderSize + cur
+		if cur+dmarStructHeaderSize > len(body) {
+			return nil, fmt.Errorf("remapping structure at offset 0x%X is truncated", offset)
+		}
+		structType := DMARStructType(binaryOrder.Uint16(body[cur:]))
+		length := int(binaryOrder.Uint16(body[cur+2:]))
+		if length < dmarStructHeaderSize || cur+length > len(body) {
+			return nil, fmt.Errorf("remapping structure at offset 0x%X has invalid length %d", offset, length)
+		}
+		data := body[cur : cur+length]
+		cur += length
+
+		switch structType {
+		case DMARStructTypeDRHD:
+			if len(data) < dmarDRHDHeaderSize {
+				return nil, fmt.Errorf("DRHD at offset 0x%X is too short: %d", offset, len(data))
+			}
+			scopes, err := parseDeviceScopes(data[dmarDRHDHeaderSize:], offset+dmarDRHDHeaderSize)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse device scopes of DRHD at offset 0x%X: %w", offset, err)
+			}
+			dmar.DRHDs = append(dmar.DRHDs, DMARDRHD{
+				Flags:               data[4],
+				Size:                data[5],
+				Segment:             binaryOrder.Uint16(data[6:]),
+				RegisterBaseAddress: binaryOrder.Uint64(data[8:]),
+				DeviceScopes:        scopes,
+			})
+		case DMARStructTypeRMRR:
+			if len(data) < dmarRMRRHeaderSize {
+				return nil, fmt.Errorf("RMRR at offset 0x%X is too short: %d", offset, len(data))
+			}
+			scopes, err := parseDeviceScopes(data[dmarRMRRHeaderSize:], offset+dmarRMRRHeaderSize)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse device scopes of RMRR at offset 0x%X: %w", offset, err)
+			}
+			dmar.RMRRs = append(dmar.RMRRs, DMARRMRR{
+				Segment:      binaryOrder.Uint16(data[6:]),
+				BaseAddress:  binaryOrder.Uint64(data[8:]),
+				LimitAddress: binaryOrder.Uint64(data[16:]),
+				DeviceScopes: scopes,
+			})
+		default:
+			dmar.Others = append(dmar.Others, DMARStruct{
+				Type: structType,
+				Data: data[dmarStructHeaderSize:],
+			})
+		}
+	}
+	return dmar, nil
+}
+
+// parseDeviceScopes parses a list of device scope entries. The length of
+// each entry is validated only to the extent required to walk the list,
+// see DMARDeviceScope.ValidateLength.
+func parseDeviceScopes(b []byte, baseOffset int) ([]DMARDeviceScope, error) {
+	var result []DMARDeviceScope
+	for cur := 0; cur < len(b); {
+		if cur+dmarDeviceScopeMinSize > len(b) {
+			return nil, fmt.Errorf("device scope at offset 0x%X is truncated", baseOffset+cur)
+		}
+		length := int(b[cur+1])
+		if length < dmarDeviceScopeMinSize || cur+length > len(b) {
+			return nil, fmt.Errorf("device scope at offset 0x%X has invalid length %d", baseOffset+cur, length)
+		}
+		scope := DMARDeviceScope{
+			Type:           DMARDeviceScopeType(b[cur]),
+			Length:         b[cur+1],
+			Flags:          b[cur+2],
+			Reserved:       b[cur+3],
+			EnumerationID:  b[cur+4],
+			StartBusNumber: b[cur+5],
+		}
+		for idx := cur + dmarDeviceScopeMinSize; idx+1 < cur+length; idx += 2 {
+			scope.Path = append(scope.Path, DMARPCIPath{
+				Device:   b[idx],
+				Function: b[idx+1],
+			})
+		}
+		result = append(result, scope)
+		cur += length
+	}
+	return result, nil
+}
+
+// DeviceScopes returns the device scopes of all the DRHDs.
+func (d *DMAR) DeviceScopes() []DMARDeviceScope {
+	var result []DMARDeviceScope
+	for _, drhd := range d.DRHDs {
+		result = append(result, drhd.DeviceScopes...)
+	}
+	return result
+}
@@ -0,0 +1,65 @@
+package acpi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDMAR(t *testing.T) {
+	b := readTestTable(t, "synthetic_DMAR.bin")
+
+	dmar, err := ParseDMAR(b)
+	require.NoError(t, err)
+	require.Equal(t, uint8(38), dmar.HostAddressWidth)
+	require.Len(t, dmar.DRHDs, 2)
+	require.Len(t, dmar.RMRRs, 1)
+	require.Empty(t, dmar.Others)
+
+	gfx := dmar.DRHDs[0]
+	require.False(t, gfx.IncludePCIAll())
+	require.Equal(t, uint64(0xFED90000), gfx.RegisterBaseAddress)
+	require.Equal(t, uint64(4096), gfx.RegisterSetSize())
+	require.Equal(t, []DMARDeviceScope{{
+		Type:   DMARDeviceScopeTypePCIEndpoint,
+		Length: 8,
+		Path:   []DMARPCIPath{{Device: 2}},
+	}}, gfx.DeviceScopes)
+
+	pch := dmar.DRHDs[1]
+	require.True(t, pch.IncludePCIAll())
+	require.Len(t, pch.DeviceScopes, 2)
+	require.Equal(t, DMARDeviceScopeTypeIOAPIC, pch.DeviceScopes[0].Type)
+	require.Equal(t, DMARDeviceScopeTypeHPET, pch.DeviceScopes[1].Type)
+	require.Len(t, dmar.DeviceScopes(), 3)
+
+	require.Equal(t, uint64(0x3D000000), dmar.RMRRs[0].BaseAddress)
+	require.Equal(t, uint64(0x3F7FFFFF), dmar.RMRRs[0].LimitAddress)
+
+	for _, scope := range dmar.DeviceScopes() {
+		require.NoError(t, scope.Validate())
+		require.NoError(t, scope.ValidateLength())
+	}
+}
+
+func TestParseDMARInvalidDeviceScope(t *testing.T) {
+	// the first device scope of the first DRHD
+	const scopeOffset = HeaderSize + dmarFixedSize + dmarDRHDHeaderSize
+
+	b := append([]byte{}, readTestTable(t, "synthetic_DMAR.bin")...)
+	b[scopeOffset+1] = 0xff
+	fixChecksum(b)
+	_, err := ParseDMAR(b)
+	require.Error(t, err)
+
+	b = append([]byte{}, readTestTable(t, "synthetic_DMAR.bin")...)
+	b[scopeOffset] = 0x7
+	fixChecksum(b)
+	dmar, err := ParseDMAR(b)
+	require.NoError(t, err)
+	require.Error(t, dmar.DRHDs[0].DeviceScopes[0].Validate())
+
+	scope := dmar.DRHDs[0].DeviceScopes[0]
+	scope.Length = 7
+	require.Error(t, scope.ValidateLength())
+}
@@ -0,0 +1,63 @@
+// Package acpi implements parsers of ACPI tables relevant for
+// the security validation of a platform (DMAR, MADT, MCFG and RSDP).
+package acpi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// HeaderSize is the size of the System Description Table Header.
+const HeaderSize = 36
+
+var binaryOrder = binary.LittleEndian
+
+// Header represents the table header as defined in ACPI Spec 6.2
+// "5.2.6 System Description Table Header"
+type Header struct {
+	Signature       [4]uint8
+	Length          uint32
+	Revision        uint8
+	Checksum        uint8
+	OEMID           [6]uint8
+	OEMTableID      [8]uint8
+	OEMRevision     uint32
+	CreatorID       uint32
+	CreatorRevision uint32
+}
+
+// ParseHeader parses and validates the header of the ACPI table
+// with the given signature. It returns the header and the table
+// contents following the header.
+func ParseHeader(b []byte, signature string) (*Header, []byte, error) {
+	if len(b) < HeaderSize {
+		return nil, nil, fmt.Errorf("ACPI table %s is too short: %d < %d", signature, len(b), HeaderSize)
+	}
+
+	var hdr Header
+	if err := binary.Read(bytes.NewReader(b), binaryOrder, &hdr); err != nil {
+		return nil, nil, fmt.Errorf("unable to parse the header of ACPI table %s: %w", signature, err)
+	}
+	if string(hdr.Signature[:]) != signature {
+		return nil, nil, fmt.Errorf("ACPI table %s has invalid signature '%s'", signature, hdr.Signature[:])
+	}
+	if hdr.Length != uint32(len(b)) {
+		return nil, nil, fmt.Errorf("ACPI table %s has invalid length: %d != %d", signature, hdr.Length, len(b))
+	}
+	if checksum(b) != 0 {
+		return nil, nil, fmt.Errorf("ACPI table %s has invalid checksum", signature)
+	}
+
+	return &hdr, b[HeaderSize:], nil
+}
+
+// checksum returns the 8-bit sum of all the bytes, a valid ACPI
+// structure sums up to zero.
+func checksum(b []byte) uint8 {
+	var sum uint8
+	for _, v := range b {
+		sum += v
+	}
+	return sum
+}
@@ -0,0 +1,93 @@
+package acpi
+
+import (
+	"fmt"
+)
+
+// MADTSignature is the signature of the MADT table.
+const MADTSignature = "APIC"
+
+// MADTEntryType is the type of an interrupt controller structure.
+type MADTEntryType uint8
+
+const (
+	// MADTEntryTypeLocalAPIC is the Processor Local APIC structure
+	MADTEntryTypeLocalAPIC = MADTEntryType(0)
+	// MADTEntryTypeIOAPIC is the I/O APIC structure
+	MADTEntryTypeIOAPIC = MADTEntryType(1)
+)
+
+// MADTEntry is an interrupt controller structure of the MADT.
+type MADTEntry struct {
+	Type MADTEntryType
+	// Data is the structure contents following Type and Length.
+	Data []byte
+}
+
+// MADTIOAPIC is the I/O APIC structure as defined in ACPI Spec 6.2
+// "5.2.12.3 I/O APIC Structure"
+type MADTIOAPIC struct {
+	ID                        uint8
+	Address                   uint32
+	GlobalSystemInterruptBase uint32
+}
+
+// MADT is the Multiple APIC Description Table as defined in ACPI Spec 6.2
+// "5.2.12 Multiple APIC Description Table (MADT)"
+type MADT struct {
+	Header
+	LocalAPICAddress uint32
+	Flags            uint32
+	Entries          []MADTEntry
+}
+
+// ParseMADT parses and validates the MADT table.
+func ParseMADT(b []byte) (*MADT, error) {
+	hdr, body, err := ParseHeader(b, MADTSignature)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) < 8 {
+		return nil, fmt.Errorf("MADT is too short")
+	}
+
+	madt := &MADT{
+		Header:           *hdr,
+		LocalAPICAddress: binaryOrder.Uint32(body[0:]),
+		Flags:            binaryOrder.Uint32(body[4:]),
+	}
+	for cur := 8; cur < len(body); {
+		if cur+2 > len(body) {
+			return nil, fmt.Errorf("MADT entry at offset 0x%X is truncated", HeaderSize+cur)
+		}
+		length := int(body[cur+1])
+		if length < 2 || cur+length > len(body) {
+			return nil, fmt.Errorf("MADT entry at offset 0x%X has invalid length %d", HeaderSize+cur, length)
+		}
+		madt.Entries = append(madt.Entries, MADTEntry{
+			Type: MADTEntryType(body[cur]),
+			Data: body[cur+2 : cur+length],
+		})
+		cur += length
+	}
+	return madt, nil
+}
+
+// IOAPICs returns all the I/O APIC structures of the MADT.
+func (m *MADT) IOAPICs() ([]MADTIOAPIC, error) {
+	var result []MADTIOAPIC
+	for _, entry := range m.Entries {
+		if entry.Type != MADTEntryTypeIOAPIC {
+			continue
+		}
+		if len(entry.Data) != 10 {
+			return nil, fmt.Errorf("I/O APIC structure has invalid length %d", len(entry.Data)+2)
+		}
+		result = append(result, MADTIOAPIC{
+			ID:                        entry.Data[0],
+			Address:                   binaryOrder.Uint32(entry.Data[2:]),
+			GlobalSystemInterruptBase: binaryOrder.Uint32(entry.Data[6:]),
+		})
+	}
+	return result, nil
+}
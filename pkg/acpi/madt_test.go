@@ -0,0 +1,46 @@
+package acpi
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testDataPath = "../../testdata/acpi/"
+
+func readTestTable(t *testing.T, name string) []byte {
+	b, err := os.ReadFile(testDataPath + name)
+	require.NoError(t, err)
+	return b
+}
+
+// fixChecksum updates the checksum of a modified ACPI table.
+func fixChecksum(b []byte) {
+	b[9] = 0
+	b[9] = -checksum(b)
+}
+
+func TestParseMADT(t *testing.T) {
+	b := readTestTable(t, "firecracker_APIC.bin")
+
+	madt, err := ParseMADT(b)
+	require.NoError(t, err)
+	require.Equal(t, uint32(0xFEE00000), madt.LocalAPICAddress)
+	require.Len(t, madt.Entries, 2)
+	require.Equal(t, MADTEntryTypeIOAPIC, madt.Entries[0].Type)
+	require.Equal(t, MADTEntryTypeLocalAPIC, madt.Entries[1].Type)
+
+	ioapics, err := madt.IOAPICs()
+	require.NoError(t, err)
+	require.Equal(t, []MADTIOAPIC{{ID: 0, Address: 0xFEC00000}}, ioapics)
+
+	_, err = ParseMADT(b[:len(b)-1])
+	require.Error(t, err)
+
+	b = append([]byte{}, b...)
+	b[HeaderSize+8+1] = 0xff // I/O APIC entry length
+	fixChecksum(b)
+	_, err = ParseMADT(b)
+	require.Error(t, err)
+}
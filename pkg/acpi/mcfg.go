@@ -0,0 +1,71 @@
+package acpi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// MCFGSignature is the signature of the MCFG table.
+const MCFGSignature = "MCFG"
+
+// MCFGAllocation is an enhanced configuration space base address
+// allocation structure as defined in "PCI Firmware Specification 3.2",
+// table 4-3.
+type MCFGAllocation struct {
+	BaseAddress uint64
+	Segment     uint16
+	StartBus    uint8
+	EndBus      uint8
+	Reserved    uint32
+}
+
+// MCFG is the PCI Express memory mapped configuration space base
+// address description table.
+type MCFG struct {
+	Header
+	Reserved    uint64
+	Allocations []MCFGAllocation
+}
+
+// ParseMCFG parses and validates the MCFG table.
+func ParseMCFG(b []byte) (*MCFG, error) {
+	hdr, body, err := ParseHeader(b, MCFGSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	mcfg := &MCFG{Header: *hdr}
+	r := bytes.NewReader(body)
+	if err := binary.Read(r, binaryOrder, &mcfg.Reserved); err != nil {
+		return nil, fmt.Errorf("unable to parse MCFG: %w", err)
+	}
+	if r.Len()%16 != 0 {
+		return nil, fmt.Errorf("MCFG has invalid length of allocations: %d", r.Len())
+	}
+	for r.Len() > 0 {
+		var alloc MCFGAllocation
+		if err := binary.Read(r, binaryOrder, &alloc); err != nil {
+			return nil, fmt.Errorf("unable to parse MCFG allocation: %w", err)
+		}
+		mcfg.Allocations = append(mcfg.Allocations, alloc)
+	}
+	return mcfg, nil
+}
+
+// BusRange returns the range of bus numbers decoded for the PCI segment.
+func (m *MCFG) BusRange(segment uint16) (start, end uint8, ok bool) {
+	for _, alloc := range m.Allocations {
+		if alloc.Segment != segment {
+			continue
+		}
+		if !ok || alloc.StartBus < start {
+			start = alloc.StartBus
+		}
+		if !ok || alloc.EndBus > end {
+			end = alloc.EndBus
+		}
+		ok = true
+	}
+	return
+}
@@ -0,0 +1,28 @@
+package acpi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMCFG(t *testing.T) {
+	b := readTestTable(t, "firecracker_MCFG.bin")
+
+	mcfg, err := ParseMCFG(b)
+	require.NoError(t, err)
+	require.Equal(t, []MCFGAllocation{{BaseAddress: 0xEEC00000}}, mcfg.Allocations)
+
+	start, end, ok := mcfg.BusRange(0)
+	require.True(t, ok)
+	require.Equal(t, uint8(0), start)
+	require.Equal(t, uint8(0), end)
+
+	_, _, ok = mcfg.BusRange(1)
+	require.False(t, ok)
+
+	b = append([]byte{}, b...)
+	b[10] = 'X' // OEMID
+	_, err = ParseMCFG(b)
+	require.Error(t, err)
+}
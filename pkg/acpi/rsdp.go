@@ -0,0 +1,63 @@
+package acpi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	// rsdpV1Size is the size of the RSDP structure of ACPI 1.0
+	rsdpV1Size = 20
+	// rsdpV2Size is the size of the RSDP structure of ACPI 2.0 and later
+	rsdpV2Size = 36
+)
+
+// RSDP as defined in ACPI Spec 6.2 "5.2.5.3 Root System Description Pointer (RSDP) Structure"
+type RSDP struct {
+	Signature        [8]uint8
+	Checksum         uint8
+	OEMID            [6]uint8
+	Revision         uint8
+	RSDTAddress      uint32
+	Length           uint32
+	XSDTAddress      uint64
+	ExtendedChecksum uint8
+	Reserved         [3]uint8
+}
+
+// ParseRSDP parses and validates the RSDP structure.
+func ParseRSDP(b []byte) (*RSDP, error) {
+	if len(b) < rsdpV1Size {
+		return nil, fmt.Errorf("RSDP is too short: %d < %d", len(b), rsdpV1Size)
+	}
+
+	var rsdp RSDP
+	raw := make([]byte, rsdpV2Size)
+	copy(raw, b)
+	if err := binary.Read(bytes.NewReader(raw), binaryOrder, &rsdp); err != nil {
+		return nil, fmt.Errorf("unable to parse RSDP: %w", err)
+	}
+	if string(rsdp.Signature[:]) != "RSD PTR " {
+		return nil, fmt.Errorf("RSDP has invalid signature '%s'", rsdp.Signature[:])
+	}
+	if checksum(b[:rsdpV1Size]) != 0 {
+		return nil, fmt.Errorf("RSDP has invalid checksum")
+	}
+	if rsdp.Revision == 0 {
+		// ACPI 1.0 has neither Length nor XSDTAddress
+		rsdp.Length = 0
+		rsdp.XSDTAddress = 0
+		rsdp.ExtendedChecksum = 0
+		rsdp.Reserved = [3]uint8{}
+		return &rsdp, nil
+	}
+
+	if rsdp.Length < rsdpV2Size || uint64(rsdp.Length) > uint64(len(b)) {
+		return nil, fmt.Errorf("RSDP has invalid length: %d", rsdp.Length)
+	}
+	if checksum(b[:rsdp.Length]) != 0 {
+		return nil, fmt.Errorf("RSDP has invalid extended checksum")
+	}
+	return &rsdp, nil
+}
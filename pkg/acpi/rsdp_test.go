@@ -0,0 +1,35 @@
+package acpi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRSDP(t *testing.T) {
+	b := make([]byte, rsdpV2Size)
+	copy(b, "RSD PTR ")
+	copy(b[9:], "OEMID ")
+	b[15] = 2
+	binaryOrder.PutUint32(b[16:], 0x7FFE0000)
+	binaryOrder.PutUint32(b[20:], rsdpV2Size)
+	binaryOrder.PutUint64(b[24:], 0x7FFE1000)
+	b[8] = -checksum(b[:rsdpV1Size])
+	b[32] = -checksum(b)
+
+	rsdp, err := ParseRSDP(b)
+	require.NoError(t, err)
+	require.Equal(t, uint32(0x7FFE0000), rsdp.RSDTAddress)
+	require.Equal(t, uint64(0x7FFE1000), rsdp.XSDTAddress)
+
+	b[24]++
+	_, err = ParseRSDP(b)
+	require.Error(t, err)
+
+	b[15] = 0
+	b[8] = 0
+	b[8] = -checksum(b[:rsdpV1Size])
+	rsdp, err = ParseRSDP(b[:rsdpV1Size])
+	require.NoError(t, err)
+	require.Zero(t, rsdp.XSDTAddress)
+}
@@ -0,0 +1,180 @@
+package attestation
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"sort"
+
+	"github.com/google/go-tpm/legacy/tpm2"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
+)
+
+// attestMagic is the value of TPM_GENERATED_VALUE, which prefixes
+// all structures generated by a TPM.
+const attestMagic = 0xff544347
+
+// ParseAKPublic parses the public key of an Attestation Key. Supported formats:
+// PEM-encoded PKIX ("tpm2_createak -f pem"), TPM2B_PUBLIC ("tpm2_createak -u")
+// and TPMT_PUBLIC.
+func ParseAKPublic(b []byte) (crypto.PublicKey, error) {
+	if block, _ := pem.Decode(b); block != nil {
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse PEM-encoded public key: %w", err)
+		}
+		return pub, nil
+	}
+
+	public, err := tpm2.DecodePublic(stripSizePrefix(b))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode TPM public area: %w", err)
+	}
+	pub, err := public.Key()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get the key from TPM public area: %w", err)
+	}
+	return pub, nil
+}
+
+// stripSizePrefix removes the size field of a TPM2B structure, if the
+// first two bytes of the input are equal to the length of the rest.
+func stripSizePrefix(b []byte) []byte {
+	if len(b) >= 2 && int(binary.BigEndian.Uint16(b)) == len(b)-2 {
+		return b[2:]
+	}
+	return b
+}
+
+// Quote is a decoded TPM2 quote with a verified signature.
+type Quote struct {
+	// Attest is the signed TPMS_ATTEST structure.
+	Attest *tpm2.AttestationData
+
+	// HashAlgo is the hash algorithm of the signature, it is also
+	// used by TPM to calculate the PCR digest of the quote.
+	HashAlgo tpm2.Algorithm
+}
+
+// VerifyQuote verifies the signature of a quote and decodes it.
+//
+// `attest` is the TPMS_ATTEST structure ("tpm2_quote -m"), TPM2B_ATTEST
+// is accepted as well; `signature` is the TPMT_SIGNATURE structure
+// ("tpm2_quote -s" in the default "tss" format).
+func VerifyQuote(akPub crypto.PublicKey, attest, signature []byte) (*Quote, error) {
+	if len(attest) >= 6 && binary.BigEndian.Uint32(attest) != attestMagic && binary.BigEndian.Uint32(attest[2:]) == attestMagic {
+		attest = attest[2:]
+	}
+
+	sig, err := tpm2.DecodeSignature(bytes.NewBuffer(signature))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode the signature: %w", err)
+	}
+
+	var sigHashAlgo tpm2.Algorithm
+	switch {
+	case sig.RSA != nil:
+		sigHashAlgo = sig.RSA.HashAlg
+	case sig.ECC != nil:
+		sigHashAlgo = sig.ECC.HashAlg
+	}
+	hash, err := sigHashAlgo.Hash()
+	if err != nil {
+		return nil, fmt.Errorf("unsupported hash algorithm of the signature %s: %w", sigHashAlgo, err)
+	}
+	h := hash.New()
+	h.Write(attest)
+	digest := h.Sum(nil)
+
+	switch pub := akPub.(type) {
+	case *rsa.PublicKey:
+		if sig.RSA == nil {
+			return nil, fmt.Errorf("the signature algorithm %s does not match the RSA key", sig.Alg)
+		}
+		switch sig.Alg {
+		case tpm2.AlgRSASSA:
+			err = rsa.VerifyPKCS1v15(pub, hash, digest, sig.RSA.Signature)
+		case tpm2.AlgRSAPSS:
+			err = rsa.VerifyPSS(pub, hash, digest, sig.RSA.Signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid signature: %w", err)
+		}
+	case *ecdsa.PublicKey:
+		if sig.ECC == nil {
+			return nil, fmt.Errorf("the signature algorithm %s does not match the ECC key", sig.Alg)
+		}
+		if !ecdsa.Verify(pub, digest, sig.ECC.R, sig.ECC.S) {
+			return nil, fmt.Errorf("invalid signature")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", akPub)
+	}
+
+	attestData, err := tpm2.DecodeAttestationData(attest)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode the quote: %w", err)
+	}
+	if attestData.AttestedQuoteInfo == nil {
+		return nil, fmt.Errorf("the attestation structure is not a quote, but of type 0x%X", attestData.Type)
+	}
+
+	return &Quote{
+		Attest:   attestData,
+		HashAlgo: sigHashAlgo,
+	}, nil
+}
+
+// PCRSelection returns the PCR bank and the PCRs covered by the quote.
+func (q *Quote) PCRSelection() tpm2.PCRSelection {
+	return q.Attest.AttestedQuoteInfo.PCRSelection
+}
+
+// PCRIDs returns the indexes of the quoted PCRs in the ascending order.
+func (q *Quote) PCRIDs() []pcr.ID {
+	var result []pcr.ID
+	for _, pcrIndex := range q.PCRSelection().PCRs {
+		result = append(result, pcr.ID(pcrIndex))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i] < result[j]
+	})
+	return result
+}
+
+// VerifyNonce checks that the quote was made with the given
+// qualifying data (nonce).
+func (q *Quote) VerifyNonce(nonce []byte) error {
+	if !bytes.Equal(q.Attest.ExtraData, nonce) {
+		return fmt.Errorf("the nonce of the quote %X does not match the expected one %X", []byte(q.Attest.ExtraData), nonce)
+	}
+	return nil
+}
+
+// VerifyPCRs checks that the PCR digest of the quote matches the given
+// PCR values. Values of all quoted PCRs are required.
+func (q *Quote) VerifyPCRs(values map[pcr.ID][]byte) error {
+	hash, err := q.HashAlgo.Hash()
+	if err != nil {
+		return fmt.Errorf("unsupported hash algorithm %s: %w", q.HashAlgo, err)
+	}
+	h := hash.New()
+	for _, pcrIndex := range q.PCRIDs() {
+		value, ok := values[pcrIndex]
+		if !ok {
+			return fmt.Errorf("no value for the quoted PCR%d", pcrIndex)
+		}
+		h.Write(value)
+	}
+	if digest := h.Sum(nil); !bytes.Equal(digest, q.Attest.AttestedQuoteInfo.PCRDigest) {
+		return fmt.Errorf("the PCR digest of the quote %X does not match the digest of the given PCR values %X",
+			[]byte(q.Attest.AttestedQuoteInfo.PCRDigest), digest)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package attestation
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"io"
+	"testing"
+
+	"github.com/google/go-tpm/legacy/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpm/simulator"
+)
+
+var (
+	akTemplateRSA = tpm2.Public{
+		Type:       tpm2.AlgRSA,
+		NameAlg:    tpm2.AlgSHA256,
+		Attributes: tpm2.FlagSignerDefault,
+		RSAParameters: &tpm2.RSAParams{
+			Sign:    &tpm2.SigScheme{Alg: tpm2.AlgRSASSA, Hash: tpm2.AlgSHA256},
+			KeyBits: 2048,
+		},
+	}
+	akTemplateECC = tpm2.Public{
+		Type:       tpm2.AlgECC,
+		NameAlg:    tpm2.AlgSHA256,
+		Attributes: tpm2.FlagSignerDefault,
+		ECCParameters: &tpm2.ECCParams{
+			Sign:    &tpm2.SigScheme{Alg: tpm2.AlgECDSA, Hash: tpm2.AlgSHA256},
+			CurveID: tpm2.CurveNISTP256,
+		},
+	}
+)
+
+// newAK creates an Attestation Key in the endorsement hierarchy of the TPM.
+func newAK(t *testing.T, rw io.ReadWriter, template tpm2.Public) (tpmutil.Handle, crypto.PublicKey) {
+	ak, akPub, err := tpm2.CreatePrimary(rw, tpm2.HandleEndorsement, tpm2.PCRSelection{}, "", "", template)
+	require.NoError(t, err)
+	return ak, akPub
+}
+
+// quote quotes the SHA256 PCRs and returns TPMS_ATTEST and TPMT_SIGNATURE.
+func quote(t *testing.T, rw io.ReadWriter, ak tpmutil.Handle, nonce []byte, pcrs ...int) ([]byte, []byte) {
+	attest, signature, err := tpm2.QuoteRaw(rw, ak, "", "", nonce, tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: pcrs}, tpm2.AlgNull)
+	require.NoError(t, err)
+	return attest, signature
+}
+
+func readPCR(t *testing.T, rw io.ReadWriter, pcrIndex int) []byte {
+	value, err := tpm2.ReadPCR(rw, pcrIndex, tpm2.AlgSHA256)
+	require.NoError(t, err)
+	return value
+}
+
+func TestVerifyQuote(t *testing.T) {
+	rw := simulator.New()
+	digest := sha256.Sum256([]byte("measurement"))
+	require.NoError(t, tpm2.PCRExtend(rw, 1, tpm2.AlgSHA256, digest[:], ""))
+	pcr0, pcr1 := readPCR(t, rw, 0), readPCR(t, rw, 1)
+
+	nonce := []byte("nonce")
+	rsaAK, rsaPub := newAK(t, rw, akTemplateRSA)
+	rsaAttest, rsaSignature := quote(t, rw, rsaAK, nonce, 0, 1)
+	ecAK, ecPub := newAK(t, rw, akTemplateECC)
+	ecAttest, ecSignature := quote(t, rw, ecAK, nonce, 0, 1)
+
+	t.Run("rsa", func(t *testing.T) {
+		quote, err := VerifyQuote(rsaPub, rsaAttest, rsaSignature)
+		require.NoError(t, err)
+		require.Equal(t, tpm2.AlgSHA256, quote.HashAlgo)
+		require.Equal(t, []pcr.ID{0, 1}, quote.PCRIDs())
+		require.NoError(t, quote.VerifyNonce(nonce))
+		require.Error(t, quote.VerifyNonce([]byte("other")))
+		require.NoError(t, quote.VerifyPCRs(map[pcr.ID][]byte{0: pcr0, 1: pcr1}))
+		require.Error(t, quote.VerifyPCRs(map[pcr.ID][]byte{0: pcr1, 1: pcr0}))
+		require.Error(t, quote.VerifyPCRs(map[pcr.ID][]byte{0: pcr0}))
+	})
+
+	t.Run("ecdsa_tpm2b_attest", func(t *testing.T) {
+		tpm2bAttest := binary.BigEndian.AppendUint16(nil, uint16(len(ecAttest)))
+		tpm2bAttest = append(tpm2bAttest, ecAttest...)
+		quote, err := VerifyQuote(ecPub, tpm2bAttest, ecSignature)
+		require.NoError(t, err)
+		require.NoError(t, quote.VerifyPCRs(map[pcr.ID][]byte{0: pcr0, 1: pcr1}))
+	})
+
+	t.Run("wrong_key", func(t *testing.T) {
+		_, err := VerifyQuote(ecPub, rsaAttest, rsaSignature)
+		require.Error(t, err)
+
+		require.NoError(t, tpm2.FlushContext(rw, ecAK))
+		_, otherPub := newAK(t, rw, akTemplateRSA)
+		_, err = VerifyQuote(otherPub, rsaAttest, rsaSignature)
+		require.Error(t, err)
+	})
+
+	t.Run("tampered_quote", func(t *testing.T) {
+		tampered := append([]byte{}, rsaAttest...)
+		tampered[len(tampered)-1] ^= 0xFF
+		_, err := VerifyQuote(rsaPub, tampered, rsaSignature)
+		require.Error(t, err)
+	})
+}
+
+func TestParseAKPublic(t *testing.T) {
+	rw := simulator.New()
+	ak, akPub := newAK(t, rw, akTemplateRSA)
+
+	pkix, err := x509.MarshalPKIXPublicKey(akPub)
+	require.NoError(t, err)
+	pub, err := ParseAKPublic(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pkix}))
+	require.NoError(t, err)
+	require.True(t, pub.(interface{ Equal(crypto.PublicKey) bool }).Equal(akPub))
+
+	public, _, _, err := tpm2.ReadPublic(rw, ak)
+	require.NoError(t, err)
+	encodedPublic, err := public.Encode()
+	require.NoError(t, err)
+	pub, err = ParseAKPublic(encodedPublic)
+	require.NoError(t, err)
+	require.True(t, pub.(interface{ Equal(crypto.PublicKey) bool }).Equal(akPub))
+
+	tpm2bPublic, err := tpmutil.Pack(tpmutil.U16Bytes(encodedPublic))
+	require.NoError(t, err)
+	pub, err = ParseAKPublic(tpm2bPublic)
+	require.NoError(t, err)
+	require.True(t, pub.(interface{ Equal(crypto.PublicKey) bool }).Equal(akPub))
+}
@@ -0,0 +1,170 @@
+package attestation
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"fmt"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/bootengine"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcrbruteforcer"
+	"github.com/9elements/converged-security-suite/v2/pkg/registers"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmeventlog"
+)
+
+// Evidence is the data reported by the attested machine.
+type Evidence struct {
+	// Attest is the TPMS_ATTEST structure of the quote.
+	Attest []byte
+
+	// Signature is the TPMT_SIGNATURE structure of the quote.
+	Signature []byte
+
+	// AKPublic is the public key of the Attestation Key, which signed the quote.
+	AKPublic crypto.PublicKey
+
+	// EventLog is the TPM EventLog of the attested machine.
+	EventLog *tpmeventlog.TPMEventLog
+
+	// Nonce is the expected qualifying data of the quote. It is not
+	// checked if nil.
+	Nonce []byte
+}
+
+// Verdict is the final decision of the verification.
+type Verdict int
+
+const (
+	// VerdictUndefined means the verification was not performed.
+	VerdictUndefined = Verdict(iota)
+
+	// VerdictTrusted means the quote is correctly signed, the EventLog
+	// is consistent with the quote and PCR0 matches the firmware image.
+	VerdictTrusted
+
+	// VerdictUntrusted means at least one of the checks failed, see
+	// Result.Reasons.
+	VerdictUntrusted
+)
+
+// String implements fmt.Stringer.
+func (v Verdict) String() string {
+	switch v {
+	case VerdictUndefined:
+		return "undefined"
+	case VerdictTrusted:
+		return "trusted"
+	case VerdictUntrusted:
+		return "untrusted"
+	}
+	return fmt.Sprintf("unknown_verdict_%d", int(v))
+}
+
+// Result is the result of a verification.
+type Result struct {
+	Verdict Verdict
+
+	// Reasons are the failed checks, which made the verdict untrusted.
+	Reasons []error
+
+	// Quote is the decoded quote, it is nil if the signature is invalid.
+	Quote *Quote
+
+	// ReplayedPCRs are the values of the quoted PCRs replayed from the EventLog.
+	ReplayedPCRs map[pcr.ID][]byte
+
+	// ExpectedPCR0 is the value of PCR0 predicted for the firmware image.
+	ExpectedPCR0 []byte
+
+	// EventLogResult, EventLogIssues and ACMPolicyStatus are the diagnostics
+	// of pcrbruteforcer.ReproduceEventLog, see its description.
+	EventLogResult  pcrbruteforcer.ReproduceEventLogResult
+	EventLogIssues  []pcrbruteforcer.Issue
+	ACMPolicyStatus *registers.ACMPolicyStatus
+}
+
+func (result *Result) untrusted(err error) {
+	result.Verdict = VerdictUntrusted
+	result.Reasons = append(result.Reasons, err)
+}
+
+// Verify validates the evidence against the expected boot process of
+// the firmware image (see bootengine.NewBootProcess):
+//
+//  1. The signature of the quote is verified with the AK public key.
+//  2. The nonce of the quote is compared with the expected one (if set).
+//  3. The quoted PCRs are replayed from the EventLog and compared with
+//     the PCR digest of the quote.
+//  4. The replayed PCR0 is compared with the PCR0 value calculated for
+//     the firmware image.
+//
+// The EventLog is also compared with the calculated measurements through
+// pcrbruteforcer.ReproduceEventLog to explain the mismatches.
+//
+// An error is returned only if the verification could not be performed,
+// all failed checks are reported via Result.Reasons.
+func Verify(
+	ctx context.Context,
+	evidence Evidence,
+	process *bootengine.BootProcess,
+) (*Result, error) {
+	result := &Result{
+		Verdict: VerdictTrusted,
+	}
+
+	quote, err := VerifyQuote(evidence.AKPublic, evidence.Attest, evidence.Signature)
+	if err != nil {
+		result.untrusted(fmt.Errorf("unable to verify the quote: %w", err))
+		return result, nil
+	}
+	result.Quote = quote
+
+	if evidence.Nonce != nil {
+		if err := quote.VerifyNonce(evidence.Nonce); err != nil {
+			result.untrusted(err)
+		}
+	}
+
+	hashAlgo := quote.PCRSelection().Hash
+	result.ReplayedPCRs = map[pcr.ID][]byte{}
+	for _, pcrIndex := range quote.PCRIDs() {
+		value, err := tpmeventlog.Replay(evidence.EventLog, pcrIndex, hashAlgo, nil)
+		if err != nil {
+			return nil, fmt.Errorf("unable to replay PCR%d of bank %s: %w", pcrIndex, pcr.AlgorithmString(hashAlgo), err)
+		}
+		result.ReplayedPCRs[pcrIndex] = value
+	}
+	if err := quote.VerifyPCRs(result.ReplayedPCRs); err != nil {
+		result.untrusted(fmt.Errorf("the EventLog does not match the quote: %w", err))
+	}
+
+	tpmInstance, err := tpm.GetFrom(process.CurrentState)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get the TPM of the boot process: %w", err)
+	}
+	expectedPCR0, err := tpmInstance.PCRValues.Get(0, hashAlgo)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get the calculated PCR0 of bank %s: %w", pcr.AlgorithmString(hashAlgo), err)
+	}
+	result.ExpectedPCR0 = expectedPCR0
+	if replayedPCR0, ok := result.ReplayedPCRs[0]; !ok {
+		result.untrusted(fmt.Errorf("PCR0 is not quoted"))
+	} else if !bytes.Equal(replayedPCR0, expectedPCR0) {
+		result.untrusted(fmt.Errorf("PCR0 %X does not match the value %X expected for the firmware image", replayedPCR0, []byte(expectedPCR0)))
+	}
+
+	result.EventLogResult, result.ACMPolicyStatus, result.EventLogIssues, err = pcrbruteforcer.ReproduceEventLog(
+		ctx,
+		process,
+		evidence.EventLog,
+		hashAlgo,
+		pcrbruteforcer.DefaultSettingsReproduceEventLog(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compare the EventLog with the calculated measurements: %w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,156 @@
+package attestation
+
+import (
+	"context"
+	"crypto/sha256"
+	"io"
+	"testing"
+
+	"github.com/google/go-tpm/legacy/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+	"github.com/linuxboot/fiano/pkg/guid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/actors"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/bootengine"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/datasources"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/steps/commonsteps"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/steps/tpmsteps"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/biosimage"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/types"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpm/simulator"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmeventlog"
+	ffsConsts "github.com/9elements/converged-security-suite/v2/pkg/uefi/ffs/consts"
+	"github.com/9elements/converged-security-suite/v2/testdata/firmware"
+)
+
+// testFlow measures the firmware image into PCR0. TPM is initialized at
+// locality 0, because the simulator does not support localities.
+var testFlow = types.NewFlow("attestation-test-flow", types.Steps{
+	tpmsteps.InitTPM(0, true),
+	commonsteps.SetActor(actors.PEI{}),
+	tpmsteps.Measure(0, tpmeventlog.EV_S_CRTM_VERSION, datasources.Bytes{0x1E, 0xFB, 0x6B, 0x54}),
+	tpmsteps.Measure(0, tpmeventlog.EV_EFI_PLATFORM_FIRMWARE_BLOB2, datasources.UEFIGUIDFirst([]guid.GUID{ffsConsts.GUIDDXEContainer, ffsConsts.GUIDDXE})),
+	tpmsteps.Measure(0, tpmeventlog.EV_SEPARATOR, datasources.Bytes{0, 0, 0, 0}),
+	commonsteps.SetActor(actors.DXE{}),
+})
+
+func newTestBootProcess(ctx context.Context, t *testing.T) *bootengine.BootProcess {
+	state := types.NewState()
+	state.IncludeSubSystem(tpm.NewTPM())
+	state.IncludeSystemArtifact(biosimage.New(firmware.FakeIntelFirmware))
+	state.SetFlow(testFlow)
+	process := bootengine.NewBootProcess(state)
+	process.Finish(ctx)
+	require.NoError(t, process.Log.Error())
+	return process
+}
+
+// boot extends the PCRs of the TPM by the EventLog as the firmware would do.
+func boot(t *testing.T, rw io.ReadWriter, eventLog *tpmeventlog.TPMEventLog) {
+	for _, ev := range eventLog.Events {
+		if ev.Type == tpmeventlog.EV_NO_ACTION || ev.Digest.HashAlgo != tpm2.AlgSHA256 {
+			continue
+		}
+		require.NoError(t, tpm2.PCRExtend(rw, tpmutil.Handle(ev.PCRIndex), ev.Digest.HashAlgo, ev.Digest.Digest, ""))
+	}
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	process := newTestBootProcess(ctx, t)
+	tpmInstance, err := tpm.GetFrom(process.CurrentState)
+	require.NoError(t, err)
+	eventLog := tpmInstance.EventLog.TPMEventLog()
+
+	rw := simulator.New()
+	boot(t, rw, eventLog)
+	ak, akPub := newAK(t, rw, akTemplateRSA)
+	nonce := []byte("nonce")
+	attest, signature := quote(t, rw, ak, nonce, 0)
+	evidence := Evidence{
+		Attest:    attest,
+		Signature: signature,
+		AKPublic:  akPub,
+		EventLog:  eventLog,
+		Nonce:     nonce,
+	}
+
+	t.Run("trusted", func(t *testing.T) {
+		result, err := Verify(ctx, evidence, process)
+		require.NoError(t, err)
+		require.Empty(t, result.Reasons)
+		require.Equal(t, VerdictTrusted, result.Verdict)
+		require.Equal(t, readPCR(t, rw, 0), result.ReplayedPCRs[0])
+		require.Equal(t, result.ReplayedPCRs[0], result.ExpectedPCR0)
+		require.Empty(t, result.EventLogIssues)
+	})
+
+	t.Run("wrong_nonce", func(t *testing.T) {
+		evidence := evidence
+		evidence.Nonce = []byte("other")
+		result, err := Verify(ctx, evidence, process)
+		require.NoError(t, err)
+		require.Equal(t, VerdictUntrusted, result.Verdict)
+		require.Len(t, result.Reasons, 1)
+	})
+
+	t.Run("tampered_eventlog", func(t *testing.T) {
+		// the EventLog hides a measurement of an unexpected firmware
+		digest := sha256.Sum256([]byte("malicious firmware"))
+		rw := simulator.New()
+		boot(t, rw, eventLog)
+		require.NoError(t, tpm2.PCRExtend(rw, 0, tpm2.AlgSHA256, digest[:], ""))
+		ak, akPub := newAK(t, rw, akTemplateRSA)
+		attest, signature := quote(t, rw, ak, nonce, 0)
+
+		evidence := evidence
+		evidence.Attest, evidence.Signature, evidence.AKPublic = attest, signature, akPub
+		result, err := Verify(ctx, evidence, process)
+		require.NoError(t, err)
+		require.Equal(t, VerdictUntrusted, result.Verdict)
+		require.Len(t, result.Reasons, 1)
+		require.ErrorContains(t, result.Reasons[0], "the EventLog does not match the quote")
+	})
+
+	t.Run("other_firmware", func(t *testing.T) {
+		// the quote is consistent with the EventLog, but the firmware differs
+		otherEventLog := &tpmeventlog.TPMEventLog{}
+		for _, ev := range eventLog.Events {
+			ev := *ev
+			if ev.Type == tpmeventlog.EV_EFI_PLATFORM_FIRMWARE_BLOB2 {
+				digest := *ev.Digest
+				digest.Digest = make([]byte, len(digest.Digest))
+				ev.Digest = &digest
+			}
+			otherEventLog.Events = append(otherEventLog.Events, &ev)
+		}
+		rw := simulator.New()
+		boot(t, rw, otherEventLog)
+		ak, akPub := newAK(t, rw, akTemplateECC)
+		attest, signature := quote(t, rw, ak, nonce, 0)
+
+		result, err := Verify(ctx, Evidence{
+			Attest:    attest,
+			Signature: signature,
+			AKPublic:  akPub,
+			EventLog:  otherEventLog,
+			Nonce:     nonce,
+		}, process)
+		require.NoError(t, err)
+		require.Equal(t, VerdictUntrusted, result.Verdict)
+		require.Len(t, result.Reasons, 1)
+		require.ErrorContains(t, result.Reasons[0], "does not match the value")
+		require.NotEmpty(t, result.EventLogIssues)
+	})
+
+	t.Run("invalid_signature", func(t *testing.T) {
+		evidence := evidence
+		_, evidence.AKPublic = newAK(t, rw, akTemplateRSA)
+		result, err := Verify(ctx, evidence, process)
+		require.NoError(t, err)
+		require.Equal(t, VerdictUntrusted, result.Verdict)
+		require.Nil(t, result.Quote)
+	})
+}
@@ -46,8 +46,8 @@ func (ev *TPMEvent) Apply(ctx context.Context, state *types.State) error {
 	if err != nil {
 		return err
 	}
-	for _, hashAlgo := range tpm.SupportedHashAlgos() {
-		h, err := hashAlgo.Hash()
+	for _, hashAlgo := range t.SupportedAlgos {
+		h, err := pcr.Hash(hashAlgo)
 		if err != nil {
 			return fmt.Errorf("unable to get hasher factory for algo %v: %w", hashAlgo, err)
 		}
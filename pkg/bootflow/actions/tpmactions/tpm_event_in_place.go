@@ -0,0 +1,83 @@
+package tpmactions
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/types"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmeventlog"
+)
+
+// TPMEventInPlace is similar to TPMEvent, but the data is not hashed: it is
+// extended "in place" (padded with zeros up to the digest size).
+//
+// For example, this is how the ACM measures its date into a TPM1.2.
+type TPMEventInPlace struct {
+	DataSource types.DataSource
+	PCRIndex   pcr.ID
+	Type       tpmeventlog.EventType
+	EventData  []byte
+}
+
+var _ types.Action = (*TPMEventInPlace)(nil)
+
+// NewTPMEventInPlace returns a new instance of TPMEventInPlace.
+func NewTPMEventInPlace(
+	pcrIndex pcr.ID,
+	dataSource types.DataSource,
+	evType tpmeventlog.EventType,
+	eventData []byte,
+) *TPMEventInPlace {
+	return &TPMEventInPlace{
+		DataSource: dataSource,
+		PCRIndex:   pcrIndex,
+		Type:       evType,
+		EventData:  eventData,
+	}
+}
+
+// Apply implements types.Action.
+func (ev *TPMEventInPlace) Apply(ctx context.Context, state *types.State) error {
+	data, err := ev.DataSource.Data(ctx, state)
+	if err != nil {
+		return fmt.Errorf("unable to extract the data: %w", err)
+	}
+
+	t, err := tpm.GetFrom(state)
+	if err != nil {
+		return err
+	}
+	b := data.ConvertedBytes()
+	for _, hashAlgo := range t.SupportedAlgos {
+		h, err := pcr.Hash(hashAlgo)
+		if err != nil {
+			return fmt.Errorf("unable to get hasher factory for algo %v: %w", hashAlgo, err)
+		}
+		if len(b) > h.Size() {
+			return fmt.Errorf("the data is too long for algo %v: %d > %d", hashAlgo, len(b), h.Size())
+		}
+		digest := make([]byte, h.Size())
+		copy(digest, b)
+
+		if err := t.TPMExtend(ctx, ev.PCRIndex, hashAlgo, digest, NewLogInfoProvider(state)); err != nil {
+			return fmt.Errorf("unable to extend: %w", err)
+		}
+
+		if err := t.TPMEventLogAdd(ctx, ev.PCRIndex, hashAlgo, digest, ev.Type, ev.EventData, NewLogInfoProvider(state)); err != nil {
+			return fmt.Errorf("unable to add an entry to TPM EventLog: %w", err)
+		}
+	}
+
+	state.AddMeasuredData(*data, t, ev.DataSource)
+	return nil
+}
+
+// String implements fmt.Stringer.
+func (ev TPMEventInPlace) String() string {
+	if len(ev.EventData) == 0 {
+		return fmt.Sprintf("TPMEventInPlace(PCR: %d, DataSource: %v, Type: %s)", ev.PCRIndex, ev.DataSource, ev.Type)
+	}
+	return fmt.Sprintf("TPMEventInPlace(PCR: %d, DataSource: %v, Type: %s, EventData: %X)", ev.PCRIndex, ev.DataSource, ev.Type, ev.EventData)
+}
@@ -0,0 +1,196 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Persistent is implemented by keys of entries, which should be stored
+// by Disk on the disk (to be reused by other processes).
+//
+// Values of such entries should be encodable by "encoding/gob" and their
+// concrete types should be registered through gob.Register.
+type Persistent interface {
+	// PersistentKey returns the unique identifier of the entry, which
+	// is safe to be used as a file name.
+	PersistentKey() string
+}
+
+type diskEntry struct {
+	Value any
+}
+
+// Disk is an implementation of Cache, which stores entries with
+// Persistent keys in a directory, so that they survive the process.
+// All the entries (including Persistent ones) are also stored in
+// the wrapped in-memory Cache.
+//
+// Entries are stored in subdirectory "<tool>/<version>", so entries written
+// by other versions of the tool are never used (see also Prune), and the
+// directory could be shared with other tools.
+type Disk struct {
+	// Cache is the in-memory Cache used for all entries.
+	Cache Cache
+
+	// OnError (if not nil) is called on every failure to read or
+	// write an entry. Such failures are otherwise treated as cache misses.
+	OnError func(err error)
+
+	dir     string
+	tool    string
+	version string
+}
+
+var _ Cache = (*Disk)(nil)
+
+// NewDisk returns a new instance of Disk, which stores the entries
+// in directory "dir" for version "version" of tool "tool".
+//
+// If "mem" is nil, then entries are not cached in memory.
+func NewDisk(dir string, tool string, version string, mem Cache) *Disk {
+	if mem == nil {
+		mem = DummyCache{}
+	}
+	return &Disk{
+		Cache:   mem,
+		dir:     dir,
+		tool:    escapePathComponent(tool),
+		version: escapePathComponent(version),
+	}
+}
+
+// Get implements Cache.
+func (c *Disk) Get(k any) (any, bool) {
+	if v, ok := c.Cache.Get(k); ok {
+		return v, true
+	}
+	p, ok := k.(Persistent)
+	if !ok {
+		return nil, false
+	}
+
+	path := c.path(p)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			c.error(fmt.Errorf("unable to read cache entry '%s': %w", path, err))
+		}
+		return nil, false
+	}
+	var entry diskEntry
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&entry); err != nil {
+		c.error(fmt.Errorf("unable to decode cache entry '%s': %w", path, err))
+		_ = os.Remove(path)
+		return nil, false
+	}
+
+	c.Cache.Set(k, entry.Value)
+	return entry.Value, true
+}
+
+// Set implements Cache.
+func (c *Disk) Set(k, v any) {
+	c.Cache.Set(k, v)
+	p, ok := k.(Persistent)
+	if !ok {
+		return
+	}
+	if err := c.write(c.path(p), v); err != nil {
+		c.error(err)
+	}
+}
+
+// Reset implements Cache.
+//
+// It resets only the in-memory Cache, the entries stored
+// on the disk are kept (see Prune).
+func (c *Disk) Reset() {
+	c.Cache.Reset()
+}
+
+// Prune removes the entries stored by other versions of the tool.
+//
+// Only the subdirectory of the tool is pruned, the rest of the directory
+// is never touched.
+func (c *Disk) Prune() error {
+	toolDir := filepath.Join(c.dir, c.tool)
+	dirEntries, err := os.ReadDir(toolDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("unable to list directory '%s': %w", toolDir, err)
+	}
+
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() || dirEntry.Name() == c.version {
+			continue
+		}
+		path := filepath.Join(toolDir, dirEntry.Name())
+		if err := os.RemoveAll(path); err != nil {
+			return fmt.Errorf("unable to remove directory '%s': %w", path, err)
+		}
+	}
+	return nil
+}
+
+func (c *Disk) path(k Persistent) string {
+	return filepath.Join(c.dir, c.tool, c.version, escapePathComponent(k.PersistentKey()))
+}
+
+func (c *Disk) write(path string, v any) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(diskEntry{Value: v}); err != nil {
+		return fmt.Errorf("unable to encode cache entry '%s': %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("unable to create directory '%s': %w", dir, err)
+	}
+
+	// writing to a temporary file and renaming it, so that concurrent
+	// readers never observe a partially written entry.
+	f, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("unable to create a temporary file in '%s': %w", dir, err)
+	}
+	_, err = f.Write(buf.Bytes())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return fmt.Errorf("unable to write cache entry '%s': %w", path, err)
+	}
+	return nil
+}
+
+func (c *Disk) error(err error) {
+	if c.OnError != nil {
+		c.OnError(err)
+	}
+}
+
+func escapePathComponent(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case r == '-' || r == '_' || r == '.' || r == '+':
+			return r
+		}
+		return '_'
+	}, s)
+	if s == "" || s == "." || s == ".." {
+		s = "_" + s
+	}
+	return s
+}
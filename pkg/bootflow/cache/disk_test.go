@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testPersistentKey string
+
+func (k testPersistentKey) PersistentKey() string {
+	return string(k)
+}
+
+type testPersistentValue struct {
+	Names  []string
+	Ranges map[string]uint64
+}
+
+func init() {
+	gob.Register(&testPersistentValue{})
+}
+
+func TestDisk(t *testing.T) {
+	dir := t.TempDir()
+	value := &testPersistentValue{
+		Names:  []string{"a", "b"},
+		Ranges: map[string]uint64{"a": 1},
+	}
+
+	c := NewDisk(dir, "tool", "v1", NewLRU(0, 0, nil))
+	c.OnError = func(err error) { require.NoError(t, err) }
+	c.Set(testPersistentKey("key"), value)
+	c.Set("volatile", 1)
+
+	// a new process with the same version
+	c = NewDisk(dir, "tool", "v1", NewLRU(0, 0, nil))
+	c.OnError = func(err error) { require.NoError(t, err) }
+	v, ok := c.Get(testPersistentKey("key"))
+	require.True(t, ok)
+	require.Equal(t, value, v)
+	_, ok = c.Get("volatile")
+	require.False(t, ok)
+
+	// a new process with another version
+	c = NewDisk(dir, "tool", "v2", nil)
+	_, ok = c.Get(testPersistentKey("key"))
+	require.False(t, ok)
+
+	// directories not belonging to the tool are kept by Prune
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "another-tool", "v1"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "v1"), 0o755))
+	require.NoError(t, c.Prune())
+	_, err := os.Stat(filepath.Join(dir, "tool", "v1"))
+	require.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(dir, "another-tool", "v1"))
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(dir, "v1"))
+	require.NoError(t, err)
+}
+
+func TestDiskCorruptedEntry(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "tool", "v1"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tool", "v1", "key"), []byte("garbage"), 0o644))
+
+	var errs []error
+	c := NewDisk(dir, "tool", "v1", nil)
+	c.OnError = func(err error) { errs = append(errs, err) }
+	_, ok := c.Get(testPersistentKey("key"))
+	require.False(t, ok)
+	require.Len(t, errs, 1)
+	_, err := os.Stat(filepath.Join(dir, "tool", "v1", "key"))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestEscapePathComponent(t *testing.T) {
+	require.Equal(t, "v2.1.0-0.2024_abc", escapePathComponent("v2.1.0-0.2024/abc"))
+	require.Equal(t, "_..", escapePathComponent(".."))
+	require.Equal(t, "_", escapePathComponent(""))
+}
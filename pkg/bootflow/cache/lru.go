@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"container/list"
+	"reflect"
+	"sync"
+)
+
+const (
+	// DefaultMaxEntries is the default limit of entries of an LRU.
+	DefaultMaxEntries = 4096
+
+	// DefaultMaxBytes is the default limit of the approximate size of an LRU.
+	DefaultMaxBytes = 1 << 30
+)
+
+// SizeFunc returns the approximate amount of memory consumed by an entry.
+type SizeFunc func(k, v any) uint64
+
+// Stats is the statistics of a cache.
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Entries   int
+	Bytes     uint64
+}
+
+type lruEntry struct {
+	key   any
+	value any
+	size  uint64
+}
+
+// LRU is a concurrency-safe implementation of Cache, which evicts
+// the least recently used entries when either the amount of entries
+// or their approximate size exceeds the limit.
+//
+// Keys should be comparable, entries with non-comparable keys are
+// never cached.
+type LRU struct {
+	locker     sync.Mutex
+	maxEntries int
+	maxBytes   uint64
+	sizeFunc   SizeFunc
+	list       *list.List
+	items      map[any]*list.Element
+	bytes      uint64
+	stats      Stats
+}
+
+var _ Cache = (*LRU)(nil)
+
+// NewLRU returns a new instance of LRU.
+//
+// Zero maxEntries or maxBytes means no limit. If sizeFunc is nil,
+// then ApproximateSize is used to estimate the size of entries.
+func NewLRU(maxEntries int, maxBytes uint64, sizeFunc SizeFunc) *LRU {
+	if sizeFunc == nil {
+		sizeFunc = func(k, v any) uint64 {
+			return ApproximateSize(k) + ApproximateSize(v)
+		}
+	}
+	return &LRU{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		sizeFunc:   sizeFunc,
+		list:       list.New(),
+		items:      map[any]*list.Element{},
+	}
+}
+
+// Get implements Cache.
+func (c *LRU) Get(k any) (any, bool) {
+	if !isComparable(k) {
+		return nil, false
+	}
+
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	el, ok := c.items[k]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	c.stats.Hits++
+	c.list.MoveToFront(el)
+	return el.Value.(*lruEntry).value, true
+}
+
+// Set implements Cache.
+func (c *LRU) Set(k, v any) {
+	if !isComparable(k) {
+		return
+	}
+	size := c.sizeFunc(k, v)
+
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	if el, ok := c.items[k]; ok {
+		c.remove(el)
+	}
+	if c.maxBytes != 0 && size > c.maxBytes {
+		// would evict everything and still not fit
+		return
+	}
+	c.items[k] = c.list.PushFront(&lruEntry{key: k, value: v, size: size})
+	c.bytes += size
+	for c.overLimit() {
+		c.remove(c.list.Back())
+		c.stats.Evictions++
+	}
+}
+
+// Reset implements Cache.
+func (c *LRU) Reset() {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	c.list.Init()
+	c.items = map[any]*list.Element{}
+	c.bytes = 0
+}
+
+// Stats returns the hit/miss statistics and the current usage of the cache.
+func (c *LRU) Stats() Stats {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	stats := c.stats
+	stats.Entries = c.list.Len()
+	stats.Bytes = c.bytes
+	return stats
+}
+
+func (c *LRU) overLimit() bool {
+	if c.maxEntries != 0 && c.list.Len() > c.maxEntries {
+		return true
+	}
+	if c.maxBytes != 0 && c.bytes > c.maxBytes {
+		return true
+	}
+	return false
+}
+
+func (c *LRU) remove(el *list.Element) {
+	entry := c.list.Remove(el).(*lruEntry)
+	delete(c.items, entry.key)
+	c.bytes -= entry.size
+}
+
+func isComparable(k any) bool {
+	if k == nil {
+		return true
+	}
+	return reflect.ValueOf(k).Comparable()
+}
@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRUMaxEntries(t *testing.T) {
+	c := NewLRU(2, 0, nil)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	_, ok := c.Get("a")
+	require.True(t, ok)
+	c.Set("c", 3)
+
+	_, ok = c.Get("b")
+	require.False(t, ok)
+	v, ok := c.Get("a")
+	require.True(t, ok)
+	require.Equal(t, 1, v)
+	v, ok = c.Get("c")
+	require.True(t, ok)
+	require.Equal(t, 3, v)
+
+	stats := c.Stats()
+	require.Equal(t, uint64(3), stats.Hits)
+	require.Equal(t, uint64(1), stats.Misses)
+	require.Equal(t, uint64(1), stats.Evictions)
+	require.Equal(t, 2, stats.Entries)
+}
+
+func TestLRUMaxBytes(t *testing.T) {
+	c := NewLRU(0, 10, func(k, v any) uint64 {
+		return uint64(len(v.([]byte)))
+	})
+	c.Set(1, make([]byte, 4))
+	c.Set(2, make([]byte, 4))
+	c.Set(3, make([]byte, 4))
+	require.Equal(t, Stats{Evictions: 1, Entries: 2, Bytes: 8}, c.Stats())
+
+	// replacing an entry releases its size
+	c.Set(3, make([]byte, 6))
+	require.Equal(t, uint64(10), c.Stats().Bytes)
+
+	// too large to be cached at all
+	c.Set(4, make([]byte, 11))
+	_, ok := c.Get(4)
+	require.False(t, ok)
+	_, ok = c.Get(3)
+	require.True(t, ok)
+}
+
+func TestLRUNonComparableKey(t *testing.T) {
+	c := NewLRU(0, 0, nil)
+	key := struct{ s []byte }{}
+	c.Set(key, 1)
+	_, ok := c.Get(key)
+	require.False(t, ok)
+	require.Zero(t, c.Stats().Entries)
+}
+
+func TestLRUReset(t *testing.T) {
+	c := NewLRU(0, 0, nil)
+	c.Set("a", "b")
+	c.Reset()
+	_, ok := c.Get("a")
+	require.False(t, ok)
+	require.Zero(t, c.Stats().Bytes)
+}
+
+func TestLRUConcurrent(t *testing.T) {
+	c := NewLRU(16, 0, nil)
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for idx := 0; idx < 1000; idx++ {
+				key := fmt.Sprint(idx % 32)
+				if _, ok := c.Get(key); !ok {
+					c.Set(key, worker)
+				}
+			}
+		}(worker)
+	}
+	wg.Wait()
+	stats := c.Stats()
+	require.Equal(t, 16, stats.Entries)
+	require.Equal(t, uint64(8000), stats.Hits+stats.Misses)
+}
+
+func TestScoped(t *testing.T) {
+	c := NewLRU(0, 0, nil)
+	a, b := NewScoped(c, "a"), NewScoped(c, "b")
+	a.Set("key", 1)
+	b.Set("key", 2)
+
+	v, ok := a.Get("key")
+	require.True(t, ok)
+	require.Equal(t, 1, v)
+	v, ok = b.Get("key")
+	require.True(t, ok)
+	require.Equal(t, 2, v)
+	_, ok = c.Get("key")
+	require.False(t, ok)
+}
+
+func TestApproximateSize(t *testing.T) {
+	require.Zero(t, ApproximateSize(nil))
+	require.Equal(t, uint64(8), ApproximateSize(uint64(0)))
+	require.Equal(t, uint64(16+5), ApproximateSize("hello"))
+	require.Equal(t, uint64(24+10), ApproximateSize(make([]byte, 10, 100)))
+	require.Equal(t, uint64(24+2*16+3), ApproximateSize([]string{"a", "bc"}))
+	require.Equal(t, uint64(24+3*8), ApproximateSize([][8]byte{{}, {}, {}}))
+
+	type node struct {
+		Parent   *node
+		Children []*node
+		Data     []byte
+	}
+	root := &node{Data: make([]byte, 1000)}
+	root.Children = []*node{{Parent: root}, {Parent: root}}
+	size := ApproximateSize(root)
+	require.Greater(t, size, uint64(1000))
+	require.Less(t, size, uint64(2000))
+}
@@ -0,0 +1,45 @@
+package cache
+
+type scopedKey struct {
+	scope any
+	key   any
+}
+
+// Scoped is a Cache, which stores the entries in another Cache
+// isolated from the entries of other scopes.
+//
+// It allows to share one Cache between multiple independent consumers
+// (for example accessors of different firmware images), which use
+// the same keys.
+type Scoped struct {
+	Cache Cache
+	Scope any
+}
+
+var _ Cache = (*Scoped)(nil)
+
+// NewScoped returns a Cache, which stores the entries in the given
+// Cache within the given scope. The scope should be comparable.
+func NewScoped(cache Cache, scope any) *Scoped {
+	return &Scoped{
+		Cache: cache,
+		Scope: scope,
+	}
+}
+
+// Get implements Cache.
+func (c *Scoped) Get(k any) (any, bool) {
+	return c.Cache.Get(scopedKey{scope: c.Scope, key: k})
+}
+
+// Set implements Cache.
+func (c *Scoped) Set(k, v any) {
+	c.Cache.Set(scopedKey{scope: c.Scope, key: k}, v)
+}
+
+// Reset implements Cache.
+//
+// It resets the underlying Cache, including entries of other scopes.
+func (c *Scoped) Reset() {
+	c.Cache.Reset()
+}
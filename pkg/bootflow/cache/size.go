@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"reflect"
+)
+
+// ApproximateSize returns the approximate amount of memory consumed
+// by the value, including the memory referenced by it.
+//
+// Memory referenced multiple times is counted once. Memory referenced
+// through functions, channels and unsafe pointers is not counted.
+func ApproximateSize(v any) uint64 {
+	if v == nil {
+		return 0
+	}
+	s := sizer{
+		visited:     map[visitKey]struct{}{},
+		hasPointers: map[reflect.Type]bool{},
+	}
+	value := reflect.ValueOf(v)
+	return uint64(value.Type().Size()) + s.referenced(value)
+}
+
+type visitKey struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+type sizer struct {
+	visited     map[visitKey]struct{}
+	hasPointers map[reflect.Type]bool
+}
+
+func (s *sizer) visit(ptr uintptr, typ reflect.Type) bool {
+	key := visitKey{ptr: ptr, typ: typ}
+	if _, ok := s.visited[key]; ok {
+		return false
+	}
+	s.visited[key] = struct{}{}
+	return true
+}
+
+// mayReference returns false if values of the type can not reference
+// any memory outside of themselves, so they need not be traversed.
+func (s *sizer) mayReference(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.String, reflect.Slice, reflect.Map:
+		return true
+	case reflect.Array:
+		return typ.Len() > 0 && s.mayReference(typ.Elem())
+	case reflect.Struct:
+		if result, ok := s.hasPointers[typ]; ok {
+			return result
+		}
+		result := false
+		for idx := 0; idx < typ.NumField(); idx++ {
+			if s.mayReference(typ.Field(idx).Type) {
+				result = true
+				break
+			}
+		}
+		s.hasPointers[typ] = result
+		return result
+	}
+	return false
+}
+
+// referenced returns the size of the memory referenced by the value,
+// excluding the size of the value itself.
+func (s *sizer) referenced(v reflect.Value) uint64 {
+	if !s.mayReference(v.Type()) {
+		return 0
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() || !s.visit(v.Pointer(), v.Type()) {
+			return 0
+		}
+		elem := v.Elem()
+		return uint64(elem.Type().Size()) + s.referenced(elem)
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		elem := v.Elem()
+		return uint64(elem.Type().Size()) + s.referenced(elem)
+	case reflect.String:
+		return uint64(v.Len())
+	case reflect.Slice:
+		if v.IsNil() || !s.visit(v.Pointer(), v.Type()) {
+			return 0
+		}
+		result := uint64(v.Len()) * uint64(v.Type().Elem().Size())
+		if !s.mayReference(v.Type().Elem()) {
+			return result
+		}
+		for idx := 0; idx < v.Len(); idx++ {
+			result += s.referenced(v.Index(idx))
+		}
+		return result
+	case reflect.Array:
+		var result uint64
+		for idx := 0; idx < v.Len(); idx++ {
+			result += s.referenced(v.Index(idx))
+		}
+		return result
+	case reflect.Struct:
+		var result uint64
+		for idx := 0; idx < v.NumField(); idx++ {
+			result += s.referenced(v.Field(idx))
+		}
+		return result
+	case reflect.Map:
+		if v.IsNil() || !s.visit(v.Pointer(), v.Type()) {
+			return 0
+		}
+		entrySize := uint64(v.Type().Key().Size() + v.Type().Elem().Size())
+		result := uint64(v.Len()) * entrySize
+		if !s.mayReference(v.Type().Key()) && !s.mayReference(v.Type().Elem()) {
+			return result
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			result += s.referenced(iter.Key()) + s.referenced(iter.Value())
+		}
+		return result
+	}
+	return 0
+}
@@ -0,0 +1,31 @@
+package amdconds
+
+import (
+	"context"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/biosimage/accessor/amdbiosimage"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/types"
+	"github.com/facebookincubator/go-belt/tool/logger"
+)
+
+// LegacyPSPDirectory checks if the Embedded Firmware Structure points directly
+// to a PSP directory (instead of a PSP combo directory).
+type LegacyPSPDirectory struct{}
+
+var _ types.Condition = (*LegacyPSPDirectory)(nil)
+
+// Check implements types.Condition.
+func (LegacyPSPDirectory) Check(ctx context.Context, s *types.State) bool {
+	amdAccessor, err := amdbiosimage.Get(ctx, s)
+	if err != nil {
+		return false
+	}
+
+	layout, err := amdAccessor.PSPDirectoryLayout()
+	if err != nil {
+		logger.Debugf(ctx, "unable to detect the PSP directory layout: %v", err)
+		return false
+	}
+
+	return layout == amdbiosimage.PSPDirectoryLayoutLegacy
+}
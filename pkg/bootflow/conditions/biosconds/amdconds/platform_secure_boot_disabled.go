@@ -0,0 +1,29 @@
+package amdconds
+
+import (
+	"context"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/amdregisters"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/types"
+	"github.com/9elements/converged-security-suite/v2/pkg/registers"
+)
+
+// PlatformSecureBootDisabled checks if AMD Platform Secure Boot is
+// known to be disabled (according to register MP0_C2P_MSG_37).
+//
+// If PSB is disabled, then PSP does not measure anything and the TPM
+// is initialized from locality 0. If the register is not available,
+// then the condition is not satisfied.
+type PlatformSecureBootDisabled struct{}
+
+var _ types.Condition = (*PlatformSecureBootDisabled)(nil)
+
+// Check implements types.Condition.
+func (PlatformSecureBootDisabled) Check(_ context.Context, s *types.State) bool {
+	var reg registers.MP0C2PMsg37
+	if err := amdregisters.GetRegister(s, &reg); err != nil {
+		return false
+	}
+
+	return !reg.IsPlatformSecureBootEnabled()
+}
@@ -0,0 +1,34 @@
+package tpmconds
+
+import (
+	"context"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/types"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmeventlog"
+)
+
+// EventLogged checks if the TPM EventLog already has an event
+// of the specified type for the specified PCR.
+type EventLogged struct {
+	PCRIndex pcr.ID
+	Type     tpmeventlog.EventType
+}
+
+var _ types.Condition = (*EventLogged)(nil)
+
+// Check implements types.Condition.
+func (c EventLogged) Check(_ context.Context, s *types.State) bool {
+	t, err := tpm.GetFrom(s)
+	if err != nil {
+		return false
+	}
+
+	for _, ev := range t.EventLog {
+		if ev.PCRIndex == c.PCRIndex && ev.Type == c.Type {
+			return true
+		}
+	}
+	return false
+}
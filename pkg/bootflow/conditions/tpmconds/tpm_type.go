@@ -0,0 +1,26 @@
+package tpmconds
+
+import (
+	"context"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/types"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmdetection"
+)
+
+// TPMType checks if Trusted Platform Module is of the specified family (TPM1.2 or TPM2.0).
+type TPMType struct {
+	Type tpmdetection.Type
+}
+
+var _ types.Condition = (*TPMType)(nil)
+
+// Check implements types.Condition.
+func (cond TPMType) Check(_ context.Context, s *types.State) bool {
+	t, err := tpm.GetFrom(s)
+	if err != nil {
+		return false
+	}
+
+	return t.Type == cond.Type
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/biosimage"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/biosimage/accessor/amdbiosimage"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/types"
+	"github.com/linuxboot/fiano/pkg/amd/manifest"
 	pkgbytes "github.com/linuxboot/fiano/pkg/bytes"
 )
 
@@ -29,25 +30,29 @@ func (BIOSDirectory) Data(ctx context.Context, s *types.State) (*types.Data, err
 
 	pspFW := amdFW.PSPFirmware()
 
-	headerSizeL1 := uint64(binary.Size(pspFW.BIOSDirectoryLevel1.BIOSDirectoryTableHeader))
-	headerSizeL2 := uint64(binary.Size(pspFW.BIOSDirectoryLevel2.BIOSDirectoryTableHeader))
-	ranges := pkgbytes.Ranges{
-		pkgbytes.Range{
-			Offset: pspFW.BIOSDirectoryLevel1Range.Offset,
-			Length: headerSizeL1,
-		},
-		pkgbytes.Range{
-			Offset: pspFW.BIOSDirectoryLevel1Range.Offset + headerSizeL1,
-			Length: pspFW.BIOSDirectoryLevel1Range.Length - headerSizeL1,
-		},
-		pkgbytes.Range{
-			Offset: pspFW.BIOSDirectoryLevel2Range.Offset,
-			Length: headerSizeL2,
-		},
-		pkgbytes.Range{
-			Offset: pspFW.BIOSDirectoryLevel2Range.Offset + headerSizeL2,
-			Length: pspFW.BIOSDirectoryLevel2Range.Length - headerSizeL2,
-		},
+	// A legacy layout may have no BIOS directory of level 2.
+	var ranges pkgbytes.Ranges
+	for _, biosDirectory := range []struct {
+		Table *manifest.BIOSDirectoryTable
+		Range pkgbytes.Range
+	}{
+		{Table: pspFW.BIOSDirectoryLevel1, Range: pspFW.BIOSDirectoryLevel1Range},
+		{Table: pspFW.BIOSDirectoryLevel2, Range: pspFW.BIOSDirectoryLevel2Range},
+	} {
+		if biosDirectory.Table == nil {
+			continue
+		}
+		headerSize := uint64(binary.Size(biosDirectory.Table.BIOSDirectoryTableHeader))
+		ranges = append(ranges,
+			pkgbytes.Range{
+				Offset: biosDirectory.Range.Offset,
+				Length: headerSize,
+			},
+			pkgbytes.Range{
+				Offset: biosDirectory.Range.Offset + headerSize,
+				Length: biosDirectory.Range.Length - headerSize,
+			},
+		)
 	}
 	addrMapper := biosimage.PhysMemMapper{}
 	ranges = addrMapper.UnresolveFullImageOffset(amdAccessor.Image, ranges...)
@@ -28,9 +28,11 @@ func (PSPDirectory) Data(ctx context.Context, s *types.State) (*types.Data, erro
 
 	pspFW := amdFW.PSPFirmware()
 
-	ranges := pkgbytes.Ranges{
-		pspFW.PSPDirectoryLevel1Range,
-		pspFW.PSPDirectoryLevel2Range,
+	// A legacy layout (without a combo directory) may have no PSP
+	// directory of level 2.
+	ranges := pkgbytes.Ranges{pspFW.PSPDirectoryLevel1Range}
+	if pspFW.PSPDirectoryLevel2 != nil {
+		ranges = append(ranges, pspFW.PSPDirectoryLevel2Range)
 	}
 	addrMapper := biosimage.PhysMemMapper{}
 	ranges = addrMapper.UnresolveFullImageOffset(amdAccessor.Image, ranges...)
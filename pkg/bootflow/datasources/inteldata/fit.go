@@ -22,15 +22,17 @@ func (d FITFirst) Data(_ context.Context, state *types.State) (*types.Data, erro
 	if err != nil {
 		return nil, fmt.Errorf("unable to get BIOS image: %w", err)
 	}
-	fitEntries, err := fit.GetEntries(biosFW.Content)
+	// the FIT does not depend on the UEFI layout, so its errors are ignored
+	layout, _ := biosFW.Layout()
+	fitEntries, err := layout.FITEntries()
 	if err != nil {
-		return nil, fmt.Errorf("unable to parse FIT table: %w", err)
+		return nil, err
 	}
 
 	for _, fitEntry := range fitEntries {
-		if fitEntry.GetEntryBase().Headers.Type() == fit.EntryType(d) {
-			offset := fitEntry.GetEntryBase().Headers.Address.Pointer()
-			length := len(fitEntry.GetEntryBase().DataSegmentBytes)
+		if fitEntry.Type == fit.EntryType(d) {
+			offset := fitEntry.Address
+			length := fitEntry.DataSize
 
 			return types.NewData(&types.Reference{
 				Artifact: biosFW,
@@ -38,7 +40,7 @@ func (d FITFirst) Data(_ context.Context, state *types.State) (*types.Data, erro
 					AddressMapper: biosimage.PhysMemMapper{},
 					Ranges: pkgbytes.Ranges{{
 						Offset: offset,
-						Length: uint64(length),
+						Length: length,
 					}},
 				},
 			}), nil
@@ -65,9 +67,11 @@ func (d FITAll) Data(_ context.Context, state *types.State) (*types.Data, error)
 	if err != nil {
 		return nil, fmt.Errorf("unable to get BIOS image: %w", err)
 	}
-	fitEntries, err := fit.GetEntries(biosFW.Content)
+	// the FIT does not depend on the UEFI layout, so its errors are ignored
+	layout, _ := biosFW.Layout()
+	fitEntries, err := layout.FITEntries()
 	if err != nil {
-		return nil, fmt.Errorf("unable to parse FIT table: %w", err)
+		return nil, err
 	}
 
 	ref := &types.Reference{
@@ -78,13 +82,13 @@ func (d FITAll) Data(_ context.Context, state *types.State) (*types.Data, error)
 		},
 	}
 	for _, fitEntry := range fitEntries {
-		if fitEntry.GetEntryBase().Headers.Type() == fit.EntryType(d) {
-			offset := fitEntry.GetEntryBase().Headers.Address.Pointer()
-			length := len(fitEntry.GetEntryBase().DataSegmentBytes)
+		if fitEntry.Type == fit.EntryType(d) {
+			offset := fitEntry.Address
+			length := fitEntry.DataSize
 
 			ref.Ranges = append(ref.Ranges, pkgbytes.Range{
 				Offset: offset,
-				Length: uint64(length),
+				Length: length,
 			})
 		}
 	}
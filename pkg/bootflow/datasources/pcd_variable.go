@@ -2,12 +2,19 @@ package datasources
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/conditions/biosconds/ocpconds"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/biosimage"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/types"
+	"github.com/9elements/converged-security-suite/v2/pkg/pcd"
+	"github.com/9elements/converged-security-suite/v2/pkg/uefi/varstore"
+	"github.com/facebookincubator/go-belt/tool/logger"
 )
 
+// PCDVariable implements types.DataSource by referencing to the value
+// of a variable from the Platform Configuration Database (PCD).
 type PCDVariable string
 
 var _ types.DataSource = PCDVariable("")
@@ -22,7 +29,7 @@ func (d PCDVariable) Data(ctx context.Context, s *types.State) (*types.Data, err
 		case ocpconds.IsOCPv1{}.Check(ctx, s):
 			return types.NewData((ocpconds.IsOCPv1{}).FirmwareVendorVersion()), nil
 		default:
-			return nil, fmt.Errorf("no PCD parser is defined for this case")
+			return pcdFirmwareVendorVersion(ctx, s)
 		}
 	default:
 		return nil, fmt.Errorf("unknown PCD variable '%s'", string(d))
@@ -33,3 +40,51 @@ func (d PCDVariable) Data(ctx context.Context, s *types.State) (*types.Data, err
 func (d PCDVariable) String() string {
 	return fmt.Sprintf(`PCDVariable("%s")`, string(d))
 }
+
+func pcdFirmwareVendorVersion(ctx context.Context, s *types.State) (*types.Data, error) {
+	imgRaw, err := biosimage.Get(s)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get BIOS Firmware: %w", err)
+	}
+	layout, err := imgRaw.Layout()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the firmware image: %w", err)
+	}
+	pcdLayout := layout.PCD
+	if pcdLayout.Error != "" {
+		return nil, errors.New(pcdLayout.Error)
+	}
+
+	// OCP firmwares are already handled, so an AMI-based firmware here
+	// is not an OCP one. pcd.ParseFirmwareOCP would still recognize it
+	// and return the OCP default value, which is not valid for it.
+	if pcdLayout.IsAMI {
+		return nil, &pcd.ErrAMINotOCP{}
+	}
+
+	if !pcdLayout.Found {
+		// No known TCG PEI module (it is vendor-specific or it is in
+		// a volume we cannot parse), so using the default value.
+		// See PcdFirmwareVersionString in MdeModulePkg/MdeModulePkg.dec
+		logger.Debugf(ctx, "no PCD parser recognized the firmware, using the default PcdFirmwareVersionString")
+		return types.NewData(types.RawBytes(varstore.EncodeUCS2(""))), nil
+	}
+	if pcdLayout.Warning != "" {
+		logger.Debugf(ctx, "got a warning while parsing PCD: %s", pcdLayout.Warning)
+	}
+
+	ranges := pcdLayout.FirmwareVendorVersionRanges
+	if len(ranges) == 0 {
+		// the value is not stored in the image, using the default one
+		return types.NewData(types.RawBytes(pcdLayout.FirmwareVendorVersion)), nil
+	}
+
+	addrMapper := biosimage.PhysMemMapper{}
+	return types.NewData(&types.Reference{
+		Artifact: imgRaw,
+		MappedRanges: types.MappedRanges{
+			AddressMapper: addrMapper,
+			Ranges:        addrMapper.UnresolveFullImageOffset(imgRaw, ranges...),
+		},
+	}), nil
+}
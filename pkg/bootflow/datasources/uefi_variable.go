@@ -0,0 +1,156 @@
+package datasources
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/biosimage"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/biosimage/accessor/uefibiosimage"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/types"
+	"github.com/9elements/converged-security-suite/v2/pkg/uefi/varstore"
+	pkgbytes "github.com/linuxboot/fiano/pkg/bytes"
+	"github.com/linuxboot/fiano/pkg/guid"
+)
+
+// UEFIVariable implements types.DataSource by referencing to an UEFI variable
+// (stored in the non-volatile variable store of the image) in form of
+// UEFI_VARIABLE_DATA, which is how EDK2 measures variables
+// with event type EV_EFI_VARIABLE_DRIVER_CONFIG.
+//
+// If the variable is not found, then it is assumed to be empty (EDK2 measures
+// absent Secure Boot variables with zero data length).
+type UEFIVariable struct {
+	Name       string
+	VendorGUID guid.GUID
+}
+
+var _ types.DataSource = UEFIVariable{}
+
+// Data implements types.DataSource.
+func (ds UEFIVariable) Data(ctx context.Context, s *types.State) (*types.Data, error) {
+	a, err := uefibiosimage.Get(ctx, s)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get UEFI accessor: %w", err)
+	}
+	// corrupt stores are skipped, see varstore.FindStores
+	stores, storesErr := a.VariableStores()
+
+	v := stores.Find(ds.Name, ds.VendorGUID)
+	if v == nil {
+		if storesErr != nil {
+			return nil, fmt.Errorf("the variable is not found and some of the UEFI variable stores are corrupt: %w", storesErr)
+		}
+		return types.NewData(uefiVariableData(ds.Name, ds.VendorGUID, 0)), nil
+	}
+
+	imgRaw := a.Image
+	addrMapper := biosimage.PhysMemMapper{}
+	result := types.NewData(types.References{
+		*types.NewReference(uefiVariableData(ds.Name, ds.VendorGUID, uint64(len(v.Data)))),
+	})
+	if len(v.Data) > 0 {
+		result.References = append(result.References, types.Reference{
+			Artifact: imgRaw,
+			MappedRanges: types.MappedRanges{
+				AddressMapper: addrMapper,
+				Ranges: addrMapper.UnresolveFullImageOffset(imgRaw, pkgbytes.Range{
+					Offset: v.DataOffset,
+					Length: uint64(len(v.Data)),
+				}),
+			},
+		})
+	}
+	return result, nil
+}
+
+// String implements fmt.Stringer.
+func (ds UEFIVariable) String() string {
+	return fmt.Sprintf("UEFIVariable(%s:%s)", ds.VendorGUID, ds.Name)
+}
+
+// SecureBootVariable implements types.DataSource by providing the value
+// of the volatile UEFI variable "SecureBoot" in form of UEFI_VARIABLE_DATA.
+//
+// If the variable is stored in the image, then its value is used as is.
+// Otherwise it is calculated the way AuthVariableLib of EDK2 does:
+// Secure Boot is enabled if the platform is in user mode (variable "SetupMode"
+// is 0, or PK is enrolled if "SetupMode" is not stored) and it is not
+// disabled by variable "SecureBootEnable".
+type SecureBootVariable struct{}
+
+var _ types.DataSource = SecureBootVariable{}
+
+// Data implements types.DataSource.
+func (SecureBootVariable) Data(ctx context.Context, s *types.State) (*types.Data, error) {
+	a, err := uefibiosimage.Get(ctx, s)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get UEFI accessor: %w", err)
+	}
+	stores, err := a.VariableStores()
+	if err != nil && len(stores) == 0 {
+		return nil, fmt.Errorf("unable to parse the UEFI variable stores: %w", err)
+	}
+
+	value := secureBootValue(stores)
+	return types.NewData(append(uefiVariableData("SecureBoot", varstore.GUIDGlobalVariable, 1), value)), nil
+}
+
+// See AuthVariableLibInitialize of SecurityPkg/Library/AuthVariableLib/AuthVariableLib.c
+func secureBootValue(stores varstore.Stores) byte {
+	const (
+		userMode          = 0
+		setupMode         = 1
+		secureBootEnable  = 1
+		secureBootDisable = 0
+	)
+
+	if v := stores.Find("SecureBoot", varstore.GUIDGlobalVariable); v != nil && len(v.Data) > 0 {
+		return v.Data[0]
+	}
+
+	pkEnrolled := false
+	if pk := stores.Find("PK", varstore.GUIDGlobalVariable); pk != nil && len(pk.Data) > 0 {
+		pkEnrolled = true
+	}
+
+	mode := byte(setupMode)
+	if v := stores.Find("SetupMode", varstore.GUIDGlobalVariable); v != nil && len(v.Data) > 0 {
+		mode = v.Data[0]
+	} else if pkEnrolled {
+		mode = userMode
+	}
+
+	enable := byte(secureBootDisable)
+	if v := stores.Find("SecureBootEnable", varstore.GUIDSecureBootEnableDisable); v != nil && len(v.Data) > 0 {
+		enable = v.Data[0]
+	} else if pkEnrolled {
+		// EDK2 creates the variable as enabled if PK is enrolled
+		enable = secureBootEnable
+	}
+
+	if mode == userMode && enable == secureBootEnable {
+		return 1
+	}
+	return 0
+}
+
+// String implements fmt.Stringer.
+func (SecureBootVariable) String() string {
+	return "SecureBootVariable"
+}
+
+// uefiVariableData returns the header of UEFI_VARIABLE_DATA (everything,
+// but the variable data itself).
+func uefiVariableData(name string, vendorGUID guid.GUID, dataLength uint64) types.RawBytes {
+	// the name is measured without the terminating null character
+	nameEncoded := varstore.EncodeUCS2(name)
+	nameEncoded = nameEncoded[:len(nameEncoded)-2]
+
+	result := make([]byte, 0, len(vendorGUID)+8+8+len(nameEncoded))
+	result = append(result, vendorGUID[:]...)
+	result = binary.LittleEndian.AppendUint64(result, uint64(len(nameEncoded)/2))
+	result = binary.LittleEndian.AppendUint64(result, dataLength)
+	result = append(result, nameEncoded...)
+	return result
+}
@@ -2,6 +2,7 @@ package flows
 
 import (
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/actors/amdactors"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/conditions/biosconds/amdconds"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/conditions/commonconds"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/conditions/tpmconds"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/datasources"
@@ -14,29 +15,82 @@ import (
 
 // this flows were reconstructed by looking into TPM EventLog, so they might be wrong.
 
+// AMD selects the flow by the PSP directory layout: only Milan uses
+// the legacy layout (the Embedded Firmware Structure points directly
+// to a PSP directory).
 var AMD = NewFlow("AMD", types.Steps{
-	commonsteps.SetFlow(AMDGenoa),
+	commonsteps.If(amdconds.LegacyPSPDirectory{}, commonsteps.SetFlow(AMDMilan), commonsteps.SetFlow(AMDGenoa)),
 })
 
+// AMDMilan selects the Milan flow by the PSP directory layout and
+// by the Platform Secure Boot state (register MP0_C2P_MSG_37).
 var AMDMilan = NewFlow("AMDMilan", types.Steps{
-	commonsteps.SetFlow(AMDMilanLocality3),
+	commonsteps.If(
+		amdconds.LegacyPSPDirectory{},
+		commonsteps.If(amdconds.PlatformSecureBootDisabled{}, commonsteps.SetFlow(AMDMilanLegacyLocality0), commonsteps.SetFlow(AMDMilanLegacyLocality3)),
+		commonsteps.If(amdconds.PlatformSecureBootDisabled{}, commonsteps.SetFlow(AMDMilanLocality0), commonsteps.SetFlow(AMDMilanLocality3)),
+	),
 })
 
+// AMDMilanLegacyLocality0 is the flow of Milan with the legacy PSP directory
+// layout (there is no combo directory, and there are no directories of
+// level 2) and disabled PSB: PSP does not measure anything, and TPM is
+// initialized from locality 0.
 var AMDMilanLegacyLocality0 = NewFlow("AMDMilanLegacyLocality0", types.Steps{
-	commonsteps.Panic("this case is not implemented, yet"),
+	commonsteps.If(commonconds.Not(tpmconds.TPMIsInited{}), tpmsteps.InitTPM(0, false), nil),
+	amdsteps.MeasureMP0C2PMsgRegisters{},
+	amdsteps.MeasureEmbeddedFirmwareStructure{},
+	amdsteps.MeasureBIOSDirectory{},
+	amdsteps.MeasureBIOSStaticEntries{},
+	amdsteps.MeasurePMUFirmware{},
+	amdsteps.MeasureMicrocodePatch{},
+	amdsteps.MeasureVideoImageInterpreter{},
+	commonsteps.SetFlow(PEI),
 })
 
+// AMDMilanLegacyLocality3 is the flow of Milan with the legacy PSP directory
+// layout and enabled PSB: PSP initializes TPM from locality 3 and measures
+// its version and the BIOS RTM volume (from the BIOS directory of level 1).
 var AMDMilanLegacyLocality3 = NewFlow("AMDMilanLegacyLocality3", types.Steps{
-	commonsteps.Panic("this case is not implemented, yet"),
+	commonsteps.SetActor(amdactors.PSP{}),
+	amdsteps.VerifyPSPDirectory(AMDMilanLegacyVerificationFailure),
+	tpmsteps.InitTPM(3, true),
+	amdsteps.MeasurePSPVersion{},
+	amdsteps.MeasureBIOSRTMVolume{},
+	commonsteps.SetFlow(AMDMilanLegacyLocality0),
+})
+
+var AMDMilanLegacyVerificationFailure = NewFlow("AMDMilanLegacyVerificationFailure", types.Steps{
+	commonsteps.SetFlow(AMDMilanLegacyLocality0),
 })
 
+// AMDMilanLocality0 is the flow of Milan with the PSP combo directory
+// and disabled PSB.
 var AMDMilanLocality0 = NewFlow("AMDMilanLocality0", types.Steps{
-	commonsteps.Panic("this case is not implemented, yet"),
+	commonsteps.If(commonconds.Not(tpmconds.TPMIsInited{}), tpmsteps.InitTPM(0, false), nil),
+	amdsteps.MeasureMP0C2PMsgRegisters{},
+	amdsteps.MeasureEmbeddedFirmwareStructure{},
+	amdsteps.MeasureBIOSDirectory{},
+	amdsteps.MeasureBIOSStaticEntries{},
+	amdsteps.MeasurePMUFirmware{},
+	amdsteps.MeasureMicrocodePatch{},
+	amdsteps.MeasureVideoImageInterpreter{},
+	commonsteps.SetFlow(PEI),
 })
 
+// AMDMilanLocality3 is the flow of Milan with the PSP combo directory
+// and enabled PSB.
 var AMDMilanLocality3 = NewFlow("AMDMilanLocality3", types.Steps{
-	// temporary
-	commonsteps.SetFlow(AMDGenoaLocality3),
+	commonsteps.SetActor(amdactors.PSP{}),
+	amdsteps.VerifyPSPDirectory(AMDMilanVerificationFailure),
+	tpmsteps.InitTPM(3, true),
+	amdsteps.MeasurePSPVersion{},
+	amdsteps.MeasureBIOSRTMVolume{},
+	commonsteps.SetFlow(AMDMilanLocality0),
+})
+
+var AMDMilanVerificationFailure = NewFlow("AMDMilanVerificationFailure", types.Steps{
+	commonsteps.SetFlow(AMDMilanLocality0),
 })
 
 var AMDGenoa = NewFlow("AMDGenoa", types.Steps{
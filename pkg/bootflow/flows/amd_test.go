@@ -0,0 +1,64 @@
+package flows
+
+import (
+	"context"
+	"testing"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/bootengine"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/amdregisters"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/biosimage"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/types"
+	"github.com/9elements/converged-security-suite/v2/pkg/registers"
+	"github.com/9elements/converged-security-suite/v2/testdata/firmware"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAMDMilanFlowNames(t *testing.T) {
+	for _, name := range []string{
+		"AMDMilan",
+		"AMDMilanLegacyLocality0",
+		"AMDMilanLegacyLocality3",
+		"AMDMilanLocality0",
+		"AMDMilanLocality3",
+	} {
+		flow, ok := GetFlowByName(name)
+		require.True(t, ok, name)
+		require.Equal(t, name, flow.Name)
+	}
+}
+
+// nextFlow executes the first step of the flow and returns the flow
+// it switched to.
+func nextFlow(t *testing.T, flow types.Flow, regs registers.Registers) string {
+	state := types.NewState()
+	state.IncludeSubSystem(tpm.NewTPM())
+	// not an AMD image, so the PSP directory layout is not legacy
+	state.IncludeSystemArtifact(biosimage.New(firmware.FakeIntelFirmware))
+	state.IncludeSystemArtifact(amdregisters.New(regs))
+	state.SetFlow(flow)
+	process := bootengine.NewBootProcess(state)
+	require.True(t, process.NextStep(context.Background()))
+	require.NoError(t, process.Log.Error())
+	return state.CurrentActionCoordinates.Flow.Name
+}
+
+func TestAMDMilanSelection(t *testing.T) {
+	const (
+		psbEnabled  = 0x110000AD
+		psbDisabled = 0x100000AD
+	)
+	require.True(t, registers.ParseMP0C2PMsg37Register(psbEnabled).IsPlatformSecureBootEnabled())
+	require.False(t, registers.ParseMP0C2PMsg37Register(psbDisabled).IsPlatformSecureBootEnabled())
+
+	require.Equal(t, "AMDMilanLocality3", nextFlow(t, AMDMilan, registers.Registers{registers.ParseMP0C2PMsg37Register(psbEnabled)}))
+	require.Equal(t, "AMDMilanLocality0", nextFlow(t, AMDMilan, registers.Registers{registers.ParseMP0C2PMsg37Register(psbDisabled)}))
+
+	// without the register the PSB state is unknown, assuming PSP measures the firmware
+	require.Equal(t, "AMDMilanLocality3", nextFlow(t, AMDMilan, nil))
+}
+
+func TestAMDSelection(t *testing.T) {
+	// the PSB state does not affect the choice between Milan and Genoa
+	require.Equal(t, "AMDGenoa", nextFlow(t, AMD, registers.Registers{registers.ParseMP0C2PMsg37Register(0x100000AD)}))
+}
@@ -2,11 +2,54 @@ package flows
 
 import (
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/actors"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/conditions/tpmconds"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/datasources"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/steps/commonsteps"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/steps/tpmsteps"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/types"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmeventlog"
+	"github.com/9elements/converged-security-suite/v2/pkg/uefi/varstore"
+	"github.com/linuxboot/fiano/pkg/uefi"
 )
 
-// DXE represents the steps of the DXE
+// DXE represents the steps of the DXE (as it is implemented in Tcg2Dxe of EDK2).
 var DXE = NewFlow("DXE", types.Steps{
 	commonsteps.SetActor(actors.DXE{}),
+	commonsteps.If(tpmconds.TPMIsInited{}, nil, tpmsteps.InitTPM(0, false)),
+	// the volumes already measured in PEI are skipped by MeasureFirmwareVolumes
+	tpmsteps.MeasureFirmwareVolumes(0, datasources.VolumeOf(dxeFiles)),
+	commonsteps.MergeSteps(dxeSecureBootMeasurements),
+	commonsteps.MergeSteps(dxeSeparators),
 })
+
+var dxeFiles = datasources.UEFIFilesByType{
+	uefi.FVFileTypeDXECore,
+	uefi.FVFileTypeDriver,
+	uefi.FVFileTypeApplication,
+	uefi.FVFileTypeCombinedSMMDXE,
+	uefi.FVFileTypeSMMCore,
+}
+
+// see MeasureAllSecureVariables of SecurityPkg/Tcg/Tcg2Dxe/Tcg2Dxe.c
+var dxeSecureBootMeasurements = types.Steps{
+	tpmsteps.MeasureUEFIVariable(7, tpmeventlog.EV_EFI_VARIABLE_DRIVER_CONFIG, datasources.SecureBootVariable{}),
+	tpmsteps.MeasureUEFIVariable(7, tpmeventlog.EV_EFI_VARIABLE_DRIVER_CONFIG, datasources.UEFIVariable{Name: "PK", VendorGUID: varstore.GUIDGlobalVariable}),
+	tpmsteps.MeasureUEFIVariable(7, tpmeventlog.EV_EFI_VARIABLE_DRIVER_CONFIG, datasources.UEFIVariable{Name: "KEK", VendorGUID: varstore.GUIDGlobalVariable}),
+	tpmsteps.MeasureUEFIVariable(7, tpmeventlog.EV_EFI_VARIABLE_DRIVER_CONFIG, datasources.UEFIVariable{Name: "db", VendorGUID: varstore.GUIDImageSecurityDatabase}),
+	tpmsteps.MeasureUEFIVariable(7, tpmeventlog.EV_EFI_VARIABLE_DRIVER_CONFIG, datasources.UEFIVariable{Name: "dbx", VendorGUID: varstore.GUIDImageSecurityDatabase}),
+	tpmsteps.Measure(7, tpmeventlog.EV_SEPARATOR, datasources.Bytes{0, 0, 0, 0}),
+}
+
+// see MeasureSeparatorEvent of SecurityPkg/Tcg/Tcg2Dxe/Tcg2Dxe.c
+var dxeSeparators = func() types.Steps {
+	var result types.Steps
+	for pcrIndex := pcr.ID(0); pcrIndex < 7; pcrIndex++ {
+		result = append(result, commonsteps.If(
+			tpmconds.EventLogged{PCRIndex: pcrIndex, Type: tpmeventlog.EV_SEPARATOR},
+			nil,
+			tpmsteps.Measure(pcrIndex, tpmeventlog.EV_SEPARATOR, datasources.Bytes{0, 0, 0, 0}),
+		))
+	}
+	return result
+}()
@@ -0,0 +1,100 @@
+package flows
+
+import (
+	"context"
+	"testing"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/bootengine"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/datasources"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/steps/commonsteps"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/steps/tpmsteps"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/biosimage"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/types"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmeventlog"
+	"github.com/9elements/converged-security-suite/v2/testdata/firmware"
+	"github.com/stretchr/testify/require"
+)
+
+const testImagePath = `../../../testdata/firmware/GALAGOPRO3.fd.xz`
+
+func runFlow(t *testing.T, image []byte, flow types.Flow) tpm.EventLog {
+	tpmInstance := tpm.NewTPM()
+	state := types.NewState()
+	state.IncludeSubSystem(tpmInstance)
+	state.IncludeSystemArtifact(biosimage.New(image))
+	state.SetFlow(flow)
+	process := bootengine.NewBootProcess(state)
+	process.Finish(context.Background())
+	require.NoError(t, process.Log.Error())
+	return tpmInstance.EventLog
+}
+
+// firmwareVolumeEvents returns the event data of the PCR0 firmware volume
+// measurements (one per event, regardless of the amount of hash algorithms)
+// logged after the first PCR0 event of type startAfter.
+func firmwareVolumeEvents(log tpm.EventLog, startAfter tpmeventlog.EventType) [][]byte {
+	var result [][]byte
+	started := startAfter == 0
+	for _, entry := range log {
+		if entry.PCRIndex != 0 {
+			continue
+		}
+		if !started {
+			started = entry.Type == startAfter
+			continue
+		}
+		switch entry.Type {
+		case tpmeventlog.EV_EFI_PLATFORM_FIRMWARE_BLOB, tpmeventlog.EV_EFI_PLATFORM_FIRMWARE_BLOB2:
+		default:
+			continue
+		}
+		if len(result) > 0 && string(result[len(result)-1]) == string(entry.Data) {
+			continue
+		}
+		result = append(result, entry.Data)
+	}
+	return result
+}
+
+func TestDXEMeasuresFirmwareVolumes(t *testing.T) {
+	image, err := firmware.GetTestImage(testImagePath)
+	require.NoError(t, err)
+
+	// a PEI which closes PCR0 without measuring the DXE volumes
+	log := runFlow(t, image, types.Flow{
+		Name: "test",
+		Steps: types.Steps{
+			tpmsteps.InitTPM(0, false),
+			tpmsteps.Measure(0, tpmeventlog.EV_SEPARATOR, datasources.Bytes{0, 0, 0, 0}),
+			commonsteps.SetFlow(DXE),
+		},
+	})
+	require.NotEmpty(t, firmwareVolumeEvents(log, tpmeventlog.EV_SEPARATOR))
+}
+
+func TestDXESkipsVolumesMeasuredInPEI(t *testing.T) {
+	image, err := firmware.GetTestImage(testImagePath)
+	require.NoError(t, err)
+
+	// a PEI which measures the DXE volumes (like EDK2PEI does)
+	log := runFlow(t, image, types.Flow{
+		Name: "test",
+		Steps: types.Steps{
+			tpmsteps.InitTPM(0, false),
+			tpmsteps.MeasureFirmwareVolumes(0, datasources.VolumeOf(dxeFiles)),
+			tpmsteps.Measure(0, tpmeventlog.EV_SEPARATOR, datasources.Bytes{0, 0, 0, 0}),
+			commonsteps.SetFlow(DXE),
+		},
+	})
+	events := firmwareVolumeEvents(log, 0)
+	require.NotEmpty(t, events)
+	require.Empty(t, firmwareVolumeEvents(log, tpmeventlog.EV_SEPARATOR))
+
+	seen := map[string]struct{}{}
+	for _, ev := range events {
+		_, ok := seen[string(ev)]
+		require.False(t, ok, "the volume is measured twice: %X", ev)
+		seen[string(ev)] = struct{}{}
+	}
+}
@@ -0,0 +1,32 @@
+package flows
+
+import (
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/actors"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/conditions/tpmconds"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/datasources"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/steps/commonsteps"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/steps/tpmsteps"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/types"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmeventlog"
+	"github.com/linuxboot/fiano/pkg/uefi"
+)
+
+// EDK2PEI represents the steps of a generic (non-OCP) PEI phase, as
+// it is implemented in Tcg2Pei of EDK2:
+// * MeasureCRTMVersion: the S-CRTM version from PcdFirmwareVersionString;
+// * MeasureMainBios and FirmwareVolumeInfoPpiNotifyCallback: the
+// boot firmware volume and the firmware volumes reported by FV HOBs
+// (FV_MAIN with the DXE volume).
+var EDK2PEI = NewFlow("EDK2PEI", types.Steps{
+	commonsteps.SetActor(actors.PEI{}),
+	commonsteps.If(tpmconds.TPMIsInited{}, nil, tpmsteps.InitTPM(0, false)),
+	tpmsteps.Measure(0, tpmeventlog.EV_S_CRTM_VERSION, datasources.PCDVariable("FirmwareVendorVersion")),
+	tpmsteps.MeasureFirmwareVolumes(0, datasources.VolumeOf(datasources.UEFIFilesByType{
+		uefi.FVFileTypePEICore,
+		uefi.FVFileTypePEIM,
+		uefi.FVFileTypeCombinedPEIMDriver,
+	})),
+	tpmsteps.MeasureFirmwareVolumes(0, datasources.VolumeOf(dxeFiles)),
+	tpmsteps.Measure(0, tpmeventlog.EV_SEPARATOR, datasources.Bytes{0, 0, 0, 0}),
+	commonsteps.SetFlow(DXE),
+})
@@ -0,0 +1,57 @@
+package flows
+
+import (
+	"testing"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmeventlog"
+	"github.com/9elements/converged-security-suite/v2/testdata/firmware"
+	"github.com/stretchr/testify/require"
+)
+
+// eventTypes returns the types of the events of a PCR (one per event,
+// regardless of the amount of hash algorithms).
+func eventTypes(log tpm.EventLog, pcrIndex tpm.PCRID) []tpmeventlog.EventType {
+	var (
+		result   []tpmeventlog.EventType
+		lastData []byte
+	)
+	for _, entry := range log {
+		if entry.PCRIndex != pcrIndex {
+			continue
+		}
+		if len(result) > 0 && result[len(result)-1] == entry.Type && string(lastData) == string(entry.Data) {
+			continue
+		}
+		result = append(result, entry.Type)
+		lastData = entry.Data
+	}
+	return result
+}
+
+func TestEDK2PEI(t *testing.T) {
+	image, err := firmware.GetTestImage(testImagePath)
+	require.NoError(t, err)
+
+	pcr0 := eventTypes(runFlow(t, image, EDK2PEI), 0)
+	require.GreaterOrEqual(t, len(pcr0), 3)
+	require.Equal(t, tpmeventlog.EV_S_CRTM_VERSION, pcr0[0])
+	for _, evType := range pcr0[1 : len(pcr0)-1] {
+		require.Contains(t, []tpmeventlog.EventType{
+			tpmeventlog.EV_EFI_PLATFORM_FIRMWARE_BLOB,
+			tpmeventlog.EV_EFI_PLATFORM_FIRMWARE_BLOB2,
+		}, evType)
+	}
+	require.Equal(t, tpmeventlog.EV_SEPARATOR, pcr0[len(pcr0)-1])
+}
+
+func TestPEIFallsBackToEDK2PEI(t *testing.T) {
+	image, err := firmware.GetTestImage(testImagePath)
+	require.NoError(t, err)
+
+	// the image is not OCP
+	require.Equal(t,
+		eventTypes(runFlow(t, image, EDK2PEI), 0),
+		eventTypes(runFlow(t, image, PEI), 0),
+	)
+}
@@ -3,17 +3,20 @@ package flows
 import (
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/actors/intelactors"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/conditions/biosconds/intelconds"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/conditions/tpmconds"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/datasources"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/steps/commonsteps"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/steps/intelsteps"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/steps/tpmsteps"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/types"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmdetection"
 	"github.com/9elements/converged-security-suite/v2/pkg/tpmeventlog"
 	"github.com/linuxboot/fiano/pkg/intel/metadata/fit"
 )
 
 var Intel = NewFlow("Intel", types.Steps{
 	commonsteps.If(intelconds.BPMPresent{}, commonsteps.SetFlow(IntelCBnT), nil),
+	commonsteps.If(tpmconds.TPMType{Type: tpmdetection.TypeTPM12}, commonsteps.SetFlow(IntelLegacyTXTEnabledTPM12), nil),
 	commonsteps.SetFlow(IntelLegacyTXTEnabled),
 })
 
@@ -44,8 +47,18 @@ var IntelLegacyTXTEnabled = NewFlow("IntelLegacyTXTEnabled", types.Steps{
 	commonsteps.SetFlow(PEI),
 })
 
+// IntelLegacyTXTEnabledTPM12 is the same as IntelLegacyTXTEnabled, but
+// for a TPM1.2: there is only the SHA1 bank, the TPM initialization does not
+// affect PCR0 (thus no "StartupLocality" event) and the ACM date is not hashed.
 var IntelLegacyTXTEnabledTPM12 = NewFlow("IntelLegacyTXTEnabledTPM12", types.Steps{
-	commonsteps.Panic("legacy TXT flow for TPM1.2 is not implemented"),
+	commonsteps.SetActor(intelactors.PCH{}),
+	intelsteps.VerifyACM(IntelLegacyTXTDisabled),
+	commonsteps.SetActor(intelactors.ACM{}),
+	tpmsteps.InitTPM(3, false),
+	intelsteps.MeasureACMDateInPlace{},
+	intelsteps.MeasureFITData(fit.EntryTypeBIOSStartupModuleEntry),
+	tpmsteps.Measure(0, tpmeventlog.EV_SEPARATOR, datasources.Bytes{0, 0, 0, 0}),
+	commonsteps.SetFlow(PEI),
 })
 
 var IntelLegacyTXTDisabled = NewFlow("IntelLegacyTXTDisabled", types.Steps{
@@ -0,0 +1,62 @@
+package flows
+
+import (
+	"context"
+	"testing"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/bootengine"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/intelpch"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/biosimage"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/txtpublic"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/types"
+	"github.com/9elements/converged-security-suite/v2/pkg/registers"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmdetection"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmeventlog"
+	"github.com/9elements/converged-security-suite/v2/testdata/firmware"
+	"github.com/google/go-tpm/legacy/tpm2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntelLegacyTXTEnabledTPM12(t *testing.T) {
+	tpmInstance := tpm.NewTPMOfType(tpmdetection.TypeTPM12)
+	state := types.NewState()
+	state.IncludeSubSystem(tpmInstance)
+	state.IncludeSubSystem(intelpch.NewPCH())
+	state.IncludeSystemArtifact(biosimage.New(firmware.FakeIntelFirmware))
+	state.IncludeSystemArtifact(&txtpublic.TXTPublic{
+		Registers: registers.Registers{registers.ParseACMPolicyStatusRegister(0x0000000200108681)},
+	})
+	// only the SEC part of the flow, the image has no PEI to simulate
+	steps := IntelLegacyTXTEnabledTPM12.Steps
+	state.SetFlow(types.Flow{Name: "test", Steps: steps[:len(steps)-1]})
+	process := bootengine.NewBootProcess(state)
+	process.Finish(context.Background())
+	require.NoError(t, process.Log.Error())
+
+	require.Equal(t, []tpm.Algorithm{tpm2.AlgSHA1}, tpmInstance.SupportedAlgos)
+	for _, entry := range tpmInstance.EventLog {
+		require.Equal(t, tpm2.AlgSHA1, entry.HashAlgo)
+	}
+
+	// no StartupLocality event, the ACM date and the IBB, then the separator
+	require.Equal(t, []tpmeventlog.EventType{
+		tpmeventlog.EV_S_CRTM_CONTENTS,
+		tpmeventlog.EV_S_CRTM_CONTENTS,
+		tpmeventlog.EV_SEPARATOR,
+	}, eventTypes(tpmInstance.EventLog, 0))
+
+	// the ACM date (4 bytes) is extended as is
+	acmDate := tpmInstance.EventLog[0]
+	require.Equal(t, []byte("ACM_date"), acmDate.Data)
+	require.Len(t, acmDate.Digest, 20)
+	require.NotEqual(t, make([]byte, 4), []byte(acmDate.Digest[:4]))
+	require.Equal(t, make([]byte, 16), []byte(acmDate.Digest[4:]))
+
+	// PCR0 of a TPM1.2 starts from zeros regardless of the locality
+	replayed, err := tpmeventlog.Replay(tpmInstance.EventLog.TPMEventLog(), 0, tpm2.AlgSHA1, nil)
+	require.NoError(t, err)
+	pcr0, err := tpmInstance.PCRValues.Get(0, tpm2.AlgSHA1)
+	require.NoError(t, err)
+	require.Equal(t, replayed, []byte(pcr0))
+}
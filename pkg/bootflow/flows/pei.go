@@ -7,9 +7,12 @@ import (
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/types"
 )
 
+// PEI selects the PEI flow by the firmware: the OCP ones are recognized
+// explicitly, any other firmware is assumed to measure the way Tcg2Pei
+// of EDK2 does.
 var PEI = NewFlow("PEI", types.Steps{
 	commonsteps.SetActor(actors.Unknown{}),
 	commonsteps.If(ocpconds.IsOCPv0{}, commonsteps.SetFlow(OCPPEI), nil),
 	commonsteps.If(ocpconds.IsOCPv1{}, commonsteps.SetFlow(OCPPEI), nil),
-	commonsteps.Panic("unknown flow: is not OCP"),
+	commonsteps.SetFlow(EDK2PEI),
 })
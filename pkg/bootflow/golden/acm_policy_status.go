@@ -0,0 +1,165 @@
+package golden
+
+import (
+	"context"
+
+	"github.com/facebookincubator/go-belt/tool/logger"
+	key "github.com/linuxboot/fiano/pkg/intel/metadata/cbnt/keymanifest"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/bootengine"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/biosimage"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/biosimage/accessor/intelbiosimage"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/txtpublic"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/types"
+	"github.com/9elements/converged-security-suite/v2/pkg/registers"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmdetection"
+)
+
+// Bits of ACM_POLICY_STATUS, see the getters of registers.ACMPolicyStatus.
+const (
+	acmPolicyStatusBootPolicyM         = 1 << 4
+	acmPolicyStatusBootPolicyV         = 1 << 5
+	acmPolicyStatusBootPolicyT         = 1 << 7
+	acmPolicyStatusTPMTypeShift        = 13
+	acmPolicyStatusTPMSuccess          = 1 << 15
+	acmPolicyStatusSCRTMStatusShift    = 32
+	acmPolicyStatusTPMStartupLocality0 = 1 << 36
+)
+
+// GeneratedACMPolicyStatuses returns the plausible values of the Intel
+// ACM_POLICY_STATUS register of a machine, which successfully booted
+// the image through Boot Guard:
+//
+//   - KMID is the ID of the Key Manifest of the image (zero if there is none);
+//   - the Boot Guard profile is "measured", "verified" or "verified and measured";
+//   - TXT is supported or not;
+//   - the TPM is of the given type and was initialized successfully
+//     (a TPM2.0 could be either a discrete TPM or Intel PTT);
+//   - S-CRTM is Boot Guard;
+//   - the TPM startup locality is 3 or 0.
+func GeneratedACMPolicyStatuses(ctx context.Context, image []byte, tpmType tpmdetection.Type) []registers.ACMPolicyStatus {
+	return generatedACMPolicyStatuses(ctx, newBIOSImage(ctx, image), tpmType)
+}
+
+func generatedACMPolicyStatuses(ctx context.Context, img *biosimage.BIOSImage, tpmType tpmdetection.Type) []registers.ACMPolicyStatus {
+	var tpmTypes []registers.TPMType
+	switch tpmType {
+	case tpmdetection.TypeTPM12:
+		tpmTypes = []registers.TPMType{registers.TPMType12}
+	case tpmdetection.TypeTPM20:
+		tpmTypes = []registers.TPMType{registers.TPMType20, registers.TPMTypeIntelPTT}
+	default:
+		return nil
+	}
+
+	base := registers.ACMPolicyStatus(kmID(ctx, img)) |
+		acmPolicyStatusTPMSuccess |
+		registers.ACMPolicyStatus(registers.SCRTMStatusBtG)<<acmPolicyStatusSCRTMStatusShift
+
+	var result []registers.ACMPolicyStatus
+	for _, profile := range []registers.ACMPolicyStatus{
+		acmPolicyStatusBootPolicyM,
+		acmPolicyStatusBootPolicyV,
+		acmPolicyStatusBootPolicyM | acmPolicyStatusBootPolicyV,
+	} {
+		for _, txt := range []registers.ACMPolicyStatus{0, acmPolicyStatusBootPolicyT} {
+			for _, tpmType := range tpmTypes {
+				for _, locality := range []registers.ACMPolicyStatus{0, acmPolicyStatusTPMStartupLocality0} {
+					result = append(result, base|profile|txt|registers.ACMPolicyStatus(tpmType)<<acmPolicyStatusTPMTypeShift|locality)
+				}
+			}
+		}
+	}
+	return result
+}
+
+// kmID returns the KMID of the Key Manifest of the image, or zero
+// if the image has no Key Manifest.
+func kmID(ctx context.Context, img *biosimage.BIOSImage) uint8 {
+	state := types.NewState()
+	state.IncludeSystemArtifact(img)
+	intelFW, err := intelbiosimage.Get(ctx, state)
+	if err != nil {
+		logger.Debugf(ctx, "unable to get Intel-specific data accessor: %v", err)
+		return 0
+	}
+	keyManifest, _, err := intelFW.KeyManifest()
+	if err != nil {
+		logger.Debugf(ctx, "unable to get the Key Manifest: %v", err)
+		return 0
+	}
+	switch km := (*keyManifest).(type) {
+	case *key.BGManifest:
+		return uint8(km.KMID)
+	case *key.CBnTManifest:
+		return uint8(km.KMID)
+	}
+	return 0
+}
+
+// acmPolicyStatusVariants returns the value of ACM_POLICY_STATUS from the
+// registers followed by the additional and the generated values without
+// duplicates. A nil item means the register is not set.
+func acmPolicyStatusVariants(ctx context.Context, img *biosimage.BIOSImage, settings Settings) []*registers.ACMPolicyStatus {
+	var result []*registers.ACMPolicyStatus
+	seen := map[registers.ACMPolicyStatus]struct{}{}
+	add := func(value registers.ACMPolicyStatus) {
+		if _, ok := seen[value]; ok {
+			return
+		}
+		seen[value] = struct{}{}
+		result = append(result, &value)
+	}
+	if reg, ok := settings.Registers.Find(registers.AcmPolicyStatusRegisterID).(registers.ACMPolicyStatus); ok {
+		add(reg)
+	}
+	for _, value := range settings.ACMPolicyStatuses {
+		add(value)
+	}
+	if settings.GenerateACMPolicyStatuses {
+		for _, value := range generatedACMPolicyStatuses(ctx, img, settings.TPMType) {
+			add(value)
+		}
+	}
+	if len(result) == 0 {
+		result = append(result, nil)
+	}
+	return result
+}
+
+// withACMPolicyStatus returns a copy of the registers with
+// the ACM_POLICY_STATUS register replaced by the given value.
+func withACMPolicyStatus(regs registers.Registers, value *registers.ACMPolicyStatus) registers.Registers {
+	if value == nil {
+		return regs
+	}
+	result := make(registers.Registers, 0, len(regs)+1)
+	for _, reg := range regs {
+		if reg.ID() != registers.AcmPolicyStatusRegisterID {
+			result = append(result, reg)
+		}
+	}
+	return append(result, *value)
+}
+
+// measuresACMPolicyStatus returns true if the ACM_POLICY_STATUS register
+// was measured by the boot process. The register does not affect the
+// flows otherwise, so its other values need not to be tried.
+func measuresACMPolicyStatus(process *bootengine.BootProcess) bool {
+	txtPublic, err := txtpublic.Get(process.CurrentState)
+	if err != nil {
+		return false
+	}
+	regStart := uint64(registers.ACMPolicyStatusRegisterOffset)
+	regEnd := regStart + uint64(registers.ACMPolicyStatus(0).BitSize()/8)
+	for _, m := range process.CurrentState.MeasuredData {
+		for _, ref := range m.BySystemArtifact(txtPublic) {
+			for _, r := range ref.Ranges {
+				if r.Offset < regEnd && r.Offset+r.Length > regStart {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package golden
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/biosimage"
+	"github.com/9elements/converged-security-suite/v2/pkg/registers"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmdetection"
+	"github.com/9elements/converged-security-suite/v2/testdata/firmware"
+)
+
+func TestGeneratedACMPolicyStatuses(t *testing.T) {
+	ctx := context.Background()
+
+	values := GeneratedACMPolicyStatuses(ctx, firmware.FakeIntelFirmware, tpmdetection.TypeTPM20)
+	require.Len(t, values, 24)
+	unique := map[registers.ACMPolicyStatus]struct{}{}
+	for _, value := range values {
+		unique[value] = struct{}{}
+		require.True(t, value.BootPolicyM() || value.BootPolicyV(), "%016X", uint64(value))
+		require.True(t, value.TPMSuccess(), "%016X", uint64(value))
+		require.Equal(t, registers.SCRTMStatusBtG, value.SCRTMStatus())
+		require.Contains(t, []registers.TPMType{registers.TPMType20, registers.TPMTypeIntelPTT}, value.TPMType())
+	}
+	require.Len(t, unique, len(values))
+
+	require.Len(t, GeneratedACMPolicyStatuses(ctx, firmware.FakeIntelFirmware, tpmdetection.TypeTPM12), 12)
+	require.Empty(t, GeneratedACMPolicyStatuses(ctx, firmware.FakeIntelFirmware, tpmdetection.TypeNoTPM))
+}
+
+func TestACMPolicyStatusVariants(t *testing.T) {
+	ctx := context.Background()
+	reg := registers.ACMPolicyStatus(0x0000000200108681)
+
+	settings := Settings{TPMType: tpmdetection.TypeTPM20}
+	require.Equal(t, []*registers.ACMPolicyStatus{nil}, acmPolicyStatusVariants(ctx, nil, settings))
+
+	img := biosimage.New(firmware.FakeIntelFirmware)
+
+	settings.Registers = registers.Registers{reg}
+	settings.ACMPolicyStatuses = []registers.ACMPolicyStatus{reg, reg + 1}
+	variants := acmPolicyStatusVariants(ctx, img, settings)
+	require.Len(t, variants, 2)
+	require.Equal(t, reg, *variants[0])
+	require.Equal(t, reg+1, *variants[1])
+
+	settings.GenerateACMPolicyStatuses = true
+	variants = acmPolicyStatusVariants(ctx, img, settings)
+	require.Len(t, variants, 2+24)
+}
+
+func TestEntriesForImage(t *testing.T) {
+	ctx := context.Background()
+
+	entries, err := EntriesForImage(ctx, "fake.bin", firmware.FakeIntelFirmware, DefaultSettings())
+	require.NoError(t, err)
+	require.NotEmpty(t, entries)
+
+	seen := map[string]struct{}{}
+	withACMPolicyStatus := 0
+	for _, entry := range entries {
+		require.False(t, isSeen(seen, entry.Flow+"/"+pcr0Key(entry.PCR0)), "duplicate PCR0 of flow %s", entry.Flow)
+		if entry.ACMPolicyStatus != nil {
+			withACMPolicyStatus++
+		}
+	}
+	require.Greater(t, withACMPolicyStatus, 1, "the generated ACM_POLICY_STATUS values should be tried")
+}
@@ -0,0 +1,269 @@
+package golden
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/facebookincubator/go-belt/tool/logger"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/bootengine"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/cache"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/conditions/biosconds/amdconds"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/conditions/biosconds/intelconds"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/flows"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/amdpsp"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/intelpch"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/amdregisters"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/biosimage"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/txtpublic"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/types"
+	"github.com/9elements/converged-security-suite/v2/pkg/registers"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmdetection"
+)
+
+// Settings defines how the expected PCR0 values are calculated.
+type Settings struct {
+	// Flows are the boot flows to try. If empty, then the flows
+	// applicable to an image are used, see ApplicableFlows.
+	Flows []types.Flow
+
+	// TPMType is the type of the TPM of the target machines.
+	TPMType tpmdetection.Type
+
+	// Registers are the registers of the target machines.
+	Registers registers.Registers
+
+	// ACMPolicyStatuses are the plausible values of the Intel ACM_POLICY_STATUS
+	// register to try in addition to the value in Registers.
+	ACMPolicyStatuses []registers.ACMPolicyStatus
+
+	// GenerateACMPolicyStatuses enables trying the values of the Intel
+	// ACM_POLICY_STATUS register generated for an image, see
+	// GeneratedACMPolicyStatuses.
+	GenerateACMPolicyStatuses bool
+}
+
+// DefaultSettings returns the default settings: all applicable flows,
+// TPM2.0, no registers and the generated ACM_POLICY_STATUS values.
+func DefaultSettings() Settings {
+	return Settings{
+		TPMType:                   tpmdetection.TypeTPM20,
+		GenerateACMPolicyStatuses: true,
+	}
+}
+
+// newBIOSImage returns a BIOSImage, which reuses the parsing results cached
+// in the context. EntriesForImage shares a single BIOSImage between all
+// the boots of an image, so the image is parsed only once.
+func newBIOSImage(ctx context.Context, image []byte) *biosimage.BIOSImage {
+	img := biosimage.New(image)
+	img.Cache = cache.FromCtx(ctx)
+	return img
+}
+
+// ApplicableFlows returns the flows of the vendor of the firmware image:
+// "Intel*" if the image has FIT and "AMD*" if it has AMD firmware structures.
+func ApplicableFlows(ctx context.Context, image []byte) []types.Flow {
+	return applicableFlows(ctx, newBIOSImage(ctx, image))
+}
+
+func applicableFlows(ctx context.Context, img *biosimage.BIOSImage) []types.Flow {
+	state := types.NewState()
+	state.IncludeSystemArtifact(img)
+
+	var prefixes []string
+	if (intelconds.FITPresent{}).Check(ctx, state) {
+		prefixes = append(prefixes, "Intel")
+	}
+	if (amdconds.ManifestPresent{}).Check(ctx, state) {
+		prefixes = append(prefixes, "AMD")
+	}
+
+	var result []types.Flow
+	for _, flow := range flows.All() {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(flow.Name, prefix) {
+				result = append(result, flow)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// Build calculates the expected PCR0 values of all firmware images
+// in the directory (recursively).
+//
+// Images, which could not be booted through any flow, are skipped
+// with a warning.
+func Build(ctx context.Context, dir string, settings Settings) (*Database, error) {
+	db := &Database{
+		Version: Version,
+	}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return fmt.Errorf("unable to get the relative path of '%s': %w", path, err)
+		}
+		image, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("unable to read image '%s': %w", path, err)
+		}
+		entries, err := EntriesForImage(ctx, relPath, image, settings)
+		if err != nil {
+			logger.Warnf(ctx, "skipping image '%s': %v", path, err)
+			return nil
+		}
+		db.Entries = append(db.Entries, entries...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to walk through '%s': %w", dir, err)
+	}
+	return db, nil
+}
+
+// EntriesForImage calculates the expected PCR0 values of the firmware image
+// for every flow and every plausible ACM_POLICY_STATUS value. ACM_POLICY_STATUS
+// values are tried for a flow only if it measures the register.
+//
+// Every PCR0 value is reported once per flow, by the first ACM_POLICY_STATUS
+// value which produced it.
+func EntriesForImage(ctx context.Context, name string, image []byte, settings Settings) ([]Entry, error) {
+	imageHash := sha256.Sum256(image)
+	img := newBIOSImage(ctx, image)
+	biosInfo, err := img.Info()
+	if err != nil {
+		logger.Debugf(ctx, "unable to get BIOS info of image '%s': %v", name, err)
+		biosInfo = nil
+	}
+
+	bootFlows := settings.Flows
+	if len(bootFlows) == 0 {
+		bootFlows = applicableFlows(ctx, img)
+	}
+	if len(bootFlows) == 0 {
+		return nil, fmt.Errorf("no applicable boot flows")
+	}
+
+	var result []Entry
+	acmPolicyStatuses := acmPolicyStatusVariants(ctx, img, settings)
+	for _, flow := range bootFlows {
+		seen := map[string]struct{}{}
+		for idx, acmPolicyStatus := range acmPolicyStatuses {
+			process := boot(ctx, flow, settings.TPMType, img, withACMPolicyStatus(settings.Registers, acmPolicyStatus))
+			if err := process.Log.Error(); err != nil {
+				logger.Debugf(ctx, "flow %s is not applicable to image '%s': %v", flow.Name, name, err)
+				break
+			}
+			pcr0, err := expectedPCR0(process)
+			if err != nil {
+				return nil, fmt.Errorf("unable to get PCR0 of flow %s: %w", flow.Name, err)
+			}
+			if pcr0 == nil {
+				logger.Debugf(ctx, "flow %s does not measure image '%s' into PCR0", flow.Name, name)
+				break
+			}
+			if key := pcr0Key(pcr0); !isSeen(seen, key) {
+				result = append(result, Entry{
+					Image:           name,
+					ImageSHA256:     hexDigest(imageHash[:]),
+					BIOSInfo:        biosInfo,
+					Flow:            flow.Name,
+					ACMPolicyStatus: acmPolicyStatus,
+					PCR0:            pcr0,
+				})
+			}
+			if idx == 0 && !measuresACMPolicyStatus(process) {
+				break
+			}
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("none of %d boot flows is applicable", len(bootFlows))
+	}
+	return result, nil
+}
+
+func boot(
+	ctx context.Context,
+	flow types.Flow,
+	tpmType tpmdetection.Type,
+	img *biosimage.BIOSImage,
+	regs registers.Registers,
+) *bootengine.BootProcess {
+	state := types.NewState()
+	state.IncludeSubSystem(tpm.NewTPMOfType(tpmType))
+	state.IncludeSubSystem(intelpch.NewPCH())
+	state.IncludeSubSystem(amdpsp.NewPSP())
+	state.IncludeSystemArtifact(img)
+	state.IncludeSystemArtifact(txtpublic.New(regs))
+	state.IncludeSystemArtifact(amdregisters.New(regs))
+	state.SetFlow(flow)
+	process := bootengine.NewBootProcess(state)
+	process.Finish(ctx)
+	return process
+}
+
+// expectedPCR0 returns PCR0 values of all banks in hex, or nil
+// if nothing was measured into PCR0.
+func expectedPCR0(process *bootengine.BootProcess) (map[string]string, error) {
+	tpmInstance, err := tpm.GetFrom(process.CurrentState)
+	if err != nil {
+		return nil, err
+	}
+
+	isMeasured := false
+	for _, entry := range tpmInstance.CommandLog {
+		if cmd, ok := entry.Command.(*tpm.CommandExtend); ok && cmd.PCRIndex == 0 {
+			isMeasured = true
+			break
+		}
+	}
+	if !isMeasured {
+		return nil, nil
+	}
+
+	result := map[string]string{}
+	for _, hashAlgo := range tpmInstance.SupportedAlgos {
+		value, err := tpmInstance.PCRValues.Get(0, hashAlgo)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get PCR0 of bank %s: %w", pcr.AlgorithmString(hashAlgo), err)
+		}
+		result[pcr.AlgorithmString(hashAlgo)] = hexDigest(value)
+	}
+	return result, nil
+}
+
+// pcr0Key returns a string, which is equal for equal sets of PCR0 values.
+func pcr0Key(pcr0 map[string]string) string {
+	banks := make([]string, 0, len(pcr0))
+	for bank, value := range pcr0 {
+		banks = append(banks, bank+":"+value)
+	}
+	sort.Strings(banks)
+	return strings.Join(banks, ",")
+}
+
+// isSeen returns true if the key is in the set, and adds it otherwise.
+func isSeen(set map[string]struct{}, key string) bool {
+	if _, ok := set[key]; ok {
+		return true
+	}
+	set[key] = struct{}{}
+	return false
+}
@@ -0,0 +1,218 @@
+// Package golden builds and queries a database of golden (expected)
+// PCR0 values of a corpus of firmware images.
+package golden
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/dmidecode"
+	"github.com/9elements/converged-security-suite/v2/pkg/registers"
+)
+
+// Version is the version of the database format, it should be
+// incremented on every incompatible change.
+const Version = 1
+
+// Database is a collection of expected PCR0 values of firmware images.
+type Database struct {
+	Version int
+	Entries []Entry
+}
+
+// Entry is an expected PCR0 value of a firmware image booted through
+// a specific flow.
+type Entry struct {
+	// Image is the path to the image relative to the corpus directory.
+	Image string
+
+	// ImageSHA256 is the SHA256 hash of the image in hex.
+	ImageSHA256 string
+
+	// BIOSInfo is the SMBIOS BIOS information of the image, it is nil
+	// if the image contains no SMBIOS data.
+	BIOSInfo *dmidecode.BIOSInfo `json:",omitempty"`
+
+	// Flow is the name of the boot flow (see package flows).
+	Flow string
+
+	// ACMPolicyStatus is the value of the Intel ACM_POLICY_STATUS register
+	// used to calculate the PCR0 value, it is nil if not set.
+	ACMPolicyStatus *registers.ACMPolicyStatus `json:",omitempty"`
+
+	// PCR0 are the expected PCR0 values in hex indexed by the name of
+	// the PCR bank (for example "SHA256").
+	PCR0 map[string]string
+}
+
+// Match is an entry found by Lookup.
+type Match struct {
+	Entry *Entry
+	Bank  string
+}
+
+// Lookup returns all entries, which have the given PCR0 value in any bank.
+func (db *Database) Lookup(pcr0 []byte) []Match {
+	value := hexDigest(pcr0)
+	var result []Match
+	for idx := range db.Entries {
+		entry := &db.Entries[idx]
+		for _, bank := range entry.Banks() {
+			if strings.EqualFold(entry.PCR0[bank], value) {
+				result = append(result, Match{Entry: entry, Bank: bank})
+			}
+		}
+	}
+	return result
+}
+
+// Banks returns the names of PCR banks of the entry in the alphabetical order.
+func (entry *Entry) Banks() []string {
+	result := make([]string, 0, len(entry.PCR0))
+	for bank := range entry.PCR0 {
+		result = append(result, bank)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// WriteJSON writes the database as JSON.
+func (db *Database) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(db)
+}
+
+var csvHeader = []string{
+	"version", "image", "image_sha256",
+	"bios_vendor", "bios_version", "bios_release_date", "bios_revision",
+	"flow", "acm_policy_status", "bank", "pcr0",
+}
+
+// WriteCSV writes the database as CSV, a line per entry and PCR bank.
+func (db *Database) WriteCSV(w io.Writer) error {
+	csvWriter := csv.NewWriter(w)
+	if err := csvWriter.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, entry := range db.Entries {
+		var biosInfo dmidecode.BIOSInfo
+		if entry.BIOSInfo != nil {
+			biosInfo = *entry.BIOSInfo
+		}
+		var acmPolicyStatus string
+		if entry.ACMPolicyStatus != nil {
+			acmPolicyStatus = fmt.Sprintf("%016X", uint64(*entry.ACMPolicyStatus))
+		}
+		for _, bank := range entry.Banks() {
+			err := csvWriter.Write([]string{
+				strconv.Itoa(db.Version), entry.Image, entry.ImageSHA256,
+				biosInfo.Vendor, biosInfo.Version, biosInfo.ReleaseDate, biosInfo.Revision,
+				entry.Flow, acmPolicyStatus, bank, entry.PCR0[bank],
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// Read reads a database written by WriteJSON or WriteCSV.
+func Read(r io.Reader) (*Database, error) {
+	bufReader := bufio.NewReader(r)
+	firstByte, err := bufReader.Peek(1)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the database: %w", err)
+	}
+
+	var db *Database
+	if firstByte[0] == '{' {
+		db = &Database{}
+		if err := json.NewDecoder(bufReader).Decode(db); err != nil {
+			return nil, fmt.Errorf("unable to parse the database as JSON: %w", err)
+		}
+	} else {
+		db, err = readCSV(bufReader)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse the database as CSV: %w", err)
+		}
+	}
+	if db.Version != Version {
+		return nil, fmt.Errorf("unsupported database version %d, expected %d", db.Version, Version)
+	}
+	return db, nil
+}
+
+func readCSV(r io.Reader) (*Database, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 || strings.Join(records[0], ",") != strings.Join(csvHeader, ",") {
+		return nil, fmt.Errorf("invalid header, expected: %s", strings.Join(csvHeader, ","))
+	}
+
+	db := &Database{Version: Version}
+	for lineIdx, record := range records[1:] {
+		version, err := strconv.Atoi(record[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid version '%s' on line %d: %w", record[0], lineIdx+2, err)
+		}
+		db.Version = version
+
+		entry := Entry{
+			Image:       record[1],
+			ImageSHA256: record[2],
+			Flow:        record[7],
+		}
+		if biosInfo := (dmidecode.BIOSInfo{
+			Vendor:      record[3],
+			Version:     record[4],
+			ReleaseDate: record[5],
+			Revision:    record[6],
+		}); biosInfo != (dmidecode.BIOSInfo{}) {
+			entry.BIOSInfo = &biosInfo
+		}
+		if record[8] != "" {
+			value, err := strconv.ParseUint(record[8], 16, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ACM_POLICY_STATUS '%s' on line %d: %w", record[8], lineIdx+2, err)
+			}
+			acmPolicyStatus := registers.ACMPolicyStatus(value)
+			entry.ACMPolicyStatus = &acmPolicyStatus
+		}
+
+		// Lines of the same entry (different PCR banks) are written sequentially.
+		if n := len(db.Entries); n > 0 && db.Entries[n-1].isSameBoot(&entry) {
+			db.Entries[n-1].PCR0[record[9]] = record[10]
+			continue
+		}
+		entry.PCR0 = map[string]string{record[9]: record[10]}
+		db.Entries = append(db.Entries, entry)
+	}
+	return db, nil
+}
+
+func (entry *Entry) isSameBoot(other *Entry) bool {
+	if entry.ImageSHA256 != other.ImageSHA256 || entry.Image != other.Image || entry.Flow != other.Flow {
+		return false
+	}
+	if (entry.ACMPolicyStatus == nil) != (other.ACMPolicyStatus == nil) {
+		return false
+	}
+	return entry.ACMPolicyStatus == nil || *entry.ACMPolicyStatus == *other.ACMPolicyStatus
+}
+
+// hexDigest formats a digest the same way as it is stored in the database.
+func hexDigest(digest []byte) string {
+	return strings.ToUpper(hex.EncodeToString(digest))
+}
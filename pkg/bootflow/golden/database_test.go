@@ -0,0 +1,63 @@
+package golden
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/dmidecode"
+	"github.com/9elements/converged-security-suite/v2/pkg/registers"
+)
+
+func TestDatabase(t *testing.T) {
+	acmPolicyStatus := registers.ACMPolicyStatus(0x0000000200108681)
+	db := &Database{
+		Version: Version,
+		Entries: []Entry{
+			{
+				Image:           "vendor/v1.bin",
+				ImageSHA256:     strings.Repeat("AB", 32),
+				BIOSInfo:        &dmidecode.BIOSInfo{Vendor: "Vendor", Version: "1.0", ReleaseDate: "01/02/2023"},
+				Flow:            "IntelCBnT",
+				ACMPolicyStatus: &acmPolicyStatus,
+				PCR0: map[string]string{
+					"SHA1":   strings.Repeat("01", 20),
+					"SHA256": strings.Repeat("02", 32),
+				},
+			},
+			{
+				Image:       "vendor/v2.bin",
+				ImageSHA256: strings.Repeat("CD", 32),
+				Flow:        "IntelLegacyTXTDisabled",
+				PCR0: map[string]string{
+					"SHA256": strings.Repeat("03", 32),
+				},
+			},
+		},
+	}
+
+	for name, write := range map[string]func(*bytes.Buffer) error{
+		"json": func(buf *bytes.Buffer) error { return db.WriteJSON(buf) },
+		"csv":  func(buf *bytes.Buffer) error { return db.WriteCSV(buf) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, write(&buf))
+			parsed, err := Read(&buf)
+			require.NoError(t, err)
+			require.Equal(t, db, parsed)
+
+			matches := parsed.Lookup(bytes.Repeat([]byte{0x02}, 32))
+			require.Len(t, matches, 1)
+			require.Equal(t, "vendor/v1.bin", matches[0].Entry.Image)
+			require.Equal(t, "SHA256", matches[0].Bank)
+
+			require.Empty(t, parsed.Lookup(bytes.Repeat([]byte{0x04}, 32)))
+		})
+	}
+
+	_, err := Read(strings.NewReader(`{"Version": 2}`))
+	require.Error(t, err)
+}
@@ -13,7 +13,7 @@ import (
 // MeasureACMDate measures ACM date to TPM.
 //
 // Note: this action does not support TPM1.2 behavior,
-// because in TPM1.2 the ACM date is not hashed.
+// because in TPM1.2 the ACM date is not hashed (see MeasureACMDateInPlace).
 type MeasureACMDate struct{}
 
 var _ types.Step = (*MeasureACMDate)(nil)
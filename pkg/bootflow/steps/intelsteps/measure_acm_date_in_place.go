@@ -0,0 +1,29 @@
+package intelsteps
+
+import (
+	"context"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/actions/tpmactions"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/datasources/inteldata"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/types"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmeventlog"
+)
+
+// MeasureACMDateInPlace measures ACM date to TPM the TPM1.2 way:
+// the date is not hashed, but extended as is (padded with zeros).
+type MeasureACMDateInPlace struct{}
+
+var _ types.Step = (*MeasureACMDateInPlace)(nil)
+
+// Actions implements types.Step.
+func (MeasureACMDateInPlace) Actions(ctx context.Context, state *types.State) types.Actions {
+	return types.Actions{
+		tpmactions.NewTPMEventInPlace(
+			pcr.ID(0),
+			inteldata.ACMDate{},
+			tpmeventlog.EV_S_CRTM_CONTENTS,
+			[]byte("ACM_date"),
+		),
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/actions/commonactions"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/actions/tpmactions"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/types"
 	"github.com/9elements/converged-security-suite/v2/pkg/tpmeventlog"
 )
@@ -32,7 +33,7 @@ func (s LogInitStruct) Actions(ctx context.Context, state *types.State) types.Ac
 
 	var result types.Actions
 	for _, algo := range tpmInstance.SupportedAlgos {
-		h, err := algo.Hash()
+		h, err := pcr.Hash(algo)
 		if err != nil {
 			return types.Actions{
 				commonactions.Panic(fmt.Errorf("unable to initialize hashes for algo %s: %w", algo, err)),
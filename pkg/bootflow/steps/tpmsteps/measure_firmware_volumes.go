@@ -0,0 +1,157 @@
+package tpmsteps
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/actions/commonactions"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/actions/tpmactions"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/datasources"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/biosimage"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/types"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmeventlog"
+	"github.com/9elements/converged-security-suite/v2/pkg/uefi/ffs"
+	pkgbytes "github.com/linuxboot/fiano/pkg/bytes"
+	"github.com/linuxboot/fiano/pkg/guid"
+	"github.com/linuxboot/fiano/pkg/uefi"
+)
+
+// MeasureFirmwareVolumesStruct is the structure returned by MeasureFirmwareVolumes.
+type MeasureFirmwareVolumesStruct struct {
+	PCRIndex   pcr.ID
+	DataSource types.DataSource
+}
+
+var _ types.Step = (*MeasureFirmwareVolumesStruct)(nil)
+
+// MeasureFirmwareVolumes measures each firmware volume (within the data
+// provided by the DataSource) as a separate event. This is how TCG2 DXE
+// driver of EDK2 measures firmware volumes (see MeasureFvImage).
+//
+// Volumes which were already measured (for example, in PEI) are skipped.
+func MeasureFirmwareVolumes(pcrIndex pcr.ID, dataSource types.DataSource) types.Step {
+	return &MeasureFirmwareVolumesStruct{
+		PCRIndex:   pcrIndex,
+		DataSource: dataSource,
+	}
+}
+
+// Actions implements types.Step.
+func (s MeasureFirmwareVolumesStruct) Actions(ctx context.Context, state *types.State) types.Actions {
+	actions, err := s.actions(ctx, state)
+	if err != nil {
+		return types.Actions{
+			commonactions.Panic(fmt.Errorf("unable to measure firmware volumes of %v: %w", s.DataSource, err)),
+		}
+	}
+	return actions
+}
+
+func (s MeasureFirmwareVolumesStruct) actions(ctx context.Context, state *types.State) (types.Actions, error) {
+	imgRaw, err := biosimage.Get(state)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get BIOS Firmware: %w", err)
+	}
+	imgUEFI, err := imgRaw.Parse()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the firmware image: %w", err)
+	}
+
+	data, err := s.DataSource.Data(ctx, state)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get the data: %w", err)
+	}
+
+	refs := data.References.BySystemArtifact(imgRaw)
+	if err := refs.Resolve(); err != nil {
+		return nil, fmt.Errorf("unable to resolve the references: %w", err)
+	}
+	ranges := refs.Ranges()
+
+	var alreadyMeasured pkgbytes.Ranges
+	if t, err := tpm.GetFrom(state); err == nil {
+		measuredRefs := state.GetDataMeasuredBy(t).References().BySystemArtifact(imgRaw)
+		if err := measuredRefs.Resolve(); err != nil {
+			return nil, fmt.Errorf("unable to resolve the references of already measured data: %w", err)
+		}
+		alreadyMeasured = measuredRefs.Ranges()
+	}
+
+	addrMapper := biosimage.PhysMemMapper{}
+	var actions types.Actions
+	err = (&ffs.NodeVisitor{
+		Callback: func(node ffs.Node) (bool, error) {
+			volume, ok := node.Firmware.(*uefi.FirmwareVolume)
+			if !ok || node.Offset == math.MaxUint64 {
+				return true, nil
+			}
+			if !isRangeWithin(node.Range, ranges) {
+				return true, nil
+			}
+			if isRangeIntersecting(node.Range, alreadyMeasured) {
+				return false, nil
+			}
+
+			physRanges := addrMapper.UnresolveFullImageOffset(imgRaw, node.Range)
+			evType, evData := firmwareVolumeEvent(volume.FVName, physRanges[0])
+			actions = append(actions, tpmactions.NewTPMEvent(
+				s.PCRIndex,
+				(*datasources.StaticData)(types.NewData(&types.Reference{
+					Artifact: imgRaw,
+					MappedRanges: types.MappedRanges{
+						AddressMapper: addrMapper,
+						Ranges:        physRanges,
+					},
+				})),
+				evType,
+				evData,
+			))
+
+			// the nested volumes are measured as part of this one
+			return false, nil
+		},
+	}).Run(imgUEFI)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find firmware volumes: %w", err)
+	}
+
+	return actions, nil
+}
+
+// firmwareVolumeEvent returns the event type and event data (UEFI_PLATFORM_FIRMWARE_BLOB2
+// or UEFI_PLATFORM_FIRMWARE_BLOB) for a firmware volume.
+func firmwareVolumeEvent(fvName guid.GUID, physRange pkgbytes.Range) (tpmeventlog.EventType, []byte) {
+	var result []byte
+	evType := tpmeventlog.EV_EFI_PLATFORM_FIRMWARE_BLOB
+	if fvName != (guid.GUID{}) {
+		description := fmt.Sprintf("Fv(%s)", fvName.String())
+		result = append(result, uint8(len(description)))
+		result = append(result, description...)
+		evType = tpmeventlog.EV_EFI_PLATFORM_FIRMWARE_BLOB2
+	}
+	result = binary.LittleEndian.AppendUint64(result, physRange.Offset)
+	result = binary.LittleEndian.AppendUint64(result, physRange.Length)
+	return evType, result
+}
+
+func isRangeWithin(r pkgbytes.Range, ranges pkgbytes.Ranges) bool {
+	for _, cmp := range ranges {
+		if r.Offset >= cmp.Offset && r.End() <= cmp.End() {
+			return true
+		}
+	}
+	return false
+}
+
+func isRangeIntersecting(r pkgbytes.Range, ranges pkgbytes.Ranges) bool {
+	for _, cmp := range ranges {
+		if r.Intersect(cmp) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package tpmsteps
+
+import (
+	"context"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/actions/tpmactions"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/datasources"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/types"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmeventlog"
+	"github.com/facebookincubator/go-belt/tool/logger"
+)
+
+// MeasureUEFIVariableStruct is the structure returned by MeasureUEFIVariable.
+type MeasureUEFIVariableStruct struct {
+	PCRIndex   pcr.ID
+	Type       tpmeventlog.EventType
+	DataSource types.DataSource
+}
+
+var _ types.Step = (*MeasureUEFIVariableStruct)(nil)
+
+// MeasureUEFIVariable measures an UEFI variable provided by the DataSource
+// in form of UEFI_VARIABLE_DATA (for example, datasources.UEFIVariable).
+//
+// Unlike Measure, the measured UEFI_VARIABLE_DATA is also logged as
+// the event data, this is how EDK2 logs EV_EFI_VARIABLE_DRIVER_CONFIG
+// events (see MeasureVariable of SecurityPkg/Tcg/Tcg2Dxe/Tcg2Dxe.c).
+//
+// If the variable cannot be read (for example, the variable store is
+// corrupt), then the measurement is skipped: a broken store should not
+// prevent simulating the rest of the boot.
+func MeasureUEFIVariable(pcrIndex pcr.ID, eventType tpmeventlog.EventType, dataSource types.DataSource) types.Step {
+	return &MeasureUEFIVariableStruct{
+		PCRIndex:   pcrIndex,
+		Type:       eventType,
+		DataSource: dataSource,
+	}
+}
+
+// Actions implements types.Step.
+func (s MeasureUEFIVariableStruct) Actions(ctx context.Context, state *types.State) types.Actions {
+	data, err := s.DataSource.Data(ctx, state)
+	if err != nil {
+		logger.Warnf(ctx, "unable to get the UEFI variable %v, skipping the measurement: %v", s.DataSource, err)
+		return nil
+	}
+	return types.Actions{
+		tpmactions.NewTPMEvent(s.PCRIndex, (*datasources.StaticData)(data), s.Type, data.RawBytes()),
+	}
+}
@@ -0,0 +1,88 @@
+package tpmsteps
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/bootengine"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/datasources"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/biosimage"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/types"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmeventlog"
+	"github.com/9elements/converged-security-suite/v2/pkg/uefi/varstore"
+	"github.com/stretchr/testify/require"
+)
+
+// corruptVariableStoreImage returns an image with an NV data firmware
+// volume, which has a variable store with an unknown signature.
+func corruptVariableStoreImage() []byte {
+	const fvHeaderLength = 0x48
+	image := make([]byte, 0x1000)
+	copy(image[16:], varstore.GUIDSystemNVDataFV[:])
+	binary.LittleEndian.PutUint64(image[32:], uint64(len(image)))
+	copy(image[40:], "_FVH")
+	binary.LittleEndian.PutUint16(image[48:], fvHeaderLength)
+	return image
+}
+
+func measureUEFIVariables(t *testing.T, image []byte) tpm.EventLog {
+	tpmInstance := tpm.NewTPM()
+	state := types.NewState()
+	state.IncludeSubSystem(tpmInstance)
+	state.IncludeSystemArtifact(biosimage.New(image))
+	state.SetFlow(types.Flow{
+		Name: "test",
+		Steps: types.Steps{
+			InitTPM(0, false),
+			MeasureUEFIVariable(7, tpmeventlog.EV_EFI_VARIABLE_DRIVER_CONFIG, datasources.SecureBootVariable{}),
+			MeasureUEFIVariable(7, tpmeventlog.EV_EFI_VARIABLE_DRIVER_CONFIG, datasources.UEFIVariable{Name: "PK", VendorGUID: varstore.GUIDGlobalVariable}),
+			Measure(7, tpmeventlog.EV_SEPARATOR, datasources.Bytes{0, 0, 0, 0}),
+		},
+	})
+	process := bootengine.NewBootProcess(state)
+	process.Finish(context.Background())
+	require.NoError(t, process.Log.Error())
+	return tpmInstance.EventLog
+}
+
+// variableEvents returns the data of the EV_EFI_VARIABLE_DRIVER_CONFIG
+// events (one per event, regardless of the amount of hash algorithms).
+func variableEvents(log tpm.EventLog) [][]byte {
+	var result [][]byte
+	for _, entry := range log {
+		if entry.Type != tpmeventlog.EV_EFI_VARIABLE_DRIVER_CONFIG {
+			continue
+		}
+		if len(result) > 0 && string(result[len(result)-1]) == string(entry.Data) {
+			continue
+		}
+		result = append(result, entry.Data)
+	}
+	return result
+}
+
+func TestMeasureUEFIVariableMissingStore(t *testing.T) {
+	events := variableEvents(measureUEFIVariables(t, make([]byte, 0x1000)))
+	require.Len(t, events, 2)
+
+	// Secure Boot is disabled without PK
+	require.Equal(t, byte(0), events[0][len(events[0])-1])
+
+	// an absent variable is measured with zero data length
+	pk := append([]byte{}, varstore.GUIDGlobalVariable[:]...)
+	pk = binary.LittleEndian.AppendUint64(pk, 2)
+	pk = binary.LittleEndian.AppendUint64(pk, 0)
+	pk = append(pk, 'P', 0, 'K', 0)
+	require.Equal(t, pk, events[1])
+}
+
+func TestMeasureUEFIVariableCorruptStore(t *testing.T) {
+	log := measureUEFIVariables(t, corruptVariableStoreImage())
+
+	// the variables are skipped, but the boot goes on
+	require.Empty(t, variableEvents(log))
+	require.NotEmpty(t, log)
+	require.Equal(t, tpmeventlog.EV_SEPARATOR, log[len(log)-1].Type)
+}
@@ -3,9 +3,11 @@ package tpm
 import (
 	"context"
 	"fmt"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmdetection"
 )
 
-// CommandInit represents _TPM_init + TPM2_Startup(CLEAR).
+// CommandInit represents _TPM_init + TPM2_Startup(CLEAR) (or TPM_Startup(ST_CLEAR) for TPM1.2).
 type CommandInit struct {
 	Locality uint8
 }
@@ -41,42 +43,68 @@ func (cmd *CommandInit) Apply(_ context.Context, tpm *TPM) error {
 		tpm.PCRValues = make(PCRValues, PCRRegistersAmount)
 	}
 
-	supportedAlgos := SupportedHashAlgos()
-	for _, hashAlgo := range supportedAlgos {
-		h, err := hashAlgo.Hash()
-		if err != nil {
-			return fmt.Errorf("unable to initialize a hasher factory for hash algo %v", hashAlgo)
+	if len(tpm.SupportedAlgos) == 0 {
+		// for example after DoNotUse_ResetNoInit
+		tpm.init()
+	}
+
+	// Only the banks of tpm.SupportedAlgos are allocated, so for example
+	// a brute-forcer of a single bank does not waste time on the others.
+	bankSizesSum := 0
+	for _, hashAlgo := range tpm.SupportedAlgos {
+		size := hashSize(hashAlgo)
+		if size == 0 {
+			return fmt.Errorf("hash algorithm %s is not supported", hashAlgo)
 		}
-		hasher := h.New()
-		for pcrID := PCRID(0); pcrID < PCRRegistersAmount; pcrID++ {
-			banksPerPCR := tpmMaxHashAlgo + 1
-			if cap(tpm.PCRValues[pcrID]) >= int(banksPerPCR) {
-				tpm.PCRValues[pcrID] = tpm.PCRValues[pcrID][:banksPerPCR]
-			} else {
-				tpm.PCRValues[pcrID] = make([]Digest, tpmMaxHashAlgo+1)
-			}
-			if cap(tpm.PCRValues[pcrID][hashAlgo]) >= hasher.Size() {
-				tpm.PCRValues[pcrID][hashAlgo] = tpm.PCRValues[pcrID][hashAlgo][:hasher.Size()]
-				zeroSlice(tpm.PCRValues[pcrID][hashAlgo])
-			} else {
-				tpm.PCRValues[pcrID][hashAlgo] = make(Digest, hasher.Size())
+		bankSizesSum += size
+	}
+
+	// All the PCR values are stored in a single buffer to avoid
+	// a memory allocation per PCR value (and to make the reset cheap).
+	bufSize := PCRRegistersAmount * bankSizesSum
+	if cap(tpm.pcrValuesBuffer) >= bufSize {
+		tpm.pcrValuesBuffer = tpm.pcrValuesBuffer[:bufSize]
+	} else {
+		tpm.pcrValuesBuffer = make([]byte, bufSize)
+	}
+	buf := tpm.pcrValuesBuffer
+
+	// TPM1.2 does not encode the startup locality into PCR0.
+	startupLocality := cmd.Locality
+	if tpm.Type == tpmdetection.TypeTPM12 {
+		startupLocality = 0
+	}
+
+	// The startup values do not depend on the locality except PCR0, so
+	// if all the banks are enabled they are copied from a precalculated
+	// buffer instead of setting each PCR value separately.
+	isAllBanks := isAllSupportedHashAlgos(tpm.SupportedAlgos)
+	if isAllBanks {
+		copy(buf, cachedStartupPCRValues)
+	}
+
+	banksPerPCR := int(tpmMaxHashAlgo) + 1
+	for pcrID := PCRID(0); pcrID < PCRRegistersAmount; pcrID++ {
+		if cap(tpm.PCRValues[pcrID]) >= banksPerPCR {
+			tpm.PCRValues[pcrID] = tpm.PCRValues[pcrID][:banksPerPCR]
+			if !isAllBanks {
+				// drop the banks left from a previous run with other SupportedAlgos
+				for idx := range tpm.PCRValues[pcrID] {
+					tpm.PCRValues[pcrID][idx] = nil
+				}
 			}
-			pcrValue := tpm.PCRValues[pcrID][hashAlgo]
-			switch pcrID {
-			case 0:
-				pcrValue[len(pcrValue)-1] = cmd.Locality
-			case 1:
-			default:
-				return fmt.Errorf("unexpected PCR ID: %d", pcrID)
+		} else {
+			tpm.PCRValues[pcrID] = make([]Digest, banksPerPCR)
+		}
+		for _, hashAlgo := range tpm.SupportedAlgos {
+			size := hashSize(hashAlgo)
+			pcrValue := buf[:size:size]
+			buf = buf[size:]
+			if !isAllBanks || pcrID == 0 {
+				pcrID.SetStartupValue(pcrValue, startupLocality)
 			}
+			tpm.PCRValues[pcrID][hashAlgo] = pcrValue
 		}
 	}
 	return nil
 }
-
-func zeroSlice[E any](s []E) {
-	var zeroItem E
-	for idx := range s {
-		s[idx] = zeroItem
-	}
-}
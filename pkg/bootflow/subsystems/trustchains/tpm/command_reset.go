@@ -0,0 +1,52 @@
+package tpm
+
+import (
+	"context"
+	"fmt"
+)
+
+// CommandReset represents TPM2_PCR_Reset. It is also used to represent
+// the reset of the DRTM PCRs by _TPM_Hash_Start (with locality 4).
+type CommandReset struct {
+	PCRIndex PCRID
+	Locality uint8
+}
+
+var _ Command = (*CommandReset)(nil)
+
+// NewCommandReset returns a new instance of CommandReset.
+func NewCommandReset(pcrIndex PCRID, locality uint8) *CommandReset {
+	return &CommandReset{
+		PCRIndex: pcrIndex,
+		Locality: locality,
+	}
+}
+
+// LogString implements Command.
+func (cmd *CommandReset) LogString() string {
+	return fmt.Sprintf("TPMReset(%d, %d)", cmd.PCRIndex, cmd.Locality)
+}
+
+// String implements fmt.Stringer.
+func (cmd *CommandReset) String() string {
+	return cmd.LogString()
+}
+
+// Apply implements Command.
+func (cmd *CommandReset) Apply(_ context.Context, tpm *TPM) error {
+	if !tpm.IsInitialized() {
+		return fmt.Errorf("TPM is not initialized")
+	}
+	if !cmd.PCRIndex.Attributes().ResetLocalities.Has(cmd.Locality) {
+		return fmt.Errorf("PCR %d cannot be reset from locality %d", cmd.PCRIndex, cmd.Locality)
+	}
+
+	for _, hashAlgo := range tpm.SupportedAlgos {
+		pcrValue, err := tpm.PCRValues.Get(cmd.PCRIndex, hashAlgo)
+		if err != nil {
+			return fmt.Errorf("unable to get the PCR value: %w", err)
+		}
+		cmd.PCRIndex.SetResetValue(pcrValue)
+	}
+	return nil
+}
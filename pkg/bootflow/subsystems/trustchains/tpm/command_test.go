@@ -1,11 +1,13 @@
 package tpm
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"fmt"
 	"testing"
 
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmdetection"
 	"github.com/facebookincubator/go-belt/tool/logger"
 	"github.com/facebookincubator/go-belt/tool/logger/implementation/logrus"
 	"github.com/google/go-tpm/legacy/tpm2"
@@ -47,3 +49,88 @@ func BenchmarkCommandsApply(b *testing.B) {
 		})
 	}
 }
+
+// BenchmarkCommandsApplyAllPCRs is BenchmarkCommandsApply, but the extends
+// are spread over all PCRs and the DRTM PCRs are reset, to compare with
+// extending only PCR0.
+func BenchmarkCommandsApplyAllPCRs(b *testing.B) {
+	ctx := logger.CtxWithLogger(context.Background(), logrus.Default())
+	tpmInstance := NewTPM()
+	for _, hashAlgo := range []tpm2.Algorithm{tpm2.AlgSHA1, tpm2.AlgSHA256} {
+		h, err := hashAlgo.Hash()
+		require.NoError(b, err)
+		log := Commands{&CommandInit{}, NewCommandReset(17, 4)}
+		for idx := 2; idx < 1000; idx++ {
+			log = append(log, &CommandExtend{
+				PCRIndex: PCRID(idx % PCRRegistersAmount),
+				HashAlgo: hashAlgo,
+				Digest:   make([]byte, h.Size()),
+			})
+		}
+		b.Run(hashAlgo.String(), func(b *testing.B) {
+			for _, logSize := range []uint{1, 10, 100, 1000} {
+				b.Run(fmt.Sprintf("logSize-%d", logSize), func(b *testing.B) {
+					b.ReportAllocs()
+					b.ResetTimer()
+					for i := 0; i < b.N; i++ {
+						tpmInstance.DoNotUse_ResetNoInit()
+						err := log[:logSize].Apply(ctx, tpmInstance)
+						require.NoError(b, err)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestCommandInitTPM12(t *testing.T) {
+	ctx := context.Background()
+
+	tpmInstance := NewTPMOfType(tpmdetection.TypeTPM12)
+	require.Equal(t, []Algorithm{tpm2.AlgSHA1}, tpmInstance.SupportedAlgos)
+
+	err := NewCommandInit(3).Apply(ctx, tpmInstance)
+	require.NoError(t, err)
+
+	pcr0, err := tpmInstance.PCRValues.Get(0, tpm2.AlgSHA1)
+	require.NoError(t, err)
+	require.Equal(t, Digest(make([]byte, 20)), pcr0)
+
+	tpmInstance.Reset()
+	require.Equal(t, []Algorithm{tpm2.AlgSHA1}, tpmInstance.SupportedAlgos)
+}
+
+func TestCommandInitSupportedAlgos(t *testing.T) {
+	ctx := context.Background()
+
+	tpmInstance := NewTPM()
+	err := NewCommandInit(3).Apply(ctx, tpmInstance)
+	require.NoError(t, err)
+	pcr0SHA1, err := tpmInstance.PCRValues.Get(0, tpm2.AlgSHA1)
+	require.NoError(t, err)
+	require.NotNil(t, pcr0SHA1)
+
+	// only the requested banks are calculated
+	tpmInstance.DoNotUse_ResetNoInit()
+	tpmInstance.SupportedAlgos = []Algorithm{tpm2.AlgSHA256}
+	err = NewCommandInit(3).Apply(ctx, tpmInstance)
+	require.NoError(t, err)
+
+	pcr0SHA1, err = tpmInstance.PCRValues.Get(0, tpm2.AlgSHA1)
+	require.NoError(t, err)
+	require.Nil(t, pcr0SHA1)
+
+	pcr0SHA256, err := tpmInstance.PCRValues.Get(0, tpm2.AlgSHA256)
+	require.NoError(t, err)
+	expected := make([]byte, sha256.Size)
+	expected[len(expected)-1] = 3
+	require.Equal(t, Digest(expected), pcr0SHA256)
+
+	pcr17SHA256, err := tpmInstance.PCRValues.Get(17, tpm2.AlgSHA256)
+	require.NoError(t, err)
+	require.Equal(t, Digest(bytes.Repeat([]byte{0xFF}, sha256.Size)), pcr17SHA256)
+
+	err = NewCommandReset(17, 4).Apply(ctx, tpmInstance)
+	require.NoError(t, err)
+	require.Equal(t, Digest(make([]byte, sha256.Size)), pcr17SHA256)
+}
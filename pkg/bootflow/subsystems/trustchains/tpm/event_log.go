@@ -3,6 +3,7 @@ package tpm
 import (
 	"fmt"
 
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
 	"github.com/9elements/converged-security-suite/v2/pkg/tpmeventlog"
 )
 
@@ -29,6 +30,26 @@ func EventLogEntryFromParsed(ev *tpmeventlog.Event) EventLogEntry {
 	}
 }
 
+// TPMEventLog converts the EventLog into the format of package tpmeventlog
+// (for example to serialize it with WriteCryptoAgile).
+func (log EventLog) TPMEventLog() *tpmeventlog.TPMEventLog {
+	result := &tpmeventlog.TPMEventLog{
+		Events: make([]*tpmeventlog.Event, 0, len(log)),
+	}
+	for _, e := range log {
+		result.Events = append(result.Events, &tpmeventlog.Event{
+			PCRIndex: e.PCRIndex,
+			Type:     e.Type,
+			Data:     e.Data,
+			Digest: &tpmeventlog.Digest{
+				HashAlgo: e.HashAlgo,
+				Digest:   e.Digest,
+			},
+		})
+	}
+	return result
+}
+
 // EventLogEntry is a single entry of EventLog.
 type EventLogEntry struct {
 	CommandExtend
@@ -89,26 +110,30 @@ func (log EventLog) RestoreCommands() []Command {
 	return result
 }
 
+// Replay calculates the value of the PCR (of the given hash algorithm)
+// by replaying the EventLog.
+//
+// "locality" is the locality of TPM2_Startup (it affects only PCR0).
 func (log EventLog) Replay(pcrID PCRID, hashAlgo Algorithm, locality uint8) Digest {
 	// TODO: consider removing the argument 'locality' (instead use Startup entry)
 
-	h, err := hashAlgo.Hash()
+	if !pcrID.IsValid() {
+		panic(fmt.Errorf("invalid PCR index: %d", pcrID))
+	}
+
+	h, err := pcr.Hash(hashAlgo)
 	if err != nil {
 		panic(fmt.Errorf("unable to initialize a hash function: %w", err))
 	}
 	hasher := h.New()
 
-	if pcrID != 0 {
-		// TODO: add code for initial values of other PCRs and delete this line:
-		panic("currently only replay of PCR0 is supported")
-	}
-
 	result := make(Digest, h.Size())
-	result[len(result)-1] = locality
+	pcrID.SetStartupValue(result, locality)
 	for _, ev := range log {
 		if ev.PCRIndex != pcrID || ev.HashAlgo != hashAlgo {
 			continue
 		}
+		hasher.Reset()
 		hasher.Write(result)
 		hasher.Write(ev.Digest)
 		result = hasher.Sum(result[:0])
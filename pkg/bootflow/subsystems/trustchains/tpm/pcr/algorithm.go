@@ -1,6 +1,89 @@
 package pcr
 
-import "github.com/google/go-tpm/legacy/tpm2"
+import (
+	_ "crypto/sha1"   // registers crypto.SHA1
+	_ "crypto/sha256" // registers crypto.SHA256
+	_ "crypto/sha512" // registers crypto.SHA384 and crypto.SHA512
+	"fmt"
+	"hash"
+	"strings"
+
+	"github.com/google/go-tpm/legacy/tpm2"
+	"github.com/tjfoc/gmsm/sm3"
+)
 
 // Algorithm is just a type-alias.
 type Algorithm = tpm2.Algorithm
+
+const (
+	// AlgSM3_256 is the identifier of hash algorithm SM3 (with a 256-bit digest).
+	//
+	// See also: "TCG Algorithm Registry", TPM_ALG_SM3_256.
+	AlgSM3_256 = Algorithm(0x0012)
+
+	sm3Size = 32
+)
+
+// HashFactory is a factory of hashers of a specific hash algorithm.
+//
+// It is similar to crypto.Hash, but also supports algorithms which
+// are not registered in package "crypto" (for example SM3).
+type HashFactory struct {
+	newFunc func() hash.Hash
+	size    int
+}
+
+// New returns a new hasher.
+func (f HashFactory) New() hash.Hash {
+	return f.newFunc()
+}
+
+// Size returns the digest size of the hash algorithm.
+func (f HashFactory) Size() int {
+	return f.size
+}
+
+// Hash returns the HashFactory for the given TPM hash algorithm.
+//
+// Unlike tpm2.Algorithm.Hash it also supports SM3_256.
+func Hash(algo Algorithm) (HashFactory, error) {
+	if algo == AlgSM3_256 {
+		return HashFactory{newFunc: sm3.New, size: sm3Size}, nil
+	}
+
+	h, err := algo.Hash()
+	if err != nil {
+		return HashFactory{}, err
+	}
+	if !h.Available() {
+		return HashFactory{}, fmt.Errorf("hash function %v is not linked into the binary", h)
+	}
+	return HashFactory{newFunc: h.New, size: h.Size()}, nil
+}
+
+// AlgorithmString returns a human-readable name of the hash algorithm.
+//
+// Unlike tpm2.Algorithm.String it also knows about SM3_256.
+func AlgorithmString(algo Algorithm) string {
+	if algo == AlgSM3_256 {
+		return "SM3_256"
+	}
+	return algo.String()
+}
+
+// AlgorithmFromString parses a hash algorithm name as returned
+// by AlgorithmString (case-insensitive, for example "sha256" or "SM3_256").
+func AlgorithmFromString(s string) (Algorithm, error) {
+	for _, algo := range []Algorithm{
+		tpm2.AlgSHA1,
+		tpm2.AlgSHA256,
+		tpm2.AlgSHA384,
+		tpm2.AlgSHA512,
+		AlgSM3_256,
+	} {
+		if strings.EqualFold(AlgorithmString(algo), s) {
+			return algo, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown hash algorithm '%s'", s)
+}
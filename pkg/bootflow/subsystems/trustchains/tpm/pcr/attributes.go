@@ -0,0 +1,98 @@
+package pcr
+
+// The PCR attributes are defined in "TCG PC Client Platform TPM Profile
+// Specification for TPM 2.0", table "PCR Attributes".
+
+const (
+	// Amount is the amount of PCRs of a PC Client TPM.
+	Amount = 24
+
+	// LocalityDRTM is the locality used by the Dynamic Root of Trust for Measurement
+	// (for example, by the CPU microcode when executing GETSEC[SENTER] on Intel TXT).
+	LocalityDRTM = 4
+)
+
+// LocalitySet is a set of localities (as a bitmask: bit N means locality N).
+type LocalitySet uint8
+
+// Has returns true if the given locality is in the set.
+func (s LocalitySet) Has(locality uint8) bool {
+	return locality < 8 && s&(1<<locality) != 0
+}
+
+func localities(localities ...uint8) LocalitySet {
+	var result LocalitySet
+	for _, locality := range localities {
+		result |= 1 << locality
+	}
+	return result
+}
+
+// Attributes defines the platform-specific behavior of a PCR.
+//
+// The localities allowed to extend a PCR are not modeled: the extends
+// (and the TPM EventLog entries they are restored from) do not carry
+// the locality.
+type Attributes struct {
+	// ResetLocalities are the localities allowed to reset the PCR (TPM2_PCR_Reset).
+	ResetLocalities LocalitySet
+
+	// IsDRTM is true if the PCR is set to 0xFF-s on TPM2_Startup and
+	// reset to zeros on a DRTM event (_TPM_Hash_Start).
+	IsDRTM bool
+}
+
+var attributes = func() [Amount]Attributes {
+	var result [Amount]Attributes
+	all := localities(0, 1, 2, 3, 4)
+	result[16] = Attributes{ResetLocalities: all} // debug
+	result[17] = Attributes{ResetLocalities: localities(4), IsDRTM: true}
+	result[18] = Attributes{ResetLocalities: localities(4), IsDRTM: true}
+	result[19] = Attributes{ResetLocalities: localities(4), IsDRTM: true}
+	result[20] = Attributes{ResetLocalities: localities(2, 4), IsDRTM: true}
+	result[21] = Attributes{ResetLocalities: localities(2), IsDRTM: true}
+	result[22] = Attributes{ResetLocalities: localities(2), IsDRTM: true}
+	result[23] = Attributes{ResetLocalities: all} // application-specific
+	return result
+}()
+
+// IsValid returns true if the PCR exists on a PC Client TPM.
+func (id ID) IsValid() bool {
+	return id < Amount
+}
+
+// Attributes returns the attributes of the PCR.
+//
+// Returns the zero value if the PCR does not exist.
+func (id ID) Attributes() Attributes {
+	if !id.IsValid() {
+		return Attributes{}
+	}
+	return attributes[id]
+}
+
+// SetStartupValue sets the value of the PCR after TPM2_Startup(CLEAR)
+// to "value" (the length of "value" is expected to be equal to the digest
+// size).
+//
+// "locality" is the locality of TPM2_Startup, it affects only PCR0.
+func (id ID) SetStartupValue(value []byte, locality uint8) {
+	fill := byte(0)
+	if id.Attributes().IsDRTM {
+		fill = 0xff
+	}
+	for idx := range value {
+		value[idx] = fill
+	}
+	if id == 0 && len(value) > 0 {
+		value[len(value)-1] = locality
+	}
+}
+
+// SetResetValue sets the value of the PCR after a reset (TPM2_PCR_Reset
+// or a DRTM event) to "value".
+func (id ID) SetResetValue(value []byte) {
+	for idx := range value {
+		value[idx] = 0
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/dataconverters"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/datasources"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/biosimage"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/types"
 	"github.com/9elements/converged-security-suite/v2/pkg/diff"
@@ -73,7 +74,7 @@ func (e logEntryExplainer) guessMeasurement(
 ) (m *types.MeasuredData, digests [][]byte) {
 	var digest []byte
 
-	if e.EventDataParsed != nil {
+	if e.EventDataParsed != nil && len(e.EventDataParsed.Ranges) > 0 {
 		m, digest = e.guessMeasurementFromEventRanges(
 			ctx,
 			s,
@@ -179,7 +180,7 @@ func tryMeasurement(
 	ev *tpmeventlog.Event,
 	chunks types.References,
 ) (*types.MeasuredData, []byte) {
-	h, err := ev.Digest.HashAlgo.Hash()
+	h, err := pcr.Hash(ev.Digest.HashAlgo)
 	if err != nil {
 		panic(err) // should never happen
 	}
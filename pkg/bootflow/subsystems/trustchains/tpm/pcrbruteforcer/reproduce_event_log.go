@@ -17,6 +17,7 @@ import (
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/bootengine"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/dataconverters"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/biosimage"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/txtpublic"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/types"
@@ -36,6 +37,9 @@ type SettingsReproduceEventLog struct {
 	SettingsBruteforceACMPolicyStatus
 	DisabledEventsMaxDistance uint64
 	MaxDigestRangeGuesses     uint64
+
+	// PCRIndex is the index of the PCR which measurements are verified.
+	PCRIndex tpm.PCRID
 }
 
 // DefaultSettingsReproduceEventLog returns recommended default PCR0 settings
@@ -243,7 +247,7 @@ func tryHardToExplainUnexpectedDigests(
 			continue
 		}
 		hashAlgo := logEntryExplainer.Event.Digest.HashAlgo
-		h, err := hashAlgo.Hash()
+		h, err := pcr.Hash(hashAlgo)
 		if err != nil {
 			logger.Warnf(ctx, "unable to get hasher for algo %s", hashAlgo)
 			continue
@@ -261,7 +265,7 @@ func tryHardToExplainUnexpectedDigests(
 	}
 
 	for hashAlgo, digests := range digestsPerAlgo {
-		h, err := hashAlgo.Hash()
+		h, err := pcr.Hash(hashAlgo)
 		if err != nil {
 			logger.Warnf(ctx, "unable to get hasher for algo %s", hashAlgo)
 			continue
@@ -412,7 +416,7 @@ func bruteForceACMPolicyStatus(
 
 	pcr0Data := m.RawBytes()
 
-	hashFactory, err := hashAlgo.Hash()
+	hashFactory, err := pcr.Hash(hashAlgo)
 	if err != nil {
 		return 0, fmt.Errorf("unable to initialize a hasher factory for algo %s: %w", hashAlgo, err)
 	}
@@ -484,7 +488,7 @@ func alignLogsAndMeasurements(
 	measurementsCalculatedUnaligned, eventsCalculatedUnaligned, coordsUnaligned, err := alignLogAndMeasurements(
 		ctx,
 		calculated,
-		0,
+		settings.PCRIndex,
 		hashAlgo,
 	)
 	if err != nil {
@@ -681,7 +685,7 @@ func alignLogs(
 	issues []Issue,
 	err error,
 ) {
-	_eventsExpected, err := eventLogExpected.FilterEvents(0, hashAlgo)
+	_eventsExpected, err := eventLogExpected.FilterEvents(settings.PCRIndex, hashAlgo)
 	if err != nil {
 		err = fmt.Errorf("unable to filter TPM EventLog events: %w", err)
 		return
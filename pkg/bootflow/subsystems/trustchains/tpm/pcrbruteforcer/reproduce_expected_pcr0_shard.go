@@ -0,0 +1,190 @@
+package pcrbruteforcer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm"
+	"github.com/9elements/converged-security-suite/v2/pkg/bruteforcer"
+	"github.com/9elements/converged-security-suite/v2/pkg/registers"
+)
+
+// reproducePCR0ShardLocalities is the order of localities in the search space
+// of ReproduceExpectedPCR0Shard.
+var reproducePCR0ShardLocalities = []uint8{0, 3}
+
+// ReproducePCR0ShardResult is a serializable form of ReproducePCR0Result,
+// which is stored in checkpoints of ReproduceExpectedPCR0Shard.
+type ReproducePCR0ShardResult struct {
+	Locality        uint8
+	ACMPolicyStatus *registers.ACMPolicyStatus
+
+	// DisabledMeasurements are indexes of the disabled measurements among
+	// the PCR0 measurements of the hash algorithm.
+	DisabledMeasurements []int
+	OrderSwaps           OrderSwaps
+}
+
+// ReproduceExpectedPCR0Shard is the same as ReproduceExpectedPCR0, but it
+// processes only the given shard of the search space and saves the progress
+// to checkpointPath (if not empty), so that the search could be interrupted
+// and resumed, or distributed among multiple processes (one shard per process).
+//
+// The results of all shards are combined by MergeReproduceExpectedPCR0Shards.
+//
+// The search space is ordered by the amount of disabled measurements and
+// then by locality, thus the merged result is deterministic and does not
+// depend on the sharding.
+func ReproduceExpectedPCR0Shard(
+	ctx context.Context,
+	measurements tpm.CommandLog,
+	hashAlgo tpm.Algorithm,
+	expectedPCR0 tpm.Digest,
+	settings SettingsReproducePCR0,
+	shard bruteforcer.Shard,
+	checkpointPath string,
+	shardSettings bruteforcer.ShardSettings,
+) (*bruteforcer.Checkpoint[ReproducePCR0ShardResult], error) {
+	handler, err := newReproduceExpectedPCR0Handler(
+		measurements,
+		hashAlgo,
+		expectedPCR0,
+		settings,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize a handler: %w", err)
+	}
+
+	jobs := make([]*reproduceExpectedPCR0Job, 0, len(reproducePCR0ShardLocalities))
+	for _, locality := range reproducePCR0ShardLocalities {
+		jobs = append(jobs, handler.newJob(locality))
+	}
+
+	space, segmentDisabledCounts := handler.searchSpace()
+	search := func(
+		ctx context.Context,
+		segment int,
+		combinationID uint64,
+		count uint64,
+	) (bool, uint64, ReproducePCR0ShardResult, error) {
+		job := jobs[segment%len(jobs)]
+		iterator := bruteforcer.NewUniqueUnorderedCombinationIterator(segmentDisabledCounts[segment], int64(len(job.measurements)))
+		if combinationID != 0 {
+			iterator.SetCombinationID(combinationID)
+		}
+		tpmInstance := tpm.NewTPM()
+		buf := make([]bool, len(job.measurements))
+		for idx := uint64(0); idx < count; idx++ {
+			if isDone(ctx) {
+				return false, 0, ReproducePCR0ShardResult{}, ctx.Err()
+			}
+			comb := iterator.GetCombinationUnsafe()
+			isSuccess, orderSwaps, acmPolicyStatus, err := job.tryDisabledMeasurementsCombination(ctx, tpmInstance, comb, buf)
+			if err != nil {
+				return false, 0, ReproducePCR0ShardResult{}, err
+			}
+			if isSuccess {
+				var disabledMeasurements []int
+				for _, disabledIdx := range comb {
+					if int(disabledIdx) < len(job.measurements) {
+						disabledMeasurements = append(disabledMeasurements, int(disabledIdx))
+					}
+				}
+				return true, combinationID + idx, ReproducePCR0ShardResult{
+					Locality:             job.tpmInitCmd.Locality,
+					ACMPolicyStatus:      acmPolicyStatus,
+					DisabledMeasurements: disabledMeasurements,
+					OrderSwaps:           orderSwaps,
+				}, nil
+			}
+			if !iterator.Next() {
+				break
+			}
+		}
+		return false, 0, ReproducePCR0ShardResult{}, nil
+	}
+
+	return bruteforcer.RunShard(
+		ctx,
+		space,
+		shard,
+		handler.fingerprint(),
+		checkpointPath,
+		shardSettings,
+		search,
+	)
+}
+
+// searchSpace returns the search space of ReproduceExpectedPCR0Shard and
+// the amount of disabled measurements for each segment of it.
+func (h *reproduceExpectedPCR0Handler) searchSpace() (bruteforcer.SearchSpace, []uint64) {
+	maxDisabledMeasurements := len(h.filteredMeasurements)
+	if h.settings.MaxDisabledMeasurements < maxDisabledMeasurements {
+		maxDisabledMeasurements = h.settings.MaxDisabledMeasurements
+	}
+
+	var (
+		space                 bruteforcer.SearchSpace
+		segmentDisabledCounts []uint64
+	)
+	for disabledMeasurements := 0; disabledMeasurements < maxDisabledMeasurements; disabledMeasurements++ {
+		amount := bruteforcer.NewUniqueUnorderedCombinationIterator(uint64(disabledMeasurements), int64(len(h.filteredMeasurements))).AmountOfCombinations()
+		for range reproducePCR0ShardLocalities {
+			space = append(space, amount)
+			segmentDisabledCounts = append(segmentDisabledCounts, uint64(disabledMeasurements))
+		}
+	}
+	return space, segmentDisabledCounts
+}
+
+// fingerprint identifies the input data and settings of the search, see
+// bruteforcer.Checkpoint.Fingerprint.
+func (h *reproduceExpectedPCR0Handler) fingerprint() string {
+	measurements := make([]string, 0, len(h.filteredMeasurements))
+	for _, m := range h.filteredMeasurements {
+		measurements = append(measurements, fmt.Sprint(m.Command))
+	}
+	return bruteforcer.Fingerprint(
+		h.hashAlgo,
+		[]byte(h.expectedPCR0),
+		h.settings,
+		measurements,
+	)
+}
+
+// MergeReproduceExpectedPCR0Shards combines the checkpoints of all shards
+// processed by ReproduceExpectedPCR0Shard into the result of the search.
+//
+// `measurements` and `hashAlgo` should be the same as were passed to
+// ReproduceExpectedPCR0Shard.
+//
+// It returns nil if all the shards are finished and PCR0 was not reproduced,
+// and bruteforcer.ErrShardIncomplete if the result depends on a shard
+// which is not finished yet.
+func MergeReproduceExpectedPCR0Shards(
+	measurements tpm.CommandLog,
+	hashAlgo tpm.Algorithm,
+	checkpoints []*bruteforcer.Checkpoint[ReproducePCR0ShardResult],
+) (*ReproducePCR0Result, error) {
+	found, err := bruteforcer.MergeCheckpoints(checkpoints)
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, nil
+	}
+
+	filtered := filteredMeasurements(measurements, hashAlgo)
+	result := &ReproducePCR0Result{
+		Locality:        found.Result.Locality,
+		ACMPolicyStatus: found.Result.ACMPolicyStatus,
+		OrderSwaps:      found.Result.OrderSwaps,
+	}
+	for _, idx := range found.Result.DisabledMeasurements {
+		if idx < 0 || idx >= len(filtered) {
+			return nil, fmt.Errorf("disabled measurement index %d is out of range [0, %d)", idx, len(filtered))
+		}
+		result.DisabledMeasurements = append(result.DisabledMeasurements, filtered[idx])
+	}
+	return result, nil
+}
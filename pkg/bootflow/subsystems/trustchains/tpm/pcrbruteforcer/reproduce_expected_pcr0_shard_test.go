@@ -0,0 +1,65 @@
+package pcrbruteforcer
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/bootengine"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/intelpch"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/biosimage"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/txtpublic"
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/types"
+	"github.com/9elements/converged-security-suite/v2/pkg/bruteforcer"
+	"github.com/9elements/converged-security-suite/v2/pkg/registers"
+	"github.com/9elements/converged-security-suite/v2/testdata/firmware"
+	"github.com/google/go-tpm/legacy/tpm2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReproduceExpectedPCR0Shard(t *testing.T) {
+	ctx := context.Background()
+
+	tpmInstance := tpm.NewTPM()
+	state := types.NewState()
+	state.IncludeSubSystem(tpmInstance)
+	state.IncludeSubSystem(intelpch.NewPCH())
+	state.IncludeSystemArtifact(biosimage.New(firmware.FakeIntelFirmware))
+	state.IncludeSystemArtifact(&txtpublic.TXTPublic{
+		Registers: registers.Registers{registers.ParseACMPolicyStatusRegister(0x0000000200108681)},
+	})
+	state.SetFlow(testFlow)
+	process := bootengine.NewBootProcess(state)
+	process.Finish(ctx)
+	require.NoError(t, process.Log.Error())
+
+	// PCR0 without "PCD Firmware Vendor Version" and "Separator",
+	// see TestReproduceExpectedPCR0.
+	pcr0 := unhex(t, "4CB03F39E94B0AB4AD99F9A54E3FD0DEFB0BB2D4")
+	settings := DefaultSettingsReproducePCR0()
+
+	dir := t.TempDir()
+	var checkpoints []*bruteforcer.Checkpoint[ReproducePCR0ShardResult]
+	for index := uint64(0); index < 3; index++ {
+		shard := bruteforcer.Shard{Index: index, Count: 3}
+		path := filepath.Join(dir, fmt.Sprintf("%d.json", index))
+		cp, err := ReproduceExpectedPCR0Shard(ctx, tpmInstance.CommandLog, tpm2.AlgSHA1, pcr0, settings, shard, path, bruteforcer.ShardSettings{ChunkSize: 2})
+		require.NoError(t, err)
+		require.True(t, cp.IsDone())
+
+		loaded, err := bruteforcer.LoadCheckpoint[ReproducePCR0ShardResult](path)
+		require.NoError(t, err)
+		checkpoints = append(checkpoints, loaded)
+	}
+
+	result, err := MergeReproduceExpectedPCR0Shards(tpmInstance.CommandLog, tpm2.AlgSHA1, checkpoints)
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	require.Equal(t, uint8(3), result.Locality)
+	require.Len(t, result.DisabledMeasurements, 2)
+
+	_, err = MergeReproduceExpectedPCR0Shards(tpmInstance.CommandLog, tpm2.AlgSHA1, checkpoints[1:])
+	require.ErrorAs(t, err, &bruteforcer.ErrShardIncomplete{})
+}
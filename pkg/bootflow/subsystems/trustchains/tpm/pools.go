@@ -4,6 +4,7 @@ import (
 	"hash"
 	"sync"
 
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
 	"github.com/google/go-tpm/legacy/tpm2"
 )
 
@@ -22,7 +23,7 @@ func acquireHasher(algo tpm2.Algorithm) (*hasher, error) {
 		return r.(*hasher), nil
 	}
 
-	h, err := algo.Hash()
+	h, err := pcr.Hash(algo)
 	if err != nil {
 		return nil, err
 	}
@@ -2,18 +2,20 @@ package tpm
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/types"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmdetection"
 	"github.com/9elements/converged-security-suite/v2/pkg/tpmeventlog"
 	"github.com/google/go-tpm/legacy/tpm2"
 )
 
 const (
-	// currently we support only PCR0 and PCR1
+	// PCRRegistersAmount is the amount of PCRs of the simulated TPM.
 	//
 	// TODO: move this value into TPM settings
-	PCRRegistersAmount = 2
+	PCRRegistersAmount = pcr.Amount
 )
 
 var _ types.SubSystem = (*TPM)(nil)
@@ -24,15 +26,31 @@ type Algorithm = pcr.Algorithm
 // TPM is a TrustChain implementation which represents
 // measured boot backed by a Trusted Platform Module (TPM).
 type TPM struct {
+	// Type is the TPM family (TPM1.2 or TPM2.0).
+	//
+	// TPM1.2 has only a SHA1 PCR bank and does not encode
+	// the startup locality into PCR0.
+	Type tpmdetection.Type
+
 	SupportedAlgos []Algorithm
 	PCRValues      PCRValues
 	CommandLog     CommandLog
 	EventLog       EventLog
+
+	// pcrValuesBuffer is the storage for PCRValues.
+	pcrValuesBuffer []byte
 }
 
-// NewTPM returns a new instance of TPM.
+// NewTPM returns a new instance of TPM (TPM2.0).
 func NewTPM() *TPM {
-	tpm := &TPM{}
+	return NewTPMOfType(tpmdetection.TypeTPM20)
+}
+
+// NewTPMOfType returns a new instance of TPM of the given family.
+func NewTPMOfType(tpmType tpmdetection.Type) *TPM {
+	tpm := &TPM{
+		Type: tpmType,
+	}
 	tpm.init()
 	return tpm
 }
@@ -59,13 +77,16 @@ func (tpm *TPM) DoNotUse_ResetNoInit() {
 }
 
 func (tpm *TPM) init() {
-	if cap(tpm.SupportedAlgos) < len(cachedSupportedHashAlgos) {
-		tpm.SupportedAlgos = SupportedHashAlgos()
-		return
+	supportedAlgos := cachedSupportedHashAlgos
+	if tpm.Type == tpmdetection.TypeTPM12 {
+		supportedAlgos = tpm12HashAlgos
 	}
 
-	tpm.SupportedAlgos = tpm.SupportedAlgos[:len(cachedSupportedHashAlgos)]
-	copy(tpm.SupportedAlgos, cachedSupportedHashAlgos)
+	if cap(tpm.SupportedAlgos) < len(supportedAlgos) {
+		tpm.SupportedAlgos = make([]Algorithm, len(supportedAlgos))
+	}
+	tpm.SupportedAlgos = tpm.SupportedAlgos[:len(supportedAlgos)]
+	copy(tpm.SupportedAlgos, supportedAlgos)
 }
 
 // CommandLogInfoProvider is an abstract provider of additional/optional information
@@ -75,16 +96,34 @@ type CommandLogInfoProvider interface {
 	CauseAction() types.Action
 }
 
-// SupportedHashAlgos the list of currently supported hashing algorithms.
+// SupportedHashAlgos the list of currently supported hashing algorithms (PCR banks).
 func SupportedHashAlgos() []Algorithm {
 	return []Algorithm{
 		tpm2.AlgSHA1,
 		tpm2.AlgSHA256,
+		tpm2.AlgSHA384,
+		tpm2.AlgSHA512,
+		pcr.AlgSM3_256,
 	}
 }
 
+// tpm12HashAlgos is the list of hashing algorithms (PCR banks) of a TPM1.2.
+var tpm12HashAlgos = []Algorithm{
+	tpm2.AlgSHA1,
+}
+
 var tpmMaxHashAlgo Algorithm
 var cachedSupportedHashAlgos []Algorithm
+var cachedSupportedHashSizes []int
+var cachedSupportedHashSizesSum int
+
+// cachedHashSizeByAlgo is the digest size indexed by the hash algorithm
+// (zero for not supported algorithms).
+var cachedHashSizeByAlgo []int
+
+// cachedStartupPCRValues is the content of TPM.pcrValuesBuffer after
+// TPM2_Startup from locality 0, see CommandInit.
+var cachedStartupPCRValues []byte
 
 func init() {
 	supportedAlgos := SupportedHashAlgos()
@@ -93,9 +132,52 @@ func init() {
 		if algo > tpmMaxHashAlgo {
 			tpmMaxHashAlgo = algo
 		}
+		h, err := pcr.Hash(algo)
+		if err != nil {
+			panic(fmt.Errorf("unable to initialize a hasher factory for hash algo %v: %w", algo, err))
+		}
+		cachedSupportedHashSizes = append(cachedSupportedHashSizes, h.Size())
+		cachedSupportedHashSizesSum += h.Size()
 	}
 
 	cachedSupportedHashAlgos = supportedAlgos
+
+	cachedHashSizeByAlgo = make([]int, int(tpmMaxHashAlgo)+1)
+	for algoIdx, algo := range supportedAlgos {
+		cachedHashSizeByAlgo[algo] = cachedSupportedHashSizes[algoIdx]
+	}
+
+	cachedStartupPCRValues = make([]byte, 0, PCRRegistersAmount*cachedSupportedHashSizesSum)
+	for pcrID := PCRID(0); pcrID < PCRRegistersAmount; pcrID++ {
+		for _, size := range cachedSupportedHashSizes {
+			pcrValue := make([]byte, size)
+			pcrID.SetStartupValue(pcrValue, 0)
+			cachedStartupPCRValues = append(cachedStartupPCRValues, pcrValue...)
+		}
+	}
+}
+
+// hashSize returns the digest size of the given hash algorithm,
+// or zero if the algorithm is not supported.
+func hashSize(algo Algorithm) int {
+	if int(algo) >= len(cachedHashSizeByAlgo) {
+		return 0
+	}
+	return cachedHashSizeByAlgo[algo]
+}
+
+// isAllSupportedHashAlgos returns true if algos is exactly
+// the list of SupportedHashAlgos.
+func isAllSupportedHashAlgos(algos []Algorithm) bool {
+	if len(algos) != len(cachedSupportedHashAlgos) {
+		return false
+	}
+	for idx, algo := range algos {
+		if algo != cachedSupportedHashAlgos[idx] {
+			return false
+		}
+	}
+	return true
 }
 
 // GetFrom returns a TPM given a State.
@@ -139,6 +221,14 @@ func (tpm *TPM) TPMInit(ctx context.Context, locality uint8, info CommandLogInfo
 	), info)
 }
 
+// TPMReset is just a wrapper which creates a CommandReset and executes it.
+func (tpm *TPM) TPMReset(ctx context.Context, pcrIndex PCRID, locality uint8, info CommandLogInfoProvider) error {
+	return tpm.TPMExecute(ctx, NewCommandReset(
+		pcrIndex,
+		locality,
+	), info)
+}
+
 // TPMExtend is just a wrapper which creates CommandExtend and executes it.
 func (tpm *TPM) TPMExtend(
 	ctx context.Context,
@@ -27,7 +27,8 @@ func getOrCreateFromImage[T any, A Accessor[T]](img *biosimage.BIOSImage, c cach
 	if c == nil {
 		c = cache.DummyCache{}
 	}
-	A(&newAccessor).Init(img, c)
+	// the same cache may be shared by accessors of different images
+	A(&newAccessor).Init(img, cache.NewScoped(c, img))
 	return &newAccessor
 }
 
@@ -42,14 +43,25 @@ func GetOrCreate[T any, A Accessor[T]](ctx context.Context, s *types.State) (*T,
 	return getOrCreateFromImage[T, A](img, cache.FromCtx(ctx)), nil
 }
 
+type memoizeKey struct {
+	resultType reflect.Type
+	function   uintptr
+}
+
 // Memoize caches the result of a function and reuses it on a next call.
+//
+// The result is cached per result type and per function, so different
+// functions returning the same type do not collide.
 func Memoize[R any](c cache.Cache, calculate func() R) R {
-	var zeroResult R
-	if result, ok := c.Get(zeroResult); ok {
+	key := memoizeKey{
+		resultType: reflect.TypeOf((*R)(nil)).Elem(),
+		function:   reflect.ValueOf(calculate).Pointer(),
+	}
+	if result, ok := c.Get(key); ok {
 		return result.(R)
 	}
 
 	result := calculate()
-	c.Set(zeroResult, result)
+	c.Set(key, result)
 	return result
 }
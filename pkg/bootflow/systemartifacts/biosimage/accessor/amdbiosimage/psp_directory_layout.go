@@ -0,0 +1,92 @@
+package amdbiosimage
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/biosimage/accessor"
+)
+
+// PSPDirectoryLayout defines how the PSP directory is referenced
+// from the Embedded Firmware Structure.
+type PSPDirectoryLayout int
+
+const (
+	PSPDirectoryLayoutUndefined = PSPDirectoryLayout(iota)
+
+	// PSPDirectoryLayoutLegacy means the Embedded Firmware Structure
+	// points directly to a PSP directory ("$PSP"), for example on Milan.
+	PSPDirectoryLayoutLegacy
+
+	// PSPDirectoryLayoutCombo means the Embedded Firmware Structure
+	// points to a PSP combo directory ("2PSP"), which references
+	// PSP directories for different CPU families.
+	PSPDirectoryLayoutCombo
+)
+
+const (
+	// efsPSPDirectoryPointerOffset is the offset of field "PSP Directory Table pointer"
+	// within the Embedded Firmware Structure.
+	efsPSPDirectoryPointerOffset = 0x14
+
+	pspDirectoryCookie      = 0x50535024 // "$PSP"
+	pspComboDirectoryCookie = 0x50535032 // "2PSP"
+)
+
+func (l PSPDirectoryLayout) String() string {
+	switch l {
+	case PSPDirectoryLayoutUndefined:
+		return "undefined"
+	case PSPDirectoryLayoutLegacy:
+		return "legacy"
+	case PSPDirectoryLayoutCombo:
+		return "combo"
+	}
+	return fmt.Sprintf("unknown_value_%d", int(l))
+}
+
+// PSPDirectoryLayout returns the layout of the PSP directory referenced
+// by the Embedded Firmware Structure.
+func (a *Accessor) PSPDirectoryLayout() (PSPDirectoryLayout, error) {
+	result := accessor.Memoize(a.Cache, func() (result struct {
+		layout PSPDirectoryLayout
+		err    error
+	}) {
+		result.layout, result.err = a.pspDirectoryLayout()
+		return
+	})
+
+	return result.layout, result.err
+}
+
+func (a *Accessor) pspDirectoryLayout() (PSPDirectoryLayout, error) {
+	amdFW, err := a.AMDFirmware()
+	if err != nil {
+		return PSPDirectoryLayoutUndefined, fmt.Errorf("unable to get AMD firmware: %w", err)
+	}
+	image := a.Image.Content
+
+	efsOffset := amdFW.PSPFirmware().EmbeddedFirmwareRange.Offset
+	if efsOffset+efsPSPDirectoryPointerOffset+4 > uint64(len(image)) {
+		return PSPDirectoryLayoutUndefined, fmt.Errorf("the Embedded Firmware Structure at 0x%X is out of the image bounds", efsOffset)
+	}
+	pspDirectoryPointer := uint64(binary.LittleEndian.Uint32(image[efsOffset+efsPSPDirectoryPointerOffset:]))
+
+	pspDirectoryOffset := pspDirectoryPointer
+	if pspDirectoryOffset >= uint64(len(image)) {
+		// it is a physical address, not an offset
+		pspDirectoryOffset = amdImgWrapper{Image: a.Image}.PhysAddrToOffset(pspDirectoryPointer)
+	}
+	if pspDirectoryOffset+4 > uint64(len(image)) {
+		return PSPDirectoryLayoutUndefined, fmt.Errorf("the PSP directory pointer 0x%X is out of the image bounds", pspDirectoryPointer)
+	}
+
+	switch cookie := binary.LittleEndian.Uint32(image[pspDirectoryOffset:]); cookie {
+	case pspDirectoryCookie:
+		return PSPDirectoryLayoutLegacy, nil
+	case pspComboDirectoryCookie:
+		return PSPDirectoryLayoutCombo, nil
+	default:
+		return PSPDirectoryLayoutUndefined, fmt.Errorf("unexpected PSP directory cookie 0x%08X at offset 0x%X", cookie, pspDirectoryOffset)
+	}
+}
@@ -0,0 +1,18 @@
+package uefibiosimage
+
+import (
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/systemartifacts/biosimage/accessor"
+	"github.com/9elements/converged-security-suite/v2/pkg/uefi/varstore"
+)
+
+// VariableStores returns the UEFI variable stores found in the image.
+func (a *Accessor) VariableStores() (varstore.Stores, error) {
+	result := accessor.Memoize(a.Cache, func() (result struct {
+		stores varstore.Stores
+		err    error
+	}) {
+		result.stores, result.err = varstore.FindStores(a.Image.Content)
+		return
+	})
+	return result.stores, result.err
+}
@@ -2,12 +2,15 @@ package biosimage
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"fmt"
 	"reflect"
 
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/cache"
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/types"
 	"github.com/9elements/converged-security-suite/v2/pkg/dmidecode"
 	"github.com/9elements/converged-security-suite/v2/pkg/uefi"
+	fianoUEFI "github.com/linuxboot/fiano/pkg/uefi"
 )
 
 var _ types.SystemArtifact = (*BIOSImage)(nil)
@@ -17,7 +20,14 @@ type BIOSImage struct {
 	Content         []byte
 	CacheParsed     *uefi.UEFI
 	CacheParseError error
+	CacheSHA256     *[sha256.Size]byte
+	CacheLayout     *Layout
 	Accessors       map[reflect.Type]any
+
+	// Cache (if not nil) is used by Parse and Layout to reuse the results
+	// for the same image content parsed before. Layouts could also be
+	// reused by another process (see cache.Disk).
+	Cache cache.Cache
 }
 
 // New returns a new instance of BIOSImage.
@@ -50,12 +60,41 @@ func (img *BIOSImage) ReadAt(b []byte, offset int64) (n int, err error) {
 	return bytes.NewReader(img.Content).ReadAt(b, offset)
 }
 
+// parsedKey is the Cache key of the result of Parse.
+type parsedKey struct {
+	SHA256        [sha256.Size]byte
+	Decompression bool
+}
+
+type parsedResult struct {
+	Parsed *uefi.UEFI
+	Err    error
+}
+
 // Parse returns a parsed UEFI image.
 func (fw *BIOSImage) Parse() (*uefi.UEFI, error) {
-	if fw.CacheParsed == nil && fw.CacheParseError == nil {
-		fw.CacheParsed, fw.CacheParseError = uefi.ParseUEFIFirmwareBytes(fw.Content)
+	if fw.CacheParsed != nil || fw.CacheParseError != nil {
+		return fw.CacheParsed, fw.CacheParseError
+	}
+
+	var key parsedKey
+	if fw.Cache != nil {
+		key = parsedKey{
+			SHA256:        fw.SHA256(),
+			Decompression: !fianoUEFI.DisableDecompression,
+		}
+		if v, ok := fw.Cache.Get(key); ok {
+			if result, ok := v.(parsedResult); ok {
+				fw.CacheParsed, fw.CacheParseError = result.Parsed, result.Err
+				return fw.CacheParsed, fw.CacheParseError
+			}
+		}
 	}
 
+	fw.CacheParsed, fw.CacheParseError = uefi.ParseUEFIFirmwareBytes(fw.Content)
+	if fw.Cache != nil {
+		fw.Cache.Set(key, parsedResult{Parsed: fw.CacheParsed, Err: fw.CacheParseError})
+	}
 	return fw.CacheParsed, fw.CacheParseError
 }
 
@@ -0,0 +1,329 @@
+package biosimage
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	fianoGUID "github.com/linuxboot/fiano/pkg/guid"
+	"github.com/linuxboot/fiano/pkg/intel/metadata/fit"
+	fianoUEFI "github.com/linuxboot/fiano/pkg/uefi"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/cache"
+	"github.com/9elements/converged-security-suite/v2/pkg/pcd"
+	"github.com/9elements/converged-security-suite/v2/pkg/uefi/ffs"
+	pkgbytes "github.com/linuxboot/fiano/pkg/bytes"
+)
+
+func init() {
+	gob.Register(&Layout{})
+}
+
+// Layout is the layout of a BIOS firmware image extracted from
+// the parsed image. Unlike *uefi.UEFI it contains only plain data,
+// so it could be cached on the disk (see cache.Disk).
+type Layout struct {
+	// Nodes are the UEFI nodes in the order of a depth-first walk.
+	Nodes []LayoutNode
+
+	// RangeNodes are indexes of Nodes with a detected range in the order
+	// returned by (*uefi.UEFI).GetByRange.
+	RangeNodes []int
+
+	// NameToRanges is the result of (*uefi.UEFI).NameToRangesMap.
+	NameToRanges map[string]pkgbytes.Ranges
+
+	// FIT are the entries of the Intel Firmware Interface Table.
+	FIT []LayoutFITEntry
+
+	// FITError is the error of parsing the FIT (for example, if there
+	// is no FIT in the image), see FITEntries.
+	FITError string
+
+	// PCD is the PCD data of the image (see package pcd).
+	PCD LayoutPCD
+
+	// UEFIError is the error of parsing the UEFI layout. If it is set,
+	// then only FIT and FITError are filled.
+	UEFIError string
+}
+
+// LayoutFITEntry is an entry of the Intel Firmware Interface Table.
+type LayoutFITEntry struct {
+	Type fit.EntryType
+
+	// Address is the physical address of the data of the entry.
+	Address uint64
+
+	// DataSize is the size of the data segment of the entry.
+	DataSize uint64
+}
+
+// LayoutPCD is the PCD firmware vendor version of the image.
+type LayoutPCD struct {
+	// IsAMI is the result of pcd.IsAMI.
+	IsAMI bool
+
+	// Found is true if a PCD parser recognized the image.
+	Found bool
+
+	// FirmwareVendorVersion is the firmware vendor version value.
+	FirmwareVendorVersion []byte
+
+	// FirmwareVendorVersionRanges are the ranges of the firmware vendor
+	// version value within the image, empty if the value is not stored
+	// in the image.
+	FirmwareVendorVersionRanges pkgbytes.Ranges
+
+	// Warning is the non-fatal error returned by the PCD parser.
+	Warning string
+
+	// Error is the error of parsing the PCD.
+	Error string
+}
+
+// FITEntries returns the entries of the Intel Firmware Interface Table.
+func (l *Layout) FITEntries() ([]LayoutFITEntry, error) {
+	if l.FITError != "" {
+		return nil, errors.New(l.FITError)
+	}
+	return l.FIT, nil
+}
+
+// BIOSRegion returns the range of the BIOS region within the image.
+func (l *Layout) BIOSRegion() (pkgbytes.Range, error) {
+	var result []pkgbytes.Range
+	for _, node := range l.Nodes {
+		if node.Type != layoutNodeTypeBIOSRegion || !node.HasRange {
+			continue
+		}
+		result = append(result, node.Range)
+	}
+	if len(result) != 1 {
+		return pkgbytes.Range{}, fmt.Errorf("expected exactly one BIOS region, but found %d", len(result))
+	}
+	return result[0], nil
+}
+
+var layoutNodeTypeBIOSRegion = fmt.Sprintf("%T", &fianoUEFI.BIOSRegion{})
+
+// LayoutNode is an UEFI node of a Layout.
+type LayoutNode struct {
+	// Level is the nesting level of the node (0 for the root node).
+	Level int
+
+	// Type is the Go type of the fiano's node (for example "*uefi.File").
+	Type string
+
+	// GUID is the GUID of a file or a volume, nil otherwise.
+	GUID *fianoGUID.GUID
+
+	// ModuleName is the module name of a file, nil if there is none.
+	ModuleName *string
+
+	// Name is the result of String() for nodes other than files,
+	// volumes and BIOS regions.
+	Name string
+
+	// Range is the range of the node within the image, it is valid
+	// only if HasRange is true.
+	Range    pkgbytes.Range
+	HasRange bool
+}
+
+// LayoutKey is the cache key of a Layout.
+type LayoutKey struct {
+	SHA256 [sha256.Size]byte
+
+	// Decompression is true if compressed sections were parsed
+	// (see fianoUEFI.DisableDecompression).
+	Decompression bool
+}
+
+var _ cache.Persistent = LayoutKey{}
+
+// PersistentKey implements cache.Persistent.
+func (k LayoutKey) PersistentKey() string {
+	suffix := ""
+	if k.Decompression {
+		suffix = "-decompressed"
+	}
+	return "biosimage-layout-" + hex.EncodeToString(k.SHA256[:]) + suffix
+}
+
+// SHA256 returns the SHA256 hash of the image content.
+func (img *BIOSImage) SHA256() [sha256.Size]byte {
+	if img.CacheSHA256 == nil {
+		hash := sha256.Sum256(img.Content)
+		img.CacheSHA256 = &hash
+	}
+	return *img.CacheSHA256
+}
+
+// Layout returns the Layout of the image.
+//
+// The Layout is looked up in Cache (if it is set) by the hash of
+// the image, and only on a cache miss the image is parsed.
+//
+// If the UEFI layout could not be parsed, then the error is returned
+// together with the Layout, which has only the FIT data.
+func (img *BIOSImage) Layout() (*Layout, error) {
+	if img.CacheLayout != nil {
+		return img.CacheLayout, img.CacheLayout.uefiErr()
+	}
+
+	key := LayoutKey{
+		SHA256:        img.SHA256(),
+		Decompression: !fianoUEFI.DisableDecompression,
+	}
+	if img.Cache != nil {
+		if v, ok := img.Cache.Get(key); ok {
+			if layout, ok := v.(*Layout); ok {
+				img.CacheLayout = layout
+				return layout, layout.uefiErr()
+			}
+		}
+	}
+
+	layout := img.parseLayout()
+	if img.Cache != nil {
+		img.Cache.Set(key, layout)
+	}
+	img.CacheLayout = layout
+	return layout, layout.uefiErr()
+}
+
+func (l *Layout) uefiErr() error {
+	if l.UEFIError == "" {
+		return nil
+	}
+	return errors.New(l.UEFIError)
+}
+
+func (img *BIOSImage) parseLayout() *Layout {
+	layout := &Layout{}
+
+	fitEntries, err := fit.GetEntries(img.Content)
+	if err != nil {
+		layout.FITError = fmt.Sprintf("unable to parse FIT table: %v", err)
+	}
+	for _, entry := range fitEntries {
+		entryBase := entry.GetEntryBase()
+		layout.FIT = append(layout.FIT, LayoutFITEntry{
+			Type:     entryBase.Headers.Type(),
+			Address:  entryBase.Headers.Address.Pointer(),
+			DataSize: uint64(len(entryBase.DataSegmentBytes)),
+		})
+	}
+
+	if err := layout.parseUEFI(img); err != nil {
+		layout.UEFIError = err.Error()
+	}
+	return layout
+}
+
+func (l *Layout) parseUEFI(img *BIOSImage) error {
+	parsed, err := img.Parse()
+	if err != nil {
+		return fmt.Errorf("unable to parse the firmware image: %w", err)
+	}
+
+	rangeNodes, err := parsed.GetByRange(pkgbytes.Range{
+		Offset: 0,
+		Length: uint64(len(parsed.Buf())),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to scan for UEFI nodes: %w", err)
+	}
+	rangeMap := make(map[fianoUEFI.Firmware]pkgbytes.Range, len(rangeNodes))
+	for _, node := range rangeNodes {
+		rangeMap[node.Firmware] = node.Range
+	}
+
+	l.NameToRanges = parsed.NameToRangesMap()
+	nodeIndex := map[fianoUEFI.Firmware]int{}
+	err = parsed.Apply(&layoutVisitor{Callback: func(level int, f fianoUEFI.Firmware) {
+		nodeIndex[f] = len(l.Nodes)
+		l.Nodes = append(l.Nodes, newLayoutNode(level, f, rangeMap))
+	}})
+	if err != nil {
+		return fmt.Errorf("unable to walk UEFI nodes: %w", err)
+	}
+	for _, node := range rangeNodes {
+		idx, ok := nodeIndex[node.Firmware]
+		if !ok {
+			// is not expected to happen, since both walk the same tree
+			continue
+		}
+		l.RangeNodes = append(l.RangeNodes, idx)
+	}
+
+	l.PCD = newLayoutPCD(parsed)
+	return nil
+}
+
+func newLayoutPCD(parsed pcd.FirmwareImage) LayoutPCD {
+	var result LayoutPCD
+
+	isAMI, err := pcd.IsAMI(parsed)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.IsAMI = isAMI
+
+	pcdParsed, err := pcd.ParseFirmware(parsed)
+	var errUnknownVendor *pcd.ErrUnknownVendorType
+	switch {
+	case pcdParsed == nil && (err == nil || errors.As(err, &errUnknownVendor)):
+		// no known TCG PEI module
+		return result
+	case pcdParsed == nil:
+		result.Error = fmt.Sprintf("unable to parse PCD: %v", err)
+		return result
+	case err != nil:
+		result.Warning = err.Error()
+	}
+	result.Found = true
+	result.FirmwareVendorVersion = pcdParsed.GetFirmwareVendorVersion()
+	result.FirmwareVendorVersionRanges = pcdParsed.GetFirmwareVendorVersionRanges()
+	return result
+}
+
+func newLayoutNode(level int, f fianoUEFI.Firmware, rangeMap map[fianoUEFI.Firmware]pkgbytes.Range) LayoutNode {
+	node := ffs.Node{Firmware: f}
+	result := LayoutNode{
+		Level:      level,
+		Type:       fmt.Sprintf("%T", f),
+		GUID:       node.GUID(),
+		ModuleName: node.ModuleName(),
+	}
+	switch f.(type) {
+	case *fianoUEFI.FirmwareVolume, *fianoUEFI.File, *fianoUEFI.BIOSRegion:
+	default:
+		if stringer, ok := f.(fmt.Stringer); ok {
+			result.Name = stringer.String()
+		}
+	}
+	result.Range, result.HasRange = rangeMap[f]
+	return result
+}
+
+type layoutVisitor struct {
+	Callback func(level int, f fianoUEFI.Firmware)
+	Level    int
+}
+
+func (v *layoutVisitor) Run(f fianoUEFI.Firmware) error {
+	return f.Apply(v)
+}
+
+func (v *layoutVisitor) Visit(f fianoUEFI.Firmware) error {
+	v.Callback(v.Level, f)
+	return f.ApplyChildren(&layoutVisitor{
+		Callback: v.Callback,
+		Level:    v.Level + 1,
+	})
+}
@@ -0,0 +1,106 @@
+package biosimage
+
+import (
+	"testing"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/cache"
+	"github.com/9elements/converged-security-suite/v2/testdata/firmware"
+	pkgbytes "github.com/linuxboot/fiano/pkg/bytes"
+	"github.com/linuxboot/fiano/pkg/intel/metadata/fit"
+	fianoUEFI "github.com/linuxboot/fiano/pkg/uefi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLayout(t *testing.T) {
+	img := New(firmware.FakeIntelFirmware)
+	layout, err := img.Layout()
+	require.NoError(t, err)
+
+	parsed, err := img.Parse()
+	require.NoError(t, err)
+	rangeNodes, err := parsed.GetByRange(pkgbytes.Range{
+		Offset: 0,
+		Length: uint64(len(parsed.Buf())),
+	})
+	require.NoError(t, err)
+
+	require.NotEmpty(t, layout.Nodes)
+	require.Zero(t, layout.Nodes[0].Level)
+	require.Len(t, layout.RangeNodes, len(rangeNodes))
+	for idx, nodeIdx := range layout.RangeNodes {
+		node := layout.Nodes[nodeIdx]
+		require.True(t, node.HasRange)
+		require.Equal(t, rangeNodes[idx].Range, node.Range)
+	}
+	require.Equal(t, parsed.NameToRangesMap(), layout.NameToRanges)
+
+	fitEntries, err := fit.GetEntries(img.Content)
+	require.NoError(t, err)
+	layoutFIT, err := layout.FITEntries()
+	require.NoError(t, err)
+	require.Len(t, layoutFIT, len(fitEntries))
+	for idx, entry := range fitEntries {
+		require.Equal(t, entry.GetEntryBase().Headers.Type(), layoutFIT[idx].Type)
+		require.Equal(t, entry.GetEntryBase().Headers.Address.Pointer(), layoutFIT[idx].Address)
+		require.Equal(t, uint64(len(entry.GetEntryBase().DataSegmentBytes)), layoutFIT[idx].DataSize)
+	}
+
+	biosRegions, err := parsed.GetByRegionType(fianoUEFI.RegionTypeBIOS)
+	require.NoError(t, err)
+	require.Len(t, biosRegions, 1)
+	biosRegion, err := layout.BIOSRegion()
+	require.NoError(t, err)
+	require.Equal(t, biosRegions[0].Range, biosRegion)
+
+	// the result is kept in the image
+	layoutAgain, err := img.Layout()
+	require.NoError(t, err)
+	require.Same(t, layout, layoutAgain)
+}
+
+func TestLayoutCache(t *testing.T) {
+	c := cache.NewLRU(0, 0, nil)
+
+	img0 := New(firmware.FakeIntelFirmware)
+	img0.Cache = c
+	layout0, err := img0.Layout()
+	require.NoError(t, err)
+
+	img1 := New(firmware.FakeIntelFirmware)
+	img1.Cache = c
+	layout1, err := img1.Layout()
+	require.NoError(t, err)
+	require.Same(t, layout0, layout1)
+	require.Nil(t, img1.CacheParsed, "the image should not be parsed on a cache hit")
+
+	parsed0, err := img0.Parse()
+	require.NoError(t, err)
+	parsed1, err := img1.Parse()
+	require.NoError(t, err)
+	require.Same(t, parsed0, parsed1)
+}
+
+func TestLayoutDiskCache(t *testing.T) {
+	dir := t.TempDir()
+
+	c := cache.NewDisk(dir, "test", "v1", nil)
+	c.OnError = func(err error) { require.NoError(t, err) }
+	img := New(firmware.FakeIntelFirmware)
+	img.Cache = c
+	layout, err := img.Layout()
+	require.NoError(t, err)
+
+	// a new process
+	c = cache.NewDisk(dir, "test", "v1", nil)
+	c.OnError = func(err error) { require.NoError(t, err) }
+	img = New(firmware.FakeIntelFirmware)
+	img.Cache = c
+	cachedLayout, err := img.Layout()
+	require.NoError(t, err)
+	require.Nil(t, img.CacheParsed, "the image should not be parsed on a cache hit")
+	require.Equal(t, layout.Nodes, cachedLayout.Nodes)
+	require.Equal(t, layout.RangeNodes, cachedLayout.RangeNodes)
+	require.Equal(t, layout.NameToRanges, cachedLayout.NameToRanges)
+	require.Equal(t, layout.FIT, cachedLayout.FIT)
+	require.Equal(t, layout.PCD, cachedLayout.PCD)
+}
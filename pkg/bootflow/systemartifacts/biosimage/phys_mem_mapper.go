@@ -4,10 +4,8 @@ import (
 	"fmt"
 
 	pkgbytes "github.com/linuxboot/fiano/pkg/bytes"
-	"github.com/linuxboot/fiano/pkg/uefi"
 
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/types"
-	"github.com/9elements/converged-security-suite/v2/pkg/uefi/ffs"
 )
 
 // PhysMemMapper maps physical memory address space to a BIOS region of a system artifact BIOSImage.
@@ -91,24 +89,16 @@ func (PhysMemMapper) UnresolveBIOSRegionOffset(artifact types.SystemArtifact, ra
 	return result, nil
 }
 
-func getBIOSRegion(artifact types.SystemArtifact) (*ffs.Node, error) {
+func getBIOSRegion(artifact types.SystemArtifact) (pkgbytes.Range, error) {
 	img, ok := artifact.(*BIOSImage)
 	if !ok {
-		return nil, fmt.Errorf("artifact %T is not a BIOSImage", artifact)
+		return pkgbytes.Range{}, fmt.Errorf("artifact %T is not a BIOSImage", artifact)
 	}
 
-	root, err := img.Parse()
+	layout, err := img.Layout()
 	if err != nil {
-		return nil, fmt.Errorf("unable to parse UEFI: %w", err)
+		return pkgbytes.Range{}, fmt.Errorf("unable to parse UEFI: %w", err)
 	}
 
-	biosRegions, err := root.GetByRegionType(uefi.RegionTypeBIOS)
-	if err != nil {
-		return nil, fmt.Errorf("unable to find the BIOS region: %w", err)
-	}
-	if len(biosRegions) != 1 {
-		return nil, fmt.Errorf("expected exactly one BIOS region, but found %d", len(biosRegions))
-	}
-
-	return biosRegions[0], nil
+	return layout.BIOSRegion()
 }
@@ -1,6 +1,7 @@
 package bruteforcer
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"math/big"
@@ -231,3 +232,83 @@ func try[E Item, T Slice[E]](b *bruteForcer[E, T], ctx any, data T, iterator *Un
 	b.applyBitFlipsFunc(iterator.GetCombinationUnsafe(), data)
 	return false
 }
+
+// BruteForceShard is the same as BruteForce, but processes only one shard
+// of all combinations and saves the progress to checkpointPath (if not
+// empty), see RunShard. The results of all shards could be merged through
+// MergeCheckpoints, the merged result is the same as the result of BruteForce
+// with maxConcurrency equals 1.
+//
+// The checkpoint is bound to initialData, itemSize and the distances, but it
+// is the caller's responsibility to not reuse it for another checkFunc.
+func BruteForceShard[E Item, T Slice[E]](
+	ctx context.Context,
+	initialData T,
+	itemSize uint64,
+	minDistance uint64,
+	maxDistance uint64,
+	initFunc InitFunc,
+	checkFunc CheckFunc[E],
+	applyBitFlipsFunc ApplyBitFlipsFunc[E],
+	shard Shard,
+	checkpointPath string,
+	settings ShardSettings,
+) (*Checkpoint[UniqueUnorderedCombination], error) {
+	if minDistance > maxDistance {
+		return nil, fmt.Errorf("minimal distance (%d) is higher than maximal distance (%d)", minDistance, maxDistance)
+	}
+	b := newBruteForcer(initialData, initFunc, checkFunc, applyBitFlipsFunc)
+
+	totalBitLength := uint64(len(initialData)) * itemSize
+	if maxDistance > totalBitLength {
+		maxDistance = totalBitLength
+	}
+
+	var space SearchSpace
+	for distance := minDistance; distance <= maxDistance; distance++ {
+		amountOfCombinations := big.NewInt(1).Binomial(int64(totalBitLength), int64(distance))
+		if amountOfCombinations.Cmp(big.NewInt(math.MaxInt64)) >= 0 {
+			return nil, fmt.Errorf("distance is too high (amount of combinations causes uint64 overflow)")
+		}
+		space = append(space, amountOfCombinations.Uint64())
+	}
+	if space.Total() >= math.MaxInt64 {
+		return nil, fmt.Errorf("distances are too high (amount of combinations causes uint64 overflow)")
+	}
+
+	search := func(
+		ctx context.Context,
+		segment int,
+		combinationID uint64,
+		count uint64,
+	) (bool, uint64, UniqueUnorderedCombination, error) {
+		distance := minDistance + uint64(segment)
+		bfctx, err := b.initFunc()
+		if err != nil {
+			return false, 0, nil, fmt.Errorf("brute forcer initFunc error: %w", err)
+		}
+		if distance == 0 {
+			return b.checkFunc(bfctx, b.initialData), 0, NewUniqueUnorderedCombination(0), nil
+		}
+
+		dataCopy := make(T, len(b.initialData))
+		copy(dataCopy, b.initialData)
+		iterator := NewUniqueUnorderedCombinationIterator(distance, int64(totalBitLength)-1)
+		iterator.SetCombinationID(combinationID)
+		for i := uint64(0); i < count; i++ {
+			if i > 0 {
+				iterator.Next()
+			}
+			if try(b, bfctx, dataCopy, iterator) {
+				return true, combinationID + i, iterator.GetCombination(), nil
+			}
+			if i%minIterationsPerCPU == 0 && ctx.Err() != nil {
+				return false, 0, nil, ctx.Err()
+			}
+		}
+		return false, 0, nil, nil
+	}
+
+	fingerprint := Fingerprint(initialData, itemSize, minDistance, maxDistance)
+	return RunShard[UniqueUnorderedCombination](ctx, space, shard, fingerprint, checkpointPath, settings, search)
+}
@@ -0,0 +1,387 @@
+package bruteforcer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/errors"
+)
+
+// SearchSpace is a sequence of segments of combinations, where each
+// segment (for example, all combinations of a specific distance) is
+// defined by the amount of combinations in it.
+//
+// The combinations are enumerated through the whole space in order:
+// segment by segment, and by combination ID inside a segment. The position
+// of a combination in this order is called "offset".
+type SearchSpace []uint64
+
+// Total returns the amount of combinations in the whole space.
+func (space SearchSpace) Total() uint64 {
+	var result uint64
+	for _, size := range space {
+		result += size
+	}
+	return result
+}
+
+// Position returns the segment and the combination ID inside it
+// of the combination with the given offset.
+func (space SearchSpace) Position(offset uint64) (segment int, combinationID uint64) {
+	for segment, size := range space {
+		if offset < size {
+			return segment, offset
+		}
+		offset -= size
+	}
+	return len(space), offset
+}
+
+// Offset is the reverse function of Position.
+func (space SearchSpace) Offset(segment int, combinationID uint64) uint64 {
+	var result uint64
+	for _, size := range space[:segment] {
+		result += size
+	}
+	return result + combinationID
+}
+
+// Shard is a part of a search space. The space is split into Count shards
+// of (almost) equal size, and Index is the index of the shard.
+type Shard struct {
+	Index uint64
+	Count uint64
+}
+
+// ParseShard parses a shard in format "<index>/<count>", for example "0/4".
+func ParseShard(s string) (Shard, error) {
+	indexString, countString, ok := strings.Cut(s, "/")
+	if !ok {
+		return Shard{}, fmt.Errorf("invalid shard '%s', expected format '<index>/<count>'", s)
+	}
+	index, err := strconv.ParseUint(indexString, 10, 64)
+	if err != nil {
+		return Shard{}, fmt.Errorf("invalid shard index '%s': %w", indexString, err)
+	}
+	count, err := strconv.ParseUint(countString, 10, 64)
+	if err != nil {
+		return Shard{}, fmt.Errorf("invalid shards count '%s': %w", countString, err)
+	}
+	shard := Shard{Index: index, Count: count}
+	if err := shard.Validate(); err != nil {
+		return Shard{}, err
+	}
+	return shard, nil
+}
+
+// Validate returns an error if the shard is invalid.
+func (shard Shard) Validate() error {
+	if shard.Count == 0 {
+		return fmt.Errorf("the amount of shards should be positive")
+	}
+	if shard.Index >= shard.Count {
+		return fmt.Errorf("shard index %d is out of range [0, %d)", shard.Index, shard.Count)
+	}
+	return nil
+}
+
+// String implements fmt.Stringer.
+func (shard Shard) String() string {
+	return fmt.Sprintf("%d/%d", shard.Index, shard.Count)
+}
+
+// Range returns the offsets [start, end) of the shard in a search space of
+// the given size.
+func (shard Shard) Range(total uint64) (start, end uint64) {
+	piece := total / shard.Count
+	remainder := total % shard.Count
+	start = shard.Index*piece + min(shard.Index, remainder)
+	end = start + piece
+	if shard.Index < remainder {
+		end++
+	}
+	return start, end
+}
+
+// Fingerprint returns a fingerprint of a job defined by the given values
+// (input data and settings), see Checkpoint.Fingerprint. The values are
+// formatted using "%#v".
+func Fingerprint(values ...any) string {
+	h := sha256.New()
+	for _, value := range values {
+		fmt.Fprintf(h, "%#v\n", value)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Found is a found combination.
+type Found[R any] struct {
+	// Offset is the offset of the combination in the search space.
+	Offset uint64
+
+	// Result is the result returned by SearchFunc for the combination.
+	Result R
+}
+
+// Checkpoint is the state of processing of a shard.
+type Checkpoint[R any] struct {
+	// Fingerprint identifies the job (the input data and settings),
+	// it is used to avoid resuming or merging unrelated checkpoints.
+	Fingerprint string
+
+	Shard Shard
+	Start uint64
+	End   uint64
+
+	// Next is the offset of the first combination not processed yet.
+	Next uint64
+
+	// Found is the first found combination of the shard, if any.
+	Found *Found[R] `json:",omitempty"`
+}
+
+// IsDone returns true if the processing of the shard is finished.
+func (cp *Checkpoint[R]) IsDone() bool {
+	return cp.Found != nil || cp.Next >= cp.End
+}
+
+// LoadCheckpoint reads a checkpoint saved by Checkpoint.Save.
+func LoadCheckpoint[R any](path string) (*Checkpoint[R], error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cp Checkpoint[R]
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return nil, fmt.Errorf("unable to parse checkpoint '%s': %w", path, err)
+	}
+	return &cp, nil
+}
+
+// Save atomically writes the checkpoint to the file.
+func (cp *Checkpoint[R]) Save(path string) error {
+	b, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("unable to serialize the checkpoint: %w", err)
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, b, 0o644); err != nil {
+		return fmt.Errorf("unable to write '%s': %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("unable to rename '%s' to '%s': %w", tmpPath, path, err)
+	}
+	return nil
+}
+
+// SearchFunc checks `count` combinations of the segment starting from
+// `combinationID`. If a sought combination is found, then it returns
+// true, the ID of the first found combination and the result to be saved
+// in the checkpoint.
+type SearchFunc[R any] func(
+	ctx context.Context,
+	segment int,
+	combinationID uint64,
+	count uint64,
+) (isFound bool, foundCombinationID uint64, result R, err error)
+
+// ShardSettings defines how a shard is processed.
+type ShardSettings struct {
+	// ChunkSize is the amount of combinations processed between two
+	// saves of the checkpoint.
+	ChunkSize uint64
+
+	// MaxConcurrency is the maximal amount of goroutines processing
+	// a chunk; zero means GOMAXPROCS.
+	MaxConcurrency uint
+}
+
+// DefaultShardSettings returns recommended default settings of processing a shard.
+func DefaultShardSettings() ShardSettings {
+	return ShardSettings{
+		ChunkSize: 1 << 16,
+	}
+}
+
+// RunShard searches for the first sought combination in the shard of the
+// search space. If checkpointPath is not empty, then the progress is saved
+// there after every chunk, and the processing is resumed from there
+// on a next call (or returned immediately, if the shard is already done).
+//
+// If ctx is cancelled, then the processing stops after the current chunk,
+// and the checkpoint is returned together with the context error.
+func RunShard[R any](
+	ctx context.Context,
+	space SearchSpace,
+	shard Shard,
+	fingerprint string,
+	checkpointPath string,
+	settings ShardSettings,
+	search SearchFunc[R],
+) (*Checkpoint[R], error) {
+	if err := shard.Validate(); err != nil {
+		return nil, err
+	}
+	start, end := shard.Range(space.Total())
+
+	cp := &Checkpoint[R]{
+		Fingerprint: fingerprint,
+		Shard:       shard,
+		Start:       start,
+		End:         end,
+		Next:        start,
+	}
+	if checkpointPath != "" {
+		saved, err := LoadCheckpoint[R](checkpointPath)
+		switch {
+		case err == nil:
+			if saved.Fingerprint != cp.Fingerprint || saved.Shard != cp.Shard || saved.Start != cp.Start || saved.End != cp.End {
+				return nil, fmt.Errorf("checkpoint '%s' belongs to another job or shard", checkpointPath)
+			}
+			cp = saved
+		case os.IsNotExist(err):
+		default:
+			return nil, fmt.Errorf("unable to load checkpoint '%s': %w", checkpointPath, err)
+		}
+	}
+
+	chunkSize := settings.ChunkSize
+	if chunkSize == 0 {
+		chunkSize = DefaultShardSettings().ChunkSize
+	}
+	concurrencyFactor := uint64(runtime.GOMAXPROCS(0))
+	if settings.MaxConcurrency > 0 && uint64(settings.MaxConcurrency) < concurrencyFactor {
+		concurrencyFactor = uint64(settings.MaxConcurrency)
+	}
+
+	for !cp.IsDone() {
+		if err := ctx.Err(); err != nil {
+			return cp, err
+		}
+
+		chunkEnd := min(cp.Next+chunkSize, cp.End)
+		found, err := searchRange(ctx, space, cp.Next, chunkEnd, concurrencyFactor, search)
+		if err != nil {
+			return cp, err
+		}
+		cp.Found = found
+		cp.Next = chunkEnd
+
+		if checkpointPath != "" {
+			if err := cp.Save(checkpointPath); err != nil {
+				return cp, err
+			}
+		}
+	}
+	return cp, nil
+}
+
+// searchRange searches in the range of offsets [start, end) concurrently and
+// returns the found combination with the lowest offset.
+func searchRange[R any](
+	ctx context.Context,
+	space SearchSpace,
+	start, end uint64,
+	concurrencyFactor uint64,
+	search SearchFunc[R],
+) (*Found[R], error) {
+	piece := (end - start + concurrencyFactor - 1) / concurrencyFactor
+
+	var (
+		wg     sync.WaitGroup
+		locker sync.Mutex
+		result *Found[R]
+		mErr   errors.MultiError
+	)
+	for pieceStart := start; pieceStart < end; pieceStart += piece {
+		pieceEnd := min(pieceStart+piece, end)
+		wg.Add(1)
+		go func(pieceStart, pieceEnd uint64) {
+			defer wg.Done()
+			// Each goroutine stops on its own first finding only, otherwise
+			// the result would depend on the timings.
+			for offset := pieceStart; offset < pieceEnd; {
+				segment, combinationID := space.Position(offset)
+				count := min(space[segment]-combinationID, pieceEnd-offset)
+				isFound, foundCombinationID, r, err := search(ctx, segment, combinationID, count)
+				locker.Lock()
+				if err != nil {
+					_ = mErr.Add(fmt.Errorf("unable to search in segment %d at combinations [%d, %d): %w", segment, combinationID, combinationID+count, err))
+				}
+				if isFound {
+					foundOffset := space.Offset(segment, foundCombinationID)
+					if result == nil || foundOffset < result.Offset {
+						result = &Found[R]{Offset: foundOffset, Result: r}
+					}
+				}
+				locker.Unlock()
+				if isFound || err != nil {
+					return
+				}
+				offset += count
+			}
+		}(pieceStart, pieceEnd)
+	}
+	wg.Wait()
+
+	if err := mErr.ReturnValue(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ErrShardIncomplete means the result could not be determined, because
+// the shard is not processed yet.
+type ErrShardIncomplete struct {
+	Index uint64
+}
+
+// Error implements interface `error`.
+func (err ErrShardIncomplete) Error() string {
+	return fmt.Sprintf("shard %d is not finished", err.Index)
+}
+
+// MergeCheckpoints returns the found combination with the lowest offset
+// among all shards, so the result is the same as it would be if the whole
+// space was processed by a single RunShard. It returns nil if all shards are
+// finished, but nothing was found, and ErrShardIncomplete if the result
+// depends on an unfinished (or absent) shard.
+func MergeCheckpoints[R any](checkpoints []*Checkpoint[R]) (*Found[R], error) {
+	if len(checkpoints) == 0 {
+		return nil, fmt.Errorf("no checkpoints")
+	}
+	fingerprint := checkpoints[0].Fingerprint
+	shardsCount := checkpoints[0].Shard.Count
+	byIndex := map[uint64]*Checkpoint[R]{}
+	for _, cp := range checkpoints {
+		if cp.Fingerprint != fingerprint {
+			return nil, fmt.Errorf("checkpoints of different jobs: fingerprints '%s' and '%s'", fingerprint, cp.Fingerprint)
+		}
+		if cp.Shard.Count != shardsCount {
+			return nil, fmt.Errorf("checkpoints of different sharding: %d and %d shards", shardsCount, cp.Shard.Count)
+		}
+		if _, ok := byIndex[cp.Shard.Index]; ok {
+			return nil, fmt.Errorf("duplicate checkpoints of shard %s", cp.Shard)
+		}
+		byIndex[cp.Shard.Index] = cp
+	}
+
+	for index := uint64(0); index < shardsCount; index++ {
+		cp, ok := byIndex[index]
+		if !ok || !cp.IsDone() {
+			return nil, ErrShardIncomplete{Index: index}
+		}
+		if cp.Found != nil {
+			return cp.Found, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,113 @@
+package bruteforcer
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardRange(t *testing.T) {
+	for _, total := range []uint64{0, 1, 7, 100, 101} {
+		var next uint64
+		for index := uint64(0); index < 7; index++ {
+			start, end := Shard{Index: index, Count: 7}.Range(total)
+			require.Equal(t, next, start)
+			require.LessOrEqual(t, end-start, total/7+1)
+			next = end
+		}
+		require.Equal(t, total, next)
+	}
+
+	shard, err := ParseShard("2/8")
+	require.NoError(t, err)
+	require.Equal(t, Shard{Index: 2, Count: 8}, shard)
+	require.Equal(t, "2/8", shard.String())
+	_, err = ParseShard("8/8")
+	require.Error(t, err)
+	_, err = ParseShard("1")
+	require.Error(t, err)
+}
+
+func TestBruteForceShard(t *testing.T) {
+	initialData := []byte{0x00, 0x00, 0x00}
+	// two solutions with distance 3, the first one has the lower combination ID
+	solutions := map[uint32]struct{}{
+		0x000007: {},
+		0x700000: {},
+	}
+	checkFunc := func(_ any, data []byte) bool {
+		_, ok := solutions[uint32(data[0])|uint32(data[1])<<8|uint32(data[2])<<16]
+		return ok
+	}
+
+	expected, err := BruteForce(initialData, 8, 0, 4, nil, checkFunc, ApplyBitFlipsBytes, 1)
+	require.NoError(t, err)
+	require.Equal(t, UniqueUnorderedCombination{0, 1, 2}, expected)
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	settings := ShardSettings{ChunkSize: 100, MaxConcurrency: 3}
+	var checkpoints []*Checkpoint[UniqueUnorderedCombination]
+	// processing the shards in the reverse order: the second solution
+	// is found first, but the merged result is still the first solution
+	for index := 9; index >= 0; index-- {
+		shard := Shard{Index: uint64(index), Count: 10}
+		path := filepath.Join(dir, fmt.Sprintf("%d.json", index))
+		cp, err := BruteForceShard(ctx, initialData, 8, 0, 4, nil, checkFunc, ApplyBitFlipsBytes, shard, path, settings)
+		require.NoError(t, err)
+		require.True(t, cp.IsDone())
+
+		loaded, err := LoadCheckpoint[UniqueUnorderedCombination](path)
+		require.NoError(t, err)
+		require.Equal(t, cp, loaded)
+		checkpoints = append(checkpoints, loaded)
+
+		if index > 0 {
+			_, err = MergeCheckpoints(checkpoints)
+			require.ErrorAs(t, err, &ErrShardIncomplete{})
+		}
+	}
+
+	found, err := MergeCheckpoints(checkpoints)
+	require.NoError(t, err)
+	require.Equal(t, expected, found.Result)
+	require.NotNil(t, checkpoints[8].Found)
+	require.Equal(t, UniqueUnorderedCombination{20, 21, 22}, checkpoints[8].Found.Result)
+}
+
+func TestRunShardResume(t *testing.T) {
+	space := SearchSpace{10, 1000}
+	var checked uint64
+	search := func(ctx context.Context, segment int, combinationID, count uint64) (bool, uint64, int, error) {
+		atomic.AddUint64(&checked, count)
+		return false, 0, 0, nil
+	}
+
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	ctx, cancelFn := context.WithCancel(context.Background())
+	var calls int
+	cp, err := RunShard[int](ctx, space, Shard{Index: 0, Count: 1}, "job", path, ShardSettings{ChunkSize: 100, MaxConcurrency: 1}, func(ctx context.Context, segment int, combinationID, count uint64) (bool, uint64, int, error) {
+		calls++
+		if calls == 2 {
+			cancelFn()
+		}
+		return search(ctx, segment, combinationID, count)
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	require.False(t, cp.IsDone())
+
+	resumedFrom := cp.Next
+	checked = 0
+	cp, err = RunShard[int](context.Background(), space, Shard{Index: 0, Count: 1}, "job", path, ShardSettings{ChunkSize: 100}, search)
+	require.NoError(t, err)
+	require.True(t, cp.IsDone())
+	require.Nil(t, cp.Found)
+	require.Equal(t, space.Total()-resumedFrom, checked)
+
+	_, err = RunShard[int](context.Background(), space, Shard{Index: 0, Count: 1}, "another job", path, ShardSettings{}, search)
+	require.Error(t, err)
+}
@@ -233,7 +233,7 @@ func Analyze(
 	}
 
 	goodData := goodFirmware.Content
-	goodUEFI, err := goodFirmware.Parse()
+	goodLayout, err := goodFirmware.Layout()
 	if err != nil {
 		return AnalysisReport{}, fmt.Errorf("unable to parse the UEFI layout: %w", err)
 	}
@@ -242,15 +242,8 @@ func Analyze(
 	// Preparing data structures to quickly find UEFI nodes overlapping with
 	// a byte range.
 
-	allNodes, err := goodUEFI.GetByRange(pkgbytes.Range{
-		Offset: 0,
-		Length: uint64(len(goodData)),
-	})
-	if err != nil {
-		return AnalysisReport{}, fmt.Errorf("unable to scan for UEFI nodes: %w", err)
-	}
-	nodesIntervalTree := newNodesIntervalTree(allNodes)
-	namesIntervalTree := newNamesIntervalTree(goodUEFI.NameToRangesMap())
+	nodesIntervalTree := newNodesIntervalTree(goodLayout)
+	namesIntervalTree := newNamesIntervalTree(goodLayout.NameToRanges)
 
 	// Preparing a report
 
@@ -293,9 +286,9 @@ func Analyze(
 		//
 		// analysisEntry.Nodes should contain a list of UEFI nodes (regions,
 		// volumes, modules, files) which overlaps with the diffRange.
-		var overlappedNodes []*ffs.Node
+		var overlappedNodes []biosimage.LayoutNode
 		for _, node := range nodesIntervalTree.FindOverlapping(rM) {
-			overlappedNodes = append(overlappedNodes, node.(*ffs.Node))
+			overlappedNodes = append(overlappedNodes, node.(biosimage.LayoutNode))
 		}
 		if len(overlappedNodes) == 0 {
 			// We use an ugly `unsafe` hack to extract bytes ranges,
@@ -313,7 +306,7 @@ func Analyze(
 				})
 			}
 		} else {
-			analysisEntry.Nodes = GetNodesInfo(overlappedNodes)
+			analysisEntry.Nodes = GetLayoutNodesInfo(overlappedNodes)
 		}
 
 		// Filling report
@@ -356,12 +349,13 @@ func (item *interval) ID() uint64 {
 	return item.IDValue
 }
 
-func newNodesIntervalTree(nodes []*ffs.Node) intervalTree {
+func newNodesIntervalTree(layout *biosimage.Layout) intervalTree {
 	t := intervalTree{
 		Tree: augmentedtree.New(1),
 	}
 
-	for idx, node := range nodes {
+	for idx, nodeIdx := range layout.RangeNodes {
+		node := layout.Nodes[nodeIdx]
 		t.Add(&interval{
 			IDValue: uint64(idx),
 			Range:   node.Range,
@@ -437,7 +431,6 @@ func GetNodesInfo(nodes []*ffs.Node) NodeInfos {
 
 		var nodeType string
 		var id string
-		moduleName := node.ModuleName()
 		switch f := node.Firmware.(type) {
 		case *fianoUEFI.FirmwareVolume:
 			nodeType = "volume"
@@ -458,25 +451,57 @@ func GetNodesInfo(nodes []*ffs.Node) NodeInfos {
 			}
 		}
 
-		// Compiling the string for the node
+		result = append(result, newNodeInfo(nodeType, id, node.ModuleName()))
+	}
+	return result
+}
 
-		var description strings.Builder
-		description.WriteString(nodeType)
-		if id != "" {
-			description.WriteString(":" + id)
+var (
+	layoutNodeTypeVolume     = fmt.Sprintf("%T", (*fianoUEFI.FirmwareVolume)(nil))
+	layoutNodeTypeFile       = fmt.Sprintf("%T", (*fianoUEFI.File)(nil))
+	layoutNodeTypeBIOSRegion = fmt.Sprintf("%T", (*fianoUEFI.BIOSRegion)(nil))
+)
+
+// GetLayoutNodesInfo is the same as GetNodesInfo, but for nodes
+// of a biosimage.Layout.
+func GetLayoutNodesInfo(nodes []biosimage.LayoutNode) NodeInfos {
+	var result NodeInfos
+	for _, node := range nodes {
+		var nodeType string
+		switch node.Type {
+		case layoutNodeTypeVolume:
+			nodeType = "volume"
+		case layoutNodeTypeFile:
+			nodeType = "file"
+		case layoutNodeTypeBIOSRegion:
+			nodeType = "bios_region"
+		default:
+			nodeType = node.Type
 		}
-		if moduleName != nil {
-			description.WriteString(":" + *moduleName)
+		id := node.Name
+		if id == `` && node.GUID != nil {
+			id = node.GUID.String()
 		}
 
-		// Appending
-
-		// TODO: do not String()-ify and then parse UUID, just use it as is.
-		uuidValue, _ := uuid.Parse(id)
-		result = append(result, NodeInfo{
-			UUID:        uuidValue,
-			Description: description.String(),
-		})
+		result = append(result, newNodeInfo(nodeType, id, node.ModuleName))
 	}
 	return result
 }
+
+func newNodeInfo(nodeType, id string, moduleName *string) NodeInfo {
+	var description strings.Builder
+	description.WriteString(nodeType)
+	if id != "" {
+		description.WriteString(":" + id)
+	}
+	if moduleName != nil {
+		description.WriteString(":" + *moduleName)
+	}
+
+	// TODO: do not String()-ify and then parse UUID, just use it as is.
+	uuidValue, _ := uuid.Parse(id)
+	return NodeInfo{
+		UUID:        uuidValue,
+		Description: description.String(),
+	}
+}
@@ -0,0 +1,50 @@
+package hwapi
+
+import (
+	"fmt"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/registers"
+	"github.com/9elements/go-linux-lowlevel-hw/pkg/hwapi"
+)
+
+const (
+	// amdSMNIndexOffset is the offset of the SMN index register in
+	// the PCI config space of the AMD root complex (00:00.0)
+	amdSMNIndexOffset = 0xB8
+	// amdSMNDataOffset is the offset of the SMN data register in
+	// the PCI config space of the AMD root complex (00:00.0)
+	amdSMNDataOffset = 0xBC
+
+	// AMDSMNMP0C2PMsg37 is the SMN address of MP0_C2P_MSG_37 (PSB status)
+	AMDSMNMP0C2PMsg37 = 0x03810994
+	// AMDSMNMP0C2PMsg38 is the SMN address of MP0_C2P_MSG_38 (PSB HSTI status)
+	AMDSMNMP0C2PMsg38 = 0x03810998
+)
+
+// ReadSMN32 reads a 32bit value from the AMD System Management Network
+// using the index/data register pair of the root complex.
+func ReadSMN32(hw hwapi.LowLevelHardwareInterfaces, addr uint32) (uint32, error) {
+	rootComplex := hwapi.PCIDevice{}
+	if err := hw.PCIWriteConfig32(rootComplex, amdSMNIndexOffset, addr); err != nil {
+		return 0, fmt.Errorf("unable to write SMN index 0x%08X: %w", addr, err)
+	}
+	value, err := hw.PCIReadConfig32(rootComplex, amdSMNDataOffset)
+	if err != nil {
+		return 0, fmt.Errorf("unable to read SMN data of 0x%08X: %w", addr, err)
+	}
+	return value, nil
+}
+
+// ReadPSBRegisters reads the MP0_C2P_MSG_37 and MP0_C2P_MSG_38 registers
+// which contain the Platform Secure Boot status of AMD platforms.
+func ReadPSBRegisters(hw hwapi.LowLevelHardwareInterfaces) (registers.MP0C2PMsg37, registers.MP0C2PMsg38, error) {
+	msg37, err := ReadSMN32(hw, AMDSMNMP0C2PMsg37)
+	if err != nil {
+		return 0, 0, fmt.Errorf("unable to read %s: %w", registers.MP0C2PMSG37RegisterID, err)
+	}
+	msg38, err := ReadSMN32(hw, AMDSMNMP0C2PMsg38)
+	if err != nil {
+		return 0, 0, fmt.Errorf("unable to read %s: %w", registers.MP0C2PMSG38RegisterID, err)
+	}
+	return registers.ParseMP0C2PMsg37Register(msg37), registers.ParseMP0C2PMsg38Register(msg38), nil
+}
@@ -0,0 +1,33 @@
+package hwapi
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/9elements/go-linux-lowlevel-hw/pkg/hwapi"
+)
+
+// procCmdlinePath is the path to the command line of the running kernel.
+const procCmdlinePath = "/proc/cmdline"
+
+// KernelCmdlineReader is implemented by the hardware interfaces which
+// provide the command line of the kernel by themselves (for example,
+// NewSnapshotReplay and MockBuilder).
+type KernelCmdlineReader interface {
+	KernelCmdline() (string, error)
+}
+
+// KernelCmdline returns the command line of the running kernel. It is
+// taken from hw if it implements KernelCmdlineReader, otherwise
+// /proc/cmdline of the host is read.
+func KernelCmdline(hw hwapi.LowLevelHardwareInterfaces) (string, error) {
+	if reader, ok := hw.(KernelCmdlineReader); ok {
+		return reader.KernelCmdline()
+	}
+	cmdline, err := os.ReadFile(procCmdlinePath)
+	if err != nil {
+		return "", fmt.Errorf("unable to read the kernel command line: %w", err)
+	}
+	return strings.TrimSpace(string(cmdline)), nil
+}
@@ -0,0 +1,380 @@
+package hwapi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/9elements/go-linux-lowlevel-hw/pkg/hwapi"
+	"github.com/digitalocean/go-smbios/smbios"
+)
+
+// mockPCIConfigSize is the default size of the PCI config space of
+// the devices declared in the MockBuilder.
+const mockPCIConfigSize = 256
+
+// MockBuilder declares the state of the hardware to be mocked in unit tests.
+//
+// Everything which is not declared is reported as ErrNotRecorded by
+// the mock, thus a test could trigger the internal error path of the code
+// just by omitting the data. Example:
+//
+//	hw := hwapi.NewMockBuilder().
+//		WithVendor("GenuineIntel").
+//		WithMSR(0x3A, 0xFF05, 0xFF05, 0xFF01).
+//		WithTPM(hwapi.TPMVersion20).
+//		Build()
+//
+// The resulting mock is stateful: PCI config space and the declared physical
+// memory could be written.
+type MockBuilder struct {
+	snapshot Snapshot
+	msrs     []mockMSR
+	tpm      TPMBackend
+}
+
+type mockMSR struct {
+	address int64
+	values  []uint64
+	err     error
+}
+
+// NewMockBuilder returns a MockBuilder without any hardware declared.
+func NewMockBuilder() *MockBuilder {
+	return &MockBuilder{
+		snapshot: Snapshot{
+			Version:    SnapshotVersion,
+			SMN:        map[uint32]uint32{},
+			ACPITables: map[string][]byte{},
+		},
+	}
+}
+
+func (b *MockBuilder) cpuidLeaf(leaf, subleaf uint32) *CPUIDLeaf {
+	for idx := range b.snapshot.CPUID {
+		item := &b.snapshot.CPUID[idx]
+		if item.Leaf == leaf && item.Subleaf == subleaf {
+			return item
+		}
+	}
+	b.snapshot.CPUID = append(b.snapshot.CPUID, CPUIDLeaf{Leaf: leaf, Subleaf: subleaf})
+	return &b.snapshot.CPUID[len(b.snapshot.CPUID)-1]
+}
+
+// WithCPUID declares the result of the CPUID instruction for the leaf.
+func (b *MockBuilder) WithCPUID(leaf, subleaf, eax, ebx, ecx, edx uint32) *MockBuilder {
+	*b.cpuidLeaf(leaf, subleaf) = CPUIDLeaf{
+		Leaf:    leaf,
+		Subleaf: subleaf,
+		EAX:     eax,
+		EBX:     ebx,
+		ECX:     ecx,
+		EDX:     edx,
+	}
+	return b
+}
+
+// WithVendor declares the CPU vendor string (for example "GenuineIntel")
+// returned by the CPUID leaf 0.
+func (b *MockBuilder) WithVendor(vendor string) *MockBuilder {
+	var buf [12]byte
+	copy(buf[:], vendor)
+	leaf := b.cpuidLeaf(0, 0)
+	leaf.EBX = binary.LittleEndian.Uint32(buf[0:])
+	leaf.EDX = binary.LittleEndian.Uint32(buf[4:])
+	leaf.ECX = binary.LittleEndian.Uint32(buf[8:])
+	return b
+}
+
+// WithCPUSignature declares the CPU signature (family, model, stepping)
+// returned in EAX of the CPUID leaf 1. The features of the leaf are
+// preserved.
+func (b *MockBuilder) WithCPUSignature(signature uint32) *MockBuilder {
+	b.cpuidLeaf(1, 0).EAX = signature
+	return b
+}
+
+// WithProcessorBrandName declares the processor brand string returned by
+// the CPUID leaves 0x80000002-0x80000004.
+func (b *MockBuilder) WithProcessorBrandName(name string) *MockBuilder {
+	var buf [48]byte
+	copy(buf[:], name)
+	for idx := 0; idx < 3; idx++ {
+		regs := buf[idx*16:]
+		b.WithCPUID(0x80000002+uint32(idx), 0,
+			binary.LittleEndian.Uint32(regs[0:]),
+			binary.LittleEndian.Uint32(regs[4:]),
+			binary.LittleEndian.Uint32(regs[8:]),
+			binary.LittleEndian.Uint32(regs[12:]),
+		)
+	}
+	return b
+}
+
+// WithMSR declares the value of the MSR per core. If only one value is
+// given, all the cores have the same value. If the values differ,
+// ReadMSRAllCores returns an error and ReadMSR returns the value
+// of the first core.
+func (b *MockBuilder) WithMSR(msr int64, valuesPerCore ...uint64) *MockBuilder {
+	if len(valuesPerCore) == 0 {
+		panic(fmt.Sprintf("no values of MSR 0x%X given", msr))
+	}
+	b.setMSR(mockMSR{address: msr, values: valuesPerCore})
+	return b
+}
+
+// WithMSRError declares that the MSR could not be read.
+func (b *MockBuilder) WithMSRError(msr int64, err error) *MockBuilder {
+	b.setMSR(mockMSR{address: msr, err: err})
+	return b
+}
+
+func (b *MockBuilder) setMSR(msr mockMSR) {
+	for idx := range b.msrs {
+		if b.msrs[idx].address == msr.address {
+			b.msrs[idx] = msr
+			return
+		}
+	}
+	b.msrs = append(b.msrs, msr)
+}
+
+func (b *MockBuilder) pciConfig(dev hwapi.PCIDevice, size int) []byte {
+	for idx := range b.snapshot.PCIDevices {
+		item := &b.snapshot.PCIDevices[idx]
+		if item.Device != dev {
+			continue
+		}
+		if len(item.Config) < size {
+			item.Config = append(item.Config, make([]byte, size-len(item.Config))...)
+		}
+		return item.Config
+	}
+	if size < mockPCIConfigSize {
+		size = mockPCIConfigSize
+	}
+	b.snapshot.PCIDevices = append(b.snapshot.PCIDevices, PCIConfigSpace{
+		Device: dev,
+		Config: make([]byte, size),
+	})
+	return b.snapshot.PCIDevices[len(b.snapshot.PCIDevices)-1].Config
+}
+
+// WithPCIDevice declares a PCI device with the config space filled with
+// zeros, except the vendor and device IDs.
+func (b *MockBuilder) WithPCIDevice(dev hwapi.PCIDevice, vendorID, deviceID uint16) *MockBuilder {
+	config := b.pciConfig(dev, mockPCIConfigSize)
+	binary.LittleEndian.PutUint16(config[0:], vendorID)
+	binary.LittleEndian.PutUint16(config[2:], deviceID)
+	return b
+}
+
+// WithPCIConfig declares the bytes of the PCI config space of the device
+// at the offset. The device is declared if it was not.
+func (b *MockBuilder) WithPCIConfig(dev hwapi.PCIDevice, off int, data []byte) *MockBuilder {
+	copy(b.pciConfig(dev, off+len(data))[off:], data)
+	return b
+}
+
+// WithPCIConfig32 declares a 32bit register of the PCI config space of
+// the device. The device is declared if it was not.
+func (b *MockBuilder) WithPCIConfig32(dev hwapi.PCIDevice, off int, value uint32) *MockBuilder {
+	binary.LittleEndian.PutUint32(b.pciConfig(dev, off+4)[off:], value)
+	return b
+}
+
+// WithSMN declares a register of the AMD System Management Network,
+// see ReadSMN32.
+func (b *MockBuilder) WithSMN(addr uint32, value uint32) *MockBuilder {
+	b.pciConfig(hwapi.PCIDevice{}, mockPCIConfigSize)
+	b.snapshot.SMN[addr] = value
+	return b
+}
+
+// WithE820Range declares a range of the E820 memory map of the type
+// (for example "Reserved").
+func (b *MockBuilder) WithE820Range(rangeType string, start, end uint64) *MockBuilder {
+	b.snapshot.E820 = append(b.snapshot.E820, E820Range{
+		Type:  rangeType,
+		Start: start,
+		End:   end,
+	})
+	return b
+}
+
+// WithACPITable declares an ACPI table with the signature (for example "DMAR").
+func (b *MockBuilder) WithACPITable(signature string, data []byte) *MockBuilder {
+	b.snapshot.ACPITables[signature] = data
+	return b
+}
+
+// WithSMBIOS declares an SMBIOS structure.
+func (b *MockBuilder) WithSMBIOS(s *smbios.Structure) *MockBuilder {
+	b.snapshot.SMBIOS = append(b.snapshot.SMBIOS, s)
+	return b
+}
+
+// WithPhysMemory declares the content of the physical memory at the address.
+// If the range is within an already declared region, the region is
+// modified. Otherwise a new region is declared, which takes precedence
+// over the previously declared regions.
+func (b *MockBuilder) WithPhysMemory(addr uint64, data []byte) *MockBuilder {
+	for idx := len(b.snapshot.Memory) - 1; idx >= 0; idx-- {
+		region := &b.snapshot.Memory[idx]
+		if addr >= region.Address && addr+uint64(len(data)) <= region.Address+uint64(len(region.Data)) {
+			copy(region.Data[addr-region.Address:], data)
+			return b
+		}
+	}
+	b.snapshot.Memory = append(b.snapshot.Memory, MemoryRegion{
+		Address: addr,
+		Data:    append([]byte{}, data...),
+	})
+	return b
+}
+
+// WithPhysMemoryValue declares a fixed-size value (for example uint64 or
+// a struct) in the physical memory at the address, encoded in little endian.
+// See WithPhysMemory.
+func (b *MockBuilder) WithPhysMemoryValue(addr uint64, value interface{}) *MockBuilder {
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, value); err != nil {
+		panic(fmt.Sprintf("unable to serialize value %v: %v", value, err))
+	}
+	return b.WithPhysMemory(addr, buf.Bytes())
+}
+
+// WithTPM declares a TPM of the version without NV indexes and PCRs, see
+// WithTPMNVIndex and WithTPMPCR.
+func (b *MockBuilder) WithTPM(version hwapi.TPMVersion) *MockBuilder {
+	b.tpm = &TPMSnapshot{Version: version}
+	return b
+}
+
+// WithTPMBackend declares a TPM with custom behavior (for example,
+// a simulator).
+func (b *MockBuilder) WithTPMBackend(backend TPMBackend) *MockBuilder {
+	b.tpm = backend
+	return b
+}
+
+func (b *MockBuilder) tpmSnapshot() *TPMSnapshot {
+	tpm, ok := b.tpm.(*TPMSnapshot)
+	if !ok {
+		panic("the TPM should be declared by WithTPM")
+	}
+	return tpm
+}
+
+// WithTPMNVLocked declares if the NV storage of the TPM is locked.
+func (b *MockBuilder) WithTPMNVLocked(locked bool) *MockBuilder {
+	b.tpmSnapshot().NVLocked = locked
+	return b
+}
+
+// WithTPMNVIndex declares a TPM NV index with the public area
+// (TPM2B_NV_PUBLIC or TPM_NV_DATA_PUBLIC depending on the TPM version) and
+// the value.
+func (b *MockBuilder) WithTPMNVIndex(index uint32, public, value []byte) *MockBuilder {
+	tpm := b.tpmSnapshot()
+	nvIndex := NVIndex{Index: index, Public: public, Value: value}
+	for idx := range tpm.NVIndexes {
+		if tpm.NVIndexes[idx].Index == index {
+			tpm.NVIndexes[idx] = nvIndex
+			return b
+		}
+	}
+	tpm.NVIndexes = append(tpm.NVIndexes, nvIndex)
+	return b
+}
+
+// WithTPMPCR declares the value of the PCR.
+func (b *MockBuilder) WithTPMPCR(index uint32, value []byte) *MockBuilder {
+	tpm := b.tpmSnapshot()
+	for idx := range tpm.PCRs {
+		if tpm.PCRs[idx].Index == index {
+			tpm.PCRs[idx].Value = value
+			return b
+		}
+	}
+	tpm.PCRs = append(tpm.PCRs, PCR{Index: index, Value: value})
+	return b
+}
+
+// WithKernelCmdline declares the command line of the running kernel.
+func (b *MockBuilder) WithKernelCmdline(cmdline string) *MockBuilder {
+	b.snapshot.KernelCmdline = cmdline
+	return b
+}
+
+// Snapshot returns the declared hardware as a Snapshot, for example to
+// prepare an input for `txt-suite exec-tests --snapshot`. A TPM declared
+// by WithTPMBackend and MSRs declared by WithMSRError are not included.
+func (b *MockBuilder) Snapshot() *Snapshot {
+	// the builder could be modified afterwards and the memory of the mock
+	// is writable, so nothing should be shared
+	snapshot := &Snapshot{
+		Version:    b.snapshot.Version,
+		CPUID:      append([]CPUIDLeaf{}, b.snapshot.CPUID...),
+		SMN:        map[uint32]uint32{},
+		E820:       append([]E820Range{}, b.snapshot.E820...),
+		ACPITables: map[string][]byte{},
+		SMBIOS:     append([]*smbios.Structure{}, b.snapshot.SMBIOS...),
+
+		KernelCmdline: b.snapshot.KernelCmdline,
+	}
+	for _, region := range b.snapshot.Memory {
+		snapshot.Memory = append(snapshot.Memory, MemoryRegion{
+			Address: region.Address,
+			Data:    append([]byte{}, region.Data...),
+		})
+	}
+	for _, msr := range b.msrs {
+		if msr.err != nil {
+			continue
+		}
+		snapshot.MSRs = append(snapshot.MSRs, MSR{
+			Address: msr.address,
+			Values:  append([]uint64{}, msr.values...),
+		})
+	}
+	for _, dev := range b.snapshot.PCIDevices {
+		snapshot.PCIDevices = append(snapshot.PCIDevices, PCIConfigSpace{
+			Device: dev.Device,
+			Config: append([]byte{}, dev.Config...),
+		})
+	}
+	for addr, value := range b.snapshot.SMN {
+		snapshot.SMN[addr] = value
+	}
+	for signature, table := range b.snapshot.ACPITables {
+		snapshot.ACPITables[signature] = append([]byte{}, table...)
+	}
+	if tpm, ok := b.tpm.(*TPMSnapshot); ok {
+		tpmCopy := *tpm
+		tpmCopy.NVIndexes = append([]NVIndex{}, tpm.NVIndexes...)
+		tpmCopy.PCRs = append([]PCR{}, tpm.PCRs...)
+		snapshot.TPM = &tpmCopy
+	}
+	return snapshot
+}
+
+// Build returns the mock of the declared hardware. The builder could be
+// modified and reused afterwards, it does not affect the returned mock.
+func (b *MockBuilder) Build() hwapi.LowLevelHardwareInterfaces {
+	snapshot := b.Snapshot()
+	r := NewSnapshotReplay(snapshot).(*snapshotReplay)
+	if snapshot.TPM == nil && b.tpm != nil {
+		r.tpmBackend = b.tpm
+	}
+	for _, msr := range b.msrs {
+		if msr.err != nil {
+			if r.msrErrors == nil {
+				r.msrErrors = map[int64]error{}
+			}
+			r.msrErrors[msr.address] = msr.err
+		}
+	}
+	r.isPhysWritable = true
+	return r
+}
@@ -0,0 +1,132 @@
+package hwapi
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/9elements/go-linux-lowlevel-hw/pkg/hwapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockBuilder(t *testing.T) {
+	hostBridge := hwapi.PCIDevice{}
+	builder := NewMockBuilder().
+		WithVendor("GenuineIntel").
+		WithCPUID(1, 0, 0, 0, 1<<6, 0).
+		WithCPUSignature(0x906EA).
+		WithProcessorBrandName("Intel(R) Xeon(R) W-2195 CPU @ 2.30GHz").
+		WithMSR(0x3A, 0xFF07).
+		WithMSR(0x17, 1, 2).
+		WithMSRError(0xFE, errors.New("unable to read")).
+		WithPCIDevice(hostBridge, 0x8086, 0x3E30).
+		WithPCIConfig32(hostBridge, 0x5C, 0x00300001).
+		WithE820Range("Reserved", 0xFED00000, 0xFEDFFFFF).
+		WithACPITable("DMAR", []byte("DMAR")).
+		WithPhysMemory(0xFED30000, make([]byte, 0x1000)).
+		WithPhysMemoryValue(0xFED30110, uint16(0x8086)).
+		WithTPM(hwapi.TPMVersion20).
+		WithTPMNVIndex(0x1C10103, []byte{1}, []byte{1, 2, 3}).
+		WithTPMPCR(0, bytes.Repeat([]byte{0xAA}, 32))
+	hw := builder.Build()
+
+	require.Equal(t, "GenuineIntel", hw.VersionString())
+	require.True(t, hw.HasSMX())
+	require.False(t, hw.HasVMX())
+	require.Equal(t, uint32(0x906EA), hw.CPUSignature())
+	require.Equal(t, "Intel(R) Xeon(R) W-2195 CPU @ 2.30GHz", hw.ProcessorBrandName())
+	require.True(t, CPUWhitelistTXTSupport(hw))
+
+	value, err := hw.ReadMSRAllCores(0x3A)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0xFF07), value)
+	require.Equal(t, uint64(1), hw.ReadMSR(0x17))
+	_, err = hw.ReadMSRAllCores(0x17)
+	require.Error(t, err)
+	values, err := ReadMSRPerCore(hw, 0x17)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1, 2}, values)
+	_, err = hw.ReadMSRAllCores(0xFE)
+	require.EqualError(t, err, "unable to read")
+	_, err = hw.ReadMSRAllCores(0x1F2)
+	require.ErrorIs(t, err, ErrNotRecorded)
+
+	vendorID, err := hw.PCIReadVendorID(hostBridge)
+	require.NoError(t, err)
+	require.Equal(t, uint16(0x8086), vendorID)
+	dpr, err := hw.PCIReadConfig32(hostBridge, 0x5C)
+	require.NoError(t, err)
+	require.Equal(t, uint32(0x00300001), dpr)
+	require.NoError(t, hw.PCIWriteConfig32(hostBridge, 0x5C, 0))
+	dpr, err = hw.PCIReadConfig32(hostBridge, 0x5C)
+	require.NoError(t, err)
+	require.Zero(t, dpr)
+	_, err = hw.PCIReadVendorID(hwapi.PCIDevice{Device: 0x1F})
+	require.ErrorIs(t, err, ErrNotRecorded)
+
+	var found bool
+	_, err = hw.IterateOverE820Ranges("Reserved", func(start, end uint64) bool {
+		found = start == 0xFED00000 && end == 0xFEDFFFFF
+		return found
+	})
+	require.NoError(t, err)
+	require.True(t, found)
+	dmar, err := hw.GetACPITable("DMAR")
+	require.NoError(t, err)
+	require.Equal(t, []byte("DMAR"), dmar)
+	_, err = hw.GetACPITable("MADT")
+	require.ErrorIs(t, err, ErrNotRecorded)
+
+	var vid hwapi.Uint16
+	require.NoError(t, hw.ReadPhys(0xFED30110, &vid))
+	require.Equal(t, hwapi.Uint16(0x8086), vid)
+	newVID := hwapi.Uint16(0x1022)
+	require.NoError(t, hw.WritePhys(0xFED30110, &newVID))
+	require.NoError(t, hw.ReadPhys(0xFED30110, &vid))
+	require.Equal(t, newVID, vid)
+	require.Error(t, hw.ReadPhysBuf(0xFED2FFFF, make([]byte, 2)))
+
+	tpm, err := hw.NewTPM()
+	require.NoError(t, err)
+	require.Equal(t, hwapi.TPMVersion20, tpm.Version)
+	nvValue, err := hw.NVReadValue(tpm, 0x1C10103, "", 3, 0)
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, nvValue)
+	_, err = hw.ReadNVPublic(tpm, 0x1C10104)
+	require.ErrorIs(t, err, ErrNotRecorded)
+	pcr0, err := hw.ReadPCR(tpm, 0)
+	require.NoError(t, err)
+	require.Len(t, pcr0, 32)
+
+	// the built mock is not affected by the builder afterwards
+	builder.WithMSR(0x3A, 0).WithPhysMemoryValue(0xFED30110, uint16(0))
+	value, err = hw.ReadMSRAllCores(0x3A)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0xFF07), value)
+	require.NoError(t, hw.ReadPhys(0xFED30110, &vid))
+	require.Equal(t, newVID, vid)
+
+	_, err = NewMockBuilder().Build().NewTPM()
+	require.ErrorIs(t, err, ErrNotRecorded)
+}
+
+func TestMockBuilderSnapshot(t *testing.T) {
+	var buf bytes.Buffer
+	snapshot := NewMockBuilder().
+		WithVendor("AuthenticAMD").
+		WithSMN(AMDSMNMP0C2PMsg37, 0x1).
+		WithMSR(0x3A, 5).
+		WithMSR(0x17, 1, 2).
+		WithMSRError(0xFE, errors.New("unable to read")).
+		Snapshot()
+	require.NoError(t, snapshot.Write(&buf))
+
+	parsed, err := ReadSnapshot(&buf)
+	require.NoError(t, err)
+	require.Equal(t, snapshot, parsed)
+	require.Equal(t, []MSR{{Address: 0x3A, Values: []uint64{5}}, {Address: 0x17, Values: []uint64{1, 2}}}, parsed.MSRs)
+
+	value, err := ReadSMN32(NewSnapshotReplay(parsed), AMDSMNMP0C2PMsg37)
+	require.NoError(t, err)
+	require.Equal(t, uint32(0x1), value)
+}
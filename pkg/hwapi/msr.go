@@ -0,0 +1,23 @@
+package hwapi
+
+import (
+	"github.com/9elements/converged-security-suite/v2/pkg/registers"
+	"github.com/9elements/go-linux-lowlevel-hw/pkg/hwapi"
+)
+
+// MSRPerCoreReader is implemented by the hardware interfaces which
+// provide the values of MSRs per core by themselves (for example,
+// NewSnapshotReplay and MockBuilder).
+type MSRPerCoreReader interface {
+	ReadMSRPerCore(msr int64) ([]uint64, error)
+}
+
+// ReadMSRPerCore returns the values of the MSR on every core. They are
+// taken from hw if it implements MSRPerCoreReader, otherwise the MSR is
+// read on the cores of the host.
+func ReadMSRPerCore(hw hwapi.LowLevelHardwareInterfaces, msr int64) ([]uint64, error) {
+	if reader, ok := hw.(MSRPerCoreReader); ok {
+		return reader.ReadMSRPerCore(msr)
+	}
+	return registers.ReadMSRPerCore(msr)
+}
@@ -0,0 +1,203 @@
+package hwapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/9elements/go-linux-lowlevel-hw/pkg/hwapi"
+	"github.com/digitalocean/go-smbios/smbios"
+)
+
+// SnapshotVersion is the version of the snapshot format.
+const SnapshotVersion = 1
+
+// ErrNotRecorded means the requested data is not recorded in the snapshot
+// (or is not declared in the MockBuilder).
+var ErrNotRecorded = errors.New("not recorded in the snapshot")
+
+// Snapshot is a recorded state of the hardware of a machine, which could
+// be replayed by NewSnapshotReplay to run tests after the fact on another
+// machine. See CaptureSnapshot.
+type Snapshot struct {
+	Version int
+
+	// Memory contains the recorded ranges of the physical memory:
+	// the TXT config space, the TXT heap, the SINIT region, the firmware
+	// flash, ACPI tables and VT-d registers.
+	Memory []MemoryRegion
+
+	MSRs       []MSR
+	CPUID      []CPUIDLeaf
+	PCIDevices []PCIConfigSpace
+
+	// SMN contains the recorded registers of the AMD System Management
+	// Network, see ReadSMN32.
+	SMN map[uint32]uint32 `json:",omitempty"`
+
+	E820       []E820Range
+	ACPITables map[string][]byte
+	SMBIOS     []*smbios.Structure
+	TPM        *TPMSnapshot `json:",omitempty"`
+
+	// KernelCmdline is the command line of the running kernel, see
+	// KernelCmdline.
+	KernelCmdline string `json:",omitempty"`
+}
+
+// MemoryRegion is a recorded range of the physical memory.
+type MemoryRegion struct {
+	Address uint64
+	Data    []byte
+}
+
+// MSR is a recorded model specific register.
+type MSR struct {
+	Address int64
+
+	// Values are the values of the register per core. ReadMSR returns
+	// the value of the first core, ReadMSRAllCores fails if the values
+	// differ among the cores.
+	Values []uint64
+}
+
+// CPUIDLeaf is a recorded result of the CPUID instruction.
+type CPUIDLeaf struct {
+	Leaf    uint32
+	Subleaf uint32
+	EAX     uint32
+	EBX     uint32
+	ECX     uint32
+	EDX     uint32
+}
+
+// PCIConfigSpace is a recorded PCI configuration space of a device.
+type PCIConfigSpace struct {
+	Device hwapi.PCIDevice
+	Config []byte
+}
+
+// E820Range is a recorded range of the E820 memory map.
+type E820Range struct {
+	Type  string
+	Start uint64
+	End   uint64
+}
+
+// TPMSnapshot is a recorded state of a TPM.
+type TPMSnapshot struct {
+	Version       hwapi.TPMVersion
+	NVLocked      bool
+	NVLockedError string `json:",omitempty"`
+	NVIndexes     []NVIndex
+	PCRs          []PCR
+}
+
+// NVIndex is a recorded TPM NV index. The errors are recorded as well,
+// since the tests distinguish between different reasons of failures.
+type NVIndex struct {
+	Index       uint32
+	Public      []byte `json:",omitempty"`
+	PublicError string `json:",omitempty"`
+	Value       []byte `json:",omitempty"`
+	ValueError  string `json:",omitempty"`
+}
+
+// PCR is a recorded value of a PCR (of the default bank).
+type PCR struct {
+	Index uint32
+	Value []byte
+}
+
+// TPMBackend provides the TPM data to NewSnapshotReplay and MockBuilder.
+// The methods correspond to the TPM methods of
+// hwapi.LowLevelHardwareInterfaces.
+type TPMBackend interface {
+	TPMVersion() hwapi.TPMVersion
+	IsNVLocked() (bool, error)
+	ReadNVPublic(index uint32) ([]byte, error)
+	NVReadValue(index uint32, password string, size, offhandle uint32) ([]byte, error)
+	ReadPCR(pcr uint32) ([]byte, error)
+}
+
+var _ TPMBackend = (*TPMSnapshot)(nil)
+
+// TPMVersion implements TPMBackend.
+func (tpm *TPMSnapshot) TPMVersion() hwapi.TPMVersion {
+	return tpm.Version
+}
+
+// IsNVLocked implements TPMBackend.
+func (tpm *TPMSnapshot) IsNVLocked() (bool, error) {
+	if tpm.NVLockedError != "" {
+		return false, fmt.Errorf("%s", tpm.NVLockedError)
+	}
+	return tpm.NVLocked, nil
+}
+
+func (tpm *TPMSnapshot) nvIndex(index uint32) (*NVIndex, error) {
+	for idx := range tpm.NVIndexes {
+		nvIndex := &tpm.NVIndexes[idx]
+		if nvIndex.Index == index {
+			return nvIndex, nil
+		}
+	}
+	return nil, fmt.Errorf("NV index 0x%X: %w", index, ErrNotRecorded)
+}
+
+// ReadNVPublic implements TPMBackend.
+func (tpm *TPMSnapshot) ReadNVPublic(index uint32) ([]byte, error) {
+	nvIndex, err := tpm.nvIndex(index)
+	if err != nil {
+		return nil, err
+	}
+	if nvIndex.PublicError != "" {
+		return nil, fmt.Errorf("%s", nvIndex.PublicError)
+	}
+	return append([]byte{}, nvIndex.Public...), nil
+}
+
+// NVReadValue implements TPMBackend.
+func (tpm *TPMSnapshot) NVReadValue(index uint32, password string, size, offhandle uint32) ([]byte, error) {
+	nvIndex, err := tpm.nvIndex(index)
+	if err != nil {
+		return nil, err
+	}
+	if nvIndex.ValueError != "" {
+		return nil, fmt.Errorf("%s", nvIndex.ValueError)
+	}
+	if int(size) > len(nvIndex.Value) {
+		return nil, fmt.Errorf("only %d bytes of NV index 0x%X are recorded, requested %d", len(nvIndex.Value), index, size)
+	}
+	return append([]byte{}, nvIndex.Value[:size]...), nil
+}
+
+// ReadPCR implements TPMBackend.
+func (tpm *TPMSnapshot) ReadPCR(pcr uint32) ([]byte, error) {
+	for _, item := range tpm.PCRs {
+		if item.Index == pcr {
+			return append([]byte{}, item.Value...), nil
+		}
+	}
+	return nil, fmt.Errorf("PCR%d: %w", pcr, ErrNotRecorded)
+}
+
+// ReadSnapshot parses a snapshot written by Snapshot.Write.
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	var snapshot Snapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("unable to parse the snapshot: %w", err)
+	}
+	if snapshot.Version != SnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d, expected %d", snapshot.Version, SnapshotVersion)
+	}
+	return &snapshot, nil
+}
+
+// Write writes the snapshot in JSON format.
+func (s *Snapshot) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
@@ -0,0 +1,407 @@
+package hwapi
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/errors"
+	"github.com/9elements/converged-security-suite/v2/pkg/registers"
+	"github.com/9elements/go-linux-lowlevel-hw/pkg/hwapi"
+	"github.com/digitalocean/go-smbios/smbios"
+)
+
+const (
+	// snapshotFlashSize is the size of the recorded firmware flash mapped
+	// right below 4GiB, it contains the FIT and the components it points to.
+	snapshotFlashSize = 16 << 20
+
+	// snapshotMemoryChunkSize is the granularity of reading memory ranges,
+	// unreadable chunks are skipped.
+	snapshotMemoryChunkSize = 64 << 10
+
+	// the legacy BIOS area, which contains the ACPI RSDP on legacy systems
+	snapshotLegacyBIOSAreaBase = 0xE0000
+	snapshotLegacyBIOSAreaSize = 0x20000
+
+	// the size of the registers of a DMA remapping unit
+	snapshotVTdRegistersSize = 0x1000
+
+	cpuidBasicMaxLeaf    = 0x0
+	cpuidExtendedMaxLeaf = 0x80000000
+)
+
+var (
+	// SnapshotMSRs are the MSRs recorded by CaptureSnapshot.
+	SnapshotMSRs = []int64{
+		registers.IA32PlatformIDRegisterOffset,
+		registers.IA32FeatureControlRegisterOffset,
+		0x8B, // IA32_BIOS_SIGN_ID (microcode revision)
+		registers.IA32MTRRCAPRegisterOffset,
+		registers.BootGuardPBECRegisterOffset,
+		registers.BTGSACMInfoRegisterOffset,
+		registers.IA32SMRRPhysBaseRegisterOffset,
+		registers.IA32SMRRPhysMaskRegisterOffset,
+		0x2FF, // IA32_MTRR_DEF_TYPE
+		registers.IA32DebugInterfaceRegisterOffset,
+		0xC0010010, // AMD64_SYSCFG
+	}
+
+	// SnapshotPCIDevices are the PCI devices recorded by CaptureSnapshot.
+	SnapshotPCIDevices = []hwapi.PCIDevice{
+		{},                          // host bridge
+		{Device: 0x16},              // MEI
+		{Device: 0x1f},              // LPC
+		{Device: 0x1f, Function: 2}, // PMC
+	}
+
+	// SnapshotE820Types are the types of E820 ranges recorded by CaptureSnapshot.
+	SnapshotE820Types = []string{
+		"System RAM",
+		"Reserved",
+		"reserved",
+		"ACPI Tables",
+		"ACPI Non-volatile Storage",
+		"Unusable memory",
+		"Persistent Memory",
+	}
+
+	// SnapshotACPITables are the ACPI tables recorded by CaptureSnapshot.
+	SnapshotACPITables = []string{
+		"RSDP", "RSDT", "XSDT", "FACP", "DSDT", "FACS", "APIC", "MCFG",
+		"HPET", "DMAR", "TPM2", "TCPA", "SSDT", "BGRT", "WSMT",
+	}
+
+	// snapshotTPM12NVIndexes are the TPM1.2 NV indexes used by the TXT tests
+	// with the sizes of their values.
+	snapshotTPM12NVIndexes = []struct {
+		index uint32
+		size  uint32
+	}{
+		{0x50000001, 54}, // PS
+		{0x50000002, 64}, // old AUX
+		{0x50000003, 64}, // AUX
+		{0x40000001, 54}, // PO
+	}
+
+	// snapshotTPM20NVIndexes are the TPM2.0 NV indexes used by the TXT tests,
+	// the sizes of their values are taken from their public areas.
+	snapshotTPM20NVIndexes = []uint32{
+		0x1C10103, // PS
+		0x1800001, // old PS
+		0x1C10102, // AUX
+		0x1800003, // old AUX
+		0x1C10106, // PO
+		0x1400001, // old PO
+	}
+)
+
+// CaptureSnapshot records the state of the hardware needed to run the tests,
+// see Snapshot. The data which could not be read is skipped and
+// the failures are returned as the error together with the snapshot.
+func CaptureSnapshot(hw hwapi.LowLevelHardwareInterfaces) (*Snapshot, error) {
+	s := &Snapshot{
+		Version:    SnapshotVersion,
+		ACPITables: map[string][]byte{},
+	}
+	var mErr errors.MultiError
+
+	s.captureCPUID(hw)
+
+	// the MSRs are vendor specific, so the unreadable ones are just skipped
+	for _, msr := range SnapshotMSRs {
+		values, err := ReadMSRPerCore(hw, msr)
+		if err != nil || len(values) == 0 {
+			continue
+		}
+		s.MSRs = append(s.MSRs, MSR{Address: msr, Values: values})
+	}
+
+	for _, dev := range SnapshotPCIDevices {
+		if err := s.capturePCIDevice(hw, dev); err != nil {
+			_ = mErr.Add(err)
+		}
+	}
+	if hw.VersionString() == "AuthenticAMD" {
+		s.SMN = map[uint32]uint32{}
+		for _, addr := range []uint32{AMDSMNMP0C2PMsg37, AMDSMNMP0C2PMsg38} {
+			value, err := ReadSMN32(hw, addr)
+			if err != nil {
+				_ = mErr.Add(err)
+				continue
+			}
+			s.SMN[addr] = value
+		}
+	}
+
+	for _, e820Type := range SnapshotE820Types {
+		_, err := hw.IterateOverE820Ranges(e820Type, func(start, end uint64) bool {
+			s.E820 = append(s.E820, E820Range{Type: e820Type, Start: start, End: end})
+			return false
+		})
+		if err != nil {
+			_ = mErr.Add(fmt.Errorf("unable to read E820 ranges of type '%s': %w", e820Type, err))
+		}
+	}
+
+	for _, name := range SnapshotACPITables {
+		table, err := hw.GetACPITable(name)
+		if err != nil {
+			continue
+		}
+		s.ACPITables[name] = table
+	}
+
+	for m := 0; m <= 0xff; m++ {
+		_, err := hw.IterateOverSMBIOSTables(uint8(m), func(structure *smbios.Structure) bool {
+			s.SMBIOS = append(s.SMBIOS, structure)
+			return false
+		})
+		if err != nil {
+			_ = mErr.Add(fmt.Errorf("unable to read SMBIOS tables: %w", err))
+			break
+		}
+	}
+
+	if err := s.captureMemory(hw); err != nil {
+		_ = mErr.Add(err)
+	}
+
+	if err := s.captureTPM(hw); err != nil {
+		_ = mErr.Add(err)
+	}
+
+	cmdline, err := KernelCmdline(hw)
+	if err != nil {
+		_ = mErr.Add(err)
+	}
+	s.KernelCmdline = cmdline
+
+	return s, mErr.ReturnValue()
+}
+
+func (s *Snapshot) captureCPUID(hw hwapi.LowLevelHardwareInterfaces) {
+	for _, base := range []uint32{cpuidBasicMaxLeaf, cpuidExtendedMaxLeaf} {
+		maxLeaf, _, _, _ := hw.CPUID(base, 0)
+		if maxLeaf < base || maxLeaf-base > 0xff {
+			maxLeaf = base
+		}
+		for leaf := base; leaf <= maxLeaf; leaf++ {
+			// leaf 7 contains the structured extended feature flags in
+			// multiple subleaves, other subleaves are not used by the tests
+			subleaves := uint32(1)
+			if leaf == 7 {
+				eax, _, _, _ := hw.CPUID(leaf, 0)
+				subleaves = min(eax, 0xf) + 1
+			}
+			for subleaf := uint32(0); subleaf < subleaves; subleaf++ {
+				eax, ebx, ecx, edx := hw.CPUID(leaf, subleaf)
+				s.CPUID = append(s.CPUID, CPUIDLeaf{
+					Leaf:    leaf,
+					Subleaf: subleaf,
+					EAX:     eax,
+					EBX:     ebx,
+					ECX:     ecx,
+					EDX:     edx,
+				})
+			}
+		}
+	}
+}
+
+func (s *Snapshot) capturePCIDevice(hw hwapi.LowLevelHardwareInterfaces, dev hwapi.PCIDevice) error {
+	vendorID, err := hw.PCIReadVendorID(dev)
+	if err != nil {
+		return fmt.Errorf("unable to read the vendor ID of PCI device %v: %w", dev, err)
+	}
+	if vendorID == 0xFFFF {
+		return nil
+	}
+	config := make([]byte, 256)
+	for off := 0; off < len(config); off += 4 {
+		value, err := hw.PCIReadConfig32(dev, off)
+		if err != nil {
+			return fmt.Errorf("unable to read the config space of PCI device %v at 0x%X: %w", dev, off, err)
+		}
+		binary.LittleEndian.PutUint32(config[off:], value)
+	}
+	s.PCIDevices = append(s.PCIDevices, PCIConfigSpace{Device: dev, Config: config})
+	return nil
+}
+
+func (s *Snapshot) captureMemory(hw hwapi.LowLevelHardwareInterfaces) error {
+	var mErr errors.MultiError
+
+	configSpace, err := registers.FetchTXTConfigSpaceSafe(hw)
+	if err != nil {
+		_ = mErr.Add(fmt.Errorf("unable to read the TXT config space: %w", err))
+	} else {
+		s.Memory = append(s.Memory, MemoryRegion{Address: registers.TxtPublicSpace, Data: configSpace})
+
+		heapBase, errBase := registers.ReadTXTHeapBase(configSpace)
+		heapSize, errSize := registers.ReadTXTHeapSize(configSpace)
+		if errBase == nil && errSize == nil {
+			s.captureMemoryRange(hw, uint64(heapBase), uint64(heapSize))
+		}
+		sinitBase, errBase := registers.ReadTXTSInitBase(configSpace)
+		sinitSize, errSize := registers.ReadTXTSInitSize(configSpace)
+		if errBase == nil && errSize == nil {
+			s.captureMemoryRange(hw, uint64(sinitBase), uint64(sinitSize))
+		}
+	}
+
+	s.captureMemoryRange(hw, 1<<32-snapshotFlashSize, snapshotFlashSize)
+	s.captureMemoryRange(hw, snapshotLegacyBIOSAreaBase, snapshotLegacyBIOSAreaSize)
+
+	// ACPI tables are also accessed through /dev/mem, so the tables
+	// referenced by RSDT/XSDT are recorded as memory as well.
+	for _, name := range []string{"RSDT", "XSDT"} {
+		table := s.ACPITables[name]
+		if len(table) < acpiHeaderSize {
+			continue
+		}
+		entrySize := 4
+		if name == "XSDT" {
+			entrySize = 8
+		}
+		for off := acpiHeaderSize; off+entrySize <= len(table); off += entrySize {
+			var addr uint64
+			if entrySize == 4 {
+				addr = uint64(binary.LittleEndian.Uint32(table[off:]))
+			} else {
+				addr = binary.LittleEndian.Uint64(table[off:])
+			}
+			header := make([]byte, acpiHeaderSize)
+			if err := hw.ReadPhysBuf(int64(addr), header); err != nil {
+				continue
+			}
+			s.captureMemoryRange(hw, addr, uint64(binary.LittleEndian.Uint32(header[4:])))
+		}
+	}
+
+	// VT-d registers are accessed to check the DMA protection
+	for _, base := range dmarRemappingUnits(s.ACPITables["DMAR"]) {
+		s.captureMemoryRange(hw, base, snapshotVTdRegistersSize)
+	}
+
+	return mErr.ReturnValue()
+}
+
+// captureMemoryRange records the readable parts of the memory range.
+func (s *Snapshot) captureMemoryRange(hw hwapi.LowLevelHardwareInterfaces, addr, size uint64) {
+	var region *MemoryRegion
+	for off := uint64(0); off < size; off += snapshotMemoryChunkSize {
+		chunk := make([]byte, min(snapshotMemoryChunkSize, size-off))
+		if err := hw.ReadPhysBuf(int64(addr+off), chunk); err != nil {
+			region = nil
+			continue
+		}
+		if region == nil {
+			s.Memory = append(s.Memory, MemoryRegion{Address: addr + off})
+			region = &s.Memory[len(s.Memory)-1]
+		}
+		region.Data = append(region.Data, chunk...)
+	}
+}
+
+func (s *Snapshot) captureTPM(hw hwapi.LowLevelHardwareInterfaces) error {
+	tpmCon, err := hw.NewTPM()
+	if err != nil {
+		return fmt.Errorf("unable to open the TPM: %w", err)
+	}
+	defer func() {
+		if err := tpmCon.Close(); err != nil {
+			fmt.Printf("warning: failed to close the TPM: %v\n", err)
+		}
+	}()
+
+	tpm := &TPMSnapshot{Version: tpmCon.Version}
+	tpm.NVLocked, err = hw.NVLocked(tpmCon)
+	if err != nil {
+		tpm.NVLockedError = err.Error()
+	}
+
+	switch tpmCon.Version {
+	case hwapi.TPMVersion12:
+		for _, item := range snapshotTPM12NVIndexes {
+			nvIndex := NVIndex{Index: item.index}
+			nvIndex.Public, err = hw.ReadNVPublic(tpmCon, item.index)
+			if err != nil {
+				nvIndex.PublicError = err.Error()
+			}
+			nvIndex.Value, err = hw.NVReadValue(tpmCon, item.index, "", item.size, 0)
+			if err != nil {
+				nvIndex.ValueError = err.Error()
+			}
+			tpm.NVIndexes = append(tpm.NVIndexes, nvIndex)
+		}
+	case hwapi.TPMVersion20:
+		for _, index := range snapshotTPM20NVIndexes {
+			nvIndex := NVIndex{Index: index}
+			nvIndex.Public, err = hw.ReadNVPublic(tpmCon, index)
+			if err != nil {
+				nvIndex.PublicError = err.Error()
+			} else if size, err := tpm20NVDataSize(nvIndex.Public); err != nil {
+				nvIndex.ValueError = err.Error()
+			} else {
+				nvIndex.Value, err = hw.NVReadValue(tpmCon, index, "", uint32(size), index)
+				if err != nil {
+					nvIndex.ValueError = err.Error()
+				}
+			}
+			tpm.NVIndexes = append(tpm.NVIndexes, nvIndex)
+		}
+	}
+
+	for pcr := uint32(0); pcr < 24; pcr++ {
+		value, err := hw.ReadPCR(tpmCon, pcr)
+		if err != nil {
+			continue
+		}
+		tpm.PCRs = append(tpm.PCRs, PCR{Index: pcr, Value: value})
+	}
+
+	s.TPM = tpm
+	return nil
+}
+
+// tpm20NVDataSize returns the dataSize of TPMS_NV_PUBLIC.
+func tpm20NVDataSize(public []byte) (uint16, error) {
+	// nvIndex (4 bytes), nameAlg (2 bytes), attributes (4 bytes), authPolicy (TPM2B)
+	const authPolicyOffset = 10
+	if len(public) < authPolicyOffset+2 {
+		return 0, fmt.Errorf("NV public area is too short: %d", len(public))
+	}
+	dataSizeOffset := authPolicyOffset + 2 + int(binary.BigEndian.Uint16(public[authPolicyOffset:]))
+	if len(public) < dataSizeOffset+2 {
+		return 0, fmt.Errorf("NV public area is too short: %d", len(public))
+	}
+	return binary.BigEndian.Uint16(public[dataSizeOffset:]), nil
+}
+
+const (
+	acpiHeaderSize = 36
+
+	// dmarRemappingStructuresOffset is the offset of the remapping
+	// structures in the DMAR table
+	dmarRemappingStructuresOffset = 48
+	// dmarTypeDRHD is the type of DMA Remapping Hardware Unit Definition
+	dmarTypeDRHD = 0
+)
+
+// dmarRemappingUnits returns the register base addresses of the DMA
+// remapping hardware units defined in the DMAR ACPI table.
+func dmarRemappingUnits(dmar []byte) []uint64 {
+	var result []uint64
+	for off := dmarRemappingStructuresOffset; off+4 <= len(dmar); {
+		structType := binary.LittleEndian.Uint16(dmar[off:])
+		structLength := int(binary.LittleEndian.Uint16(dmar[off+2:]))
+		if structLength < 4 || off+structLength > len(dmar) {
+			break
+		}
+		if structType == dmarTypeDRHD && structLength >= 16 {
+			result = append(result, binary.LittleEndian.Uint64(dmar[off+8:]))
+		}
+		off += structLength
+	}
+	return result
+}
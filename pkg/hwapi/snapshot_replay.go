@@ -0,0 +1,508 @@
+package hwapi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/registers"
+	"github.com/9elements/go-linux-lowlevel-hw/pkg/hwapi"
+	"github.com/digitalocean/go-smbios/smbios"
+)
+
+// snapshotReplay implements hwapi.LowLevelHardwareInterfaces on top of
+// a Snapshot.
+type snapshotReplay struct {
+	snapshot   *Snapshot
+	tpmBackend TPMBackend
+
+	// msrErrors are the MSRs declared as unreadable by MockBuilder,
+	// the errors could not be recorded in the snapshot.
+	msrErrors map[int64]error
+
+	// PCI config space is writable (for example, to access SMN), so
+	// the replay works on a copy of it.
+	pciLocker  sync.Mutex
+	pciConfigs map[hwapi.PCIDevice][]byte
+
+	// isPhysWritable enables WritePhys, the snapshot memory is modified
+	// in this case. It is used by MockBuilder, which owns the snapshot.
+	isPhysWritable bool
+	physLocker     sync.RWMutex
+}
+
+// NewSnapshotReplay returns APIInterfaces which replays the snapshot instead
+// of accessing the hardware, see CaptureSnapshot.
+func NewSnapshotReplay(snapshot *Snapshot) hwapi.LowLevelHardwareInterfaces {
+	r := &snapshotReplay{
+		snapshot:   snapshot,
+		pciConfigs: map[hwapi.PCIDevice][]byte{},
+	}
+	for _, dev := range snapshot.PCIDevices {
+		r.pciConfigs[dev.Device] = append([]byte{}, dev.Config...)
+	}
+	if snapshot.TPM != nil {
+		r.tpmBackend = snapshot.TPM
+	}
+	return r
+}
+
+func (r *snapshotReplay) cpuid(leaf, subleaf uint32) (uint32, uint32, uint32, uint32) {
+	for _, item := range r.snapshot.CPUID {
+		if item.Leaf == leaf && item.Subleaf == subleaf {
+			return item.EAX, item.EBX, item.ECX, item.EDX
+		}
+	}
+	return 0, 0, 0, 0
+}
+
+func (r *snapshotReplay) CPUBlacklistTXTSupport() bool {
+	return CPUBlacklistTXTSupport(r)
+}
+
+func (r *snapshotReplay) CPUWhitelistTXTSupport() bool {
+	return CPUWhitelistTXTSupport(r)
+}
+
+func (r *snapshotReplay) VersionString() string {
+	_, ebx, ecx, edx := r.cpuid(0, 0)
+	var buf bytes.Buffer
+	for _, reg := range []uint32{ebx, edx, ecx} {
+		_ = binary.Write(&buf, binary.LittleEndian, reg)
+	}
+	return buf.String()
+}
+
+func (r *snapshotReplay) HasSMX() bool {
+	_, _, ecx, _ := r.cpuid(1, 0)
+	return ecx&(1<<6) != 0
+}
+
+func (r *snapshotReplay) HasVMX() bool {
+	_, _, ecx, _ := r.cpuid(1, 0)
+	return ecx&(1<<5) != 0
+}
+
+func (r *snapshotReplay) HasMTRR() bool {
+	_, _, _, edx := r.cpuid(1, 0)
+	return edx&(1<<12) != 0
+}
+
+func (r *snapshotReplay) ProcessorBrandName() string {
+	var buf bytes.Buffer
+	for leaf := uint32(0x80000002); leaf <= 0x80000004; leaf++ {
+		eax, ebx, ecx, edx := r.cpuid(leaf, 0)
+		for _, reg := range []uint32{eax, ebx, ecx, edx} {
+			_ = binary.Write(&buf, binary.LittleEndian, reg)
+		}
+	}
+	return strings.TrimSpace(strings.TrimRight(buf.String(), "\x00"))
+}
+
+func (r *snapshotReplay) CPUSignature() uint32 {
+	eax, _, _, _ := r.cpuid(1, 0)
+	return eax
+}
+
+func (r *snapshotReplay) CPUSignatureFull() (uint32, uint32, uint32, uint32) {
+	return r.cpuid(1, 0)
+}
+
+func (r *snapshotReplay) CPULogCount() uint32 {
+	_, ebx, _, _ := r.cpuid(1, 0)
+	return (ebx >> 16) & 0xff
+}
+
+func (r *snapshotReplay) CPUID(leaf uint32, subleaf uint32) (uint32, uint32, uint32, uint32) {
+	return r.cpuid(leaf, subleaf)
+}
+
+func (r *snapshotReplay) IsReservedInE820(start uint64, end uint64) (bool, error) {
+	return hwapi.IsReservedInE820(r, start, end)
+}
+
+func (r *snapshotReplay) UsableMemoryAbove4G() (size uint64, err error) {
+	return 0, fmt.Errorf("usable memory above 4G: %w", ErrNotRecorded)
+}
+
+func (r *snapshotReplay) UsableMemoryBelow4G() (size uint64, err error) {
+	return 0, fmt.Errorf("usable memory below 4G: %w", ErrNotRecorded)
+}
+
+func (r *snapshotReplay) LookupIOAddress(addr uint64, regs hwapi.VTdRegisters) ([]uint64, error) {
+	return nil, fmt.Errorf("IO address lookup: %w", ErrNotRecorded)
+}
+
+func (r *snapshotReplay) AddressRangesIsDMAProtected(first, end uint64) (bool, error) {
+	return hwapi.AddressRangesIsDMAProtected(r, first, end)
+}
+
+func (r *snapshotReplay) msr(msr int64) *MSR {
+	for idx := range r.snapshot.MSRs {
+		item := &r.snapshot.MSRs[idx]
+		if item.Address == msr {
+			return item
+		}
+	}
+	return nil
+}
+
+// ReadMSRPerCore implements MSRPerCoreReader.
+func (r *snapshotReplay) ReadMSRPerCore(msr int64) ([]uint64, error) {
+	if err := r.msrErrors[msr]; err != nil {
+		return nil, err
+	}
+	item := r.msr(msr)
+	if item == nil || len(item.Values) == 0 {
+		return nil, fmt.Errorf("MSR 0x%X: %w", msr, ErrNotRecorded)
+	}
+	return append([]uint64{}, item.Values...), nil
+}
+
+func (r *snapshotReplay) ReadMSR(msr int64) uint64 {
+	values, err := r.ReadMSRPerCore(msr)
+	if err != nil {
+		return 0
+	}
+	return values[0]
+}
+
+func (r *snapshotReplay) ReadMSRAllCores(msr int64) (uint64, error) {
+	values, err := r.ReadMSRPerCore(msr)
+	if err != nil {
+		return 0, err
+	}
+	for _, value := range values[1:] {
+		if value != values[0] {
+			return 0, fmt.Errorf("MSR 0x%X differs among the cores: %X", msr, values)
+		}
+	}
+	return values[0], nil
+}
+
+// Read implements registers.MSRReader.
+func (r *snapshotReplay) Read(msr int64) (uint64, error) {
+	return r.ReadMSRAllCores(msr)
+}
+
+func (r *snapshotReplay) HasSMRR() (bool, error) {
+	return hwapi.HasSMRR(r)
+}
+
+func (r *snapshotReplay) GetSMRRInfo() (hwapi.SMRR, error) {
+	return hwapi.GetSMRRInfo(r)
+}
+
+func (r *snapshotReplay) IA32FeatureControlIsLocked() (bool, error) {
+	return hwapi.IA32FeatureControlIsLocked(r)
+}
+
+func (r *snapshotReplay) IA32PlatformID() (uint64, error) {
+	return hwapi.IA32PlatformID(r)
+}
+
+func (r *snapshotReplay) AllowsVMXInSMX() (bool, error) {
+	return hwapi.AllowsVMXInSMX(r)
+}
+
+func (r *snapshotReplay) TXTLeavesAreEnabled() (bool, error) {
+	return hwapi.TXTLeavesAreEnabled(r)
+}
+
+func (r *snapshotReplay) IA32DebugInterfaceEnabledOrLocked() (*hwapi.IA32Debug, error) {
+	return hwapi.IA32DebugInterfaceEnabledOrLocked(r)
+}
+
+func (r *snapshotReplay) GetMSRRegisters() (registers.Registers, error) {
+	return registers.ReadMSRRegisters(r)
+}
+
+func (r *snapshotReplay) PCIEnumerateVisibleDevices(cb func(d hwapi.PCIDevice) (abort bool)) (err error) {
+	for _, dev := range r.snapshot.PCIDevices {
+		if cb(dev.Device) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// pciConfig returns the config space of the device, the caller should
+// hold pciLocker.
+func (r *snapshotReplay) pciConfig(d hwapi.PCIDevice, off int, size int) ([]byte, error) {
+	config, ok := r.pciConfigs[d]
+	if !ok {
+		return nil, fmt.Errorf("PCI device %v: %w", d, ErrNotRecorded)
+	}
+	if off < 0 || off+size > len(config) {
+		return nil, fmt.Errorf("PCI config space offset 0x%X (size %d) of device %v is out of range", off, size, d)
+	}
+	return config[off : off+size], nil
+}
+
+func (r *snapshotReplay) PCIReadConfig8(d hwapi.PCIDevice, off int) (uint8, error) {
+	b, err := r.PCIReadConfigSpace(d, off, 1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (r *snapshotReplay) PCIReadConfig16(d hwapi.PCIDevice, off int) (uint16, error) {
+	b, err := r.PCIReadConfigSpace(d, off, 2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint16(b), nil
+}
+
+func (r *snapshotReplay) PCIReadConfig32(d hwapi.PCIDevice, off int) (uint32, error) {
+	if d == (hwapi.PCIDevice{}) && off == amdSMNDataOffset && r.snapshot.SMN != nil {
+		smnAddr, err := r.PCIReadConfig32(d, amdSMNIndexOffset)
+		if err != nil {
+			return 0, err
+		}
+		value, ok := r.snapshot.SMN[smnAddr]
+		if !ok {
+			return 0, fmt.Errorf("SMN address 0x%08X: %w", smnAddr, ErrNotRecorded)
+		}
+		return value, nil
+	}
+	b, err := r.PCIReadConfigSpace(d, off, 4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+func (r *snapshotReplay) PCIWriteConfig8(d hwapi.PCIDevice, off int, val uint8) error {
+	return r.PCIWriteConfigSpace(d, off, val)
+}
+
+func (r *snapshotReplay) PCIWriteConfig16(d hwapi.PCIDevice, off int, val uint16) error {
+	return r.PCIWriteConfigSpace(d, off, val)
+}
+
+func (r *snapshotReplay) PCIWriteConfig32(d hwapi.PCIDevice, off int, val uint32) error {
+	return r.PCIWriteConfigSpace(d, off, val)
+}
+
+func (r *snapshotReplay) PCIReadVendorID(d hwapi.PCIDevice) (uint16, error) {
+	return r.PCIReadConfig16(d, 0)
+}
+
+func (r *snapshotReplay) PCIReadDeviceID(d hwapi.PCIDevice) (uint16, error) {
+	return r.PCIReadConfig16(d, 2)
+}
+
+func (r *snapshotReplay) ReadHostBridgeTseg() (uint32, uint32, error) {
+	return hwapi.ReadHostBridgeTseg(r)
+}
+
+func (r *snapshotReplay) ReadHostBridgeDPR() (hwapi.DMAProtectedRange, error) {
+	return hwapi.ReadHostBridgeDPR(r)
+}
+
+func (r *snapshotReplay) ReadPhys(addr int64, data hwapi.UintN) error {
+	buf := make([]byte, int(data.Size()))
+	if err := r.ReadPhysBuf(addr, buf); err != nil {
+		return err
+	}
+	return binary.Read(bytes.NewReader(buf), binary.LittleEndian, data)
+}
+
+func (r *snapshotReplay) ReadPhysBuf(addr int64, buf []byte) error {
+	r.physLocker.RLock()
+	defer r.physLocker.RUnlock()
+	// the requested range may span multiple adjacent regions
+	for len(buf) > 0 {
+		region := r.memoryRegion(uint64(addr))
+		if region == nil {
+			return fmt.Errorf("physical memory at 0x%X: %w", addr, ErrNotRecorded)
+		}
+		n := copy(buf, region.Data[uint64(addr)-region.Address:])
+		buf = buf[n:]
+		addr += int64(n)
+	}
+	return nil
+}
+
+// memoryRegion returns the region containing the address. If regions
+// overlap the last one takes precedence.
+func (r *snapshotReplay) memoryRegion(addr uint64) *MemoryRegion {
+	for idx := len(r.snapshot.Memory) - 1; idx >= 0; idx-- {
+		region := &r.snapshot.Memory[idx]
+		if addr >= region.Address && addr < region.Address+uint64(len(region.Data)) {
+			return region
+		}
+	}
+	return nil
+}
+
+func (r *snapshotReplay) WritePhys(addr int64, data hwapi.UintN) error {
+	if !r.isPhysWritable {
+		return fmt.Errorf("unable to write physical memory at 0x%X: snapshots are read-only", addr)
+	}
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, data); err != nil {
+		return fmt.Errorf("unable to serialize value %v: %w", data, err)
+	}
+
+	r.physLocker.Lock()
+	defer r.physLocker.Unlock()
+	region := r.memoryRegion(uint64(addr))
+	if region == nil || uint64(addr)+uint64(buf.Len()) > region.Address+uint64(len(region.Data)) {
+		return fmt.Errorf("physical memory at 0x%X (size %d): %w", addr, buf.Len(), ErrNotRecorded)
+	}
+	copy(region.Data[uint64(addr)-region.Address:], buf.Bytes())
+	return nil
+}
+
+func (r *snapshotReplay) NewTPM() (*hwapi.TPM, error) {
+	tpm, err := r.tpm()
+	if err != nil {
+		return nil, err
+	}
+	return &hwapi.TPM{
+		Version: tpm.TPMVersion(),
+		RWC:     snapshotTPMDevice{},
+	}, nil
+}
+
+func (r *snapshotReplay) tpm() (TPMBackend, error) {
+	if r.tpmBackend == nil {
+		return nil, fmt.Errorf("TPM: %w", ErrNotRecorded)
+	}
+	return r.tpmBackend, nil
+}
+
+func (r *snapshotReplay) NVLocked(tpmCon *hwapi.TPM) (bool, error) {
+	tpm, err := r.tpm()
+	if err != nil {
+		return false, err
+	}
+	return tpm.IsNVLocked()
+}
+
+func (r *snapshotReplay) ReadNVPublic(tpmCon *hwapi.TPM, index uint32) ([]byte, error) {
+	tpm, err := r.tpm()
+	if err != nil {
+		return nil, err
+	}
+	return tpm.ReadNVPublic(index)
+}
+
+func (r *snapshotReplay) NVReadValue(tpmCon *hwapi.TPM, index uint32, password string, size, offhandle uint32) ([]byte, error) {
+	tpm, err := r.tpm()
+	if err != nil {
+		return nil, err
+	}
+	return tpm.NVReadValue(index, password, size, offhandle)
+}
+
+func (r *snapshotReplay) ReadPCR(tpmCon *hwapi.TPM, pcr uint32) ([]byte, error) {
+	tpm, err := r.tpm()
+	if err != nil {
+		return nil, err
+	}
+	return tpm.ReadPCR(pcr)
+}
+
+func (r *snapshotReplay) GetACPITable(arg string) ([]byte, error) {
+	table, ok := r.snapshot.ACPITables[arg]
+	if !ok {
+		return nil, fmt.Errorf("ACPI table '%s': %w", arg, ErrNotRecorded)
+	}
+	return append([]byte{}, table...), nil
+}
+
+func (r *snapshotReplay) GetACPITableSysFS(arg string) ([]byte, error) {
+	return r.GetACPITable(arg)
+}
+
+func (r *snapshotReplay) GetACPITableDevMem(s string) ([]byte, error) {
+	return r.GetACPITable(s)
+}
+
+func (r *snapshotReplay) IterateOverSMBIOSTables(m uint8, callback func(s *smbios.Structure) bool) (ret bool, err error) {
+	for _, s := range r.snapshot.SMBIOS {
+		if s.Header.Type != m {
+			continue
+		}
+		if callback(s) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *snapshotReplay) IterateOverSMBIOSTablesType0(callback func(t0 *hwapi.SMBIOSType0) bool) (ret bool, err error) {
+	return false, fmt.Errorf("parsed SMBIOS tables: %w", ErrNotRecorded)
+}
+
+func (r *snapshotReplay) IterateOverSMBIOSTablesType17(callback func(t17 *hwapi.SMBIOSType17) bool) (ret bool, err error) {
+	return false, fmt.Errorf("parsed SMBIOS tables: %w", ErrNotRecorded)
+}
+
+func (r *snapshotReplay) IterateOverE820Ranges(target string, callback func(start uint64, end uint64) bool) (bool, error) {
+	for _, item := range r.snapshot.E820 {
+		if item.Type != target {
+			continue
+		}
+		if callback(item.Start, item.End) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *snapshotReplay) PCIReadConfigSpace(d hwapi.PCIDevice, off int, len int) ([]byte, error) {
+	r.pciLocker.Lock()
+	defer r.pciLocker.Unlock()
+	b, err := r.pciConfig(d, off, len)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{}, b...), nil
+}
+
+func (r *snapshotReplay) PCIWriteConfigSpace(d hwapi.PCIDevice, off int, val interface{}) error {
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, val); err != nil {
+		return fmt.Errorf("unable to serialize value %v: %w", val, err)
+	}
+	r.pciLocker.Lock()
+	defer r.pciLocker.Unlock()
+	b, err := r.pciConfig(d, off, buf.Len())
+	if err != nil {
+		return err
+	}
+	copy(b, buf.Bytes())
+	return nil
+}
+
+// KernelCmdline implements KernelCmdlineReader.
+func (r *snapshotReplay) KernelCmdline() (string, error) {
+	if r.snapshot.KernelCmdline == "" {
+		return "", fmt.Errorf("kernel command line: %w", ErrNotRecorded)
+	}
+	return r.snapshot.KernelCmdline, nil
+}
+
+// snapshotTPMDevice is a placeholder of the TPM device of a snapshot: all
+// the recorded TPM data is returned by the snapshotReplay methods, while
+// commands could not be sent to a snapshot.
+type snapshotTPMDevice struct{}
+
+func (snapshotTPMDevice) Read([]byte) (int, error) {
+	return 0, fmt.Errorf("unable to send a command to a TPM of a snapshot")
+}
+
+func (snapshotTPMDevice) Write([]byte) (int, error) {
+	return 0, fmt.Errorf("unable to send a command to a TPM of a snapshot")
+}
+
+func (snapshotTPMDevice) Close() error {
+	return nil
+}
@@ -0,0 +1,323 @@
+package hwapi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/registers"
+	"github.com/9elements/go-linux-lowlevel-hw/pkg/hwapi"
+	"github.com/digitalocean/go-smbios/smbios"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testHeapBase  = 0x7AF00000
+	testHeapSize  = 0x20000
+	testSinitBase = 0x7AF20000
+	testSinitSize = 0x10000
+)
+
+var (
+	testHostBridge = hwapi.PCIDevice{}
+	testPOPublic   = []byte{
+		0x01, 0xC1, 0x01, 0x06, // nvIndex
+		0x00, 0x0B, // nameAlg
+		0x00, 0x04, 0x00, 0x02, // attributes
+		0x00, 0x02, 0xAB, 0xCD, // authPolicy
+		0x00, 0x46, // dataSize
+	}
+)
+
+// snapshotTestHardware is a machine with an Intel CPU, TXT and a TPM2.0,
+// the data which is not declared here is not readable.
+type snapshotTestHardware struct {
+	hwapi.LowLevelHardwareInterfaces
+	memory []MemoryRegion
+}
+
+func newSnapshotTestHardware() *snapshotTestHardware {
+	configSpace := make([]byte, registers.TxtPublicSpaceSize)
+	binary.LittleEndian.PutUint64(configSpace[0x270:], testSinitBase)
+	binary.LittleEndian.PutUint64(configSpace[0x278:], testSinitSize)
+	binary.LittleEndian.PutUint64(configSpace[0x300:], testHeapBase)
+	binary.LittleEndian.PutUint64(configSpace[0x308:], testHeapSize)
+	binary.LittleEndian.PutUint32(configSpace[0x110:], 0x8086)
+
+	return &snapshotTestHardware{
+		LowLevelHardwareInterfaces: GetPcMock(func(uint64) byte { return 0xff }),
+		memory: []MemoryRegion{
+			{Address: registers.TxtPublicSpace, Data: configSpace},
+			{Address: testHeapBase, Data: bytes.Repeat([]byte{0x11}, testHeapSize)},
+			{Address: testSinitBase, Data: bytes.Repeat([]byte{0x22}, testSinitSize)},
+			// FIT pointer and the reset vector, the rest of the flash is not readable
+			{Address: 1<<32 - snapshotMemoryChunkSize, Data: bytes.Repeat([]byte{0x33}, snapshotMemoryChunkSize)},
+		},
+	}
+}
+
+func (hw *snapshotTestHardware) VersionString() string {
+	return "GenuineIntel"
+}
+
+func (hw *snapshotTestHardware) CPUID(leaf, subleaf uint32) (uint32, uint32, uint32, uint32) {
+	switch leaf {
+	case cpuidBasicMaxLeaf:
+		return 7, 0x756e6547, 0x6c65746e, 0x49656e69
+	case 1:
+		return 0x906EA, 0, 1<<5 | 1<<6, 1 << 12
+	case 7:
+		return 1, subleaf + 1, 0, 0
+	case cpuidExtendedMaxLeaf:
+		return cpuidExtendedMaxLeaf + 1, 0, 0, 0
+	}
+	return leaf, 0, 0, 0
+}
+
+func (hw *snapshotTestHardware) ReadMSR(msr int64) uint64 {
+	return uint64(msr) << 8
+}
+
+func (hw *snapshotTestHardware) ReadMSRPerCore(msr int64) ([]uint64, error) {
+	switch msr {
+	case registers.IA32SMRRPhysMaskRegisterOffset:
+		return []uint64{hw.ReadMSR(msr), hw.ReadMSR(msr), 0}, nil
+	case 0xC0010010:
+		return nil, errors.New("no such MSR")
+	}
+	return []uint64{hw.ReadMSR(msr), hw.ReadMSR(msr)}, nil
+}
+
+func (hw *snapshotTestHardware) PCIReadVendorID(d hwapi.PCIDevice) (uint16, error) {
+	if d != testHostBridge {
+		return 0xFFFF, nil
+	}
+	return 0x8086, nil
+}
+
+func (hw *snapshotTestHardware) PCIReadConfig32(d hwapi.PCIDevice, off int) (uint32, error) {
+	if d != testHostBridge {
+		return 0, fmt.Errorf("no device %v", d)
+	}
+	return 0x10000*uint32(off) + 0x8086, nil
+}
+
+func (hw *snapshotTestHardware) IterateOverE820Ranges(target string, callback func(start, end uint64) bool) (bool, error) {
+	if target != "Reserved" {
+		return false, nil
+	}
+	return callback(0xFED20000, 0xFED8FFFF), nil
+}
+
+func (hw *snapshotTestHardware) GetACPITable(name string) ([]byte, error) {
+	if name != "DMAR" {
+		return nil, fmt.Errorf("no table %s", name)
+	}
+	return []byte("DMAR"), nil
+}
+
+func (hw *snapshotTestHardware) IterateOverSMBIOSTables(m uint8, callback func(s *smbios.Structure) bool) (bool, error) {
+	if m != 0 {
+		return false, nil
+	}
+	return callback(&smbios.Structure{
+		Header:    smbios.Header{Type: 0, Length: 0x18},
+		Formatted: []byte{1, 2, 3},
+		Strings:   []string{"Vendor"},
+	}), nil
+}
+
+func (hw *snapshotTestHardware) ReadPhysBuf(addr int64, buf []byte) error {
+	for _, region := range hw.memory {
+		if uint64(addr) >= region.Address && uint64(addr)+uint64(len(buf)) <= region.Address+uint64(len(region.Data)) {
+			copy(buf, region.Data[uint64(addr)-region.Address:])
+			return nil
+		}
+	}
+	return fmt.Errorf("memory at 0x%X is not readable", addr)
+}
+
+func (hw *snapshotTestHardware) NewTPM() (*hwapi.TPM, error) {
+	return &hwapi.TPM{Version: hwapi.TPMVersion20, RWC: snapshotTPMDevice{}}, nil
+}
+
+func (hw *snapshotTestHardware) NVLocked(tpmCon *hwapi.TPM) (bool, error) {
+	return true, nil
+}
+
+func (hw *snapshotTestHardware) ReadNVPublic(tpmCon *hwapi.TPM, index uint32) ([]byte, error) {
+	if index != 0x1C10106 {
+		return nil, fmt.Errorf("NV index 0x%X is not defined", index)
+	}
+	return testPOPublic, nil
+}
+
+func (hw *snapshotTestHardware) NVReadValue(tpmCon *hwapi.TPM, index uint32, password string, size, offhandle uint32) ([]byte, error) {
+	if index != 0x1C10106 {
+		return nil, fmt.Errorf("NV index 0x%X is not defined", index)
+	}
+	return bytes.Repeat([]byte{0x44}, int(size)), nil
+}
+
+func (hw *snapshotTestHardware) ReadPCR(tpmCon *hwapi.TPM, pcr uint32) ([]byte, error) {
+	if pcr > 17 {
+		return nil, fmt.Errorf("PCR%d is not readable", pcr)
+	}
+	return bytes.Repeat([]byte{byte(pcr)}, 32), nil
+}
+
+func (hw *snapshotTestHardware) KernelCmdline() (string, error) {
+	return "root=/dev/sda1 intel_iommu=on", nil
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	hw := newSnapshotTestHardware()
+	snapshot, err := CaptureSnapshot(hw)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, snapshot.Write(&buf))
+	decoded, err := ReadSnapshot(&buf)
+	require.NoError(t, err)
+	require.Equal(t, snapshot, decoded)
+
+	replay := NewSnapshotReplay(decoded)
+
+	t.Run("CPU", func(t *testing.T) {
+		require.Equal(t, hw.VersionString(), replay.VersionString())
+		for _, leaf := range []uint32{0, 1, 5, cpuidExtendedMaxLeaf + 1} {
+			eax, ebx, ecx, edx := hw.CPUID(leaf, 0)
+			require.Equal(t, []uint32{eax, ebx, ecx, edx}, cpuidSlice(replay.CPUID(leaf, 0)), "leaf 0x%X", leaf)
+		}
+		eax, ebx, ecx, edx := hw.CPUID(7, 1)
+		require.Equal(t, []uint32{eax, ebx, ecx, edx}, cpuidSlice(replay.CPUID(7, 1)))
+		require.True(t, replay.HasSMX())
+		require.True(t, replay.HasVMX())
+	})
+
+	t.Run("MSR", func(t *testing.T) {
+		value, err := replay.ReadMSRAllCores(registers.IA32FeatureControlRegisterOffset)
+		require.NoError(t, err)
+		require.Equal(t, hw.ReadMSR(registers.IA32FeatureControlRegisterOffset), value)
+
+		_, err = replay.ReadMSRAllCores(registers.IA32SMRRPhysMaskRegisterOffset)
+		require.Error(t, err)
+		require.Equal(t, hw.ReadMSR(registers.IA32SMRRPhysMaskRegisterOffset), replay.ReadMSR(registers.IA32SMRRPhysMaskRegisterOffset))
+		values, err := ReadMSRPerCore(replay, registers.IA32SMRRPhysMaskRegisterOffset)
+		require.NoError(t, err)
+		require.Equal(t, []uint64{hw.ReadMSR(registers.IA32SMRRPhysMaskRegisterOffset), hw.ReadMSR(registers.IA32SMRRPhysMaskRegisterOffset), 0}, values)
+
+		_, err = replay.ReadMSRAllCores(0xC0010010)
+		require.ErrorIs(t, err, ErrNotRecorded)
+
+		_, err = replay.ReadMSRAllCores(0x10)
+		require.ErrorIs(t, err, ErrNotRecorded)
+	})
+
+	t.Run("PCI", func(t *testing.T) {
+		vendorID, err := replay.PCIReadVendorID(testHostBridge)
+		require.NoError(t, err)
+		require.Equal(t, uint16(0x8086), vendorID)
+		value, err := replay.PCIReadConfig32(testHostBridge, 0x5C)
+		require.NoError(t, err)
+		expected, _ := hw.PCIReadConfig32(testHostBridge, 0x5C)
+		require.Equal(t, expected, value)
+
+		_, err = replay.PCIReadConfig32(hwapi.PCIDevice{Device: 0x16}, 0)
+		require.Error(t, err)
+	})
+
+	t.Run("E820", func(t *testing.T) {
+		reserved, err := replay.IsReservedInE820(0xFED30000, 0xFED3FFFF)
+		require.NoError(t, err)
+		require.True(t, reserved)
+	})
+
+	t.Run("ACPI", func(t *testing.T) {
+		table, err := replay.GetACPITable("DMAR")
+		require.NoError(t, err)
+		require.Equal(t, []byte("DMAR"), table)
+		_, err = replay.GetACPITable("APIC")
+		require.Error(t, err)
+	})
+
+	t.Run("SMBIOS", func(t *testing.T) {
+		var structures []*smbios.Structure
+		_, err := replay.IterateOverSMBIOSTables(0, func(s *smbios.Structure) bool {
+			structures = append(structures, s)
+			return false
+		})
+		require.NoError(t, err)
+		require.Len(t, structures, 1)
+		require.Equal(t, []string{"Vendor"}, structures[0].Strings)
+	})
+
+	t.Run("Memory", func(t *testing.T) {
+		for _, region := range hw.memory {
+			data := make([]byte, len(region.Data))
+			require.NoError(t, replay.ReadPhysBuf(int64(region.Address), data), "region 0x%X", region.Address)
+			require.Equal(t, region.Data, data, "region 0x%X", region.Address)
+		}
+
+		var value hwapi.Uint64
+		require.NoError(t, replay.ReadPhys(registers.TxtPublicSpace+0x300, &value))
+		require.Equal(t, hwapi.Uint64(testHeapBase), value)
+
+		// only the readable chunks of the flash are recorded
+		err := replay.ReadPhysBuf(1<<32-snapshotFlashSize, make([]byte, 4))
+		require.ErrorIs(t, err, ErrNotRecorded)
+
+		value = 0
+		require.Error(t, replay.WritePhys(testHeapBase, &value))
+	})
+
+	t.Run("TPM", func(t *testing.T) {
+		tpmCon, err := replay.NewTPM()
+		require.NoError(t, err)
+		require.Equal(t, hwapi.TPMVersion20, tpmCon.Version)
+
+		locked, err := replay.NVLocked(tpmCon)
+		require.NoError(t, err)
+		require.True(t, locked)
+
+		public, err := replay.ReadNVPublic(tpmCon, 0x1C10106)
+		require.NoError(t, err)
+		require.Equal(t, testPOPublic, public)
+		value, err := replay.NVReadValue(tpmCon, 0x1C10106, "", 0x46, 0x1C10106)
+		require.NoError(t, err)
+		require.Equal(t, bytes.Repeat([]byte{0x44}, 0x46), value)
+
+		_, err = replay.ReadNVPublic(tpmCon, 0x1C10103)
+		require.Error(t, err)
+		_, err = replay.NVReadValue(tpmCon, 0x50000001, "", 1, 0)
+		require.ErrorIs(t, err, ErrNotRecorded)
+
+		pcr, err := replay.ReadPCR(tpmCon, 17)
+		require.NoError(t, err)
+		require.Equal(t, bytes.Repeat([]byte{17}, 32), pcr)
+		_, err = replay.ReadPCR(tpmCon, 18)
+		require.ErrorIs(t, err, ErrNotRecorded)
+	})
+
+	t.Run("KernelCmdline", func(t *testing.T) {
+		cmdline, err := KernelCmdline(replay)
+		require.NoError(t, err)
+		require.Equal(t, "root=/dev/sda1 intel_iommu=on", cmdline)
+
+		_, err = KernelCmdline(NewSnapshotReplay(&Snapshot{Version: SnapshotVersion}))
+		require.ErrorIs(t, err, ErrNotRecorded)
+	})
+}
+
+func TestReadSnapshotVersion(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, (&Snapshot{Version: SnapshotVersion + 1}).Write(&buf))
+	_, err := ReadSnapshot(&buf)
+	require.Error(t, err)
+}
+
+func cpuidSlice(eax, ebx, ecx, edx uint32) []uint32 {
+	return []uint32{eax, ebx, ecx, edx}
+}
@@ -0,0 +1,81 @@
+package hwapi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/tpm/simulator"
+	"github.com/9elements/go-linux-lowlevel-hw/pkg/hwapi"
+	"github.com/google/go-tpm/legacy/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// simulatorBackend is a TPMBackend, which sends TPM 2.0 commands to
+// the simulator.
+type simulatorBackend struct {
+	rw io.ReadWriter
+}
+
+var _ TPMBackend = (*simulatorBackend)(nil)
+
+// NewSimulatorBackend returns a TPMBackend on top of the in-process TPM 2.0
+// simulator, so the tests could be executed against the NV indices and PCRs
+// provisioned by real TPM commands (see MockBuilder.WithTPMBackend).
+func NewSimulatorBackend(sim *simulator.Simulator) TPMBackend {
+	return &simulatorBackend{rw: sim}
+}
+
+// TPMVersion implements TPMBackend.
+func (b *simulatorBackend) TPMVersion() hwapi.TPMVersion {
+	return hwapi.TPMVersion20
+}
+
+// IsNVLocked implements TPMBackend.
+//
+// TPM 2.0 has no global NV lock, the platform NV indices are protected
+// by the platform authorization instead. Thus the NV is considered locked
+// if the platform authorization is not empty (the same way as txt-prov
+// checks it).
+func (b *simulatorBackend) IsNVLocked() (bool, error) {
+	emptyAuth := tpm2.AuthCommand{Session: tpm2.HandlePasswordSession, Attributes: tpm2.AttrContinueSession}
+	err := tpm2.HierarchyChangeAuth(b.rw, tpm2.HandlePlatform, emptyAuth, "")
+	if err == nil {
+		return false, nil
+	}
+	var sessionErr tpm2.SessionError
+	if errors.As(err, &sessionErr) && (sessionErr.Code == tpm2.RCAuthFail || sessionErr.Code == tpm2.RCBadAuth) {
+		return true, nil
+	}
+	return false, fmt.Errorf("unable to check the platform authorization: %w", err)
+}
+
+// ReadNVPublic implements TPMBackend.
+func (b *simulatorBackend) ReadNVPublic(index uint32) ([]byte, error) {
+	public, err := tpm2.NVReadPublic(b.rw, tpmutil.Handle(index))
+	if err != nil {
+		return nil, err
+	}
+	return tpmutil.Pack(public)
+}
+
+// NVReadValue implements TPMBackend.
+func (b *simulatorBackend) NVReadValue(index uint32, password string, size, offhandle uint32) ([]byte, error) {
+	authHandle := tpmutil.Handle(offhandle)
+	if offhandle == 0 {
+		authHandle = tpmutil.Handle(index)
+	}
+	value, err := tpm2.NVReadEx(b.rw, tpmutil.Handle(index), authHandle, password, 0)
+	if err != nil {
+		return nil, err
+	}
+	if int(size) > len(value) {
+		return nil, fmt.Errorf("NV index 0x%X has only %d bytes, requested %d", index, len(value), size)
+	}
+	return value[:size], nil
+}
+
+// ReadPCR implements TPMBackend. The value is read from the SHA1 bank.
+func (b *simulatorBackend) ReadPCR(pcr uint32) ([]byte, error) {
+	return tpm2.ReadPCR(b.rw, int(pcr), tpm2.AlgSHA1)
+}
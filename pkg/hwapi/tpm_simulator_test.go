@@ -0,0 +1,61 @@
+package hwapi
+
+import (
+	"crypto/sha1"
+	"testing"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/tpm/simulator"
+	"github.com/9elements/go-linux-lowlevel-hw/pkg/hwapi"
+	"github.com/google/go-tpm/legacy/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimulatorBackend(t *testing.T) {
+	const index = 0x1C10106
+	sim := simulator.New()
+	attrs := tpm2.AttrOwnerWrite + tpm2.AttrAuthRead + tpm2.AttrNoDA
+	require.NoError(t, tpm2.NVDefineSpace(sim, tpm2.HandleOwner, index, "", "", nil, attrs, 4))
+	require.NoError(t, tpm2.NVWrite(sim, tpm2.HandleOwner, index, "", []byte{1, 2, 3, 4}, 0))
+	digest := sha1.Sum([]byte("test"))
+	require.NoError(t, tpm2.PCRExtend(sim, 0, tpm2.AlgSHA1, digest[:], ""))
+
+	hw := NewMockBuilder().WithTPMBackend(NewSimulatorBackend(sim)).Build()
+	tpmCon, err := hw.NewTPM()
+	require.NoError(t, err)
+	require.Equal(t, hwapi.TPMVersion20, tpmCon.Version)
+
+	public, err := hw.ReadNVPublic(tpmCon, index)
+	require.NoError(t, err)
+	var parsed tpm2.NVPublic
+	_, err = tpmutil.Unpack(public, &parsed)
+	require.NoError(t, err)
+	require.Equal(t, attrs|tpm2.AttrWritten, parsed.Attributes)
+	require.Equal(t, uint16(4), parsed.DataSize)
+
+	value, err := hw.NVReadValue(tpmCon, index, "", 3, index)
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, value)
+	_, err = hw.NVReadValue(tpmCon, index, "", 5, index)
+	require.Error(t, err)
+
+	_, err = hw.ReadNVPublic(tpmCon, index+1)
+	require.ErrorContains(t, err, "error code 0xb")
+
+	expectedPCR0 := sha1.Sum(append(make([]byte, sha1.Size), digest[:]...))
+	pcr0, err := hw.ReadPCR(tpmCon, 0)
+	require.NoError(t, err)
+	require.Equal(t, expectedPCR0[:], pcr0)
+	pcr1, err := hw.ReadPCR(tpmCon, 1)
+	require.NoError(t, err)
+	require.Equal(t, make([]byte, sha1.Size), pcr1)
+
+	locked, err := hw.NVLocked(tpmCon)
+	require.NoError(t, err)
+	require.False(t, locked)
+	platformAuth := tpm2.AuthCommand{Session: tpm2.HandlePasswordSession, Attributes: tpm2.AttrContinueSession}
+	require.NoError(t, tpm2.HierarchyChangeAuth(sim, tpm2.HandlePlatform, platformAuth, "platform"))
+	locked, err = hw.NVLocked(tpmCon)
+	require.NoError(t, err)
+	require.True(t, locked)
+}
@@ -128,3 +128,29 @@ type ErrDummyFirmwareVersionFileWrongType struct{}
 func (err *ErrDummyFirmwareVersionFileWrongType) Error() string {
 	return "version file of a dummy firmware is not a file"
 }
+
+// ErrEDK2FirmwareVersionStringNotFound means the value of
+// PcdFirmwareVersionString was not found in the TCG PEI module,
+// so the default value is assumed.
+type ErrEDK2FirmwareVersionStringNotFound struct {
+	Err error
+}
+
+func (err *ErrEDK2FirmwareVersionStringNotFound) Error() string {
+	if err.Err == nil {
+		return "[EDK2] unable to find the value of PcdFirmwareVersionString"
+	}
+	return fmt.Sprintf("[EDK2] unable to find the value of PcdFirmwareVersionString: %v", err.Err)
+}
+
+func (err *ErrEDK2FirmwareVersionStringNotFound) Unwrap() error {
+	return err.Err
+}
+
+// ErrAMINotOCP means the firmware is AMI-based, but it is not an OCP firmware,
+// the source of PCD values of such firmwares is unknown.
+type ErrAMINotOCP struct{}
+
+func (err *ErrAMINotOCP) Error() string {
+	return "[AMI] the firmware is not OCP, the source of the PCD values is unknown"
+}
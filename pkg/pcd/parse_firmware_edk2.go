@@ -0,0 +1,125 @@
+package pcd
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/dmidecode"
+	ffsConsts "github.com/9elements/converged-security-suite/v2/pkg/uefi/ffs/consts"
+	"github.com/9elements/converged-security-suite/v2/pkg/uefi/varstore"
+	pkgbytes "github.com/linuxboot/fiano/pkg/bytes"
+	"github.com/linuxboot/fiano/pkg/guid"
+)
+
+func init() {
+	addFirmwareParser(ParseFirmwareEDK2)
+}
+
+// ParseFirmwareEDK2 is a variant of ParseFirmware for generic EDK2-based
+// firmwares (which measure PcdFirmwareVersionString by the TCG PEI module).
+func ParseFirmwareEDK2(
+	firmwareImage FirmwareImage,
+) (ParsedFirmware, error) {
+	// AMI-based firmwares are not EDK2 in the sense of TCG measurements:
+	// OCP ones are handled by ParseFirmwareOCP, other ones are not supported.
+	isAMI, err := IsAMI(firmwareImage)
+	if err != nil {
+		return nil, err
+	}
+	if isAMI {
+		return nil, nil
+	}
+
+	for _, sourceGUID := range []guid.GUID{ffsConsts.GUIDModuleTcg2Pie, ffsConsts.GUIDModuleTcgPie} {
+		nodes, err := firmwareImage.GetByGUID(sourceGUID)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse the firmware: %w", err)
+		}
+		switch len(nodes) {
+		case 0:
+			continue
+		case 1:
+		default:
+			return nil, &ErrTooManyTCGPEIModules{}
+		}
+		node := nodes[0]
+		if node.Offset == math.MaxUint64 {
+			// the module is in a compressed area
+			return &ParsedFirmwareEDK2{
+				ParsedFirmwareGeneric: ParsedFirmwareGeneric{
+					FirmwareImage:                firmwareImage,
+					FirmwareVendorVersionFFSGUID: sourceGUID,
+				},
+			}, nil
+		}
+
+		result := &ParsedFirmwareEDK2{
+			ParsedFirmwareGeneric: ParsedFirmwareGeneric{
+				FirmwareImage: firmwareImage,
+				FirmwareVendorVersionCodeRanges: pkgbytes.Ranges{{
+					Offset: node.Offset,
+					Length: uint64(len(node.Buf())),
+				}},
+				FirmwareVendorVersionFFSGUID: sourceGUID,
+			},
+		}
+
+		// If PcdFirmwareVersionString is FixedAtBuild (or PatchableInModule), then
+		// the value is embedded into the module. Platforms usually set
+		// it to the same value as the BIOS version in SMBIOS, so
+		// we look for that value.
+		dmiTable, err := dmidecode.DMITableFromFirmware(firmwareImage)
+		if err != nil {
+			return result, &ErrEDK2FirmwareVersionStringNotFound{Err: err}
+		}
+		version := dmiTable.BIOSInfo().Version
+		if version == "" {
+			return result, &ErrEDK2FirmwareVersionStringNotFound{}
+		}
+		needle := varstore.EncodeUCS2(version)
+		idx := bytes.Index(node.Buf(), needle)
+		if idx < 0 {
+			return result, &ErrEDK2FirmwareVersionStringNotFound{}
+		}
+		result.FirmwareVendorVersionRanges = pkgbytes.Ranges{{
+			Offset: node.Offset + uint64(idx),
+			Length: uint64(len(needle)),
+		}}
+		return result, nil
+	}
+
+	return nil, nil
+}
+
+// IsAMI returns true if the firmware image contains an AMI TCG PEI module
+// (AmiTcgPlatformPeiAfterMem or AmiTpm20PlatformPei).
+func IsAMI(firmwareImage FirmwareImage) (bool, error) {
+	for _, moduleGUID := range []guid.GUID{ffsConsts.GUIDAmiTcgPlatformPeiAfterMem, ffsConsts.GUIDAmiTpm20PlatformPei} {
+		nodes, err := firmwareImage.GetByGUID(moduleGUID)
+		if err != nil {
+			return false, fmt.Errorf("unable to parse the firmware: %w", err)
+		}
+		if len(nodes) != 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ParsedFirmwareEDK2 is the PCD parsed from a generic EDK2-based firmware.
+type ParsedFirmwareEDK2 struct {
+	ParsedFirmwareGeneric
+}
+
+// GetFirmwareVendorVersion returns the firmware vendor version, or
+// the default value of PcdFirmwareVersionString (an empty UCS-2 string) if
+// the value was not found in the image.
+func (pcd *ParsedFirmwareEDK2) GetFirmwareVendorVersion() []byte {
+	if pcd.FirmwareVendorVersionRanges != nil {
+		return pcd.ParsedFirmwareGeneric.GetFirmwareVendorVersion()
+	}
+
+	// See PcdFirmwareVersionString in MdeModulePkg/MdeModulePkg.dec
+	return varstore.EncodeUCS2("")
+}
@@ -0,0 +1,30 @@
+package txt
+
+import (
+	"fmt"
+	"io"
+
+	tpm2 "github.com/google/go-tpm/legacy/tpm2"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefinePOIndexTPM20 creates the PO index for TPM 2.0
+func DefinePOIndexTPM20(rw io.ReadWriter, passHash []byte) error {
+	_, err := tpm2.NVReadPublic(rw, tpm2POIndexDef.NVIndex)
+	if err == nil {
+		return fmt.Errorf("PO index already defined in TPM 2.0 - Delete first")
+	}
+	poPolicyHash, err := getPSPolicyHash(rw, passHash)
+	if err != nil {
+		return fmt.Errorf("getPSPolicyHash() failed: %v", err)
+	}
+	tpm2POIndexDef.AuthPolicy = poPolicyHash
+	authArea := tpm2.AuthCommand{Session: tpm2.HandlePasswordSession, Attributes: tpm2.AttrContinueSession, Auth: tpm2.EmptyAuth}
+	err = tpm2.NVDefineSpaceEx(rw, tpm2.HandleOwner, "", tpm2POIndexDef, authArea)
+	if err != nil {
+		return fmt.Errorf("NVDefineSpaceEx() failed: %v", err)
+	}
+	log.Info("PO index defined successfully")
+	return nil
+}
@@ -0,0 +1,20 @@
+package txt
+
+import (
+	"fmt"
+	"io"
+
+	tpm2 "github.com/google/go-tpm/legacy/tpm2"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DeletePOIndexTPM20 deletes the PO index on TPM 2.0
+func DeletePOIndexTPM20(rw io.ReadWriter) error {
+	err := tpm2.NVUndefineSpace(rw, "", tpm2.HandleOwner, tpm2POIndexDef.NVIndex)
+	if err != nil {
+		return fmt.Errorf("NVUndefineSpace() failed: %v", err)
+	}
+	log.Info("PO index deleted successfully")
+	return nil
+}
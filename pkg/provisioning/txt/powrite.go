@@ -0,0 +1,18 @@
+package txt
+
+import (
+	"io"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/tools"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// WritePOIndexTPM20 writes the LCP Policy2 into the PO index of TPM 2.0
+func WritePOIndexTPM20(rw io.ReadWriter, lcppol *tools.LCPPolicy2, passHash []byte) error {
+	if err := writeLCPPolicy2TPM20(rw, tpm2POIndexDef.NVIndex, lcppol, passHash); err != nil {
+		return err
+	}
+	log.Info("PO index updated successfully")
+	return nil
+}
@@ -0,0 +1,153 @@
+package txt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/tools"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpm/simulator"
+	"github.com/google/go-tpm/legacy/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testPassHash  = passHash("password")
+	wrongPassHash = passHash("wrong password")
+)
+
+func passHash(password string) []byte {
+	hash := sha256.Sum256([]byte(password))
+	return hash[:]
+}
+
+func testLCPPolicy2(policyControl uint32) *tools.LCPPolicy2 {
+	return &tools.LCPPolicy2{
+		Version:            0x302,
+		HashAlg:            tpm2.AlgSHA256,
+		PolicyType:         tools.LCPPolicyTypeAny,
+		PolicyControl:      policyControl,
+		MaxSINITMinVersion: 0xFF,
+		Reserved:           0xFF,
+		LcpHashAlgMask:     0x0008,
+		LcpSignAlgMask:     0x0008,
+		Reserved2:          0x0008,
+	}
+}
+
+func readLCPPolicy2(t *testing.T, rw *simulator.Simulator, index tpmutil.Handle) *tools.LCPPolicy2 {
+	data, err := tpm2.NVRead(rw, index)
+	require.NoError(t, err)
+	pol, pol2, err := tools.ParsePolicy(data)
+	require.NoError(t, err)
+	require.Nil(t, pol)
+	return pol2
+}
+
+func binaryLCPPolicy2(t *testing.T, pol *tools.LCPPolicy2) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, binary.Write(&buf, binary.LittleEndian, *pol))
+	return buf.Bytes()
+}
+
+func TestPSIndex(t *testing.T) {
+	rw := simulator.New()
+	pol := testLCPPolicy2(0)
+
+	require.Error(t, WritePSIndexTPM20(rw, pol, testPassHash))
+	require.Error(t, DeletePSIndexTPM20(rw, testPassHash))
+
+	require.NoError(t, DefinePSIndexTPM20(rw, testPassHash))
+	require.Error(t, DefinePSIndexTPM20(rw, testPassHash))
+
+	public, err := tpm2.NVReadPublic(rw, tpm2PSIndexDef.NVIndex)
+	require.NoError(t, err)
+	require.Equal(t, tpm2PSIndexDef.Attributes, public.Attributes)
+	require.Equal(t, uint16(tpm2PSIndexSize), public.DataSize)
+	policyHash, err := getPSPolicyHash(rw, testPassHash)
+	require.NoError(t, err)
+	require.Equal(t, policyHash, []byte(public.AuthPolicy))
+
+	require.Error(t, WritePSIndexTPM20(rw, pol, wrongPassHash))
+	require.NoError(t, WritePSIndexTPM20(rw, pol, testPassHash))
+	require.Len(t, binaryLCPPolicy2(t, pol), tpm2PSIndexSize)
+	require.Equal(t, pol, readLCPPolicy2(t, rw, tpm2PSNVIndex))
+
+	// the PS index is deleted only through the policy session
+	require.Error(t, tpm2.NVUndefineSpace(rw, "", tpm2.HandlePlatform, tpm2PSIndexDef.NVIndex))
+	require.Error(t, DeletePSIndexTPM20(rw, wrongPassHash))
+	require.NoError(t, DeletePSIndexTPM20(rw, testPassHash))
+	_, err = tpm2.NVReadPublic(rw, tpm2PSIndexDef.NVIndex)
+	require.Error(t, err)
+
+	// the index can be defined with another password after the deletion
+	require.NoError(t, DefinePSIndexTPM20(rw, wrongPassHash))
+	require.Error(t, WritePSIndexTPM20(rw, pol, testPassHash))
+	require.NoError(t, WritePSIndexTPM20(rw, pol, wrongPassHash))
+	require.NoError(t, DeletePSIndexTPM20(rw, wrongPassHash))
+}
+
+func TestAUXIndex(t *testing.T) {
+	rw := simulator.New()
+
+	require.NoError(t, DefineAUXIndexTPM20(rw))
+	require.Error(t, DefineAUXIndexTPM20(rw))
+
+	public, err := tpm2.NVReadPublic(rw, tpm20AUXIndexDef.NVIndex)
+	require.NoError(t, err)
+	require.Equal(t, tpm20AUXIndexDef.Attributes, public.Attributes)
+	require.Equal(t, uint16(tpm2AUXIndexSize), public.DataSize)
+	require.Equal(t, tpm20AUXIndexHashData, public.AuthPolicy)
+
+	// the AUX index policy is satisfied only by SINIT, the deletion is
+	// requested through the AuxDelete bit in the PS index
+	require.Error(t, tpm2.NVUndefineSpace(rw, "", tpm2.HandlePlatform, tpm20AUXIndexDef.NVIndex))
+	require.Error(t, DeleteAUXindexTPM20(rw, testLCPPolicy2(0), testPassHash))
+	auxDelete := testLCPPolicy2(1 << 31)
+	require.Error(t, DeleteAUXindexTPM20(rw, auxDelete, testPassHash))
+
+	require.NoError(t, DefinePSIndexTPM20(rw, testPassHash))
+	require.Error(t, DeleteAUXindexTPM20(rw, auxDelete, wrongPassHash))
+	require.NoError(t, DeleteAUXindexTPM20(rw, auxDelete, testPassHash))
+	require.True(t, readLCPPolicy2(t, rw, tpm2PSNVIndex).ParsePolicyControl2().AuxDelete)
+	_, err = tpm2.NVReadPublic(rw, tpm20AUXIndexDef.NVIndex)
+	require.NoError(t, err)
+}
+
+func TestPOIndex(t *testing.T) {
+	rw := simulator.New()
+	pol := testLCPPolicy2(0)
+
+	require.Error(t, DeletePOIndexTPM20(rw))
+	require.NoError(t, DefinePOIndexTPM20(rw, testPassHash))
+	require.Error(t, DefinePOIndexTPM20(rw, testPassHash))
+
+	public, err := tpm2.NVReadPublic(rw, tpm2POIndexDef.NVIndex)
+	require.NoError(t, err)
+	require.Equal(t, tpm2POIndexDef.Attributes, public.Attributes)
+	require.Equal(t, uint16(tpm2POIndexSize), public.DataSize)
+
+	require.Error(t, WritePOIndexTPM20(rw, pol, wrongPassHash))
+	require.NoError(t, WritePOIndexTPM20(rw, pol, testPassHash))
+	require.Equal(t, pol, readLCPPolicy2(t, rw, tpm2PONVIndex))
+
+	pol.SINITMinVersion = 1
+	require.NoError(t, WritePOIndexTPM20(rw, pol, testPassHash))
+	require.Equal(t, pol, readLCPPolicy2(t, rw, tpm2PONVIndex))
+
+	require.NoError(t, DeletePOIndexTPM20(rw))
+	_, err = tpm2.NVReadPublic(rw, tpm2POIndexDef.NVIndex)
+	require.Error(t, err)
+}
+
+func TestPrintProvisioningTPM20(t *testing.T) {
+	rw := simulator.New()
+	PrintProvisioningTPM20(rw)
+
+	require.NoError(t, DefinePSIndexTPM20(rw, testPassHash))
+	require.NoError(t, WritePSIndexTPM20(rw, testLCPPolicy2(0), testPassHash))
+	require.NoError(t, DefineAUXIndexTPM20(rw))
+	PrintProvisioningTPM20(rw)
+}
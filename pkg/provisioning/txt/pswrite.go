@@ -15,6 +15,16 @@ import (
 
 // WritePSIndexTPM20 writes the LCP Policy2 into the PS index of TPM 2.0
 func WritePSIndexTPM20(rw io.ReadWriter, lcppol *tools.LCPPolicy2, passHash []byte) error {
+	if err := writeLCPPolicy2TPM20(rw, tpm2PSIndexDef.NVIndex, lcppol, passHash); err != nil {
+		return err
+	}
+	log.Info("PS index updated successfully")
+	return nil
+}
+
+// writeLCPPolicy2TPM20 writes the LCP Policy2 into an index protected by
+// the policy returned by getPSPolicyHash.
+func writeLCPPolicy2TPM20(rw io.ReadWriter, index tpmutil.Handle, lcppol *tools.LCPPolicy2, passHash []byte) error {
 	zeroHash := make([]byte, 32)
 	delPol, err := constructDelBranch(rw, passHash, zeroHash)
 	if err != nil {
@@ -28,6 +38,9 @@ func WritePSIndexTPM20(rw io.ReadWriter, lcppol *tools.LCPPolicy2, passHash []by
 	if err != nil {
 		return fmt.Errorf("StartAuthSession in writePSPolicy failed: %v", err)
 	}
+	defer func() {
+		_ = tpm2.FlushContext(rw, sess)
+	}()
 
 	a := tpm2.TPMLDigest{Digests: []tpmutil.U16Bytes{passHash, zeroHash}}
 	b := tpm2.TPMLDigest{Digests: []tpmutil.U16Bytes{delPol, writePol}}
@@ -53,11 +66,10 @@ func WritePSIndexTPM20(rw io.ReadWriter, lcppol *tools.LCPPolicy2, passHash []by
 	if err != nil {
 		return fmt.Errorf("NVWrite in writePSPolicy failed: %v", err)
 	}
-	err = tpm2.NVWriteEx(rw, tpm2PSIndexDef.NVIndex, tpm2PSIndexDef.NVIndex, authArea, buf.Bytes(), 0)
+	err = tpm2.NVWriteEx(rw, index, index, authArea, buf.Bytes(), 0)
 	if err != nil {
 		return fmt.Errorf("NVWrite in writePSPolicy failed: %v", err)
 	}
-	log.Info("PS index updated successfully")
 	return nil
 }
 
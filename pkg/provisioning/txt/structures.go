@@ -10,8 +10,10 @@ import (
 const (
 	tpm2PSNVIndex    = 0x01C10103
 	tpm2AUXNVIndex   = 0x01C10102
+	tpm2PONVIndex    = 0x01C10106
 	tpm2PSIndexSize  = 70
 	tpm2AUXIndexSize = 104
+	tpm2POIndexSize  = 70
 )
 
 var (
@@ -34,6 +36,14 @@ var (
 		AuthPolicy: tpm20AUXIndexHashData,
 		DataSize:   uint16(tpm2AUXIndexSize),
 	}
+
+	tpm2POIndexDef = tpm2.NVPublic{
+		NVIndex: tpmutil.Handle(tpm2PONVIndex),
+		NameAlg: tpm2.AlgSHA256,
+		Attributes: tpm2.AttrOwnerWrite + tpm2.AttrPolicyWrite +
+			tpm2.AttrAuthRead + tpm2.AttrNoDA,
+		DataSize: uint16(tpm2POIndexSize),
+	}
 )
 
 // HashMapping exports a map to convert hash names to its respective library object.
@@ -23,6 +23,16 @@ func TestMP0C2PMsg37Register(t *testing.T) {
 		t.Errorf("Unexepcted value of platform secure boot enabled '%t' of the register", reg.IsPlatformSecureBootEnabled())
 	}
 
+	if reg.PlatformVendorID() != 0xAD {
+		t.Errorf("Unexepcted value of platform vendor ID '0x%X' of the register", reg.PlatformVendorID())
+	}
+	if !reg.IsCustomerKeyLocked() {
+		t.Errorf("Unexepcted value of customer key lock '%t' of the register", reg.IsCustomerKeyLocked())
+	}
+	if reg.IsAMDKeyDisabled() {
+		t.Errorf("Unexepcted value of AMD key disabled '%t' of the register", reg.IsAMDKeyDisabled())
+	}
+
 	fields := reg.Fields()
 	if len(fields) != 3 {
 		t.Errorf("Unexepcted value of fields '%d' of the register", len(fields))
@@ -0,0 +1,69 @@
+package registers
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// DumpFormat is a format of a file with register values.
+type DumpFormat int
+
+const (
+	// DumpFormatUndefined is the zero value of DumpFormat.
+	DumpFormatUndefined = DumpFormat(iota)
+
+	// DumpFormatYAML is the format of Registers.MarshalYAML.
+	DumpFormatYAML
+
+	// DumpFormatJSON is the obsolete format of Registers.MarshalJSON.
+	DumpFormatJSON
+
+	// DumpFormatTXTPublicSpace is a raw dump of the TXT public space
+	// (TxtPublicSpaceSize bytes starting at TxtPublicSpace).
+	DumpFormatTXTPublicSpace
+)
+
+// String implements fmt.Stringer.
+func (f DumpFormat) String() string {
+	switch f {
+	case DumpFormatUndefined:
+		return "undefined"
+	case DumpFormatYAML:
+		return "yaml"
+	case DumpFormatJSON:
+		return "json"
+	case DumpFormatTXTPublicSpace:
+		return "txt-public-space"
+	}
+	return fmt.Sprintf("unknown_format_%d", int(f))
+}
+
+// UnmarshalDump parses registers from a file of any of the supported
+// formats and returns the detected format.
+func UnmarshalDump(b []byte) (Registers, DumpFormat, error) {
+	if len(b) == TxtPublicSpaceSize {
+		regs, err := ReadTXTRegisters(b)
+		return regs, DumpFormatTXTPublicSpace, err
+	}
+
+	var mErr errors.MultiError
+
+	// TODO: Remove the JSON format, it is added only for backward compatibility
+	var regs Registers
+	err := json.Unmarshal(b, &regs)
+	if err == nil {
+		return regs, DumpFormatJSON, nil
+	}
+	_ = mErr.Add(fmt.Errorf("not a JSON dump: %w", err))
+
+	err = yaml.Unmarshal(b, &regs)
+	if err == nil {
+		return regs, DumpFormatYAML, nil
+	}
+	_ = mErr.Add(fmt.Errorf("not a YAML dump: %w", err))
+
+	return nil, DumpFormatUndefined, mErr.ReturnValue()
+}
@@ -0,0 +1,116 @@
+package registers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/hwapi"
+	"github.com/9elements/converged-security-suite/v2/pkg/registers"
+	"gopkg.in/yaml.v3"
+)
+
+type msrReaderMock struct{}
+
+func (m *msrReaderMock) Read(msr int64) (uint64, error) {
+	return 0x0102030405060708 ^ uint64(msr), nil
+}
+
+func txtAndMSRRegistersSample(t *testing.T) registers.Registers {
+	txtAPI := hwapi.GetPcMock(func(addr uint64) byte { return hwapi.MockPCReadMemory(addr) })
+	data, err := registers.FetchTXTConfigSpaceSafe(txtAPI)
+	if err != nil {
+		t.Fatalf("FetchTXTConfigSpaceSafe() failed: %v", err)
+	}
+	txtRegisters, err := registers.ReadTXTRegisters(data)
+	if err != nil {
+		t.Fatalf("ReadTXTRegisters() failed: %v", err)
+	}
+	msrRegisters, err := registers.ReadMSRRegisters(&msrReaderMock{})
+	if err != nil {
+		t.Fatalf("ReadMSRRegisters() failed: %v", err)
+	}
+	regs := append(txtRegisters, msrRegisters...)
+	for idx, reg := range regs {
+		if reg.ID() == registers.TXTPublicKeyRegisterID {
+			regs[idx] = registers.ParseTXTPublicKey([32]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31, 32})
+		}
+	}
+	regs.Sort()
+	return regs
+}
+
+func checkRoundTrip(t *testing.T, initialRegisters, resultRegisters registers.Registers) {
+	if len(initialRegisters) != len(resultRegisters) {
+		t.Fatalf("amount of registers differs: %d != %d", len(initialRegisters), len(resultRegisters))
+	}
+	for idx, reg := range initialRegisters {
+		initialValue, err := registers.ValueBytes(reg)
+		if err != nil {
+			t.Fatalf("failed to get the value of register %s: %v", reg.ID(), err)
+		}
+		resultValue, err := registers.ValueBytes(resultRegisters[idx])
+		if err != nil {
+			t.Fatalf("failed to get the value of register %s: %v", resultRegisters[idx].ID(), err)
+		}
+		if reg.ID() != resultRegisters[idx].ID() || !bytes.Equal(initialValue, resultValue) {
+			t.Errorf("register %s:%X is restored as %s:%X", reg.ID(), initialValue, resultRegisters[idx].ID(), resultValue)
+		}
+		restored, err := registers.ValueFromBytes(reg.ID(), resultValue)
+		if err != nil {
+			t.Fatalf("failed to unmarshal register %s: %v", reg.ID(), err)
+		}
+		if !reflect.DeepEqual(reg, restored) {
+			t.Errorf("initial register %v is not equal to restored %v", reg, restored)
+		}
+	}
+}
+
+func TestUnmarshalDump(t *testing.T) {
+	initialRegisters := txtAndMSRRegistersSample(t)
+
+	for format, marshal := range map[registers.DumpFormat]func(v any) ([]byte, error){
+		registers.DumpFormatYAML: yaml.Marshal,
+		registers.DumpFormatJSON: json.Marshal,
+	} {
+		t.Run(format.String(), func(t *testing.T) {
+			b, err := marshal(initialRegisters)
+			if err != nil {
+				t.Fatalf("failed to marshal registers: %v", err)
+			}
+			resultRegisters, detectedFormat, err := registers.UnmarshalDump(b)
+			if err != nil {
+				t.Fatalf("failed to unmarshal registers: %v", err)
+			}
+			if detectedFormat != format {
+				t.Errorf("detected format %s is not equal to %s", detectedFormat, format)
+			}
+			resultRegisters.Sort()
+			checkRoundTrip(t, initialRegisters, resultRegisters)
+		})
+	}
+
+	t.Run("txt-public-space", func(t *testing.T) {
+		txtAPI := hwapi.GetPcMock(func(addr uint64) byte { return hwapi.MockPCReadMemory(addr) })
+		data, err := registers.FetchTXTConfigSpaceSafe(txtAPI)
+		if err != nil {
+			t.Fatalf("FetchTXTConfigSpaceSafe() failed: %v", err)
+		}
+		regs, format, err := registers.UnmarshalDump(data)
+		if err != nil {
+			t.Fatalf("failed to unmarshal registers: %v", err)
+		}
+		if format != registers.DumpFormatTXTPublicSpace {
+			t.Errorf("detected format %s is not equal to %s", format, registers.DumpFormatTXTPublicSpace)
+		}
+		if regs.Find(registers.TXTStatusRegisterID) == nil {
+			t.Errorf("TXT.STS is not found in %v", regs)
+		}
+	})
+
+	_, _, err := registers.UnmarshalDump([]byte("not a dump"))
+	if err == nil {
+		t.Errorf("an invalid dump is expected to be an error")
+	}
+}
@@ -53,11 +53,51 @@ func (r MP0C2PMsg37) Address() uint64 {
 	return 0
 }
 
+// PlatformVendorID returns the platform vendor ID fused into the SoC
+func (r MP0C2PMsg37) PlatformVendorID() uint8 {
+	return uint8(r & 0xff)
+}
+
+// PlatformModelID returns the platform model ID fused into the SoC
+func (r MP0C2PMsg37) PlatformModelID() uint8 {
+	return uint8((r >> 8) & 0xf)
+}
+
+// BIOSKeyRevisionID returns the revision of the BIOS signing key fused into the SoC
+func (r MP0C2PMsg37) BIOSKeyRevisionID() uint8 {
+	return uint8((r >> 12) & 0xf)
+}
+
+// IsRootKeySelected specifies if the alternative root key is selected
+func (r MP0C2PMsg37) IsRootKeySelected() bool {
+	return (r>>16)&0x1 == 1
+}
+
 // IsPlatformSecureBootEnabled specifies if PSB is enabled and enforced
 func (r MP0C2PMsg37) IsPlatformSecureBootEnabled() bool {
 	return (r>>24)&0x1 == 1
 }
 
+// IsAntiRollbackEnabled specifies if the BIOS key anti-rollback is enabled
+func (r MP0C2PMsg37) IsAntiRollbackEnabled() bool {
+	return (r>>25)&0x1 == 1
+}
+
+// IsAMDKeyDisabled specifies if the usage of AMD signed BIOS is disabled
+func (r MP0C2PMsg37) IsAMDKeyDisabled() bool {
+	return (r>>26)&0x1 == 1
+}
+
+// IsSecureDebugDisabled specifies if unlocking of the secure debug is disabled
+func (r MP0C2PMsg37) IsSecureDebugDisabled() bool {
+	return (r>>27)&0x1 == 1
+}
+
+// IsCustomerKeyLocked specifies if the customer key has been fused
+func (r MP0C2PMsg37) IsCustomerKeyLocked() bool {
+	return (r>>28)&0x1 == 1
+}
+
 var _ RawRegister32 = ParseMP0C2PMsg37Register(0)
 
 // ParseMP0C2PMsg37Register returns MP0C2PMsg37 register from a raw 32bit value
@@ -45,6 +45,17 @@ func (r MP0C2PMsg38) Address() uint64 {
 	return 0
 }
 
+// PSBTestStatus returns the status code of the last Platform Secure Boot
+// validation, a non-zero value indicates an error
+func (r MP0C2PMsg38) PSBTestStatus() uint8 {
+	return uint8(r & 0xff)
+}
+
+// IsPSBFusingReady specifies if the platform is ready to fuse PSB
+func (r MP0C2PMsg38) IsPSBFusingReady() bool {
+	return (r>>8)&0x1 == 1
+}
+
 var _ RawRegister32 = ParseMP0C2PMsg38Register(0)
 
 // ParseMP0C2PMsg38Register returns MP0C2PMsg38 register from a raw 32bit value
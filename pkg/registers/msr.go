@@ -33,23 +33,33 @@ func readMSRFromCpu(msr int64, cpu int) (uint64, error) {
 	return msrCtx.Read(msr)
 }
 
-// Read reads a single MSR register, the returned value is not length, but
-// the value itself.
-func (r *DefaultMSRReader) Read(msr int64) (uint64, error) {
-	var data uint64
+// ReadMSRPerCore reads a single MSR register on every core of the local
+// host, the values are returned in the order of the cores.
+func ReadMSRPerCore(msr int64) ([]uint64, error) {
+	values := make([]uint64, 0, runtime.NumCPU())
 	for i := 0; i < runtime.NumCPU(); i++ {
 		msrData, err := readMSRFromCpu(msr, i)
 		if err != nil {
-			return 0, err
+			return nil, err
 		}
-		if i != 0 {
-			if data != msrData {
-				return 0, fmt.Errorf("MSR: cores of MSR 0x%x non equal", msr)
-			}
+		values = append(values, msrData)
+	}
+	return values, nil
+}
+
+// Read reads a single MSR register, the returned value is not length, but
+// the value itself.
+func (r *DefaultMSRReader) Read(msr int64) (uint64, error) {
+	values, err := ReadMSRPerCore(msr)
+	if err != nil {
+		return 0, err
+	}
+	for _, msrData := range values[1:] {
+		if msrData != values[0] {
+			return 0, fmt.Errorf("MSR: cores of MSR 0x%x non equal", msr)
 		}
-		data = msrData
 	}
-	return data, nil
+	return values[0], nil
 }
 
 type supportedMSRRegister struct {
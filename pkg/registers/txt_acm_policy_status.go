@@ -231,7 +231,7 @@ func (reg ACMPolicyStatus) BackupAction() BackupAction {
 
 func (reg ACMPolicyStatus) TXTProfileSelection() uint8 {
 	// bits 20-24
-	return uint8(reg >> 20)
+	return uint8((reg >> 20) & 0x1f)
 }
 
 func (reg ACMPolicyStatus) MemoryScrubbingPolicy() MemoryScrubbingPolicy {
@@ -6,14 +6,11 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/9elements/converged-security-suite/v2/pkg/acpi"
+	"github.com/9elements/converged-security-suite/v2/pkg/tools"
 	"github.com/9elements/go-linux-lowlevel-hw/pkg/hwapi"
 )
 
-// nolint
-func notImplemented(txtAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
-	return false, nil, fmt.Errorf("not implemented")
-}
-
 // nolint
 var (
 	testRSDPChecksum = Test{
@@ -29,7 +26,7 @@ var (
 		Name:                    "ACPI RSDT present",
 		Required:                true,
 		function:                CheckRSDTPresent,
-		Status:                  NotImplemented,
+		Status:                  Implemented,
 		SpecificationChapter:    "SINIT Class 0xC Major 2",
 		SpecificiationTitle:     ServerGrantleyPlatformSpecificationTitle,
 		SpecificationDocumentID: ServerGrantleyPlatformDocumentID,
@@ -118,8 +115,8 @@ var (
 	testRSDPValid = Test{
 		Name:                    "ACPI RSDP is valid",
 		Required:                true,
-		function:                notImplemented,
-		Status:                  NotImplemented,
+		function:                CheckRSDPStructureValid,
+		Status:                  Implemented,
 		SpecificationChapter:    "SINIT Class 0xC Major 8",
 		SpecificiationTitle:     ServerGrantleyPlatformSpecificationTitle,
 		SpecificationDocumentID: ServerGrantleyPlatformDocumentID,
@@ -128,8 +125,8 @@ var (
 	testTXTHeapSizeFitsMADTCopy = Test{
 		Name:                    "ACPI MADT copy fits into TXT heap",
 		Required:                true,
-		function:                notImplemented,
-		Status:                  NotImplemented,
+		function:                CheckTXTHeapFitsMADTCopy,
+		Status:                  Implemented,
 		SpecificationChapter:    "SINIT Class 9 Major 7 Minor 1",
 		SpecificiationTitle:     ServerGrantleyPlatformSpecificationTitle,
 		SpecificationDocumentID: ServerGrantleyPlatformDocumentID,
@@ -138,8 +135,8 @@ var (
 	testTXTHeapSizeFitsDynamicMadt = Test{
 		Name:                    "Dynamic ACPI MADT fits into TXT heap",
 		Required:                true,
-		function:                notImplemented,
-		Status:                  NotImplemented,
+		function:                CheckTXTHeapFitsDynamicMADT,
+		Status:                  Implemented,
 		SpecificationChapter:    "SINIT Class 9 Major 7 Minor 2",
 		SpecificiationTitle:     ServerGrantleyPlatformSpecificationTitle,
 		SpecificationDocumentID: ServerGrantleyPlatformDocumentID,
@@ -148,18 +145,18 @@ var (
 	testTXTHeapSizeFitsDMARCopy = Test{
 		Name:                    "ACPI DMAR copy fits into TXT heap",
 		Required:                true,
-		function:                notImplemented,
-		Status:                  NotImplemented,
+		function:                CheckTXTHeapFitsDMARCopy,
+		Status:                  Implemented,
 		SpecificationChapter:    "SINIT Class 9 Major 7 Minor 3",
 		SpecificiationTitle:     ServerGrantleyPlatformSpecificationTitle,
 		SpecificationDocumentID: ServerGrantleyPlatformDocumentID,
-		dependencies:            []*Test{&testRSDTorXSDTValid},
+		dependencies:            []*Test{&testDMARPresent},
 	}
 	testACPIRSDPInOSToSINITData = Test{
 		Name:                    "ACPI RSDP in 'OS to SINIT data' points to address below 4 GiB",
 		Required:                true,
-		function:                notImplemented,
-		Status:                  NotImplemented,
+		function:                CheckOSSINITDataRSDPBelowFourGiB,
+		Status:                  Implemented,
 		SpecificationChapter:    "SINIT Class 9 Major 0xc",
 		SpecificiationTitle:     ServerGrantleyPlatformSpecificationTitle,
 		SpecificationDocumentID: ServerGrantleyPlatformDocumentID,
@@ -167,129 +164,129 @@ var (
 	testACPIDMARValidHPET = Test{
 		Name:                    "ACPI DMAR table has valid HPET configuration",
 		Required:                true,
-		function:                notImplemented,
-		Status:                  NotImplemented,
+		function:                CheckDMARValidHPET,
+		Status:                  Implemented,
 		SpecificationChapter:    "SINIT Class 0xA Major 3 Minor 1",
 		SpecificiationTitle:     ServerGrantleyPlatformSpecificationTitle,
 		SpecificationDocumentID: ServerGrantleyPlatformDocumentID,
-		dependencies:            []*Test{&testRSDTorXSDTValid},
+		dependencies:            []*Test{&testDMARValid},
 	}
 	testACPIDMARValidBus = Test{
 		Name:                    "ACPI DMAR table has valid BUS configuration",
 		Required:                true,
-		function:                notImplemented,
-		Status:                  NotImplemented,
+		function:                CheckDMARValidBus,
+		Status:                  Implemented,
 		SpecificationChapter:    "SINIT Class 0xA Major 3 Minor 2",
 		SpecificiationTitle:     ServerGrantleyPlatformSpecificationTitle,
 		SpecificationDocumentID: ServerGrantleyPlatformDocumentID,
-		dependencies:            []*Test{&testRSDTorXSDTValid},
+		dependencies:            []*Test{&testDMARValid},
 	}
 	testACPIDMARValidAzalia = Test{
 		Name:                    "ACPI DMAR table Azalia device scope is valid",
 		Required:                true,
-		function:                notImplemented,
-		Status:                  NotImplemented,
+		function:                CheckDMARValidAzalia,
+		Status:                  Implemented,
 		SpecificationChapter:    "SINIT Class 0xA Major 3 Minor 3",
 		SpecificiationTitle:     ServerGrantleyPlatformSpecificationTitle,
 		SpecificationDocumentID: ServerGrantleyPlatformDocumentID,
-		dependencies:            []*Test{&testRSDTorXSDTValid},
+		dependencies:            []*Test{&testDMARValid},
 	}
 	testACPIDMARDeviceScopePresent = Test{
 		Name:                    "ACPI DMAR table device scope is present",
 		Required:                true,
-		function:                notImplemented,
-		Status:                  NotImplemented,
+		function:                CheckDMARDeviceScopePresent,
+		Status:                  Implemented,
 		SpecificationChapter:    "SINIT Class 0xA Major 3 Minor 4",
 		SpecificiationTitle:     ServerGrantleyPlatformSpecificationTitle,
 		SpecificationDocumentID: ServerGrantleyPlatformDocumentID,
-		dependencies:            []*Test{&testRSDTorXSDTValid},
+		dependencies:            []*Test{&testDMARValid},
 	}
 	testACPIDMARHPETScopeDuplicated = Test{
 		Name:                    "ACPI DMAR table has no duplicated HPET scope",
 		Required:                true,
-		function:                notImplemented,
-		Status:                  NotImplemented,
+		function:                CheckDMARHPETScopeNotDuplicated,
+		Status:                  Implemented,
 		SpecificationChapter:    "SINIT Class 0xA Major 3 Minor 5",
 		SpecificiationTitle:     ServerGrantleyPlatformSpecificationTitle,
 		SpecificationDocumentID: ServerGrantleyPlatformDocumentID,
-		dependencies:            []*Test{&testRSDTorXSDTValid},
+		dependencies:            []*Test{&testDMARValid},
 	}
 	testACPIDMARDrhdVtdDevice = Test{
-		Name:                    "ACPI DMAR table DRHD device ",
+		Name:                    "ACPI DMAR table DRHD device",
 		Required:                true,
-		function:                notImplemented,
-		Status:                  NotImplemented,
+		function:                CheckDMARDRHDDevice,
+		Status:                  Implemented,
 		SpecificationChapter:    "SINIT Class 0xA Major 3 Minor 6",
 		SpecificiationTitle:     ServerGrantleyPlatformSpecificationTitle,
 		SpecificationDocumentID: ServerGrantleyPlatformDocumentID,
-		dependencies:            []*Test{&testRSDTorXSDTValid},
+		dependencies:            []*Test{&testDMARValid},
 	}
 	testACPIDMARDrhdVtdScope = Test{
 		Name:                    "ACPI DMAR table DRHD device scope",
 		Required:                true,
-		function:                notImplemented,
-		Status:                  NotImplemented,
+		function:                CheckDMARDRHDScope,
+		Status:                  Implemented,
 		SpecificationChapter:    "SINIT Class 0xA Major 3 Minor 7",
 		SpecificiationTitle:     ServerGrantleyPlatformSpecificationTitle,
 		SpecificationDocumentID: ServerGrantleyPlatformDocumentID,
-		dependencies:            []*Test{&testRSDTorXSDTValid},
+		dependencies:            []*Test{&testDMARValid},
 	}
 	testACPIDMARDrhdPchApic = Test{
 		Name:                    "ACPI DMAR table DRHD PCH APIC present",
 		Required:                true,
-		function:                notImplemented,
-		Status:                  NotImplemented,
+		function:                CheckDMARDRHDPCHAPIC,
+		Status:                  Implemented,
 		SpecificationChapter:    "SINIT Class 0xA Major 3 Minor 8",
 		SpecificiationTitle:     ServerGrantleyPlatformSpecificationTitle,
 		SpecificationDocumentID: ServerGrantleyPlatformDocumentID,
-		dependencies:            []*Test{&testRSDTorXSDTValid},
+		dependencies:            []*Test{&testDMARValid},
 	}
 	testACPIDMARDrhdBaseaddressBelowFourGiB = Test{
 		Name:                    "ACPI DMAR table DRHD base address below 4 GiB",
 		Required:                true,
-		function:                notImplemented,
-		Status:                  NotImplemented,
+		function:                CheckDMARDRHDBaseBelowFourGiB,
+		Status:                  Implemented,
 		SpecificationChapter:    "SINIT Class 0xA Major 3 Minor 9",
 		SpecificiationTitle:     ServerGrantleyPlatformSpecificationTitle,
 		SpecificationDocumentID: ServerGrantleyPlatformDocumentID,
-		dependencies:            []*Test{&testRSDTorXSDTValid},
+		dependencies:            []*Test{&testDMARValid},
 	}
 	testACPIDMARDrhdTopaddressBelowFourGiB = Test{
 		Name:                    "ACPI DMAR table DRHD top address below 4 GiB",
 		Required:                true,
-		function:                notImplemented,
-		Status:                  NotImplemented,
+		function:                CheckDMARDRHDTopBelowFourGiB,
+		Status:                  Implemented,
 		SpecificationChapter:    "SINIT Class 0xA Major 3 Minor 0xa",
 		SpecificiationTitle:     ServerGrantleyPlatformSpecificationTitle,
 		SpecificationDocumentID: ServerGrantleyPlatformDocumentID,
-		dependencies:            []*Test{&testRSDTorXSDTValid},
+		dependencies:            []*Test{&testDMARValid},
 	}
 	testACPIDMARDrhdBadDevicescopeEntry = Test{
 		Name:                    "ACPI DMAR table DRHD device scope entries are valid",
 		Required:                true,
-		function:                notImplemented,
-		Status:                  NotImplemented,
+		function:                CheckDMARDeviceScopeEntries,
+		Status:                  Implemented,
 		SpecificationChapter:    "SINIT Class 0xA Major 3 Minor 0xb",
 		SpecificiationTitle:     ServerGrantleyPlatformSpecificationTitle,
 		SpecificationDocumentID: ServerGrantleyPlatformDocumentID,
-		dependencies:            []*Test{&testRSDTorXSDTValid},
+		dependencies:            []*Test{&testDMARValid},
 	}
 	testACPIDMARDrhdBadDevicescopeLength = Test{
 		Name:                    "ACPI DMAR table DRHD device scope length are valid",
 		Required:                true,
-		function:                notImplemented,
-		Status:                  NotImplemented,
+		function:                CheckDMARDeviceScopeLengths,
+		Status:                  Implemented,
 		SpecificationChapter:    "SINIT Class 0xA Major 3 Minor 0xc",
 		SpecificiationTitle:     ServerGrantleyPlatformSpecificationTitle,
 		SpecificationDocumentID: ServerGrantleyPlatformDocumentID,
-		dependencies:            []*Test{&testRSDTorXSDTValid},
+		dependencies:            []*Test{&testDMARValid},
 	}
 
 	testACPIPWRMBarBelowFourGib = Test{
 		Name:                    "ACPI PWRM BAR is below 4 GiB",
 		Required:                true,
-		function:                notImplemented,
-		Status:                  NotImplemented,
+		function:                CheckPWRMBARBelowFourGiB,
+		Status:                  Implemented,
 		SpecificationChapter:    "SINIT Class 0x35 Major 4",
 		SpecificiationTitle:     CBtGTXTPlatformSpecificationTitle,
 		SpecificationDocumentID: CBtGTXTPlatformDocumentID,
@@ -318,6 +315,24 @@ var (
 		&testXSDTPresent,
 		&testXSDTValid,
 		&testRSDTorXSDTValid,
+		&testRSDPValid,
+		&testTXTHeapSizeFitsMADTCopy,
+		&testTXTHeapSizeFitsDynamicMadt,
+		&testTXTHeapSizeFitsDMARCopy,
+		&testACPIRSDPInOSToSINITData,
+		&testACPIDMARValidHPET,
+		&testACPIDMARValidBus,
+		&testACPIDMARValidAzalia,
+		&testACPIDMARDeviceScopePresent,
+		&testACPIDMARHPETScopeDuplicated,
+		&testACPIDMARDrhdVtdDevice,
+		&testACPIDMARDrhdVtdScope,
+		&testACPIDMARDrhdPchApic,
+		&testACPIDMARDrhdBaseaddressBelowFourGiB,
+		&testACPIDMARDrhdTopaddressBelowFourGiB,
+		&testACPIDMARDrhdBadDevicescopeEntry,
+		&testACPIDMARDrhdBadDevicescopeLength,
+		&testACPIPWRMBarBelowFourGib,
 	}
 )
 
@@ -769,6 +784,464 @@ func CheckDMARValid(txtAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, e
 		return false, fmt.Errorf("ACPI table DMAR not valid"), nil
 	}
 
-	//FIXME: Additional checks here
+	_, err, interr = getDMAR(txtAPI)
+	if interr != nil {
+		return false, nil, interr
+	} else if err != nil {
+		return false, err, nil
+	}
+	return true, nil, nil
+}
+
+const (
+	// azaliaDevice and azaliaFunction are the PCI location of the Azalia
+	// (HD Audio) controller on bus 0
+	azaliaDevice   = 0x1b
+	azaliaFunction = 0
+
+	// pmcDevice and pmcFunction are the PCI location of the PCH Power
+	// Management Controller on bus 0
+	pmcDevice   = 0x1f
+	pmcFunction = 2
+	// pmcPWRMBaseOffset is the offset of PWRMBASE in the PCI config space
+	// of the PMC
+	pmcPWRMBaseOffset = 0x48
+)
+
+func getACPITable(txtAPI hwapi.LowLevelHardwareInterfaces, name string) ([]byte, error, error) {
+	table, err := hwapi.GetACPITableDevMem(txtAPI, name)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("ACPI table %s not found", name), nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+	return table, nil, nil
+}
+
+func getDMAR(txtAPI hwapi.LowLevelHardwareInterfaces) (*acpi.DMAR, error, error) {
+	table, err, interr := getACPITable(txtAPI, acpi.DMARSignature)
+	if err != nil || interr != nil {
+		return nil, err, interr
+	}
+	dmar, err := acpi.ParseDMAR(table)
+	if err != nil {
+		return nil, err, nil
+	}
+	return dmar, nil, nil
+}
+
+// checkDMAR runs a check on the parsed DMAR ACPI table
+func checkDMAR(txtAPI hwapi.LowLevelHardwareInterfaces, check func(dmar *acpi.DMAR) error) (bool, error, error) {
+	dmar, err, interr := getDMAR(txtAPI)
+	if interr != nil {
+		return false, nil, interr
+	} else if err != nil {
+		return false, err, nil
+	}
+	if err := check(dmar); err != nil {
+		return false, err, nil
+	}
+	return true, nil, nil
+}
+
+// checkTablesFitTXTHeap checks that the given ACPI tables fit into the
+// part of the TXT heap which is left for the SinitMleData table, where
+// SINIT copies them to.
+func checkTablesFitTXTHeap(heap []byte, tables ...[]byte) error {
+	txtHeap, err := tools.ParseTXTHeap(heap)
+	if err != nil {
+		return err
+	}
+	required := uint64(8) // the size field of SinitMleData
+	for _, table := range tables {
+		required += uint64(len(table))
+	}
+	if free := txtHeap.SinitMleDataSpace(); required > free {
+		return fmt.Errorf("TXT heap has %d bytes left, but %d bytes are required", free, required)
+	}
+	return nil
+}
+
+// osSinitDataRSDP returns the RSDP pointer passed to SINIT in the
+// OsSinitData table of the TXT heap
+func osSinitDataRSDP(heap []byte) (uint64, error) {
+	txtHeap, err := tools.ParseTXTHeap(heap)
+	if err != nil {
+		return 0, err
+	}
+	if txtHeap.OsSinitData == nil {
+		return 0, fmt.Errorf("TXT heap has no valid OsSinitData table")
+	}
+	// it is zero for versions before 5, then SINIT searches the legacy BIOS area
+	return txtHeap.OsSinitData.EFIRSDPPointer, nil
+}
+
+// decodePWRMBAR returns the base address of a memory BAR given the
+// values of its lower and upper double words
+func decodePWRMBAR(low, high uint32) uint64 {
+	base := uint64(low &^ 0xfff)
+	if (low>>1)&0x3 == 0x2 {
+		// 64-bit BAR
+		base |= uint64(high) << 32
+	}
+	return base
+}
+
+func checkRSDPStructure(raw []byte) error {
+	rsdp, err := acpi.ParseRSDP(raw)
+	if err != nil {
+		return err
+	}
+	if rsdp.RSDTAddress == 0 && rsdp.XSDTAddress == 0 {
+		return fmt.Errorf("RSDP points neither to RSDT nor to XSDT")
+	}
+	return nil
+}
+
+func checkDMARValidHPET(dmar *acpi.DMAR) error {
+	found := false
+	for _, scope := range dmar.DeviceScopes() {
+		if scope.Type != acpi.DMARDeviceScopeTypeHPET {
+			continue
+		}
+		if len(scope.Path) != 1 {
+			return fmt.Errorf("HPET device scope %d has a path of %d elements, expected 1", scope.EnumerationID, len(scope.Path))
+		}
+		found = true
+	}
+	if !found {
+		return fmt.Errorf("no HPET device scope found")
+	}
+	return nil
+}
+
+func checkDMARValidBus(dmar *acpi.DMAR, mcfg *acpi.MCFG) error {
+	for _, drhd := range dmar.DRHDs {
+		start, end, ok := mcfg.BusRange(drhd.Segment)
+		if !ok {
+			return fmt.Errorf("PCI segment %d of DRHD 0x%X is not described in MCFG", drhd.Segment, drhd.RegisterBaseAddress)
+		}
+		for _, scope := range drhd.DeviceScopes {
+			switch scope.Type {
+			case acpi.DMARDeviceScopeTypePCIEndpoint, acpi.DMARDeviceScopeTypePCISubHierarchy:
+			default:
+				continue
+			}
+			if scope.StartBusNumber < start || scope.StartBusNumber > end {
+				return fmt.Errorf("device scope start bus 0x%X of DRHD 0x%X is out of the bus range 0x%X-0x%X", scope.StartBusNumber, drhd.RegisterBaseAddress, start, end)
+			}
+		}
+	}
+	return nil
+}
+
+func checkDMARValidAzalia(dmar *acpi.DMAR) error {
+	for _, scope := range dmar.DeviceScopes() {
+		if scope.StartBusNumber != 0 || len(scope.Path) == 0 {
+			continue
+		}
+		if scope.Path[0].Device != azaliaDevice || scope.Path[0].Function != azaliaFunction {
+			continue
+		}
+		if scope.Type != acpi.DMARDeviceScopeTypePCIEndpoint || len(scope.Path) != 1 {
+			return fmt.Errorf("azalia device scope must be a PCI endpoint, but is %s with a path of %d elements", scope.Type, len(scope.Path))
+		}
+	}
+	return nil
+}
+
+func checkDMARDeviceScopePresent(dmar *acpi.DMAR) error {
+	for _, drhd := range dmar.DRHDs {
+		if !drhd.IncludePCIAll() && len(drhd.DeviceScopes) == 0 {
+			return fmt.Errorf("DRHD 0x%X has no device scope", drhd.RegisterBaseAddress)
+		}
+	}
+	return nil
+}
+
+func checkDMARHPETScopeNotDuplicated(dmar *acpi.DMAR) error {
+	hpets := map[uint8]bool{}
+	for _, scope := range dmar.DeviceScopes() {
+		if scope.Type != acpi.DMARDeviceScopeTypeHPET {
+			continue
+		}
+		if hpets[scope.EnumerationID] {
+			return fmt.Errorf("HPET device scope %d is duplicated", scope.EnumerationID)
+		}
+		hpets[scope.EnumerationID] = true
+	}
+	return nil
+}
+
+func checkDMARDRHDDevice(dmar *acpi.DMAR) error {
+	if len(dmar.DRHDs) == 0 {
+		return fmt.Errorf("no DRHD found")
+	}
+	includeAll := map[uint16]bool{}
+	for _, drhd := range dmar.DRHDs {
+		if drhd.RegisterBaseAddress == 0 || drhd.RegisterBaseAddress%4096 != 0 {
+			return fmt.Errorf("DRHD has invalid register base address 0x%X", drhd.RegisterBaseAddress)
+		}
+		if includeAll[drhd.Segment] {
+			return fmt.Errorf("DRHD 0x%X follows the INCLUDE_PCI_ALL DRHD of PCI segment %d", drhd.RegisterBaseAddress, drhd.Segment)
+		}
+		if drhd.IncludePCIAll() {
+			includeAll[drhd.Segment] = true
+		}
+	}
+	return nil
+}
+
+func checkDMARDRHDScope(dmar *acpi.DMAR) error {
+	for _, drhd := range dmar.DRHDs {
+		if !drhd.IncludePCIAll() {
+			continue
+		}
+		for _, scope := range drhd.DeviceScopes {
+			switch scope.Type {
+			case acpi.DMARDeviceScopeTypeIOAPIC, acpi.DMARDeviceScopeTypeHPET, acpi.DMARDeviceScopeTypeACPINamespaceDevice:
+			default:
+				return fmt.Errorf("INCLUDE_PCI_ALL DRHD 0x%X has a device scope of type %s", drhd.RegisterBaseAddress, scope.Type)
+			}
+		}
+	}
+	return nil
+}
+
+func checkDMARDRHDPCHAPIC(dmar *acpi.DMAR, ioapics []acpi.MADTIOAPIC) error {
+	ids := map[uint8]bool{}
+	for _, ioapic := range ioapics {
+		ids[ioapic.ID] = true
+	}
+	for _, scope := range dmar.DeviceScopes() {
+		if scope.Type == acpi.DMARDeviceScopeTypeIOAPIC && ids[scope.EnumerationID] {
+			return nil
+		}
+	}
+	return fmt.Errorf("no DRHD device scope for an I/O APIC of MADT found")
+}
+
+func checkDMARDRHDBaseBelowFourGiB(dmar *acpi.DMAR) error {
+	for _, drhd := range dmar.DRHDs {
+		if drhd.RegisterBaseAddress >= FourGiB {
+			return fmt.Errorf("DRHD base address 0x%X is above 4 GiB", drhd.RegisterBaseAddress)
+		}
+	}
+	return nil
+}
+
+func checkDMARDRHDTopBelowFourGiB(dmar *acpi.DMAR) error {
+	for _, drhd := range dmar.DRHDs {
+		if top := drhd.RegisterBaseAddress + drhd.RegisterSetSize(); top > FourGiB {
+			return fmt.Errorf("DRHD 0x%X top address 0x%X is above 4 GiB", drhd.RegisterBaseAddress, top-1)
+		}
+	}
+	return nil
+}
+
+func checkDMARDeviceScopeEntries(dmar *acpi.DMAR) error {
+	for _, scope := range dmar.DeviceScopes() {
+		if err := scope.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkDMARDeviceScopeLengths(dmar *acpi.DMAR) error {
+	for _, scope := range dmar.DeviceScopes() {
+		if err := scope.ValidateLength(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckRSDPStructureValid tests if the RSDP structure, including the extended checksum, is valid
+func CheckRSDPStructureValid(txtAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	raw, err, interr := getACPITable(txtAPI, "RSDP")
+	if err != nil || interr != nil {
+		return false, err, interr
+	}
+	if err := checkRSDPStructure(raw); err != nil {
+		return false, err, nil
+	}
+	return true, nil, nil
+}
+
+// CheckTXTHeapFitsMADTCopy tests if the TXT heap has enough space for the copy of MADT made by SINIT
+func CheckTXTHeapFitsMADTCopy(txtAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	madt, err, interr := getACPITable(txtAPI, acpi.MADTSignature)
+	if err != nil || interr != nil {
+		return false, err, interr
+	}
+	heap, err := tools.FetchTXTHeap(txtAPI)
+	if err != nil {
+		return false, nil, err
+	}
+	if err := checkTablesFitTXTHeap(heap, madt); err != nil {
+		return false, err, nil
+	}
+	return true, nil, nil
+}
+
+// CheckTXTHeapFitsDynamicMADT tests if the TXT heap has enough space for all the ACPI tables
+// SINIT copies dynamically (MADT and DMAR)
+func CheckTXTHeapFitsDynamicMADT(txtAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	madt, err, interr := getACPITable(txtAPI, acpi.MADTSignature)
+	if err != nil || interr != nil {
+		return false, err, interr
+	}
+	dmar, err, interr := getACPITable(txtAPI, acpi.DMARSignature)
+	if err != nil || interr != nil {
+		return false, err, interr
+	}
+	heap, err := tools.FetchTXTHeap(txtAPI)
+	if err != nil {
+		return false, nil, err
+	}
+	if err := checkTablesFitTXTHeap(heap, madt, dmar); err != nil {
+		return false, err, nil
+	}
+	return true, nil, nil
+}
+
+// CheckTXTHeapFitsDMARCopy tests if the TXT heap has enough space for the copy of DMAR made by SINIT
+func CheckTXTHeapFitsDMARCopy(txtAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	dmar, err, interr := getACPITable(txtAPI, acpi.DMARSignature)
+	if err != nil || interr != nil {
+		return false, err, interr
+	}
+	heap, err := tools.FetchTXTHeap(txtAPI)
+	if err != nil {
+		return false, nil, err
+	}
+	if err := checkTablesFitTXTHeap(heap, dmar); err != nil {
+		return false, err, nil
+	}
+	return true, nil, nil
+}
+
+// CheckOSSINITDataRSDPBelowFourGiB tests if the RSDP pointer in OsSinitData points below 4 GiB
+func CheckOSSINITDataRSDPBelowFourGiB(txtAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	heap, err := tools.FetchTXTHeap(txtAPI)
+	if err != nil {
+		return false, nil, err
+	}
+	rsdp, err := osSinitDataRSDP(heap)
+	if err != nil {
+		return false, err, nil
+	}
+	if rsdp >= FourGiB {
+		return false, fmt.Errorf("RSDP pointer 0x%X in OsSinitData is above 4 GiB", rsdp), nil
+	}
+	return true, nil, nil
+}
+
+// CheckDMARValidHPET tests if the DMAR ACPI table has a valid HPET device scope
+func CheckDMARValidHPET(txtAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	return checkDMAR(txtAPI, checkDMARValidHPET)
+}
+
+// CheckDMARValidBus tests if the DMAR ACPI table device scopes are within the bus range of MCFG
+func CheckDMARValidBus(txtAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	table, err, interr := getACPITable(txtAPI, acpi.MCFGSignature)
+	if err != nil || interr != nil {
+		return false, err, interr
+	}
+	mcfg, err := acpi.ParseMCFG(table)
+	if err != nil {
+		return false, err, nil
+	}
+	return checkDMAR(txtAPI, func(dmar *acpi.DMAR) error {
+		return checkDMARValidBus(dmar, mcfg)
+	})
+}
+
+// CheckDMARValidAzalia tests if the DMAR ACPI table Azalia device scope is valid
+func CheckDMARValidAzalia(txtAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	return checkDMAR(txtAPI, checkDMARValidAzalia)
+}
+
+// CheckDMARDeviceScopePresent tests if every DRHD without INCLUDE_PCI_ALL flag has a device scope
+func CheckDMARDeviceScopePresent(txtAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	return checkDMAR(txtAPI, checkDMARDeviceScopePresent)
+}
+
+// CheckDMARHPETScopeNotDuplicated tests if the DMAR ACPI table reports every HPET only once
+func CheckDMARHPETScopeNotDuplicated(txtAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	return checkDMAR(txtAPI, checkDMARHPETScopeNotDuplicated)
+}
+
+// CheckDMARDRHDDevice tests if the DRHDs have valid register base addresses and order
+func CheckDMARDRHDDevice(txtAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	return checkDMAR(txtAPI, checkDMARDRHDDevice)
+}
+
+// CheckDMARDRHDScope tests if INCLUDE_PCI_ALL DRHDs have only IOAPIC, HPET and ACPI namespace device scopes
+func CheckDMARDRHDScope(txtAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	return checkDMAR(txtAPI, checkDMARDRHDScope)
+}
+
+// CheckDMARDRHDPCHAPIC tests if an I/O APIC of MADT is covered by a DRHD
+func CheckDMARDRHDPCHAPIC(txtAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	table, err, interr := getACPITable(txtAPI, acpi.MADTSignature)
+	if err != nil || interr != nil {
+		return false, err, interr
+	}
+	madt, err := acpi.ParseMADT(table)
+	if err != nil {
+		return false, err, nil
+	}
+	ioapics, err := madt.IOAPICs()
+	if err != nil {
+		return false, err, nil
+	}
+	return checkDMAR(txtAPI, func(dmar *acpi.DMAR) error {
+		return checkDMARDRHDPCHAPIC(dmar, ioapics)
+	})
+}
+
+// CheckDMARDRHDBaseBelowFourGiB tests if the DRHD register base addresses are below 4 GiB
+func CheckDMARDRHDBaseBelowFourGiB(txtAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	return checkDMAR(txtAPI, checkDMARDRHDBaseBelowFourGiB)
+}
+
+// CheckDMARDRHDTopBelowFourGiB tests if the DRHD register sets end below 4 GiB
+func CheckDMARDRHDTopBelowFourGiB(txtAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	return checkDMAR(txtAPI, checkDMARDRHDTopBelowFourGiB)
+}
+
+// CheckDMARDeviceScopeEntries tests if the DRHD device scope entries are valid
+func CheckDMARDeviceScopeEntries(txtAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	return checkDMAR(txtAPI, checkDMARDeviceScopeEntries)
+}
+
+// CheckDMARDeviceScopeLengths tests if the DRHD device scope lengths are valid
+func CheckDMARDeviceScopeLengths(txtAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	return checkDMAR(txtAPI, checkDMARDeviceScopeLengths)
+}
+
+// CheckPWRMBARBelowFourGiB tests if the PWRM BAR of the PMC is below 4 GiB
+func CheckPWRMBARBelowFourGiB(txtAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	pmc := hwapi.PCIDevice{Device: pmcDevice, Function: pmcFunction}
+	vid, err := txtAPI.PCIReadVendorID(pmc)
+	if err != nil {
+		return false, nil, err
+	}
+	if vid == 0xffff {
+		return false, nil, fmt.Errorf("PMC device is not visible")
+	}
+	low, err := txtAPI.PCIReadConfig32(pmc, pmcPWRMBaseOffset)
+	if err != nil {
+		return false, nil, err
+	}
+	high, err := txtAPI.PCIReadConfig32(pmc, pmcPWRMBaseOffset+4)
+	if err != nil {
+		return false, nil, err
+	}
+	if base := decodePWRMBAR(low, high); base >= FourGiB {
+		return false, fmt.Errorf("PWRM BAR 0x%X is above 4 GiB", base), nil
+	}
 	return true, nil, nil
 }
@@ -0,0 +1,238 @@
+package test
+
+import (
+	"encoding/binary"
+	"os"
+	"testing"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/acpi"
+	"github.com/stretchr/testify/require"
+)
+
+const acpiTestDataPath = "../../testdata/acpi/"
+
+func readTestDMAR(t *testing.T) *acpi.DMAR {
+	b, err := os.ReadFile(acpiTestDataPath + "synthetic_DMAR.bin")
+	require.NoError(t, err)
+	dmar, err := acpi.ParseDMAR(b)
+	require.NoError(t, err)
+	return dmar
+}
+
+func TestDMARChecks(t *testing.T) {
+	for name, check := range map[string]func(*acpi.DMAR) error{
+		"HPET":               checkDMARValidHPET,
+		"Azalia":             checkDMARValidAzalia,
+		"DeviceScopePresent": checkDMARDeviceScopePresent,
+		"HPETNotDuplicated":  checkDMARHPETScopeNotDuplicated,
+		"DRHDDevice":         checkDMARDRHDDevice,
+		"DRHDScope":          checkDMARDRHDScope,
+		"BaseBelowFourGiB":   checkDMARDRHDBaseBelowFourGiB,
+		"TopBelowFourGiB":    checkDMARDRHDTopBelowFourGiB,
+		"DeviceScopeEntries": checkDMARDeviceScopeEntries,
+		"DeviceScopeLengths": checkDMARDeviceScopeLengths,
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, check(readTestDMAR(t)))
+		})
+	}
+}
+
+func TestDMARValidHPET(t *testing.T) {
+	dmar := readTestDMAR(t)
+	dmar.DRHDs[1].DeviceScopes = dmar.DRHDs[1].DeviceScopes[:1]
+	require.Error(t, checkDMARValidHPET(dmar))
+
+	dmar = readTestDMAR(t)
+	dmar.DRHDs[1].DeviceScopes[1].Path = append(dmar.DRHDs[1].DeviceScopes[1].Path, acpi.DMARPCIPath{})
+	require.Error(t, checkDMARValidHPET(dmar))
+}
+
+func TestDMARValidBus(t *testing.T) {
+	b, err := os.ReadFile(acpiTestDataPath + "firecracker_MCFG.bin")
+	require.NoError(t, err)
+	mcfg, err := acpi.ParseMCFG(b)
+	require.NoError(t, err)
+
+	dmar := readTestDMAR(t)
+	require.NoError(t, checkDMARValidBus(dmar, mcfg))
+
+	dmar.DRHDs[0].DeviceScopes[0].StartBusNumber = 1
+	require.Error(t, checkDMARValidBus(dmar, mcfg))
+
+	dmar = readTestDMAR(t)
+	dmar.DRHDs[0].Segment = 1
+	require.Error(t, checkDMARValidBus(dmar, mcfg))
+}
+
+func TestDMARValidAzalia(t *testing.T) {
+	dmar := readTestDMAR(t)
+	dmar.DRHDs[0].DeviceScopes[0].Path[0] = acpi.DMARPCIPath{Device: azaliaDevice, Function: azaliaFunction}
+	require.NoError(t, checkDMARValidAzalia(dmar))
+
+	dmar.DRHDs[0].DeviceScopes[0].Type = acpi.DMARDeviceScopeTypePCISubHierarchy
+	require.Error(t, checkDMARValidAzalia(dmar))
+}
+
+func TestDMARDeviceScopePresent(t *testing.T) {
+	dmar := readTestDMAR(t)
+	dmar.DRHDs[1].DeviceScopes = nil
+	require.NoError(t, checkDMARDeviceScopePresent(dmar))
+
+	dmar.DRHDs[0].DeviceScopes = nil
+	require.Error(t, checkDMARDeviceScopePresent(dmar))
+}
+
+func TestDMARHPETScopeNotDuplicated(t *testing.T) {
+	dmar := readTestDMAR(t)
+	hpet := dmar.DRHDs[1].DeviceScopes[1]
+	dmar.DRHDs[1].DeviceScopes = append(dmar.DRHDs[1].DeviceScopes, hpet)
+	require.Error(t, checkDMARHPETScopeNotDuplicated(dmar))
+
+	dmar.DRHDs[1].DeviceScopes[2].EnumerationID++
+	require.NoError(t, checkDMARHPETScopeNotDuplicated(dmar))
+}
+
+func TestDMARDRHDDevice(t *testing.T) {
+	dmar := readTestDMAR(t)
+	dmar.DRHDs[0], dmar.DRHDs[1] = dmar.DRHDs[1], dmar.DRHDs[0]
+	require.Error(t, checkDMARDRHDDevice(dmar))
+
+	dmar = readTestDMAR(t)
+	dmar.DRHDs[0].RegisterBaseAddress |= 0x10
+	require.Error(t, checkDMARDRHDDevice(dmar))
+
+	dmar.DRHDs = nil
+	require.Error(t, checkDMARDRHDDevice(dmar))
+}
+
+func TestDMARDRHDScope(t *testing.T) {
+	dmar := readTestDMAR(t)
+	dmar.DRHDs[1].DeviceScopes = append(dmar.DRHDs[1].DeviceScopes, dmar.DRHDs[0].DeviceScopes[0])
+	require.Error(t, checkDMARDRHDScope(dmar))
+}
+
+func TestDMARDRHDPCHAPIC(t *testing.T) {
+	b, err := os.ReadFile(acpiTestDataPath + "firecracker_APIC.bin")
+	require.NoError(t, err)
+	madt, err := acpi.ParseMADT(b)
+	require.NoError(t, err)
+	ioapics, err := madt.IOAPICs()
+	require.NoError(t, err)
+
+	dmar := readTestDMAR(t)
+	require.NoError(t, checkDMARDRHDPCHAPIC(dmar, ioapics))
+
+	dmar.DRHDs[1].DeviceScopes[0].EnumerationID = 8
+	require.Error(t, checkDMARDRHDPCHAPIC(dmar, ioapics))
+}
+
+func TestDMARDRHDBelowFourGiB(t *testing.T) {
+	dmar := readTestDMAR(t)
+	dmar.DRHDs[0].RegisterBaseAddress = FourGiB - 4096
+	require.NoError(t, checkDMARDRHDBaseBelowFourGiB(dmar))
+	require.NoError(t, checkDMARDRHDTopBelowFourGiB(dmar))
+
+	dmar.DRHDs[0].Size = 1
+	require.NoError(t, checkDMARDRHDBaseBelowFourGiB(dmar))
+	require.Error(t, checkDMARDRHDTopBelowFourGiB(dmar))
+
+	dmar.DRHDs[0].RegisterBaseAddress = FourGiB
+	require.Error(t, checkDMARDRHDBaseBelowFourGiB(dmar))
+}
+
+func TestDMARDeviceScopeEntriesAndLengths(t *testing.T) {
+	dmar := readTestDMAR(t)
+	dmar.DRHDs[0].DeviceScopes[0].Type = 0
+	require.Error(t, checkDMARDeviceScopeEntries(dmar))
+	require.NoError(t, checkDMARDeviceScopeLengths(dmar))
+
+	dmar = readTestDMAR(t)
+	dmar.DRHDs[0].DeviceScopes[0].Length = 9
+	require.NoError(t, checkDMARDeviceScopeEntries(dmar))
+	require.Error(t, checkDMARDeviceScopeLengths(dmar))
+}
+
+func TestRSDPStructure(t *testing.T) {
+	rsdp := make([]byte, 36)
+	copy(rsdp, "RSD PTR ")
+	rsdp[15] = 2
+	binary.LittleEndian.PutUint32(rsdp[20:], 36)
+	setChecksums := func() {
+		rsdp[8], rsdp[32] = 0, 0
+		var sum uint8
+		for _, b := range rsdp[:20] {
+			sum += b
+		}
+		rsdp[8] = -sum
+		sum = 0
+		for _, b := range rsdp {
+			sum += b
+		}
+		rsdp[32] = -sum
+	}
+	setChecksums()
+	require.Error(t, checkRSDPStructure(rsdp))
+
+	binary.LittleEndian.PutUint64(rsdp[24:], 0x7FFE1000)
+	setChecksums()
+	require.NoError(t, checkRSDPStructure(rsdp))
+
+	rsdp[32]++
+	require.Error(t, checkRSDPStructure(rsdp))
+}
+
+// osSinitDataRSDPOffset is the offset of the EFI RSDP pointer in the
+// OsSinitData table (version 5 and later), excluding the size field
+const osSinitDataRSDPOffset = 84
+
+// buildTXTHeap returns a TXT heap of the given size with regions of the given sizes
+func buildTXTHeap(size int, regionSizes ...uint64) []byte {
+	heap := make([]byte, size)
+	cur := uint64(0)
+	for _, regionSize := range regionSizes {
+		binary.LittleEndian.PutUint64(heap[cur:], regionSize)
+		cur += regionSize
+	}
+	return heap
+}
+
+func TestTablesFitTXTHeap(t *testing.T) {
+	madt, err := os.ReadFile(acpiTestDataPath + "firecracker_APIC.bin")
+	require.NoError(t, err)
+	dmar, err := os.ReadFile(acpiTestDataPath + "synthetic_DMAR.bin")
+	require.NoError(t, err)
+
+	heap := buildTXTHeap(0x1000, 0x100, 0x100, 0x100)
+	require.NoError(t, checkTablesFitTXTHeap(heap, madt, dmar))
+
+	heap = buildTXTHeap(0x380, 0x100, 0x100, 0x100)
+	require.NoError(t, checkTablesFitTXTHeap(heap, madt))
+	require.Error(t, checkTablesFitTXTHeap(heap, madt, dmar))
+
+	require.Error(t, checkTablesFitTXTHeap(make([]byte, 0x400), madt))
+}
+
+func TestOSSINITDataRSDP(t *testing.T) {
+	heap := buildTXTHeap(0x1000, 0x100, 0x100, 0x100)
+	osSinitData := heap[0x200+8:]
+
+	binary.LittleEndian.PutUint32(osSinitData, 4)
+	rsdp, err := osSinitDataRSDP(heap)
+	require.NoError(t, err)
+	require.Zero(t, rsdp)
+
+	binary.LittleEndian.PutUint32(osSinitData, 6)
+	binary.LittleEndian.PutUint64(osSinitData[osSinitDataRSDPOffset:], 0x7FFE0000)
+	rsdp, err = osSinitDataRSDP(heap)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0x7FFE0000), rsdp)
+
+	_, err = osSinitDataRSDP(buildTXTHeap(0x1000, 0x100))
+	require.Error(t, err)
+}
+
+func TestDecodePWRMBAR(t *testing.T) {
+	require.Equal(t, uint64(0xFE000000), decodePWRMBAR(0xFE000000, 0x1))
+	require.Equal(t, uint64(0x1FE000000), decodePWRMBAR(0xFE000004, 0x1))
+}
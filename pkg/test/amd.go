@@ -0,0 +1,555 @@
+package test
+
+import (
+	"fmt"
+	"strings"
+
+	hwInternal "github.com/9elements/converged-security-suite/v2/pkg/hwapi"
+	"github.com/9elements/converged-security-suite/v2/pkg/registers"
+	"github.com/9elements/go-linux-lowlevel-hw/pkg/hwapi"
+)
+
+const (
+	//AMD64ArchitectureSpecificationTitle the title of AMD64 Architecture Programmer's Manual Volume 2
+	AMD64ArchitectureSpecificationTitle = "AMD64 Architecture Programmer's Manual Volume 2: System Programming"
+	//AMD64ArchitectureSpecificationDocumentID the document ID of AMD64 Architecture Programmer's Manual Volume 2
+	AMD64ArchitectureSpecificationDocumentID = "24593"
+
+	cpuidExtendedMaxLeaf  = 0x80000000
+	cpuidAMDMemEncryption = 0x8000001f
+
+	msrAMD64SYSCFG = 0xC0010010
+)
+
+type psbRegisters struct {
+	msg37 registers.MP0C2PMsg37
+	msg38 registers.MP0C2PMsg38
+}
+
+// nolint
+var (
+	testcheckforamdcpu = Test{
+		Name:     "AMD CPU family and model",
+		Required: true,
+		function: CheckForAMDCPU,
+		Status:   Implemented,
+	}
+	testpsbstatuszero = Test{
+		Name:         "PSB status register contains zero",
+		Required:     true,
+		function:     PSBStatusZero,
+		dependencies: []*Test{&testcheckforamdcpu},
+		Status:       Implemented,
+	}
+	testpsbenabled = Test{
+		Name:         "PSB enabled",
+		Required:     true,
+		function:     PSBEnabled,
+		dependencies: []*Test{&testcheckforamdcpu},
+		Status:       Implemented,
+	}
+	testpsbvendorid = Test{
+		Name:         "PSB platform vendor ID is not zero",
+		Required:     true,
+		function:     PSBPlatformVendorID,
+		dependencies: []*Test{&testpsbenabled},
+		Status:       Implemented,
+	}
+	testpsbmodelid = Test{
+		Name:         "PSB platform model ID is not zero",
+		Required:     true,
+		function:     PSBPlatformModelID,
+		dependencies: []*Test{&testpsbenabled},
+		Status:       Implemented,
+	}
+	testpsbbioskeyrevision = Test{
+		Name:         "PSB BIOS key revision is not zero",
+		Required:     true,
+		function:     PSBBIOSKeyRevision,
+		dependencies: []*Test{&testpsbenabled},
+		Status:       Implemented,
+	}
+	testpsbamdkeydisabled = Test{
+		Name:         "PSB AMD key is disabled",
+		Required:     true,
+		function:     PSBAMDKeyDisabled,
+		dependencies: []*Test{&testpsbenabled},
+		Status:       Implemented,
+	}
+	testpsbsecuredebugdisabled = Test{
+		Name:         "PSB secure debug is disabled",
+		Required:     true,
+		function:     PSBSecureDebugDisabled,
+		dependencies: []*Test{&testpsbenabled},
+		Status:       Implemented,
+	}
+	testpsbkeysfused = Test{
+		Name:         "PSB customer keys are fused",
+		Required:     true,
+		function:     PSBKeysFused,
+		dependencies: []*Test{&testpsbenabled},
+		Status:       Implemented,
+	}
+	testpsbpolicyhash = Test{
+		Name:         "PSB policy hash",
+		Required:     true,
+		function:     PSBPolicyHash,
+		dependencies: []*Test{&testpsbenabled},
+		Status:       NotImplemented,
+	}
+	testpsbrevocationstatus = Test{
+		Name:         "PSB revocation status",
+		Required:     true,
+		function:     PSBRevocationStatus,
+		dependencies: []*Test{&testpsbenabled},
+		Status:       NotImplemented,
+	}
+	testsmesupported = Test{
+		Name:                    "SME supported",
+		Required:                false,
+		function:                SMESupported,
+		dependencies:            []*Test{&testcheckforamdcpu},
+		Status:                  Implemented,
+		SpecificationChapter:    "7.10 Secure Memory Encryption",
+		SpecificiationTitle:     AMD64ArchitectureSpecificationTitle,
+		SpecificationDocumentID: AMD64ArchitectureSpecificationDocumentID,
+	}
+	testsmeenabled = Test{
+		Name:                    "SME enabled",
+		Required:                false,
+		function:                SMEEnabled,
+		dependencies:            []*Test{&testsmesupported},
+		Status:                  Implemented,
+		SpecificationChapter:    "7.10 Secure Memory Encryption",
+		SpecificiationTitle:     AMD64ArchitectureSpecificationTitle,
+		SpecificationDocumentID: AMD64ArchitectureSpecificationDocumentID,
+	}
+	testsmekerneloption = Test{
+		Name:         "SME kernel option set",
+		Required:     false,
+		function:     SMEKernelOption,
+		dependencies: []*Test{&testsmesupported},
+		Status:       NotImplemented,
+	}
+	testsmekernelcmdline = Test{
+		Name:         "SME kernel commandline",
+		Required:     false,
+		function:     SMEKernelCmdline,
+		dependencies: []*Test{&testsmesupported},
+		Status:       Implemented,
+	}
+	testsmefunctionality = Test{
+		Name:         "SME functionality",
+		Required:     false,
+		function:     SMEFunctionality,
+		dependencies: []*Test{&testsmeenabled},
+		Status:       NotImplemented,
+	}
+	testsevsupported = Test{
+		Name:                    "SEV supported",
+		Required:                false,
+		function:                SEVSupported,
+		dependencies:            []*Test{&testcheckforamdcpu},
+		Status:                  Implemented,
+		SpecificationChapter:    "15.34 Secure Encrypted Virtualization",
+		SpecificiationTitle:     AMD64ArchitectureSpecificationTitle,
+		SpecificationDocumentID: AMD64ArchitectureSpecificationDocumentID,
+	}
+	testsevenabled = Test{
+		Name:                    "SEV enabled",
+		Required:                false,
+		function:                SEVEnabled,
+		dependencies:            []*Test{&testsevsupported},
+		Status:                  Implemented,
+		SpecificationChapter:    "15.34 Secure Encrypted Virtualization",
+		SpecificiationTitle:     AMD64ArchitectureSpecificationTitle,
+		SpecificationDocumentID: AMD64ArchitectureSpecificationDocumentID,
+	}
+	testsevfirmwareversion = Test{
+		Name:         "SEV firmware version",
+		Required:     false,
+		function:     SEVFirmwareVersion,
+		dependencies: []*Test{&testsevenabled},
+		Status:       NotImplemented,
+	}
+	testsevguestconfig = Test{
+		Name:         "SEV guest configuration",
+		Required:     false,
+		function:     SEVGuestConfig,
+		dependencies: []*Test{&testsevenabled},
+		Status:       NotImplemented,
+	}
+	testsnpsupported = Test{
+		Name:                    "SEV-SNP supported",
+		Required:                false,
+		function:                SNPSupported,
+		dependencies:            []*Test{&testcheckforamdcpu},
+		Status:                  Implemented,
+		SpecificationChapter:    "15.36 Secure Nested Paging (SEV-SNP)",
+		SpecificiationTitle:     AMD64ArchitectureSpecificationTitle,
+		SpecificationDocumentID: AMD64ArchitectureSpecificationDocumentID,
+	}
+	testsnpenabled = Test{
+		Name:                    "SEV-SNP enabled",
+		Required:                false,
+		function:                SNPEnabled,
+		dependencies:            []*Test{&testsnpsupported},
+		Status:                  Implemented,
+		SpecificationChapter:    "15.36 Secure Nested Paging (SEV-SNP)",
+		SpecificiationTitle:     AMD64ArchitectureSpecificationTitle,
+		SpecificationDocumentID: AMD64ArchitectureSpecificationDocumentID,
+	}
+	testsnpdebugregisters = Test{
+		Name:         "SEV-SNP debug registers disabled",
+		Required:     false,
+		function:     SNPDebugRegistersDisabled,
+		dependencies: []*Test{&testsnpenabled},
+		Status:       NotImplemented,
+	}
+	testsnpsidechannelprotection = Test{
+		Name:                    "SEV-SNP side-channel protection enabled",
+		Required:                false,
+		function:                SNPSideChannelProtection,
+		dependencies:            []*Test{&testsnpenabled},
+		Status:                  NotImplemented,
+		SpecificationChapter:    "15.36.17 Side-Channel Protection",
+		SpecificiationTitle:     AMD64ArchitectureSpecificationTitle,
+		SpecificationDocumentID: AMD64ArchitectureSpecificationDocumentID,
+	}
+	testsnpfirmwareversion = Test{
+		Name:         "SEV-SNP firmware version",
+		Required:     false,
+		function:     SNPFirmwareVersion,
+		dependencies: []*Test{&testsnpenabled},
+		Status:       NotImplemented,
+	}
+	testsnpmeasurement = Test{
+		Name:         "SEV-SNP protected VM boot measurement",
+		Required:     false,
+		function:     SNPMeasurement,
+		dependencies: []*Test{&testsnpenabled},
+		Status:       NotImplemented,
+	}
+	testsnpattestation = Test{
+		Name:         "SEV-SNP attestation reporting",
+		Required:     false,
+		function:     SNPAttestation,
+		dependencies: []*Test{&testsnpenabled},
+		Status:       NotImplemented,
+	}
+
+	// TestsAMD exports slice with AMD PSB, SME, SEV and SEV-SNP related tests
+	TestsAMD = [...]*Test{
+		&testcheckforamdcpu,
+		&testpsbstatuszero,
+		&testpsbenabled,
+		&testpsbvendorid,
+		&testpsbmodelid,
+		&testpsbbioskeyrevision,
+		&testpsbamdkeydisabled,
+		&testpsbsecuredebugdisabled,
+		&testpsbkeysfused,
+		&testpsbpolicyhash,
+		&testpsbrevocationstatus,
+		&testsmesupported,
+		&testsmeenabled,
+		&testsmekerneloption,
+		&testsmekernelcmdline,
+		&testsmefunctionality,
+		&testsevsupported,
+		&testsevenabled,
+		&testsevfirmwareversion,
+		&testsevguestconfig,
+		&testsnpsupported,
+		&testsnpenabled,
+		&testsnpdebugregisters,
+		&testsnpsidechannelprotection,
+		&testsnpfirmwareversion,
+		&testsnpmeasurement,
+		&testsnpattestation,
+	}
+)
+
+// getPSBRegisters reads the PSB status registers. They are not cached,
+// so each run of a test gets the current values.
+func getPSBRegisters(amdAPI hwapi.LowLevelHardwareInterfaces) (*psbRegisters, error) {
+	msg37, msg38, err := hwInternal.ReadPSBRegisters(amdAPI)
+	if err != nil {
+		return nil, err
+	}
+	return &psbRegisters{msg37: msg37, msg38: msg38}, nil
+}
+
+// amdFamily returns the CPU family including the extended family
+func amdFamily(amdAPI hwapi.LowLevelHardwareInterfaces) uint32 {
+	signature := amdAPI.CPUSignature()
+	family := (signature >> 8) & 0xf
+	if family == 0xf {
+		family += (signature >> 20) & 0xff
+	}
+	return family
+}
+
+// amdModel returns the CPU model including the extended model
+func amdModel(amdAPI hwapi.LowLevelHardwareInterfaces) uint32 {
+	signature := amdAPI.CPUSignature()
+	return ((signature >> 12) & 0xf0) | ((signature >> 4) & 0xf)
+}
+
+// amdMemEncryptionFeatures returns EAX of CPUID Fn8000_001F, or zero
+// if the leaf is not supported
+func amdMemEncryptionFeatures(amdAPI hwapi.LowLevelHardwareInterfaces) uint32 {
+	maxLeaf, _, _, _ := amdAPI.CPUID(cpuidExtendedMaxLeaf, 0)
+	if maxLeaf < cpuidAMDMemEncryption {
+		return 0
+	}
+	eax, _, _, _ := amdAPI.CPUID(cpuidAMDMemEncryption, 0)
+	return eax
+}
+
+// CheckForAMDCPU Check we're running on a AMD CPU of a family supporting PSB
+func CheckForAMDCPU(amdAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	if amdAPI.VersionString() != "AuthenticAMD" {
+		return false, fmt.Errorf("no AMD CPU detected"), nil
+	}
+	switch family := amdFamily(amdAPI); family {
+	case 0x17, 0x19, 0x1a:
+		return true, nil, nil
+	default:
+		return false, fmt.Errorf("unsupported AMD CPU family 0x%X model 0x%X", family, amdModel(amdAPI)), nil
+	}
+}
+
+// PSBStatusZero Check that the PSB status contains no error
+func PSBStatusZero(amdAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	regs, err := getPSBRegisters(amdAPI)
+	if err != nil {
+		return false, nil, err
+	}
+	if status := regs.msg38.PSBTestStatus(); status != 0 {
+		return false, fmt.Errorf("PSB status reports error 0x%02X", status), nil
+	}
+	return true, nil, nil
+}
+
+// PSBEnabled Check that Platform Secure Boot is fused and enforced
+func PSBEnabled(amdAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	regs, err := getPSBRegisters(amdAPI)
+	if err != nil {
+		return false, nil, err
+	}
+	if !regs.msg37.IsPlatformSecureBootEnabled() {
+		return false, fmt.Errorf("PSB is not enabled"), nil
+	}
+	return true, nil, nil
+}
+
+// PSBPlatformVendorID Check that the platform vendor ID is fused
+func PSBPlatformVendorID(amdAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	regs, err := getPSBRegisters(amdAPI)
+	if err != nil {
+		return false, nil, err
+	}
+	if regs.msg37.PlatformVendorID() == 0 {
+		return false, fmt.Errorf("platform vendor ID is zero"), nil
+	}
+	return true, nil, nil
+}
+
+// PSBPlatformModelID Check that the platform model ID is fused
+func PSBPlatformModelID(amdAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	regs, err := getPSBRegisters(amdAPI)
+	if err != nil {
+		return false, nil, err
+	}
+	if regs.msg37.PlatformModelID() == 0 {
+		return false, fmt.Errorf("platform model ID is zero"), nil
+	}
+	return true, nil, nil
+}
+
+// PSBBIOSKeyRevision Check that the BIOS key revision is fused
+func PSBBIOSKeyRevision(amdAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	regs, err := getPSBRegisters(amdAPI)
+	if err != nil {
+		return false, nil, err
+	}
+	if regs.msg37.BIOSKeyRevisionID() == 0 {
+		return false, fmt.Errorf("BIOS key revision is zero"), nil
+	}
+	return true, nil, nil
+}
+
+// PSBAMDKeyDisabled Check that AMD signed BIOS images are not accepted
+func PSBAMDKeyDisabled(amdAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	regs, err := getPSBRegisters(amdAPI)
+	if err != nil {
+		return false, nil, err
+	}
+	if !regs.msg37.IsAMDKeyDisabled() {
+		return false, fmt.Errorf("AMD key is not disabled, AMD signed firmware will still boot"), nil
+	}
+	return true, nil, nil
+}
+
+// PSBSecureDebugDisabled Check that unlocking the secure debug is disabled
+func PSBSecureDebugDisabled(amdAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	regs, err := getPSBRegisters(amdAPI)
+	if err != nil {
+		return false, nil, err
+	}
+	if !regs.msg37.IsSecureDebugDisabled() {
+		return false, fmt.Errorf("secure debug unlock is not disabled"), nil
+	}
+	return true, nil, nil
+}
+
+// PSBKeysFused Check that the customer keys are fused
+func PSBKeysFused(amdAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	regs, err := getPSBRegisters(amdAPI)
+	if err != nil {
+		return false, nil, err
+	}
+	if !regs.msg37.IsCustomerKeyLocked() {
+		return false, fmt.Errorf("customer key lock is not set"), nil
+	}
+	return true, nil, nil
+}
+
+// PSBPolicyHash Check the PSB policy hash
+func PSBPolicyHash(amdAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	return false, nil, fmt.Errorf("unimplemented: no comment")
+}
+
+// PSBRevocationStatus Check the PSB revocation status
+func PSBRevocationStatus(amdAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	return false, nil, fmt.Errorf("unimplemented: no comment")
+}
+
+// SMESupported Check that the CPU supports Secure Memory Encryption
+func SMESupported(amdAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	if amdMemEncryptionFeatures(amdAPI)&(1<<0) == 0 {
+		return false, fmt.Errorf("CPU does not support SME"), nil
+	}
+	return true, nil, nil
+}
+
+// SMEEnabled Check that Secure Memory Encryption is enabled in SYSCFG
+func SMEEnabled(amdAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	syscfg, err := amdAPI.ReadMSRAllCores(msrAMD64SYSCFG)
+	if err != nil {
+		return false, nil, err
+	}
+	// MemEncryptionModEn
+	if syscfg&(1<<23) == 0 {
+		return false, fmt.Errorf("SME is not enabled in SYSCFG"), nil
+	}
+	return true, nil, nil
+}
+
+// SMEKernelOption Check that the kernel was built with SME support
+func SMEKernelOption(amdAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	return false, nil, fmt.Errorf("unimplemented: no comment")
+}
+
+// SMEKernelCmdline Check that SME is activated on the kernel commandline
+func SMEKernelCmdline(amdAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	cmdline, err := hwInternal.KernelCmdline(amdAPI)
+	if err != nil {
+		return false, nil, err
+	}
+	for _, option := range strings.Fields(cmdline) {
+		if option == "mem_encrypt=on" {
+			return true, nil, nil
+		}
+	}
+	return false, fmt.Errorf("mem_encrypt=on is not set on the kernel commandline"), nil
+}
+
+// SMEFunctionality Check that memory pages are marked for encryption
+func SMEFunctionality(amdAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	return false, nil, fmt.Errorf("unimplemented: no comment")
+}
+
+// SEVSupported Check that the CPU supports Secure Encrypted Virtualization
+func SEVSupported(amdAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	if amdMemEncryptionFeatures(amdAPI)&(1<<1) == 0 {
+		return false, fmt.Errorf("CPU does not support SEV"), nil
+	}
+	return true, nil, nil
+}
+
+// SEVEnabled Check that Secure Encrypted Virtualization is enabled on the host:
+// memory encryption is enabled in SYSCFG and the firmware reserved ASIDs
+// for encrypted guests (CPUID Fn8000_001F ECX)
+func SEVEnabled(amdAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	syscfg, err := amdAPI.ReadMSRAllCores(msrAMD64SYSCFG)
+	if err != nil {
+		return false, nil, err
+	}
+	// MemEncryptionModEn
+	if syscfg&(1<<23) == 0 {
+		return false, fmt.Errorf("memory encryption is not enabled in SYSCFG"), nil
+	}
+	_, _, encryptedGuests, _ := amdAPI.CPUID(cpuidAMDMemEncryption, 0)
+	if encryptedGuests == 0 {
+		return false, fmt.Errorf("no ASIDs are available for SEV guests"), nil
+	}
+	return true, nil, nil
+}
+
+// SEVFirmwareVersion Check the SEV firmware version
+func SEVFirmwareVersion(amdAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	return false, nil, fmt.Errorf("unimplemented: no comment")
+}
+
+// SEVGuestConfig Check the SEV guest configuration
+func SEVGuestConfig(amdAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	return false, nil, fmt.Errorf("unimplemented: no comment")
+}
+
+// SNPSupported Check that the CPU supports SEV-SNP
+func SNPSupported(amdAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	if amdMemEncryptionFeatures(amdAPI)&(1<<4) == 0 {
+		return false, fmt.Errorf("CPU does not support SEV-SNP"), nil
+	}
+	return true, nil, nil
+}
+
+// SNPEnabled Check that SEV-SNP is enabled in SYSCFG
+func SNPEnabled(amdAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	syscfg, err := amdAPI.ReadMSRAllCores(msrAMD64SYSCFG)
+	if err != nil {
+		return false, nil, err
+	}
+	// SNPEn
+	if syscfg&(1<<24) == 0 {
+		return false, fmt.Errorf("SEV-SNP is not enabled in SYSCFG"), nil
+	}
+	return true, nil, nil
+}
+
+// SNPDebugRegistersDisabled Check that the CPU debug registers are disabled through SEV_FEATURES
+func SNPDebugRegistersDisabled(amdAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	return false, nil, fmt.Errorf("unimplemented: no comment")
+}
+
+// SNPSideChannelProtection Check that the side-channel protection is enabled
+func SNPSideChannelProtection(amdAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	return false, nil, fmt.Errorf("unimplemented: no comment")
+}
+
+// SNPFirmwareVersion Check the SEV-SNP firmware version
+func SNPFirmwareVersion(amdAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	return false, nil, fmt.Errorf("unimplemented: no comment")
+}
+
+// SNPMeasurement Check the measurement of a SNP protected VM boot
+func SNPMeasurement(amdAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	return false, nil, fmt.Errorf("unimplemented: no comment")
+}
+
+// SNPAttestation Check the SEV-SNP attestation reports
+func SNPAttestation(amdAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	return false, nil, fmt.Errorf("unimplemented: no comment")
+}
@@ -0,0 +1,139 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+
+	hwInternal "github.com/9elements/converged-security-suite/v2/pkg/hwapi"
+)
+
+const (
+	// family 0x19, model 0x01 (Milan)
+	testAMDCPUSignature = 0x00A00F11
+	// vendor 0x5A, model 0x1, key revision 0x1, PSB enabled, AMD key
+	// disabled, secure debug disabled, customer key locked
+	testPSBStatus = uint32(0x5A | 0x1<<8 | 0x1<<12 | 1<<24 | 1<<26 | 1<<27 | 1<<28)
+	// SME, SEV, SEV-ES and SEV-SNP
+	testAMDMemEncryptionFeatures = uint32(1<<0 | 1<<1 | 1<<3 | 1<<4)
+	// MemEncryptionModEn and SNPEn
+	testAMDSYSCFG = uint64(1<<23 | 1<<24)
+)
+
+// amdMock declares an AMD CPU with PSB fused and SME, SEV and SEV-SNP
+// enabled. The test cases modify it to trigger failures.
+func amdMock() *hwInternal.MockBuilder {
+	return hwInternal.NewMockBuilder().
+		WithVendor("AuthenticAMD").
+		WithCPUSignature(testAMDCPUSignature).
+		WithCPUID(cpuidExtendedMaxLeaf, 0, cpuidAMDMemEncryption, 0, 0, 0).
+		WithCPUID(cpuidAMDMemEncryption, 0, testAMDMemEncryptionFeatures, 0x33, 509, 100).
+		WithMSR(msrAMD64SYSCFG, testAMDSYSCFG).
+		WithSMN(hwInternal.AMDSMNMP0C2PMsg37, testPSBStatus).
+		WithSMN(hwInternal.AMDSMNMP0C2PMsg38, 0)
+}
+
+func TestCheckForAMDCPU(t *testing.T) {
+	runHWTestCases(t, &testcheckforamdcpu, []hwTestCase{
+		{name: "Milan", hw: amdMock(), result: ResultPass},
+		{name: "Genoa", hw: amdMock().WithCPUSignature(0x00A10F11), result: ResultPass},
+		{name: "Intel", hw: amdMock().WithVendor("GenuineIntel"), result: ResultFail},
+		{name: "OldFamily", hw: amdMock().WithCPUSignature(0x00600F20), result: ResultFail},
+	})
+}
+
+func TestPSB(t *testing.T) {
+	for _, tc := range []struct {
+		test *Test
+		bit  uint32
+	}{
+		{test: &testpsbenabled, bit: 1 << 24},
+		{test: &testpsbamdkeydisabled, bit: 1 << 26},
+		{test: &testpsbsecuredebugdisabled, bit: 1 << 27},
+		{test: &testpsbkeysfused, bit: 1 << 28},
+		{test: &testpsbvendorid, bit: 0xFF},
+		{test: &testpsbmodelid, bit: 0xF << 8},
+		{test: &testpsbbioskeyrevision, bit: 0xF << 12},
+	} {
+		t.Run(tc.test.Name, func(t *testing.T) {
+			runHWTestCases(t, tc.test, []hwTestCase{
+				{name: "Set", hw: amdMock(), result: ResultPass},
+				{
+					name:   "NotSet",
+					hw:     amdMock().WithSMN(hwInternal.AMDSMNMP0C2PMsg37, testPSBStatus&^tc.bit),
+					result: ResultFail,
+				},
+				{name: "NotReadable", hw: hwInternal.NewMockBuilder(), result: ResultInternalError},
+			})
+		})
+	}
+
+	runHWTestCases(t, &testpsbstatuszero, []hwTestCase{
+		{name: "NoError", hw: amdMock(), result: ResultPass},
+		{name: "Error", hw: amdMock().WithSMN(hwInternal.AMDSMNMP0C2PMsg38, 0x3E), result: ResultFail},
+		{name: "NotReadable", hw: hwInternal.NewMockBuilder(), result: ResultInternalError},
+	})
+
+	// the registers are not cached between the runs
+	runHWTestCases(t, &testpsbenabled, []hwTestCase{
+		{name: "Disabled", hw: amdMock().WithSMN(hwInternal.AMDSMNMP0C2PMsg37, 0), result: ResultFail},
+		{name: "Enabled", hw: amdMock(), result: ResultPass},
+	})
+}
+
+func TestMemoryEncryptionSupported(t *testing.T) {
+	for _, tc := range []struct {
+		test *Test
+		bit  uint32
+	}{
+		{test: &testsmesupported, bit: 1 << 0},
+		{test: &testsevsupported, bit: 1 << 1},
+		{test: &testsnpsupported, bit: 1 << 4},
+	} {
+		t.Run(tc.test.Name, func(t *testing.T) {
+			runHWTestCases(t, tc.test, []hwTestCase{
+				{name: "Supported", hw: amdMock(), result: ResultPass},
+				{
+					name:   "NotSupported",
+					hw:     amdMock().WithCPUID(cpuidAMDMemEncryption, 0, testAMDMemEncryptionFeatures&^tc.bit, 0x33, 509, 100),
+					result: ResultFail,
+				},
+				{
+					name:   "NoLeaf",
+					hw:     amdMock().WithCPUID(cpuidExtendedMaxLeaf, 0, 0x80000008, 0, 0, 0),
+					result: ResultFail,
+				},
+			})
+		})
+	}
+}
+
+func TestMemoryEncryptionEnabled(t *testing.T) {
+	runHWTestCases(t, &testsmeenabled, []hwTestCase{
+		{name: "Enabled", hw: amdMock(), result: ResultPass},
+		{name: "Disabled", hw: amdMock().WithMSR(msrAMD64SYSCFG, testAMDSYSCFG&^(1<<23)), result: ResultFail},
+		{name: "NotReadable", hw: amdMock().WithMSRError(msrAMD64SYSCFG, fmt.Errorf("no msr module")), result: ResultInternalError},
+	})
+	runHWTestCases(t, &testsevenabled, []hwTestCase{
+		{name: "Enabled", hw: amdMock(), result: ResultPass},
+		{name: "MemEncryptionDisabled", hw: amdMock().WithMSR(msrAMD64SYSCFG, testAMDSYSCFG&^(1<<23)), result: ResultFail},
+		{
+			name:   "NoASIDs",
+			hw:     amdMock().WithCPUID(cpuidAMDMemEncryption, 0, testAMDMemEncryptionFeatures, 0x33, 0, 0),
+			result: ResultFail,
+		},
+		{name: "NotReadable", hw: amdMock().WithMSRError(msrAMD64SYSCFG, fmt.Errorf("no msr module")), result: ResultInternalError},
+	})
+	runHWTestCases(t, &testsnpenabled, []hwTestCase{
+		{name: "Enabled", hw: amdMock(), result: ResultPass},
+		{name: "Disabled", hw: amdMock().WithMSR(msrAMD64SYSCFG, testAMDSYSCFG&^(1<<24)), result: ResultFail},
+		{name: "DifferentPerCore", hw: amdMock().WithMSR(msrAMD64SYSCFG, testAMDSYSCFG, 0), result: ResultInternalError},
+	})
+}
+
+func TestSMEKernelCmdline(t *testing.T) {
+	runHWTestCases(t, &testsmekernelcmdline, []hwTestCase{
+		{name: "Enabled", hw: amdMock().WithKernelCmdline("root=/dev/sda1 mem_encrypt=on quiet"), result: ResultPass},
+		{name: "Disabled", hw: amdMock().WithKernelCmdline("root=/dev/sda1 mem_encrypt=off"), result: ResultFail},
+		{name: "NotRecorded", hw: amdMock(), result: ResultInternalError},
+	})
+}
@@ -0,0 +1,221 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/provisioning/bootguard"
+	"github.com/9elements/converged-security-suite/v2/pkg/registers"
+	"github.com/9elements/converged-security-suite/v2/pkg/tools"
+	"github.com/9elements/go-linux-lowlevel-hw/pkg/hwapi"
+	"github.com/linuxboot/fiano/pkg/intel/metadata/cbnt"
+	bootpolicy "github.com/linuxboot/fiano/pkg/intel/metadata/cbnt/bootpolicy"
+	"github.com/linuxboot/fiano/pkg/intel/metadata/fit"
+)
+
+var (
+	testcbntbpmhastxtelement = Test{
+		Name:                    "BPM contains a TXT element",
+		Description:             "Parses the CBnT BPM from FIT and checks it has a TXT element.",
+		Required:                true,
+		function:                CBnTBPMHasTXTElement,
+		dependencies:            []*Test{&testbootguardfit},
+		Status:                  Implemented,
+		SpecificationChapter:    "",
+		SpecificiationTitle:     IntelBootGuardSpecificationTitle,
+		SpecificationDocumentID: IntelBootGuardSpecificationDocumentID,
+	}
+	testcbnttxtcontrolflags = Test{
+		Name:                    "[RUNTIME] TXT element control flags match ACM policy status",
+		Description:             "Compares execution profile, memory scrubbing and backup action of the BPM TXT element with ACM_POLICY_STATUS.",
+		Required:                true,
+		function:                CBnTTXTControlFlagsMatchACMPolicyStatus,
+		dependencies:            []*Test{&testcbntbpmhastxtelement},
+		Status:                  Implemented,
+		SpecificationChapter:    "",
+		SpecificiationTitle:     IntelBootGuardSpecificationTitle,
+		SpecificationDocumentID: IntelBootGuardSpecificationDocumentID,
+	}
+	testcbntsinitminsvn = Test{
+		Name:                    "[RUNTIME] SINIT ACM meets BPM and PS index SinitMinSvn",
+		Description:             "Checks the SVN of the SINIT ACM in TXT memory is not below SinitMinSvnAuth of the BPM and SINITMinVersion of the PS index LCP.",
+		Required:                true,
+		function:                CBnTSINITMinSVN,
+		dependencies:            []*Test{&testcbntbpmhastxtelement},
+		Status:                  Implemented,
+		SpecificationChapter:    "D.1.3 LCP_POLICY2",
+		SpecificiationTitle:     IntelTXTSpecificationTitle,
+		SpecificationDocumentID: IntelTXTSpecificationDocumentID,
+	}
+	testcbntpowerdowninterval = Test{
+		Name:                    "[RUNTIME] TXT element PowerDownInterval matches backup action",
+		Description:             "Checks PowerDownInterval of the BPM TXT element is set if ACM_POLICY_STATUS reports memory power down as backup action.",
+		Required:                true,
+		function:                CBnTPowerDownInterval,
+		dependencies:            []*Test{&testcbntbpmhastxtelement},
+		Status:                  Implemented,
+		SpecificationChapter:    "",
+		SpecificiationTitle:     IntelBootGuardSpecificationTitle,
+		SpecificationDocumentID: IntelBootGuardSpecificationDocumentID,
+	}
+
+	// TestsCBnT exposes the slice for CBnT TXT element related tests
+	TestsCBnT = [...]*Test{
+		&testcbntbpmhastxtelement,
+		&testcbnttxtcontrolflags,
+		&testcbntsinitminsvn,
+		&testcbntpowerdowninterval,
+	}
+)
+
+// cbntTXTElement returns the TXT element of the CBnT BPM in firmware
+func cbntTXTElement(p *PreSet) (*bootpolicy.TXT, error) {
+	entries, err := fit.GetEntries(p.Firmware)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse FIT: %w", err)
+	}
+	var bpmReader *bytes.Reader
+	for _, entry := range entries {
+		switch entry := entry.(type) {
+		case *fit.EntryBootPolicyManifestRecord:
+			bpmReader = bytes.NewReader(entry.DataSegmentBytes)
+		}
+	}
+	if bpmReader == nil {
+		return nil, fmt.Errorf("couldn't find BPM in FIT")
+	}
+	b, err := bootguard.NewBPM(bpmReader)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse BPM: %w", err)
+	}
+	if b.Version != cbnt.Version20 && b.Version != cbnt.Version21 {
+		return nil, fmt.Errorf("BPM is not a CBnT manifest")
+	}
+	if b.VData.CBNTbpm.TXTE == nil {
+		return nil, fmt.Errorf("BPM has no TXT element")
+	}
+	return b.VData.CBNTbpm.TXTE, nil
+}
+
+func readACMPolicyStatus(txtAPI hwapi.LowLevelHardwareInterfaces) (registers.ACMPolicyStatus, error) {
+	buf, err := tools.FetchTXTRegs(txtAPI)
+	if err != nil {
+		return 0, err
+	}
+	return registers.ReadACMPolicyStatusRegister(buf)
+}
+
+// CBnTBPMHasTXTElement checks the CBnT BPM has a TXT element
+func CBnTBPMHasTXTElement(txtAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	if _, err := cbntTXTElement(p); err != nil {
+		return false, err, nil
+	}
+	return true, nil, nil
+}
+
+// CBnTTXTControlFlagsMatchACMPolicyStatus checks the TXT element control flags were applied by the S-ACM
+func CBnTTXTControlFlagsMatchACMPolicyStatus(txtAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	txt, err := cbntTXTElement(p)
+	if err != nil {
+		return false, err, nil
+	}
+	status, err := readACMPolicyStatus(txtAPI)
+	if err != nil {
+		return false, nil, err
+	}
+	if err := checkTXTControlFlags(txt.ControlFlags, status); err != nil {
+		return false, err, nil
+	}
+	return true, nil, nil
+}
+
+// CBnTSINITMinSVN checks the SINIT ACM isn't older than allowed by BPM and PS index
+func CBnTSINITMinSVN(txtAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	txt, err := cbntTXTElement(p)
+	if err != nil {
+		return false, err, nil
+	}
+	regs, err := getTxtRegisters(txtAPI)
+	if err != nil {
+		return false, nil, err
+	}
+	acm, err := sinitACM(txtAPI, *regs)
+	if err != nil {
+		return false, err, nil
+	}
+	pol1, pol2, err := readPSLCPPolicy(txtAPI)
+	if err != nil {
+		return false, nil, err
+	}
+	var psMinSVN uint8
+	if pol1 != nil {
+		psMinSVN = pol1.SINITMinVersion
+	}
+	if pol2 != nil {
+		psMinSVN = pol2.SINITMinVersion
+	}
+	if err := checkSINITMinSVN(uint16(acm.Header.GetTXTSVN()), txt.SInitMinSVNAuth, psMinSVN); err != nil {
+		return false, err, nil
+	}
+	return true, nil, nil
+}
+
+// CBnTPowerDownInterval checks PowerDownInterval is usable for the backup action in effect
+func CBnTPowerDownInterval(txtAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
+	txt, err := cbntTXTElement(p)
+	if err != nil {
+		return false, err, nil
+	}
+	status, err := readACMPolicyStatus(txtAPI)
+	if err != nil {
+		return false, nil, err
+	}
+	if err := checkPowerDownInterval(txt.PwrDownInterval, status); err != nil {
+		return false, err, nil
+	}
+	return true, nil, nil
+}
+
+func checkTXTControlFlags(flags bootpolicy.TXTControlFlags, status registers.ACMPolicyStatus) error {
+	if !status.BootPolicyT() {
+		return fmt.Errorf("ACM policy status doesn't indicate TXT support")
+	}
+	if profile := uint8(flags.ExecutionProfile()); profile != 0 && profile != status.TXTProfileSelection() {
+		return fmt.Errorf("TXT execution profile %d of BPM doesn't match profile %d in ACM policy status", profile, status.TXTProfileSelection())
+	}
+	if policy := flags.MemoryScrubbingPolicy(); policy != bootpolicy.MemoryScrubbingPolicyDefault &&
+		uint8(policy) != uint8(status.MemoryScrubbingPolicy()) {
+		return fmt.Errorf("memory scrubbing policy %d of BPM doesn't match policy %d in ACM policy status", policy, status.MemoryScrubbingPolicy())
+	}
+	switch flags.BackupActionPolicy() {
+	case bootpolicy.BackupActionPolicyForceMemoryPowerDown:
+		if status.BackupAction() != registers.BackupActionMemoryPowerDown {
+			return fmt.Errorf("BPM forces memory power down but ACM policy status reports backup action %d", status.BackupAction())
+		}
+	case bootpolicy.BackupActionPolicyForceBtGUnbreakableShutdown:
+		if status.BackupAction() != registers.BackupActionBtGUnbreakableShutdown {
+			return fmt.Errorf("BPM forces BtG unbreakable shutdown but ACM policy status reports backup action %d", status.BackupAction())
+		}
+	}
+	if flags.IsSACMRequestedToExtendStaticPCRs() && !status.TPMSuccess() {
+		return fmt.Errorf("BPM requests S-ACM to extend static PCRs but ACM policy status reports no TPM success")
+	}
+	return nil
+}
+
+func checkSINITMinSVN(sinitSVN uint16, bpmMinSVN, psMinSVN uint8) error {
+	if sinitSVN < uint16(bpmMinSVN) {
+		return fmt.Errorf("SINIT ACM SVN %d is below SinitMinSvnAuth %d of BPM", sinitSVN, bpmMinSVN)
+	}
+	if sinitSVN < uint16(psMinSVN) {
+		return fmt.Errorf("SINIT ACM SVN %d is below SINITMinVersion %d of PS index", sinitSVN, psMinSVN)
+	}
+	return nil
+}
+
+func checkPowerDownInterval(interval bootpolicy.Duration16In5Sec, status registers.ACMPolicyStatus) error {
+	if status.BackupAction() == registers.BackupActionMemoryPowerDown && interval == 0 {
+		return fmt.Errorf("ACM policy status reports memory power down as backup action but BPM PowerDownInterval is zero")
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/registers"
+	bootpolicy "github.com/linuxboot/fiano/pkg/intel/metadata/cbnt/bootpolicy"
+	"github.com/stretchr/testify/require"
+)
+
+// TXT supported, TPM success, execution profile 1 and S-ACM memory scrubbing
+const testACMPolicyStatus = registers.ACMPolicyStatus(1<<7 | 1<<15 | 1<<20 | 2<<25)
+
+func TestTXTControlFlags(t *testing.T) {
+	// execution profile 1, S-ACM memory scrubbing, forced memory power down
+	flags := bootpolicy.TXTControlFlags(0x1 | 0x2<<5 | 0x1<<7)
+	require.NoError(t, checkTXTControlFlags(flags, testACMPolicyStatus))
+	require.NoError(t, checkTXTControlFlags(0, testACMPolicyStatus))
+	require.Error(t, checkTXTControlFlags(flags, testACMPolicyStatus&^(1<<7)))
+
+	for name, flags := range map[string]bootpolicy.TXTControlFlags{
+		"ExecutionProfile":      0x2,
+		"MemoryScrubbingPolicy": 0x1 << 5,
+		"BackupActionPolicy":    0x2 << 7,
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.Error(t, checkTXTControlFlags(flags, testACMPolicyStatus))
+		})
+	}
+}
+
+func TestSINITMinSVN(t *testing.T) {
+	require.NoError(t, checkSINITMinSVN(3, 3, 2))
+	require.NoError(t, checkSINITMinSVN(3, 0, 0))
+	require.Error(t, checkSINITMinSVN(3, 4, 0))
+	require.Error(t, checkSINITMinSVN(3, 2, 4))
+}
+
+func TestPowerDownInterval(t *testing.T) {
+	require.NoError(t, checkPowerDownInterval(12, testACMPolicyStatus))
+	require.Error(t, checkPowerDownInterval(0, testACMPolicyStatus))
+	require.NoError(t, checkPowerDownInterval(0, testACMPolicyStatus|registers.ACMPolicyStatus(registers.BackupActionBtGUnbreakableShutdown)<<18))
+}
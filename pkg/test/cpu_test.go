@@ -0,0 +1,145 @@
+package test
+
+import (
+	"errors"
+	"testing"
+
+	hwInternal "github.com/9elements/converged-security-suite/v2/pkg/hwapi"
+)
+
+const (
+	msrIA32FeatureControl = 0x3A
+	msrIA32DebugInterface = 0xC80
+)
+
+func TestCheckForIntelCPU(t *testing.T) {
+	runHWTestCases(t, &testcheckforintelcpu, []hwTestCase{
+		{name: "Intel", hw: hwInternal.NewMockBuilder().WithVendor("GenuineIntel"), result: ResultPass},
+		{name: "AMD", hw: hwInternal.NewMockBuilder().WithVendor("AuthenticAMD"), result: ResultFail},
+	})
+}
+
+func TestWeybridgeOrLater(t *testing.T) {
+	runHWTestCases(t, &testwaybridgeorlater, []hwTestCase{
+		{name: "CoffeeLake", hw: hwInternal.NewMockBuilder().WithCPUSignature(0x906EA), result: ResultPass},
+		{name: "NetBurst", hw: hwInternal.NewMockBuilder().WithCPUSignature(0xF29), result: ResultFail},
+	})
+}
+
+func TestCPUSupportsTXT(t *testing.T) {
+	runHWTestCases(t, &testcpusupportstxt, []hwTestCase{
+		{
+			name:   "Whitelisted",
+			hw:     hwInternal.NewMockBuilder().WithProcessorBrandName("Intel(R) Xeon(R) W-2195 CPU @ 2.30GHz"),
+			result: ResultPass,
+		},
+		{
+			name:   "Blacklisted",
+			hw:     hwInternal.NewMockBuilder().WithProcessorBrandName("Intel(R) Celeron(R) CPU 330 @ 2.66GHz"),
+			result: ResultFail,
+		},
+		{
+			name:   "NotListed",
+			hw:     hwInternal.NewMockBuilder().WithProcessorBrandName("QEMU Virtual CPU version 2.5+"),
+			result: ResultFail,
+		},
+	})
+}
+
+func TestTXTRegisterSpaceAccessible(t *testing.T) {
+	runHWTestCases(t, &testtxtregisterspaceaccessible, []hwTestCase{
+		{name: "Accessible", hw: txtMock(), result: ResultPass},
+		{
+			name:   "UnexpectedVendorID",
+			hw:     txtMock().WithPhysMemoryValue(txtRegister(0x110), uint32(0xFFFFFFFF)),
+			result: ResultFail,
+		},
+		{
+			name:   "NoPublicKey",
+			hw:     txtMock().WithPhysMemoryValue(txtRegister(0x400), uint64(0xFFFFFFFFFFFFFFFF)),
+			result: ResultFail,
+		},
+		{
+			name:   "NoDeviceID",
+			hw:     txtMock().WithPhysMemoryValue(txtRegister(0x110), uint32(0x8086)),
+			result: ResultFail,
+		},
+		{name: "NotReadable", hw: hwInternal.NewMockBuilder(), result: ResultInternalError},
+	})
+}
+
+func TestSupportsSMXAndVMX(t *testing.T) {
+	runHWTestCases(t, &testsupportssmx, []hwTestCase{
+		{name: "SMX", hw: hwInternal.NewMockBuilder().WithCPUID(1, 0, 0, 0, 1<<6, 0), result: ResultPass},
+		{name: "NoSMX", hw: hwInternal.NewMockBuilder().WithCPUID(1, 0, 0, 0, 1<<5, 0), result: ResultFail},
+	})
+	runHWTestCases(t, &testsupportvmx, []hwTestCase{
+		{name: "VMX", hw: hwInternal.NewMockBuilder().WithCPUID(1, 0, 0, 0, 1<<5, 0), result: ResultPass},
+		{name: "NoVMX", hw: hwInternal.NewMockBuilder().WithCPUID(1, 0, 0, 0, 1<<6, 0), result: ResultFail},
+	})
+}
+
+func TestIa32FeatureCtrl(t *testing.T) {
+	runHWTestCases(t, &testia32featurectrl, []hwTestCase{
+		{name: "Locked", hw: hwInternal.NewMockBuilder().WithMSR(msrIA32FeatureControl, 0xFF67), result: ResultPass},
+		{name: "NotLocked", hw: hwInternal.NewMockBuilder().WithMSR(msrIA32FeatureControl, 0xFF66), result: ResultFail},
+		{
+			name:   "DiffersAmongCores",
+			hw:     hwInternal.NewMockBuilder().WithMSR(msrIA32FeatureControl, 0xFF67, 0xFF67, 0xFF66),
+			result: ResultInternalError,
+		},
+		{name: "NotReadable", hw: hwInternal.NewMockBuilder(), result: ResultInternalError},
+	})
+}
+
+func TestTXTNotDisabled(t *testing.T) {
+	runHWTestCases(t, &testtxtnotdisabled, []hwTestCase{
+		{name: "SENTEREnabled", hw: hwInternal.NewMockBuilder().WithMSR(msrIA32FeatureControl, 0xFF07), result: ResultPass},
+		{name: "Disabled", hw: hwInternal.NewMockBuilder().WithMSR(msrIA32FeatureControl, 0x7), result: ResultFail},
+		{
+			name:   "NotReadable",
+			hw:     hwInternal.NewMockBuilder().WithMSRError(msrIA32FeatureControl, errors.New("no such device")),
+			result: ResultInternalError,
+		},
+	})
+}
+
+func TestIBBMeasuredAndTrusted(t *testing.T) {
+	runHWTestCases(t, &testibbmeasured, []hwTestCase{
+		{name: "Measured", hw: txtMock(), result: ResultPass},
+		{
+			name:   "NotMeasured",
+			hw:     txtMock().WithPhysMemoryValue(txtRegister(0xA0), uint64(1<<63|1<<62)),
+			result: ResultFail,
+		},
+		{name: "NotReadable", hw: hwInternal.NewMockBuilder(), result: ResultInternalError},
+	})
+	runHWTestCases(t, &testibbistrusted, []hwTestCase{
+		{name: "Trusted", hw: txtMock(), result: ResultPass},
+		{
+			name:   "NotTrusted",
+			hw:     txtMock().WithPhysMemoryValue(txtRegister(0xA0), uint64(1<<63)),
+			result: ResultFail,
+		},
+		{name: "NotReadable", hw: hwInternal.NewMockBuilder(), result: ResultInternalError},
+	})
+}
+
+func TestTXTRegistersLocked(t *testing.T) {
+	runHWTestCases(t, &testtxtregisterslocked, []hwTestCase{
+		{name: "Locked", hw: txtMock(), result: ResultPass},
+		{name: "NotLocked", hw: txtMock().WithPhysMemoryValue(txtRegister(0x00), uint64(0)), result: ResultFail},
+		{name: "NotReadable", hw: hwInternal.NewMockBuilder(), result: ResultInternalError},
+	})
+}
+
+func TestIA32DebugInterfaceLockedDisabled(t *testing.T) {
+	runHWTestCases(t, &testia32debuginterfacelockeddisabled, []hwTestCase{
+		{name: "NotSupported", hw: hwInternal.NewMockBuilder().WithCPUID(1, 0, 0, 0, 1<<11, 0), result: ResultPass},
+		{name: "LockedDisabled", hw: hwInternal.NewMockBuilder().WithMSR(msrIA32DebugInterface, 1<<30), result: ResultPass},
+		{name: "Enabled", hw: hwInternal.NewMockBuilder().WithMSR(msrIA32DebugInterface, 1<<30|1), result: ResultFail},
+		{name: "NotLocked", hw: hwInternal.NewMockBuilder().WithMSR(msrIA32DebugInterface, 0), result: ResultFail},
+		{name: "ForcedByStrap", hw: hwInternal.NewMockBuilder().WithMSR(msrIA32DebugInterface, 1<<31|1<<30), result: ResultFail},
+		{name: "NotReadable", hw: hwInternal.NewMockBuilder(), result: ResultInternalError},
+	})
+}
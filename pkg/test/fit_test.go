@@ -0,0 +1,215 @@
+package test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	hwInternal "github.com/9elements/converged-security-suite/v2/pkg/hwapi"
+	"github.com/9elements/converged-security-suite/v2/pkg/tools"
+	"github.com/9elements/go-linux-lowlevel-hw/pkg/hwapi"
+)
+
+// The layout of the top of the firmware declared by fitMock.
+const (
+	testFirmwareBase = uint64(0xFFFF0000)
+	testFITBase      = testFirmwareBase
+	// the TXT policy configuration byte referenced by fitTXTPolicy
+	testTXTPolicyAddr = testFirmwareBase + 0x800
+	// the BIOS ACM referenced by fitBIOSACM is not declared
+	testBIOSACMAddr = uint64(0xFFF00000)
+)
+
+// The FIT entry types used by the tests.
+const (
+	fitTypeHeader         = 0x00
+	fitTypeMicrocode      = 0x01
+	fitTypeStartupACM     = 0x02
+	fitTypeBIOSStartup    = 0x07
+	fitTypeBIOSPolicy     = 0x09
+	fitTypeTXTPolicy      = 0x0A
+	fitEntryVersionCommon = 0x0100
+)
+
+// fitEntry returns a FIT entry without checksum.
+func fitEntry(entryType uint8, address uint64, size uint32, version uint16) []byte {
+	entry := make([]byte, 16)
+	binary.LittleEndian.PutUint64(entry, address)
+	binary.LittleEndian.PutUint32(entry[8:], size&0xFFFFFF)
+	binary.LittleEndian.PutUint16(entry[12:], version)
+	entry[14] = entryType
+	return entry
+}
+
+// fitHeaderEntry returns the FIT header of a table with the amount of entries
+// (including the header).
+func fitHeaderEntry(entries uint32) []byte {
+	return fitEntry(fitTypeHeader, binary.LittleEndian.Uint64([]byte("_FIT_   ")), entries, fitEntryVersionCommon)
+}
+
+// fitMock declares the top 64 KiB of the firmware with the FIT consisting of
+// the header and the entries and the FIT pointer to it.
+func fitMock(entries ...[]byte) *hwInternal.MockBuilder {
+	table := fitHeaderEntry(uint32(len(entries) + 1))
+	for _, entry := range entries {
+		table = append(table, entry...)
+	}
+	return hwInternal.NewMockBuilder().
+		WithPhysMemory(testFirmwareBase, make([]byte, FourGiB-testFirmwareBase)).
+		WithPhysMemory(testFITBase, table).
+		WithPhysMemoryValue(FITVector, uint32(testFITBase))
+}
+
+var (
+	fitMicrocode   = fitEntry(fitTypeMicrocode, testFirmwareBase+0x1000, 0, fitEntryVersionCommon)
+	fitBIOSACM     = fitEntry(fitTypeStartupACM, testBIOSACMAddr, 0, fitEntryVersionCommon)
+	fitIBB         = fitEntry(fitTypeBIOSStartup, testFirmwareBase+0x8000, 0x800, fitEntryVersionCommon)
+	fitBIOSPolicy  = fitEntry(fitTypeBIOSPolicy, testFirmwareBase+0x2000, 0x100, fitEntryVersionCommon)
+	fitTXTPolicy   = fitEntry(fitTypeTXTPolicy, testTXTPolicyAddr, 0, 1)
+	fitIndexedIO   = fitEntry(fitTypeTXTPolicy, 0, 0, 0)
+	fitUnknownTXTV = fitEntry(fitTypeTXTPolicy, testTXTPolicyAddr, 0, 2)
+)
+
+// runFITTestCases is runHWTestCases for the tests using the FIT parsed by
+// testhasfit.
+func runFITTestCases(t *testing.T, test *Test, cases []hwTestCase) {
+	for idx := range cases {
+		cases[idx].prerequisites = []*Test{&testfitvectorisset, &testhasfit}
+	}
+	runHWTestCases(t, test, cases)
+}
+
+func TestFITVectorIsSet(t *testing.T) {
+	runHWTestCases(t, &testfitvectorisset, []hwTestCase{
+		{name: "Set", hw: fitMock(), result: ResultPass},
+		{name: "BelowValidRange", hw: fitMock().WithPhysMemoryValue(FITVector, uint32(0xFE000000)), result: ResultFail},
+		{name: "AboveFITVector", hw: fitMock().WithPhysMemoryValue(FITVector, uint32(ResetVector)), result: ResultFail},
+		{name: "NotReadable", hw: hwInternal.NewMockBuilder(), result: ResultInternalError},
+	})
+}
+
+func TestHasFIT(t *testing.T) {
+	prerequisites := []*Test{&testfitvectorisset}
+	runHWTestCases(t, &testhasfit, []hwTestCase{
+		{name: "Valid", hw: fitMock(fitMicrocode), prerequisites: prerequisites, result: ResultPass},
+		{
+			name:          "AboveFITVector",
+			hw:            fitMock().WithPhysMemory(testFITBase, fitHeaderEntry(0x1000)),
+			prerequisites: prerequisites,
+			result:        ResultFail,
+		},
+		{
+			name:          "NotReadable",
+			hw:            fitMock().WithPhysMemoryValue(FITVector, uint32(0xFF800000)),
+			prerequisites: prerequisites,
+			result:        ResultInternalError,
+		},
+	})
+}
+
+func TestFITEntriesPresent(t *testing.T) {
+	runFITTestCases(t, &testhasmcupdate, []hwTestCase{
+		{name: "Present", hw: fitMock(fitMicrocode, fitIBB), result: ResultPass},
+		{name: "Missing", hw: fitMock(fitIBB), result: ResultFail},
+	})
+	runFITTestCases(t, &testhasbiosacm, []hwTestCase{
+		{name: "Present", hw: fitMock(fitMicrocode, fitBIOSACM), result: ResultPass},
+		{name: "Missing", hw: fitMock(fitMicrocode), result: ResultFail},
+	})
+	runFITTestCases(t, &testhasibb, []hwTestCase{
+		{name: "Present", hw: fitMock(fitMicrocode, fitIBB), result: ResultPass},
+		{name: "Missing", hw: fitMock(fitMicrocode), result: ResultFail},
+	})
+}
+
+func TestHasBIOSPolicy(t *testing.T) {
+	runFITTestCases(t, &testhaslcpTest, []hwTestCase{
+		{name: "AutoPromotion", hw: fitMock(), preset: PreSet{TXTMode: tools.AutoPromotion}, result: ResultPass},
+		{name: "Present", hw: fitMock(fitBIOSPolicy), preset: PreSet{TXTMode: tools.SignedPolicy}, result: ResultPass},
+		{name: "Missing", hw: fitMock(), preset: PreSet{TXTMode: tools.SignedPolicy}, result: ResultFail},
+		{
+			name:   "Duplicated",
+			hw:     fitMock(fitBIOSPolicy, fitBIOSPolicy),
+			preset: PreSet{TXTMode: tools.SignedPolicy},
+			result: ResultFail,
+		},
+	})
+}
+
+func TestPolicyAllowsTXT(t *testing.T) {
+	runFITTestCases(t, &testpolicyallowstxt, []hwTestCase{
+		{name: "NoRecord", hw: fitMock(fitIBB), result: ResultPass},
+		{
+			name:   "Enabled",
+			hw:     fitMock(fitTXTPolicy).WithPhysMemoryValue(testTXTPolicyAddr, uint8(1)),
+			result: ResultPass,
+		},
+		{name: "Disabled", hw: fitMock(fitTXTPolicy), result: ResultFail},
+		{name: "UnknownVersion", hw: fitMock(fitUnknownTXTV), result: ResultFail},
+		{name: "IndexedIO", hw: fitMock(fitIndexedIO), result: ResultInternalError},
+		{
+			name:   "NotReadable",
+			hw:     fitMock(fitEntry(fitTypeTXTPolicy, testBIOSACMAddr, 0, 1)),
+			result: ResultInternalError,
+		},
+	})
+}
+
+func TestIBBCovers(t *testing.T) {
+	for _, test := range []*Test{&testibbcoversresetvector, &testibbcoversfitvector, &testibbcoversfit} {
+		t.Run(test.Name, func(t *testing.T) {
+			runFITTestCases(t, test, []hwTestCase{
+				{name: "NoIBB", hw: fitMock(fitMicrocode), result: ResultFail},
+			})
+		})
+	}
+}
+
+func TestNoOverlap(t *testing.T) {
+	runFITTestCases(t, &testnoibboverlap, []hwTestCase{
+		{name: "NoIBB", hw: fitMock(fitMicrocode), result: ResultPass},
+		{name: "SingleIBB", hw: fitMock(fitMicrocode, fitIBB), result: ResultPass},
+	})
+	runFITTestCases(t, &testnobiosacmoverlap, []hwTestCase{
+		{name: "NoBIOSACM", hw: fitMock(fitIBB), result: ResultPass},
+	})
+	runFITTestCases(t, &testnobiosacmisbelow4g, []hwTestCase{
+		{name: "NoBIOSACM", hw: fitMock(fitIBB), result: ResultPass},
+	})
+}
+
+func TestBIOSACM(t *testing.T) {
+	for _, test := range []*Test{
+		&testbiosacmvalid,
+		&testbiosacmsizecorrect,
+		&testbiosacmmatcheschipset,
+		&testbiosacmmatchescpu,
+		&testacmsfornpw,
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			runFITTestCases(t, test, []hwTestCase{
+				{name: "NoBIOSACM", hw: fitMock(fitIBB), result: ResultFail},
+				{name: "NotReadable", hw: fitMock(fitBIOSACM), result: ResultFail},
+			})
+		})
+	}
+	runFITTestCases(t, &testbiosacmaligmentcorrect, []hwTestCase{
+		{name: "NoBIOSACM", hw: fitMock(fitIBB), result: ResultPass},
+		{name: "NotReadable", hw: fitMock(fitBIOSACM), result: ResultInternalError},
+	})
+}
+
+func TestSINITACMcomplyTPMSpec(t *testing.T) {
+	defer func(result Result) { testtpmispresent.Result = result }(testtpmispresent.Result)
+
+	testtpmispresent.Result = ResultPass
+	runHWTestCases(t, &testsinitacmupporttpm, []hwTestCase{
+		{name: "TPM20", hw: sinitMock(t), preset: PreSet{TPM: hwapi.TPMVersion20}, result: ResultPass},
+		{name: "NoSINIT", hw: txtMock(), preset: PreSet{TPM: hwapi.TPMVersion20}, result: ResultFail},
+		{name: "NotReadable", hw: hwInternal.NewMockBuilder(), result: ResultInternalError},
+	})
+
+	testtpmispresent.Result = ResultFail
+	runHWTestCases(t, &testsinitacmupporttpm, []hwTestCase{
+		{name: "NoTPM", hw: sinitMock(t), preset: PreSet{TPM: hwapi.TPMVersion20}, result: ResultFail},
+	})
+}
@@ -590,25 +590,16 @@ func NoSINITErrors(txtAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, er
 
 // BIOSDATAREGIONPresent checks is the BIOSDATA Region is present in TXT Register Space
 func BIOSDATAREGIONPresent(txtAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, error, error) {
-	buf, err := tools.FetchTXTRegs(txtAPI)
-	if err != nil {
-		return false, nil, err
-	}
-	regs, err := tools.ParseTXTRegs(buf)
+	heap, err := tools.FetchTXTHeap(txtAPI)
 	if err != nil {
 		return false, nil, err
 	}
 
-	txtHeap := make([]byte, regs.HeapSize)
-	err = txtAPI.ReadPhysBuf(int64(regs.HeapBase), txtHeap)
+	txtHeap, err := tools.ParseTXTHeap(heap)
 	if err != nil {
-		return false, nil, err
-	}
-
-	biosdata, err = tools.ParseBIOSDataRegion(txtHeap)
-	if err != nil {
-		return false, nil, err
+		return false, err, nil
 	}
+	biosdata = *txtHeap.BiosData
 
 	return true, nil, nil
 }
@@ -753,5 +744,5 @@ func sinitACM(txtAPI hwapi.LowLevelHardwareInterfaces, regs tools.TXTRegisterSpa
 	if acm == nil {
 		return nil, fmt.Errorf("ACM is nil")
 	}
-	return tools.ParseACM(r)
+	return acm, nil
 }
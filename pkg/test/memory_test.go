@@ -0,0 +1,268 @@
+package test
+
+import (
+	"os"
+	"testing"
+
+	hwInternal "github.com/9elements/converged-security-suite/v2/pkg/hwapi"
+	"github.com/9elements/converged-security-suite/v2/pkg/tools"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	msrIA32PlatformID = 0x17
+	msrMTRRCap        = 0xFE
+	msrSMRRPhysBase   = 0x1F2
+	msrSMRRPhysMask   = 0x1F3
+)
+
+// e820ReservedMock declares the range reserved in the E820 map. Linux names
+// the type either "Reserved" or "reserved", see hwapi.SnapshotE820Types.
+func e820ReservedMock(hw *hwInternal.MockBuilder, start, end uint64) *hwInternal.MockBuilder {
+	return hw.WithE820Range("Reserved", start, end).WithE820Range("reserved", start, end)
+}
+
+// sinitMock declares the SINIT ACM of pkg/tools/tests in the TXT SINIT
+// region of txtMock, the chipset and the CPU supported by it.
+func sinitMock(t *testing.T) *hwInternal.MockBuilder {
+	acm, err := os.ReadFile("../tools/tests/sinit_acm.bin")
+	require.NoError(t, err)
+	return txtMock().
+		WithPhysMemoryValue(txtRegister(0x110), uint64(0x8086|0xB002<<16|1<<32)).
+		WithPhysMemoryValue(txtRegister(0x278), uint32(len(acm))).
+		WithPhysMemory(uint64(testTXTSinitBase), acm).
+		WithCPUSignature(0x306F2).
+		WithMSR(msrIA32PlatformID, 0)
+}
+
+// smrrMock declares a CPU with SMX, VMX and an active SMRR covering
+// 8 MiB of TSEG.
+func smrrMock() *hwInternal.MockBuilder {
+	return hwInternal.NewMockBuilder().
+		WithCPUID(1, 0, 0, 0, 1<<5|1<<6, 1<<12).
+		WithMSR(msrMTRRCap, 1<<11).
+		WithMSR(msrSMRRPhysBase, 0x7F000006).
+		WithMSR(msrSMRRPhysMask, 0xFF800800)
+}
+
+func TestTXTHeapSpaceValid(t *testing.T) {
+	runHWTestCases(t, &testtxtmemoryrangevalid, []hwTestCase{
+		{name: "Valid", hw: txtMock(), result: ResultPass},
+		{
+			name:   "HeapTooSmall",
+			hw:     txtMock().WithPhysMemoryValue(txtRegister(0x308), uint32(0x10000)),
+			result: ResultFail,
+		},
+		{
+			name:   "SINITNotAligned",
+			hw:     txtMock().WithPhysMemoryValue(txtRegister(0x270), testTXTSinitBase+0x100),
+			result: ResultFail,
+		},
+		{
+			name:   "SINITAboveHeap",
+			hw:     txtMock().WithPhysMemoryValue(txtRegister(0x270), testTXTHeapBase+testTXTHeapSize),
+			result: ResultFail,
+		},
+		{name: "NotReadable", hw: hwInternal.NewMockBuilder(), result: ResultInternalError},
+	})
+}
+
+func TestTXTReservedInE820(t *testing.T) {
+	// TXT public and private space and the TPM decode area
+	chipsetReserved := func(hw *hwInternal.MockBuilder) *hwInternal.MockBuilder {
+		return e820ReservedMock(hw, 0xFED00000, 0xFEDFFFFF)
+	}
+	runHWTestCases(t, &testtxtpublicisreserved, []hwTestCase{
+		{name: "Reserved", hw: chipsetReserved(txtMock()), result: ResultPass},
+		{name: "NotReserved", hw: txtMock(), result: ResultFail},
+	})
+	runHWTestCases(t, &testtxtprivateisreserved, []hwTestCase{
+		{name: "Reserved", hw: chipsetReserved(txtMock()), result: ResultPass},
+		{name: "NotReserved", hw: txtMock(), result: ResultFail},
+	})
+	runHWTestCases(t, &testtpmdecodereserved, []hwTestCase{
+		{name: "Reserved", hw: chipsetReserved(txtMock()), result: ResultPass},
+		{name: "NotReserved", hw: txtMock(), result: ResultFail},
+	})
+	runHWTestCases(t, &testmemoryisreserved, []hwTestCase{
+		{
+			name:   "Reserved",
+			hw:     e820ReservedMock(txtMock(), uint64(testTXTSinitBase), 0x800FFFFF),
+			result: ResultPass,
+		},
+		{
+			name:   "HeapNotReserved",
+			hw:     e820ReservedMock(txtMock(), uint64(testTXTSinitBase), uint64(testTXTHeapBase)-1),
+			result: ResultFail,
+		},
+		{name: "NotReserved", hw: txtMock(), result: ResultFail},
+		{name: "NotReadable", hw: hwInternal.NewMockBuilder(), result: ResultInternalError},
+	})
+}
+
+func TestTXTMemoryIsDPR(t *testing.T) {
+	runHWTestCases(t, &testtxtmemoryisdpr, []hwTestCase{
+		{name: "Covered", hw: txtMock(), result: ResultPass},
+		{
+			name:   "DPRTooSmall",
+			hw:     txtMock().WithPhysMemoryValue(txtRegister(0x330), uint32(1|2<<4|0x7FF<<20)),
+			result: ResultFail,
+		},
+		{
+			name:   "HeapNotAtTop",
+			hw:     txtMock().WithPhysMemoryValue(txtRegister(0x330), uint32(1|8<<4|0x800<<20)),
+			result: ResultFail,
+		},
+		{name: "NotReadable", hw: hwInternal.NewMockBuilder(), result: ResultInternalError},
+	})
+}
+
+func TestTXTDPRisLock(t *testing.T) {
+	runHWTestCases(t, &testtxtdprislocked, []hwTestCase{
+		{name: "Locked", hw: txtMock(), result: ResultPass},
+		{
+			name:   "NotLocked",
+			hw:     txtMock().WithPhysMemoryValue(txtRegister(0x330), testTXTDPR&^1),
+			result: ResultFail,
+		},
+		{name: "NotReadable", hw: hwInternal.NewMockBuilder(), result: ResultInternalError},
+	})
+}
+
+func TestHostbridge(t *testing.T) {
+	runHWTestCases(t, &testSupportsHostbridge, []hwTestCase{
+		{name: "NoHostbridge", hw: txtMock(), result: ResultInternalError},
+	})
+	runHWTestCases(t, &testhostbridgeDPRcorrect, []hwTestCase{
+		{name: "NoHostbridge", hw: txtMock(), result: ResultFail},
+	})
+	runHWTestCases(t, &testhostbridgeDPRislocked, []hwTestCase{
+		{name: "NoHostbridge", hw: txtMock(), result: ResultInternalError},
+	})
+}
+
+func TestSINITInTXT(t *testing.T) {
+	runHWTestCases(t, &testsinitintxt, []hwTestCase{
+		{name: "SINIT", hw: sinitMock(t), result: ResultPass},
+		{name: "NoSINIT", hw: txtMock(), result: ResultFail},
+		{name: "NotReadable", hw: hwInternal.NewMockBuilder(), result: ResultInternalError},
+	})
+}
+
+func TestSINITMatchesPlatform(t *testing.T) {
+	runHWTestCases(t, &testsinitmatcheschipset, []hwTestCase{
+		{name: "Matches", hw: sinitMock(t), result: ResultPass},
+		{
+			name:   "OtherDevice",
+			hw:     sinitMock(t).WithPhysMemoryValue(txtRegister(0x110), uint64(0x8086|0xB001<<16|1<<32)),
+			result: ResultFail,
+		},
+		{
+			name:   "OtherRevision",
+			hw:     sinitMock(t).WithPhysMemoryValue(txtRegister(0x110), uint64(0x8086|0xB002<<16|2<<32)),
+			result: ResultFail,
+		},
+		{name: "NoSINIT", hw: txtMock(), result: ResultFail},
+		{name: "NotReadable", hw: hwInternal.NewMockBuilder(), result: ResultInternalError},
+	})
+	runHWTestCases(t, &testsinitmatchescpu, []hwTestCase{
+		{name: "Matches", hw: sinitMock(t), result: ResultPass},
+		{name: "OtherCPU", hw: sinitMock(t).WithCPUSignature(0x906EA), result: ResultFail},
+		{name: "NoSINIT", hw: txtMock(), result: ResultFail},
+		{name: "NotReadable", hw: hwInternal.NewMockBuilder(), result: ResultInternalError},
+	})
+}
+
+func TestNoSINITErrors(t *testing.T) {
+	runHWTestCases(t, &testnosiniterrors, []hwTestCase{
+		{name: "Success", hw: txtMock(), result: ResultPass},
+		{
+			name:   "Error",
+			hw:     txtMock().WithPhysMemoryValue(txtRegister(0x30), uint32(0x8000C0A1)),
+			result: ResultFail,
+		},
+		{name: "NotReadable", hw: hwInternal.NewMockBuilder(), result: ResultInternalError},
+	})
+}
+
+func TestBIOSDATAREGION(t *testing.T) {
+	heapBIOSData := struct {
+		Size          uint64
+		Version       uint32
+		BiosSinitSize uint32
+		Reserved1     uint64
+		Reserved2     uint64
+		NumLogProcs   uint32
+		SinitFlags    uint32
+	}{
+		Size:          0x2C,
+		Version:       4,
+		BiosSinitSize: 0x50000,
+		NumLogProcs:   8,
+	}
+	runHWTestCases(t, &testbiosdataregionpresent, []hwTestCase{
+		{
+			name: "Present",
+			hw: txtMock().
+				WithPhysMemory(uint64(testTXTHeapBase), make([]byte, testTXTHeapSize)).
+				WithPhysMemoryValue(uint64(testTXTHeapBase), heapBIOSData),
+			result: ResultPass,
+		},
+		{
+			name:   "NoBIOSData",
+			hw:     txtMock().WithPhysMemory(uint64(testTXTHeapBase), make([]byte, testTXTHeapSize)),
+			result: ResultFail,
+		},
+		{name: "HeapNotReadable", hw: txtMock(), result: ResultInternalError},
+	})
+
+	for name, tc := range map[string]struct {
+		biosData tools.TXTBiosData
+		isValid  bool
+	}{
+		"Valid":             {biosData: tools.TXTBiosData{Version: 4, BiosSinitSize: 8, NumLogProcs: 8}, isValid: true},
+		"OldVersion":        {biosData: tools.TXTBiosData{Version: 1, BiosSinitSize: 8, NumLogProcs: 8}},
+		"SINITDataTooSmall": {biosData: tools.TXTBiosData{Version: 4, BiosSinitSize: 4, NumLogProcs: 8}},
+		"NoCPUs":            {biosData: tools.TXTBiosData{Version: 4, BiosSinitSize: 8}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			biosdata = tc.biosData
+			isValid, err, internalErr := BIOSDATAREGIONValid(nil, nil)
+			require.NoError(t, internalErr)
+			require.Equal(t, tc.isValid, isValid)
+			require.Equal(t, tc.isValid, err == nil)
+		})
+	}
+}
+
+func TestMTRRAndSMRR(t *testing.T) {
+	runHWTestCases(t, &testhasmtrr, []hwTestCase{
+		{name: "MTRR", hw: smrrMock(), result: ResultPass},
+		{name: "NoMTRR", hw: hwInternal.NewMockBuilder(), result: ResultFail},
+	})
+	runHWTestCases(t, &testhassmrr, []hwTestCase{
+		{name: "SMRR", hw: smrrMock(), result: ResultPass},
+		{name: "NoSMRR", hw: smrrMock().WithMSR(msrMTRRCap, 0), result: ResultFail},
+		{name: "NotReadable", hw: hwInternal.NewMockBuilder(), result: ResultInternalError},
+	})
+	runHWTestCases(t, &testactivesmrr, []hwTestCase{
+		{name: "Active", hw: smrrMock(), result: ResultPass},
+		{name: "NotActive", hw: smrrMock().WithMSR(msrSMRRPhysMask, 0xFF800000), result: ResultFail},
+		{name: "NotReadable", hw: hwInternal.NewMockBuilder(), result: ResultInternalError},
+	})
+	runHWTestCases(t, &testvalidsmrr, []hwTestCase{
+		{name: "NoPhysMask", hw: smrrMock().WithMSR(msrSMRRPhysMask, 0x800), result: ResultFail},
+		{name: "NoPhysBase", hw: smrrMock().WithMSR(msrSMRRPhysBase, 0x6), result: ResultFail},
+		{name: "NoHostbridge", hw: smrrMock(), result: ResultInternalError},
+		{name: "NotReadable", hw: hwInternal.NewMockBuilder(), result: ResultInternalError},
+	})
+	runHWTestCases(t, &testactiveiommu, []hwTestCase{
+		{name: "NoDMAR", hw: smrrMock(), result: ResultFail},
+		{name: "NotReadable", hw: hwInternal.NewMockBuilder(), result: ResultInternalError},
+	})
+	runHWTestCases(t, &testservermodetext, []hwTestCase{
+		{name: "ServerMode", hw: smrrMock(), result: ResultPass},
+		{name: "NoVMX", hw: smrrMock().WithCPUID(1, 0, 0, 0, 1<<6, 1<<12), result: ResultFail},
+		{name: "NotReadable", hw: hwInternal.NewMockBuilder(), result: ResultInternalError},
+	})
+}
@@ -0,0 +1,74 @@
+package test
+
+import (
+	"testing"
+
+	hwInternal "github.com/9elements/converged-security-suite/v2/pkg/hwapi"
+	"github.com/9elements/converged-security-suite/v2/pkg/tools"
+	"github.com/stretchr/testify/require"
+)
+
+// The TXT config space of a correctly configured platform, see txtMock.
+const (
+	testTXTHeapSize  = uint32(0xF0000)
+	testTXTSinitSize = uint32(0x50000)
+	// the heap ends at the top of DPR (2 GiB)
+	testTXTHeapBase  = uint32(0x80000000) - testTXTHeapSize
+	testTXTSinitBase = testTXTHeapBase - testTXTSinitSize
+	// locked, 4 MiB, top at 2 GiB - 1 MiB
+	testTXTDPR = uint32(1 | 4<<4 | 0x7FF<<20)
+)
+
+// txtMock declares an Intel CPU and the TXT config space of a correctly
+// configured platform. The test cases modify it to trigger failures.
+func txtMock() *hwInternal.MockBuilder {
+	return hwInternal.NewMockBuilder().
+		WithVendor("GenuineIntel").
+		WithPhysMemory(tools.TxtPublicSpace, make([]byte, tools.TxtPublicSpaceSize)).
+		WithPhysMemoryValue(txtRegister(0x00), uint64(1<<7)).        // TXT.STS: PRIVATE-OPEN.STS
+		WithPhysMemoryValue(txtRegister(0x30), uint32(0xC0000001)).  // TXT.ERRORCODE: SINIT success
+		WithPhysMemoryValue(txtRegister(0xA0), uint64(1<<63|1<<59)). // TXT.BOOTSTATUS: IBB measured and trusted
+		WithPhysMemoryValue(txtRegister(0x110), uint32(0xB0018086)). // TXT.DIDVID
+		WithPhysMemoryValue(txtRegister(0x270), testTXTSinitBase).
+		WithPhysMemoryValue(txtRegister(0x278), testTXTSinitSize).
+		WithPhysMemoryValue(txtRegister(0x300), testTXTHeapBase).
+		WithPhysMemoryValue(txtRegister(0x308), testTXTHeapSize).
+		WithPhysMemoryValue(txtRegister(0x330), testTXTDPR)
+}
+
+// txtRegister returns the physical address of the TXT register.
+func txtRegister(offset uint64) uint64 {
+	return tools.TxtPublicSpace + offset
+}
+
+// hwTestCase is a case of a test executed on a mocked hardware.
+type hwTestCase struct {
+	name   string
+	hw     *hwInternal.MockBuilder
+	preset PreSet
+	// prerequisites are executed before the test to initialize the state
+	// they share with it (like the parsed FIT), their results are ignored.
+	prerequisites []*Test
+	result        Result
+}
+
+// runHWTestCases executes the test function (without the dependencies of
+// the test) for each case and checks the result.
+func runHWTestCases(t *testing.T, test *Test, cases []hwTestCase) {
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			txtRegisterValues = nil
+			fitPointer, fitHeaders = 0, nil
+			hw := tc.hw.Build()
+			for _, prerequisite := range tc.prerequisites {
+				_, _, _ = prerequisite.function(hw, &tc.preset)
+			}
+			isolated := Test{
+				Name:     test.Name,
+				function: test.function,
+			}
+			isolated.Run(hw, &tc.preset)
+			require.Equal(t, tc.result, isolated.Result, isolated.ErrorText)
+		})
+	}
+}
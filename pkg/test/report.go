@@ -0,0 +1,399 @@
+package test
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ReportFormat is the output format of a test report
+type ReportFormat string
+
+const (
+	// ReportFormatJSON is a JSON document of the Report structure
+	ReportFormatJSON ReportFormat = "json"
+	// ReportFormatJUnit is JUnit XML as understood by most CI systems
+	ReportFormatJUnit ReportFormat = "junit"
+	// ReportFormatSARIF is the Static Analysis Results Interchange Format 2.1.0
+	ReportFormatSARIF ReportFormat = "sarif"
+)
+
+// ReportFormats lists all supported report formats
+var ReportFormats = []ReportFormat{ReportFormatJSON, ReportFormatJUnit, ReportFormatSARIF}
+
+// ParseReportFormat returns the ReportFormat of the given name
+func ParseReportFormat(name string) (ReportFormat, error) {
+	for _, format := range ReportFormats {
+		if strings.EqualFold(name, string(format)) {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown report format '%s'", name)
+}
+
+// Extension returns the file name extension commonly used for the format
+func (f ReportFormat) Extension() string {
+	switch f {
+	case ReportFormatJUnit:
+		return "xml"
+	case ReportFormatSARIF:
+		return "sarif"
+	}
+	return "json"
+}
+
+// Report holds the results of a test run in a machine-readable form
+type Report struct {
+	Tool      string
+	Version   string
+	Group     string
+	Timestamp time.Time
+	// Properties holds additional information about the platform under test
+	Properties map[string]string `json:",omitempty"`
+	Tests      []ReportTest
+}
+
+// ReportTest is the result of a single test
+type ReportTest struct {
+	Testnumber              int
+	Testname                string
+	Description             string
+	Required                bool
+	Status                  string
+	Result                  string
+	Error                   string
+	ErrorSpec               string
+	SpecificationTitle      string
+	SpecificationDocumentID string
+	SpecificationChapter    string
+	// Dependencies is the chain of tests this test depends on, in the order they run
+	Dependencies   []string
+	RuntimeSeconds float64
+}
+
+// NewReport builds a report of the given tests. Tests which are not implemented are omitted.
+func NewReport(tool, version, group string, tests []*Test) *Report {
+	r := &Report{
+		Tool:      tool,
+		Version:   version,
+		Group:     group,
+		Timestamp: time.Now().UTC(),
+	}
+	for idx, t := range tests {
+		if t.Status == NotImplemented {
+			continue
+		}
+		r.Tests = append(r.Tests, ReportTest{
+			Testnumber:              idx,
+			Testname:                t.Name,
+			Description:             t.Description,
+			Required:                t.Required,
+			Status:                  t.Status.String(),
+			Result:                  t.Result.String(),
+			Error:                   t.ErrorText,
+			ErrorSpec:               t.ErrorTextSpec,
+			SpecificationTitle:      t.SpecificiationTitle,
+			SpecificationDocumentID: t.SpecificationDocumentID,
+			SpecificationChapter:    t.SpecificationChapter,
+			Dependencies:            dependencyChain(t),
+			RuntimeSeconds:          t.Runtime.Seconds(),
+		})
+	}
+	return r
+}
+
+// dependencyChain returns the names of all direct and indirect dependencies of t
+func dependencyChain(t *Test) []string {
+	var chain []string
+	seen := map[*Test]bool{}
+	var walk func(*Test)
+	walk = func(t *Test) {
+		for _, dep := range t.dependencies {
+			if seen[dep] {
+				continue
+			}
+			seen[dep] = true
+			walk(dep)
+			chain = append(chain, dep.Name)
+		}
+	}
+	walk(t)
+	return chain
+}
+
+// Write writes the report to w in the given format
+func (r *Report) Write(w io.Writer, format ReportFormat) error {
+	switch format {
+	case ReportFormatJSON:
+		return r.writeJSON(w)
+	case ReportFormatJUnit:
+		return r.writeJUnit(w)
+	case ReportFormatSARIF:
+		return r.writeSARIF(w)
+	}
+	return fmt.Errorf("unknown report format '%s'", format)
+}
+
+func (r *Report) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name       string          `xml:"name,attr"`
+	Classname  string          `xml:"classname,attr"`
+	Time       string          `xml:"time,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Failure    *junitMessage   `xml:"failure,omitempty"`
+	Error      *junitMessage   `xml:"error,omitempty"`
+	Skipped    *junitMessage   `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+func junitSeconds(s float64) string {
+	return fmt.Sprintf("%.6f", s)
+}
+
+func (r *Report) writeJUnit(w io.Writer) error {
+	suite := junitTestSuite{
+		Name:      r.Tool + ": " + r.Group,
+		Timestamp: r.Timestamp.Format(time.RFC3339),
+		Properties: []junitProperty{
+			{Name: "tool", Value: r.Tool},
+			{Name: "version", Value: r.Version},
+		},
+	}
+	keys := make([]string, 0, len(r.Properties))
+	for key := range r.Properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		suite.Properties = append(suite.Properties, junitProperty{Name: key, Value: r.Properties[key]})
+	}
+	var total float64
+	for _, t := range r.Tests {
+		total += t.RuntimeSeconds
+		tc := junitTestCase{
+			Name:      fmt.Sprintf("%02d - %s", t.Testnumber, t.Testname),
+			Classname: r.Tool + "." + r.Group,
+			Time:      junitSeconds(t.RuntimeSeconds),
+		}
+		for _, p := range []junitProperty{
+			{Name: "required", Value: fmt.Sprint(t.Required)},
+			{Name: "status", Value: t.Status},
+			{Name: "specification", Value: t.SpecificationTitle},
+			{Name: "document", Value: t.SpecificationDocumentID},
+			{Name: "chapter", Value: t.SpecificationChapter},
+			{Name: "dependencies", Value: strings.Join(t.Dependencies, " -> ")},
+		} {
+			if p.Value != "" {
+				tc.Properties = append(tc.Properties, p)
+			}
+		}
+		text := strings.TrimSpace(t.Error + "\n" + t.ErrorSpec)
+		switch t.Result {
+		case ResultPass.String():
+		case ResultFail.String():
+			tc.Failure = &junitMessage{Message: t.Error, Type: t.Result, Text: text}
+			suite.Failures++
+		case ResultInternalError.String():
+			tc.Error = &junitMessage{Message: t.Error, Type: t.Result, Text: text}
+			suite.Errors++
+		default:
+			tc.Skipped = &junitMessage{Message: t.Result, Text: t.Error}
+			suite.Skipped++
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	suite.Tests = len(suite.Cases)
+	suite.Time = junitSeconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool       sarifTool         `json:"tool"`
+	Results    []sarifResult     `json:"results"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string                 `json:"id"`
+	Name             string                 `json:"name"`
+	ShortDescription sarifMessage           `json:"shortDescription"`
+	FullDescription  *sarifMessage          `json:"fullDescription,omitempty"`
+	Help             *sarifMessage          `json:"help,omitempty"`
+	Properties       map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifResult struct {
+	RuleID     string                 `json:"ruleId"`
+	RuleIndex  int                    `json:"ruleIndex"`
+	Kind       string                 `json:"kind"`
+	Level      string                 `json:"level"`
+	Message    sarifMessage           `json:"message"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+// sarifRuleID turns a test name into a stable SARIF rule identifier
+func sarifRuleID(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, c := range strings.ToLower(name) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			b.WriteRune(c)
+			dash = false
+			continue
+		}
+		if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+func (r *Report) writeSARIF(w io.Writer) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           r.Tool,
+			Version:        r.Version,
+			InformationURI: "https://github.com/9elements/converged-security-suite",
+		}},
+		Results:    []sarifResult{},
+		Properties: r.Properties,
+	}
+	ruleIDs := map[string]bool{}
+	for idx, t := range r.Tests {
+		id := sarifRuleID(t.Testname)
+		if ruleIDs[id] {
+			id = fmt.Sprintf("%s-%d", id, t.Testnumber)
+		}
+		ruleIDs[id] = true
+		rule := sarifRule{
+			ID:               id,
+			Name:             t.Testname,
+			ShortDescription: sarifMessage{Text: t.Testname},
+			Properties: map[string]interface{}{
+				"required": t.Required,
+				"status":   t.Status,
+			},
+		}
+		if t.Description != "" {
+			rule.FullDescription = &sarifMessage{Text: t.Description}
+		}
+		if t.SpecificationTitle != "" || t.SpecificationDocumentID != "" {
+			rule.Help = &sarifMessage{Text: strings.TrimSpace(fmt.Sprintf("%s %s %s",
+				t.SpecificationTitle, t.SpecificationDocumentID, t.SpecificationChapter))}
+			rule.Properties["specification"] = t.SpecificationTitle
+			rule.Properties["document"] = t.SpecificationDocumentID
+			rule.Properties["chapter"] = t.SpecificationChapter
+		}
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+
+		res := sarifResult{
+			RuleID:    rule.ID,
+			RuleIndex: idx,
+			Message:   sarifMessage{Text: t.Testname + ": " + t.Result},
+			Properties: map[string]interface{}{
+				"testnumber":     t.Testnumber,
+				"result":         t.Result,
+				"runtimeSeconds": t.RuntimeSeconds,
+			},
+		}
+		if t.Error != "" {
+			res.Message.Text += " (" + t.Error + ")"
+		}
+		if t.ErrorSpec != "" {
+			res.Properties["errorSpec"] = t.ErrorSpec
+		}
+		if len(t.Dependencies) > 0 {
+			res.Properties["dependencies"] = t.Dependencies
+		}
+		switch t.Result {
+		case ResultPass.String():
+			res.Kind, res.Level = "pass", "none"
+		case ResultFail.String(), ResultInternalError.String():
+			res.Kind, res.Level = "fail", "warning"
+			if t.Required {
+				res.Level = "error"
+			}
+		default:
+			res.Kind, res.Level = "notApplicable", "none"
+		}
+		run.Results = append(run.Results, res)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  sarifSchema,
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
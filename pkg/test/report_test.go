@@ -0,0 +1,106 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func reportTestSet() []*Test {
+	fit := &Test{Name: "Has FIT", Required: true, Status: Implemented, Result: ResultPass, Runtime: time.Millisecond}
+	acm := &Test{
+		Name:                    "Has ACM",
+		Required:                true,
+		Status:                  Implemented,
+		Result:                  ResultFail,
+		ErrorText:               "no ACM",
+		ErrorTextSpec:           "Please have a look at FIT",
+		SpecificationChapter:    "4.4",
+		SpecificiationTitle:     IntelFITSpecificationTitle,
+		SpecificationDocumentID: IntelFITSpecificationDocumentID,
+		dependencies:            []*Test{fit},
+	}
+	sinit := &Test{Name: "Has SINIT", Status: Implemented, Result: ResultDependencyFailed, ErrorText: "Has ACM failed", dependencies: []*Test{acm}}
+	tpm := &Test{Name: "Has TPM", Required: true, Status: Implemented, Result: ResultInternalError, ErrorText: "no TPM connection"}
+	todo := &Test{Name: "Not yet", Status: NotImplemented}
+	return []*Test{fit, acm, sinit, tpm, todo}
+}
+
+func TestParseReportFormat(t *testing.T) {
+	for _, format := range ReportFormats {
+		parsed, err := ParseReportFormat(string(format))
+		require.NoError(t, err)
+		require.Equal(t, format, parsed)
+	}
+	_, err := ParseReportFormat("yaml")
+	require.Error(t, err)
+}
+
+func TestNewReport(t *testing.T) {
+	r := NewReport("txt-suite", "v2", "All", reportTestSet())
+	require.Len(t, r.Tests, 4)
+	require.Equal(t, 3, r.Tests[3].Testnumber)
+	require.Equal(t, []string{"Has FIT", "Has ACM"}, r.Tests[2].Dependencies)
+	require.Equal(t, IntelFITSpecificationDocumentID, r.Tests[1].SpecificationDocumentID)
+	require.Equal(t, "Please have a look at FIT", r.Tests[1].ErrorSpec)
+	require.Equal(t, 0.001, r.Tests[0].RuntimeSeconds)
+}
+
+func TestReportJSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, NewReport("txt-suite", "v2", "All", reportTestSet()).Write(&buf, ReportFormatJSON))
+
+	var r Report
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &r))
+	require.Equal(t, "All", r.Group)
+	require.Equal(t, ResultFail.String(), r.Tests[1].Result)
+}
+
+func TestReportJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewReport("bg-suite", "v2", "Static", reportTestSet())
+	r.Properties = map[string]string{"BgVersion": "CBnT 2.1"}
+	require.NoError(t, r.Write(&buf, ReportFormatJUnit))
+
+	var suites junitTestSuites
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &suites))
+	require.Len(t, suites.Suites, 1)
+	suite := suites.Suites[0]
+	require.Equal(t, 4, suite.Tests)
+	require.Equal(t, 1, suite.Failures)
+	require.Equal(t, 1, suite.Errors)
+	require.Equal(t, 1, suite.Skipped)
+	require.Contains(t, suite.Properties, junitProperty{Name: "BgVersion", Value: "CBnT 2.1"})
+	require.NotNil(t, suite.Cases[1].Failure)
+	require.Equal(t, "no ACM", suite.Cases[1].Failure.Message)
+	require.NotNil(t, suite.Cases[3].Error)
+}
+
+func TestReportSARIF(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, NewReport("txt-suite", "v2", "All", reportTestSet()).Write(&buf, ReportFormatSARIF))
+
+	var log sarifLog
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &log))
+	require.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	run := log.Runs[0]
+	require.Len(t, run.Tool.Driver.Rules, 4)
+	require.Equal(t, "has-acm", run.Tool.Driver.Rules[1].ID)
+
+	var kinds, levels []string
+	for _, res := range run.Results {
+		kinds = append(kinds, res.Kind)
+		levels = append(levels, res.Level)
+	}
+	require.Equal(t, []string{"pass", "fail", "notApplicable", "fail"}, kinds)
+	require.Equal(t, []string{"none", "error", "none", "error"}, levels)
+}
+
+func TestSARIFRuleID(t *testing.T) {
+	require.Equal(t, "runtime-verifies-post-boot-btg-txt-registers", sarifRuleID("[RUNTIME] Verifies post-boot BtG/TXT registers"))
+}
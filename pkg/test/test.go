@@ -2,6 +2,7 @@ package test
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/9elements/converged-security-suite/v2/pkg/intel"
 	"github.com/9elements/go-linux-lowlevel-hw/pkg/hwapi"
@@ -72,6 +73,8 @@ type Test struct {
 	SpecificationDocumentID string
 	// Only relevant for the runtime tests
 	SupportedVersion []intel.BgVersion
+	// Runtime of the test function, excluding its dependencies
+	Runtime time.Duration
 }
 
 // Run implements the genereal test function and exposes it.
@@ -94,7 +97,9 @@ func (t *Test) Run(hw hwapi.LowLevelHardwareInterfaces, preset *PreSet) bool {
 
 	if DepsPassed {
 		// Now run the test itself
+		start := time.Now()
 		rc, testerror, internalerror := t.function(hw, preset)
+		t.Runtime = time.Since(start)
 		if internalerror != nil && testerror == nil {
 			t.Result = ResultInternalError
 			t.ErrorText = internalerror.Error()
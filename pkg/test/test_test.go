@@ -39,6 +39,7 @@ func TestTest_Run(t *testing.T) {
 		"",
 		"",
 		[]intel.BgVersion{intel.BootGuard},
+		0,
 	}
 
 	BFailed := Test{
@@ -57,6 +58,7 @@ func TestTest_Run(t *testing.T) {
 		"",
 		"",
 		[]intel.BgVersion{intel.BootGuard},
+		0,
 	}
 	BNotRun := Test{
 		"Test B",
@@ -74,6 +76,7 @@ func TestTest_Run(t *testing.T) {
 		"",
 		"",
 		[]intel.BgVersion{intel.CBnT20},
+		0,
 	}
 
 	tests := []struct {
@@ -561,6 +561,7 @@ func POIndexConfig(txtAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, er
 		if d1.Size != tpm12POIndexSize {
 			return false, fmt.Errorf("TPM1 PO Index size incorrect. Have: %v - Want: %v", d1.Size, tpm12POIndexSize), nil
 		}
+		return true, nil, nil
 	case hwapi.TPMVersion20:
 		raw, err = txtAPI.ReadNVPublic(tpmCon, tpm20POIndex)
 		if err != nil {
@@ -610,6 +611,7 @@ func POIndexConfig(txtAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (bool, er
 		if d2.DataSize != size {
 			return false, fmt.Errorf("TPM2 PO Index incorrect. Have: %v - Want: %v", d2.DataSize, size), nil
 		}
+		return true, nil, nil
 	}
 	return false, fmt.Errorf("unknown TPM device version"), nil
 }
@@ -652,8 +654,8 @@ func PSIndexHasValidLCP(txtAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (boo
 		if pol2.HashAlg != p.LCPHash {
 			return false, fmt.Errorf("HashAlg has invalid value"), nil
 		}
-		if pol2.PolicyType != tools.LCPPolicyTypeAny && pol1.PolicyType != tools.LCPPolicyTypeList {
-			return false, fmt.Errorf("PolicyType is invalid. Have: %d - Want: %d or %d", pol1.PolicyType, tools.LCPPolicyTypeAny, tools.LCPPolicyTypeList), nil
+		if pol2.PolicyType != tools.LCPPolicyTypeAny && pol2.PolicyType != tools.LCPPolicyTypeList {
+			return false, fmt.Errorf("PolicyType is invalid. Have: %d - Want: %d or %d", pol2.PolicyType, tools.LCPPolicyTypeAny, tools.LCPPolicyTypeList), nil
 		}
 		if pol2.LcpHashAlgMask == 0 {
 			return false, fmt.Errorf("LcpHashAlgMask is invalid. Must be greater than 0"), nil
@@ -779,8 +781,8 @@ func POIndexHasValidLCP(txtAPI hwapi.LowLevelHardwareInterfaces, p *PreSet) (boo
 		if pol2.HashAlg != p.LCPHash {
 			return false, fmt.Errorf("HashAlg has invalid value"), nil
 		}
-		if pol2.PolicyType != tools.LCPPolicyTypeAny && pol1.PolicyType != tools.LCPPolicyTypeList {
-			return false, fmt.Errorf("PolicyType is invalid. Have: %d - Want: %d or %d", pol1.PolicyType, tools.LCPPolicyTypeAny, tools.LCPPolicyTypeList), nil
+		if pol2.PolicyType != tools.LCPPolicyTypeAny && pol2.PolicyType != tools.LCPPolicyTypeList {
+			return false, fmt.Errorf("PolicyType is invalid. Have: %d - Want: %d or %d", pol2.PolicyType, tools.LCPPolicyTypeAny, tools.LCPPolicyTypeList), nil
 		}
 		if pol2.LcpHashAlgMask == 0 {
 			return false, fmt.Errorf("LcpHashAlgMask is invalid. Must be greater than 0"), nil
@@ -0,0 +1,299 @@
+package test
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	hwInternal "github.com/9elements/converged-security-suite/v2/pkg/hwapi"
+	txtprov "github.com/9elements/converged-security-suite/v2/pkg/provisioning/txt"
+	"github.com/9elements/converged-security-suite/v2/pkg/tools"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpm/simulator"
+	"github.com/9elements/go-linux-lowlevel-hw/pkg/hwapi"
+	"github.com/google/go-tpm/legacy/tpm2"
+)
+
+// testPassHash is the hash of the password of the TXT NV indices
+// provisioned in the TPM2.0 simulator.
+var testPassHash = func() []byte {
+	hash := sha256.Sum256([]byte("password"))
+	return hash[:]
+}()
+
+// tpm2NVPublic returns the TPM2B_NV_PUBLIC structure as returned by
+// ReadNVPublic. The name algorithm is SHA256.
+func tpm2NVPublic(index uint32, attrs tpm2.NVAttr, policy []byte, dataSize uint16) []byte {
+	var buf bytes.Buffer
+	for _, value := range []interface{}{
+		index, tpm2.AlgSHA256, attrs, uint16(len(policy)), policy, dataSize,
+	} {
+		if err := binary.Write(&buf, binary.BigEndian, value); err != nil {
+			panic(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func tpm2Mock() *hwInternal.MockBuilder {
+	return hwInternal.NewMockBuilder().WithTPM(hwapi.TPMVersion20)
+}
+
+// tpm2SimulatorMock declares a TPM2.0 simulator provisioned by the given
+// functions through TPM commands (for example, by package provisioning/txt).
+func tpm2SimulatorMock(provision ...func(rw io.ReadWriter) error) *hwInternal.MockBuilder {
+	sim := simulator.New()
+	for _, fn := range provision {
+		if err := fn(sim); err != nil {
+			panic(err)
+		}
+	}
+	return hwInternal.NewMockBuilder().WithTPMBackend(hwInternal.NewSimulatorBackend(sim))
+}
+
+// provisionPSIndex defines the PS index and writes the LCP policy into it.
+func provisionPSIndex(policy tools.LCPPolicy2) func(rw io.ReadWriter) error {
+	return func(rw io.ReadWriter) error {
+		if err := txtprov.DefinePSIndexTPM20(rw, testPassHash); err != nil {
+			return err
+		}
+		return txtprov.WritePSIndexTPM20(rw, &policy, testPassHash)
+	}
+}
+
+// provisionPOIndex defines the PO index and writes the LCP policy into it.
+func provisionPOIndex(policy tools.LCPPolicy2) func(rw io.ReadWriter) error {
+	return func(rw io.ReadWriter) error {
+		if err := txtprov.DefinePOIndexTPM20(rw, testPassHash); err != nil {
+			return err
+		}
+		return txtprov.WritePOIndexTPM20(rw, &policy, testPassHash)
+	}
+}
+
+// lockPlatformAuth sets the platform authorization, thus the NV is locked.
+func lockPlatformAuth(rw io.ReadWriter) error {
+	emptyAuth := tpm2.AuthCommand{Session: tpm2.HandlePasswordSession, Attributes: tpm2.AttrContinueSession}
+	return tpm2.HierarchyChangeAuth(rw, tpm2.HandlePlatform, emptyAuth, "platform")
+}
+
+// extendPCR0 extends PCR0 of the SHA1 bank.
+func extendPCR0(rw io.ReadWriter) error {
+	digest := sha1.Sum([]byte("test"))
+	return tpm2.PCRExtend(rw, 0, tpm2.AlgSHA1, digest[:], "")
+}
+
+// tpm2LCPMock declares a TPM2.0 simulator with the LCP policy provisioned
+// into the PS or PO index.
+func tpm2LCPMock(index uint32, policy tools.LCPPolicy2) *hwInternal.MockBuilder {
+	if index == tpm20POIndex {
+		return tpm2SimulatorMock(provisionPOIndex(policy))
+	}
+	return tpm2SimulatorMock(provisionPSIndex(policy))
+}
+
+// testLCPPolicy2 returns a valid LCP policy of the given type.
+func testLCPPolicy2(policyType tools.LCPPolicyType) tools.LCPPolicy2 {
+	return tools.LCPPolicy2{
+		Version:         tools.LCPPolicyVersion3,
+		HashAlg:         tpm2.AlgSHA256,
+		PolicyType:      policyType,
+		SINITMinVersion: 1,
+		LcpHashAlgMask:  1,
+		LcpSignAlgMask:  1,
+	}
+}
+
+func TestTPMConnectAndPresent(t *testing.T) {
+	runHWTestCases(t, &testtpmconnection, []hwTestCase{
+		{name: "Connected", hw: tpm2Mock(), result: ResultPass},
+		{name: "NoTPM", hw: hwInternal.NewMockBuilder(), result: ResultInternalError},
+	})
+	runHWTestCases(t, &testtpmispresent, []hwTestCase{
+		{name: "Present", hw: tpm2Mock(), preset: PreSet{TPM: hwapi.TPMVersion20}, result: ResultPass},
+		{name: "OtherVersion", hw: tpm2Mock(), preset: PreSet{TPM: hwapi.TPMVersion12}, result: ResultFail},
+		{name: "NoTPM", hw: hwInternal.NewMockBuilder(), preset: PreSet{TPM: hwapi.TPMVersion20}, result: ResultFail},
+	})
+}
+
+func TestTPMNVRAMIsLocked(t *testing.T) {
+	runHWTestCases(t, &testtpmnvramislocked, []hwTestCase{
+		{name: "Locked", hw: tpm2SimulatorMock(lockPlatformAuth), result: ResultPass},
+		{name: "NotLocked", hw: tpm2SimulatorMock(), result: ResultFail},
+		{
+			name: "NotReadable",
+			hw: hwInternal.NewMockBuilder().WithTPMBackend(&hwInternal.TPMSnapshot{
+				Version:       hwapi.TPMVersion20,
+				NVLockedError: "unable to read the TPM properties",
+			}),
+			result: ResultFail,
+		},
+	})
+}
+
+func TestPSIndexConfig(t *testing.T) {
+	runHWTestCases(t, &testpsindexconfig, []hwTestCase{
+		{
+			name:   "Valid",
+			hw:     tpm2LCPMock(tpm20PSIndex, testLCPPolicy2(tools.LCPPolicyTypeAny)),
+			result: ResultPass,
+		},
+		{
+			name:   "WrongSize",
+			hw:     tpm2Mock().WithTPMNVIndex(tpm20PSIndex, tpm2NVPublic(tpm20PSIndex, tpm20PSIndexAttr, nil, tpm20PSIndexBaseSize), nil),
+			result: ResultFail,
+		},
+		{name: "NotSet", hw: tpm2SimulatorMock(), result: ResultFail},
+		{name: "NotReadable", hw: tpm2Mock(), result: ResultInternalError},
+	})
+}
+
+func TestAUXIndex(t *testing.T) {
+	auxSize := tpm20AUXIndexBaseSize + 2*32
+	runHWTestCases(t, &testauxindexconfig, []hwTestCase{
+		{
+			name:   "Valid",
+			hw:     tpm2SimulatorMock(txtprov.DefineAUXIndexTPM20),
+			result: ResultPass,
+		},
+		{
+			name:   "WrongSize",
+			hw:     tpm2Mock().WithTPMNVIndex(tpm20AUXIndex, tpm2NVPublic(tpm20AUXIndex, tpm20AUXIndexAttr, tpm20AUXIndexHashData, auxSize-32), nil),
+			result: ResultFail,
+		},
+		{name: "NotReadable", hw: tpm2Mock(), result: ResultInternalError},
+	})
+	runHWTestCases(t, &testauxindexhashdata, []hwTestCase{
+		{
+			name:   "Valid",
+			hw:     tpm2SimulatorMock(txtprov.DefineAUXIndexTPM20),
+			result: ResultPass,
+		},
+		{
+			name:   "WrongHash",
+			hw:     tpm2Mock().WithTPMNVIndex(tpm20AUXIndex, tpm2NVPublic(tpm20AUXIndex, tpm20AUXIndexAttr, make([]byte, 32), auxSize), nil),
+			result: ResultFail,
+		},
+		{name: "TPM12", hw: hwInternal.NewMockBuilder().WithTPM(hwapi.TPMVersion12), result: ResultFail},
+		{name: "NotReadable", hw: tpm2Mock(), result: ResultInternalError},
+	})
+}
+
+func TestPOIndexConfig(t *testing.T) {
+	runHWTestCases(t, &testpoindexconfig, []hwTestCase{
+		{
+			name:   "Valid",
+			hw:     tpm2LCPMock(tpm20POIndex, testLCPPolicy2(tools.LCPPolicyTypeList)),
+			result: ResultPass,
+		},
+		{
+			name:   "WrongSize",
+			hw:     tpm2Mock().WithTPMNVIndex(tpm20POIndex, tpm2NVPublic(tpm20POIndex, tpm20POIndexAttr, nil, tpm20POIndexBaseSize+20), nil),
+			result: ResultFail,
+		},
+		{name: "NotReadable", hw: tpm2Mock(), result: ResultInternalError},
+	})
+}
+
+func TestPCR0IsSet(t *testing.T) {
+	runHWTestCases(t, &testpcr00valid, []hwTestCase{
+		{name: "Set", hw: tpm2SimulatorMock(extendPCR0), result: ResultPass},
+		{name: "Zeros", hw: tpm2SimulatorMock(), result: ResultFail},
+		{name: "NotReadable", hw: tpm2Mock(), result: ResultInternalError},
+	})
+}
+
+func TestPSIndexHasValidLCP(t *testing.T) {
+	noHashAlgMask := testLCPPolicy2(tools.LCPPolicyTypeAny)
+	noHashAlgMask.LcpHashAlgMask = 0
+	runHWTestCases(t, &testpsindexissvalid, []hwTestCase{
+		{
+			name:   "Any",
+			hw:     tpm2LCPMock(tpm20PSIndex, testLCPPolicy2(tools.LCPPolicyTypeAny)),
+			preset: PreSet{LCPHash: tpm2.AlgSHA256},
+			result: ResultPass,
+		},
+		{
+			name:   "List",
+			hw:     tpm2LCPMock(tpm20PSIndex, testLCPPolicy2(tools.LCPPolicyTypeList)),
+			preset: PreSet{LCPHash: tpm2.AlgSHA256},
+			result: ResultPass,
+		},
+		{
+			name:   "OtherHashAlg",
+			hw:     tpm2LCPMock(tpm20PSIndex, testLCPPolicy2(tools.LCPPolicyTypeAny)),
+			preset: PreSet{LCPHash: tpm2.AlgSHA1},
+			result: ResultFail,
+		},
+		{
+			name:   "NoHashAlgMask",
+			hw:     tpm2LCPMock(tpm20PSIndex, noHashAlgMask),
+			preset: PreSet{LCPHash: tpm2.AlgSHA256},
+			result: ResultFail,
+		},
+		{name: "NotSet", hw: tpm2SimulatorMock(), result: ResultFail},
+		{name: "NoTPM", hw: hwInternal.NewMockBuilder(), result: ResultFail},
+	})
+}
+
+func TestPOIndexHasValidLCP(t *testing.T) {
+	noSignAlgMask := testLCPPolicy2(tools.LCPPolicyTypeList)
+	noSignAlgMask.LcpSignAlgMask = 0
+	runHWTestCases(t, &testpoindexissvalid, []hwTestCase{
+		{
+			name:   "Valid",
+			hw:     tpm2LCPMock(tpm20POIndex, testLCPPolicy2(tools.LCPPolicyTypeList)),
+			preset: PreSet{LCPHash: tpm2.AlgSHA256},
+			result: ResultPass,
+		},
+		{
+			name:   "NoSignAlgMask",
+			hw:     tpm2LCPMock(tpm20POIndex, noSignAlgMask),
+			preset: PreSet{LCPHash: tpm2.AlgSHA256},
+			result: ResultFail,
+		},
+		{name: "NotSet", hw: tpm2SimulatorMock(), result: ResultFail},
+		{name: "NotReadable", hw: tpm2Mock(), result: ResultInternalError},
+		{name: "NoTPM", hw: hwInternal.NewMockBuilder(), result: ResultFail},
+	})
+}
+
+func TestNPWModeIsNotSetInPS(t *testing.T) {
+	npw := testLCPPolicy2(tools.LCPPolicyTypeList)
+	npw.PolicyControl = tools.LCPPolicyControlNPW
+	runHWTestCases(t, &testpsnpwmodenotactive, []hwTestCase{
+		{
+			name:   "NotSet",
+			hw:     tpm2LCPMock(tpm20PSIndex, testLCPPolicy2(tools.LCPPolicyTypeList)),
+			result: ResultPass,
+		},
+		{name: "Set", hw: tpm2LCPMock(tpm20PSIndex, npw), result: ResultFail},
+		{name: "NotReadable", hw: tpm2Mock(), result: ResultFail},
+	})
+}
+
+func TestTXTModeValid(t *testing.T) {
+	runHWTestCases(t, &testtxtmodvalid, []hwTestCase{
+		{
+			name:   "AutoPromotion",
+			hw:     tpm2LCPMock(tpm20PSIndex, testLCPPolicy2(tools.LCPPolicyTypeAny)),
+			preset: PreSet{TXTMode: tools.AutoPromotion},
+			result: ResultPass,
+		},
+		{
+			name:   "SignedPolicy",
+			hw:     tpm2LCPMock(tpm20PSIndex, testLCPPolicy2(tools.LCPPolicyTypeList)),
+			preset: PreSet{TXTMode: tools.SignedPolicy},
+			result: ResultPass,
+		},
+		{
+			name:   "OtherMode",
+			hw:     tpm2LCPMock(tpm20PSIndex, testLCPPolicy2(tools.LCPPolicyTypeAny)),
+			preset: PreSet{TXTMode: tools.SignedPolicy},
+			result: ResultInternalError,
+		},
+		{name: "NotReadable", hw: tpm2Mock(), preset: PreSet{TXTMode: tools.AutoPromotion}, result: ResultInternalError},
+	})
+}
@@ -109,6 +109,7 @@ type ACMStatus struct {
 
 // TXTBiosData holds the decoded BIOSDATA regions as read from TXT config space
 type TXTBiosData struct {
+	Size          uint64
 	Version       uint32
 	BiosSinitSize uint32
 	Reserved1     uint64
@@ -116,6 +117,8 @@ type TXTBiosData struct {
 	NumLogProcs   uint32
 	SinitFlags    uint32
 	MleFlags      *TXTBiosMLEFlags
+	// ExtDataElements are present for versions 4 and later
+	ExtDataElements []TXTHeapExtElement
 }
 
 // TXTBiosMLEFlags holds the decoded BIOSDATA region MLE flags as read from TXT config space
@@ -307,65 +310,11 @@ func ParseTXTRegs(data []byte) (TXTRegisterSpace, error) {
 
 // ParseBIOSDataRegion decodes a raw copy of the BIOSDATA region
 func ParseBIOSDataRegion(heap []byte) (TXTBiosData, error) {
-	var ret TXTBiosData
-	var biosDataSize uint64
-
-	buf := bytes.NewReader(heap)
-	// TXT Heap Bios Data size
-	err := binary.Read(buf, binary.LittleEndian, &biosDataSize)
-	if err != nil {
-		return ret, err
-	}
-
-	// Bios Data
-	err = binary.Read(buf, binary.LittleEndian, &ret.Version)
-	if err != nil {
-		return ret, err
-	}
-
-	err = binary.Read(buf, binary.LittleEndian, &ret.BiosSinitSize)
-	if err != nil {
-		return ret, err
+	table, _, ok := nextTXTHeapTable(heap)
+	if !ok {
+		return TXTBiosData{}, fmt.Errorf("invalid size of the BIOSDATA region")
 	}
-
-	err = binary.Read(buf, binary.LittleEndian, &ret.Reserved1)
-	if err != nil {
-		return ret, err
-	}
-
-	err = binary.Read(buf, binary.LittleEndian, &ret.Reserved2)
-	if err != nil {
-		return ret, err
-	}
-
-	err = binary.Read(buf, binary.LittleEndian, &ret.NumLogProcs)
-	if err != nil {
-		return ret, err
-	}
-
-	if ret.Version >= 3 && ret.Version < 5 {
-		err = binary.Read(buf, binary.LittleEndian, &ret.SinitFlags)
-		if err != nil {
-			return ret, err
-		}
-	}
-
-	if ret.Version >= 5 {
-		var mleFlags uint32
-		var flags TXTBiosMLEFlags
-
-		err = binary.Read(buf, binary.LittleEndian, &mleFlags)
-		if err != nil {
-			return ret, err
-		}
-
-		flags.SupportsACPIPPI = mleFlags&1 != 0
-		flags.IsClientState = mleFlags&6 == 2
-		flags.IsServerState = mleFlags&6 == 4
-		ret.MleFlags = &flags
-	}
-
-	return ret, nil
+	return parseBIOSDataTable(table)
 }
 
 func readTXTStatus(data []byte) (TXTStatus, error) {
@@ -0,0 +1,688 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/9elements/go-linux-lowlevel-hw/pkg/hwapi"
+)
+
+// TXTHeapExtElementType is the type of an extended data element of the
+// BiosData and OsSinitData tables of the TXT heap
+type TXTHeapExtElementType uint32
+
+// Extended data element types as defined in the Intel TXT Software
+// Development Guide, Appendix C
+const (
+	TXTHeapExtElementEnd                TXTHeapExtElementType = 0
+	TXTHeapExtElementBIOSSpecVersion    TXTHeapExtElementType = 1
+	TXTHeapExtElementACM                TXTHeapExtElementType = 2
+	TXTHeapExtElementSTM                TXTHeapExtElementType = 3
+	TXTHeapExtElementCustom             TXTHeapExtElementType = 4
+	TXTHeapExtElementTPMEventLogPointer TXTHeapExtElementType = 5
+	TXTHeapExtElementMADT               TXTHeapExtElementType = 6
+	TXTHeapExtElementEventLogPointer2   TXTHeapExtElementType = 7
+	TXTHeapExtElementEventLogPointer2v1 TXTHeapExtElementType = 8
+	TXTHeapExtElementMCFG               TXTHeapExtElementType = 9
+	TXTHeapExtElementTPRRequest         TXTHeapExtElementType = 13
+	TXTHeapExtElementDTPR               TXTHeapExtElementType = 14
+	TXTHeapExtElementCEDT               TXTHeapExtElementType = 15
+)
+
+// String implements fmt.Stringer
+func (t TXTHeapExtElementType) String() string {
+	switch t {
+	case TXTHeapExtElementEnd:
+		return "END"
+	case TXTHeapExtElementBIOSSpecVersion:
+		return "BIOS_SPEC_VER"
+	case TXTHeapExtElementACM:
+		return "ACM"
+	case TXTHeapExtElementSTM:
+		return "STM"
+	case TXTHeapExtElementCustom:
+		return "CUSTOM"
+	case TXTHeapExtElementTPMEventLogPointer:
+		return "TPM_EVENT_LOG_PTR"
+	case TXTHeapExtElementMADT:
+		return "MADT"
+	case TXTHeapExtElementEventLogPointer2:
+		return "EVENT_LOG_POINTER2"
+	case TXTHeapExtElementEventLogPointer2v1:
+		return "EVENT_LOG_POINTER2_1"
+	case TXTHeapExtElementMCFG:
+		return "MCFG"
+	case TXTHeapExtElementTPRRequest:
+		return "TPR_REQ"
+	case TXTHeapExtElementDTPR:
+		return "DTPR"
+	case TXTHeapExtElementCEDT:
+		return "CEDT"
+	}
+	return fmt.Sprintf("UNKNOWN_%d", uint32(t))
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (t TXTHeapExtElementType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// TXTHeapDigest is a SHA1 digest stored in the SinitMleData table
+type TXTHeapDigest [20]byte
+
+// String implements fmt.Stringer
+func (d TXTHeapDigest) String() string {
+	return hex.EncodeToString(d[:])
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (d TXTHeapDigest) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// TXTHeapBIOSSpecVersion is the payload of the BIOS_SPEC_VER element
+type TXTHeapBIOSSpecVersion struct {
+	Major    uint16
+	Minor    uint16
+	Revision uint16
+}
+
+// TXTHeapEventLogDescriptor describes a TPM 2.0 event log container, it
+// is the payload of the EVENT_LOG_POINTER2_1 element and an entry of
+// the EVENT_LOG_POINTER2 element
+type TXTHeapEventLogDescriptor struct {
+	// HashAlg is set only for EVENT_LOG_POINTER2 elements
+	HashAlg                     uint16
+	PhysicalAddress             uint64
+	AllocatedEventContainerSize uint32
+	FirstRecordOffset           uint32
+	NextRecordOffset            uint32
+}
+
+// TXTHeapExtElement is an extended data element of the BiosData or
+// OsSinitData table
+type TXTHeapExtElement struct {
+	Type TXTHeapExtElementType
+	// Data is the payload of the element without the type and size fields.
+	// For the MADT, MCFG and CEDT elements it is a copy of the ACPI table.
+	Data []byte
+
+	// The decoded payload of the known element types
+	BIOSSpecVersion    *TXTHeapBIOSSpecVersion     `json:",omitempty"`
+	ACMAddresses       []uint64                    `json:",omitempty"`
+	CustomUUID         string                      `json:",omitempty"`
+	EventLogPointer    *uint64                     `json:",omitempty"`
+	EventLogDescriptor []TXTHeapEventLogDescriptor `json:",omitempty"`
+}
+
+// TXTOsMleData holds the OsMleData table of the TXT heap. The table is
+// defined by the MLE and is opaque for TXT.
+type TXTOsMleData struct {
+	Size uint64
+	Data []byte
+}
+
+// TXTOsSinitData holds the decoded OsSinitData table of the TXT heap
+type TXTOsSinitData struct {
+	Size             uint64
+	Version          uint32
+	Flags            uint32
+	MLEPageTableBase uint64
+	MLESize          uint64
+	MLEHeaderBase    uint64
+	PMRLowBase       uint64
+	PMRLowSize       uint64
+	PMRHighBase      uint64
+	PMRHighSize      uint64
+	LCPPOBase        uint64
+	LCPPOSize        uint64
+	Capabilities     uint32
+	// EFIRSDPPointer is set for versions 5 and later
+	EFIRSDPPointer  uint64
+	ExtDataElements []TXTHeapExtElement `json:",omitempty"`
+}
+
+// TXTSinitMDR is a SINIT memory descriptor record of the SinitMleData table
+type TXTSinitMDR struct {
+	Base   uint64
+	Length uint64
+	Type   uint8
+}
+
+// TXTSinitMleData holds the decoded SinitMleData table of the TXT heap.
+// It is written by SINIT and is present only after a measured launch.
+type TXTSinitMleData struct {
+	Size                    uint64
+	Version                 uint32
+	BiosACMID               TXTHeapDigest
+	EdxSenterFlags          uint32
+	MsegValid               uint64
+	SinitHash               TXTHeapDigest
+	MleHash                 TXTHeapDigest
+	StmHash                 TXTHeapDigest
+	LcpPolicyHash           TXTHeapDigest
+	PolicyControl           uint32
+	RlpWakeupAddr           uint32
+	NumberOfSinitMdrs       uint32
+	SinitMdrTableOffset     uint32
+	SinitVtdDmarTableSize   uint32
+	SinitVtdDmarTableOffset uint32
+	// ProcScrtmStatus is set for versions 8 and later
+	ProcScrtmStatus uint32
+	SinitMdrs       []TXTSinitMDR `json:",omitempty"`
+	// SinitVtdDmarTable is the copy of the ACPI DMAR made by SINIT
+	SinitVtdDmarTable []byte `json:",omitempty"`
+}
+
+// TXTHeap holds the decoded TXT heap. The tables which are not initialized
+// yet (like SinitMleData before a measured launch) are nil.
+type TXTHeap struct {
+	Size         uint64
+	BiosData     *TXTBiosData
+	OsMleData    *TXTOsMleData
+	OsSinitData  *TXTOsSinitData
+	SinitMleData *TXTSinitMleData
+}
+
+// txtBiosDataFields is the part of the BiosData table which is common
+// for all versions
+type txtBiosDataFields struct {
+	Version       uint32
+	BiosSinitSize uint32
+	Reserved1     uint64
+	Reserved2     uint64
+	NumLogProcs   uint32
+}
+
+type txtOsSinitDataFields struct {
+	Version          uint32
+	Flags            uint32
+	MLEPageTableBase uint64
+	MLESize          uint64
+	MLEHeaderBase    uint64
+	PMRLowBase       uint64
+	PMRLowSize       uint64
+	PMRHighBase      uint64
+	PMRHighSize      uint64
+	LCPPOBase        uint64
+	LCPPOSize        uint64
+	Capabilities     uint32
+}
+
+type txtSinitMleDataFields struct {
+	Version                 uint32
+	BiosACMID               TXTHeapDigest
+	EdxSenterFlags          uint32
+	MsegValid               uint64
+	SinitHash               TXTHeapDigest
+	MleHash                 TXTHeapDigest
+	StmHash                 TXTHeapDigest
+	LcpPolicyHash           TXTHeapDigest
+	PolicyControl           uint32
+	RlpWakeupAddr           uint32
+	Reserved                uint32
+	NumberOfSinitMdrs       uint32
+	SinitMdrTableOffset     uint32
+	SinitVtdDmarTableSize   uint32
+	SinitVtdDmarTableOffset uint32
+}
+
+type txtSinitMDRFields struct {
+	Base     uint64
+	Length   uint64
+	Type     uint8
+	Reserved [7]uint8
+}
+
+// FetchTXTHeap returns a raw copy of the TXT heap
+func FetchTXTHeap(txtAPI hwapi.LowLevelHardwareInterfaces) ([]byte, error) {
+	buf, err := FetchTXTRegs(txtAPI)
+	if err != nil {
+		return nil, err
+	}
+	regs, err := ParseTXTRegs(buf)
+	if err != nil {
+		return nil, err
+	}
+	heap := make([]byte, regs.HeapSize)
+	if err := txtAPI.ReadPhysBuf(int64(regs.HeapBase), heap); err != nil {
+		return nil, err
+	}
+	return heap, nil
+}
+
+// ParseTXTHeap decodes a raw copy of the TXT heap. Each table of the heap
+// starts with its 64-bit size (including the size field itself). The
+// BiosData table is mandatory, the following tables are decoded only
+// if they are initialized.
+func ParseTXTHeap(heap []byte) (*TXTHeap, error) {
+	ret := &TXTHeap{Size: uint64(len(heap))}
+
+	table, rest, ok := nextTXTHeapTable(heap)
+	if !ok {
+		return nil, fmt.Errorf("TXT heap has no valid BiosData table")
+	}
+	biosData, err := parseBIOSDataTable(table)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse BiosData: %w", err)
+	}
+	ret.BiosData = &biosData
+
+	if table, rest, ok = nextTXTHeapTable(rest); !ok {
+		return ret, nil
+	}
+	ret.OsMleData = &TXTOsMleData{
+		Size: uint64(len(table)),
+		Data: table[8:],
+	}
+
+	if table, rest, ok = nextTXTHeapTable(rest); !ok {
+		return ret, nil
+	}
+	if ret.OsSinitData, err = parseOsSinitDataTable(table); err != nil {
+		return nil, fmt.Errorf("unable to parse OsSinitData: %w", err)
+	}
+
+	if table, _, ok = nextTXTHeapTable(rest); !ok {
+		return ret, nil
+	}
+	if ret.SinitMleData, err = parseSinitMleDataTable(table); err != nil {
+		return nil, fmt.Errorf("unable to parse SinitMleData: %w", err)
+	}
+	return ret, nil
+}
+
+// nextTXTHeapTable returns the table at the beginning of data and the
+// rest of data
+func nextTXTHeapTable(data []byte) ([]byte, []byte, bool) {
+	if len(data) < 8 {
+		return nil, nil, false
+	}
+	size := binary.LittleEndian.Uint64(data)
+	if size < 8 || size > uint64(len(data)) {
+		return nil, nil, false
+	}
+	return data[:size], data[size:], true
+}
+
+func parseBIOSDataTable(table []byte) (TXTBiosData, error) {
+	var ret TXTBiosData
+	var fields txtBiosDataFields
+
+	buf := bytes.NewReader(table[8:])
+	if err := binary.Read(buf, binary.LittleEndian, &fields); err != nil {
+		return ret, err
+	}
+	ret.Size = uint64(len(table))
+	ret.Version = fields.Version
+	ret.BiosSinitSize = fields.BiosSinitSize
+	ret.Reserved1 = fields.Reserved1
+	ret.Reserved2 = fields.Reserved2
+	ret.NumLogProcs = fields.NumLogProcs
+
+	// SinitFlags is used by versions 3 and 4 only, but it keeps its place
+	// in the later versions (see bios_data_t of tboot)
+	if ret.Version >= 3 {
+		if err := binary.Read(buf, binary.LittleEndian, &ret.SinitFlags); err != nil {
+			return ret, err
+		}
+	}
+
+	if ret.Version >= 5 {
+		var mleFlags uint32
+		var flags TXTBiosMLEFlags
+
+		if err := binary.Read(buf, binary.LittleEndian, &mleFlags); err != nil {
+			return ret, err
+		}
+
+		flags.SupportsACPIPPI = mleFlags&1 != 0
+		flags.IsClientState = mleFlags&6 == 2
+		flags.IsServerState = mleFlags&6 == 4
+		ret.MleFlags = &flags
+	}
+
+	if ret.Version >= 4 {
+		elements, err := parseTXTHeapExtElements(table[len(table)-buf.Len():])
+		if err != nil {
+			return ret, err
+		}
+		ret.ExtDataElements = elements
+	}
+
+	return ret, nil
+}
+
+func parseOsSinitDataTable(table []byte) (*TXTOsSinitData, error) {
+	var fields txtOsSinitDataFields
+
+	buf := bytes.NewReader(table[8:])
+	if err := binary.Read(buf, binary.LittleEndian, &fields); err != nil {
+		return nil, err
+	}
+	ret := &TXTOsSinitData{
+		Size:             uint64(len(table)),
+		Version:          fields.Version,
+		Flags:            fields.Flags,
+		MLEPageTableBase: fields.MLEPageTableBase,
+		MLESize:          fields.MLESize,
+		MLEHeaderBase:    fields.MLEHeaderBase,
+		PMRLowBase:       fields.PMRLowBase,
+		PMRLowSize:       fields.PMRLowSize,
+		PMRHighBase:      fields.PMRHighBase,
+		PMRHighSize:      fields.PMRHighSize,
+		LCPPOBase:        fields.LCPPOBase,
+		LCPPOSize:        fields.LCPPOSize,
+		Capabilities:     fields.Capabilities,
+	}
+
+	if ret.Version >= 5 {
+		if err := binary.Read(buf, binary.LittleEndian, &ret.EFIRSDPPointer); err != nil {
+			return nil, err
+		}
+	}
+
+	if ret.Version >= 6 {
+		elements, err := parseTXTHeapExtElements(table[len(table)-buf.Len():])
+		if err != nil {
+			return nil, err
+		}
+		ret.ExtDataElements = elements
+	}
+
+	return ret, nil
+}
+
+func parseSinitMleDataTable(table []byte) (*TXTSinitMleData, error) {
+	var fields txtSinitMleDataFields
+
+	buf := bytes.NewReader(table[8:])
+	if err := binary.Read(buf, binary.LittleEndian, &fields); err != nil {
+		return nil, err
+	}
+	ret := &TXTSinitMleData{
+		Size:                    uint64(len(table)),
+		Version:                 fields.Version,
+		BiosACMID:               fields.BiosACMID,
+		EdxSenterFlags:          fields.EdxSenterFlags,
+		MsegValid:               fields.MsegValid,
+		SinitHash:               fields.SinitHash,
+		MleHash:                 fields.MleHash,
+		StmHash:                 fields.StmHash,
+		LcpPolicyHash:           fields.LcpPolicyHash,
+		PolicyControl:           fields.PolicyControl,
+		RlpWakeupAddr:           fields.RlpWakeupAddr,
+		NumberOfSinitMdrs:       fields.NumberOfSinitMdrs,
+		SinitMdrTableOffset:     fields.SinitMdrTableOffset,
+		SinitVtdDmarTableSize:   fields.SinitVtdDmarTableSize,
+		SinitVtdDmarTableOffset: fields.SinitVtdDmarTableOffset,
+	}
+
+	if ret.Version >= 8 {
+		if err := binary.Read(buf, binary.LittleEndian, &ret.ProcScrtmStatus); err != nil {
+			return nil, err
+		}
+	}
+
+	// the offsets are relative to the beginning of the table (its size field)
+	if ret.NumberOfSinitMdrs > 0 {
+		mdrSize := uint64(binary.Size(txtSinitMDRFields{}))
+		start := uint64(ret.SinitMdrTableOffset)
+		end := start + uint64(ret.NumberOfSinitMdrs)*mdrSize
+		if start < 8 || end > uint64(len(table)) {
+			return nil, fmt.Errorf("SINIT MDR table [0x%x:0x%x] is out of the table of size 0x%x", start, end, len(table))
+		}
+		mdrs := make([]txtSinitMDRFields, ret.NumberOfSinitMdrs)
+		if err := binary.Read(bytes.NewReader(table[start:end]), binary.LittleEndian, mdrs); err != nil {
+			return nil, err
+		}
+		for _, mdr := range mdrs {
+			ret.SinitMdrs = append(ret.SinitMdrs, TXTSinitMDR{
+				Base:   mdr.Base,
+				Length: mdr.Length,
+				Type:   mdr.Type,
+			})
+		}
+	}
+
+	if ret.SinitVtdDmarTableSize > 0 {
+		start := uint64(ret.SinitVtdDmarTableOffset)
+		end := start + uint64(ret.SinitVtdDmarTableSize)
+		if start < 8 || end > uint64(len(table)) {
+			return nil, fmt.Errorf("DMAR table [0x%x:0x%x] is out of the table of size 0x%x", start, end, len(table))
+		}
+		ret.SinitVtdDmarTable = table[start:end]
+	}
+
+	return ret, nil
+}
+
+// parseTXTHeapExtElements decodes the extended data elements till the END
+// element or the end of data
+func parseTXTHeapExtElements(data []byte) ([]TXTHeapExtElement, error) {
+	var ret []TXTHeapExtElement
+	for len(data) >= 8 {
+		elementType := TXTHeapExtElementType(binary.LittleEndian.Uint32(data))
+		size := binary.LittleEndian.Uint32(data[4:])
+		if elementType == TXTHeapExtElementEnd {
+			break
+		}
+		if size < 8 || uint64(size) > uint64(len(data)) {
+			return nil, fmt.Errorf("invalid size %d of the extended element %s", size, elementType)
+		}
+		element := TXTHeapExtElement{
+			Type: elementType,
+			Data: data[8:size],
+		}
+		if err := element.decode(); err != nil {
+			return nil, fmt.Errorf("unable to parse the extended element %s: %w", elementType, err)
+		}
+		ret = append(ret, element)
+		data = data[size:]
+	}
+	return ret, nil
+}
+
+func (e *TXTHeapExtElement) decode() error {
+	buf := bytes.NewReader(e.Data)
+	switch e.Type {
+	case TXTHeapExtElementBIOSSpecVersion:
+		e.BIOSSpecVersion = &TXTHeapBIOSSpecVersion{}
+		return binary.Read(buf, binary.LittleEndian, e.BIOSSpecVersion)
+	case TXTHeapExtElementACM:
+		var count uint32
+		if err := binary.Read(buf, binary.LittleEndian, &count); err != nil {
+			return err
+		}
+		if uint64(count)*8 > uint64(buf.Len()) {
+			return fmt.Errorf("%d ACM addresses do not fit into %d bytes", count, buf.Len())
+		}
+		e.ACMAddresses = make([]uint64, count)
+		return binary.Read(buf, binary.LittleEndian, e.ACMAddresses)
+	case TXTHeapExtElementCustom:
+		var uuid [16]byte
+		if err := binary.Read(buf, binary.LittleEndian, &uuid); err != nil {
+			return err
+		}
+		e.CustomUUID = fmt.Sprintf("%08x-%04x-%04x-%x-%x",
+			binary.LittleEndian.Uint32(uuid[0:]),
+			binary.LittleEndian.Uint16(uuid[4:]),
+			binary.LittleEndian.Uint16(uuid[6:]),
+			uuid[8:10], uuid[10:])
+	case TXTHeapExtElementTPMEventLogPointer:
+		e.EventLogPointer = new(uint64)
+		return binary.Read(buf, binary.LittleEndian, e.EventLogPointer)
+	case TXTHeapExtElementEventLogPointer2:
+		var count uint32
+		if err := binary.Read(buf, binary.LittleEndian, &count); err != nil {
+			return err
+		}
+		for idx := uint32(0); idx < count; idx++ {
+			var fields struct {
+				HashAlg                     uint16
+				Reserved                    uint16
+				PhysicalAddress             uint64
+				AllocatedEventContainerSize uint32
+				FirstRecordOffset           uint32
+				NextRecordOffset            uint32
+			}
+			if err := binary.Read(buf, binary.LittleEndian, &fields); err != nil {
+				return err
+			}
+			e.EventLogDescriptor = append(e.EventLogDescriptor, TXTHeapEventLogDescriptor{
+				HashAlg:                     fields.HashAlg,
+				PhysicalAddress:             fields.PhysicalAddress,
+				AllocatedEventContainerSize: fields.AllocatedEventContainerSize,
+				FirstRecordOffset:           fields.FirstRecordOffset,
+				NextRecordOffset:            fields.NextRecordOffset,
+			})
+		}
+	case TXTHeapExtElementEventLogPointer2v1:
+		var fields struct {
+			PhysicalAddress             uint64
+			AllocatedEventContainerSize uint32
+			FirstRecordOffset           uint32
+			NextRecordOffset            uint32
+		}
+		if err := binary.Read(buf, binary.LittleEndian, &fields); err != nil {
+			return err
+		}
+		e.EventLogDescriptor = []TXTHeapEventLogDescriptor{{
+			PhysicalAddress:             fields.PhysicalAddress,
+			AllocatedEventContainerSize: fields.AllocatedEventContainerSize,
+			FirstRecordOffset:           fields.FirstRecordOffset,
+			NextRecordOffset:            fields.NextRecordOffset,
+		}}
+	}
+	return nil
+}
+
+// SinitMleDataSpace returns the amount of bytes of the heap which are left
+// for the SinitMleData table
+func (h *TXTHeap) SinitMleDataSpace() uint64 {
+	used := h.BiosData.Size
+	if h.OsMleData != nil {
+		used += h.OsMleData.Size
+	}
+	if h.OsSinitData != nil {
+		used += h.OsSinitData.Size
+	}
+	if used > h.Size {
+		return 0
+	}
+	return h.Size - used
+}
+
+// JSON returns the JSON representation of the TXT heap
+func (h *TXTHeap) JSON() ([]byte, error) {
+	return json.MarshalIndent(h, "", "  ")
+}
+
+// PrettyString returns a human readable representation of the TXT heap
+func (h *TXTHeap) PrettyString() string {
+	var s strings.Builder
+	fmt.Fprintf(&s, "TXT heap: 0x%x bytes\n", h.Size)
+
+	b := h.BiosData
+	fmt.Fprintf(&s, "BiosData: 0x%x bytes\n", b.Size)
+	fmt.Fprintf(&s, "   Version: %d\n", b.Version)
+	fmt.Fprintf(&s, "   BiosSinitSize: 0x%x\n", b.BiosSinitSize)
+	fmt.Fprintf(&s, "   NumLogProcs: %d\n", b.NumLogProcs)
+	if b.Version >= 3 && b.Version < 5 {
+		fmt.Fprintf(&s, "   SinitFlags: 0x%08x\n", b.SinitFlags)
+	}
+	if b.MleFlags != nil {
+		fmt.Fprintf(&s, "   MleFlags: SupportsACPIPPI=%t IsClientState=%t IsServerState=%t\n",
+			b.MleFlags.SupportsACPIPPI, b.MleFlags.IsClientState, b.MleFlags.IsServerState)
+	}
+	writeTXTHeapExtElements(&s, b.ExtDataElements)
+
+	if h.OsMleData == nil {
+		s.WriteString("OsMleData: not initialized\n")
+	} else {
+		fmt.Fprintf(&s, "OsMleData: 0x%x bytes\n", h.OsMleData.Size)
+	}
+
+	if o := h.OsSinitData; o == nil {
+		s.WriteString("OsSinitData: not initialized\n")
+	} else {
+		fmt.Fprintf(&s, "OsSinitData: 0x%x bytes\n", o.Size)
+		fmt.Fprintf(&s, "   Version: %d\n", o.Version)
+		fmt.Fprintf(&s, "   Flags: 0x%08x\n", o.Flags)
+		fmt.Fprintf(&s, "   MLE page table base: 0x%x\n", o.MLEPageTableBase)
+		fmt.Fprintf(&s, "   MLE size: 0x%x\n", o.MLESize)
+		fmt.Fprintf(&s, "   MLE header base: 0x%x\n", o.MLEHeaderBase)
+		fmt.Fprintf(&s, "   PMR low: base 0x%x, size 0x%x\n", o.PMRLowBase, o.PMRLowSize)
+		fmt.Fprintf(&s, "   PMR high: base 0x%x, size 0x%x\n", o.PMRHighBase, o.PMRHighSize)
+		fmt.Fprintf(&s, "   LCP PO: base 0x%x, size 0x%x\n", o.LCPPOBase, o.LCPPOSize)
+		fmt.Fprintf(&s, "   Capabilities: 0x%08x\n", o.Capabilities)
+		if o.Version >= 5 {
+			fmt.Fprintf(&s, "   EFI RSDP pointer: 0x%x\n", o.EFIRSDPPointer)
+		}
+		writeTXTHeapExtElements(&s, o.ExtDataElements)
+	}
+
+	if m := h.SinitMleData; m == nil {
+		s.WriteString("SinitMleData: not initialized\n")
+	} else {
+		fmt.Fprintf(&s, "SinitMleData: 0x%x bytes\n", m.Size)
+		fmt.Fprintf(&s, "   Version: %d\n", m.Version)
+		fmt.Fprintf(&s, "   BIOS ACM ID: %s\n", m.BiosACMID)
+		fmt.Fprintf(&s, "   EDX SENTER flags: 0x%08x\n", m.EdxSenterFlags)
+		fmt.Fprintf(&s, "   MSEG valid: 0x%x\n", m.MsegValid)
+		fmt.Fprintf(&s, "   SINIT hash: %s\n", m.SinitHash)
+		fmt.Fprintf(&s, "   MLE hash: %s\n", m.MleHash)
+		fmt.Fprintf(&s, "   STM hash: %s\n", m.StmHash)
+		fmt.Fprintf(&s, "   LCP policy hash: %s\n", m.LcpPolicyHash)
+		fmt.Fprintf(&s, "   Policy control: 0x%08x\n", m.PolicyControl)
+		fmt.Fprintf(&s, "   RLP wakeup address: 0x%x\n", m.RlpWakeupAddr)
+		if m.Version >= 8 {
+			fmt.Fprintf(&s, "   Processor S-CRTM status: 0x%08x\n", m.ProcScrtmStatus)
+		}
+		fmt.Fprintf(&s, "   SINIT MDRs: %d\n", len(m.SinitMdrs))
+		for idx, mdr := range m.SinitMdrs {
+			fmt.Fprintf(&s, "      %d: base 0x%x, length 0x%x, type %d\n", idx, mdr.Base, mdr.Length, mdr.Type)
+		}
+		fmt.Fprintf(&s, "   SINIT VT-d DMAR table: 0x%x bytes\n", len(m.SinitVtdDmarTable))
+	}
+	return s.String()
+}
+
+func writeTXTHeapExtElements(s *strings.Builder, elements []TXTHeapExtElement) {
+	if len(elements) == 0 {
+		return
+	}
+	s.WriteString("   Extended data elements:\n")
+	for _, e := range elements {
+		fmt.Fprintf(s, "      %s: 0x%x bytes\n", e.Type, len(e.Data))
+		switch {
+		case e.BIOSSpecVersion != nil:
+			fmt.Fprintf(s, "         Version: %d.%d.%d\n", e.BIOSSpecVersion.Major, e.BIOSSpecVersion.Minor, e.BIOSSpecVersion.Revision)
+		case e.ACMAddresses != nil:
+			for _, addr := range e.ACMAddresses {
+				fmt.Fprintf(s, "         ACM: 0x%x\n", addr)
+			}
+		case e.CustomUUID != "":
+			fmt.Fprintf(s, "         UUID: %s\n", e.CustomUUID)
+		case e.EventLogPointer != nil:
+			fmt.Fprintf(s, "         Event log: 0x%x\n", *e.EventLogPointer)
+		case e.EventLogDescriptor != nil:
+			for _, d := range e.EventLogDescriptor {
+				fmt.Fprintf(s, "         Event log: 0x%x, size 0x%x, first record 0x%x, next record 0x%x",
+					d.PhysicalAddress, d.AllocatedEventContainerSize, d.FirstRecordOffset, d.NextRecordOffset)
+				if e.Type == TXTHeapExtElementEventLogPointer2 {
+					fmt.Fprintf(s, ", hash algorithm 0x%04x", d.HashAlg)
+				}
+				s.WriteString("\n")
+			}
+		case e.Type == TXTHeapExtElementMADT || e.Type == TXTHeapExtElementMCFG || e.Type == TXTHeapExtElementCEDT:
+			if len(e.Data) >= 4 {
+				fmt.Fprintf(s, "         ACPI table: %q\n", e.Data[:4])
+			}
+		}
+	}
+}
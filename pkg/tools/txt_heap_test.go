@@ -0,0 +1,200 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// txtHeapBuilder builds a synthetic TXT heap
+type txtHeapBuilder struct {
+	buf bytes.Buffer
+}
+
+func (b *txtHeapBuilder) table(fields ...interface{}) []byte {
+	var data bytes.Buffer
+	for _, field := range fields {
+		if err := binary.Write(&data, binary.LittleEndian, field); err != nil {
+			panic(err)
+		}
+	}
+	table := make([]byte, 8+data.Len())
+	binary.LittleEndian.PutUint64(table, uint64(len(table)))
+	copy(table[8:], data.Bytes())
+	b.buf.Write(table)
+	return table
+}
+
+func extElement(elementType TXTHeapExtElementType, payload ...interface{}) []byte {
+	var data bytes.Buffer
+	for _, field := range payload {
+		if err := binary.Write(&data, binary.LittleEndian, field); err != nil {
+			panic(err)
+		}
+	}
+	element := make([]byte, 8+data.Len())
+	binary.LittleEndian.PutUint32(element, uint32(elementType))
+	binary.LittleEndian.PutUint32(element[4:], uint32(len(element)))
+	copy(element[8:], data.Bytes())
+	return element
+}
+
+func testTXTHeap(withSinitMleData bool) []byte {
+	var b txtHeapBuilder
+
+	madt := append([]byte("APIC"), make([]byte, 0x28)...)
+	b.table(
+		txtBiosDataFields{Version: 6, BiosSinitSize: 0x50000, NumLogProcs: 8},
+		uint32(0),   // SinitFlags, reserved
+		uint32(4|1), // MleFlags: server state, ACPI PPI
+		extElement(TXTHeapExtElementBIOSSpecVersion, TXTHeapBIOSSpecVersion{Major: 2, Minor: 1}),
+		extElement(TXTHeapExtElementACM, uint32(2), []uint64{0xFFF00000, 0xFFF40000}),
+		extElement(TXTHeapExtElementEventLogPointer2v1, uint64(0x7A000000), uint32(0x10000), uint32(0), uint32(0x200)),
+		extElement(TXTHeapExtElementMADT, madt),
+		extElement(TXTHeapExtElementEnd),
+	)
+	b.table(uint32(3), make([]byte, 0x40))
+	b.table(
+		txtOsSinitDataFields{Version: 7, MLESize: 0x100000, PMRLowSize: 0x80000000, Capabilities: 0x2},
+		uint64(0x7FFE0000),
+		extElement(TXTHeapExtElementEnd),
+	)
+	if withSinitMleData {
+		mdrTableOffset := uint32(8 + binary.Size(txtSinitMleDataFields{}) + 4)
+		b.table(
+			txtSinitMleDataFields{
+				Version:                 9,
+				SinitHash:               TXTHeapDigest{0xAA},
+				NumberOfSinitMdrs:       2,
+				SinitMdrTableOffset:     mdrTableOffset,
+				SinitVtdDmarTableSize:   4,
+				SinitVtdDmarTableOffset: mdrTableOffset + 2*24,
+			},
+			uint32(0x1), // ProcScrtmStatus
+			txtSinitMDRFields{Base: 0, Length: 0xA0000, Type: 0},
+			txtSinitMDRFields{Base: 0x100000, Length: 0x7FF00000, Type: 1},
+			[]byte("DMAR"),
+		)
+	}
+	heap := make([]byte, 0x1000)
+	copy(heap, b.buf.Bytes())
+	return heap
+}
+
+func TestParseTXTHeap(t *testing.T) {
+	heap, err := ParseTXTHeap(testTXTHeap(true))
+	if err != nil {
+		t.Fatalf("ParseTXTHeap() failed: %v", err)
+	}
+
+	biosData := heap.BiosData
+	if biosData.Version != 6 || biosData.BiosSinitSize != 0x50000 || biosData.NumLogProcs != 8 {
+		t.Errorf("unexpected BiosData: %+v", biosData)
+	}
+	if biosData.MleFlags == nil || !biosData.MleFlags.IsServerState || !biosData.MleFlags.SupportsACPIPPI {
+		t.Errorf("unexpected MleFlags: %+v", biosData.MleFlags)
+	}
+	if len(biosData.ExtDataElements) != 4 {
+		t.Fatalf("expected 4 extended elements, got %d", len(biosData.ExtDataElements))
+	}
+	if v := biosData.ExtDataElements[0].BIOSSpecVersion; v == nil || v.Major != 2 || v.Minor != 1 {
+		t.Errorf("unexpected BIOS spec version: %+v", v)
+	}
+	if addrs := biosData.ExtDataElements[1].ACMAddresses; len(addrs) != 2 || addrs[1] != 0xFFF40000 {
+		t.Errorf("unexpected ACM addresses: %v", addrs)
+	}
+	if d := biosData.ExtDataElements[2].EventLogDescriptor; len(d) != 1 || d[0].PhysicalAddress != 0x7A000000 || d[0].NextRecordOffset != 0x200 {
+		t.Errorf("unexpected event log descriptor: %+v", d)
+	}
+	if madt := biosData.ExtDataElements[3]; madt.Type != TXTHeapExtElementMADT || string(madt.Data[:4]) != "APIC" {
+		t.Errorf("unexpected MADT element: %+v", madt)
+	}
+
+	if heap.OsMleData == nil || len(heap.OsMleData.Data) != 0x44 {
+		t.Errorf("unexpected OsMleData: %+v", heap.OsMleData)
+	}
+
+	osSinitData := heap.OsSinitData
+	if osSinitData == nil {
+		t.Fatalf("OsSinitData is not parsed")
+	}
+	if osSinitData.Version != 7 || osSinitData.MLESize != 0x100000 || osSinitData.EFIRSDPPointer != 0x7FFE0000 {
+		t.Errorf("unexpected OsSinitData: %+v", osSinitData)
+	}
+
+	sinitMleData := heap.SinitMleData
+	if sinitMleData == nil {
+		t.Fatalf("SinitMleData is not parsed")
+	}
+	if sinitMleData.Version != 9 || sinitMleData.SinitHash[0] != 0xAA || sinitMleData.ProcScrtmStatus != 1 {
+		t.Errorf("unexpected SinitMleData: %+v", sinitMleData)
+	}
+	if len(sinitMleData.SinitMdrs) != 2 || sinitMleData.SinitMdrs[1].Length != 0x7FF00000 || sinitMleData.SinitMdrs[1].Type != 1 {
+		t.Errorf("unexpected SINIT MDRs: %+v", sinitMleData.SinitMdrs)
+	}
+	if string(sinitMleData.SinitVtdDmarTable) != "DMAR" {
+		t.Errorf("unexpected DMAR table: %q", sinitMleData.SinitVtdDmarTable)
+	}
+
+	used := biosData.Size + heap.OsMleData.Size + osSinitData.Size
+	if heap.SinitMleDataSpace() != 0x1000-used {
+		t.Errorf("unexpected SinitMleData space: 0x%x", heap.SinitMleDataSpace())
+	}
+
+	b, err := heap.JSON()
+	if err != nil {
+		t.Fatalf("JSON() failed: %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Errorf("invalid JSON: %v", err)
+	}
+	if !strings.Contains(string(b), `"EVENT_LOG_POINTER2_1"`) {
+		t.Errorf("element types are expected to be named in JSON: %s", b)
+	}
+
+	s := heap.PrettyString()
+	for _, expected := range []string{"BiosData: ", "ACM: 0xfff40000", "EFI RSDP pointer: 0x7ffe0000", "SINIT MDRs: 2"} {
+		if !strings.Contains(s, expected) {
+			t.Errorf("'%s' is not found in:\n%s", expected, s)
+		}
+	}
+}
+
+func TestParseTXTHeapBeforeLaunch(t *testing.T) {
+	heap, err := ParseTXTHeap(testTXTHeap(false))
+	if err != nil {
+		t.Fatalf("ParseTXTHeap() failed: %v", err)
+	}
+	if heap.OsSinitData == nil || heap.SinitMleData != nil {
+		t.Errorf("only SinitMleData is expected to be not initialized: %+v", heap)
+	}
+	if !strings.Contains(heap.PrettyString(), "SinitMleData: not initialized") {
+		t.Errorf("SinitMleData is expected to be reported as not initialized")
+	}
+}
+
+func TestParseTXTHeapInvalid(t *testing.T) {
+	if _, err := ParseTXTHeap(make([]byte, 0x100)); err == nil {
+		t.Errorf("a heap without BiosData is expected to be an error")
+	}
+
+	heap := testTXTHeap(true)
+	// the size of the BIOS_SPEC_VER element points beyond BiosData: the
+	// element follows the size, the fields, SinitFlags, MleFlags and its type
+	binary.LittleEndian.PutUint32(heap[8+28+4+4+4:], 0x1000)
+	if _, err := ParseTXTHeap(heap); err == nil {
+		t.Errorf("an extended element out of the table is expected to be an error")
+	}
+
+	heap = testTXTHeap(true)
+	biosData, err := ParseBIOSDataRegion(heap)
+	if err != nil {
+		t.Fatalf("ParseBIOSDataRegion() failed: %v", err)
+	}
+	if biosData.NumLogProcs != 8 {
+		t.Errorf("unexpected BiosData: %+v", biosData)
+	}
+}
@@ -0,0 +1,99 @@
+package tpm
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/errors"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpm/simulator"
+	"github.com/9elements/go-linux-lowlevel-hw/pkg/hwapi"
+	"github.com/google/go-tpm/legacy/tpm2"
+	tpm1 "github.com/google/go-tpm/tpm"
+	"github.com/google/go-tpm/tpmutil/mssim"
+)
+
+// SimulatorPrefix is the prefix of a TPM device selecting a TPM 2.0
+// simulator, see OpenTPM.
+const SimulatorPrefix = "sim:"
+
+var (
+	inProcessSimulatorOnce sync.Once
+	inProcessSimulator     *simulator.Simulator
+)
+
+// OpenTPM opens the TPM selected by the device string:
+//   - "" — the TPM of the local machine (see hwapi.NewTPM);
+//   - "sim:" — the in-process TPM 2.0 simulator. The simulator is shared
+//     by all OpenTPM calls of the process, so the NV indices defined
+//     through one connection are visible through the next one;
+//   - "sim:<host>:<port>" — the Microsoft TPM 2.0 simulator listening
+//     on the command port <port> and on the platform port <port>+1;
+//   - a path to a TPM character device, for example "/dev/tpm0".
+func OpenTPM(device string) (*hwapi.TPM, error) {
+	switch {
+	case device == "":
+		return hwapi.NewTPM()
+	case device == SimulatorPrefix:
+		inProcessSimulatorOnce.Do(func() {
+			inProcessSimulator = simulator.New()
+		})
+		return &hwapi.TPM{
+			Version: hwapi.TPMVersion20,
+			RWC:     inProcessSimulator,
+		}, nil
+	case strings.HasPrefix(device, SimulatorPrefix):
+		return openMSSim(strings.TrimPrefix(device, SimulatorPrefix))
+	}
+
+	var mErr errors.MultiError
+	rwc, err := tpm2.OpenTPM(device)
+	if err == nil {
+		return &hwapi.TPM{
+			Version: hwapi.TPMVersion20,
+			RWC:     rwc,
+		}, nil
+	}
+	_ = mErr.Add(fmt.Errorf("unable to open %s as TPM 2.0: %w", device, err))
+
+	rwc, err = tpm1.OpenTPM(device)
+	if err == nil {
+		return &hwapi.TPM{
+			Version: hwapi.TPMVersion12,
+			RWC:     rwc,
+		}, nil
+	}
+	_ = mErr.Add(fmt.Errorf("unable to open %s as TPM 1.2: %w", device, err))
+	return nil, mErr.ReturnValue()
+}
+
+// openMSSim connects to the Microsoft TPM 2.0 simulator. Connecting
+// power-cycles the simulator, so the TPM is started up afterwards.
+func openMSSim(commandAddress string) (*hwapi.TPM, error) {
+	host, port, err := net.SplitHostPort(commandAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid simulator address '%s' (expected 'sim:<host>:<port>'): %w", commandAddress, err)
+	}
+	commandPort, err := strconv.ParseUint(port, 10, 16)
+	if err != nil || commandPort == 0xFFFF {
+		return nil, fmt.Errorf("invalid simulator port '%s'", port)
+	}
+
+	conn, err := mssim.Open(mssim.Config{
+		CommandAddress:  commandAddress,
+		PlatformAddress: net.JoinHostPort(host, strconv.FormatUint(commandPort+1, 10)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to the simulator at %s: %w", commandAddress, err)
+	}
+	if err := tpm2.Startup(conn, tpm2.StartupClear); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("unable to start up the simulator: %w", err)
+	}
+	return &hwapi.TPM{
+		Version: hwapi.TPMVersion20,
+		RWC:     conn,
+	}, nil
+}
@@ -0,0 +1,94 @@
+package tpm
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-tpm/legacy/tpm2"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
+)
+
+const sysfsTPMPath = `/sys/class/tpm/tpm0`
+
+// PCRBanks are PCR values indexed by the hash algorithm of the PCR bank
+// and the PCR index.
+type PCRBanks map[tpm2.Algorithm]map[pcr.ID][]byte
+
+func (banks PCRBanks) set(alg tpm2.Algorithm, pcrIndex pcr.ID, value []byte) {
+	if banks[alg] == nil {
+		banks[alg] = map[pcr.ID][]byte{}
+	}
+	banks[alg][pcrIndex] = value
+}
+
+// sysfsPCRBankNames are the names of directories "pcr-<name>" exported
+// by Linux (since 5.12) for each active PCR bank of a TPM2.0.
+var sysfsPCRBankNames = map[string]tpm2.Algorithm{
+	"sha1":    tpm2.AlgSHA1,
+	"sha256":  tpm2.AlgSHA256,
+	"sha384":  tpm2.AlgSHA384,
+	"sha512":  tpm2.AlgSHA512,
+	"sm3_256": pcr.AlgSM3_256,
+}
+
+// ReadPCRBanksFromSysfs reads all PCRs of all active PCR banks exported
+// by Linux: directories "pcr-<algo>" of a TPM2.0 (since Linux 5.12) or
+// file "pcrs" of a TPM1.2 (see parseSysfsPCRs).
+func ReadPCRBanksFromSysfs() (PCRBanks, error) {
+	return readSysfsPCRBanks(sysfsTPMPath)
+}
+
+func readSysfsPCRBanks(dir string) (PCRBanks, error) {
+	banks := PCRBanks{}
+
+	for name, alg := range sysfsPCRBankNames {
+		bankDir := filepath.Join(dir, "pcr-"+name)
+		dirEntries, err := os.ReadDir(bankDir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("unable to list '%s': %w", bankDir, err)
+		}
+		for _, dirEntry := range dirEntries {
+			pcrIndex, err := strconv.ParseUint(dirEntry.Name(), 10, 8)
+			if err != nil || pcrIndex >= amountOfPCRs {
+				continue
+			}
+			path := filepath.Join(bankDir, dirEntry.Name())
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read '%s': %w", path, err)
+			}
+			var value []byte
+			if _, err := fmt.Sscanf(strings.TrimSpace(string(data)), "%X", &value); err != nil {
+				return nil, fmt.Errorf("unable to parse '%s': %w", path, err)
+			}
+			banks.set(alg, pcr.ID(pcrIndex), value)
+		}
+	}
+	if len(banks) > 0 {
+		return banks, nil
+	}
+
+	path := filepath.Join(dir, filepath.Base(tpm12PCRsPath))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("no PCR banks are exported in '%s': %w", dir, err)
+	}
+	pcrs, err := parseSysfsPCRs(bytes.TrimSpace(data))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse '%s': %w", path, err)
+	}
+	for pcrIndex, value := range pcrs {
+		if value != nil {
+			banks.set(tpm2.AlgSHA1, pcr.ID(pcrIndex), value)
+		}
+	}
+	return banks, nil
+}
@@ -0,0 +1,52 @@
+package tpm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-tpm/legacy/tpm2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
+)
+
+func TestReadSysfsPCRBanks(t *testing.T) {
+	dir := t.TempDir()
+	for name, value := range map[string]string{
+		"pcr-sha1/0":   strings.Repeat("01", 20),
+		"pcr-sha256/0": strings.Repeat("02", 32),
+		"pcr-sha256/7": strings.Repeat("0A", 32),
+	} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(value+"\n"), 0o644))
+	}
+
+	banks, err := readSysfsPCRBanks(dir)
+	require.NoError(t, err)
+	require.Len(t, banks, 2)
+	require.Len(t, banks[tpm2.AlgSHA256], 2)
+	require.Equal(t, []byte(strings.Repeat("\x0a", 32)), banks[tpm2.AlgSHA256][7])
+	require.Equal(t, []byte(strings.Repeat("\x01", 20)), banks[tpm2.AlgSHA1][0])
+}
+
+func TestReadSysfsPCRBanksTPM12(t *testing.T) {
+	dir := t.TempDir()
+	var pcrs strings.Builder
+	for pcrIndex := 0; pcrIndex < amountOfPCRs; pcrIndex++ {
+		fmt.Fprintf(&pcrs, "PCR-%02d: %s00\n", pcrIndex, strings.Repeat("00 ", 19))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pcrs"), []byte(pcrs.String()), 0o644))
+
+	banks, err := readSysfsPCRBanks(dir)
+	require.NoError(t, err)
+	require.Len(t, banks, 1)
+	require.Len(t, banks[tpm2.AlgSHA1], amountOfPCRs)
+	require.Equal(t, make([]byte, 20), banks[tpm2.AlgSHA1][pcr.ID(17)])
+
+	_, err = readSysfsPCRBanks(t.TempDir())
+	require.Error(t, err)
+}
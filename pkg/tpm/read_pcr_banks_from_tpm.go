@@ -0,0 +1,114 @@
+package tpm
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
+	"github.com/9elements/converged-security-suite/v2/pkg/errors"
+	"github.com/9elements/go-linux-lowlevel-hw/pkg/hwapi"
+	"github.com/facebookincubator/go-belt/tool/logger"
+	"github.com/google/go-tpm/legacy/tpm2"
+	tpm1 "github.com/google/go-tpm/tpm"
+	"github.com/marcoguerri/go-tpm-tcti/abrmd"
+)
+
+// ReadPCRBanksFromTPM reads all PCRs of all active PCR banks from TPM.
+//
+// Failures to release the TPM are logged through the logger of the context.
+func ReadPCRBanksFromTPM(ctx context.Context) (PCRBanks, error) {
+	var mErr errors.MultiError
+
+	// Try abrmd first, if failure then try /dev/tpm{rm,}.
+	abrmdClient, err := abrmd.NewBroker()
+	if err == nil {
+		defer func() {
+			if err := abrmdClient.Close(); err != nil {
+				logger.Warnf(ctx, "failed to close the connection: %v", err)
+			}
+		}()
+		banks, err := readPCRBanksTPM2(abrmdClient)
+		if err != nil {
+			_ = mErr.Add(fmt.Errorf("unable to get PCR values through abrmd: %w", err))
+			return nil, mErr.ReturnValue()
+		}
+		return banks, nil
+	}
+	_ = mErr.Add(fmt.Errorf("unable to connect to abrmd: %w", err))
+
+	tpm, err := hwapi.NewTPM()
+	if err != nil {
+		_ = mErr.Add(fmt.Errorf("unable to open TPM: %w", err))
+		return nil, mErr.ReturnValue()
+	}
+	defer func() {
+		if err := tpm.Close(); err != nil {
+			logger.Warnf(ctx, "failed to close the socket: %v", err)
+		}
+	}()
+
+	var banks PCRBanks
+	switch tpm.Version {
+	case hwapi.TPMVersion12:
+		banks = PCRBanks{}
+		for pcrIndex := pcr.ID(0); pcrIndex < amountOfPCRs; pcrIndex++ {
+			var value []byte
+			value, err = tpm1.ReadPCR(tpm.RWC, uint32(pcrIndex))
+			if err != nil {
+				break
+			}
+			banks.set(tpm2.AlgSHA1, pcrIndex, value)
+		}
+	case hwapi.TPMVersion20:
+		banks, err = readPCRBanksTPM2(tpm.RWC)
+	default:
+		_ = mErr.Add(fmt.Errorf("unsupported TPM version: %x", tpm.Version))
+		return nil, mErr.ReturnValue()
+	}
+	if err != nil {
+		_ = mErr.Add(fmt.Errorf("unable to get PCR values directly from TPM: %w", err))
+		return nil, mErr.ReturnValue()
+	}
+
+	return banks, nil
+}
+
+func readPCRBanksTPM2(rw io.ReadWriter) (PCRBanks, error) {
+	caps, _, err := tpm2.GetCapability(rw, tpm2.CapabilityPCRs, 1, 0)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get the active PCR banks: %w", err)
+	}
+
+	banks := PCRBanks{}
+	for _, c := range caps {
+		selection, ok := c.(tpm2.PCRSelection)
+		if !ok {
+			return nil, fmt.Errorf("unexpected capability type %T", c)
+		}
+
+		// TPM returns a limited amount of PCRs at once, so requesting
+		// the rest until all of them are received.
+		remaining := selection.PCRs
+		for len(remaining) > 0 {
+			values, err := tpm2.ReadPCRs(rw, tpm2.PCRSelection{Hash: selection.Hash, PCRs: remaining})
+			if err != nil {
+				return nil, fmt.Errorf("unable to read PCRs %v of bank %s: %w", remaining, selection.Hash, err)
+			}
+			if len(values) == 0 {
+				return nil, fmt.Errorf("TPM returned no values for PCRs %v of bank %s", remaining, selection.Hash)
+			}
+			var notReceived []int
+			for _, pcrIndex := range remaining {
+				value, ok := values[pcrIndex]
+				if !ok {
+					notReceived = append(notReceived, pcrIndex)
+					continue
+				}
+				banks.set(selection.Hash, pcr.ID(pcrIndex), value)
+			}
+			remaining = notReceived
+		}
+	}
+	return banks, nil
+}
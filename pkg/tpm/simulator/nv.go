@@ -0,0 +1,232 @@
+package simulator
+
+import (
+	"bytes"
+
+	"github.com/google/go-tpm/legacy/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// nvIndex is a defined NV index.
+type nvIndex struct {
+	public    tpm2.NVPublic
+	authValue []byte
+	data      []byte
+}
+
+// name returns the Name of the NV index: nameAlg || H(nvPublic).
+func (nv *nvIndex) name() []byte {
+	hash, _ := nv.public.NameAlg.Hash()
+	h := hash.New()
+	h.Write(must(tpmutil.Pack(nv.public)))
+	return append(must(tpmutil.Pack(nv.public.NameAlg)), h.Sum(nil)...)
+}
+
+func (s *Simulator) nvDefineSpace(cmd *command) (*response, tpmutil.ResponseCode) {
+	authHandle := cmd.handles[0]
+	if authHandle != tpm2.HandleOwner && authHandle != tpm2.HandlePlatform {
+		return nil, rcHandle(tpm2.RCHierarchy, 1)
+	}
+	if rc := s.checkPassword(cmd, 0, s.hierarchyAuth[authHandle]); rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+
+	var auth, publicInfo tpmutil.U16Bytes
+	if rc := readParams(cmd, &auth, &publicInfo); rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+	var public tpm2.NVPublic
+	if _, err := tpmutil.Unpack(publicInfo, &public); err != nil {
+		return nil, rcParam(tpm2.RCSize, 2)
+	}
+	if len(auth) > 64 {
+		return nil, rcParam(tpm2.RCSize, 1)
+	}
+	if public.NVIndex>>24 != handleTypeNVIndex {
+		return nil, rcParam(tpm2.RCValue, 2)
+	}
+	hash, err := public.NameAlg.Hash()
+	if err != nil || !hash.Available() {
+		return nil, rcParam(tpm2.RCHash, 2)
+	}
+	if len(public.AuthPolicy) != 0 && len(public.AuthPolicy) != hash.Size() {
+		return nil, rcParam(tpm2.RCSize, 2)
+	}
+
+	attrs := public.Attributes
+	isPlatform := authHandle == tpm2.HandlePlatform
+	switch {
+	case (attrs&tpm2.AttrPlatformCreate != 0) != isPlatform,
+		attrs&tpm2.AttrPolicyDelete != 0 && !isPlatform,
+		attrs&(tpm2.AttrWritten|tpm2.AttrWriteLocked|tpm2.AttrReadLocked) != 0,
+		attrs&(tpm2.AttrPPWrite|tpm2.AttrOwnerWrite|tpm2.AttrAuthWrite|tpm2.AttrPolicyWrite) == 0,
+		attrs&(tpm2.AttrPPRead|tpm2.AttrOwnerRead|tpm2.AttrAuthRead|tpm2.AttrPolicyRead) == 0:
+		return nil, rcParam(tpm2.RCAttributes, 2)
+	}
+	if public.DataSize > maxNVIndexSize {
+		return nil, rcFmt0(tpm2.RCNVSize)
+	}
+	if s.nvIndices[public.NVIndex] != nil {
+		return nil, rcFmt0(tpm2.RCNVDefined)
+	}
+
+	s.nvIndices[public.NVIndex] = &nvIndex{
+		public:    public,
+		authValue: auth,
+	}
+	return &response{}, tpmutil.RCSuccess
+}
+
+func (s *Simulator) nvUndefineSpace(cmd *command) (*response, tpmutil.ResponseCode) {
+	authHandle, index := cmd.handles[0], cmd.handles[1]
+	if authHandle != tpm2.HandleOwner && authHandle != tpm2.HandlePlatform {
+		return nil, rcHandle(tpm2.RCHierarchy, 1)
+	}
+	if rc := s.checkPassword(cmd, 0, s.hierarchyAuth[authHandle]); rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+	nv := s.nvIndices[index]
+	if nv == nil {
+		return nil, rcHandle(tpm2.RCHandle, 2)
+	}
+	if nv.public.Attributes&tpm2.AttrPolicyDelete != 0 {
+		// should be deleted by TPM2_NV_UndefineSpaceSpecial
+		return nil, rcHandle(tpm2.RCAttributes, 2)
+	}
+	if nv.public.Attributes&tpm2.AttrPlatformCreate != 0 && authHandle != tpm2.HandlePlatform {
+		return nil, rcFmt0(tpm2.RCNVAuthorization)
+	}
+	delete(s.nvIndices, index)
+	return &response{}, tpmutil.RCSuccess
+}
+
+func (s *Simulator) nvUndefineSpaceSpecial(cmd *command) (*response, tpmutil.ResponseCode) {
+	index, platform := cmd.handles[0], cmd.handles[1]
+	nv := s.nvIndices[index]
+	if nv == nil {
+		return nil, rcHandle(tpm2.RCHandle, 1)
+	}
+	if platform != tpm2.HandlePlatform {
+		return nil, rcHandle(tpm2.RCHierarchy, 2)
+	}
+	if nv.public.Attributes&tpm2.AttrPolicyDelete == 0 {
+		return nil, rcHandle(tpm2.RCAttributes, 1)
+	}
+	if rc := s.checkPolicy(cmd, 0, nv.public.AuthPolicy); rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+	if rc := s.checkPassword(cmd, 1, s.hierarchyAuth[platform]); rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+	delete(s.nvIndices, index)
+	return &response{}, tpmutil.RCSuccess
+}
+
+func (s *Simulator) nvReadPublic(cmd *command) (*response, tpmutil.ResponseCode) {
+	nv := s.nvIndices[cmd.handles[0]]
+	if nv == nil {
+		return nil, rcHandle(tpm2.RCHandle, 1)
+	}
+	var params bytes.Buffer
+	mustWrite(&params, tpmutil.U16Bytes(must(tpmutil.Pack(nv.public))), tpmutil.U16Bytes(nv.name()))
+	return &response{params: params.Bytes()}, tpmutil.RCSuccess
+}
+
+// nvAuthorize checks the authorization of an NV index access according to
+// the attributes of the index.
+func (s *Simulator) nvAuthorize(cmd *command, nv *nvIndex, write bool) tpmutil.ResponseCode {
+	ppAttr, ownerAttr, authAttr, policyAttr := tpm2.AttrPPRead, tpm2.AttrOwnerRead, tpm2.AttrAuthRead, tpm2.AttrPolicyRead
+	if write {
+		ppAttr, ownerAttr, authAttr, policyAttr = tpm2.AttrPPWrite, tpm2.AttrOwnerWrite, tpm2.AttrAuthWrite, tpm2.AttrPolicyWrite
+	}
+	attrs := nv.public.Attributes
+
+	switch authHandle := cmd.handles[0]; authHandle {
+	case tpm2.HandlePlatform, tpm2.HandleOwner:
+		if authHandle == tpm2.HandlePlatform && attrs&ppAttr == 0 ||
+			authHandle == tpm2.HandleOwner && attrs&ownerAttr == 0 {
+			return rcFmt0(tpm2.RCNVAuthorization)
+		}
+		return s.checkPassword(cmd, 0, s.hierarchyAuth[authHandle])
+	case nv.public.NVIndex:
+		if cmd.sessions[0].Session == tpm2.HandlePasswordSession {
+			if attrs&authAttr == 0 {
+				return rcFmt0(tpm2.RCNVAuthorization)
+			}
+			return s.checkPassword(cmd, 0, nv.authValue)
+		}
+		if attrs&policyAttr == 0 {
+			return rcFmt0(tpm2.RCNVAuthorization)
+		}
+		return s.checkPolicy(cmd, 0, nv.public.AuthPolicy)
+	}
+	return rcFmt0(tpm2.RCNVAuthorization)
+}
+
+func (s *Simulator) nvWrite(cmd *command) (*response, tpmutil.ResponseCode) {
+	nv := s.nvIndices[cmd.handles[1]]
+	if nv == nil {
+		return nil, rcHandle(tpm2.RCHandle, 2)
+	}
+	if rc := s.nvAuthorize(cmd, nv, true); rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+	if nv.public.Attributes&tpm2.AttrWriteLocked != 0 {
+		return nil, rcFmt0(tpm2.RCNVLocked)
+	}
+
+	var data tpmutil.U16Bytes
+	var offset uint16
+	if rc := readParams(cmd, &data, &offset); rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+	if len(data) > maxNVBufferSize {
+		return nil, rcParam(tpm2.RCValue, 1)
+	}
+	end := int(offset) + len(data)
+	if end > int(nv.public.DataSize) {
+		return nil, rcFmt0(tpm2.RCNVRange)
+	}
+	if nv.public.Attributes&tpm2.AttrWriteAll != 0 && (offset != 0 || end != int(nv.public.DataSize)) {
+		return nil, rcFmt0(tpm2.RCNVRange)
+	}
+
+	if nv.data == nil {
+		nv.data = bytes.Repeat([]byte{0xFF}, int(nv.public.DataSize))
+	}
+	copy(nv.data[offset:end], data)
+	nv.public.Attributes |= tpm2.AttrWritten
+	return &response{}, tpmutil.RCSuccess
+}
+
+func (s *Simulator) nvRead(cmd *command) (*response, tpmutil.ResponseCode) {
+	nv := s.nvIndices[cmd.handles[1]]
+	if nv == nil {
+		return nil, rcHandle(tpm2.RCHandle, 2)
+	}
+	if rc := s.nvAuthorize(cmd, nv, false); rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+	if nv.public.Attributes&tpm2.AttrReadLocked != 0 {
+		return nil, rcFmt0(tpm2.RCNVLocked)
+	}
+	if nv.public.Attributes&tpm2.AttrWritten == 0 {
+		return nil, rcFmt0(tpm2.RCNVUninitialized)
+	}
+
+	var size, offset uint16
+	if rc := readParams(cmd, &size, &offset); rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+	if size > maxNVBufferSize {
+		return nil, rcParam(tpm2.RCValue, 1)
+	}
+	end := int(offset) + int(size)
+	if end > int(nv.public.DataSize) {
+		return nil, rcFmt0(tpm2.RCNVRange)
+	}
+
+	var params bytes.Buffer
+	mustWrite(&params, tpmutil.U16Bytes(nv.data[offset:end]))
+	return &response{params: params.Bytes()}, tpmutil.RCSuccess
+}
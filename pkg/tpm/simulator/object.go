@@ -0,0 +1,329 @@
+package simulator
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"math/big"
+
+	"github.com/google/go-tpm/legacy/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+const (
+	// rcObjectMemory is TPM_RC_OBJECT_MEMORY: out of memory for objects.
+	rcObjectMemory = tpmutil.ResponseCode(0x902)
+
+	// tagCreation is TPM_ST_CREATION, the tag of a creation ticket.
+	tagCreation = tpmutil.Tag(0x8021)
+
+	// maxObjects is the amount of transient object slots, it is the
+	// minimum required by the PC Client specification.
+	maxObjects = 3
+
+	handleTransientFirst = 0x80000000
+	maxQualifyingData    = 64
+)
+
+// object is a transient signing key created by TPM2_CreatePrimary.
+type object struct {
+	public        tpm2.Public
+	encodedPublic []byte
+	name          tpm2.Name
+	qualifiedName tpm2.Name
+	authValue     []byte
+	signer        crypto.Signer
+}
+
+func (s *Simulator) createPrimary(cmd *command) (*response, tpmutil.ResponseCode) {
+	hierarchy := cmd.handles[0]
+	if !isHierarchy(hierarchy) || hierarchy == tpm2.HandleLockout {
+		return nil, rcHandle(tpm2.RCHierarchy, 1)
+	}
+	if rc := s.checkPassword(cmd, 0, s.hierarchyAuth[hierarchy]); rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+
+	var inSensitive, inPublic, outsideInfo tpmutil.U16Bytes
+	if rc := readParams(cmd, &inSensitive, &inPublic, &outsideInfo); rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+	creationPCRs, rc := s.readPCRSelections(cmd)
+	if rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+	var userAuth, data tpmutil.U16Bytes
+	if _, err := tpmutil.Unpack(inSensitive, &userAuth, &data); err != nil {
+		return nil, rcParam(tpm2.RCSize, 1)
+	}
+	if len(data) != 0 {
+		// sensitive data could be provided only for symmetric and keyed hash objects
+		return nil, rcParam(tpm2.RCValue, 1)
+	}
+	public, err := tpm2.DecodePublic(inPublic)
+	if err != nil {
+		return nil, rcParam(tpm2.RCValue, 2)
+	}
+	nameHash, err := public.NameAlg.Hash()
+	if err != nil || !nameHash.Available() {
+		return nil, rcParam(tpm2.RCHash, 2)
+	}
+	if len(s.objects) >= maxObjects {
+		return nil, rcObjectMemory
+	}
+
+	signer, rc := generateSigningKey(&public)
+	if rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+	encodedPublic, err := public.Encode()
+	if err != nil {
+		return nil, rcParam(tpm2.RCValue, 2)
+	}
+	name, err := public.Name()
+	if err != nil {
+		return nil, rcParam(tpm2.RCValue, 2)
+	}
+	obj := &object{
+		public:        public,
+		encodedPublic: encodedPublic,
+		name:          name,
+		qualifiedName: qualifiedName(hierarchy, public.NameAlg, name),
+		authValue:     userAuth,
+		signer:        signer,
+	}
+
+	var creationData bytes.Buffer
+	writePCRSelections(&creationData, creationPCRs)
+	mustWrite(&creationData,
+		tpmutil.U16Bytes(s.pcrDigest(nameHash, creationPCRs)),
+		uint8(0),     // locality
+		tpm2.AlgNull, // the name algorithm of a hierarchy
+		tpmutil.U16Bytes(must(tpmutil.Pack(hierarchy))),
+		tpmutil.U16Bytes(must(tpmutil.Pack(hierarchy))),
+		outsideInfo,
+	)
+	creationHash := nameHash.New()
+	creationHash.Write(creationData.Bytes())
+
+	s.lastObject++
+	handle := tpmutil.Handle(handleTransientFirst | s.lastObject)
+	s.objects[handle] = obj
+
+	var params bytes.Buffer
+	mustWrite(&params,
+		tpmutil.U16Bytes(encodedPublic),
+		tpmutil.U16Bytes(creationData.Bytes()),
+		tpmutil.U16Bytes(creationHash.Sum(nil)),
+		// the ticket is not verified by the simulator, so it is not signed
+		tpm2.Ticket{Type: tagCreation, Hierarchy: hierarchy},
+		tpmutil.U16Bytes(must(name.Encode())[2:]),
+	)
+	return &response{handles: []tpmutil.Handle{handle}, params: params.Bytes()}, tpmutil.RCSuccess
+}
+
+// generateSigningKey generates the key described by the template and
+// sets its unique field. Only signing RSA and ECC keys are supported.
+func generateSigningKey(public *tpm2.Public) (crypto.Signer, tpmutil.ResponseCode) {
+	if public.Attributes&tpm2.FlagSign == 0 || public.Attributes&tpm2.FlagDecrypt != 0 {
+		return nil, rcParam(tpm2.RCAttributes, 2)
+	}
+	switch {
+	case public.Type == tpm2.AlgRSA && public.RSAParameters != nil:
+		params := public.RSAParameters
+		if params.Sign != nil && params.Sign.Alg != tpm2.AlgRSASSA && params.Sign.Alg != tpm2.AlgRSAPSS {
+			return nil, rcParam(tpm2.RCScheme, 2)
+		}
+		if params.KeyBits != 2048 && params.KeyBits != 3072 {
+			return nil, rcParam(tpm2.RCKeySize, 2)
+		}
+		if params.ExponentRaw != 0 && params.ExponentRaw != 65537 {
+			return nil, rcParam(tpm2.RCValue, 2)
+		}
+		key, err := rsa.GenerateKey(rand.Reader, int(params.KeyBits))
+		if err != nil {
+			return nil, rcFmt0(tpm2.RCFailure)
+		}
+		params.ModulusRaw = key.N.Bytes()
+		return key, tpmutil.RCSuccess
+
+	case public.Type == tpm2.AlgECC && public.ECCParameters != nil:
+		params := public.ECCParameters
+		if params.Sign != nil && params.Sign.Alg != tpm2.AlgECDSA {
+			return nil, rcParam(tpm2.RCScheme, 2)
+		}
+		var curve elliptic.Curve
+		switch params.CurveID {
+		case tpm2.CurveNISTP256:
+			curve = elliptic.P256()
+		case tpm2.CurveNISTP384:
+			curve = elliptic.P384()
+		default:
+			return nil, rcParam(tpm2.RCCurve, 2)
+		}
+		key, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			return nil, rcFmt0(tpm2.RCFailure)
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		params.Point = tpm2.ECPoint{
+			XRaw: key.X.FillBytes(make([]byte, size)),
+			YRaw: key.Y.FillBytes(make([]byte, size)),
+		}
+		return key, tpmutil.RCSuccess
+	}
+	return nil, rcParam(tpm2.RCType, 2)
+}
+
+// qualifiedName calculates the qualified name of a primary object:
+// H(handle of the hierarchy || name).
+func qualifiedName(hierarchy tpmutil.Handle, nameAlg tpm2.Algorithm, name tpm2.Name) tpm2.Name {
+	hash, _ := nameAlg.Hash()
+	h := hash.New()
+	h.Write(must(tpmutil.Pack(hierarchy)))
+	h.Write(must(name.Encode())[2:])
+	return tpm2.Name{Digest: &tpm2.HashValue{Alg: nameAlg, Value: h.Sum(nil)}}
+}
+
+// signScheme returns the signing scheme of a command: the scheme of the
+// key, or inScheme if the key does not restrict it.
+func (obj *object) signScheme(inScheme tpm2.SigScheme) (tpm2.SigScheme, tpmutil.ResponseCode) {
+	var keyScheme *tpm2.SigScheme
+	switch {
+	case obj.public.RSAParameters != nil:
+		keyScheme = obj.public.RSAParameters.Sign
+	case obj.public.ECCParameters != nil:
+		keyScheme = obj.public.ECCParameters.Sign
+	}
+	switch {
+	case keyScheme == nil || keyScheme.Alg == tpm2.AlgNull:
+		if inScheme.Alg == tpm2.AlgNull {
+			return tpm2.SigScheme{}, rcParam(tpm2.RCScheme, 2)
+		}
+		return inScheme, tpmutil.RCSuccess
+	case inScheme.Alg == tpm2.AlgNull || inScheme == *keyScheme:
+		return *keyScheme, tpmutil.RCSuccess
+	}
+	return tpm2.SigScheme{}, rcParam(tpm2.RCScheme, 2)
+}
+
+// sign signs the message by the object key and returns TPMT_SIGNATURE.
+func (obj *object) sign(scheme tpm2.SigScheme, message []byte) ([]byte, tpmutil.ResponseCode) {
+	hash, err := scheme.Hash.Hash()
+	if err != nil || !hash.Available() {
+		return nil, rcParam(tpm2.RCHash, 2)
+	}
+	h := hash.New()
+	h.Write(message)
+	digest := h.Sum(nil)
+
+	signature := tpm2.Signature{Alg: scheme.Alg}
+	switch key := obj.signer.(type) {
+	case *rsa.PrivateKey:
+		var sig []byte
+		switch scheme.Alg {
+		case tpm2.AlgRSASSA:
+			sig, err = rsa.SignPKCS1v15(rand.Reader, key, hash, digest)
+		case tpm2.AlgRSAPSS:
+			sig, err = rsa.SignPSS(rand.Reader, key, hash, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		default:
+			return nil, rcParam(tpm2.RCScheme, 2)
+		}
+		if err != nil {
+			return nil, rcFmt0(tpm2.RCFailure)
+		}
+		signature.RSA = &tpm2.SignatureRSA{HashAlg: scheme.Hash, Signature: sig}
+	case *ecdsa.PrivateKey:
+		if scheme.Alg != tpm2.AlgECDSA {
+			return nil, rcParam(tpm2.RCScheme, 2)
+		}
+		var r, s *big.Int
+		if r, s, err = ecdsa.Sign(rand.Reader, key, digest); err != nil {
+			return nil, rcFmt0(tpm2.RCFailure)
+		}
+		signature.ECC = &tpm2.SignatureECC{HashAlg: scheme.Hash, R: r, S: s}
+	}
+	return must(signature.Encode()), tpmutil.RCSuccess
+}
+
+func (s *Simulator) quote(cmd *command) (*response, tpmutil.ResponseCode) {
+	obj := s.objects[cmd.handles[0]]
+	if obj == nil {
+		return nil, rcHandle(tpm2.RCHandle, 1)
+	}
+	if rc := s.checkPassword(cmd, 0, obj.authValue); rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+
+	var qualifyingData tpmutil.U16Bytes
+	var inScheme tpm2.SigScheme
+	if rc := readParams(cmd, &qualifyingData, &inScheme.Alg); rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+	if len(qualifyingData) > maxQualifyingData {
+		return nil, rcParam(tpm2.RCSize, 1)
+	}
+	if inScheme.Alg != tpm2.AlgNull {
+		if rc := readParams(cmd, &inScheme.Hash); rc != tpmutil.RCSuccess {
+			return nil, rc
+		}
+	}
+	selections, rc := s.readPCRSelections(cmd)
+	if rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+	if len(selections) > 1 {
+		// TPMS_QUOTE_INFO of go-tpm supports a single PCR bank
+		return nil, rcParam(tpm2.RCValue, 3)
+	}
+	scheme, rc := obj.signScheme(inScheme)
+	if rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+	hash, err := scheme.Hash.Hash()
+	if err != nil || !hash.Available() {
+		return nil, rcParam(tpm2.RCHash, 2)
+	}
+
+	quoteInfo := &tpm2.QuoteInfo{PCRDigest: s.pcrDigest(hash, selections)}
+	for _, selection := range selections {
+		quoteInfo.PCRSelection = tpm2.PCRSelection{Hash: selection.hash, PCRs: selection.pcrs}
+	}
+	attest, err := tpm2.AttestationData{
+		Magic:             0xff544347, // TPM_GENERATED_VALUE
+		Type:              tpm2.TagAttestQuote,
+		QualifiedSigner:   obj.qualifiedName,
+		ExtraData:         qualifyingData,
+		ClockInfo:         tpm2.ClockInfo{Safe: 1},
+		AttestedQuoteInfo: quoteInfo,
+	}.Encode()
+	if err != nil {
+		return nil, rcFmt0(tpm2.RCFailure)
+	}
+	signature, rc := obj.sign(scheme, attest)
+	if rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+
+	var params bytes.Buffer
+	mustWrite(&params, tpmutil.U16Bytes(attest))
+	params.Write(signature)
+	return &response{params: params.Bytes()}, tpmutil.RCSuccess
+}
+
+func (s *Simulator) readPublic(cmd *command) (*response, tpmutil.ResponseCode) {
+	obj := s.objects[cmd.handles[0]]
+	if obj == nil {
+		return nil, rcHandle(tpm2.RCHandle, 1)
+	}
+	var params bytes.Buffer
+	mustWrite(&params,
+		tpmutil.U16Bytes(obj.encodedPublic),
+		tpmutil.U16Bytes(must(obj.name.Encode())[2:]),
+		tpmutil.U16Bytes(must(obj.qualifiedName.Encode())[2:]),
+	)
+	return &response{params: params.Bytes()}, tpmutil.RCSuccess
+}
@@ -0,0 +1,138 @@
+package simulator
+
+import (
+	"bytes"
+	"crypto"
+	"io"
+
+	"github.com/google/go-tpm/legacy/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// pcrSelection is TPMS_PCR_SELECTION.
+type pcrSelection struct {
+	hash tpm2.Algorithm
+	pcrs []int
+}
+
+// readPCRSelections reads TPML_PCR_SELECTION from the command parameters.
+// The selections of not allocated banks are skipped.
+func (s *Simulator) readPCRSelections(cmd *command) ([]pcrSelection, tpmutil.ResponseCode) {
+	var count uint32
+	if rc := readParams(cmd, &count); rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+	var selections []pcrSelection
+	for idx := uint32(0); idx < count; idx++ {
+		var hash tpm2.Algorithm
+		var size uint8
+		if rc := readParams(cmd, &hash, &size); rc != tpmutil.RCSuccess {
+			return nil, rc
+		}
+		bitmap := make([]byte, size)
+		if _, err := io.ReadFull(cmd.params, bitmap); err != nil {
+			return nil, rcParam(tpm2.RCInsufficient, 1)
+		}
+		if s.pcrs[hash] == nil {
+			continue
+		}
+		selection := pcrSelection{hash: hash}
+		for pcr := 0; pcr < len(bitmap)*8 && pcr < AmountOfPCRs; pcr++ {
+			if bitmap[pcr/8]&(1<<(pcr%8)) != 0 {
+				selection.pcrs = append(selection.pcrs, pcr)
+			}
+		}
+		selections = append(selections, selection)
+	}
+	return selections, tpmutil.RCSuccess
+}
+
+// writePCRSelections writes TPML_PCR_SELECTION.
+func writePCRSelections(buf *bytes.Buffer, selections []pcrSelection) {
+	mustWrite(buf, uint32(len(selections)))
+	for _, selection := range selections {
+		var bitmap [3]byte
+		for _, pcr := range selection.pcrs {
+			bitmap[pcr/8] |= 1 << (pcr % 8)
+		}
+		mustWrite(buf, selection.hash, uint8(len(bitmap)), bitmap)
+	}
+}
+
+// pcrDigest returns the digest of the concatenated values of the selected
+// PCRs, as it is used by TPM2_Quote and TPM2_CreatePrimary.
+func (s *Simulator) pcrDigest(hash crypto.Hash, selections []pcrSelection) []byte {
+	h := hash.New()
+	for _, selection := range selections {
+		for _, pcr := range selection.pcrs {
+			h.Write(s.pcrs[selection.hash][pcr])
+		}
+	}
+	return h.Sum(nil)
+}
+
+func (s *Simulator) pcrRead(cmd *command) (*response, tpmutil.ResponseCode) {
+	// the banks which are not allocated are skipped in the response
+	selections, rc := s.readPCRSelections(cmd)
+	if rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+
+	var params, digests bytes.Buffer
+	var digestsCount uint32
+	mustWrite(&params, uint32(0))
+	writePCRSelections(&params, selections)
+	for _, selection := range selections {
+		for _, pcr := range selection.pcrs {
+			mustWrite(&digests, tpmutil.U16Bytes(s.pcrs[selection.hash][pcr]))
+			digestsCount++
+		}
+	}
+	mustWrite(&params, digestsCount)
+	params.Write(digests.Bytes())
+	return &response{params: params.Bytes()}, tpmutil.RCSuccess
+}
+
+func (s *Simulator) pcrExtend(cmd *command) (*response, tpmutil.ResponseCode) {
+	pcr := cmd.handles[0]
+	if pcr >= AmountOfPCRs {
+		return nil, rcHandle(tpm2.RCValue, 1)
+	}
+	if rc := s.checkPassword(cmd, 0, nil); rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+
+	var count uint32
+	if rc := readParams(cmd, &count); rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+	extends := map[tpm2.Algorithm][]byte{}
+	for idx := uint32(0); idx < count; idx++ {
+		var alg tpm2.Algorithm
+		if rc := readParams(cmd, &alg); rc != tpmutil.RCSuccess {
+			return nil, rc
+		}
+		hash, err := alg.Hash()
+		if err != nil || !hash.Available() {
+			return nil, rcParam(tpm2.RCHash, 1)
+		}
+		digest := make([]byte, hash.Size())
+		if _, err := io.ReadFull(cmd.params, digest); err != nil {
+			return nil, rcParam(tpm2.RCInsufficient, 1)
+		}
+		extends[alg] = digest
+	}
+
+	for alg, digest := range extends {
+		bank := s.pcrs[alg]
+		if bank == nil {
+			continue
+		}
+		hash, _ := alg.Hash()
+		h := hash.New()
+		h.Write(bank[pcr])
+		h.Write(digest)
+		bank[pcr] = h.Sum(nil)
+	}
+	return &response{}, tpmutil.RCSuccess
+}
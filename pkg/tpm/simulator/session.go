@@ -0,0 +1,223 @@
+package simulator
+
+import (
+	"bytes"
+	"crypto"
+
+	"github.com/google/go-tpm/legacy/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// rcSessionMemory is TPM_RC_SESSION_MEMORY: out of memory for sessions.
+const rcSessionMemory = tpmutil.ResponseCode(0x903)
+
+// session is a policy (or trial policy) session.
+type session struct {
+	sessionType  tpm2.SessionType
+	hash         crypto.Hash
+	nonceTPM     []byte
+	policyDigest []byte
+	// commandCode is the command the session is restricted to by
+	// TPM2_PolicyCommandCode, zero if it is not restricted.
+	commandCode tpmutil.Command
+}
+
+func (session *session) reset() {
+	session.policyDigest = make([]byte, session.hash.Size())
+	session.commandCode = 0
+}
+
+// extend updates the policy digest as
+// policyDigest_new := H(policyDigest_old || commandCode || data).
+func (session *session) extend(policyDigest []byte, commandCode tpmutil.Command, data ...[]byte) {
+	h := session.hash.New()
+	h.Write(policyDigest)
+	h.Write(must(tpmutil.Pack(commandCode)))
+	for _, d := range data {
+		h.Write(d)
+	}
+	session.policyDigest = h.Sum(nil)
+}
+
+func must(b []byte, err error) []byte {
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func (s *Simulator) startAuthSession(cmd *command) (*response, tpmutil.ResponseCode) {
+	for idx, handle := range cmd.handles {
+		if handle != tpm2.HandleNull {
+			// salted and bound sessions are not supported
+			return nil, rcHandle(tpm2.RCHandle, idx+1)
+		}
+	}
+
+	var (
+		nonceCaller, encryptedSalt tpmutil.U16Bytes
+		sessionType                tpm2.SessionType
+		symmetric, authHash        tpm2.Algorithm
+	)
+	if rc := readParams(cmd, &nonceCaller, &encryptedSalt, &sessionType, &symmetric); rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+	if symmetric != tpm2.AlgNull {
+		return nil, rcParam(tpm2.RCSymmetric, 4)
+	}
+	if rc := readParams(cmd, &authHash); rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+
+	hash, err := authHash.Hash()
+	if err != nil || !hash.Available() {
+		return nil, rcParam(tpm2.RCHash, 5)
+	}
+	if len(nonceCaller) < 16 || len(nonceCaller) > hash.Size() {
+		return nil, rcParam(tpm2.RCSize, 1)
+	}
+	if len(encryptedSalt) != 0 {
+		return nil, rcParam(tpm2.RCValue, 2)
+	}
+	if sessionType != tpm2.SessionPolicy && sessionType != tpm2.SessionTrial {
+		// HMAC sessions are not supported
+		return nil, rcParam(tpm2.RCValue, 3)
+	}
+	if len(s.sessions) >= maxSessions {
+		return nil, rcSessionMemory
+	}
+
+	s.lastSession++
+	handle := tpmutil.Handle(sessionHandlePolicy | s.lastSession&0xFFFFFF)
+	newSession := &session{
+		sessionType: sessionType,
+		hash:        hash,
+		nonceTPM:    randomBytes(hash.Size()),
+	}
+	newSession.reset()
+	s.sessions[handle] = newSession
+
+	var params bytes.Buffer
+	mustWrite(&params, tpmutil.U16Bytes(newSession.nonceTPM))
+	return &response{handles: []tpmutil.Handle{handle}, params: params.Bytes()}, tpmutil.RCSuccess
+}
+
+func (s *Simulator) flushContext(cmd *command) (*response, tpmutil.ResponseCode) {
+	var handle tpmutil.Handle
+	if rc := readParams(cmd, &handle); rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+	switch {
+	case s.sessions[handle] != nil:
+		delete(s.sessions, handle)
+	case s.objects[handle] != nil:
+		delete(s.objects, handle)
+	default:
+		return nil, rcParam(tpm2.RCHandle, 1)
+	}
+	return &response{}, tpmutil.RCSuccess
+}
+
+func (s *Simulator) policySession(cmd *command) (*session, tpmutil.ResponseCode) {
+	session := s.sessions[cmd.handles[0]]
+	if session == nil {
+		return nil, rcHandle(tpm2.RCHandle, 1)
+	}
+	return session, tpmutil.RCSuccess
+}
+
+func (s *Simulator) policyOr(cmd *command) (*response, tpmutil.ResponseCode) {
+	session, rc := s.policySession(cmd)
+	if rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+	var count uint32
+	if rc := readParams(cmd, &count); rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+	if count < 2 || count > 8 {
+		return nil, rcParam(tpm2.RCSize, 1)
+	}
+	var digests [][]byte
+	found := session.sessionType == tpm2.SessionTrial
+	for idx := uint32(0); idx < count; idx++ {
+		var digest tpmutil.U16Bytes
+		if rc := readParams(cmd, &digest); rc != tpmutil.RCSuccess {
+			return nil, rc
+		}
+		found = found || bytes.Equal(digest, session.policyDigest)
+		digests = append(digests, digest)
+	}
+	if !found {
+		return nil, rcParam(tpm2.RCValue, 1)
+	}
+	session.extend(make([]byte, session.hash.Size()), tpm2.CmdPolicyOr, digests...)
+	return &response{}, tpmutil.RCSuccess
+}
+
+func (s *Simulator) policyCommandCode(cmd *command) (*response, tpmutil.ResponseCode) {
+	session, rc := s.policySession(cmd)
+	if rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+	var commandCode tpmutil.Command
+	if rc := readParams(cmd, &commandCode); rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+	if session.commandCode != 0 && session.commandCode != commandCode {
+		return nil, rcParam(tpm2.RCValue, 1)
+	}
+	session.extend(session.policyDigest, tpm2.CmdPolicyCommandCode, must(tpmutil.Pack(commandCode)))
+	session.commandCode = commandCode
+	return &response{}, tpmutil.RCSuccess
+}
+
+func (s *Simulator) policyGetDigest(cmd *command) (*response, tpmutil.ResponseCode) {
+	session, rc := s.policySession(cmd)
+	if rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+	var params bytes.Buffer
+	mustWrite(&params, tpmutil.U16Bytes(session.policyDigest))
+	return &response{params: params.Bytes()}, tpmutil.RCSuccess
+}
+
+// checkPolicy checks the policy session authorizing the handle with the
+// index authIdx.
+func (s *Simulator) checkPolicy(cmd *command, authIdx int, authPolicy []byte) tpmutil.ResponseCode {
+	if authIdx >= len(cmd.sessions) {
+		return rcFmt0(tpm2.RCAuthMissing)
+	}
+	session := s.sessions[cmd.sessions[authIdx].Session]
+	if session == nil {
+		return rcSession(tpm2.RCHandle, authIdx+1)
+	}
+	if session.sessionType == tpm2.SessionTrial {
+		return rcSession(tpm2.RCAttributes, authIdx+1)
+	}
+	if len(authPolicy) == 0 || !bytes.Equal(session.policyDigest, authPolicy) {
+		return rcSession(tpm2.RCPolicyFail, authIdx+1)
+	}
+	if session.commandCode != 0 && session.commandCode != cmd.code {
+		return rcSession(tpm2.RCPolicyCC, authIdx+1)
+	}
+	return tpmutil.RCSuccess
+}
+
+// releaseSessions resets the policy sessions used to authorize the
+// successfully executed command, or flushes them if continueSession
+// is not set.
+func (s *Simulator) releaseSessions(cmd *command) {
+	for _, authCmd := range cmd.sessions {
+		session := s.sessions[authCmd.Session]
+		if session == nil {
+			continue
+		}
+		if authCmd.Attributes&tpm2.AttrContinueSession == 0 {
+			delete(s.sessions, authCmd.Session)
+			continue
+		}
+		session.reset()
+		session.nonceTPM = randomBytes(session.hash.Size())
+	}
+}
@@ -0,0 +1,465 @@
+// Package simulator implements an in-process TPM 2.0 simulator. It supports
+// the subset of TPM 2.0 commands required to provision and check the
+// Intel TXT NV indices (NV storage, policy sessions, PCRs) and to quote
+// PCRs by a primary signing key, so the provisioning and the attestation
+// could be executed and tested without a hardware TPM.
+//
+// The simulator is not a security device: the authorization values and
+// the policies are checked, but HMAC sessions, parameter encryption and
+// dictionary attack protection are not supported.
+package simulator
+
+import (
+	"bytes"
+	"crypto/rand"
+	_ "crypto/sha1"   // the hash of the SHA1 PCR bank
+	_ "crypto/sha256" // the hash of the SHA256 PCR bank and sessions
+	_ "crypto/sha512" // SHA384 and SHA512 sessions
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/google/go-tpm/legacy/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+const (
+	// Manufacturer is the TPM_PT_MANUFACTURER reported by the simulator ("SIM ").
+	Manufacturer = 0x53494D20
+
+	// AmountOfPCRs is the amount of PCRs in each PCR bank.
+	AmountOfPCRs = 24
+
+	maxNVBufferSize = 1024
+	maxNVIndexSize  = 2048
+	maxSessions     = 64
+
+	sessionHandlePolicy = 0x03000000
+	handleTypeNVIndex   = 0x01
+)
+
+// pcrBanks are the PCR banks of the simulator.
+var pcrBanks = []tpm2.Algorithm{tpm2.AlgSHA1, tpm2.AlgSHA256}
+
+// Simulator is an in-process TPM 2.0. It implements io.ReadWriteCloser the
+// same way as /dev/tpmrm0: a command is written by a single Write and its
+// response is returned by the next Read.
+//
+// The state is kept in memory until the Simulator is garbage collected,
+// Close does not reset it.
+type Simulator struct {
+	locker sync.Mutex
+
+	response      []byte
+	hierarchyAuth map[tpmutil.Handle][]byte
+	nvIndices     map[tpmutil.Handle]*nvIndex
+	sessions      map[tpmutil.Handle]*session
+	lastSession   uint32
+	objects       map[tpmutil.Handle]*object
+	lastObject    uint32
+	pcrs          map[tpm2.Algorithm][][]byte
+}
+
+var _ io.ReadWriteCloser = (*Simulator)(nil)
+
+// New returns a started TPM 2.0 simulator with empty authorization values
+// of all the hierarchies and without NV indices.
+func New() *Simulator {
+	s := &Simulator{
+		hierarchyAuth: map[tpmutil.Handle][]byte{},
+		nvIndices:     map[tpmutil.Handle]*nvIndex{},
+		sessions:      map[tpmutil.Handle]*session{},
+		objects:       map[tpmutil.Handle]*object{},
+		pcrs:          map[tpm2.Algorithm][][]byte{},
+	}
+	for _, alg := range pcrBanks {
+		hash, _ := alg.Hash()
+		s.pcrs[alg] = make([][]byte, AmountOfPCRs)
+		for idx := range s.pcrs[alg] {
+			s.pcrs[alg][idx] = make([]byte, hash.Size())
+		}
+	}
+	return s
+}
+
+// Write executes the TPM command.
+func (s *Simulator) Write(cmd []byte) (int, error) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	if s.response != nil {
+		return 0, fmt.Errorf("the response of the previous command was not read")
+	}
+	s.response = s.execute(cmd)
+	return len(cmd), nil
+}
+
+// Read returns the response of the last command.
+func (s *Simulator) Read(b []byte) (int, error) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	if s.response == nil {
+		return 0, fmt.Errorf("no command was sent")
+	}
+	if len(b) < len(s.response) {
+		return 0, fmt.Errorf("the buffer is too small for the response: %d < %d", len(b), len(s.response))
+	}
+	n := copy(b, s.response)
+	s.response = nil
+	return n, nil
+}
+
+// Close discards an unread response. The state of the simulator is kept,
+// so it could be used again.
+func (s *Simulator) Close() error {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	s.response = nil
+	return nil
+}
+
+// command is a parsed TPM command.
+type command struct {
+	code     tpmutil.Command
+	handles  []tpmutil.Handle
+	sessions []tpm2.AuthCommand
+	params   *bytes.Reader
+}
+
+// response is a result of a successfully executed command.
+type response struct {
+	handles []tpmutil.Handle
+	params  []byte
+}
+
+// commandHandler executes a command. The command sessions are already
+// parsed, but the authorization is checked by the handler.
+type commandHandler func(s *Simulator, cmd *command) (*response, tpmutil.ResponseCode)
+
+// commandInfo describes the command layout required to parse it.
+type commandInfo struct {
+	handles     int
+	authHandles int
+	handler     commandHandler
+}
+
+var commands = map[tpmutil.Command]commandInfo{
+	tpm2.CmdStartup:                {handler: (*Simulator).startupOrShutdown},
+	tpm2.CmdShutdown:               {handler: (*Simulator).startupOrShutdown},
+	tpm2.CmdGetCapability:          {handler: (*Simulator).getCapability},
+	tpm2.CmdHierarchyChangeAuth:    {handles: 1, authHandles: 1, handler: (*Simulator).hierarchyChangeAuth},
+	tpm2.CmdPCRRead:                {handler: (*Simulator).pcrRead},
+	tpm2.CmdPCRExtend:              {handles: 1, authHandles: 1, handler: (*Simulator).pcrExtend},
+	tpm2.CmdStartAuthSession:       {handles: 2, handler: (*Simulator).startAuthSession},
+	tpm2.CmdFlushContext:           {handler: (*Simulator).flushContext},
+	tpm2.CmdPolicyOr:               {handles: 1, handler: (*Simulator).policyOr},
+	tpm2.CmdPolicyCommandCode:      {handles: 1, handler: (*Simulator).policyCommandCode},
+	tpm2.CmdPolicyGetDigest:        {handles: 1, handler: (*Simulator).policyGetDigest},
+	tpm2.CmdDefineSpace:            {handles: 1, authHandles: 1, handler: (*Simulator).nvDefineSpace},
+	tpm2.CmdUndefineSpace:          {handles: 2, authHandles: 1, handler: (*Simulator).nvUndefineSpace},
+	tpm2.CmdCreatePrimary:          {handles: 1, authHandles: 1, handler: (*Simulator).createPrimary},
+	tpm2.CmdReadPublic:             {handles: 1, handler: (*Simulator).readPublic},
+	tpm2.CmdQuote:                  {handles: 1, authHandles: 1, handler: (*Simulator).quote},
+	tpm2.CmdNVUndefineSpaceSpecial: {handles: 2, authHandles: 2, handler: (*Simulator).nvUndefineSpaceSpecial},
+	tpm2.CmdReadPublicNV:           {handles: 1, handler: (*Simulator).nvReadPublic},
+	tpm2.CmdReadNV:                 {handles: 2, authHandles: 1, handler: (*Simulator).nvRead},
+	tpm2.CmdWriteNV:                {handles: 2, authHandles: 1, handler: (*Simulator).nvWrite},
+}
+
+func (s *Simulator) execute(cmdBytes []byte) []byte {
+	var header struct {
+		Tag  tpmutil.Tag
+		Size uint32
+		Code tpmutil.Command
+	}
+	buf := bytes.NewReader(cmdBytes)
+	if err := binary.Read(buf, binary.BigEndian, &header); err != nil || int(header.Size) != len(cmdBytes) {
+		return errorResponse(rcFmt0(tpm2.RCCommandSize))
+	}
+	info, ok := commands[header.Code]
+	if !ok {
+		return errorResponse(rcFmt0(tpm2.RCCommandCode))
+	}
+
+	cmd := &command{code: header.Code}
+	for idx := 0; idx < info.handles; idx++ {
+		var handle tpmutil.Handle
+		if err := binary.Read(buf, binary.BigEndian, &handle); err != nil {
+			return errorResponse(rcFmt0(tpm2.RCCommandSize))
+		}
+		cmd.handles = append(cmd.handles, handle)
+	}
+
+	switch header.Tag {
+	case tpm2.TagNoSessions:
+		if info.authHandles > 0 {
+			return errorResponse(rcFmt0(tpm2.RCAuthMissing))
+		}
+	case tpm2.TagSessions:
+		if info.authHandles == 0 {
+			return errorResponse(rcFmt0(tpm2.RCAuthContext))
+		}
+		sessions, rc := parseSessions(buf, info.authHandles)
+		if rc != tpmutil.RCSuccess {
+			return errorResponse(rc)
+		}
+		cmd.sessions = sessions
+	default:
+		return errorResponse(rcFmt1(tpm2.RCTag))
+	}
+	cmd.params = buf
+
+	resp, rc := info.handler(s, cmd)
+	if rc != tpmutil.RCSuccess {
+		return errorResponse(rc)
+	}
+	s.releaseSessions(cmd)
+	return s.successResponse(header.Tag, cmd, resp)
+}
+
+func parseSessions(buf *bytes.Reader, authHandles int) ([]tpm2.AuthCommand, tpmutil.ResponseCode) {
+	var authSize uint32
+	if err := binary.Read(buf, binary.BigEndian, &authSize); err != nil || int(authSize) > buf.Len() {
+		return nil, rcFmt0(tpm2.RCAuthSize)
+	}
+	authArea := make([]byte, authSize)
+	if _, err := io.ReadFull(buf, authArea); err != nil {
+		return nil, rcFmt0(tpm2.RCAuthSize)
+	}
+
+	var sessions []tpm2.AuthCommand
+	for len(authArea) > 0 {
+		var session tpm2.AuthCommand
+		read, err := tpmutil.Unpack(authArea, &session.Session, &session.Nonce, &session.Attributes, &session.Auth)
+		if err != nil {
+			return nil, rcFmt0(tpm2.RCAuthSize)
+		}
+		authArea = authArea[read:]
+		sessions = append(sessions, session)
+	}
+	if len(sessions) == 0 || len(sessions) > authHandles {
+		return nil, rcFmt0(tpm2.RCAuthSize)
+	}
+	return sessions, tpmutil.RCSuccess
+}
+
+func (s *Simulator) successResponse(tag tpmutil.Tag, cmd *command, resp *response) []byte {
+	var body bytes.Buffer
+	for _, handle := range resp.handles {
+		mustWrite(&body, handle)
+	}
+	if tag == tpm2.TagSessions {
+		mustWrite(&body, uint32(len(resp.params)))
+		body.Write(resp.params)
+		for _, authCmd := range cmd.sessions {
+			var nonce tpmutil.U16Bytes
+			if session := s.sessions[authCmd.Session]; session != nil {
+				nonce = session.nonceTPM
+			}
+			mustWrite(&body, nonce, authCmd.Attributes&tpm2.AttrContinueSession, tpmutil.U16Bytes(nil))
+		}
+	} else {
+		body.Write(resp.params)
+	}
+
+	var out bytes.Buffer
+	mustWrite(&out, tag, uint32(10+body.Len()), tpmutil.RCSuccess)
+	out.Write(body.Bytes())
+	return out.Bytes()
+}
+
+func errorResponse(rc tpmutil.ResponseCode) []byte {
+	var out bytes.Buffer
+	mustWrite(&out, tpm2.TagNoSessions, uint32(10), rc)
+	return out.Bytes()
+}
+
+// mustWrite packs the values into the buffer. The values are always of the
+// types supported by tpmutil and bytes.Buffer does not fail, so the error is
+// not expected.
+func mustWrite(buf *bytes.Buffer, values ...interface{}) {
+	b, err := tpmutil.Pack(values...)
+	if err != nil {
+		panic(fmt.Sprintf("unable to pack %v: %v", values, err))
+	}
+	buf.Write(b)
+}
+
+// readParams unpacks the command parameters, a failure is reported as
+// an error of the first parameter.
+func readParams(cmd *command, values ...interface{}) tpmutil.ResponseCode {
+	if err := tpmutil.UnpackBuf(paramsReader{cmd.params}, values...); err != nil {
+		return rcParam(tpm2.RCInsufficient, 1)
+	}
+	return tpmutil.RCSuccess
+}
+
+// paramsReader allows reading zero bytes at the end of the parameters,
+// which is required to unpack an empty TPM2B as the last parameter
+// (bytes.Reader returns io.EOF in this case).
+type paramsReader struct {
+	*bytes.Reader
+}
+
+func (r paramsReader) Read(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	return r.Reader.Read(b)
+}
+
+// startupOrShutdown does nothing: the simulator is always started and
+// the state is not saved.
+func (s *Simulator) startupOrShutdown(cmd *command) (*response, tpmutil.ResponseCode) {
+	var startupType tpm2.StartupType
+	if rc := readParams(cmd, &startupType); rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+	return &response{}, tpmutil.RCSuccess
+}
+
+func (s *Simulator) getCapability(cmd *command) (*response, tpmutil.ResponseCode) {
+	var capability tpm2.Capability
+	var property, count uint32
+	if rc := readParams(cmd, &capability, &property, &count); rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+
+	var data bytes.Buffer
+	switch capability {
+	case tpm2.CapabilityTPMProperties:
+		var props []tpm2.TaggedProperty
+		for _, prop := range []tpm2.TaggedProperty{
+			{Tag: tpm2.FamilyIndicator, Value: 0x322E3000}, // "2.0"
+			{Tag: tpm2.Manufacturer, Value: Manufacturer},
+			{Tag: tpm2.NVMaxBufferSize, Value: maxNVBufferSize},
+		} {
+			if uint32(prop.Tag) >= property && uint32(len(props)) < count {
+				props = append(props, prop)
+			}
+		}
+		mustWrite(&data, uint32(len(props)))
+		for _, prop := range props {
+			mustWrite(&data, prop)
+		}
+	case tpm2.CapabilityHandles:
+		var handles []tpmutil.Handle
+		for _, handle := range s.handles() {
+			if handle>>24 == tpmutil.Handle(property>>24) && uint32(handle) >= property && uint32(len(handles)) < count {
+				handles = append(handles, handle)
+			}
+		}
+		mustWrite(&data, uint32(len(handles)))
+		for _, handle := range handles {
+			mustWrite(&data, handle)
+		}
+	case tpm2.CapabilityPCRs:
+		mustWrite(&data, uint32(len(pcrBanks)))
+		for _, alg := range pcrBanks {
+			mustWrite(&data, alg, uint8(3), [3]byte{0xFF, 0xFF, 0xFF})
+		}
+	default:
+		return nil, rcParam(tpm2.RCValue, 1)
+	}
+
+	var params bytes.Buffer
+	mustWrite(&params, uint8(0), capability)
+	params.Write(data.Bytes())
+	return &response{params: params.Bytes()}, tpmutil.RCSuccess
+}
+
+func (s *Simulator) hierarchyChangeAuth(cmd *command) (*response, tpmutil.ResponseCode) {
+	hierarchy := cmd.handles[0]
+	if !isHierarchy(hierarchy) {
+		return nil, rcHandle(tpm2.RCHierarchy, 1)
+	}
+	if rc := s.checkPassword(cmd, 0, s.hierarchyAuth[hierarchy]); rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+	var newAuth tpmutil.U16Bytes
+	if rc := readParams(cmd, &newAuth); rc != tpmutil.RCSuccess {
+		return nil, rc
+	}
+	if len(newAuth) > 64 {
+		return nil, rcParam(tpm2.RCSize, 1)
+	}
+	s.hierarchyAuth[hierarchy] = newAuth
+	return &response{}, tpmutil.RCSuccess
+}
+
+func isHierarchy(handle tpmutil.Handle) bool {
+	switch handle {
+	case tpm2.HandleOwner, tpm2.HandlePlatform, tpm2.HandleLockout, tpm2.HandleEndorsement:
+		return true
+	}
+	return false
+}
+
+// checkPassword checks the password session authorizing the handle with
+// the index authIdx.
+func (s *Simulator) checkPassword(cmd *command, authIdx int, authValue []byte) tpmutil.ResponseCode {
+	if authIdx >= len(cmd.sessions) {
+		return rcFmt0(tpm2.RCAuthMissing)
+	}
+	session := cmd.sessions[authIdx]
+	if session.Session != tpm2.HandlePasswordSession {
+		return rcSession(tpm2.RCHandle, authIdx+1)
+	}
+	if !bytes.Equal(session.Auth, authValue) {
+		return rcSession(tpm2.RCAuthFail, authIdx+1)
+	}
+	return tpmutil.RCSuccess
+}
+
+func (s *Simulator) handles() []tpmutil.Handle {
+	var handles []tpmutil.Handle
+	for handle := range s.nvIndices {
+		handles = append(handles, handle)
+	}
+	for handle := range s.sessions {
+		handles = append(handles, handle)
+	}
+	for handle := range s.objects {
+		handles = append(handles, handle)
+	}
+	sort.Slice(handles, func(i, j int) bool {
+		return handles[i] < handles[j]
+	})
+	return handles
+}
+
+func randomBytes(size int) []byte {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("unable to generate random bytes: %v", err))
+	}
+	return b
+}
+
+const (
+	rcVer1       = 0x100
+	rcFmt1Flag   = 0x080
+	rcParamFlag  = 0x040
+	rcSessionNum = 0x800
+)
+
+func rcFmt0(code tpm2.RCFmt0) tpmutil.ResponseCode {
+	return tpmutil.ResponseCode(rcVer1 | uint32(code))
+}
+
+func rcFmt1(code tpm2.RCFmt1) tpmutil.ResponseCode {
+	return tpmutil.ResponseCode(rcFmt1Flag | uint32(code))
+}
+
+func rcHandle(code tpm2.RCFmt1, handleNum int) tpmutil.ResponseCode {
+	return rcFmt1(code) | tpmutil.ResponseCode(handleNum<<8)
+}
+
+func rcParam(code tpm2.RCFmt1, paramNum int) tpmutil.ResponseCode {
+	return rcFmt1(code) | rcParamFlag | tpmutil.ResponseCode(paramNum<<8)
+}
+
+func rcSession(code tpm2.RCFmt1, sessionNum int) tpmutil.ResponseCode {
+	return rcFmt1(code) | rcSessionNum | tpmutil.ResponseCode(sessionNum<<8)
+}
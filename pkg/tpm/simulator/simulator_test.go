@@ -0,0 +1,304 @@
+package simulator
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/google/go-tpm/legacy/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+	"github.com/stretchr/testify/require"
+)
+
+var passwordAuth = tpm2.AuthCommand{Session: tpm2.HandlePasswordSession, Attributes: tpm2.AttrContinueSession}
+
+func TestCapabilities(t *testing.T) {
+	rw := New()
+	require.NoError(t, tpm2.Startup(rw, tpm2.StartupClear))
+
+	manufacturer, err := tpm2.GetManufacturer(rw)
+	require.NoError(t, err)
+	require.Equal(t, []byte("SIM "), manufacturer)
+
+	props, _, err := tpm2.GetCapability(rw, tpm2.CapabilityTPMProperties, 1, uint32(tpm2.NVMaxBufferSize))
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{tpm2.TaggedProperty{Tag: tpm2.NVMaxBufferSize, Value: maxNVBufferSize}}, props)
+
+	banks, _, err := tpm2.GetCapability(rw, tpm2.CapabilityPCRs, 1, 0)
+	require.NoError(t, err)
+	require.Len(t, banks, 2)
+
+	// not supported
+	_, _, err = tpm2.ReadClock(rw)
+	require.ErrorIs(t, err, tpm2.Error{Code: tpm2.RCCommandCode})
+}
+
+func TestPCRs(t *testing.T) {
+	rw := New()
+
+	pcr0, err := tpm2.ReadPCR(rw, 0, tpm2.AlgSHA256)
+	require.NoError(t, err)
+	require.Equal(t, make([]byte, sha256.Size), pcr0)
+
+	digest := sha256.Sum256([]byte("test"))
+	require.NoError(t, tpm2.PCRExtend(rw, 0, tpm2.AlgSHA256, digest[:], ""))
+	expected := sha256.Sum256(append(make([]byte, sha256.Size), digest[:]...))
+	pcr0, err = tpm2.ReadPCR(rw, 0, tpm2.AlgSHA256)
+	require.NoError(t, err)
+	require.Equal(t, expected[:], pcr0)
+
+	pcr0SHA1, err := tpm2.ReadPCR(rw, 0, tpm2.AlgSHA1)
+	require.NoError(t, err)
+	require.Equal(t, make([]byte, 20), pcr0SHA1)
+}
+
+func TestNVIndex(t *testing.T) {
+	rw := New()
+	const index = tpmutil.Handle(0x01500000)
+
+	_, err := tpm2.NVReadPublic(rw, index)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "error code 0xb")
+
+	attrs := tpm2.AttrOwnerWrite + tpm2.AttrAuthRead + tpm2.AttrAuthWrite + tpm2.AttrNoDA
+	require.NoError(t, tpm2.NVDefineSpace(rw, tpm2.HandleOwner, index, "", "pass", nil, attrs, 8))
+	err = tpm2.NVDefineSpace(rw, tpm2.HandleOwner, index, "", "pass", nil, attrs, 8)
+	require.ErrorIs(t, err, tpm2.Error{Code: tpm2.RCNVDefined})
+	// platform-created indices require AttrPlatformCreate
+	err = tpm2.NVDefineSpace(rw, tpm2.HandlePlatform, index+1, "", "", nil, attrs, 8)
+	require.Error(t, err)
+
+	_, err = tpm2.NVReadEx(rw, index, index, "pass", 0)
+	require.ErrorContains(t, err, tpm2.Error{Code: tpm2.RCNVUninitialized}.Error())
+
+	require.NoError(t, tpm2.NVWrite(rw, tpm2.HandleOwner, index, "", []byte{1, 2, 3, 4}, 2))
+	require.Error(t, tpm2.NVWrite(rw, index, index, "wrong", []byte{1}, 0))
+	require.Error(t, tpm2.NVWrite(rw, index, index, "pass", []byte{1}, 8))
+	require.NoError(t, tpm2.NVWrite(rw, index, index, "pass", []byte{0}, 7))
+
+	pub, err := tpm2.NVReadPublic(rw, index)
+	require.NoError(t, err)
+	require.Equal(t, attrs|tpm2.AttrWritten, pub.Attributes)
+	require.Equal(t, uint16(8), pub.DataSize)
+
+	data, err := tpm2.NVReadEx(rw, index, index, "pass", 0)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0xFF, 0xFF, 1, 2, 3, 4, 0xFF, 0}, data)
+	_, err = tpm2.NVReadEx(rw, index, tpm2.HandleOwner, "", 0)
+	require.ErrorContains(t, err, tpm2.Error{Code: tpm2.RCNVAuthorization}.Error())
+
+	require.NoError(t, tpm2.NVUndefineSpace(rw, "", tpm2.HandleOwner, index))
+	_, err = tpm2.NVReadPublic(rw, index)
+	require.Error(t, err)
+}
+
+func TestHierarchyChangeAuth(t *testing.T) {
+	rw := New()
+	require.NoError(t, tpm2.HierarchyChangeAuth(rw, tpm2.HandleOwner, passwordAuth, "owner"))
+	err := tpm2.NVDefineSpace(rw, tpm2.HandleOwner, 0x01500000, "", "", nil, tpm2.AttrOwnerWrite+tpm2.AttrOwnerRead, 8)
+	require.Error(t, err)
+	require.NoError(t, tpm2.NVDefineSpace(rw, tpm2.HandleOwner, 0x01500000, "owner", "", nil, tpm2.AttrOwnerWrite+tpm2.AttrOwnerRead, 8))
+}
+
+func startPolicySession(t *testing.T, rw *Simulator, sessionType tpm2.SessionType) tpmutil.Handle {
+	session, nonce, err := tpm2.StartAuthSession(rw, tpm2.HandleNull, tpm2.HandleNull, make([]byte, 16), nil, sessionType, tpm2.AlgNull, tpm2.AlgSHA256)
+	require.NoError(t, err)
+	require.Len(t, nonce, sha256.Size)
+	return session
+}
+
+func TestPolicySession(t *testing.T) {
+	rw := New()
+	zeroDigest := make([]byte, sha256.Size)
+	otherDigest := bytes.Repeat([]byte{1}, sha256.Size)
+
+	session := startPolicySession(t, rw, tpm2.SessionPolicy)
+	digest, err := tpm2.PolicyGetDigest(rw, session)
+	require.NoError(t, err)
+	require.Equal(t, zeroDigest, []byte(digest))
+
+	// TPM2_PolicyOR
+	require.NoError(t, tpm2.PolicyOr(rw, session, tpm2.TPMLDigest{Digests: []tpmutil.U16Bytes{otherDigest, zeroDigest}}))
+	h := sha256.New()
+	h.Write(zeroDigest)
+	h.Write([]byte{0x00, 0x00, 0x01, 0x71})
+	h.Write(otherDigest)
+	h.Write(zeroDigest)
+	expected := h.Sum(nil)
+	digest, err = tpm2.PolicyGetDigest(rw, session)
+	require.NoError(t, err)
+	require.Equal(t, expected, []byte(digest))
+
+	// the current digest is not in the list
+	err = tpm2.PolicyOr(rw, session, tpm2.TPMLDigest{Digests: []tpmutil.U16Bytes{otherDigest, zeroDigest}})
+	require.ErrorIs(t, err, tpm2.ParameterError{Code: tpm2.RCValue, Parameter: tpm2.RC1})
+
+	// TPM2_PolicyCommandCode
+	require.NoError(t, tpm2.PolicyCommandCode(rw, session, tpm2.CmdNVUndefineSpaceSpecial))
+	h = sha256.New()
+	h.Write(expected)
+	h.Write([]byte{0x00, 0x00, 0x01, 0x6C, 0x00, 0x00, 0x01, 0x1F})
+	digest, err = tpm2.PolicyGetDigest(rw, session)
+	require.NoError(t, err)
+	require.Equal(t, h.Sum(nil), []byte(digest))
+	require.Error(t, tpm2.PolicyCommandCode(rw, session, tpm2.CmdWriteNV))
+
+	require.NoError(t, tpm2.FlushContext(rw, session))
+	require.Error(t, tpm2.FlushContext(rw, session))
+	_, err = tpm2.PolicyGetDigest(rw, session)
+	require.Error(t, err)
+
+	// a trial session does not check the current digest
+	trial := startPolicySession(t, rw, tpm2.SessionTrial)
+	require.NoError(t, tpm2.PolicyOr(rw, trial, tpm2.TPMLDigest{Digests: []tpmutil.U16Bytes{otherDigest, otherDigest}}))
+	require.NoError(t, tpm2.FlushContext(rw, trial))
+
+	_, _, err = tpm2.StartAuthSession(rw, tpm2.HandleNull, tpm2.HandleNull, make([]byte, 16), nil, tpm2.SessionHMAC, tpm2.AlgNull, tpm2.AlgSHA256)
+	require.Error(t, err)
+}
+
+func TestPolicyAuthorizedNVIndex(t *testing.T) {
+	rw := New()
+	const index = tpmutil.Handle(0x01C10103)
+	zeroDigest := make([]byte, sha256.Size)
+	secretDigest := bytes.Repeat([]byte{0xAA}, sha256.Size)
+	branches := tpm2.TPMLDigest{Digests: []tpmutil.U16Bytes{secretDigest, zeroDigest}}
+
+	// compute the policy: PolicyOR(secret, zero) && PolicyCommandCode(NV_UndefineSpaceSpecial)
+	trial := startPolicySession(t, rw, tpm2.SessionTrial)
+	require.NoError(t, tpm2.PolicyOr(rw, trial, branches))
+	writePolicy, err := tpm2.PolicyGetDigest(rw, trial)
+	require.NoError(t, err)
+	require.NoError(t, tpm2.PolicyCommandCode(rw, trial, tpm2.CmdNVUndefineSpaceSpecial))
+	deletePolicy, err := tpm2.PolicyGetDigest(rw, trial)
+	require.NoError(t, err)
+	require.NoError(t, tpm2.FlushContext(rw, trial))
+
+	require.NoError(t, tpm2.NVDefineSpaceEx(rw, tpm2.HandlePlatform, "", tpm2.NVPublic{
+		NVIndex:    index,
+		NameAlg:    tpm2.AlgSHA256,
+		Attributes: tpm2.AttrPolicyWrite + tpm2.AttrPolicyDelete + tpm2.AttrAuthRead + tpm2.AttrNoDA + tpm2.AttrPlatformCreate,
+		AuthPolicy: deletePolicy,
+		DataSize:   4,
+	}, passwordAuth))
+	require.Error(t, tpm2.NVUndefineSpace(rw, "", tpm2.HandlePlatform, index))
+
+	session := startPolicySession(t, rw, tpm2.SessionPolicy)
+	policyAuth := tpm2.AuthCommand{Session: session, Attributes: tpm2.AttrContinueSession}
+
+	// the policy is not satisfied
+	err = tpm2.NVUndefineSpaceSpecial(rw, index, policyAuth, passwordAuth)
+	require.ErrorIs(t, err, tpm2.SessionError{Code: tpm2.RCPolicyFail, Session: tpm2.RC1})
+
+	// the policy is satisfied, but restricted to another command
+	require.NoError(t, tpm2.PolicyOr(rw, session, branches))
+	require.NoError(t, tpm2.PolicyCommandCode(rw, session, tpm2.CmdNVUndefineSpaceSpecial))
+	err = tpm2.NVWriteEx(rw, index, index, policyAuth, []byte{1, 2, 3, 4}, 0)
+	require.Error(t, err)
+
+	require.NoError(t, tpm2.NVUndefineSpaceSpecial(rw, index, policyAuth, passwordAuth))
+	_, err = tpm2.NVReadPublic(rw, index)
+	require.Error(t, err)
+
+	// the session is reset after the authorization
+	digest, err := tpm2.PolicyGetDigest(rw, session)
+	require.NoError(t, err)
+	require.Equal(t, zeroDigest, []byte(digest))
+	require.NotEqual(t, writePolicy, deletePolicy)
+	require.NoError(t, tpm2.FlushContext(rw, session))
+}
+
+func TestQuote(t *testing.T) {
+	rw := New()
+	digest := sha256.Sum256([]byte("test"))
+	require.NoError(t, tpm2.PCRExtend(rw, 0, tpm2.AlgSHA256, digest[:], ""))
+	pcr0, err := tpm2.ReadPCR(rw, 0, tpm2.AlgSHA256)
+	require.NoError(t, err)
+	pcr1, err := tpm2.ReadPCR(rw, 1, tpm2.AlgSHA256)
+	require.NoError(t, err)
+	sel := tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: []int{0, 1}}
+	expectedPCRDigest := sha256.Sum256(append(pcr0, pcr1...))
+
+	for name, template := range map[string]tpm2.Public{
+		"rsa": {
+			Type:       tpm2.AlgRSA,
+			NameAlg:    tpm2.AlgSHA256,
+			Attributes: tpm2.FlagSignerDefault,
+			RSAParameters: &tpm2.RSAParams{
+				Sign:    &tpm2.SigScheme{Alg: tpm2.AlgRSASSA, Hash: tpm2.AlgSHA256},
+				KeyBits: 2048,
+			},
+		},
+		"ecc": {
+			Type:       tpm2.AlgECC,
+			NameAlg:    tpm2.AlgSHA256,
+			Attributes: tpm2.FlagSignerDefault,
+			ECCParameters: &tpm2.ECCParams{
+				Sign:    &tpm2.SigScheme{Alg: tpm2.AlgECDSA, Hash: tpm2.AlgSHA256},
+				CurveID: tpm2.CurveNISTP256,
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, tpm2.HierarchyChangeAuth(rw, tpm2.HandleEndorsement, passwordAuth, "endorsement"))
+			defer func() {
+				require.NoError(t, tpm2.HierarchyChangeAuth(rw, tpm2.HandleEndorsement, tpm2.AuthCommand{
+					Session:    tpm2.HandlePasswordSession,
+					Attributes: tpm2.AttrContinueSession,
+					Auth:       []byte("endorsement"),
+				}, ""))
+			}()
+			_, _, err := tpm2.CreatePrimary(rw, tpm2.HandleEndorsement, tpm2.PCRSelection{}, "", "", template)
+			require.Error(t, err)
+
+			ak, akPub, err := tpm2.CreatePrimary(rw, tpm2.HandleEndorsement, sel, "endorsement", "ak", template)
+			require.NoError(t, err)
+			public, _, _, err := tpm2.ReadPublic(rw, ak)
+			require.NoError(t, err)
+			name, err := public.Name()
+			require.NoError(t, err)
+
+			attest, sig, err := tpm2.Quote(rw, ak, "ak", "", []byte("nonce"), sel, tpm2.AlgNull)
+			require.NoError(t, err)
+			attestData, err := tpm2.DecodeAttestationData(attest)
+			require.NoError(t, err)
+			require.Equal(t, tpm2.TagAttestQuote, attestData.Type)
+			require.Equal(t, []byte("nonce"), []byte(attestData.ExtraData))
+			require.Equal(t, sel, attestData.AttestedQuoteInfo.PCRSelection)
+			require.Equal(t, expectedPCRDigest[:], []byte(attestData.AttestedQuoteInfo.PCRDigest))
+			require.NotEqual(t, name, attestData.QualifiedSigner)
+
+			attestDigest := sha256.Sum256(attest)
+			switch pub := akPub.(type) {
+			case *rsa.PublicKey:
+				require.NoError(t, rsa.VerifyPKCS1v15(pub, crypto.SHA256, attestDigest[:], sig.RSA.Signature))
+			case *ecdsa.PublicKey:
+				require.True(t, ecdsa.Verify(pub, attestDigest[:], sig.ECC.R, sig.ECC.S))
+			default:
+				t.Fatalf("unexpected key type %T", akPub)
+			}
+
+			_, _, err = tpm2.Quote(rw, ak, "wrong", "", nil, sel, tpm2.AlgNull)
+			require.Error(t, err)
+
+			require.NoError(t, tpm2.FlushContext(rw, ak))
+			_, _, err = tpm2.Quote(rw, ak, "ak", "", nil, sel, tpm2.AlgNull)
+			require.Error(t, err)
+		})
+	}
+
+	// only signing keys are supported
+	_, _, err = tpm2.CreatePrimary(rw, tpm2.HandleOwner, tpm2.PCRSelection{}, "", "", tpm2.Public{
+		Type:       tpm2.AlgRSA,
+		NameAlg:    tpm2.AlgSHA256,
+		Attributes: tpm2.FlagStorageDefault,
+		RSAParameters: &tpm2.RSAParams{
+			Symmetric: &tpm2.SymScheme{Alg: tpm2.AlgAES, KeyBits: 128, Mode: tpm2.AlgCFB},
+			KeyBits:   2048,
+		},
+	})
+	require.ErrorIs(t, err, tpm2.ParameterError{Code: tpm2.RCAttributes, Parameter: tpm2.RC2})
+}
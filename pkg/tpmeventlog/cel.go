@@ -0,0 +1,558 @@
+package tpmeventlog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// The TCG Canonical Event Log (CEL) is defined in
+// "TCG Canonical Event Log Format", version 1.0.
+//
+// A CEL is a sequence of records, each record has a record number,
+// a PCR index (or an NV index), digests of the content and the content.
+// Only the "pcclient_std" content (which is an event of the TCG PC Client
+// EventLog) is supported, "cel_mgt" records are skipped.
+
+// Types of the TLV fields of a CEL record.
+const (
+	celTypeRecnum      = 0
+	celTypePCR         = 1
+	celTypeNVIndex     = 2
+	celTypeDigests     = 3
+	celTypeCELMgt      = 4
+	celTypePCClientStd = 5
+	celTypeIMATemplate = 7
+	celTypeIMATLV      = 8
+)
+
+// Types of the TLV fields of the "pcclient_std" content.
+const (
+	celPCClientTypeEventType = 0
+	celPCClientTypeEventData = 1
+)
+
+const (
+	celTLVHeaderSize = 1 + 4 // Type + Length
+
+	celContentTypePCClientStd = "pcclient_std"
+	celContentTypeCELMgt      = "cel_mgt"
+)
+
+var celHashAlgNames = map[TPMAlgorithm]string{
+	TPMAlgorithmSHA1:    "sha1",
+	TPMAlgorithmSHA256:  "sha256",
+	TPMAlgorithmSHA384:  "sha384",
+	TPMAlgorithmSHA512:  "sha512",
+	TPMAlgorithmSM3_256: "sm3_256",
+}
+
+func celHashAlgByName(name string) (TPMAlgorithm, bool) {
+	for alg, algName := range celHashAlgNames {
+		if strings.EqualFold(name, algName) {
+			return alg, true
+		}
+	}
+	return 0, false
+}
+
+// WriteCEL writes the EventLog in the TCG Canonical Event Log format
+// of the given encoding: FormatCELTLV, FormatCELJSON or FormatCELCBOR.
+//
+// Events of different hash algorithms are merged into records the same
+// way as into TCG_PCR_EVENT2-s by WriteCryptoAgile.
+func (eventLog *TPMEventLog) WriteCEL(w io.Writer, format Format) error {
+	// one record per TCG_PCR_EVENT2
+	_, _, records, err := eventLog.cryptoAgileEvents()
+	if err != nil {
+		return err
+	}
+
+	var b []byte
+	switch format {
+	case FormatCELTLV:
+		b = celEncodeTLV(records)
+	case FormatCELJSON:
+		b, err = celEncodeJSON(records)
+		if err != nil {
+			return err
+		}
+	case FormatCELCBOR:
+		b, err = celEncodeCBOR(records)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("format %s is not a CEL encoding", format)
+	}
+
+	if _, err := w.Write(b); err != nil {
+		return ErrWrite{Err: err}
+	}
+	return nil
+}
+
+// parseCEL parses a CEL of the given encoding.
+func parseCEL(b []byte, format Format) (*TPMEventLog, error) {
+	var (
+		records []*cryptoAgileEvent
+		err     error
+	)
+	switch format {
+	case FormatCELTLV:
+		records, err = celDecodeTLV(b)
+	case FormatCELJSON:
+		records, err = celDecodeJSON(b)
+	case FormatCELCBOR:
+		records, err = celDecodeCBOR(b)
+	default:
+		return nil, fmt.Errorf("format %s is not a CEL encoding", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var algs []TPMAlgorithm
+	knownAlgs := map[TPMAlgorithm]struct{}{}
+	for _, record := range records {
+		for _, digest := range record.Digests {
+			if _, ok := knownAlgs[digest.HashAlgo]; ok {
+				continue
+			}
+			knownAlgs[digest.HashAlgo] = struct{}{}
+			algs = append(algs, digest.HashAlgo)
+		}
+	}
+	return eventsFromCryptoAgile(algs, records), nil
+}
+
+func celContentTypeError(contentType any) error {
+	return fmt.Errorf("unsupported CEL content type %v (only '%s' is supported)", contentType, celContentTypePCClientStd)
+}
+
+func celCheckDigest(digest *Digest) error {
+	h, err := pcr.Hash(digest.HashAlgo)
+	if err != nil {
+		return ErrNotSupportedHashAlgo{TPMAlgo: digest.HashAlgo}
+	}
+	if len(digest.Digest) != h.Size() {
+		return ErrInvalidDigestLength{Expected: h.Size(), Received: len(digest.Digest)}
+	}
+	return nil
+}
+
+func celCheckPCR(pcrIndex uint64) error {
+	if pcrIndex >= pcr.Amount {
+		return fmt.Errorf("invalid PCR index: %d", pcrIndex)
+	}
+	return nil
+}
+
+// TLV encoding
+//
+// Each field is encoded as: Type (1 byte), Length (4 bytes, big endian), Value.
+// Integer values are big endian.
+
+func celAppendTLV(b []byte, typ byte, value []byte) []byte {
+	b = append(b, typ)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(value)))
+	return append(b, value...)
+}
+
+func celEncodeTLV(records []*cryptoAgileEvent) []byte {
+	var b []byte
+	for recnum, record := range records {
+		b = celAppendTLV(b, celTypeRecnum, binary.BigEndian.AppendUint64(nil, uint64(recnum)))
+		b = celAppendTLV(b, celTypePCR, []byte{uint8(record.PCRIndex)})
+
+		var digests []byte
+		for _, digest := range record.Digests {
+			digests = celAppendTLV(digests, byte(digest.HashAlgo), digest.Digest)
+		}
+		b = celAppendTLV(b, celTypeDigests, digests)
+
+		var content []byte
+		content = celAppendTLV(content, celPCClientTypeEventType, binary.BigEndian.AppendUint32(nil, uint32(record.Type)))
+		content = celAppendTLV(content, celPCClientTypeEventData, record.Data)
+		b = celAppendTLV(b, celTypePCClientStd, content)
+	}
+	return b
+}
+
+type celTLV struct {
+	Offset uint64
+	Type   byte
+	Value  []byte
+
+	// ValueOffset is the offset of Value in the whole input.
+	ValueOffset uint64
+}
+
+// celSplitTLV splits "b" (which starts at offset "baseOffset" of the input) into TLV fields.
+func celSplitTLV(b []byte, baseOffset uint64) ([]celTLV, error) {
+	var result []celTLV
+	for cur := uint64(0); cur < uint64(len(b)); {
+		if cur+celTLVHeaderSize > uint64(len(b)) {
+			return nil, ErrParseAtOffset{Offset: baseOffset + cur, Err: io.ErrUnexpectedEOF}
+		}
+		length := uint64(binary.BigEndian.Uint32(b[cur+1:]))
+		if cur+celTLVHeaderSize+length > uint64(len(b)) {
+			return nil, ErrParseAtOffset{
+				Offset: baseOffset + cur,
+				Err:    fmt.Errorf("value of length %d exceeds the container: %w", length, io.ErrUnexpectedEOF),
+			}
+		}
+		result = append(result, celTLV{
+			Offset:      baseOffset + cur,
+			Type:        b[cur],
+			Value:       b[cur+celTLVHeaderSize : cur+celTLVHeaderSize+length],
+			ValueOffset: baseOffset + cur + celTLVHeaderSize,
+		})
+		cur += celTLVHeaderSize + length
+	}
+	return result, nil
+}
+
+func (tlv celTLV) uint(maxSize int) (uint64, error) {
+	if len(tlv.Value) == 0 || len(tlv.Value) > maxSize {
+		return 0, ErrParseAtOffset{Offset: tlv.Offset, Err: fmt.Errorf("invalid length of an integer: %d", len(tlv.Value))}
+	}
+	var v uint64
+	for _, c := range tlv.Value {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+func celDecodeTLV(b []byte) ([]*cryptoAgileEvent, error) {
+	fields, err := celSplitTLV(b, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*cryptoAgileEvent
+	for idx := 0; idx < len(fields); {
+		if fields[idx].Type != celTypeRecnum {
+			return nil, ErrParseAtOffset{Offset: fields[idx].Offset, Err: fmt.Errorf("expected a record number, but received a field of type %d", fields[idx].Type)}
+		}
+		recordOffset := fields[idx].Offset
+		idx++
+
+		record := &cryptoAgileEvent{}
+		var hasPCR, hasDigests, hasContent, skip bool
+		for ; idx < len(fields) && fields[idx].Type != celTypeRecnum; idx++ {
+			field := fields[idx]
+			switch field.Type {
+			case celTypePCR:
+				pcrIndex, err := field.uint(4)
+				if err != nil {
+					return nil, err
+				}
+				if err := celCheckPCR(pcrIndex); err != nil {
+					return nil, ErrParseAtOffset{Offset: field.Offset, Err: err}
+				}
+				record.PCRIndex = pcr.ID(pcrIndex)
+				hasPCR = true
+			case celTypeNVIndex:
+				return nil, ErrParseAtOffset{Offset: field.Offset, Err: fmt.Errorf("NV index records are not supported")}
+			case celTypeDigests:
+				digestFields, err := celSplitTLV(field.Value, field.ValueOffset)
+				if err != nil {
+					return nil, err
+				}
+				for _, digestField := range digestFields {
+					digest := &Digest{
+						HashAlgo: TPMAlgorithm(digestField.Type),
+						Digest:   digestField.Value,
+					}
+					if err := celCheckDigest(digest); err != nil {
+						return nil, ErrParseAtOffset{Offset: digestField.Offset, Err: err}
+					}
+					record.Digests = append(record.Digests, digest)
+				}
+				hasDigests = true
+			case celTypePCClientStd:
+				contentFields, err := celSplitTLV(field.Value, field.ValueOffset)
+				if err != nil {
+					return nil, err
+				}
+				for _, contentField := range contentFields {
+					switch contentField.Type {
+					case celPCClientTypeEventType:
+						eventType, err := contentField.uint(4)
+						if err != nil {
+							return nil, err
+						}
+						record.Type = EventType(eventType)
+					case celPCClientTypeEventData:
+						record.Data = contentField.Value
+					}
+				}
+				hasContent = true
+			case celTypeCELMgt:
+				skip = true
+			case celTypeIMATemplate, celTypeIMATLV:
+				return nil, ErrParseAtOffset{Offset: field.Offset, Err: celContentTypeError(field.Type)}
+			default:
+				return nil, ErrParseAtOffset{Offset: field.Offset, Err: fmt.Errorf("unknown CEL field type %d", field.Type)}
+			}
+		}
+		if skip {
+			continue
+		}
+		if !hasPCR || !hasDigests || !hasContent {
+			return nil, ErrParseAtOffset{Offset: recordOffset, Err: fmt.Errorf("incomplete record (PCR:%t, digests:%t, content:%t)", hasPCR, hasDigests, hasContent)}
+		}
+		result = append(result, record)
+	}
+	return result, nil
+}
+
+// JSON encoding
+
+type celJSONRecord struct {
+	Recnum      uint64          `json:"recnum"`
+	PCR         *uint64         `json:"pcr,omitempty"`
+	NVIndex     *uint64         `json:"nv_index,omitempty"`
+	Digests     []celJSONDigest `json:"digests"`
+	ContentType string          `json:"content_type"`
+	Content     json.RawMessage `json:"content"`
+}
+
+type celJSONDigest struct {
+	HashAlg string `json:"hashAlg"`
+	Digest  string `json:"digest"`
+}
+
+type celJSONPCClientStd struct {
+	EventType uint32 `json:"event_type"`
+	EventData []byte `json:"event_data"`
+}
+
+func celEncodeJSON(records []*cryptoAgileEvent) ([]byte, error) {
+	jsonRecords := make([]celJSONRecord, 0, len(records))
+	for recnum, record := range records {
+		pcrIndex := uint64(record.PCRIndex)
+		jsonRecord := celJSONRecord{
+			Recnum:      uint64(recnum),
+			PCR:         &pcrIndex,
+			Digests:     []celJSONDigest{},
+			ContentType: celContentTypePCClientStd,
+		}
+		for _, digest := range record.Digests {
+			jsonRecord.Digests = append(jsonRecord.Digests, celJSONDigest{
+				HashAlg: celHashAlgNames[digest.HashAlgo],
+				Digest:  hex.EncodeToString(digest.Digest),
+			})
+		}
+		content, err := json.Marshal(celJSONPCClientStd{
+			EventType: uint32(record.Type),
+			EventData: record.Data,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to serialize record #%d: %w", recnum, err)
+		}
+		jsonRecord.Content = content
+		jsonRecords = append(jsonRecords, jsonRecord)
+	}
+	return json.MarshalIndent(jsonRecords, "", " ")
+}
+
+func celDecodeJSON(b []byte) ([]*cryptoAgileEvent, error) {
+	var jsonRecords []celJSONRecord
+	if err := json.Unmarshal(b, &jsonRecords); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return nil, ErrParseAtOffset{Offset: uint64(syntaxErr.Offset), Err: err}
+		}
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, ErrParseAtOffset{Offset: uint64(typeErr.Offset), Err: err}
+		}
+		return nil, err
+	}
+
+	var result []*cryptoAgileEvent
+	for idx, jsonRecord := range jsonRecords {
+		switch {
+		case jsonRecord.ContentType == celContentTypeCELMgt:
+			continue
+		case jsonRecord.ContentType != celContentTypePCClientStd:
+			return nil, fmt.Errorf("record #%d: %w", idx, celContentTypeError(jsonRecord.ContentType))
+		case jsonRecord.NVIndex != nil:
+			return nil, fmt.Errorf("record #%d: NV index records are not supported", idx)
+		case jsonRecord.PCR == nil:
+			return nil, fmt.Errorf("record #%d: no PCR index", idx)
+		}
+		if err := celCheckPCR(*jsonRecord.PCR); err != nil {
+			return nil, fmt.Errorf("record #%d: %w", idx, err)
+		}
+
+		record := &cryptoAgileEvent{
+			PCRIndex: pcr.ID(*jsonRecord.PCR),
+		}
+		for _, jsonDigest := range jsonRecord.Digests {
+			alg, ok := celHashAlgByName(jsonDigest.HashAlg)
+			if !ok {
+				return nil, fmt.Errorf("record #%d: unknown hash algorithm '%s'", idx, jsonDigest.HashAlg)
+			}
+			digestValue, err := hex.DecodeString(jsonDigest.Digest)
+			if err != nil {
+				return nil, fmt.Errorf("record #%d: unable to decode the %s digest: %w", idx, jsonDigest.HashAlg, err)
+			}
+			digest := &Digest{HashAlgo: alg, Digest: digestValue}
+			if err := celCheckDigest(digest); err != nil {
+				return nil, fmt.Errorf("record #%d: %w", idx, err)
+			}
+			record.Digests = append(record.Digests, digest)
+		}
+
+		var content celJSONPCClientStd
+		if err := json.Unmarshal(jsonRecord.Content, &content); err != nil {
+			return nil, fmt.Errorf("record #%d: unable to parse the content: %w", idx, err)
+		}
+		record.Type = EventType(content.EventType)
+		record.Data = content.EventData
+		result = append(result, record)
+	}
+	return result, nil
+}
+
+// CBOR encoding
+//
+// The log is an array of records, each record is a map with the keys equal
+// to the TLV types (for example 0 is the record number). The digests are
+// a map from the hash algorithm identifier to the digest, the "pcclient_std"
+// content is a map with keys 0 (event type) and 1 (event data).
+
+type celCBORRecord struct {
+	Recnum      uint64              `cbor:"0,keyasint"`
+	PCR         *uint64             `cbor:"1,keyasint,omitempty"`
+	NVIndex     *uint64             `cbor:"2,keyasint,omitempty"`
+	Digests     map[uint64][]byte   `cbor:"3,keyasint,omitempty"`
+	CELMgt      cbor.RawMessage     `cbor:"4,keyasint,omitempty"`
+	PCClientStd *celCBORPCClientStd `cbor:"5,keyasint,omitempty"`
+	IMATemplate cbor.RawMessage     `cbor:"7,keyasint,omitempty"`
+	IMATLV      cbor.RawMessage     `cbor:"8,keyasint,omitempty"`
+}
+
+type celCBORPCClientStd struct {
+	EventType uint64 `cbor:"0,keyasint"`
+	EventData []byte `cbor:"1,keyasint"`
+}
+
+// celCBOREncMode writes the keys of maps in the canonical order.
+var celCBOREncMode = func() cbor.EncMode {
+	encMode, err := cbor.CoreDetEncOptions().EncMode()
+	if err != nil {
+		panic(fmt.Sprintf("internal error: %v", err))
+	}
+	return encMode
+}()
+
+func celEncodeCBOR(records []*cryptoAgileEvent) ([]byte, error) {
+	cborRecords := make([]celCBORRecord, 0, len(records))
+	for recnum, record := range records {
+		pcrIndex := uint64(record.PCRIndex)
+		cborRecord := celCBORRecord{
+			Recnum:  uint64(recnum),
+			PCR:     &pcrIndex,
+			Digests: map[uint64][]byte{},
+			PCClientStd: &celCBORPCClientStd{
+				EventType: uint64(record.Type),
+				EventData: append([]byte{}, record.Data...),
+			},
+		}
+		for _, digest := range record.Digests {
+			cborRecord.Digests[uint64(digest.HashAlgo)] = digest.Digest
+		}
+		cborRecords = append(cborRecords, cborRecord)
+	}
+	return celCBOREncMode.Marshal(cborRecords)
+}
+
+func celDecodeCBOR(b []byte) ([]*cryptoAgileEvent, error) {
+	var cborRecords []celCBORRecord
+	if err := cbor.Unmarshal(b, &cborRecords); err != nil {
+		return nil, fmt.Errorf("unable to decode CBOR: %w", err)
+	}
+
+	var result []*cryptoAgileEvent
+	for idx, cborRecord := range cborRecords {
+		switch {
+		case cborRecord.CELMgt != nil:
+			continue
+		case cborRecord.NVIndex != nil:
+			return nil, fmt.Errorf("record #%d: NV index records are not supported", idx)
+		case cborRecord.IMATemplate != nil:
+			return nil, fmt.Errorf("record #%d: %w", idx, celContentTypeError(celTypeIMATemplate))
+		case cborRecord.IMATLV != nil:
+			return nil, fmt.Errorf("record #%d: %w", idx, celContentTypeError(celTypeIMATLV))
+		case cborRecord.PCR == nil:
+			return nil, fmt.Errorf("record #%d: no PCR index", idx)
+		case cborRecord.Digests == nil:
+			return nil, fmt.Errorf("record #%d: no digests", idx)
+		case cborRecord.PCClientStd == nil:
+			return nil, fmt.Errorf("record #%d: %w", idx, celContentTypeError("<none>"))
+		}
+		if err := celCheckPCR(*cborRecord.PCR); err != nil {
+			return nil, fmt.Errorf("record #%d: %w", idx, err)
+		}
+
+		record := &cryptoAgileEvent{
+			PCRIndex: pcr.ID(*cborRecord.PCR),
+			Type:     EventType(cborRecord.PCClientStd.EventType),
+			Data:     cborRecord.PCClientStd.EventData,
+		}
+		// map iteration order is random, so sorting the digests the same way
+		// they are written.
+		algs := make([]TPMAlgorithm, 0, len(cborRecord.Digests))
+		for alg := range cborRecord.Digests {
+			algs = append(algs, TPMAlgorithm(alg))
+		}
+		sort.Slice(algs, func(i, j int) bool { return algs[i] < algs[j] })
+		for _, alg := range algs {
+			digest := &Digest{HashAlgo: alg, Digest: cborRecord.Digests[uint64(alg)]}
+			if err := celCheckDigest(digest); err != nil {
+				return nil, fmt.Errorf("record #%d: %w", idx, err)
+			}
+			record.Digests = append(record.Digests, digest)
+		}
+		result = append(result, record)
+	}
+	return result, nil
+}
+
+// looksLikeCELTLV returns true if the input starts with a TLV record number
+// followed by a PCR or NV index.
+func looksLikeCELTLV(b []byte) bool {
+	if len(b) < celTLVHeaderSize || b[0] != celTypeRecnum {
+		return false
+	}
+	length := uint64(binary.BigEndian.Uint32(b[1:]))
+	if length == 0 || length > 8 || uint64(len(b)) <= celTLVHeaderSize+length {
+		return false
+	}
+	next := b[celTLVHeaderSize+length]
+	return next == celTypePCR || next == celTypeNVIndex
+}
+
+// looksLikeJSON returns true if the input starts with a JSON array.
+func looksLikeJSON(b []byte) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(b, " \t\r\n"), []byte("["))
+}
+
+// looksLikeCBOR returns true if the input starts with a CBOR array
+// (major type 4).
+func looksLikeCBOR(b []byte) bool {
+	return len(b) > 0 && b[0]>>5 == 4
+}
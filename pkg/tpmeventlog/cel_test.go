@@ -0,0 +1,78 @@
+package tpmeventlog
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testCELEventLog() *TPMEventLog {
+	return &TPMEventLog{Events: []*Event{
+		{PCRIndex: 0, Type: EV_S_CRTM_VERSION, Data: []byte{1, 2}, Digest: &Digest{HashAlgo: TPMAlgorithmSHA1, Digest: bytes.Repeat([]byte{1}, 20)}},
+		{PCRIndex: 0, Type: EV_S_CRTM_CONTENTS, Data: []byte{}, Digest: &Digest{HashAlgo: TPMAlgorithmSHA1, Digest: bytes.Repeat([]byte{2}, 20)}},
+		{PCRIndex: 0, Type: EV_S_CRTM_VERSION, Data: []byte{1, 2}, Digest: &Digest{HashAlgo: TPMAlgorithmSHA256, Digest: bytes.Repeat([]byte{3}, 32)}},
+		{PCRIndex: 0, Type: EV_S_CRTM_CONTENTS, Data: []byte{}, Digest: &Digest{HashAlgo: TPMAlgorithmSHA256, Digest: bytes.Repeat([]byte{4}, 32)}},
+	}}
+}
+
+func TestCEL(t *testing.T) {
+	eventLog := testCELEventLog()
+	for _, format := range []Format{FormatCELTLV, FormatCELJSON, FormatCELCBOR} {
+		t.Run(format.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, eventLog.WriteCEL(&buf, format))
+
+			for _, parseFormat := range []Format{format, FormatAuto} {
+				parsed, err := ParseWithFormat(bytes.NewReader(buf.Bytes()), parseFormat)
+				require.NoError(t, err)
+				require.Len(t, parsed.Events, len(eventLog.Events))
+				for idx, ev := range parsed.Events {
+					expected := eventLog.Events[idx]
+					require.Equal(t, expected.PCRIndex, ev.PCRIndex)
+					require.Equal(t, expected.Type, ev.Type)
+					require.Equal(t, expected.Digest, ev.Digest)
+					require.Equal(t, len(expected.Data), len(ev.Data))
+					require.Equal(t, string(expected.Data), string(ev.Data))
+				}
+			}
+
+			_, err := ParseWithFormat(bytes.NewReader(buf.Bytes()[:buf.Len()-2]), format)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestCELTLVOffset(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, testCELEventLog().WriteCEL(&buf, FormatCELTLV))
+	b := buf.Bytes()
+
+	// corrupting the PCR index of the first record
+	pcrOffset := celTLVHeaderSize + 8
+	b[pcrOffset+celTLVHeaderSize] = 100
+	_, err := ParseWithFormat(bytes.NewReader(b), FormatCELTLV)
+	var errOffset ErrParseAtOffset
+	require.True(t, errors.As(err, &errOffset), err)
+	require.Equal(t, uint64(pcrOffset), errOffset.Offset)
+}
+
+func TestCELJSONSkipsManagementRecords(t *testing.T) {
+	celJSON := []byte(`[
+		{"recnum": 0, "pcr": 0, "digests": [], "content_type": "cel_mgt", "content": {"cel_version": {"major": 1, "minor": 0}}},
+		{"recnum": 1, "pcr": 7, "digests": [{"hashAlg": "sha256", "digest": "` + string(bytes.Repeat([]byte("ab"), 32)) + `"}],
+		 "content_type": "pcclient_std", "content": {"event_type": 4, "event_data": "AQI="}}
+	]`)
+
+	// the format is detected only if explicitly requested
+	_, err := Parse(bytes.NewReader(celJSON))
+	require.Error(t, err)
+
+	parsed, err := ParseWithFormat(bytes.NewReader(celJSON), FormatAuto)
+	require.NoError(t, err)
+	require.Len(t, parsed.Events, 1)
+	require.Equal(t, EV_SEPARATOR, parsed.Events[0].Type)
+	require.Equal(t, []byte{1, 2}, parsed.Events[0].Data)
+	require.Equal(t, TPMAlgorithmSHA256, parsed.Events[0].Digest.HashAlgo)
+}
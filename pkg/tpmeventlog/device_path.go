@@ -0,0 +1,226 @@
+package tpmeventlog
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/uefi/varstore"
+	"github.com/linuxboot/fiano/pkg/guid"
+)
+
+// DevicePath is a parsed EFI_DEVICE_PATH_PROTOCOL.
+//
+// See also: UEFI Specification, section "Device Path Protocol".
+type DevicePath []DevicePathNode
+
+// DevicePathNode is a single node of a DevicePath.
+type DevicePathNode struct {
+	Type    uint8
+	SubType uint8
+	Data    []byte
+}
+
+const (
+	devicePathNodeHeaderSize = 4
+
+	devicePathTypeHardware  = 0x01
+	devicePathTypeACPI      = 0x02
+	devicePathTypeMessaging = 0x03
+	devicePathTypeMedia     = 0x04
+	devicePathTypeEnd       = 0x7f
+
+	devicePathSubTypeEndInstance = 0x01
+	devicePathSubTypeEndEntire   = 0xff
+)
+
+// ParseDevicePath parses a binary EFI_DEVICE_PATH_PROTOCOL. The parsing
+// stops on the first End Entire Device Path node.
+func ParseDevicePath(b []byte) (DevicePath, error) {
+	var result DevicePath
+	for offset := 0; offset < len(b); {
+		if len(b)-offset < devicePathNodeHeaderSize {
+			return result, ErrParseAtOffset{Offset: uint64(offset), Err: io.ErrUnexpectedEOF}
+		}
+		length := int(binary.LittleEndian.Uint16(b[offset+2:]))
+		if length < devicePathNodeHeaderSize || length > len(b)-offset {
+			return result, ErrParseAtOffset{Offset: uint64(offset), Err: fmt.Errorf("invalid device path node length %d", length)}
+		}
+		node := DevicePathNode{
+			Type:    b[offset],
+			SubType: b[offset+1],
+			Data:    b[offset+devicePathNodeHeaderSize : offset+length],
+		}
+		offset += length
+		if node.Type == devicePathTypeEnd && node.SubType == devicePathSubTypeEndEntire {
+			break
+		}
+		result = append(result, node)
+	}
+	return result, nil
+}
+
+// String implements fmt.Stringer. It returns the text representation
+// of the device path, as defined in the UEFI Specification, section
+// "Device Path Nodes Text Representation" (for the supported node types).
+func (p DevicePath) String() string {
+	var result strings.Builder
+	for idx, node := range p {
+		if node.Type == devicePathTypeEnd && node.SubType == devicePathSubTypeEndInstance {
+			result.WriteString(",")
+			continue
+		}
+		if idx > 0 && !(p[idx-1].Type == devicePathTypeEnd && p[idx-1].SubType == devicePathSubTypeEndInstance) {
+			result.WriteString("/")
+		}
+		result.WriteString(node.String())
+	}
+	return result.String()
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (p DevicePath) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// String implements fmt.Stringer.
+func (n DevicePathNode) String() string {
+	if s, ok := n.text(); ok {
+		return s
+	}
+	return fmt.Sprintf("Path(%d,%d,%X)", n.Type, n.SubType, n.Data)
+}
+
+func (n DevicePathNode) text() (string, bool) {
+	d := n.Data
+	switch n.Type {
+	case devicePathTypeHardware:
+		switch n.SubType {
+		case 0x01:
+			if len(d) < 2 {
+				return "", false
+			}
+			return fmt.Sprintf("Pci(0x%X,0x%X)", d[1], d[0]), true
+		case 0x03:
+			if len(d) < 20 {
+				return "", false
+			}
+			return fmt.Sprintf("MemoryMapped(0x%X,0x%X,0x%X)",
+				binary.LittleEndian.Uint32(d), binary.LittleEndian.Uint64(d[4:]), binary.LittleEndian.Uint64(d[12:])), true
+		case 0x04:
+			return vendorDevicePathText("VenHw", d)
+		}
+	case devicePathTypeACPI:
+		switch n.SubType {
+		case 0x01:
+			if len(d) < 8 {
+				return "", false
+			}
+			hid, uid := binary.LittleEndian.Uint32(d), binary.LittleEndian.Uint32(d[4:])
+			switch hid {
+			case 0x0a0341d0: // PNP0A03
+				return fmt.Sprintf("PciRoot(0x%X)", uid), true
+			case 0x0a0841d0: // PNP0A08
+				return fmt.Sprintf("PcieRoot(0x%X)", uid), true
+			}
+			return fmt.Sprintf("Acpi(0x%08X,0x%X)", hid, uid), true
+		}
+	case devicePathTypeMessaging:
+		switch n.SubType {
+		case 0x02:
+			if len(d) < 4 {
+				return "", false
+			}
+			return fmt.Sprintf("Scsi(0x%X,0x%X)", binary.LittleEndian.Uint16(d), binary.LittleEndian.Uint16(d[2:])), true
+		case 0x05:
+			if len(d) < 2 {
+				return "", false
+			}
+			return fmt.Sprintf("USB(0x%X,0x%X)", d[0], d[1]), true
+		case 0x0a:
+			return vendorDevicePathText("VenMsg", d)
+		case 0x0b:
+			if len(d) < 33 {
+				return "", false
+			}
+			return fmt.Sprintf("MAC(%X,0x%X)", d[:6], d[32]), true
+		case 0x12:
+			if len(d) < 6 {
+				return "", false
+			}
+			return fmt.Sprintf("Sata(0x%X,0x%X,0x%X)",
+				binary.LittleEndian.Uint16(d), binary.LittleEndian.Uint16(d[2:]), binary.LittleEndian.Uint16(d[4:])), true
+		case 0x17:
+			if len(d) < 12 {
+				return "", false
+			}
+			eui := make([]string, 0, 8)
+			for idx := 11; idx >= 4; idx-- {
+				eui = append(eui, fmt.Sprintf("%02X", d[idx]))
+			}
+			return fmt.Sprintf("NVMe(0x%X,%s)", binary.LittleEndian.Uint32(d), strings.Join(eui, "-")), true
+		case 0x18:
+			return fmt.Sprintf("Uri(%s)", d), true
+		}
+	case devicePathTypeMedia:
+		switch n.SubType {
+		case 0x01:
+			if len(d) < 38 {
+				return "", false
+			}
+			partNumber := binary.LittleEndian.Uint32(d)
+			start, size := binary.LittleEndian.Uint64(d[4:]), binary.LittleEndian.Uint64(d[12:])
+			signature, signatureType := d[20:36], d[37]
+			switch signatureType {
+			case 0x01:
+				return fmt.Sprintf("HD(%d,MBR,0x%08X,0x%X,0x%X)", partNumber, binary.LittleEndian.Uint32(signature), start, size), true
+			case 0x02:
+				return fmt.Sprintf("HD(%d,GPT,%s,0x%X,0x%X)", partNumber, guidFromBytes(signature), start, size), true
+			}
+			return fmt.Sprintf("HD(%d,%d,0,0x%X,0x%X)", partNumber, signatureType, start, size), true
+		case 0x02:
+			if len(d) < 20 {
+				return "", false
+			}
+			return fmt.Sprintf("CDROM(0x%X,0x%X,0x%X)",
+				binary.LittleEndian.Uint32(d), binary.LittleEndian.Uint64(d[4:]), binary.LittleEndian.Uint64(d[12:])), true
+		case 0x03:
+			return vendorDevicePathText("VenMedia", d)
+		case 0x04:
+			return varstore.DecodeUCS2(d), true
+		case 0x06:
+			if len(d) < guidSize {
+				return "", false
+			}
+			return fmt.Sprintf("FvFile(%s)", guidFromBytes(d)), true
+		case 0x07:
+			if len(d) < guidSize {
+				return "", false
+			}
+			return fmt.Sprintf("Fv(%s)", guidFromBytes(d)), true
+		case 0x08:
+			if len(d) < 20 {
+				return "", false
+			}
+			return fmt.Sprintf("Offset(0x%X,0x%X)", binary.LittleEndian.Uint64(d[4:]), binary.LittleEndian.Uint64(d[12:])), true
+		}
+	}
+	return "", false
+}
+
+func vendorDevicePathText(name string, d []byte) (string, bool) {
+	if len(d) < guidSize {
+		return "", false
+	}
+	if len(d) == guidSize {
+		return fmt.Sprintf("%s(%s)", name, guidFromBytes(d)), true
+	}
+	return fmt.Sprintf("%s(%s,%X)", name, guidFromBytes(d), d[guidSize:]), true
+}
+
+func guidFromBytes(b []byte) guid.GUID {
+	var result guid.GUID
+	copy(result[:], b)
+	return result
+}
@@ -94,3 +94,49 @@ func (err ErrNotSupportedIndex) Error() string {
 	}
 	return result
 }
+
+// ErrWrite means unable to write to the io.Writer
+type ErrWrite struct {
+	Err error
+}
+
+// Error implements interface `error`.
+func (err ErrWrite) Error() string {
+	return fmt.Sprintf("unable to write the EventLog: %v", err.Err)
+}
+
+// Unwrap implements `xerrors.Wrapper`.
+func (err ErrWrite) Unwrap() error {
+	return err.Err
+}
+
+// ErrParseAtOffset means the EventLog is malformed at the specific offset
+// (it is usually wrapped by ErrParse).
+type ErrParseAtOffset struct {
+	Offset uint64
+	Err    error
+}
+
+// Error implements interface `error`.
+func (err ErrParseAtOffset) Error() string {
+	return fmt.Sprintf("at offset 0x%X: %v", err.Offset, err.Err)
+}
+
+// Unwrap implements `xerrors.Wrapper`.
+func (err ErrParseAtOffset) Unwrap() error {
+	return err.Err
+}
+
+// ErrMissingDigest means an event has no counterpart in the bank of
+// the specific hash algorithm, while the crypto-agile format requires
+// digests of all the banks in every event.
+type ErrMissingDigest struct {
+	PCRIndex pcr.ID
+	Type     EventType
+	HashAlgo TPMAlgorithm
+}
+
+// Error implements interface `error`.
+func (err ErrMissingDigest) Error() string {
+	return fmt.Sprintf("event %v on PCR %d has no digest of hash algorithm 0x%x", err.Type, err.PCRIndex, err.HashAlgo)
+}
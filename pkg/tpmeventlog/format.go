@@ -0,0 +1,107 @@
+package tpmeventlog
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// Format is an encoding of an EventLog.
+type Format uint
+
+const (
+	// FormatTCG is the binary format of the TCG PC Client EventLog: either
+	// the crypto-agile one or the TPM1.2 (SHA1-only) one as accepted
+	// by go-attestation.
+	FormatTCG = Format(iota)
+
+	// FormatTPM12 is the TPM1.2 (SHA1-only) TCG_PCClientPCREvent-s.
+	FormatTPM12
+
+	// FormatCELTLV is the TLV encoding of the TCG Canonical Event Log.
+	FormatCELTLV
+
+	// FormatCELJSON is the JSON encoding of the TCG Canonical Event Log.
+	FormatCELJSON
+
+	// FormatCELCBOR is the CBOR encoding of the TCG Canonical Event Log.
+	FormatCELCBOR
+
+	// FormatAuto means to guess the format by the content and to try
+	// the other binary formats if the guessed one could not be parsed
+	// (see ParseWithFormat).
+	FormatAuto
+
+	endOfFormat
+)
+
+var _ flag.Value = (*Format)(nil)
+
+// String implements fmt.Stringer.
+func (f Format) String() string {
+	switch f {
+	case FormatTCG:
+		return "tcg"
+	case FormatTPM12:
+		return "tpm12"
+	case FormatCELTLV:
+		return "cel-tlv"
+	case FormatCELJSON:
+		return "cel-json"
+	case FormatCELCBOR:
+		return "cel-cbor"
+	case FormatAuto:
+		return "auto"
+	}
+	return fmt.Sprintf("unknown_format_%d", uint(f))
+}
+
+// Set implements flag.Value.
+func (f *Format) Set(in string) error {
+	in = strings.Trim(strings.ToLower(in), " ")
+	for v := Format(0); v < endOfFormat; v++ {
+		if in == v.String() {
+			*f = v
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown EventLog format '%s'", in)
+}
+
+// Formats returns all the known formats.
+func Formats() []Format {
+	result := make([]Format, 0, endOfFormat)
+	for v := Format(0); v < endOfFormat; v++ {
+		result = append(result, v)
+	}
+	return result
+}
+
+// IsCEL returns true if the format is an encoding of
+// the TCG Canonical Event Log.
+func (f Format) IsCEL() bool {
+	switch f {
+	case FormatCELTLV, FormatCELJSON, FormatCELCBOR:
+		return true
+	}
+	return false
+}
+
+// detectFormats guesses the format of an EventLog and returns the formats
+// to try in the order of their likelihood.
+func detectFormats(b []byte) []Format {
+	switch {
+	case looksLikeJSON(b):
+		return []Format{FormatCELJSON}
+	case looksLikeCBOR(b):
+		return []Format{FormatCELCBOR}
+	case isCryptoAgile(b):
+		return []Format{FormatTCG}
+	case looksLikeCELTLV(b):
+		// the detection of TLV is not reliable, a TPM1.2 EventLog may look the same
+		return []Format{FormatCELTLV, FormatTCG, FormatTPM12}
+	}
+	// go-attestation is strict about the content of events, so falling back
+	// to just splitting a TPM1.2 EventLog into events.
+	return []Format{FormatTCG, FormatTPM12}
+}
@@ -0,0 +1,117 @@
+package tpmeventlog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
+)
+
+// The layout of a crypto-agile EventLog is defined in
+// "TCG PC Client Platform Firmware Profile Specification", section 10.
+//
+// The first event is always in the legacy (SHA1) format TCG_PCR_EVENT
+// and contains TCG_EfiSpecIDEvent, all the rest events are TCG_PCR_EVENT2.
+
+const (
+	legacyEventHeaderSize = 4 + 4 + 20 + 4 // PCRIndex + EventType + SHA1 Digest + EventSize
+	specIDEventHeaderSize = 16 + 4 + 4 + 4 // Signature + PlatformClass + Versions & UintnSize + NumberOfAlgorithms
+)
+
+var (
+	binaryOrder = binary.LittleEndian
+
+	specIDEventSignature = []byte("Spec ID Event03\x00")
+)
+
+// isCryptoAgile returns true if the EventLog starts with TCG_EfiSpecIDEvent
+// of the crypto-agile format.
+func isCryptoAgile(b []byte) bool {
+	if len(b) < legacyEventHeaderSize+len(specIDEventSignature) {
+		return false
+	}
+	if EventType(binaryOrder.Uint32(b[4:])) != EV_NO_ACTION {
+		return false
+	}
+	return bytes.HasPrefix(b[legacyEventHeaderSize:], specIDEventSignature)
+}
+
+// parseCryptoAgile parses an EventLog in the crypto-agile format.
+//
+// Unlike attest.ParseEventLog it supports any hash algorithm listed
+// in TCG_EfiSpecIDEvent (including SHA384, SHA512 and SM3_256). The
+// resulting events are grouped by the hash algorithm in the order
+// of the algorithms in TCG_EfiSpecIDEvent.
+func parseCryptoAgile(b []byte) (*TPMEventLog, error) {
+	specIDEventSize := uint64(binaryOrder.Uint32(b[legacyEventHeaderSize-4:]))
+	if uint64(len(b)) < legacyEventHeaderSize+specIDEventSize || specIDEventSize < specIDEventHeaderSize {
+		return nil, fmt.Errorf("invalid TCG_EfiSpecIDEvent size: %d", specIDEventSize)
+	}
+	specIDEvent := b[legacyEventHeaderSize : legacyEventHeaderSize+specIDEventSize]
+	algsCount := uint64(binaryOrder.Uint32(specIDEvent[specIDEventHeaderSize-4:]))
+	if specIDEventHeaderSize+algsCount*4 > specIDEventSize {
+		return nil, fmt.Errorf("invalid amount of algorithms in TCG_EfiSpecIDEvent: %d", algsCount)
+	}
+
+	digestSizes := map[TPMAlgorithm]uint16{}
+	algs := make([]TPMAlgorithm, 0, algsCount)
+	for idx := uint64(0); idx < algsCount; idx++ {
+		entry := specIDEvent[specIDEventHeaderSize+idx*4:]
+		alg := TPMAlgorithm(binaryOrder.Uint16(entry[0:]))
+		digestSizes[alg] = binaryOrder.Uint16(entry[2:])
+		algs = append(algs, alg)
+	}
+
+	var events []*cryptoAgileEvent
+	for cur := legacyEventHeaderSize + specIDEventSize; cur < uint64(len(b)); {
+		if cur+12 > uint64(len(b)) {
+			return nil, fmt.Errorf("unexpected end of the EventLog at offset 0x%X", cur)
+		}
+		pcrIndex := pcr.ID(binaryOrder.Uint32(b[cur:]))
+		eventType := EventType(binaryOrder.Uint32(b[cur+4:]))
+		digestsCount := binaryOrder.Uint32(b[cur+8:])
+		cur += 12
+
+		var digests []*Digest
+		for idx := uint32(0); idx < digestsCount; idx++ {
+			if cur+2 > uint64(len(b)) {
+				return nil, fmt.Errorf("unexpected end of the EventLog at offset 0x%X", cur)
+			}
+			alg := TPMAlgorithm(binaryOrder.Uint16(b[cur:]))
+			digestSize, ok := digestSizes[alg]
+			if !ok {
+				return nil, fmt.Errorf("hash algorithm 0x%X at offset 0x%X is not listed in TCG_EfiSpecIDEvent", uint16(alg), cur)
+			}
+			cur += 2
+			if cur+uint64(digestSize) > uint64(len(b)) {
+				return nil, fmt.Errorf("unexpected end of the EventLog at offset 0x%X", cur)
+			}
+			digests = append(digests, &Digest{
+				HashAlgo: alg,
+				Digest:   b[cur : cur+uint64(digestSize)],
+			})
+			cur += uint64(digestSize)
+		}
+
+		if cur+4 > uint64(len(b)) {
+			return nil, fmt.Errorf("unexpected end of the EventLog at offset 0x%X", cur)
+		}
+		dataSize := uint64(binaryOrder.Uint32(b[cur:]))
+		cur += 4
+		if cur+dataSize > uint64(len(b)) {
+			return nil, fmt.Errorf("unexpected end of the EventLog at offset 0x%X", cur)
+		}
+		data := b[cur : cur+dataSize]
+		cur += dataSize
+
+		events = append(events, &cryptoAgileEvent{
+			PCRIndex: pcrIndex,
+			Type:     eventType,
+			Digests:  digests,
+			Data:     data,
+		})
+	}
+
+	return eventsFromCryptoAgile(algs, events), nil
+}
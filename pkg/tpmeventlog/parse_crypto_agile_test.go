@@ -0,0 +1,57 @@
+package tpmeventlog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCryptoAgile(t *testing.T) {
+	var buf bytes.Buffer
+	write := func(v any) {
+		require.NoError(t, binary.Write(&buf, binary.LittleEndian, v))
+	}
+
+	// TCG_PCR_EVENT with TCG_EfiSpecIDEvent (SHA256 and SHA384)
+	specIDEvent := append([]byte{}, specIDEventSignature...)
+	specIDEvent = append(specIDEvent, 0, 0, 0, 0, 0, 2, 0, 2) // PlatformClass, versions and UintnSize
+	specIDEvent = binary.LittleEndian.AppendUint32(specIDEvent, 2)
+	specIDEvent = binary.LittleEndian.AppendUint16(specIDEvent, uint16(TPMAlgorithmSHA256))
+	specIDEvent = binary.LittleEndian.AppendUint16(specIDEvent, 32)
+	specIDEvent = binary.LittleEndian.AppendUint16(specIDEvent, uint16(TPMAlgorithmSHA384))
+	specIDEvent = binary.LittleEndian.AppendUint16(specIDEvent, 48)
+	specIDEvent = append(specIDEvent, 0) // VendorInfoSize
+	write(uint32(0))
+	write(uint32(EV_NO_ACTION))
+	write(make([]byte, 20))
+	write(uint32(len(specIDEvent)))
+	write(specIDEvent)
+
+	// TCG_PCR_EVENT2
+	write(uint32(0))
+	write(uint32(EV_S_CRTM_VERSION))
+	write(uint32(2))
+	write(uint16(TPMAlgorithmSHA256))
+	write(bytes.Repeat([]byte{1}, 32))
+	write(uint16(TPMAlgorithmSHA384))
+	write(bytes.Repeat([]byte{2}, 48))
+	write(uint32(3))
+	write([]byte{3, 4, 5})
+
+	eventLog, err := Parse(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Len(t, eventLog.Events, 2)
+	require.Equal(t, TPMAlgorithmSHA256, eventLog.Events[0].Digest.HashAlgo)
+	require.Equal(t, bytes.Repeat([]byte{1}, 32), eventLog.Events[0].Digest.Digest)
+	require.Equal(t, TPMAlgorithmSHA384, eventLog.Events[1].Digest.HashAlgo)
+	require.Equal(t, bytes.Repeat([]byte{2}, 48), eventLog.Events[1].Digest.Digest)
+	for _, ev := range eventLog.Events {
+		require.Equal(t, EV_S_CRTM_VERSION, ev.Type)
+		require.Equal(t, []byte{3, 4, 5}, ev.Data)
+	}
+
+	_, err = Parse(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	require.Error(t, err)
+}
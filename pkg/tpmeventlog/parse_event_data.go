@@ -43,11 +43,17 @@ func RegisterEventDataParser(pcrIndex pcr.ID, eventType EventType, fn EventDataP
 	eventDataParsers[pcrIndex][eventType] = fn
 }
 
+// EventDataParsed is the decoded event data of an Event. Only
+// the fields relevant to the event type are set.
 type EventDataParsed struct {
-	pkgbytes.Ranges
-	TPMInitLocality *uint8
-	Description     *string
-	FvGUIDs         []guid.GUID
+	pkgbytes.Ranges `json:",omitempty"`
+	TPMInitLocality *uint8                  `json:",omitempty"`
+	Description     *string                 `json:",omitempty"`
+	FvGUIDs         []guid.GUID             `json:",omitempty"`
+	Variable        *UEFIVariableData       `json:",omitempty"`
+	ImageLoad       *UEFIImageLoadEvent     `json:",omitempty"`
+	GPT             *UEFIGPTData            `json:",omitempty"`
+	HandoffTables   []EFIConfigurationTable `json:",omitempty"`
 }
 
 func (p *EventDataParsed) parseDescription() {
@@ -81,7 +87,7 @@ func ParseEventData(
 	}
 	fn, ok := m[ev.Type]
 	if !ok {
-		return nil, fmt.Errorf("event type '%s' is not supported in PCR0, yet", ev.Type)
+		return nil, fmt.Errorf("event type '%s' is not supported in PCR%d, yet", ev.Type, ev.PCRIndex)
 	}
 	return fn(ev, imageSize)
 }
@@ -0,0 +1,420 @@
+package tpmeventlog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"regexp"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
+	"github.com/9elements/converged-security-suite/v2/pkg/uefi/varstore"
+	"github.com/linuxboot/fiano/pkg/guid"
+)
+
+// The structures below are defined in
+// "TCG PC Client Platform Firmware Profile Specification", section
+// "Event Descriptions", and in the UEFI Specification.
+
+const (
+	guidSize = len(guid.GUID{})
+
+	// gptHeaderSignature is "EFI PART".
+	gptHeaderSignature = 0x5452415020494645
+)
+
+var (
+	// EFICertX509GUID is EFI_CERT_X509_GUID, the signature type of
+	// DER-encoded X.509 certificates.
+	EFICertX509GUID = *guid.MustParse("A5C059A1-94E4-4AA7-87B5-AB155C2BF072")
+
+	// EFICertSHA256GUID is EFI_CERT_SHA256_GUID, the signature type of
+	// SHA256 hashes of images.
+	EFICertSHA256GUID = *guid.MustParse("C1C41626-504C-4092-ACA9-41F936934328")
+
+	bootOptionVariableName = regexp.MustCompile(`^(Boot|Driver|SysPrep)[0-9A-Fa-f]{4}$`)
+)
+
+func init() {
+	// These events are not specific to a PCR, so registering them for all
+	// of them.
+	for pcrIndex := pcr.ID(0); pcrIndex < pcr.Amount; pcrIndex++ {
+		RegisterEventDataParser(pcrIndex, EV_EFI_VARIABLE_DRIVER_CONFIG, parseEventDataUEFIVariable)
+		RegisterEventDataParser(pcrIndex, EV_EFI_VARIABLE_BOOT, parseEventDataUEFIVariable)
+		RegisterEventDataParser(pcrIndex, EV_EFI_VARIABLE_AUTHORITY, parseEventDataUEFIVariable)
+		RegisterEventDataParser(pcrIndex, EV_EFI_BOOT_SERVICES_APPLICATION, parseEventDataUEFIImageLoad)
+		RegisterEventDataParser(pcrIndex, EV_EFI_BOOT_SERVICES_DRIVER, parseEventDataUEFIImageLoad)
+		RegisterEventDataParser(pcrIndex, EV_EFI_RUNTIME_SERVICES_DRIVER, parseEventDataUEFIImageLoad)
+		RegisterEventDataParser(pcrIndex, EV_EFI_GPT_EVENT, parseEventDataUEFIGPT)
+		RegisterEventDataParser(pcrIndex, EV_EFI_HANDOFF_TABLES, parseEventDataUEFIHandoffTables)
+		RegisterEventDataParser(pcrIndex, EV_S_CRTM_VERSION, parseEventDataSCRTMVersion)
+	}
+}
+
+// UEFIVariableData is the parsed UEFI_VARIABLE_DATA, which is the event data
+// of EV_EFI_VARIABLE_DRIVER_CONFIG, EV_EFI_VARIABLE_BOOT and
+// EV_EFI_VARIABLE_AUTHORITY events.
+type UEFIVariableData struct {
+	VariableName guid.GUID
+	UnicodeName  string
+	VariableData []byte `json:",omitempty"`
+
+	// SignatureLists is the parsed content of signature database
+	// variables (PK, KEK, db, dbx, ...).
+	SignatureLists []EFISignatureList `json:",omitempty"`
+
+	// Signature is the parsed content of EV_EFI_VARIABLE_AUTHORITY events
+	// referring to an entry of a signature database.
+	Signature *EFISignatureData `json:",omitempty"`
+
+	// LoadOption is the parsed content of Boot####, Driver####
+	// and SysPrep#### variables.
+	LoadOption *EFILoadOption `json:",omitempty"`
+}
+
+// EFISignatureList is a parsed EFI_SIGNATURE_LIST.
+type EFISignatureList struct {
+	SignatureType guid.GUID
+	Header        []byte `json:",omitempty"`
+	Signatures    []EFISignatureData
+}
+
+// EFISignatureData is a parsed EFI_SIGNATURE_DATA.
+type EFISignatureData struct {
+	SignatureOwner guid.GUID
+	SignatureData  []byte
+}
+
+// EFILoadOption is a parsed EFI_LOAD_OPTION.
+type EFILoadOption struct {
+	Attributes   uint32
+	Description  string
+	FilePathList DevicePath
+	OptionalData []byte `json:",omitempty"`
+}
+
+// UEFIImageLoadEvent is the parsed UEFI_IMAGE_LOAD_EVENT, which is the event
+// data of EV_EFI_BOOT_SERVICES_APPLICATION, EV_EFI_BOOT_SERVICES_DRIVER and
+// EV_EFI_RUNTIME_SERVICES_DRIVER events.
+type UEFIImageLoadEvent struct {
+	ImageLocationInMemory uint64
+	ImageLengthInMemory   uint64
+	ImageLinkTimeAddress  uint64
+	DevicePath            DevicePath
+}
+
+// UEFIGPTData is the parsed UEFI_GPT_DATA, which is the event data of
+// EV_EFI_GPT_EVENT events.
+type UEFIGPTData struct {
+	Header     EFIPartitionTableHeader
+	Partitions []EFIPartitionEntry
+}
+
+// EFIPartitionTableHeader is EFI_PARTITION_TABLE_HEADER.
+type EFIPartitionTableHeader struct {
+	Signature                uint64
+	Revision                 uint32
+	HeaderSize               uint32
+	HeaderCRC32              uint32
+	Reserved                 uint32
+	MyLBA                    uint64
+	AlternateLBA             uint64
+	FirstUsableLBA           uint64
+	LastUsableLBA            uint64
+	DiskGUID                 guid.GUID
+	PartitionEntryLBA        uint64
+	NumberOfPartitionEntries uint32
+	SizeOfPartitionEntry     uint32
+	PartitionEntryArrayCRC32 uint32
+}
+
+// EFIPartitionEntry is a parsed EFI_PARTITION_ENTRY.
+type EFIPartitionEntry struct {
+	PartitionTypeGUID   guid.GUID
+	UniquePartitionGUID guid.GUID
+	StartingLBA         uint64
+	EndingLBA           uint64
+	Attributes          uint64
+	PartitionName       string
+}
+
+type efiPartitionEntry struct {
+	PartitionTypeGUID   guid.GUID
+	UniquePartitionGUID guid.GUID
+	StartingLBA         uint64
+	EndingLBA           uint64
+	Attributes          uint64
+	PartitionName       [36]uint16
+}
+
+// EFIConfigurationTable is EFI_CONFIGURATION_TABLE, a single entry
+// of the event data of EV_EFI_HANDOFF_TABLES events.
+type EFIConfigurationTable struct {
+	VendorGUID  guid.GUID
+	VendorTable uint64
+}
+
+func parseEventDataUEFIVariable(
+	ev *Event,
+	imageSize uint64,
+) (*EventDataParsed, error) {
+	r := bytes.NewReader(ev.Data)
+	var header struct {
+		VariableName       guid.GUID
+		UnicodeNameLength  uint64
+		VariableDataLength uint64
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("unable to read the UEFI_VARIABLE_DATA header: %w", err)
+	}
+	if header.UnicodeNameLength > uint64(r.Len())/2 {
+		return nil, fmt.Errorf("the variable name length %d exceeds the event data: %w", header.UnicodeNameLength, io.ErrUnexpectedEOF)
+	}
+	name := make([]byte, header.UnicodeNameLength*2)
+	_, _ = r.Read(name)
+	if header.VariableDataLength > uint64(r.Len()) {
+		return nil, fmt.Errorf("the variable data length %d exceeds the event data: %w", header.VariableDataLength, io.ErrUnexpectedEOF)
+	}
+	data := make([]byte, header.VariableDataLength)
+	_, _ = r.Read(data)
+
+	variable := &UEFIVariableData{
+		VariableName: header.VariableName,
+		UnicodeName:  varstore.DecodeUCS2(name),
+		VariableData: data,
+	}
+
+	var err error
+	switch {
+	case ev.Type == EV_EFI_VARIABLE_AUTHORITY:
+		if variable.VariableName == varstore.GUIDImageSecurityDatabase {
+			if len(data) < guidSize {
+				return nil, fmt.Errorf("the EFI_SIGNATURE_DATA is too short: %d < %d", len(data), guidSize)
+			}
+			variable.Signature = &EFISignatureData{
+				SignatureOwner: guidFromBytes(data),
+				SignatureData:  data[guidSize:],
+			}
+		}
+	case isSignatureDatabase(variable.VariableName, variable.UnicodeName):
+		variable.SignatureLists, err = parseEFISignatureLists(data)
+	case variable.VariableName == varstore.GUIDGlobalVariable && bootOptionVariableName.MatchString(variable.UnicodeName):
+		variable.LoadOption, err = parseEFILoadOption(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the content of variable '%s': %w", variable.UnicodeName, err)
+	}
+
+	return &EventDataParsed{Variable: variable}, nil
+}
+
+func isSignatureDatabase(vendorGUID guid.GUID, name string) bool {
+	switch vendorGUID {
+	case varstore.GUIDGlobalVariable:
+		return name == "PK" || name == "KEK"
+	case varstore.GUIDImageSecurityDatabase:
+		return name == "db" || name == "dbx" || name == "dbt" || name == "dbr"
+	}
+	return false
+}
+
+func parseEFISignatureLists(b []byte) ([]EFISignatureList, error) {
+	var result []EFISignatureList
+	for offset := 0; offset < len(b); {
+		var header struct {
+			SignatureType       guid.GUID
+			SignatureListSize   uint32
+			SignatureHeaderSize uint32
+			SignatureSize       uint32
+		}
+		headerSize := binary.Size(header)
+		if len(b)-offset < headerSize {
+			return nil, ErrParseAtOffset{Offset: uint64(offset), Err: io.ErrUnexpectedEOF}
+		}
+		_ = binary.Read(bytes.NewReader(b[offset:]), binary.LittleEndian, &header)
+		listSize := uint64(header.SignatureListSize)
+		if listSize < uint64(headerSize)+uint64(header.SignatureHeaderSize) || listSize > uint64(len(b)-offset) {
+			return nil, ErrParseAtOffset{Offset: uint64(offset), Err: fmt.Errorf("invalid EFI_SIGNATURE_LIST size %d", listSize)}
+		}
+		if header.SignatureSize < uint32(guidSize) {
+			return nil, ErrParseAtOffset{Offset: uint64(offset), Err: fmt.Errorf("invalid EFI_SIGNATURE_DATA size %d", header.SignatureSize)}
+		}
+		list := b[offset : offset+int(listSize)]
+		sigHeaderEnd := headerSize + int(header.SignatureHeaderSize)
+		signatures := list[sigHeaderEnd:]
+		if len(signatures)%int(header.SignatureSize) != 0 {
+			return nil, ErrParseAtOffset{Offset: uint64(offset), Err: fmt.Errorf("the size of signatures %d is not a multiple of %d", len(signatures), header.SignatureSize)}
+		}
+
+		entry := EFISignatureList{
+			SignatureType: header.SignatureType,
+			Header:        list[headerSize:sigHeaderEnd],
+		}
+		for len(signatures) > 0 {
+			signature := signatures[:header.SignatureSize]
+			entry.Signatures = append(entry.Signatures, EFISignatureData{
+				SignatureOwner: guidFromBytes(signature),
+				SignatureData:  signature[guidSize:],
+			})
+			signatures = signatures[header.SignatureSize:]
+		}
+		result = append(result, entry)
+		offset += int(listSize)
+	}
+	return result, nil
+}
+
+func parseEFILoadOption(b []byte) (*EFILoadOption, error) {
+	if len(b) < 6 {
+		return nil, fmt.Errorf("the EFI_LOAD_OPTION is too short: %w", io.ErrUnexpectedEOF)
+	}
+	result := &EFILoadOption{
+		Attributes: binary.LittleEndian.Uint32(b),
+	}
+	filePathListLength := int(binary.LittleEndian.Uint16(b[4:]))
+	b = b[6:]
+
+	descriptionEnd := -1
+	for idx := 0; idx+1 < len(b); idx += 2 {
+		if b[idx] == 0 && b[idx+1] == 0 {
+			descriptionEnd = idx
+			break
+		}
+	}
+	if descriptionEnd < 0 {
+		return nil, fmt.Errorf("the description is not null-terminated")
+	}
+	result.Description = varstore.DecodeUCS2(b[:descriptionEnd])
+	b = b[descriptionEnd+2:]
+
+	if filePathListLength > len(b) {
+		return nil, fmt.Errorf("the file path list length %d exceeds the data: %w", filePathListLength, io.ErrUnexpectedEOF)
+	}
+	var err error
+	result.FilePathList, err = ParseDevicePath(b[:filePathListLength])
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the file path list: %w", err)
+	}
+	result.OptionalData = b[filePathListLength:]
+	return result, nil
+}
+
+func parseEventDataUEFIImageLoad(
+	ev *Event,
+	imageSize uint64,
+) (*EventDataParsed, error) {
+	r := bytes.NewReader(ev.Data)
+	var header struct {
+		ImageLocationInMemory uint64
+		ImageLengthInMemory   uint64
+		ImageLinkTimeAddress  uint64
+		LengthOfDevicePath    uint64
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("unable to read the UEFI_IMAGE_LOAD_EVENT header: %w", err)
+	}
+	devicePathBytes := ev.Data[len(ev.Data)-r.Len():]
+	if header.LengthOfDevicePath > uint64(len(devicePathBytes)) {
+		return nil, fmt.Errorf("the device path length %d exceeds the event data: %w", header.LengthOfDevicePath, io.ErrUnexpectedEOF)
+	}
+	devicePath, err := ParseDevicePath(devicePathBytes[:header.LengthOfDevicePath])
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the device path: %w", err)
+	}
+	return &EventDataParsed{ImageLoad: &UEFIImageLoadEvent{
+		ImageLocationInMemory: header.ImageLocationInMemory,
+		ImageLengthInMemory:   header.ImageLengthInMemory,
+		ImageLinkTimeAddress:  header.ImageLinkTimeAddress,
+		DevicePath:            devicePath,
+	}}, nil
+}
+
+func parseEventDataUEFIGPT(
+	ev *Event,
+	imageSize uint64,
+) (*EventDataParsed, error) {
+	r := bytes.NewReader(ev.Data)
+	var result UEFIGPTData
+	if err := binary.Read(r, binary.LittleEndian, &result.Header); err != nil {
+		return nil, fmt.Errorf("unable to read the EFI_PARTITION_TABLE_HEADER: %w", err)
+	}
+	if result.Header.Signature != gptHeaderSignature {
+		return nil, fmt.Errorf("invalid GPT header signature 0x%016X", result.Header.Signature)
+	}
+	var numberOfPartitions uint64
+	if err := binary.Read(r, binary.LittleEndian, &numberOfPartitions); err != nil {
+		return nil, fmt.Errorf("unable to read the number of partitions: %w", err)
+	}
+	entrySize := uint64(result.Header.SizeOfPartitionEntry)
+	if entrySize < uint64(binary.Size(efiPartitionEntry{})) {
+		return nil, fmt.Errorf("invalid size of a partition entry: %d", entrySize)
+	}
+	if numberOfPartitions > uint64(r.Len())/entrySize {
+		return nil, fmt.Errorf("%d partitions of size %d exceed the event data: %w", numberOfPartitions, entrySize, io.ErrUnexpectedEOF)
+	}
+	entries := ev.Data[len(ev.Data)-r.Len():]
+	for idx := uint64(0); idx < numberOfPartitions; idx++ {
+		var entry efiPartitionEntry
+		_ = binary.Read(bytes.NewReader(entries[idx*entrySize:]), binary.LittleEndian, &entry)
+		name := entry.PartitionName[:]
+		for len(name) > 0 && name[len(name)-1] == 0 {
+			name = name[:len(name)-1]
+		}
+		result.Partitions = append(result.Partitions, EFIPartitionEntry{
+			PartitionTypeGUID:   entry.PartitionTypeGUID,
+			UniquePartitionGUID: entry.UniquePartitionGUID,
+			StartingLBA:         entry.StartingLBA,
+			EndingLBA:           entry.EndingLBA,
+			Attributes:          entry.Attributes,
+			PartitionName:       string(utf16.Decode(name)),
+		})
+	}
+	return &EventDataParsed{GPT: &result}, nil
+}
+
+func parseEventDataUEFIHandoffTables(
+	ev *Event,
+	imageSize uint64,
+) (*EventDataParsed, error) {
+	r := bytes.NewReader(ev.Data)
+	var numberOfTables uint64
+	if err := binary.Read(r, binary.LittleEndian, &numberOfTables); err != nil {
+		return nil, fmt.Errorf("unable to read the number of tables: %w", err)
+	}
+	entrySize := uint64(binary.Size(EFIConfigurationTable{}))
+	if numberOfTables > uint64(r.Len())/entrySize {
+		return nil, fmt.Errorf("%d tables exceed the event data: %w", numberOfTables, io.ErrUnexpectedEOF)
+	}
+	tables := make([]EFIConfigurationTable, numberOfTables)
+	if err := binary.Read(r, binary.LittleEndian, tables); err != nil {
+		return nil, fmt.Errorf("unable to read the tables: %w", err)
+	}
+	return &EventDataParsed{HandoffTables: tables}, nil
+}
+
+func parseEventDataSCRTMVersion(
+	ev *Event,
+	imageSize uint64,
+) (*EventDataParsed, error) {
+	// The version is usually a null-terminated UCS-2 string,
+	// but some firmwares put a GUID there.
+	if len(ev.Data)%2 == 0 && bytes.HasSuffix(ev.Data, []byte{0, 0}) {
+		s := varstore.DecodeUCS2(ev.Data)
+		isPrintable := s != ""
+		for _, r := range s {
+			if !unicode.IsPrint(r) {
+				isPrintable = false
+				break
+			}
+		}
+		if isPrintable {
+			return &EventDataParsed{Description: ptr(s)}, nil
+		}
+	}
+	if len(ev.Data) == guidSize {
+		return &EventDataParsed{Description: ptr(guidFromBytes(ev.Data).String())}, nil
+	}
+	return nil, fmt.Errorf("unrecognized format of the S-CRTM version: 0x%X", ev.Data)
+}
@@ -0,0 +1,186 @@
+package tpmeventlog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/uefi/varstore"
+	"github.com/linuxboot/fiano/pkg/guid"
+	"github.com/stretchr/testify/require"
+)
+
+func ucs2(s string, nullTerminated bool) []byte {
+	var result []byte
+	for _, c := range utf16.Encode([]rune(s)) {
+		result = binary.LittleEndian.AppendUint16(result, c)
+	}
+	if nullTerminated {
+		result = append(result, 0, 0)
+	}
+	return result
+}
+
+func uefiVariableData(vendorGUID guid.GUID, name string, data []byte) []byte {
+	result := append([]byte{}, vendorGUID[:]...)
+	result = binary.LittleEndian.AppendUint64(result, uint64(len(name)))
+	result = binary.LittleEndian.AppendUint64(result, uint64(len(data)))
+	result = append(result, ucs2(name, false)...)
+	return append(result, data...)
+}
+
+func devicePathNode(typ, subType uint8, data []byte) []byte {
+	result := []byte{typ, subType}
+	result = binary.LittleEndian.AppendUint16(result, uint16(devicePathNodeHeaderSize+len(data)))
+	return append(result, data...)
+}
+
+var testDevicePath = bytes.Join([][]byte{
+	devicePathNode(devicePathTypeACPI, 0x01, []byte{0xd0, 0x41, 0x03, 0x0a, 0, 0, 0, 0}),
+	devicePathNode(devicePathTypeHardware, 0x01, []byte{0x00, 0x1f}),
+	devicePathNode(devicePathTypeMedia, 0x04, ucs2(`\EFI\BOOT\BOOTX64.EFI`, true)),
+	devicePathNode(devicePathTypeEnd, devicePathSubTypeEndEntire, nil),
+}, nil)
+
+func TestParseDevicePath(t *testing.T) {
+	devicePath, err := ParseDevicePath(testDevicePath)
+	require.NoError(t, err)
+	require.Equal(t, `PciRoot(0x0)/Pci(0x1F,0x0)/\EFI\BOOT\BOOTX64.EFI`, devicePath.String())
+
+	devicePath, err = ParseDevicePath(devicePathNode(0x05, 0x01, []byte{1, 2}))
+	require.NoError(t, err)
+	require.Equal(t, "Path(5,1,0102)", devicePath.String())
+
+	_, err = ParseDevicePath([]byte{0x04, 0x04, 0xff, 0x00})
+	require.Error(t, err)
+}
+
+func TestParseEventDataUEFIVariable(t *testing.T) {
+	owner := *guid.MustParse("77FA9ABD-0359-4D32-BD60-28F4E78F784B")
+	cert := []byte{0x30, 0x82, 0x01, 0x02}
+	signatureList := append([]byte{}, EFICertX509GUID[:]...)
+	signatureList = binary.LittleEndian.AppendUint32(signatureList, uint32(28+2*(guidSize+len(cert))))
+	signatureList = binary.LittleEndian.AppendUint32(signatureList, 0)
+	signatureList = binary.LittleEndian.AppendUint32(signatureList, uint32(guidSize+len(cert)))
+	for i := 0; i < 2; i++ {
+		signatureList = append(signatureList, owner[:]...)
+		signatureList = append(signatureList, cert...)
+	}
+
+	parsed, err := ParseEventData(&Event{
+		PCRIndex: 7,
+		Type:     EV_EFI_VARIABLE_DRIVER_CONFIG,
+		Data:     uefiVariableData(varstore.GUIDImageSecurityDatabase, "db", signatureList),
+	}, 0)
+	require.NoError(t, err)
+	require.NotNil(t, parsed.Variable)
+	require.Equal(t, "db", parsed.Variable.UnicodeName)
+	require.Equal(t, varstore.GUIDImageSecurityDatabase, parsed.Variable.VariableName)
+	require.Len(t, parsed.Variable.SignatureLists, 1)
+	require.Equal(t, []EFISignatureData{
+		{SignatureOwner: owner, SignatureData: cert},
+		{SignatureOwner: owner, SignatureData: cert},
+	}, parsed.Variable.SignatureLists[0].Signatures)
+
+	parsed, err = ParseEventData(&Event{
+		PCRIndex: 7,
+		Type:     EV_EFI_VARIABLE_AUTHORITY,
+		Data:     uefiVariableData(varstore.GUIDImageSecurityDatabase, "db", append(owner[:], cert...)),
+	}, 0)
+	require.NoError(t, err)
+	require.Equal(t, &EFISignatureData{SignatureOwner: owner, SignatureData: cert}, parsed.Variable.Signature)
+
+	loadOption := binary.LittleEndian.AppendUint32(nil, 1)
+	loadOption = binary.LittleEndian.AppendUint16(loadOption, uint16(len(testDevicePath)))
+	loadOption = append(loadOption, ucs2("Linux", true)...)
+	loadOption = append(loadOption, testDevicePath...)
+	parsed, err = ParseEventData(&Event{
+		PCRIndex: 1,
+		Type:     EV_EFI_VARIABLE_BOOT,
+		Data:     uefiVariableData(varstore.GUIDGlobalVariable, "Boot0001", loadOption),
+	}, 0)
+	require.NoError(t, err)
+	require.NotNil(t, parsed.Variable.LoadOption)
+	require.Equal(t, "Linux", parsed.Variable.LoadOption.Description)
+	require.Equal(t, `PciRoot(0x0)/Pci(0x1F,0x0)/\EFI\BOOT\BOOTX64.EFI`, parsed.Variable.LoadOption.FilePathList.String())
+
+	_, err = ParseEventData(&Event{
+		PCRIndex: 7,
+		Type:     EV_EFI_VARIABLE_DRIVER_CONFIG,
+		Data:     uefiVariableData(varstore.GUIDImageSecurityDatabase, "db", signatureList[:len(signatureList)-1]),
+	}, 0)
+	require.Error(t, err)
+}
+
+func TestParseEventDataUEFIImageLoad(t *testing.T) {
+	data := binary.LittleEndian.AppendUint64(nil, 0x1000)
+	data = binary.LittleEndian.AppendUint64(data, 0x2000)
+	data = binary.LittleEndian.AppendUint64(data, 0)
+	data = binary.LittleEndian.AppendUint64(data, uint64(len(testDevicePath)))
+	data = append(data, testDevicePath...)
+
+	parsed, err := ParseEventData(&Event{PCRIndex: 4, Type: EV_EFI_BOOT_SERVICES_APPLICATION, Data: data}, 0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0x1000), parsed.ImageLoad.ImageLocationInMemory)
+	require.Equal(t, uint64(0x2000), parsed.ImageLoad.ImageLengthInMemory)
+	require.Equal(t, `PciRoot(0x0)/Pci(0x1F,0x0)/\EFI\BOOT\BOOTX64.EFI`, parsed.ImageLoad.DevicePath.String())
+}
+
+func TestParseEventDataUEFIGPT(t *testing.T) {
+	header := EFIPartitionTableHeader{
+		Signature:            gptHeaderSignature,
+		Revision:             0x10000,
+		HeaderSize:           92,
+		SizeOfPartitionEntry: 128,
+		DiskGUID:             *guid.MustParse("11111111-2222-3333-4444-555555555555"),
+	}
+	var buf bytes.Buffer
+	require.NoError(t, binary.Write(&buf, binary.LittleEndian, header))
+	require.NoError(t, binary.Write(&buf, binary.LittleEndian, uint64(1)))
+	entry := efiPartitionEntry{
+		PartitionTypeGUID: *guid.MustParse("C12A7328-F81F-11D2-BA4B-00A0C93EC93B"),
+		StartingLBA:       2048,
+		EndingLBA:         4095,
+	}
+	copy(entry.PartitionName[:], utf16.Encode([]rune("EFI System")))
+	require.NoError(t, binary.Write(&buf, binary.LittleEndian, entry))
+
+	parsed, err := ParseEventData(&Event{PCRIndex: 5, Type: EV_EFI_GPT_EVENT, Data: buf.Bytes()}, 0)
+	require.NoError(t, err)
+	require.Equal(t, header, parsed.GPT.Header)
+	require.Equal(t, []EFIPartitionEntry{{
+		PartitionTypeGUID: entry.PartitionTypeGUID,
+		StartingLBA:       2048,
+		EndingLBA:         4095,
+		PartitionName:     "EFI System",
+	}}, parsed.GPT.Partitions)
+
+	_, err = ParseEventData(&Event{PCRIndex: 5, Type: EV_EFI_GPT_EVENT, Data: buf.Bytes()[:buf.Len()-1]}, 0)
+	require.Error(t, err)
+}
+
+func TestParseEventDataUEFIHandoffTables(t *testing.T) {
+	table := EFIConfigurationTable{
+		VendorGUID:  *guid.MustParse("EB9D2D31-2D88-11D3-9A16-0090273FC14D"),
+		VendorTable: 0x7f000000,
+	}
+	data := binary.LittleEndian.AppendUint64(nil, 1)
+	data = append(data, table.VendorGUID[:]...)
+	data = binary.LittleEndian.AppendUint64(data, table.VendorTable)
+
+	parsed, err := ParseEventData(&Event{PCRIndex: 1, Type: EV_EFI_HANDOFF_TABLES, Data: data}, 0)
+	require.NoError(t, err)
+	require.Equal(t, []EFIConfigurationTable{table}, parsed.HandoffTables)
+}
+
+func TestParseEventDataSCRTMVersion(t *testing.T) {
+	parsed, err := ParseEventData(&Event{PCRIndex: 0, Type: EV_S_CRTM_VERSION, Data: ucs2("1.02.03", true)}, 0)
+	require.NoError(t, err)
+	require.Equal(t, "1.02.03", *parsed.Description)
+
+	versionGUID := guid.MustParse("01020304-0506-0708-090A-0B0C0D0E0F10")
+	parsed, err = ParseEventData(&Event{PCRIndex: 0, Type: EV_S_CRTM_VERSION, Data: versionGUID[:]}, 0)
+	require.NoError(t, err)
+	require.Equal(t, versionGUID.String(), *parsed.Description)
+}
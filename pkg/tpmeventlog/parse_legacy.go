@@ -0,0 +1,79 @@
+package tpmeventlog
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
+)
+
+// The layout of a TPM1.2 EventLog is a sequence of TCG_PCClientPCREvent
+// structures, see "TCG PC Client Specific Implementation Specification
+// for Conventional BIOS", section 11.1.1:
+//
+//	PCRIndex      UINT32
+//	EventType     UINT32
+//	Digest        [20]BYTE (SHA1)
+//	EventDataSize UINT32
+//	Event         [EventDataSize]BYTE
+
+const sha1DigestSize = 20
+
+// parseLegacy parses a TPM1.2 (SHA1-only) EventLog.
+func parseLegacy(b []byte) (*TPMEventLog, error) {
+	result := &TPMEventLog{}
+	for cur := uint64(0); cur < uint64(len(b)); {
+		if cur+legacyEventHeaderSize > uint64(len(b)) {
+			return nil, ErrParseAtOffset{Offset: cur, Err: io.ErrUnexpectedEOF}
+		}
+		pcrIndex := binaryOrder.Uint32(b[cur:])
+		if pcrIndex >= pcr.Amount {
+			return nil, ErrParseAtOffset{Offset: cur, Err: fmt.Errorf("invalid PCR index: %d", pcrIndex)}
+		}
+		eventType := EventType(binaryOrder.Uint32(b[cur+4:]))
+		digest := b[cur+8 : cur+8+sha1DigestSize]
+		dataSize := uint64(binaryOrder.Uint32(b[cur+8+sha1DigestSize:]))
+		cur += legacyEventHeaderSize
+		if cur+dataSize > uint64(len(b)) {
+			return nil, ErrParseAtOffset{Offset: cur, Err: fmt.Errorf("event data of size %d exceeds the EventLog: %w", dataSize, io.ErrUnexpectedEOF)}
+		}
+
+		result.Events = append(result.Events, &Event{
+			PCRIndex: pcr.ID(pcrIndex),
+			Type:     eventType,
+			Data:     b[cur : cur+dataSize],
+			Digest: &Digest{
+				HashAlgo: TPMAlgorithmSHA1,
+				Digest:   digest,
+			},
+		})
+		cur += dataSize
+	}
+	return result, nil
+}
+
+// WriteLegacy writes the EventLog in the TPM1.2 format. Only
+// events of the SHA1 bank are written.
+func (eventLog *TPMEventLog) WriteLegacy(w io.Writer) error {
+	var b []byte
+	for idx, ev := range eventLog.Events {
+		if ev.Digest == nil || ev.Digest.HashAlgo != TPMAlgorithmSHA1 {
+			continue
+		}
+		if len(ev.Digest.Digest) != sha1DigestSize {
+			return fmt.Errorf("event #%d: %w", idx, ErrInvalidDigestLength{
+				Expected: sha1DigestSize,
+				Received: len(ev.Digest.Digest),
+			})
+		}
+		b = binaryOrder.AppendUint32(b, uint32(ev.PCRIndex))
+		b = binaryOrder.AppendUint32(b, uint32(ev.Type))
+		b = append(b, ev.Digest.Digest...)
+		b = binaryOrder.AppendUint32(b, uint32(len(ev.Data)))
+		b = append(b, ev.Data...)
+	}
+	if _, err := w.Write(b); err != nil {
+		return ErrWrite{Err: err}
+	}
+	return nil
+}
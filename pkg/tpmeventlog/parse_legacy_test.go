@@ -0,0 +1,30 @@
+package tpmeventlog
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLegacy(t *testing.T) {
+	eventLog := &TPMEventLog{Events: []*Event{
+		{PCRIndex: 0, Type: EV_S_CRTM_VERSION, Data: []byte{1, 2}, Digest: &Digest{HashAlgo: TPMAlgorithmSHA1, Digest: bytes.Repeat([]byte{1}, 20)}},
+		{PCRIndex: 17, Type: EventType(0x401), Data: []byte{}, Digest: &Digest{HashAlgo: TPMAlgorithmSHA1, Digest: bytes.Repeat([]byte{2}, 20)}},
+	}}
+	var buf bytes.Buffer
+	require.NoError(t, eventLog.WriteLegacy(&buf))
+	require.Equal(t, 2*legacyEventHeaderSize+2, buf.Len())
+
+	for _, format := range []Format{FormatTPM12, FormatAuto} {
+		parsed, err := ParseWithFormat(bytes.NewReader(buf.Bytes()), format)
+		require.NoError(t, err, format)
+		require.Equal(t, eventLog, parsed, format)
+	}
+
+	_, err := ParseWithFormat(bytes.NewReader(buf.Bytes()[:buf.Len()-1]), FormatTPM12)
+	var errOffset ErrParseAtOffset
+	require.True(t, errors.As(err, &errOffset), err)
+	require.Equal(t, uint64(legacyEventHeaderSize+2), errOffset.Offset) // the second event
+}
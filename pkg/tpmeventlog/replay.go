@@ -28,11 +28,10 @@ func ParseLocality(eventData []byte) (uint8, error) {
 // FilterEvents returns only the events which has a specified PCR index and
 // a digest of a specified hash algorithm.
 func (eventLog *TPMEventLog) FilterEvents(pcrIndex pcr.ID, hashAlgo TPMAlgorithm) ([]*Event, error) {
-	hash, err := hashAlgo.Hash()
+	hash, err := pcr.Hash(hashAlgo)
 	if err != nil {
 		return nil, ErrNotSupportedHashAlgo{TPMAlgo: hashAlgo}
 	}
-	hasher := hash.HashFunc()
 
 	var result []*Event
 	for _, event := range eventLog.Events {
@@ -43,8 +42,8 @@ func (eventLog *TPMEventLog) FilterEvents(pcrIndex pcr.ID, hashAlgo TPMAlgorithm
 			continue
 		}
 
-		if len(event.Digest.Digest) != hasher.Size() {
-			return nil, ErrInvalidDigestLength{Expected: hasher.Size(), Received: len(event.Digest.Digest)}
+		if len(event.Digest.Digest) != hash.Size() {
+			return nil, ErrInvalidDigestLength{Expected: hash.Size(), Received: len(event.Digest.Digest)}
 		}
 
 		result = append(result, event)
@@ -53,74 +52,102 @@ func (eventLog *TPMEventLog) FilterEvents(pcrIndex pcr.ID, hashAlgo TPMAlgorithm
 	return result, nil
 }
 
+// Intel TXT event types, see "Intel TXT Software Development Guide",
+// appendix "Event Logging".
+const (
+	txtEventTypeBase = EventType(0x400)
+	txtEventTypeLast = EventType(0x4FF)
+)
+
+// IsDRTMLaunched returns true if the event log contains events of
+// a dynamic launch (DRTM), which resets DRTM PCRs to zeros.
+//
+// The launch is detected by events of Intel TXT types or by events
+// extended into PCR17, which could be extended only from the localities
+// available after the dynamic launch.
+func (eventLog *TPMEventLog) IsDRTMLaunched() bool {
+	for _, event := range eventLog.Events {
+		if event.Type >= txtEventTypeBase && event.Type <= txtEventTypeLast {
+			return true
+		}
+		if event.PCRIndex == 17 && event.Type != EV_NO_ACTION {
+			return true
+		}
+	}
+	return false
+}
+
 // Replay reproduces a PCR value given events, PCR index and hash algorithm.
 func Replay(eventLog *TPMEventLog, pcrIndex pcr.ID, hashAlgo TPMAlgorithm, logOut io.Writer) ([]byte, error) {
+	if _, err := pcr.Hash(hashAlgo); err != nil {
+		return nil, ErrNotSupportedHashAlgo{TPMAlgo: hashAlgo}
+	}
+	events, err := eventLog.FilterEvents(pcrIndex, hashAlgo)
+	if err != nil {
+		return nil, fmt.Errorf("unable to filter events: %w", err)
+	}
+	return replayEvents(events, pcrIndex, hashAlgo, eventLog.IsDRTMLaunched(), logOut)
+}
+
+// replayEvents reproduces a PCR value given the events of this PCR.
+//
+// isDRTMLaunched defines the initial value of DRTM PCRs, see IsDRTMLaunched.
+func replayEvents(events []*Event, pcrIndex pcr.ID, hashAlgo TPMAlgorithm, isDRTMLaunched bool, logOut io.Writer) ([]byte, error) {
 	if logOut == nil {
 		logOut = io.Discard
 	}
-	hash, err := hashAlgo.Hash()
+	hash, err := pcr.Hash(hashAlgo)
 	if err != nil {
 		return nil, ErrNotSupportedHashAlgo{TPMAlgo: hashAlgo}
 	}
 	hasher := hash.New()
 
-	events, err := eventLog.FilterEvents(pcrIndex, hashAlgo)
-	if err != nil {
-		return nil, fmt.Errorf("unable to filter events: %w", err)
-	}
-
 	// Set the initial value.
 	//
 	// Different PCR values has different rules how to set the initial value:
 	// * PCR0 is initially filled with zeros, but with the last byte equals to TPM initialization locality.
-	// * PCR1 is initially just filled with zeros.
-	// * Some PCR values are initially filled with 0xFF-s.
+	// * DRTM PCRs (PCR17-PCR22) are initially filled with 0xFF-s, and reset to zeros by a dynamic launch.
+	// * Other PCRs are initially just filled with zeros.
+	if !pcrIndex.IsValid() {
+		return nil, ErrNotSupportedIndex{Index: pcrIndex}
+	}
 	var result []byte
-	switch pcrIndex {
-	// We currently support only PCR0 and PCR1
-	case 0:
-		// The locality to be determined from EventLog, so do not initialize it, yet.
-	case 1:
-		// The initial value is always a bunch of zeros.
+	if pcrIndex != 0 {
+		// For PCR0 the locality is to be determined from EventLog, so do not initialize it, yet.
 		result = make([]byte, hasher.Size())
+		if pcrIndex.Attributes().IsDRTM && isDRTMLaunched {
+			pcrIndex.SetResetValue(result)
+		} else {
+			pcrIndex.SetStartupValue(result, 0)
+		}
 		_, _ = fmt.Fprintf(logOut, "set(0x%X)\n", result)
-	default:
-		return nil, ErrNotSupportedIndex{Index: pcrIndex}
 	}
 
 	// Replay the log
 	for _, event := range events {
 		switch event.Type {
 		case EV_NO_ACTION:
+			if pcrIndex != 0 {
+				// EV_NO_ACTION events are not extended, and only the
+				// StartupLocality event (of PCR0) affects the PCR value.
+				continue
+			}
 			if len(result) != 0 {
 				return nil, ErrUnexpectedEventType{Event: *event, Reason: "already initialized"}
 			}
-			switch pcrIndex {
-			case 0:
-				locality, err := ParseLocality(event.Data)
-				if err != nil {
-					return nil, fmt.Errorf("unable to parse locality: %w", err)
-				}
-				result = make([]byte, hasher.Size())
-				result[len(result)-1] = locality
-				_, _ = fmt.Fprintf(logOut, "set(0x%X)\n", result)
-			default:
-				return nil, ErrNotSupportedIndex{
-					Index:       pcrIndex,
-					Description: "measurement value init event is currently supported for PCR0 only",
-				}
+			locality, err := ParseLocality(event.Data)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse locality: %w", err)
 			}
+			result = make([]byte, hasher.Size())
+			pcrIndex.SetStartupValue(result, locality)
+			_, _ = fmt.Fprintf(logOut, "set(0x%X)\n", result)
 		default:
 			if len(result) == 0 {
-				switch pcrIndex {
-				case 0:
-					// There was no event about PCR value initializing, therefore
-					// assuming the zero value.
-					result = make([]byte, hasher.Size())
-					_, _ = fmt.Fprintf(logOut, "set(0x%X)\n", result)
-				default:
-					return nil, ErrNotSupportedIndex{Index: pcrIndex}
-				}
+				// There was no event about PCR value initializing, therefore
+				// assuming the zero value.
+				result = make([]byte, hasher.Size())
+				_, _ = fmt.Fprintf(logOut, "set(0x%X)\n", result)
 			}
 
 			_, _ = fmt.Fprintf(logOut, "%T(0x%X 0x%X)", hasher, result, event.Digest.Digest)
@@ -1,6 +1,7 @@
 package tpmeventlog
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
@@ -120,13 +121,72 @@ func TestReplay(t *testing.T) {
 				// 00..0006 and 00..0007, this: {0, 6, 7}.
 				require.Equal(t, makeFinalDigest(hashAlgo, []uint8{0, 6, 7}), r)
 			})
+			t.Run("pcr7", func(t *testing.T) {
+				r, err := Replay(eventLog, 7, hashAlgo, nil)
+				require.NoError(t, err)
+				// No measurements, thus the initial value (zeros).
+				require.Equal(t, makeMeasurementDigest(hashAlgo, 0), r)
+			})
+			t.Run("pcr17", func(t *testing.T) {
+				r, err := Replay(eventLog, 17, hashAlgo, nil)
+				require.NoError(t, err)
+				// No measurements, thus the initial value of a DRTM PCR (0xFF-s).
+				h, err := hashAlgo.Hash()
+				require.NoError(t, err)
+				require.Equal(t, bytes.Repeat([]byte{0xff}, h.Size()), r)
+			})
 		}
 	})
+	t.Run("drtm", func(t *testing.T) {
+		// The events of a dynamic launch (Intel TXT): the DRTM PCRs are
+		// reset to zeros by the launch, so they are not 0xFF-s anymore.
+		const (
+			evTypeTXTHashStart      = EventType(0x402)
+			evTypeTXTBIOSACRegData  = EventType(0x40A)
+			evTypeTXTSINITPubKey    = EventType(0x410)
+			evTypeTXTLCPDetailsHash = EventType(0x412)
+		)
+		drtmEventLog := &TPMEventLog{Events: append(append([]*Event{}, eventLog.Events...),
+			&Event{PCRIndex: 17, Type: evTypeTXTHashStart, Digest: &Digest{HashAlgo: tpm2.AlgSHA1, Digest: makeMeasurementDigest(tpm2.AlgSHA1, 8)}},
+			&Event{PCRIndex: 17, Type: evTypeTXTBIOSACRegData, Digest: &Digest{HashAlgo: tpm2.AlgSHA1, Digest: makeMeasurementDigest(tpm2.AlgSHA1, 9)}},
+			&Event{PCRIndex: 18, Type: evTypeTXTSINITPubKey, Digest: &Digest{HashAlgo: tpm2.AlgSHA1, Digest: makeMeasurementDigest(tpm2.AlgSHA1, 10)}},
+			&Event{PCRIndex: 18, Type: evTypeTXTLCPDetailsHash, Digest: &Digest{HashAlgo: tpm2.AlgSHA1, Digest: makeMeasurementDigest(tpm2.AlgSHA1, 11)}},
+		)}
+		require.False(t, eventLog.IsDRTMLaunched())
+		require.True(t, drtmEventLog.IsDRTMLaunched())
+
+		r, err := Replay(drtmEventLog, 17, tpm2.AlgSHA1, nil)
+		require.NoError(t, err)
+		require.Equal(t, makeFinalDigest(tpm2.AlgSHA1, []uint8{0, 8, 9}), r)
+
+		r, err = Replay(drtmEventLog, 18, tpm2.AlgSHA1, nil)
+		require.NoError(t, err)
+		require.Equal(t, makeFinalDigest(tpm2.AlgSHA1, []uint8{0, 10, 11}), r)
+
+		// no events, but the PCR is reset by the launch anyway
+		r, err = Replay(drtmEventLog, 19, tpm2.AlgSHA1, nil)
+		require.NoError(t, err)
+		require.Equal(t, makeMeasurementDigest(tpm2.AlgSHA1, 0), r)
+
+		// the static PCRs are not affected
+		r, err = Replay(drtmEventLog, 1, tpm2.AlgSHA1, nil)
+		require.NoError(t, err)
+		require.Equal(t, makeFinalDigest(tpm2.AlgSHA1, []uint8{0, 6, 7}), r)
+
+		// VerifyPCRs replays each PCR separately, but has to take
+		// the launch into account as well
+		expected18 := makeFinalDigest(tpm2.AlgSHA1, []uint8{0, 10, 11})
+		verifications := VerifyPCRs(drtmEventLog, map[TPMAlgorithm]map[pcr.ID][]byte{
+			tpm2.AlgSHA1: {18: expected18},
+		})
+		require.Len(t, verifications, 1)
+		require.True(t, verifications[0].IsMatch())
+	})
 	t.Run("negative", func(t *testing.T) {
-		// Validate that Replay returns an error for all non-yet-supported PCR values.
-		// This is to avoid returning wrong data to an user.
+		// Validate that Replay returns an error for all PCR values which
+		// does not exist on a PC Client TPM. This is to avoid returning wrong data to an user.
 
-		for pcrID := pcr.ID(2); ; pcrID++ {
+		for pcrID := pcr.ID(pcr.Amount); ; pcrID++ {
 			for _, hashAlgo := range []tpm2.Algorithm{tpm2.AlgSHA1} {
 				t.Run("not_supported/pcr%d", func(t *testing.T) {
 					_, err := Replay(eventLog, pcrID, hashAlgo, nil)
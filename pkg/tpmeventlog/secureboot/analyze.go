@@ -0,0 +1,270 @@
+// Package secureboot analyzes the Secure Boot policy measured into PCR7
+// according to "TCG PC Client Platform Firmware Profile Specification".
+package secureboot
+
+import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+	"strings"
+
+	"github.com/linuxboot/fiano/pkg/guid"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmeventlog"
+	"github.com/9elements/converged-security-suite/v2/pkg/uefi/varstore"
+)
+
+// PCRIndex is the index of the PCR the Secure Boot policy is measured into.
+const PCRIndex = pcr.ID(7)
+
+// testKeyMarkers are substrings of subjects of test keys, which are
+// published together with their private keys (see "PKfail").
+var testKeyMarkers = []string{
+	"DO NOT TRUST",
+	"DO NOT SHIP",
+}
+
+// Report is the result of Analyze.
+type Report struct {
+	// HashAlgo is the hash algorithm of the analyzed PCR bank.
+	HashAlgo tpmeventlog.TPMAlgorithm
+
+	// PCR7 is the PCR7 value replayed from the EventLog.
+	PCR7 []byte
+
+	// SecureBoot is the state of the SecureBoot variable, it is nil
+	// if the variable was not measured.
+	SecureBoot *bool
+
+	// PK, KEK, DB and DBX are the measured signature databases, they are
+	// nil if the variable was not measured.
+	PK  *SignatureDatabase
+	KEK *SignatureDatabase
+	DB  *SignatureDatabase
+	DBX *SignatureDatabase
+
+	// Authorities are the entries used to verify loaded images
+	// (EV_EFI_VARIABLE_AUTHORITY events).
+	Authorities []Authority
+
+	Issues []Issue
+}
+
+// SignatureDatabase is the content of a signature database variable.
+type SignatureDatabase struct {
+	Certificates []Certificate
+
+	// Hashes are the hashes of images (and other non-certificate entries).
+	Hashes []tpmeventlog.EFISignatureData
+}
+
+// Len returns the total amount of entries.
+func (db *SignatureDatabase) Len() int {
+	if db == nil {
+		return 0
+	}
+	return len(db.Certificates) + len(db.Hashes)
+}
+
+// Certificate is an X.509 certificate of a signature database.
+type Certificate struct {
+	Owner   guid.GUID
+	Subject string
+	Issuer  string
+
+	// ParseError is set if the certificate could not be parsed.
+	ParseError string `json:",omitempty"`
+}
+
+// IsTestKey returns true if the certificate looks like a publicly
+// known test key (for example, the AMI "DO NOT TRUST" key).
+func (cert Certificate) IsTestKey() bool {
+	for _, marker := range testKeyMarkers {
+		if strings.Contains(strings.ToUpper(cert.Subject), marker) ||
+			strings.Contains(strings.ToUpper(cert.Issuer), marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// String implements fmt.Stringer.
+func (cert Certificate) String() string {
+	if cert.ParseError != "" {
+		return fmt.Sprintf("<invalid certificate: %s>", cert.ParseError)
+	}
+	return fmt.Sprintf("subject: '%s', issuer: '%s'", cert.Subject, cert.Issuer)
+}
+
+// Authority is an entry used to verify a loaded image.
+type Authority struct {
+	VariableName string
+	VendorGUID   guid.GUID
+
+	// Certificate is set if the entry is an X.509 certificate.
+	Certificate *Certificate `json:",omitempty"`
+
+	// Data is the raw entry, if it is not an X.509 certificate.
+	Data []byte `json:",omitempty"`
+}
+
+// Analyze replays PCR7 of the given PCR bank and reports the Secure Boot
+// policy measured into it.
+func Analyze(eventLog *tpmeventlog.TPMEventLog, hashAlgo tpmeventlog.TPMAlgorithm) (*Report, error) {
+	hash, err := pcr.Hash(hashAlgo)
+	if err != nil {
+		return nil, tpmeventlog.ErrNotSupportedHashAlgo{TPMAlgo: hashAlgo}
+	}
+	events, err := eventLog.FilterEvents(PCRIndex, hashAlgo)
+	if err != nil {
+		return nil, fmt.Errorf("unable to filter events: %w", err)
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("there are no PCR%d events in bank %s", PCRIndex, hashAlgo)
+	}
+
+	report := &Report{HashAlgo: hashAlgo}
+	report.PCR7, err = tpmeventlog.Replay(eventLog, PCRIndex, hashAlgo, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to replay PCR%d: %w", PCRIndex, err)
+	}
+
+	for _, ev := range events {
+		switch ev.Type {
+		case tpmeventlog.EV_EFI_VARIABLE_DRIVER_CONFIG, tpmeventlog.EV_EFI_VARIABLE_AUTHORITY:
+		case tpmeventlog.EV_EFI_ACTION:
+			report.addIssue(IssueSecurityRelevantAction, "%s", ev.Data)
+			continue
+		default:
+			continue
+		}
+
+		h := hash.New()
+		h.Write(ev.Data)
+		if !bytes.Equal(h.Sum(nil), ev.Digest.Digest) {
+			report.addIssue(IssueDigestMismatch, "the data of event %s does not match its digest", ev)
+			continue
+		}
+
+		parsed, err := tpmeventlog.ParseEventData(ev, 0)
+		if err != nil {
+			report.addIssue(IssueMalformedEvent, "unable to parse event %s: %v", ev, err)
+			continue
+		}
+		report.addVariable(ev.Type, parsed.Variable)
+	}
+
+	report.check()
+	return report, nil
+}
+
+func (report *Report) addIssue(kind IssueKind, format string, args ...any) {
+	report.Issues = append(report.Issues, Issue{
+		Kind:        kind,
+		Description: fmt.Sprintf(format, args...),
+	})
+}
+
+func (report *Report) addVariable(eventType tpmeventlog.EventType, v *tpmeventlog.UEFIVariableData) {
+	if eventType == tpmeventlog.EV_EFI_VARIABLE_AUTHORITY {
+		authority := Authority{
+			VariableName: v.UnicodeName,
+			VendorGUID:   v.VariableName,
+		}
+		data := v.VariableData
+		if v.Signature != nil {
+			data = v.Signature.SignatureData
+		}
+		if cert, err := x509.ParseCertificate(data); err == nil {
+			authority.Certificate = &Certificate{
+				Subject: cert.Subject.String(),
+				Issuer:  cert.Issuer.String(),
+			}
+			if v.Signature != nil {
+				authority.Certificate.Owner = v.Signature.SignatureOwner
+			}
+		} else {
+			authority.Data = data
+		}
+		report.Authorities = append(report.Authorities, authority)
+		return
+	}
+
+	switch {
+	case v.VariableName == varstore.GUIDGlobalVariable && v.UnicodeName == "SecureBoot":
+		if len(v.VariableData) != 1 {
+			report.addIssue(IssueMalformedEvent, "unexpected length of the SecureBoot variable: %d", len(v.VariableData))
+			return
+		}
+		isEnabled := v.VariableData[0] == 1
+		report.SecureBoot = &isEnabled
+	case v.VariableName == varstore.GUIDGlobalVariable && v.UnicodeName == "PK":
+		report.PK = newSignatureDatabase(v.SignatureLists)
+	case v.VariableName == varstore.GUIDGlobalVariable && v.UnicodeName == "KEK":
+		report.KEK = newSignatureDatabase(v.SignatureLists)
+	case v.VariableName == varstore.GUIDImageSecurityDatabase && v.UnicodeName == "db":
+		report.DB = newSignatureDatabase(v.SignatureLists)
+	case v.VariableName == varstore.GUIDImageSecurityDatabase && v.UnicodeName == "dbx":
+		report.DBX = newSignatureDatabase(v.SignatureLists)
+	}
+}
+
+func newSignatureDatabase(lists []tpmeventlog.EFISignatureList) *SignatureDatabase {
+	db := &SignatureDatabase{}
+	for _, list := range lists {
+		for _, signature := range list.Signatures {
+			if list.SignatureType != tpmeventlog.EFICertX509GUID {
+				db.Hashes = append(db.Hashes, signature)
+				continue
+			}
+			certificate := Certificate{Owner: signature.SignatureOwner}
+			cert, err := x509.ParseCertificate(signature.SignatureData)
+			if err != nil {
+				certificate.ParseError = err.Error()
+			} else {
+				certificate.Subject = cert.Subject.String()
+				certificate.Issuer = cert.Issuer.String()
+			}
+			db.Certificates = append(db.Certificates, certificate)
+		}
+	}
+	return db
+}
+
+func (report *Report) check() {
+	switch {
+	case report.SecureBoot == nil:
+		report.addIssue(IssueMissingVariable, "SecureBoot is not measured")
+	case !*report.SecureBoot:
+		report.addIssue(IssueSecureBootDisabled, "Secure Boot is disabled")
+	}
+
+	for _, v := range []struct {
+		Name string
+		DB   *SignatureDatabase
+	}{
+		{Name: "PK", DB: report.PK},
+		{Name: "KEK", DB: report.KEK},
+		{Name: "db", DB: report.DB},
+	} {
+		if v.DB == nil {
+			report.addIssue(IssueMissingVariable, "%s is not measured", v.Name)
+		}
+	}
+
+	switch {
+	case report.DBX == nil:
+		report.addIssue(IssueMissingDBX, "dbx is not measured")
+	case report.DBX.Len() == 0:
+		report.addIssue(IssueEmptyDBX, "dbx is empty")
+	}
+
+	if report.PK != nil {
+		for _, cert := range report.PK.Certificates {
+			if cert.IsTestKey() {
+				report.addIssue(IssueTestPlatformKey, "PK is a test key: %s", cert)
+			}
+		}
+	}
+}
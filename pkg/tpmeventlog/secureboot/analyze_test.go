@@ -0,0 +1,170 @@
+package secureboot
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/google/go-tpm/legacy/tpm2"
+	"github.com/linuxboot/fiano/pkg/guid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmeventlog"
+	"github.com/9elements/converged-security-suite/v2/pkg/uefi/varstore"
+)
+
+func newTestCertificate(t *testing.T, commonName string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return cert
+}
+
+func newVariableEvent(eventType tpmeventlog.EventType, vendorGUID guid.GUID, name string, data []byte) *tpmeventlog.Event {
+	eventData := append([]byte{}, vendorGUID[:]...)
+	eventData = binary.LittleEndian.AppendUint64(eventData, uint64(len(name)))
+	eventData = binary.LittleEndian.AppendUint64(eventData, uint64(len(data)))
+	for _, c := range utf16.Encode([]rune(name)) {
+		eventData = binary.LittleEndian.AppendUint16(eventData, c)
+	}
+	eventData = append(eventData, data...)
+	digest := sha256.Sum256(eventData)
+	return &tpmeventlog.Event{
+		PCRIndex: PCRIndex,
+		Type:     eventType,
+		Data:     eventData,
+		Digest:   &tpmeventlog.Digest{HashAlgo: tpmeventlog.TPMAlgorithmSHA256, Digest: digest[:]},
+	}
+}
+
+func newSignatureList(signatureType guid.GUID, owner guid.GUID, entries ...[]byte) []byte {
+	if len(entries) == 0 {
+		return nil
+	}
+	signatureSize := len(owner) + len(entries[0])
+	result := append([]byte{}, signatureType[:]...)
+	result = binary.LittleEndian.AppendUint32(result, uint32(28+len(entries)*signatureSize))
+	result = binary.LittleEndian.AppendUint32(result, 0)
+	result = binary.LittleEndian.AppendUint32(result, uint32(signatureSize))
+	for _, entry := range entries {
+		result = append(result, owner[:]...)
+		result = append(result, entry...)
+	}
+	return result
+}
+
+func TestAnalyze(t *testing.T) {
+	owner := *guid.MustParse("77FA9ABD-0359-4D32-BD60-28F4E78F784B")
+	pk := newTestCertificate(t, "DO NOT TRUST - AMI Test PK")
+	dbCert := newTestCertificate(t, "Test DB")
+	dbxHash := make([]byte, sha256.Size)
+
+	eventLog := &tpmeventlog.TPMEventLog{Events: []*tpmeventlog.Event{
+		newVariableEvent(tpmeventlog.EV_EFI_VARIABLE_DRIVER_CONFIG, varstore.GUIDGlobalVariable, "SecureBoot", []byte{1}),
+		newVariableEvent(tpmeventlog.EV_EFI_VARIABLE_DRIVER_CONFIG, varstore.GUIDGlobalVariable, "PK", newSignatureList(tpmeventlog.EFICertX509GUID, owner, pk)),
+		newVariableEvent(tpmeventlog.EV_EFI_VARIABLE_DRIVER_CONFIG, varstore.GUIDGlobalVariable, "KEK", newSignatureList(tpmeventlog.EFICertX509GUID, owner, pk)),
+		newVariableEvent(tpmeventlog.EV_EFI_VARIABLE_DRIVER_CONFIG, varstore.GUIDImageSecurityDatabase, "db", newSignatureList(tpmeventlog.EFICertX509GUID, owner, dbCert)),
+		newVariableEvent(tpmeventlog.EV_EFI_VARIABLE_DRIVER_CONFIG, varstore.GUIDImageSecurityDatabase, "dbx", newSignatureList(tpmeventlog.EFICertSHA256GUID, owner, dbxHash)),
+		newVariableEvent(tpmeventlog.EV_EFI_VARIABLE_AUTHORITY, varstore.GUIDImageSecurityDatabase, "db", append(owner[:], dbCert...)),
+	}}
+
+	report, err := Analyze(eventLog, tpmeventlog.TPMAlgorithmSHA256)
+	require.NoError(t, err)
+	require.NotNil(t, report.SecureBoot)
+	require.True(t, *report.SecureBoot)
+	require.Len(t, report.PK.Certificates, 1)
+	require.Equal(t, "CN=DO NOT TRUST - AMI Test PK", report.PK.Certificates[0].Subject)
+	require.Equal(t, 1, report.DB.Len())
+	require.Len(t, report.DBX.Hashes, 1)
+	require.Len(t, report.Authorities, 1)
+	require.Equal(t, "CN=Test DB", report.Authorities[0].Certificate.Subject)
+	require.Equal(t, owner, report.Authorities[0].Certificate.Owner)
+	require.Equal(t, []IssueKind{IssueTestPlatformKey}, issueKinds(report.Issues))
+
+	replayed, err := tpmeventlog.Replay(eventLog, PCRIndex, tpmeventlog.TPMAlgorithmSHA256, nil)
+	require.NoError(t, err)
+	require.NoError(t, report.VerifyPCR(replayed))
+	require.True(t, errors.As(report.VerifyPCR(make([]byte, sha256.Size)), &ErrPCRMismatch{}))
+
+	// no dbx, Secure Boot is disabled and a tampered db
+	eventLog.Events = eventLog.Events[:4]
+	eventLog.Events[0] = newVariableEvent(tpmeventlog.EV_EFI_VARIABLE_DRIVER_CONFIG, varstore.GUIDGlobalVariable, "SecureBoot", []byte{0})
+	eventLog.Events[3].Data = append([]byte{}, eventLog.Events[3].Data...)
+	eventLog.Events[3].Data[len(eventLog.Events[3].Data)-1] ^= 1
+	report, err = Analyze(eventLog, tpmeventlog.TPMAlgorithmSHA256)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []IssueKind{
+		IssueSecureBootDisabled,
+		IssueDigestMismatch,
+		IssueMissingVariable, // db
+		IssueMissingDBX,
+		IssueTestPlatformKey,
+	}, issueKinds(report.Issues))
+
+	// empty dbx
+	eventLog.Events[3] = newVariableEvent(tpmeventlog.EV_EFI_VARIABLE_DRIVER_CONFIG, varstore.GUIDImageSecurityDatabase, "dbx", nil)
+	report, err = Analyze(eventLog, tpmeventlog.TPMAlgorithmSHA256)
+	require.NoError(t, err)
+	require.Contains(t, issueKinds(report.Issues), IssueEmptyDBX)
+}
+
+func TestVerifyQuote(t *testing.T) {
+	report := &Report{
+		HashAlgo: tpmeventlog.TPMAlgorithmSHA256,
+		PCR7:     make([]byte, sha256.Size),
+	}
+	pcrDigest := sha256.Sum256(report.PCR7)
+	newQuote := func(pcrs []int, pcrDigest []byte) []byte {
+		attest, err := tpm2.AttestationData{
+			Magic:           0xff544347,
+			Type:            tpm2.TagAttestQuote,
+			QualifiedSigner: tpm2.Name{Digest: &tpm2.HashValue{Alg: tpm2.AlgSHA256, Value: make([]byte, sha256.Size)}},
+			AttestedQuoteInfo: &tpm2.QuoteInfo{
+				PCRSelection: tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: pcrs},
+				PCRDigest:    pcrDigest,
+			},
+		}.Encode()
+		require.NoError(t, err)
+		return attest
+	}
+
+	require.NoError(t, report.VerifyQuote(newQuote([]int{7}, pcrDigest[:])))
+	require.Error(t, report.VerifyQuote(newQuote([]int{0, 7}, pcrDigest[:])))
+	err := report.VerifyQuote(newQuote([]int{7}, make([]byte, sha256.Size)))
+	require.True(t, errors.As(err, &ErrPCRMismatch{}), err)
+}
+
+func TestParsePCRReadOutput(t *testing.T) {
+	value, err := ParsePCRReadOutput([]byte("0102\n"), tpmeventlog.TPMAlgorithmSHA256)
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2}, value)
+
+	output := "sha1:\n  7 : 0x0A0B\nsha256:\n  0 : 0x0000\n  7 : 0x0C0D\n"
+	value, err = ParsePCRReadOutput([]byte(output), tpmeventlog.TPMAlgorithmSHA256)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0xc, 0xd}, value)
+
+	_, err = ParsePCRReadOutput([]byte(output), tpmeventlog.TPMAlgorithmSHA384)
+	require.Error(t, err)
+}
+
+func issueKinds(issues []Issue) []IssueKind {
+	var result []IssueKind
+	for _, issue := range issues {
+		result = append(result, issue.Kind)
+	}
+	return result
+}
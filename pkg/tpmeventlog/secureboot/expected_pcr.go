@@ -0,0 +1,109 @@
+package secureboot
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-tpm/legacy/tpm2"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
+	"github.com/9elements/converged-security-suite/v2/pkg/tpmeventlog"
+)
+
+// ErrPCRMismatch means the replayed PCR value does not match
+// the value reported by the TPM.
+type ErrPCRMismatch struct {
+	Replayed []byte
+	Expected []byte
+}
+
+// Error implements interface `error`.
+func (err ErrPCRMismatch) Error() string {
+	return fmt.Sprintf("the replayed PCR%d value %X does not match the expected value %X", PCRIndex, err.Replayed, err.Expected)
+}
+
+// VerifyPCR checks that the replayed PCR7 value matches the given one.
+func (report *Report) VerifyPCR(expected []byte) error {
+	if !bytes.Equal(report.PCR7, expected) {
+		return ErrPCRMismatch{Replayed: report.PCR7, Expected: expected}
+	}
+	return nil
+}
+
+// VerifyQuote checks that the replayed PCR7 value matches the PCR digest
+// of a TPMS_ATTEST structure of a quote (for example, the message written by
+// "tpm2_quote -m"). The quote should select only PCR7 of the analyzed bank,
+// and its PCR digest is expected to be calculated with the hash algorithm
+// of the bank.
+//
+// The signature of the quote is not verified.
+func (report *Report) VerifyQuote(attest []byte) error {
+	attestData, err := tpm2.DecodeAttestationData(attest)
+	if err != nil {
+		return fmt.Errorf("unable to decode the quote: %w", err)
+	}
+	quoteInfo := attestData.AttestedQuoteInfo
+	if quoteInfo == nil {
+		return fmt.Errorf("the attestation structure is not a quote, but of type 0x%X", attestData.Type)
+	}
+	selection := quoteInfo.PCRSelection
+	if selection.Hash != report.HashAlgo || len(selection.PCRs) != 1 || selection.PCRs[0] != int(PCRIndex) {
+		return fmt.Errorf("the quote selects PCRs %v of bank %s, but expected only PCR%d of bank %s",
+			selection.PCRs, selection.Hash, PCRIndex, report.HashAlgo)
+	}
+
+	hash, err := pcr.Hash(report.HashAlgo)
+	if err != nil {
+		return tpmeventlog.ErrNotSupportedHashAlgo{TPMAlgo: report.HashAlgo}
+	}
+	h := hash.New()
+	h.Write(report.PCR7)
+	if digest := h.Sum(nil); !bytes.Equal(digest, quoteInfo.PCRDigest) {
+		return fmt.Errorf("the PCR digest of the quote %X does not match the digest of the replayed value %X: %w",
+			[]byte(quoteInfo.PCRDigest), digest, ErrPCRMismatch{Replayed: report.PCR7})
+	}
+	return nil
+}
+
+// ParsePCRReadOutput returns the PCR7 value of the given bank from the
+// output of "pcr0tool pcrread 7" (a single hex value) or "tpm2_pcrread".
+func ParsePCRReadOutput(output []byte, hashAlgo tpmeventlog.TPMAlgorithm) ([]byte, error) {
+	if value, err := hex.DecodeString(string(bytes.TrimSpace(output))); err == nil {
+		return value, nil
+	}
+
+	// tpm2_pcrread prints:
+	//
+	//	sha256:
+	//	  7 : 0x0123...
+	var bank string
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasSuffix(line, ":") {
+			bank = strings.TrimSuffix(line, ":")
+			continue
+		}
+		if !strings.EqualFold(bank, hashAlgo.String()) {
+			continue
+		}
+		index, value, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(index) != strconv.Itoa(int(PCRIndex)) {
+			continue
+		}
+		value = strings.TrimPrefix(strings.TrimSpace(value), "0x")
+		result, err := hex.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse the PCR%d value '%s': %w", PCRIndex, value, err)
+		}
+		return result, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read the output: %w", err)
+	}
+	return nil, fmt.Errorf("PCR%d of bank %s is not found", PCRIndex, hashAlgo)
+}
@@ -0,0 +1,85 @@
+package secureboot
+
+import (
+	"fmt"
+)
+
+// IssueKind is the kind of a problem found in the Secure Boot policy.
+type IssueKind uint
+
+const (
+	// IssueUndefined is a zero value, should never be used.
+	IssueUndefined = IssueKind(iota)
+
+	// IssueSecureBootDisabled means the SecureBoot variable is measured
+	// and Secure Boot is disabled.
+	IssueSecureBootDisabled
+
+	// IssueMissingVariable means one of the required variables
+	// (SecureBoot, PK, KEK, db) was not measured.
+	IssueMissingVariable
+
+	// IssueMissingDBX means the dbx variable was not measured, so
+	// no revoked certificates and images are enforced.
+	IssueMissingDBX
+
+	// IssueEmptyDBX means dbx contains no entries, so no revoked
+	// certificates and images are enforced.
+	IssueEmptyDBX
+
+	// IssueTestPlatformKey means PK is a test key, which is not supposed
+	// to be shipped (for example the AMI "DO NOT TRUST" key).
+	IssueTestPlatformKey
+
+	// IssueDigestMismatch means the event data does not match the
+	// digest, so the event data cannot be trusted.
+	IssueDigestMismatch
+
+	// IssueMalformedEvent means the event data could not be parsed.
+	IssueMalformedEvent
+
+	// IssueSecurityRelevantAction means an EV_EFI_ACTION event was measured
+	// into PCR7 (for example "UEFI Debug Mode" or "DMA Protection Disabled").
+	IssueSecurityRelevantAction
+)
+
+// String implements fmt.Stringer.
+func (kind IssueKind) String() string {
+	switch kind {
+	case IssueUndefined:
+		return "undefined"
+	case IssueSecureBootDisabled:
+		return "secure_boot_disabled"
+	case IssueMissingVariable:
+		return "missing_variable"
+	case IssueMissingDBX:
+		return "missing_dbx"
+	case IssueEmptyDBX:
+		return "empty_dbx"
+	case IssueTestPlatformKey:
+		return "test_platform_key"
+	case IssueDigestMismatch:
+		return "digest_mismatch"
+	case IssueMalformedEvent:
+		return "malformed_event"
+	case IssueSecurityRelevantAction:
+		return "security_relevant_action"
+	}
+	return fmt.Sprintf("unknown_issue_%d", uint(kind))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (kind IssueKind) MarshalText() ([]byte, error) {
+	return []byte(kind.String()), nil
+}
+
+// Issue is a problem found in the Secure Boot policy.
+type Issue struct {
+	Kind        IssueKind
+	Description string
+}
+
+// String implements fmt.Stringer.
+func (issue Issue) String() string {
+	return fmt.Sprintf("%s: %s", issue.Kind, issue.Description)
+}
@@ -55,17 +55,73 @@ const (
 
 	// TPMAlgorithmSHA256 is the identified of SHA256 algorithm.
 	TPMAlgorithmSHA256 = tpm2.AlgSHA256
+
+	// TPMAlgorithmSHA384 is the identified of SHA384 algorithm.
+	TPMAlgorithmSHA384 = tpm2.AlgSHA384
+
+	// TPMAlgorithmSHA512 is the identified of SHA512 algorithm.
+	TPMAlgorithmSHA512 = tpm2.AlgSHA512
+
+	// TPMAlgorithmSM3_256 is the identified of SM3_256 algorithm.
+	TPMAlgorithmSM3_256 = pcr.AlgSM3_256
 )
 
-// Parse parses a binary EventLog.
+// Parse parses a binary EventLog (see FormatTCG).
+//
+// To parse other formats or to detect the format use ParseWithFormat.
 func Parse(input io.Reader) (*TPMEventLog, error) {
+	return ParseWithFormat(input, FormatTCG)
+}
+
+// ParseWithFormat parses an EventLog of the given format.
+//
+// With FormatAuto the format is guessed by the content, and if the EventLog
+// could not be parsed in the guessed format, then the other plausible
+// formats are tried. The error of the first tried format is returned
+// if none of them succeeded.
+func ParseWithFormat(input io.Reader, format Format) (*TPMEventLog, error) {
 	b, err := io.ReadAll(input)
 	if err != nil {
 		return nil, ErrRead{Err: err}
 	}
+
+	formats := []Format{format}
+	if format == FormatAuto {
+		formats = detectFormats(b)
+	}
+	var firstErr error
+	for _, format := range formats {
+		result, err := parseFormat(b, format)
+		if err == nil {
+			return result, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, ErrParse{Err: firstErr}
+}
+
+func parseFormat(b []byte, format Format) (*TPMEventLog, error) {
+	switch {
+	case format == FormatTCG:
+		return parseTCG(b)
+	case format == FormatTPM12:
+		return parseLegacy(b)
+	case format.IsCEL():
+		return parseCEL(b, format)
+	}
+	return nil, fmt.Errorf("unknown EventLog format: %s", format)
+}
+
+func parseTCG(b []byte) (*TPMEventLog, error) {
+	if isCryptoAgile(b) {
+		return parseCryptoAgile(b)
+	}
+
 	eventLog, err := attest.ParseEventLog(b)
 	if err != nil {
-		return nil, ErrParse{Err: err}
+		return nil, err
 	}
 
 	result := &TPMEventLog{}
@@ -0,0 +1,204 @@
+package tpmeventlog
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
+)
+
+// firmwarePCRsAmount is the amount of PCRs, which are extended only
+// by the firmware (PCR0-PCR7), and thus are expected to be always
+// reproducible from the EventLog.
+const firmwarePCRsAmount = 8
+
+// PCRVerification is the result of comparing a PCR value with the value
+// replayed from the EventLog.
+type PCRVerification struct {
+	PCRIndex pcr.ID
+	HashAlgo TPMAlgorithm
+	Expected []byte
+	Replayed []byte
+
+	// Err is set if the PCR value could not be replayed.
+	Err error
+
+	// Diagnosis is the most likely reason of the mismatch, it is
+	// nil if the values match.
+	Diagnosis *MismatchDiagnosis
+}
+
+// IsMatch returns true if the replayed value equals to the expected one.
+func (v PCRVerification) IsMatch() bool {
+	return v.Err == nil && bytes.Equal(v.Expected, v.Replayed)
+}
+
+// MismatchDiagnosisKind is the kind of a MismatchDiagnosis.
+type MismatchDiagnosisKind uint
+
+const (
+	// MismatchDiagnosisUndefined is a zero value, should never be used.
+	MismatchDiagnosisUndefined = MismatchDiagnosisKind(iota)
+
+	// MismatchDiagnosisExtraEvents means the expected value is reached
+	// before the end of the EventLog: Event and the events after it were
+	// logged, but not extended into the PCR.
+	MismatchDiagnosisExtraEvents
+
+	// MismatchDiagnosisNotExtendedEvent means the expected value is
+	// reproduced if Event is skipped: it was logged, but not extended
+	// into the PCR (or was logged with a wrong digest).
+	MismatchDiagnosisNotExtendedEvent
+
+	// MismatchDiagnosisMissingEvents means the expected value is not
+	// reproducible by the logged events: some events were extended but
+	// not logged, most likely the EventLog is truncated after Event
+	// (Event is nil if there are no events at all).
+	MismatchDiagnosisMissingEvents
+)
+
+// String implements fmt.Stringer.
+func (kind MismatchDiagnosisKind) String() string {
+	switch kind {
+	case MismatchDiagnosisUndefined:
+		return "undefined"
+	case MismatchDiagnosisExtraEvents:
+		return "extra_events"
+	case MismatchDiagnosisNotExtendedEvent:
+		return "not_extended_event"
+	case MismatchDiagnosisMissingEvents:
+		return "missing_events"
+	}
+	return fmt.Sprintf("unknown_diagnosis_%d", uint(kind))
+}
+
+// MismatchDiagnosis points at the event, where the replayed PCR value
+// is likely to diverge from the real one.
+type MismatchDiagnosis struct {
+	Kind MismatchDiagnosisKind
+
+	// EventIndex is the index of Event within TPMEventLog.Events
+	// (-1 if Event is nil).
+	EventIndex int
+	Event      *Event
+}
+
+// String implements fmt.Stringer.
+func (d MismatchDiagnosis) String() string {
+	switch d.Kind {
+	case MismatchDiagnosisExtraEvents:
+		return fmt.Sprintf("event #%d %s and the events after it were not extended into the PCR", d.EventIndex, d.Event)
+	case MismatchDiagnosisNotExtendedEvent:
+		return fmt.Sprintf("event #%d %s was not extended into the PCR or has a wrong digest", d.EventIndex, d.Event)
+	case MismatchDiagnosisMissingEvents:
+		if d.Event == nil {
+			return "the PCR was extended, but there are no events in the EventLog"
+		}
+		return fmt.Sprintf("events are missing, the EventLog is likely truncated after event #%d %s", d.EventIndex, d.Event)
+	}
+	return d.Kind.String()
+}
+
+// HashAlgos returns the hash algorithms of the digests of the EventLog.
+func (eventLog *TPMEventLog) HashAlgos() []TPMAlgorithm {
+	m := map[TPMAlgorithm]struct{}{}
+	for _, ev := range eventLog.Events {
+		if ev.Digest != nil {
+			m[ev.Digest.HashAlgo] = struct{}{}
+		}
+	}
+	result := make([]TPMAlgorithm, 0, len(m))
+	for hashAlgo := range m {
+		result = append(result, hashAlgo)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result
+}
+
+// VerifyPCRs replays the EventLog and compares the result with the expected
+// PCR values (indexed by the hash algorithm and the PCR index).
+//
+// Only the banks present in the EventLog are verified (see HashAlgos).
+// PCRs above PCR7 without events are skipped, since they are usually
+// extended by the OS without logging to this EventLog (like PCR10 by IMA).
+func VerifyPCRs(eventLog *TPMEventLog, expected map[TPMAlgorithm]map[pcr.ID][]byte) []PCRVerification {
+	var result []PCRVerification
+	isDRTMLaunched := eventLog.IsDRTMLaunched()
+	for _, hashAlgo := range eventLog.HashAlgos() {
+		values, ok := expected[hashAlgo]
+		if !ok {
+			continue
+		}
+		pcrIndexes := make([]pcr.ID, 0, len(values))
+		for pcrIndex := range values {
+			pcrIndexes = append(pcrIndexes, pcrIndex)
+		}
+		sort.Slice(pcrIndexes, func(i, j int) bool { return pcrIndexes[i] < pcrIndexes[j] })
+
+		for _, pcrIndex := range pcrIndexes {
+			events, err := eventLog.FilterEvents(pcrIndex, hashAlgo)
+			if err == nil && len(events) == 0 && pcrIndex >= firmwarePCRsAmount {
+				continue
+			}
+			v := PCRVerification{
+				PCRIndex: pcrIndex,
+				HashAlgo: hashAlgo,
+				Expected: values[pcrIndex],
+			}
+			if err != nil {
+				v.Err = fmt.Errorf("unable to filter events: %w", err)
+				result = append(result, v)
+				continue
+			}
+			v.Replayed, v.Err = replayEvents(events, pcrIndex, hashAlgo, isDRTMLaunched, nil)
+			if v.Err == nil && !v.IsMatch() {
+				v.Diagnosis = eventLog.diagnoseMismatch(events, pcrIndex, hashAlgo, v.Expected)
+			}
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func (eventLog *TPMEventLog) diagnoseMismatch(
+	events []*Event,
+	pcrIndex pcr.ID,
+	hashAlgo TPMAlgorithm,
+	expected []byte,
+) *MismatchDiagnosis {
+	isReproducedBy := func(events []*Event) bool {
+		replayed, err := replayEvents(events, pcrIndex, hashAlgo, eventLog.IsDRTMLaunched(), nil)
+		return err == nil && bytes.Equal(replayed, expected)
+	}
+	newDiagnosis := func(kind MismatchDiagnosisKind, ev *Event) *MismatchDiagnosis {
+		d := &MismatchDiagnosis{Kind: kind, EventIndex: -1, Event: ev}
+		for idx, candidate := range eventLog.Events {
+			if candidate == ev {
+				d.EventIndex = idx
+				break
+			}
+		}
+		return d
+	}
+
+	for idx := range events {
+		if isReproducedBy(events[:idx]) {
+			return newDiagnosis(MismatchDiagnosisExtraEvents, events[idx])
+		}
+	}
+
+	for idx := range events {
+		withoutEvent := make([]*Event, 0, len(events)-1)
+		withoutEvent = append(withoutEvent, events[:idx]...)
+		withoutEvent = append(withoutEvent, events[idx+1:]...)
+		if isReproducedBy(withoutEvent) {
+			return newDiagnosis(MismatchDiagnosisNotExtendedEvent, events[idx])
+		}
+	}
+
+	if len(events) == 0 {
+		return newDiagnosis(MismatchDiagnosisMissingEvents, nil)
+	}
+	return newDiagnosis(MismatchDiagnosisMissingEvents, events[len(events)-1])
+}
@@ -0,0 +1,63 @@
+package tpmeventlog
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyPCRs(t *testing.T) {
+	newEvent := func(pcrIndex pcr.ID, data string) *Event {
+		digest := sha256.Sum256([]byte(data))
+		return &Event{
+			PCRIndex: pcrIndex,
+			Type:     EV_ACTION,
+			Data:     []byte(data),
+			Digest:   &Digest{HashAlgo: TPMAlgorithmSHA256, Digest: digest[:]},
+		}
+	}
+	eventLog := &TPMEventLog{Events: []*Event{
+		newEvent(1, "a"),
+		newEvent(2, "b"),
+		newEvent(1, "c"),
+		newEvent(2, "d"),
+		newEvent(3, "e"),
+		newEvent(1, "f"),
+	}}
+	replay := func(events ...*Event) []byte {
+		result, err := Replay(&TPMEventLog{Events: events}, events[0].PCRIndex, TPMAlgorithmSHA256, nil)
+		require.NoError(t, err)
+		return result
+	}
+	ev := eventLog.Events
+
+	results := VerifyPCRs(eventLog, map[TPMAlgorithm]map[pcr.ID][]byte{
+		TPMAlgorithmSHA256: {
+			1:  replay(ev[0], ev[2]),                  // the last event was not extended
+			2:  replay(ev[1], ev[3]),                  // OK
+			3:  replay(ev[4], newEvent(3, "missing")), // an event is missing
+			10: make([]byte, sha256.Size),             // no events, skipped
+		},
+		TPMAlgorithmSHA1: {
+			1: make([]byte, 20), // no such bank in the EventLog
+		},
+	})
+	require.Len(t, results, 3)
+
+	require.Equal(t, pcr.ID(1), results[0].PCRIndex)
+	require.False(t, results[0].IsMatch())
+	require.Equal(t, &MismatchDiagnosis{Kind: MismatchDiagnosisExtraEvents, EventIndex: 5, Event: ev[5]}, results[0].Diagnosis)
+
+	require.True(t, results[1].IsMatch())
+	require.Nil(t, results[1].Diagnosis)
+
+	require.Equal(t, &MismatchDiagnosis{Kind: MismatchDiagnosisMissingEvents, EventIndex: 4, Event: ev[4]}, results[2].Diagnosis)
+
+	results = VerifyPCRs(eventLog, map[TPMAlgorithm]map[pcr.ID][]byte{
+		TPMAlgorithmSHA256: {1: replay(ev[0], ev[5])},
+	})
+	require.Len(t, results, 1)
+	require.Equal(t, &MismatchDiagnosis{Kind: MismatchDiagnosisNotExtendedEvent, EventIndex: 2, Event: ev[2]}, results[0].Diagnosis)
+}
@@ -0,0 +1,213 @@
+package tpmeventlog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/bootflow/subsystems/trustchains/tpm/pcr"
+)
+
+const (
+	// specIDEventSpecVersionMajor, specIDEventSpecVersionMinor and
+	// specIDEventSpecErrata define the version of
+	// "TCG PC Client Platform Firmware Profile Specification" declared
+	// in TCG_EfiSpecIDEvent.
+	specIDEventSpecVersionMajor = 2
+	specIDEventSpecVersionMinor = 0
+	specIDEventSpecErrata       = 0
+
+	// specIDEventUintnSize is the size of UINTN in UINT32-s (2 means UINT64).
+	specIDEventUintnSize = 2
+)
+
+// cryptoAgileEvent is a TCG_PCR_EVENT2 (an event with digests of multiple banks).
+type cryptoAgileEvent struct {
+	PCRIndex pcr.ID
+	Type     EventType
+	Digests  []*Digest
+	Data     []byte
+}
+
+// WriteCryptoAgile writes the EventLog in the crypto-agile format (see
+// "TCG PC Client Platform Firmware Profile Specification", section 10),
+// which could be parsed back by Parse.
+//
+// TCG_EfiSpecIDEvent lists the hash algorithms of the events in the order of
+// their first appearance. Events of different hash algorithms are merged
+// into a single TCG_PCR_EVENT2 if they have the same PCR index, type and
+// data: the N-th such event of one bank is merged with the N-th such event
+// of every other bank. Every TCG_PCR_EVENT2 has to contain digests of all
+// the banks, so an event without a counterpart in some bank results into
+// ErrMissingDigest (except EV_NO_ACTION events, which have zero digests).
+func (eventLog *TPMEventLog) WriteCryptoAgile(w io.Writer) error {
+	algs, digestSizes, events, err := eventLog.cryptoAgileEvents()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+
+	// TCG_PCR_EVENT with TCG_EfiSpecIDEvent
+	specIDEvent := append([]byte{}, specIDEventSignature...)
+	specIDEvent = binaryOrder.AppendUint32(specIDEvent, 0) // PlatformClass
+	specIDEvent = append(specIDEvent,
+		specIDEventSpecVersionMinor,
+		specIDEventSpecVersionMajor,
+		specIDEventSpecErrata,
+		specIDEventUintnSize,
+	)
+	specIDEvent = binaryOrder.AppendUint32(specIDEvent, uint32(len(algs)))
+	for _, alg := range algs {
+		specIDEvent = binaryOrder.AppendUint16(specIDEvent, uint16(alg))
+		specIDEvent = binaryOrder.AppendUint16(specIDEvent, uint16(digestSizes[alg]))
+	}
+	specIDEvent = append(specIDEvent, 0) // VendorInfoSize
+
+	b := binaryOrder.AppendUint32(nil, 0) // PCRIndex
+	b = binaryOrder.AppendUint32(b, uint32(EV_NO_ACTION))
+	b = append(b, make([]byte, 20)...) // SHA1 Digest
+	b = binaryOrder.AppendUint32(b, uint32(len(specIDEvent)))
+	buf.Write(b)
+	buf.Write(specIDEvent)
+
+	// TCG_PCR_EVENT2-s
+	for _, ev := range events {
+		b := binaryOrder.AppendUint32(nil, uint32(ev.PCRIndex))
+		b = binaryOrder.AppendUint32(b, uint32(ev.Type))
+		b = binaryOrder.AppendUint32(b, uint32(len(ev.Digests)))
+		for _, digest := range ev.Digests {
+			b = binaryOrder.AppendUint16(b, uint16(digest.HashAlgo))
+			b = append(b, digest.Digest...)
+		}
+		b = binaryOrder.AppendUint32(b, uint32(len(ev.Data)))
+		b = append(b, ev.Data...)
+		buf.Write(b)
+	}
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return ErrWrite{Err: err}
+	}
+	return nil
+}
+
+// cryptoAgileEvents groups the events into TCG_PCR_EVENT2-s (see WriteCryptoAgile)
+// and returns them with the list of used hash algorithms and their digest sizes.
+func (eventLog *TPMEventLog) cryptoAgileEvents() ([]TPMAlgorithm, map[TPMAlgorithm]int, []*cryptoAgileEvent, error) {
+	var algs []TPMAlgorithm
+	digestSizes := map[TPMAlgorithm]int{}
+	for idx, ev := range eventLog.Events {
+		if ev.Digest == nil {
+			return nil, nil, nil, fmt.Errorf("event #%d has no digest", idx)
+		}
+		alg := ev.Digest.HashAlgo
+		if _, ok := digestSizes[alg]; !ok {
+			h, err := pcr.Hash(alg)
+			if err != nil {
+				return nil, nil, nil, ErrNotSupportedHashAlgo{TPMAlgo: alg}
+			}
+			digestSizes[alg] = h.Size()
+			algs = append(algs, alg)
+		}
+		if len(ev.Digest.Digest) != digestSizes[alg] {
+			return nil, nil, nil, fmt.Errorf("event #%d: %w", idx, ErrInvalidDigestLength{
+				Expected: digestSizes[alg],
+				Received: len(ev.Digest.Digest),
+			})
+		}
+	}
+
+	// the N-th event with the specific identity in one bank corresponds
+	// to the N-th event with the same identity in every other bank.
+	type groupKey struct {
+		identity   cryptoAgileEventIdentity
+		occurrence int
+	}
+	var events []*cryptoAgileEvent
+	groups := map[groupKey]*cryptoAgileEvent{}
+	occurrences := map[TPMAlgorithm]map[cryptoAgileEventIdentity]int{}
+	for _, ev := range eventLog.Events {
+		alg := ev.Digest.HashAlgo
+		identity := cryptoAgileEventIdentity{
+			PCRIndex: ev.PCRIndex,
+			Type:     ev.Type,
+			Data:     string(ev.Data),
+		}
+		if occurrences[alg] == nil {
+			occurrences[alg] = map[cryptoAgileEventIdentity]int{}
+		}
+		key := groupKey{identity: identity, occurrence: occurrences[alg][identity]}
+		occurrences[alg][identity]++
+
+		group := groups[key]
+		if group == nil {
+			group = &cryptoAgileEvent{
+				PCRIndex: ev.PCRIndex,
+				Type:     ev.Type,
+				Data:     ev.Data,
+			}
+			groups[key] = group
+			events = append(events, group)
+		}
+		group.Digests = append(group.Digests, ev.Digest)
+	}
+
+	for _, ev := range events {
+		digests := make([]*Digest, 0, len(algs))
+		for _, alg := range algs {
+			digest := ev.digest(alg)
+			if digest == nil {
+				if ev.Type != EV_NO_ACTION {
+					return nil, nil, nil, ErrMissingDigest{
+						PCRIndex: ev.PCRIndex,
+						Type:     ev.Type,
+						HashAlgo: alg,
+					}
+				}
+				digest = &Digest{HashAlgo: alg, Digest: make([]byte, digestSizes[alg])}
+			}
+			digests = append(digests, digest)
+		}
+		ev.Digests = digests
+	}
+	return algs, digestSizes, events, nil
+}
+
+// eventsFromCryptoAgile is the reverse of cryptoAgileEvents: it splits the
+// events by hash algorithm and groups them in the order of "algs".
+func eventsFromCryptoAgile(algs []TPMAlgorithm, events []*cryptoAgileEvent) *TPMEventLog {
+	eventsByAlg := map[TPMAlgorithm][]*Event{}
+	for _, ev := range events {
+		for _, digest := range ev.Digests {
+			eventsByAlg[digest.HashAlgo] = append(eventsByAlg[digest.HashAlgo], &Event{
+				PCRIndex: ev.PCRIndex,
+				Type:     ev.Type,
+				Data:     ev.Data,
+				Digest:   digest,
+			})
+		}
+	}
+
+	result := &TPMEventLog{}
+	for _, alg := range algs {
+		result.Events = append(result.Events, eventsByAlg[alg]...)
+	}
+	return result
+}
+
+// cryptoAgileEventIdentity is the identity of an event regardless
+// of the bank it belongs to.
+type cryptoAgileEventIdentity struct {
+	PCRIndex pcr.ID
+	Type     EventType
+	Data     string
+}
+
+func (ev *cryptoAgileEvent) digest(alg TPMAlgorithm) *Digest {
+	for _, digest := range ev.Digests {
+		if digest.HashAlgo == alg {
+			return digest
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package tpmeventlog
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteCryptoAgile(t *testing.T) {
+	locality := []byte("StartupLocality\x00\x03")
+	eventLog := &TPMEventLog{Events: []*Event{
+		{PCRIndex: 0, Type: EV_NO_ACTION, Data: locality, Digest: &Digest{HashAlgo: TPMAlgorithmSHA1, Digest: make([]byte, 20)}},
+		{PCRIndex: 0, Type: EV_NO_ACTION, Data: locality, Digest: &Digest{HashAlgo: TPMAlgorithmSHA256, Digest: make([]byte, 32)}},
+		{PCRIndex: 0, Type: EV_S_CRTM_VERSION, Data: []byte{1}, Digest: &Digest{HashAlgo: TPMAlgorithmSHA1, Digest: bytes.Repeat([]byte{1}, 20)}},
+		{PCRIndex: 0, Type: EV_S_CRTM_VERSION, Data: []byte{1}, Digest: &Digest{HashAlgo: TPMAlgorithmSHA256, Digest: bytes.Repeat([]byte{2}, 32)}},
+		{PCRIndex: 0, Type: EV_S_CRTM_CONTENTS, Data: []byte("PCR0_DATA"), Digest: &Digest{HashAlgo: TPMAlgorithmSHA1, Digest: bytes.Repeat([]byte{3}, 20)}},
+		{PCRIndex: 0, Type: EV_S_CRTM_CONTENTS, Data: []byte("PCR0_DATA"), Digest: &Digest{HashAlgo: TPMAlgorithmSHA256, Digest: bytes.Repeat([]byte{4}, 32)}},
+	}}
+
+	var buf bytes.Buffer
+	require.NoError(t, eventLog.WriteCryptoAgile(&buf))
+
+	// TCG_PCR_EVENT + 3 TCG_PCR_EVENT2-s
+	specIDEventSize := len(specIDEventSignature) + 4 + 4 + 4 + 2*4 + 1
+	require.Equal(t,
+		legacyEventHeaderSize+specIDEventSize+
+			(12+2+20+2+32+4+len(locality))+
+			(12+2+20+2+32+4+1)+
+			(12+2+20+2+32+4+9),
+		buf.Len(),
+	)
+
+	parsed, err := Parse(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	byBank := []*Event{
+		eventLog.Events[0], eventLog.Events[2], eventLog.Events[4],
+		eventLog.Events[1], eventLog.Events[3], eventLog.Events[5],
+	}
+	require.Equal(t, byBank, parsed.Events)
+
+	// events are merged by their identity, not by their order
+	var bufByBank bytes.Buffer
+	require.NoError(t, (&TPMEventLog{Events: byBank}).WriteCryptoAgile(&bufByBank))
+	require.Equal(t, buf.Bytes(), bufByBank.Bytes())
+
+	// repeated events are merged in the order of their occurrence
+	repeated := &TPMEventLog{Events: []*Event{
+		{PCRIndex: 2, Type: EV_SEPARATOR, Data: []byte{0, 0, 0, 0}, Digest: &Digest{HashAlgo: TPMAlgorithmSHA1, Digest: bytes.Repeat([]byte{1}, 20)}},
+		{PCRIndex: 2, Type: EV_SEPARATOR, Data: []byte{0, 0, 0, 0}, Digest: &Digest{HashAlgo: TPMAlgorithmSHA1, Digest: bytes.Repeat([]byte{2}, 20)}},
+		{PCRIndex: 2, Type: EV_SEPARATOR, Data: []byte{0, 0, 0, 0}, Digest: &Digest{HashAlgo: TPMAlgorithmSHA256, Digest: bytes.Repeat([]byte{1}, 32)}},
+		{PCRIndex: 2, Type: EV_SEPARATOR, Data: []byte{0, 0, 0, 0}, Digest: &Digest{HashAlgo: TPMAlgorithmSHA256, Digest: bytes.Repeat([]byte{2}, 32)}},
+	}}
+	_, _, events, err := repeated.cryptoAgileEvents()
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	for idx, ev := range events {
+		require.Equal(t, []*Digest{repeated.Events[idx].Digest, repeated.Events[idx+2].Digest}, ev.Digests)
+	}
+
+	err = (&TPMEventLog{Events: []*Event{
+		{Digest: &Digest{HashAlgo: TPMAlgorithmSHA256, Digest: make([]byte, 20)}},
+	}}).WriteCryptoAgile(&buf)
+	require.ErrorAs(t, err, &ErrInvalidDigestLength{})
+
+	// every TCG_PCR_EVENT2 has digests of all the banks
+	err = (&TPMEventLog{Events: []*Event{
+		{PCRIndex: 0, Type: EV_S_CRTM_VERSION, Digest: &Digest{HashAlgo: TPMAlgorithmSHA1, Digest: make([]byte, 20)}},
+		{PCRIndex: 0, Type: EV_S_CRTM_CONTENTS, Digest: &Digest{HashAlgo: TPMAlgorithmSHA256, Digest: make([]byte, 32)}},
+	}}).WriteCryptoAgile(&buf)
+	require.ErrorAs(t, err, &ErrMissingDigest{})
+
+	// except EV_NO_ACTION events, which have zero digests
+	noAction := &TPMEventLog{Events: []*Event{
+		{PCRIndex: 0, Type: EV_NO_ACTION, Data: locality, Digest: &Digest{HashAlgo: TPMAlgorithmSHA1, Digest: make([]byte, 20)}},
+		{PCRIndex: 0, Type: EV_S_CRTM_VERSION, Digest: &Digest{HashAlgo: TPMAlgorithmSHA256, Digest: make([]byte, 32)}},
+		{PCRIndex: 0, Type: EV_S_CRTM_VERSION, Digest: &Digest{HashAlgo: TPMAlgorithmSHA1, Digest: make([]byte, 20)}},
+	}}
+	_, _, events, err = noAction.cryptoAgileEvents()
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, []*Digest{
+		{HashAlgo: TPMAlgorithmSHA1, Digest: make([]byte, 20)},
+		{HashAlgo: TPMAlgorithmSHA256, Digest: make([]byte, 32)},
+	}, events[0].Digests)
+}
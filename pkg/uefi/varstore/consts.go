@@ -0,0 +1,60 @@
+package varstore
+
+import (
+	"github.com/linuxboot/fiano/pkg/guid"
+)
+
+var (
+	// GUIDSystemNVDataFV is the file system GUID of the firmware volume
+	// which contains the variable store (EFI_SYSTEM_NV_DATA_FV_GUID).
+	GUIDSystemNVDataFV = *guid.MustParse("FFF12B8D-7696-4C8B-A985-2747075B4F50")
+
+	// GUIDVariable is the signature of a variable store with
+	// non-authenticated variables (gEfiVariableGuid).
+	GUIDVariable = *guid.MustParse("DDCF3616-3275-4164-98B6-FE85707FFE7D")
+
+	// GUIDAuthenticatedVariable is the signature of a variable store with
+	// authenticated variables (gEfiAuthenticatedVariableGuid).
+	GUIDAuthenticatedVariable = *guid.MustParse("AAF32C78-947B-439A-A180-2E144EC37792")
+
+	// GUIDGlobalVariable is the vendor GUID of the variables defined
+	// by the UEFI specification, like "SecureBoot", "PK" and "KEK" (EFI_GLOBAL_VARIABLE).
+	GUIDGlobalVariable = *guid.MustParse("8BE4DF61-93CA-11D2-AA0D-00E098032B8C")
+
+	// GUIDImageSecurityDatabase is the vendor GUID of the variables
+	// "db", "dbx", "dbt" and "dbr" (EFI_IMAGE_SECURITY_DATABASE_GUID).
+	GUIDImageSecurityDatabase = *guid.MustParse("D719B2CB-3D3A-4596-A3BC-DAD00E67656F")
+
+	// GUIDSecureBootEnableDisable is the vendor GUID of the EDK2 variable
+	// "SecureBootEnable" (gEfiSecureBootEnableDisableGuid).
+	GUIDSecureBootEnableDisable = *guid.MustParse("F0A30BC7-AF08-4556-99C4-001009C93A44")
+)
+
+const (
+	// variableStartID is the value of field StartId of each variable header.
+	variableStartID = 0x55AA
+
+	// storeFormatted is the value of field Format of a formatted variable store.
+	storeFormatted = 0x5A
+
+	// variableAlignment is the alignment of each variable header within the store.
+	variableAlignment = 4
+)
+
+// State is the value of field State of a variable header.
+type State uint8
+
+const (
+	// StateInDeletedTransition means the variable is being deleted.
+	StateInDeletedTransition = State(0xFE)
+
+	// StateDeleted means the variable is deleted.
+	StateDeleted = State(0xFD)
+
+	// StateHeaderValidOnly means the variable header was written, but
+	// the data is not.
+	StateHeaderValidOnly = State(0x7F)
+
+	// StateAdded means the variable is completely written.
+	StateAdded = State(0x3F)
+)
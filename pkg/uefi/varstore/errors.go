@@ -0,0 +1,50 @@
+package varstore
+
+import (
+	"fmt"
+)
+
+// ErrUnknownSignature means the variable store header has an unknown
+// signature GUID (neither gEfiVariableGuid nor gEfiAuthenticatedVariableGuid).
+type ErrUnknownSignature struct {
+	Offset uint64
+}
+
+func (err ErrUnknownSignature) Error() string {
+	return fmt.Sprintf("unknown variable store signature at offset 0x%X", err.Offset)
+}
+
+// ErrNotFormatted means the variable store is not formatted.
+type ErrNotFormatted struct {
+	Offset uint64
+}
+
+func (err ErrNotFormatted) Error() string {
+	return fmt.Sprintf("the variable store at offset 0x%X is not formatted", err.Offset)
+}
+
+// ErrOutOfBounds means a structure crosses the end of the buffer.
+type ErrOutOfBounds struct {
+	What   string
+	Offset uint64
+	Length uint64
+}
+
+func (err ErrOutOfBounds) Error() string {
+	return fmt.Sprintf("%s at offset 0x%X of length 0x%X is out of bounds", err.What, err.Offset, err.Length)
+}
+
+// ErrCorruptStore means the variable store of the firmware volume
+// could not be parsed.
+type ErrCorruptStore struct {
+	VolumeOffset uint64
+	Err          error
+}
+
+func (err ErrCorruptStore) Error() string {
+	return fmt.Sprintf("unable to parse the variable store of the volume at offset 0x%X: %v", err.VolumeOffset, err.Err)
+}
+
+func (err ErrCorruptStore) Unwrap() error {
+	return err.Err
+}
@@ -0,0 +1,237 @@
+package varstore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"unicode/utf16"
+
+	"github.com/9elements/converged-security-suite/v2/pkg/errors"
+	"github.com/linuxboot/fiano/pkg/guid"
+)
+
+// The layout of the EDK2 variable store is defined in
+// MdeModulePkg/Include/Guid/VariableFormat.h
+
+const (
+	fvSignatureOffset    = 40
+	fvHeaderLengthOffset = 48
+	fvMinHeaderSize      = 56
+
+	storeHeaderSize = 28
+
+	variableHeaderSize              = 32
+	authenticatedVariableHeaderSize = 60
+)
+
+var (
+	binaryOrder = binary.LittleEndian
+	fvSignature = []byte("_FVH")
+)
+
+// Variable is a single UEFI variable found in a variable store.
+type Variable struct {
+	Name       string
+	VendorGUID guid.GUID
+	Attributes uint32
+	State      State
+
+	// HeaderOffset is the offset of the variable header within the firmware image.
+	HeaderOffset uint64
+
+	// DataOffset is the offset of the variable data within the firmware image.
+	DataOffset uint64
+
+	Data []byte
+}
+
+// IsValid returns true if the variable is not deleted and completely written.
+func (v *Variable) IsValid() bool {
+	return v.State == StateAdded || v.State == StateAdded&StateInDeletedTransition
+}
+
+// String implements fmt.Stringer.
+func (v *Variable) String() string {
+	return fmt.Sprintf("%s:%s", v.VendorGUID, v.Name)
+}
+
+// Store is a parsed variable store.
+type Store struct {
+	// Offset is the offset of the variable store header within the firmware image.
+	Offset uint64
+
+	// Size is the size of the variable store (including its header).
+	Size uint64
+
+	IsAuthenticated bool
+	Variables       []Variable
+}
+
+// Stores is a slice of Store-s.
+type Stores []*Store
+
+// Find returns the valid variable with the given name and vendor GUID.
+//
+// Returns nil if there is no such variable.
+func (s Stores) Find(name string, vendorGUID guid.GUID) *Variable {
+	for _, store := range s {
+		if v := store.Find(name, vendorGUID); v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+// Find returns the valid variable with the given name and vendor GUID.
+//
+// Returns nil if there is no such variable.
+func (store *Store) Find(name string, vendorGUID guid.GUID) *Variable {
+	var result *Variable
+	for idx := range store.Variables {
+		v := &store.Variables[idx]
+		if v.Name != name || v.VendorGUID != vendorGUID || !v.IsValid() {
+			continue
+		}
+		if v.State == StateAdded {
+			// A variable in state "added" always takes precedence over
+			// its copy which is in the deletion transition.
+			return v
+		}
+		result = v
+	}
+	return result
+}
+
+// FindStores finds and parses all the variable stores in the firmware image.
+//
+// The stores which could not be parsed are skipped, each of them is
+// reported as ErrCorruptStore in the returned error (the valid stores
+// are returned anyway).
+func FindStores(image []byte) (Stores, error) {
+	var (
+		result Stores
+		mErr   errors.MultiError
+	)
+	for searchOffset := 0; ; {
+		idx := bytes.Index(image[searchOffset:], fvSignature)
+		if idx < 0 {
+			break
+		}
+		signatureOffset := searchOffset + idx
+		searchOffset = signatureOffset + len(fvSignature)
+
+		fvOffset := signatureOffset - fvSignatureOffset
+		if fvOffset < 0 || fvOffset+fvMinHeaderSize > len(image) {
+			continue
+		}
+		var fsGUID guid.GUID
+		copy(fsGUID[:], image[fvOffset+16:])
+		if fsGUID != GUIDSystemNVDataFV {
+			continue
+		}
+
+		headerLength := binaryOrder.Uint16(image[fvOffset+fvHeaderLengthOffset:])
+		store, err := ParseStore(image, uint64(fvOffset)+uint64(headerLength))
+		if err != nil {
+			_ = mErr.Add(ErrCorruptStore{VolumeOffset: uint64(fvOffset), Err: err})
+			continue
+		}
+		result = append(result, store)
+	}
+	return result, mErr.ReturnValue()
+}
+
+// ParseStore parses a variable store with the header at the given offset.
+func ParseStore(image []byte, offset uint64) (*Store, error) {
+	if offset+storeHeaderSize > uint64(len(image)) {
+		return nil, ErrOutOfBounds{What: "variable store header", Offset: offset, Length: storeHeaderSize}
+	}
+	hdr := image[offset:]
+
+	var signature guid.GUID
+	copy(signature[:], hdr)
+	store := &Store{
+		Offset: offset,
+		Size:   uint64(binaryOrder.Uint32(hdr[16:])),
+	}
+	switch signature {
+	case GUIDVariable:
+	case GUIDAuthenticatedVariable:
+		store.IsAuthenticated = true
+	default:
+		return nil, ErrUnknownSignature{Offset: offset}
+	}
+	if hdr[20] != storeFormatted {
+		return nil, ErrNotFormatted{Offset: offset}
+	}
+
+	end := offset + store.Size
+	if end > uint64(len(image)) || store.Size < storeHeaderSize {
+		return nil, ErrOutOfBounds{What: "variable store", Offset: offset, Length: store.Size}
+	}
+
+	headerSize := uint64(variableHeaderSize)
+	if store.IsAuthenticated {
+		headerSize = authenticatedVariableHeaderSize
+	}
+
+	for cur := alignUp(offset + storeHeaderSize); cur+headerSize <= end; {
+		varHdr := image[cur : cur+headerSize]
+		if binaryOrder.Uint16(varHdr) != variableStartID {
+			break
+		}
+
+		// The common fields, see VARIABLE_HEADER and AUTHENTICATED_VARIABLE_HEADER.
+		v := Variable{
+			State:        State(varHdr[2]),
+			Attributes:   binaryOrder.Uint32(varHdr[4:]),
+			HeaderOffset: cur,
+		}
+		tail := varHdr[headerSize-24:]
+		nameSize := uint64(binaryOrder.Uint32(tail[0:]))
+		dataSize := uint64(binaryOrder.Uint32(tail[4:]))
+		copy(v.VendorGUID[:], tail[8:])
+
+		nameOffset := cur + headerSize
+		v.DataOffset = nameOffset + nameSize
+		if v.DataOffset+dataSize > end {
+			return nil, ErrOutOfBounds{What: "variable", Offset: cur, Length: headerSize + nameSize + dataSize}
+		}
+		v.Name = DecodeUCS2(image[nameOffset:v.DataOffset])
+		v.Data = image[v.DataOffset : v.DataOffset+dataSize]
+		store.Variables = append(store.Variables, v)
+
+		cur = alignUp(v.DataOffset + dataSize)
+	}
+
+	return store, nil
+}
+
+func alignUp(offset uint64) uint64 {
+	return (offset + variableAlignment - 1) &^ (variableAlignment - 1)
+}
+
+// DecodeUCS2 decodes a string encoded the way variable names are stored
+// in UEFI (UTF-16LE), the string ends at the first null character.
+func DecodeUCS2(b []byte) string {
+	chars := make([]uint16, 0, len(b)/2)
+	for idx := 0; idx+1 < len(b); idx += 2 {
+		c := binaryOrder.Uint16(b[idx:])
+		if c == 0 {
+			break
+		}
+		chars = append(chars, c)
+	}
+	return string(utf16.Decode(chars))
+}
+
+// EncodeUCS2 encodes a string the way variable names are stored
+// in UEFI (UTF-16LE with a terminating null character).
+func EncodeUCS2(s string) []byte {
+	chars := utf16.Encode([]rune(s))
+	result := make([]byte, (len(chars)+1)*2)
+	for idx, c := range chars {
+		binaryOrder.PutUint16(result[idx*2:], c)
+	}
+	return result
+}
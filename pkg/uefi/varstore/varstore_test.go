@@ -0,0 +1,89 @@
+package varstore
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/linuxboot/fiano/pkg/guid"
+	"github.com/stretchr/testify/require"
+)
+
+func appendVariable(b []byte, name string, vendorGUID guid.GUID, state State, data []byte) []byte {
+	nameEncoded := EncodeUCS2(name)
+	b = binary.LittleEndian.AppendUint16(b, variableStartID)
+	b = append(b, byte(state), 0)
+	b = binary.LittleEndian.AppendUint32(b, 0x7) // NV+BS+RT
+	b = binary.LittleEndian.AppendUint64(b, 0)   // MonotonicCount
+	b = append(b, make([]byte, 16)...)           // TimeStamp
+	b = binary.LittleEndian.AppendUint32(b, 0)   // PubKeyIndex
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(nameEncoded)))
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(data)))
+	b = append(b, vendorGUID[:]...)
+	b = append(b, nameEncoded...)
+	b = append(b, data...)
+	for len(b)%variableAlignment != 0 {
+		b = append(b, 0xff)
+	}
+	return b
+}
+
+const testFVHeaderLength = 0x48
+
+// appendVolume appends a firmware volume with a variable store.
+func appendVolume(image []byte, signature guid.GUID, vars []byte) []byte {
+	var storeHdr []byte
+	storeHdr = append(storeHdr, signature[:]...)
+	storeHdr = binary.LittleEndian.AppendUint32(storeHdr, uint32(storeHeaderSize+len(vars)+0x10))
+	storeHdr = append(storeHdr, storeFormatted, 0xFE, 0, 0, 0, 0, 0, 0)
+
+	fv := make([]byte, testFVHeaderLength)
+	copy(fv[16:], GUIDSystemNVDataFV[:])
+	copy(fv[fvSignatureOffset:], fvSignature)
+	binary.LittleEndian.PutUint16(fv[fvHeaderLengthOffset:], testFVHeaderLength)
+	image = append(image, fv...)
+	image = append(image, storeHdr...)
+	image = append(image, vars...)
+	for idx := 0; idx < 0x20; idx++ {
+		image = append(image, 0xff)
+	}
+	return image
+}
+
+func TestFindStores(t *testing.T) {
+	var vars []byte
+	vars = appendVariable(vars, "PK", GUIDGlobalVariable, StateAdded&StateInDeletedTransition, []byte{1, 2, 3})
+	vars = appendVariable(vars, "PK", GUIDGlobalVariable, StateAdded, []byte{4, 5, 6, 7, 8})
+	vars = appendVariable(vars, "db", GUIDImageSecurityDatabase, StateAdded&StateDeleted, []byte{9})
+
+	image := appendVolume(make([]byte, 0x100), GUIDAuthenticatedVariable, vars)
+
+	stores, err := FindStores(image)
+	require.NoError(t, err)
+	require.Len(t, stores, 1)
+	require.True(t, stores[0].IsAuthenticated)
+	require.Equal(t, uint64(0x100+testFVHeaderLength), stores[0].Offset)
+	require.Len(t, stores[0].Variables, 3)
+
+	pk := stores.Find("PK", GUIDGlobalVariable)
+	require.NotNil(t, pk)
+	require.Equal(t, []byte{4, 5, 6, 7, 8}, pk.Data)
+	require.Equal(t, pk.Data, image[pk.DataOffset:pk.DataOffset+uint64(len(pk.Data))])
+
+	require.Nil(t, stores.Find("db", GUIDImageSecurityDatabase))
+	require.Nil(t, stores.Find("PK", GUIDImageSecurityDatabase))
+}
+
+func TestFindStoresCorrupt(t *testing.T) {
+	image := appendVolume(make([]byte, 0x100), GUIDSystemNVDataFV, nil)
+	corruptOffset := uint64(0x100)
+	image = appendVolume(image, GUIDAuthenticatedVariable, appendVariable(nil, "KEK", GUIDGlobalVariable, StateAdded, []byte{1}))
+
+	stores, err := FindStores(image)
+	require.Len(t, stores, 1)
+	require.NotNil(t, stores.Find("KEK", GUIDGlobalVariable))
+
+	var errCorrupt ErrCorruptStore
+	require.ErrorAs(t, err, &errCorrupt)
+	require.Equal(t, corruptOffset, errCorrupt.VolumeOffset)
+	require.ErrorAs(t, err, &ErrUnknownSignature{})
+}